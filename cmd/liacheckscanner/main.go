@@ -1,18 +1,29 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"runtime/debug"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/lia/liacheckscanner_go/internal/config"
-	"github.com/lia/liacheckscanner_go/internal/extractor"
+	"github.com/lia/liacheckscanner_go/internal/diagnostics"
 	"github.com/lia/liacheckscanner_go/internal/gui"
 	"github.com/lia/liacheckscanner_go/internal/logger"
-	"github.com/lia/liacheckscanner_go/internal/models"
+	"github.com/lia/liacheckscanner_go/internal/server"
+	"github.com/lia/liacheckscanner_go/internal/updates"
+	"github.com/lia/liacheckscanner_go/pkg/extractor"
+	"github.com/lia/liacheckscanner_go/pkg/models"
 )
 
 const (
@@ -48,11 +59,50 @@ func createRequiredDirectories() error {
 }
 
 func main() {
+	// ----- diff subcommand, with flags of its own -----
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			os.Stderr.WriteString("Error loading configuration: " + err.Error() + "\n")
+			os.Exit(1)
+		}
+		os.Exit(runDiff(extractor.NewExtractor(cfg.Database, nil), os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	// ----- CLI flags -----
 	cliMode := flag.Bool("cli", false, "Run in headless CLI mode (no GUI)")
 	outputFile := flag.String("output", "", "Output file path (CLI mode); defaults to stdout")
-	outputFormat := flag.String("format", "csv", "Output format: csv or json (CLI mode)")
+	outputFormat := flag.String("format", "csv", "Output format: csv, json, or an export template: "+strings.Join(extractor.ExportTemplateNames(), ", ")+" (CLI mode)")
 	enableRDAP := flag.Bool("rdap", false, "Enable RDAP enrichment (CLI mode)")
+	blockedOnly := flag.Bool("blocked-only", false, "Only output IPs whose greylisting state is blocked (CLI mode)")
+	requireApproval := flag.Bool("require-approval", false, "Show the enforcement delta and ask for confirmation before writing blocked IPs (CLI mode; implies -blocked-only)")
+	dryRun := flag.Bool("enforcement-dry-run", false, "Print the enforcement delta and the collateral matches against protected_prefixes_file, then exit without publishing; non-zero when publishing would be refused (CLI mode)")
+	preset := flag.String("preset", "", "Performance preset for this run: "+strings.Join(config.PresetNames(), ", ")+" (CLI mode)")
+	registries := flag.String("registries", "", "Comma-separated RDAP registries queried in this run instead of registries ("+strings.Join(extractor.RegistryNames, ", ")+"), or all (CLI mode)")
+	serve := flag.Bool("serve", false, "Keep serving the results over the REST API after the run (CLI mode; requires enable_api)")
+	selfTest := flag.Bool("selftest", false, "Check git, data directories, RDAP registries, geolocation and clock, then exit (non-zero on failure)")
+	verify := flag.String("verify", "", "Check a result file against the hash recorded in its run metadata when it was written, then exit; non-zero when it was modified or has no recorded hash")
+	reportAbuse := flag.Bool("report-abuseipdb", false, "Report blocked IPs to AbuseIPDB after the run (CLI mode; requires abuseipdb_report and abuseipdb_key)")
+	hitsFile := flag.String("hits", "", "Import a honeypot hits feed (CSV ip,timestamp,port or JSON) before correlating it with the dataset (CLI mode)")
+	seenAttacking := flag.Bool("seen-attacking", false, "Only output IPs that hit your honeypots (CLI mode)")
+	anonymize := flag.Bool("anonymize", false, "Strip contact emails to their domain, truncate host names and drop notes and contacts, for lists shared outside the team (CLI mode)")
+	ownershipChanged := flag.Bool("ownership-changed", false, "Only output records whose RDAP owner changed since the previous lookup, a possible transfer or hijack (CLI mode)")
+	window := flag.String("window", "", "Only output records whose date is recent, as field:duration with field registered, last_changed, first_seen or last_seen (e.g. registered:90d) (CLI mode)")
+	exportDB := flag.String("export-db", "", "Also upsert the output records, keyed by IP: postgres writes a psql script to the results directory, clickhouse loads them into clickhouse_url (CLI mode)")
+	remote := flag.Bool("remote", false, "Pull the dataset from the LiaCheckScanner API at remote_api_url instead of extracting it, fetching only the records updated since the last pull (CLI mode)")
+	dedup := flag.Bool("dedup", false, "Merge duplicate records (same IP and scanner, including IPv6 spellings) before writing the output (CLI mode)")
+	resume := flag.Bool("resume", false, "Resume an interrupted enrichment run on its IPs instead of extracting them again; implies -rdap (CLI mode)")
+	auditIPv6 := flag.Bool("audit-ipv6", false, "Report the IPv6 issues of the feed files and of the records (unparsed or missed addresses, zone IDs, non-canonical forms, RDAP ranges, exported ranges), then exit; non-zero when issues are found (CLI mode)")
+	minConfidence := flag.String("min-confidence", "", "Only output records whose scanner attribution is at least this confident: low, medium or high (CLI mode)")
+	prewarm := flag.String("prewarm", "", "Look up each prefix of this file (one per line) once in RDAP to pre-warm the cache before enrichment, or \"feed\" for the feed's ranges and the /24 or /48 of its IPs (CLI mode)")
+	riskFilter := flag.String("risk", "", "Only output records with one of these comma-separated risk levels, e.g. High,Medium (CLI mode)")
+	typeFilter := flag.String("scanner-type", "", "Only output records of these comma-separated scanner types, e.g. shodan,censys (CLI mode)")
+	countryFilter := flag.String("country", "", "Only output records from these comma-separated country codes, e.g. CN,RU (CLI mode)")
+	progress := flag.String("progress", progressText, "Progress lines of the run: text (processed=... total=... rate=... eta=...), json or none; written to stdout, or to stderr when the output goes to stdout (CLI mode)")
+	progressInterval := flag.Duration("progress-interval", 10*time.Second, "Minimum time between two progress lines of a stage (CLI mode)")
+	quiet := flag.Bool("quiet", false, "No progress lines and only warnings and errors logged, for cron jobs (CLI mode)")
+	exportPreset := flag.String("export-preset", "", "Redact the output with this export preset of export_presets; default_export_preset applies when unset (CLI mode)")
+	approver := flag.String("approver", os.Getenv("USER"), "Name recorded as approver with -require-approval (CLI mode)")
 	flag.Parse()
 
 	// Create required directories first
@@ -64,83 +114,449 @@ func main() {
 	// Initialiser le logger
 	log := logger.NewLogger()
 	log.Info("Main", "Starting "+AppName+" v"+Version)
+	extractor.Version = Version
 	log.Info("Main", "Owner: "+Owner)
 	log.Info("Main", "Directories created successfully")
 
 	// Charger la configuration
 	cfg, err := config.LoadConfig()
-	if err != nil {
+	var verr *config.ValidationError
+	switch {
+	case err == nil:
+		log.Info("Main", "Configuration loaded successfully")
+	case errors.As(err, &verr) && !*cliMode:
+		// The GUI opens anyway and lists the problems in a dialog.
+		for _, p := range verr.Problems {
+			log.Warning("Main", "Configuration problem: "+p)
+		}
+	default:
 		log.Error("Main", "Error loading configuration: "+err.Error())
 		os.Exit(1)
 	}
-	log.Info("Main", "Configuration loaded successfully")
+
+	// Apply the configured log level
+	if cfg != nil {
+		level, lerr := logger.ParseLevel(cfg.LogLevel)
+		if lerr != nil {
+			log.Warning("Main", lerr.Error()+", using INFO")
+		}
+		log.SetLogLevel(level)
+		log.SetPlainLabels(cfg.PlainLabels)
+	}
+
+	// Move file logging to the configured directory
+	if cfg != nil && cfg.Database.LogsDir != "" {
+		if err := log.SetLogsDir(cfg.Database.LogsDir); err != nil {
+			log.Warning("Main", "Cannot use logs directory: "+err.Error())
+		}
+	}
+
+	// ----- Self-test -----
+	if *selfTest {
+		ext := extractor.NewExtractor(cfg.Database, log)
+		if !printSelfTest(ext.SelfTest(), os.Stdout) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// ----- Integrity check -----
+	if *verify != "" {
+		os.Exit(runVerify(*verify, os.Stdout))
+	}
 
 	// ----- CLI mode -----
 	if *cliMode {
-		runCLI(cfg, log, *outputFile, *outputFormat, *enableRDAP)
+		runCLI(cfg, log, cliOptions{
+			outputFile:       *outputFile,
+			outputFormat:     *outputFormat,
+			enableRDAP:       *enableRDAP,
+			blockedOnly:      *blockedOnly,
+			requireApproval:  *requireApproval,
+			approver:         *approver,
+			dryRun:           *dryRun,
+			serve:            *serve,
+			preset:           *preset,
+			registries:       *registries,
+			reportAbuse:      *reportAbuse,
+			hitsFile:         *hitsFile,
+			seenAttacking:    *seenAttacking,
+			ownershipChanged: *ownershipChanged,
+			anonymize:        *anonymize,
+			window:           *window,
+			exportDB:         *exportDB,
+			remote:           *remote,
+			dedup:            *dedup,
+			resume:           *resume,
+			auditIPv6:        *auditIPv6,
+			minConfidence:    *minConfidence,
+			prewarm:          *prewarm,
+			filter:           extractor.ParseExportFilter(*riskFilter, *typeFilter, *countryFilter),
+			progress:         *progress,
+			progressInterval: *progressInterval,
+			quiet:            *quiet,
+			exportPreset:     *exportPreset,
+		})
 		return
 	}
 
 	// ----- GUI mode (default) -----
 	app := gui.NewApp(cfg, log)
+	if !cfg.DisableUpdateCheck {
+		app.CheckForUpdates(updates.NewChecker(), Version)
+	}
 	app.Run()
 
 	log.Info("Main", AppName+" closed successfully")
 }
 
+// cliOptions holds the command-line flags that drive runCLI.
+type cliOptions struct {
+	outputFile       string
+	outputFormat     string
+	enableRDAP       bool
+	blockedOnly      bool // only write records in the blocked state
+	requireApproval  bool // confirm the enforcement delta before writing
+	approver         string
+	dryRun           bool   // report the enforcement impact and exit
+	serve            bool   // serve the results over the REST API until interrupted
+	preset           string // performance preset applied for this run only
+	registries       string // RDAP registries queried in this run only
+	reportAbuse      bool   // report blocked IPs to AbuseIPDB after writing the output
+	hitsFile         string // honeypot hits feed imported before correlation
+	seenAttacking    bool   // only write records with honeypot hits
+	ownershipChanged bool   // only write records whose RDAP owner changed
+	anonymize        bool   // strip personal data from the output
+	window           string // time window filter, e.g. "registered:90d"
+	exportDB         string // "postgres" or "clickhouse" bulk export of the output
+	remote           bool   // pull the dataset from remote_api_url instead of extracting it
+	dedup            bool   // merge duplicate records before writing
+	resume           bool   // enrich the IPs of the interrupted run instead of extracting
+	auditIPv6        bool   // report the IPv6 issues of the feeds and records and exit
+	minConfidence    string // keep only records attributed at least this confidently
+	prewarm          string // prefix list (or "feed") looked up in RDAP before enrichment
+
+	// risk, scanner type and country filters of the output
+	filter extractor.ExportFilter
+
+	// progress lines of headless runs
+	progress         string        // progressText, progressJSON or progressNone
+	progressInterval time.Duration // minimum time between two lines of a stage
+	quiet            bool          // no progress lines, warnings and errors only
+
+	// redaction preset of the output ("" = default_export_preset)
+	exportPreset string
+}
+
 // runCLI executes the headless CLI workflow: extract IPs, optionally enrich
-// with RDAP, and write results to stdout or to a file.
-func runCLI(cfg *models.AppConfig, log *logger.Logger, outputFile, outputFormat string, enableRDAP bool) {
+// with RDAP, advance the greylisting lifecycle, and write results to stdout or
+// to a file. With -blocked-only or -require-approval, only records in the blocked state are written.
+func runCLI(cfg *models.AppConfig, log *logger.Logger, opts cliOptions) {
+
 	log.Info("CLI", "Running in CLI (headless) mode")
 
-	ext := extractor.NewExtractor(cfg.Database, log)
+	// A panic writes a crash report to <logs_dir>/crash before exiting
+	crash := diagnostics.New(cfg, log)
+	defer func() {
+		if v := recover(); v != nil {
+			crash.HandlePanic("CLI", v, debug.Stack())
+			os.Exit(2)
+		}
+	}()
 
-	// --- Extract IPs from the internet-scanners repository ---
-	log.Info("CLI", "Extracting IPs from repository...")
-	ips, err := ext.ExtractIPsOnly()
-	if err != nil {
-		log.Error("CLI", "Extraction failed: "+err.Error())
+	if opts.minConfidence != "" && !extractor.ValidConfidence(opts.minConfidence) {
+		log.Error("CLI", fmt.Sprintf("invalid -min-confidence %q (want low, medium or high)", opts.minConfidence))
+		os.Exit(1)
+	}
+
+	var window extractor.TimeWindow
+	if opts.window != "" {
+		w, err := extractor.ParseTimeWindow(opts.window)
+		if err != nil {
+			log.Error("CLI", err.Error())
+			os.Exit(1)
+		}
+		window = w
+	}
+
+	progress := strings.ToLower(opts.progress)
+	switch progress {
+	case progressText, progressJSON, progressNone:
+	default:
+		log.Error("CLI", "Unsupported -progress "+opts.progress+". Use text, json or none.")
+		os.Exit(1)
+	}
+	if opts.quiet {
+		progress = progressNone
+		log.SetLogLevel(models.LogLevelWarning)
+	}
+
+	switch strings.ToLower(opts.exportDB) {
+	case "", "postgres", "clickhouse":
+	default:
+		log.Error("CLI", "Unsupported -export-db "+opts.exportDB+". Use postgres or clickhouse.")
+		os.Exit(1)
+	}
+
+	if opts.preset != "" {
+		if err := config.ApplyPreset(&cfg.Database, opts.preset); err != nil {
+			log.Error("CLI", err.Error())
+			os.Exit(1)
+		}
+		log.Info("CLI", fmt.Sprintf("Preset %s: parallelism=%d throttle=%.2fs retries=%d batch=%d",
+			opts.preset, cfg.Database.Parallelism, cfg.Database.APIThrottle, cfg.Database.MaxRetries, cfg.Database.BatchSize))
+	}
+	if opts.registries != "" {
+		regs, err := extractor.ParseRegistries(opts.registries)
+		if err != nil {
+			log.Error("CLI", err.Error())
+			os.Exit(1)
+		}
+		cfg.Database.Registries = regs
+		log.Info("CLI", "RDAP registries for this run: "+opts.registries)
+	}
+
+	ext := extractor.NewExtractor(cfg.Database, log)
+	ext.Events().Subscribe(log.HandleEvent)
+	// An unknown preset fails now rather than after the extraction
+	if _, _, err := ext.ExportPreset(opts.exportPreset); err != nil {
+		log.Error("CLI", err.Error())
 		os.Exit(1)
 	}
-	log.Info("CLI", fmt.Sprintf("Extracted %d unique IPs", len(ips)))
+	ext.SetPanicHandler(crash.HandlePanic)
+	if progress != progressNone {
+		// Progress lines stay out of the records when they go to stdout
+		out := os.Stdout
+		if opts.outputFile == "" {
+			out = os.Stderr
+		}
+		ext.Events().Subscribe(newProgressReporter(out, progress, opts.progressInterval).HandleEvent)
+	}
 
-	// Build base ScannerData records
-	data := ext.BuildBaseRecords(ips)
+	// Ctrl-C aborts the run, requests in flight included; the enrichment
+	// state is kept for -resume
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 
-	// --- Optional RDAP enrichment ---
-	if enableRDAP {
-		log.Info("CLI", "RDAP enrichment enabled, enriching records...")
-		for i := range data {
-			if err := ext.EnrichRecordWithDelay(&data[i], 0); err != nil {
-				log.Warning("CLI", fmt.Sprintf("Enrichment error for %s: %v", data[i].IPOrCIDR, err))
+	var data []models.ScannerData
+	if opts.remote {
+		// --- Pull the dataset enriched by another instance ---
+		data = pullRemoteDataset(ctx, ext, log)
+	} else {
+		// An enrichment run killed midway leaves its state behind; the IPs it
+		// enriched come back from the RDAP cache when it is resumed
+		job, err := ext.InterruptedJob(extractor.JobEnrich)
+		if err != nil {
+			log.Warning("CLI", "Interrupted runs not checked: "+err.Error())
+		}
+		var ips []string
+		if job != nil && opts.resume {
+			log.Info("CLI", fmt.Sprintf("Resuming the enrichment run started %s at %d/%d IPs",
+				job.StartedAt.Local().Format("2006-01-02 15:04"), job.Done, len(job.IPs)))
+			ips = job.IPs
+			opts.enableRDAP = true
+		} else {
+			if job != nil {
+				log.Warning("CLI", fmt.Sprintf("The enrichment run started %s was interrupted at %d/%d IPs; use -resume to resume it",
+					job.StartedAt.Local().Format("2006-01-02 15:04"), job.Done, len(job.IPs)))
+			} else if opts.resume {
+				log.Info("CLI", "No interrupted enrichment run to resume")
 			}
+			// --- Extract IPs from the internet-scanners repository ---
+			log.Info("CLI", "Extracting IPs from repository...")
+			if ips, err = ext.ExtractIPsOnly(ctx); err != nil {
+				log.Error("CLI", "Extraction failed: "+err.Error())
+				os.Exit(1)
+			}
+			log.Info("CLI", fmt.Sprintf("Extracted %d unique IPs", len(ips)))
+		}
+
+		// --- Pre-warm the RDAP cache once per prefix ---
+		if opts.prewarm != "" {
+			prewarmRDAP(ctx, ext, log, opts.prewarm, ips)
 		}
-		log.Info("CLI", fmt.Sprintf("Enrichment complete: %d records", len(data)))
+
+		// Build base ScannerData records, enriched on the worker pool when
+		// RDAP is enabled; only the requested output is written
+		if opts.enableRDAP {
+			log.Info("CLI", "RDAP enrichment enabled, enriching records...")
+			if data, err = ext.EnrichIPs(ctx, ips); err != nil {
+				log.Error("CLI", "Enrichment failed: "+err.Error())
+				if errors.Is(err, context.Canceled) {
+					log.Info("CLI", "Use -resume to resume the enrichment run")
+				}
+				os.Exit(1)
+			}
+			log.Info("CLI", fmt.Sprintf("Enrichment complete: %d records", len(data)))
+			for _, line := range extractor.BuildRegistryReport(data, cfg.Database).Lines() {
+				log.Info("CLI", line)
+			}
+		} else {
+			data = ext.BuildBaseRecords(ips)
+		}
+
+		// --- Greylisting lifecycle ---
+		if err := ext.UpdateLifecycle(data); err != nil {
+			log.Warning("CLI", "Lifecycle update failed: "+err.Error())
+		}
+	}
+	ext.AttributeScanners(data)
+	if opts.auditIPv6 {
+		audit, err := ext.AuditIPv6(data)
+		if err != nil {
+			log.Error("CLI", "IPv6 audit failed: "+err.Error())
+			os.Exit(1)
+		}
+		if !printIPv6Audit(audit, os.Stdout) {
+			os.Exit(1)
+		}
+		return
+	}
+	if opts.dedup {
+		data = ext.Deduplicate(data)
+	}
+	ext.ApplyPrefixPolicy(data)
+	ext.ApplyEnforcementPolicy(data)
+	if err := ext.PushMetrics(ctx, data); err != nil {
+		log.Warning("CLI", "Metrics push failed: "+err.Error())
+	}
+	if warnings, err := ext.ExpiryWarnings(data, time.Now()); err != nil {
+		log.Warning("CLI", "Expiry check failed: "+err.Error())
+	} else {
+		for _, w := range warnings {
+			log.Warning("CLI", "Expiring: "+w.String())
+		}
+		if _, err := ext.NotifyExpiry(ctx, warnings); err != nil {
+			log.Warning("CLI", "Expiry webhook failed: "+err.Error())
+		}
+	}
+	if opts.dryRun {
+		delta, err := ext.EnforcementDelta(data)
+		if err != nil {
+			log.Error("CLI", "Computing enforcement delta failed: "+err.Error())
+			os.Exit(1)
+		}
+		printEnforcementDelta(delta, os.Stdout)
+		if critical := delta.CriticalCollateral(); len(critical) > 0 {
+			log.Warning("CLI", fmt.Sprintf("Publishing would be refused: %d critical collateral matches", len(critical)))
+			os.Exit(1)
+		}
+		return
+	}
+	// --- Honeypot hits ---
+	if opts.hitsFile != "" {
+		added, err := ext.ImportHitsFile(opts.hitsFile)
+		if err != nil {
+			log.Error("CLI", "Importing honeypot hits failed: "+err.Error())
+			os.Exit(1)
+		}
+		log.Info("CLI", fmt.Sprintf("Imported %d new honeypot hits", added))
+	}
+	if err := ext.ApplyHits(data); err != nil {
+		log.Warning("CLI", "Honeypot hits not applied: "+err.Error())
+	}
+	if opts.seenAttacking {
+		data = extractor.SeenAttacking(data)
+		log.Info("CLI", fmt.Sprintf("%d records seen attacking your honeypots", len(data)))
+	}
+	if opts.ownershipChanged {
+		data = extractor.OwnershipChanged(data)
+		log.Info("CLI", fmt.Sprintf("%d records whose RDAP owner changed", len(data)))
+	}
+	if opts.minConfidence != "" {
+		data = extractor.AtLeastConfidence(data, opts.minConfidence)
+		log.Info("CLI", fmt.Sprintf("%d records attributed with %s confidence or higher", len(data), opts.minConfidence))
+	}
+	if window.Field != "" {
+		data = window.Filter(data, time.Now())
+		log.Info("CLI", fmt.Sprintf("%d records with %s in the last %s", len(data), window.Field, window.Within))
 	}
 
-	// --- Output ---
-	format := strings.ToLower(outputFormat)
-	if format != "csv" && format != "json" {
-		log.Error("CLI", "Unsupported format: "+outputFormat+". Use csv or json.")
+	if !opts.filter.Empty() {
+		data = opts.filter.Apply(data)
+		log.Info("CLI", fmt.Sprintf("%d records match the risk, scanner type and country filters", len(data)))
+	}
+
+	format := strings.ToLower(opts.outputFormat)
+	tmpl, isTemplate := extractor.LookupExportTemplate(format)
+	if format != "csv" && format != "json" && !isTemplate {
+		log.Error("CLI", "Unsupported format: "+opts.outputFormat+". Use csv, json or one of: "+strings.Join(extractor.ExportTemplateNames(), ", ")+".")
+		os.Exit(1)
+	}
+
+	// Firewall rules only ever block the enforceable records
+	if opts.blockedOnly || opts.requireApproval || tmpl.Enforcement {
+		data = extractor.Enforceable(data)
+		log.Info("CLI", fmt.Sprintf("%d blocked records selected for output", len(data)))
+	}
+
+	// The approval covers exactly the records written below
+	if opts.requireApproval {
+		delta, err := ext.EnforcementDelta(data)
+		if err != nil {
+			log.Error("CLI", "Computing enforcement delta failed: "+err.Error())
+			os.Exit(1)
+		}
+		if critical := delta.CriticalCollateral(); len(critical) > 0 {
+			printEnforcementDelta(delta, os.Stderr)
+			log.Error("CLI", fmt.Sprintf("Enforcement export refused: %d entries overlap critical protected prefixes", len(critical)))
+			os.Exit(1)
+		}
+		if !confirmApproval(delta, os.Stdin, os.Stderr) {
+			log.Warning("CLI", "Enforcement export not approved; nothing written")
+			os.Exit(1)
+		}
+		if _, err := ext.ApproveEnforcement(data, opts.approver); err != nil {
+			log.Error("CLI", "Recording approval failed: "+err.Error())
+			os.Exit(1)
+		}
+	}
+
+	if opts.anonymize {
+		data = extractor.Anonymize(data)
+		log.Info("CLI", "Output anonymized: emails reduced to their domain, host names truncated, notes and contacts removed")
+	}
+
+	redacted, err := ext.RedactForExport(data, opts.exportPreset)
+	if err != nil {
+		log.Error("CLI", err.Error())
 		os.Exit(1)
 	}
+	data = redacted
 
-	if outputFile != "" {
-		if format == "json" {
-			if err := ext.SaveToJSON(data, outputFile); err != nil {
+	// --- Output ---
+	if opts.outputFile != "" {
+		if isTemplate {
+			if err := ext.ExportWithTemplate(data, opts.outputFile, tmpl.Name); err != nil {
+				log.Error("CLI", "Failed to write "+tmpl.Name+" export: "+err.Error())
+				os.Exit(1)
+			}
+		} else if format == "json" {
+			if err := ext.SaveToJSON(data, opts.outputFile); err != nil {
 				log.Error("CLI", "Failed to write JSON: "+err.Error())
 				os.Exit(1)
 			}
 		} else {
-			if err := ext.SaveToCSV(data, outputFile); err != nil {
+			if err := ext.SaveToCSV(data, opts.outputFile); err != nil {
 				log.Error("CLI", "Failed to write CSV: "+err.Error())
 				os.Exit(1)
 			}
 		}
-		log.Info("CLI", "Results written to "+outputFile)
+		log.Info("CLI", "Results written to "+opts.outputFile)
+		// Traces the published list back to the upstream commit it was built from
+		if err := ext.SaveRunMetadata(opts.outputFile, data); err != nil {
+			log.Warning("CLI", "Run metadata not written: "+err.Error())
+		}
+		if rev, ok := ext.LastSourceRevision(); ok {
+			log.Info("CLI", fmt.Sprintf("Built from %s at commit %s", rev.RepoURL, rev.Commit))
+		}
 	} else {
 		// Write to stdout
-		if format == "json" {
+		if isTemplate {
+			if _, err := extractor.WriteTemplate(os.Stdout, data, tmpl); err != nil {
+				log.Error("CLI", err.Error())
+				os.Exit(1)
+			}
+		} else if format == "json" {
 			encoder := json.NewEncoder(os.Stdout)
 			encoder.SetIndent("", "  ")
 			if err := encoder.Encode(data); err != nil {
@@ -152,7 +568,252 @@ func runCLI(cfg *models.AppConfig, log *logger.Logger, outputFile, outputFormat
 		}
 	}
 
+	switch strings.ToLower(opts.exportDB) {
+	case "postgres":
+		script, err := ext.ExportPostgres(data)
+		if err != nil {
+			log.Error("CLI", "Database export failed: "+err.Error())
+			os.Exit(1)
+		}
+		log.Info("CLI", fmt.Sprintf("PostgreSQL upsert of %d records written to %s; load it with: %s", len(data), script, ext.PostgresCommand(script)))
+	case "clickhouse":
+		if err := ext.ExportClickHouse(ctx, data); err != nil {
+			log.Error("CLI", "Database export failed: "+err.Error())
+			os.Exit(1)
+		}
+		log.Info("CLI", fmt.Sprintf("%d records upserted into clickhouse", len(data)))
+	}
+
+	if opts.reportAbuse {
+		sum, err := ext.ReportToAbuseIPDB(ctx, data)
+		if err != nil {
+			log.Error("CLI", "AbuseIPDB reporting failed: "+err.Error())
+			os.Exit(1)
+		}
+		log.Info("CLI", fmt.Sprintf("AbuseIPDB: %d reported, %d skipped, %d failed, %d reports left today",
+			sum.Reported, sum.Skipped, sum.Failed, sum.QuotaLeft))
+		if sum.QuotaExhausted {
+			log.Warning("CLI", "AbuseIPDB daily quota reached; the remaining IPs will be reported by a later run")
+		}
+	}
+
+	stop()
 	log.Info("CLI", "CLI mode completed successfully")
+
+	if opts.serve {
+		if !cfg.Database.EnableAPI {
+			log.Error("CLI", "-serve requires enable_api in the configuration")
+			os.Exit(1)
+		}
+		srv := server.New(cfg, ext, log)
+		if err := ext.ApplyAnnotations(data); err != nil {
+			log.Warning("CLI", "Annotations not applied: "+err.Error())
+		}
+		if err := ext.ApplyLocks(data); err != nil {
+			log.Warning("CLI", "Record locks not applied: "+err.Error())
+		}
+		srv.SetRecords(data)
+		if err := srv.Start(); err != nil {
+			log.Error("CLI", err.Error())
+			os.Exit(1)
+		}
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt)
+		<-sig
+		_ = srv.Close()
+		log.Info("CLI", "API server stopped")
+	}
+}
+
+// runDiff runs "liacheckscanner diff [-format csv|json] [-output file]
+// [from to]": it writes the delta between the two latest runs saved in the
+// results directory, or between the runs from and to, and returns the exit
+// code.
+func runDiff(ext *extractor.Extractor, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "csv", "Output format: csv or json")
+	output := fs.String("output", "", "Output file path; defaults to stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: liacheckscanner diff [-format csv|json] [-output file] [from to]")
+		fmt.Fprintln(stderr, "Compares the latest run of the results directory with the previous one, or run from with run to.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 && fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	var d extractor.RunDiff
+	var err error
+	if fs.NArg() == 2 {
+		d, err = ext.DiffRunFiles(fs.Arg(0), fs.Arg(1))
+	} else {
+		d, err = ext.DiffRuns()
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "Diff failed: "+err.Error())
+		return 1
+	}
+
+	out := stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(stderr, "Diff failed: "+err.Error())
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := extractor.WriteRunDiff(out, d, *format); err != nil {
+		fmt.Fprintln(stderr, "Diff failed: "+err.Error())
+		return 1
+	}
+	fmt.Fprintf(stderr, "%s -> %s: %d added, %d removed, %d changed\n", d.From, d.To, len(d.Added), len(d.Removed), len(d.Changed))
+	return 0
+}
+
+// remoteDatasetFile is the local copy of the dataset pulled with -remote,
+// in the results directory, merged with each delta.
+const remoteDatasetFile = "remote_dataset.json"
+
+// pullRemoteDataset syncs the local copy of the remote dataset and returns
+// it, exiting on failure.
+func pullRemoteDataset(ctx context.Context, ext *extractor.Extractor, log *logger.Logger) []models.ScannerData {
+	local, err := ext.LoadFromJSON(remoteDatasetFile)
+	if err != nil {
+		local = nil // first pull: no local copy yet
+	}
+	data, received, err := ext.SyncRemote(ctx, local)
+	if err != nil {
+		log.Error("CLI", "Remote sync failed: "+err.Error())
+		os.Exit(1)
+	}
+	if err := ext.SaveToJSON(data, remoteDatasetFile); err != nil {
+		log.Error("CLI", "Saving the remote dataset failed: "+err.Error())
+		os.Exit(1)
+	}
+	log.Info("CLI", fmt.Sprintf("Pulled %d updated records from the remote API, %d in total", received, len(data)))
+	return data
+}
+
+// prewarmRDAP looks up the prefixes of source, a prefix list file or "feed"
+// for the prefixes aggregated from ips, once in RDAP. A failed pre-warm is
+// only a warning: enrichment then looks the IPs up one by one.
+func prewarmRDAP(ctx context.Context, ext *extractor.Extractor, log *logger.Logger, source string, ips []string) {
+	var prefixes []string
+	if source == "feed" {
+		prefixes = extractor.PrewarmPrefixes(ips)
+	} else {
+		var err error
+		if prefixes, err = extractor.ReadPrefixList(source); err != nil {
+			log.Error("CLI", err.Error())
+			os.Exit(1)
+		}
+	}
+	res, err := ext.PrewarmRDAP(ctx, prefixes)
+	if err != nil {
+		log.Warning("CLI", "RDAP pre-warm incomplete: "+err.Error())
+	}
+	log.Info("CLI", fmt.Sprintf("RDAP pre-warm: %d prefixes, %d looked up, %d already cached, %d failed",
+		res.Prefixes, res.Fetched, res.Cached, res.Failed))
+}
+
+// confirmApproval prints the enforcement delta to out and reads a yes/no
+// answer from in. Only "y" or "yes" approves.
+func confirmApproval(delta extractor.EnforcementDelta, in io.Reader, out io.Writer) bool {
+	printEnforcementDelta(delta, out)
+	fmt.Fprint(out, "Approve publication? [y/N] ")
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// printEnforcementDelta writes the changes, collateral matches and policy
+// violations of delta to out.
+func printEnforcementDelta(delta extractor.EnforcementDelta, out io.Writer) {
+	fmt.Fprintf(out, "Enforcement export: %d IPs (+%d / -%d)\n", delta.Total, len(delta.Added), len(delta.Removed))
+	for _, ip := range delta.Added {
+		fmt.Fprintln(out, "  + "+ip)
+	}
+	for _, ip := range delta.Removed {
+		fmt.Fprintln(out, "  - "+ip)
+	}
+	for _, c := range delta.OwnershipChanged {
+		fmt.Fprintf(out, "  ~ %s owner changed: %s -> %s\n", c.IP, c.Previous, c.Current)
+	}
+	for _, m := range delta.Collateral {
+		mark := "!"
+		if m.Critical {
+			mark = "!!"
+		}
+		fmt.Fprintln(out, strings.TrimSpace(fmt.Sprintf("  %s %s overlaps %s prefix %s %s", mark, m.IP, m.Kind, m.Prefix, m.Label)))
+	}
+	for _, v := range delta.PolicyHeld {
+		fmt.Fprintf(out, "  x %s held back by policy: %s\n", v.IP, v.Reason)
+	}
+}
+
+// printIPv6Audit writes the IPv6 audit report to out, a count per kind then
+// one line per issue, and reports whether no issue was found.
+func printIPv6Audit(audit extractor.IPv6Audit, out io.Writer) bool {
+	fmt.Fprintf(out, "IPv6 audit: %d feed files, %d lines, %d records, %d issues\n",
+		audit.Files, audit.Lines, audit.Records, len(audit.Issues))
+	counts := audit.Counts()
+	kinds := make([]string, 0, len(counts))
+	for k := range counts {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	for _, k := range kinds {
+		fmt.Fprintf(out, "  %-18s %d\n", k, counts[k])
+	}
+	for _, i := range audit.Issues {
+		fmt.Fprintf(out, "[%s] %s %s: %s\n", i.Kind, i.Source, i.Value, i.Detail)
+	}
+	return len(audit.Issues) == 0
+}
+
+// printSelfTest writes the self-test checklist to out and reports whether
+// every check passed.
+func printSelfTest(results []extractor.SelfTestResult, out io.Writer) bool {
+	for _, r := range results {
+		mark := "PASS"
+		if !r.OK {
+			mark = "FAIL"
+		}
+		fmt.Fprintf(out, "[%s] %-16s %s\n", mark, r.Name, r.Detail)
+	}
+	ok := extractor.SelfTestPassed(results)
+	if ok {
+		fmt.Fprintln(out, "Self-test passed")
+	} else {
+		fmt.Fprintln(out, "Self-test failed")
+	}
+	return ok
+}
+
+// runVerify checks the result file at path against its recorded hash and
+// prints the outcome. It returns the exit code: 0 when the file is intact,
+// 1 when it was modified or has no recorded hash.
+func runVerify(path string, out io.Writer) int {
+	check, err := extractor.VerifyDataset(path)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return 1
+	}
+	fmt.Fprintln(out, check)
+	if !check.Signed || !check.Intact {
+		return 1
+	}
+	return 0
 }
 
 // writeCSVToStdout writes scanner data as CSV to standard output.
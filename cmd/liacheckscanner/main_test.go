@@ -1,14 +1,19 @@
 package main
 
 import (
+	"bytes"
 	"encoding/csv"
+	"encoding/json"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
-	"github.com/lia/liacheckscanner_go/internal/models"
+	"github.com/lia/liacheckscanner_go/pkg/events"
+	"github.com/lia/liacheckscanner_go/pkg/extractor"
+	"github.com/lia/liacheckscanner_go/pkg/models"
 )
 
 // -------------------------------------------------------
@@ -162,3 +167,192 @@ func TestWriteCSVToStdout_WithData(t *testing.T) {
 		t.Errorf("Row 2 IP: want %q, got %q", "5.6.7.8", records[2][1])
 	}
 }
+
+// -------------------------------------------------------
+// confirmApproval
+// -------------------------------------------------------
+
+func TestConfirmApproval(t *testing.T) {
+	delta := extractor.EnforcementDelta{Added: []string{"192.0.2.1"}, Removed: []string{"192.0.2.9"}, Total: 1}
+	cases := map[string]bool{"y\n": true, "YES\n": true, "n\n": false, "\n": false, "": false}
+	for input, want := range cases {
+		var out bytes.Buffer
+		if got := confirmApproval(delta, strings.NewReader(input), &out); got != want {
+			t.Errorf("confirmApproval(%q) = %v, want %v", input, got, want)
+		}
+		if !strings.Contains(out.String(), "+ 192.0.2.1") || !strings.Contains(out.String(), "- 192.0.2.9") {
+			t.Errorf("delta not shown: %q", out.String())
+		}
+	}
+}
+
+func TestPrintEnforcementDelta_ShowsCollateral(t *testing.T) {
+	delta := extractor.EnforcementDelta{Total: 2, Collateral: []extractor.CollateralMatch{
+		{IP: "192.0.2.1", Prefix: "192.0.2.0/24", Kind: "own", Label: "Office", Critical: true},
+		{IP: "8.8.8.8", Prefix: "8.8.8.8/32", Kind: "service"},
+	}}
+	var out bytes.Buffer
+	printEnforcementDelta(delta, &out)
+	for _, want := range []string{"!! 192.0.2.1 overlaps own prefix 192.0.2.0/24 Office", "! 8.8.8.8 overlaps service prefix 8.8.8.8/32\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output %q does not contain %q", out.String(), want)
+		}
+	}
+}
+
+func TestPrintEnforcementDelta_ShowsPolicyHeld(t *testing.T) {
+	delta := extractor.EnforcementDelta{Total: 1, PolicyHeld: []extractor.PolicyViolation{
+		{IP: "192.0.2.2", Scanner: "Shodan", Reason: "country FR excluded"},
+	}}
+	var out bytes.Buffer
+	printEnforcementDelta(delta, &out)
+	if want := "  x 192.0.2.2 held back by policy: country FR excluded\n"; !strings.Contains(out.String(), want) {
+		t.Errorf("output %q does not contain %q", out.String(), want)
+	}
+}
+
+func TestPrintIPv6Audit(t *testing.T) {
+	var out strings.Builder
+	ok := printIPv6Audit(extractor.IPv6Audit{Files: 1, Lines: 3, Issues: []extractor.IPv6Issue{
+		{Kind: extractor.IPv6IssueZoneID, Value: "fe80::1%eth0", Source: "x.nft:2", Detail: "zone ID dropped, extracted as fe80::1"},
+	}}, &out)
+	if ok {
+		t.Error("printIPv6Audit should report the issue")
+	}
+	for _, want := range []string{"1 feed files, 3 lines, 0 records, 1 issues", "zone-id", "[zone-id] x.nft:2 fe80::1%eth0"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+	if !printIPv6Audit(extractor.IPv6Audit{}, &out) {
+		t.Error("printIPv6Audit without issues should pass")
+	}
+}
+
+func TestPrintSelfTest(t *testing.T) {
+	var out strings.Builder
+	ok := printSelfTest([]extractor.SelfTestResult{
+		{Name: "git", OK: true, Detail: "/usr/bin/git"},
+		{Name: "rdap arin", Detail: "HTTP 503"},
+	}, &out)
+	if ok {
+		t.Error("printSelfTest should report the failure")
+	}
+	got := out.String()
+	for _, want := range []string{"[PASS] git", "[FAIL] rdap arin", "HTTP 503", "Self-test failed"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestRunVerify(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "list.csv")
+	if err := os.WriteFile(path, []byte("IP/CIDR\n192.0.2.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if code := runVerify(path, &out); code != 1 || !strings.Contains(out.String(), "no recorded hash") {
+		t.Errorf("runVerify without hash = %d, %q", code, out.String())
+	}
+
+	integrity, err := extractor.HashDataset(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := extractor.WriteRunMetadata(path, models.RunMetadata{Name: "list.csv", Integrity: &integrity}); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if code := runVerify(path, &out); code != 0 || !strings.Contains(out.String(), "intact") {
+		t.Errorf("runVerify = %d, %q; want intact", code, out.String())
+	}
+
+	if err := os.WriteFile(path, []byte("IP/CIDR\n192.0.2.9\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if code := runVerify(path, &out); code != 1 || !strings.Contains(out.String(), "modified outside") {
+		t.Errorf("runVerify after an edit = %d, %q", code, out.String())
+	}
+}
+
+func TestRunDiff(t *testing.T) {
+	dir := t.TempDir()
+	ext := extractor.NewExtractor(models.DatabaseConfig{ResultsDir: dir}, nil)
+	var stdout, stderr bytes.Buffer
+	if code := runDiff(ext, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("runDiff without runs = %d, want 1", code)
+	}
+
+	_ = ext.SaveToCSV([]models.ScannerData{{IPOrCIDR: "192.0.2.1", ScannerName: "shodan"}}, "2024-01-01_00-00-00_liacheckscanner.csv")
+	_ = ext.SaveToCSV([]models.ScannerData{{IPOrCIDR: "192.0.2.2", ScannerName: "shodan"}}, "2024-01-02_00-00-00_liacheckscanner.csv")
+	stdout.Reset()
+	if code := runDiff(ext, []string{"-format", "json"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("runDiff = %d: %s", code, stderr.String())
+	}
+	var d extractor.RunDiff
+	if err := json.Unmarshal(stdout.Bytes(), &d); err != nil || len(d.Added) != 1 || len(d.Removed) != 1 {
+		t.Errorf("runDiff JSON = %s (%v), want 1 added and 1 removed", stdout.String(), err)
+	}
+
+	out := filepath.Join(dir, "delta.csv")
+	args := []string{"-output", out, "2024-01-02_00-00-00_liacheckscanner.csv", "2024-01-01_00-00-00_liacheckscanner.csv"}
+	if code := runDiff(ext, args, &stdout, &stderr); code != 0 {
+		t.Fatalf("runDiff = %d: %s", code, stderr.String())
+	}
+	b, _ := os.ReadFile(out)
+	if !strings.Contains(string(b), "added,192.0.2.1,shodan") {
+		t.Errorf("delta.csv = %s, want 192.0.2.1 added going back to the first run", b)
+	}
+	if code := runDiff(ext, []string{"only-one"}, &stdout, &stderr); code != 2 {
+		t.Errorf("runDiff with one run name = %d, want 2", code)
+	}
+}
+
+// -------------------------------------------------------
+// progressReporter
+// -------------------------------------------------------
+
+func TestProgressReporter(t *testing.T) {
+	var buf bytes.Buffer
+	p := newProgressReporter(&buf, progressText, 10*time.Second)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	p.now = func() time.Time { return now }
+
+	p.HandleEvent(events.Event{Type: events.RecordsParsed, Count: 500})
+	p.HandleEvent(events.Event{Type: events.RecordsEnriched, Count: 0, Total: 500})
+	now = start.Add(5 * time.Second)
+	p.HandleEvent(events.Event{Type: events.RecordsEnriched, Count: 50, Total: 500}) // within the interval
+	now = start.Add(10 * time.Second)
+	p.HandleEvent(events.Event{Type: events.RecordsEnriched, Count: 100, Total: 500})
+	now = start.Add(11 * time.Second)
+	p.HandleEvent(events.Event{Type: events.RecordsEnriched, Count: 500, Total: 500}) // the total is always reported
+	p.HandleEvent(events.Event{Type: events.RunFailed, Message: "boom"})
+
+	want := []string{
+		"stage=parse processed=500 total=500",
+		"stage=enrich processed=0 total=500 rate=0.0/s eta=unknown",
+		"stage=enrich processed=100 total=500 rate=10.0/s eta=40s",
+		"stage=enrich processed=500 total=500 rate=45.5/s eta=0s",
+		`stage=run processed=0 total=0 status=failed message="boom"`,
+	}
+	if got := strings.Split(strings.TrimSpace(buf.String()), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("progress lines:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	buf.Reset()
+	p = newProgressReporter(&buf, progressJSON, time.Minute)
+	p.now = func() time.Time { return start }
+	p.HandleEvent(events.Event{Type: events.SourceProgress, Count: 3, Total: 4})
+	p.HandleEvent(events.Event{Type: events.Warning})
+	var line progressLine
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("JSON progress line %q: %v", buf.String(), err)
+	}
+	if line.Stage != "sync" || line.Processed != 3 || line.Total != 4 {
+		t.Errorf("JSON progress line = %+v", line)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/events"
+)
+
+// Progress line formats of -progress.
+const (
+	progressText = "text"
+	progressJSON = "json"
+	progressNone = "none"
+)
+
+// progressLine is one progress report of a headless run, written as JSON
+// with -progress json.
+type progressLine struct {
+	Time      time.Time `json:"time"`
+	Stage     string    `json:"stage"`
+	Processed int       `json:"processed"`
+	Total     int       `json:"total"`
+	Rate      float64   `json:"rate"`                  // items per second since the stage began
+	ETA       float64   `json:"eta_seconds,omitempty"` // unknown while the rate is zero
+	Status    string    `json:"status,omitempty"`      // "done" or "failed" for the final line of a run
+	Message   string    `json:"message,omitempty"`
+}
+
+// progressReporter turns the extractor's progress events into periodic,
+// machine-parsable lines for CI jobs and cron, where no progress bar is
+// drawn. Lines of a stage are at least interval apart, except the first
+// and the one reaching the total.
+type progressReporter struct {
+	w        io.Writer
+	format   string
+	interval time.Duration
+	now      func() time.Time
+
+	mu         sync.Mutex
+	stage      string
+	startAt    time.Time // first event of the stage
+	startCount int       // its count, so rates ignore work done before
+	lastCount  int
+	lastLine   time.Time
+}
+
+// newProgressReporter returns a reporter writing lines in format
+// (progressText or progressJSON) to w.
+func newProgressReporter(w io.Writer, format string, interval time.Duration) *progressReporter {
+	return &progressReporter{w: w, format: format, interval: interval, now: time.Now}
+}
+
+// progressStages maps the events carrying a count to the stage they report.
+var progressStages = map[events.Type]string{
+	events.SourceProgress:  "sync",
+	events.RecordsEnriched: "enrich",
+}
+
+// HandleEvent reports ev; it is meant to be subscribed to the extractor's
+// event bus.
+func (p *progressReporter) HandleEvent(ev events.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	switch ev.Type {
+	case events.RecordsParsed:
+		p.write(progressLine{Time: now, Stage: "parse", Processed: ev.Count, Total: ev.Count})
+	case events.RunCompleted:
+		p.write(progressLine{Time: now, Stage: "run", Processed: ev.Count, Total: ev.Count, Status: "done"})
+	case events.RunFailed:
+		p.write(progressLine{Time: now, Stage: "run", Status: "failed", Message: ev.Message})
+	default:
+		stage, ok := progressStages[ev.Type]
+		if !ok || ev.Total <= 0 {
+			return
+		}
+		// A new stage, or a count going back, starts a new measure
+		first := stage != p.stage || ev.Count < p.lastCount
+		if first {
+			p.stage, p.startAt, p.startCount = stage, now, ev.Count
+		}
+		p.lastCount = ev.Count
+		if !first && ev.Count < ev.Total && now.Sub(p.lastLine) < p.interval {
+			return
+		}
+		line := progressLine{Time: now, Stage: stage, Processed: ev.Count, Total: ev.Total}
+		if elapsed := now.Sub(p.startAt).Seconds(); elapsed > 0 {
+			line.Rate = float64(ev.Count-p.startCount) / elapsed
+		}
+		if line.Rate > 0 {
+			line.ETA = float64(ev.Total-ev.Count) / line.Rate
+		}
+		p.write(line)
+	}
+}
+
+// write prints line and remembers when.
+func (p *progressReporter) write(line progressLine) {
+	p.lastLine = line.Time
+	if p.format == progressJSON {
+		b, _ := json.Marshal(line)
+		fmt.Fprintf(p.w, "%s\n", b)
+		return
+	}
+	fmt.Fprintln(p.w, line.String())
+}
+
+// String formats l as key=value pairs, e.g.
+// "stage=enrich processed=1234 total=5000 rate=12.0/s eta=5m14s".
+func (l progressLine) String() string {
+	s := fmt.Sprintf("stage=%s processed=%d total=%d", l.Stage, l.Processed, l.Total)
+	if l.Status != "" {
+		s += " status=" + l.Status
+		if l.Message != "" {
+			s += fmt.Sprintf(" message=%q", l.Message)
+		}
+		return s
+	}
+	if l.Stage == "parse" {
+		return s
+	}
+	s += fmt.Sprintf(" rate=%.1f/s", l.Rate)
+	switch {
+	case l.Processed >= l.Total:
+		s += " eta=0s"
+	case l.Rate > 0:
+		s += " eta=" + (time.Duration(l.ETA) * time.Second).String()
+	default:
+		s += " eta=unknown"
+	}
+	return s
+}
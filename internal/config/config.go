@@ -3,11 +3,17 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
+	"unicode"
 
-	"github.com/lia/liacheckscanner_go/internal/models"
+	"github.com/lia/liacheckscanner_go/pkg/models"
 )
 
 // ConfigManager manages loading, saving, and accessing the application configuration.
@@ -38,6 +44,8 @@ func LoadConfig() (*models.AppConfig, error) {
 }
 
 // Load reads and parses the configuration file, creating it with defaults if it does not exist.
+// If the file parses but fails validation, the configuration is returned along
+// with an error wrapping a *ValidationError.
 func (cm *ConfigManager) Load() (*models.AppConfig, error) {
 	// Configuration par défaut
 	defaultConfig := &models.AppConfig{
@@ -61,6 +69,20 @@ func (cm *ConfigManager) Load() (*models.AppConfig, error) {
 			AutoUpdate:     false,
 			UpdateInterval: 24,  // heures
 			CacheTTLHours:  168, // 7 days
+			GeoProvider:    "ip-api",
+
+			RDAPRegistryConcurrency: 4,
+
+			CandidateAfterRuns: 2,
+			BlockAfterRuns:     3,
+			RetireAfterRuns:    3,
+
+			RiskHalfLifeDays: 30,
+			StaleAfterDays:   90,
+
+			APIListen: "127.0.0.1:8088",
+
+			CollateralCriticalKinds: []string{"own", "partner"},
 		},
 	}
 
@@ -86,7 +108,10 @@ func (cm *ConfigManager) Load() (*models.AppConfig, error) {
 	}
 
 	if err := Validate(&config); err != nil {
-		return nil, fmt.Errorf("config validation: %w", err)
+		// Return the parsed configuration too, so the GUI can start and
+		// show the problems instead of refusing to open.
+		cm.config = &config
+		return &config, fmt.Errorf("config validation: %w", err)
 	}
 
 	cm.config = &config
@@ -177,44 +202,464 @@ func (cm *ConfigManager) DisableAPI() error {
 	return cm.Save(cm.config)
 }
 
-// Validate checks that the given AppConfig has valid values.
-// It returns an error describing the first validation failure, or nil if valid.
+// maxParallelism bounds Database.Parallelism; RDAP registries throttle
+// aggressive clients well before this.
+const maxParallelism = 64
+
+// knownRegistries are the accepted Database.Registries values.
+var knownRegistries = map[string]bool{"arin": true, "ripe": true, "apnic": true, "lacnic": true, "afrinic": true}
+
+// sqlIdentifier matches the table names accepted for SQL exports.
+var sqlIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// policyASN and policyCountry match the entries of the enforcement policy
+// lists.
+var (
+	policyASN     = regexp.MustCompile(`^(?i:AS)?[0-9]+$`)
+	policyCountry = regexp.MustCompile(`^[A-Za-z]{2}$`)
+)
+
+// ValidationError lists every problem found by Validate.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return e.Problems[0]
+	}
+	return fmt.Sprintf("%d configuration problems: %s", len(e.Problems), strings.Join(e.Problems, "; "))
+}
+
+// Validate checks that cfg contains sensible values. It reports every
+// problem at once as a *ValidationError rather than stopping at the first.
 func Validate(cfg *models.AppConfig) error {
 	if cfg == nil {
 		return fmt.Errorf("config is nil")
 	}
 
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
 	if strings.TrimSpace(cfg.AppName) == "" {
-		return fmt.Errorf("AppName must not be empty")
+		add("AppName must not be empty")
 	}
 
 	if strings.TrimSpace(cfg.Version) == "" {
-		return fmt.Errorf("Version must not be empty")
+		add("Version must not be empty")
 	}
 
 	switch strings.ToUpper(cfg.LogLevel) {
 	case "DEBUG", "INFO", "WARNING", "ERROR", "CRITICAL":
 		// valid
 	default:
-		return fmt.Errorf("LogLevel must be one of DEBUG, INFO, WARNING, ERROR; got %q", cfg.LogLevel)
+		add("LogLevel must be one of DEBUG, INFO, WARNING, ERROR; got %q", cfg.LogLevel)
 	}
 
 	if cfg.MaxLogSize <= 0 {
-		return fmt.Errorf("MaxLogSize must be > 0; got %d", cfg.MaxLogSize)
+		add("MaxLogSize must be > 0; got %d", cfg.MaxLogSize)
 	}
 
 	if cfg.LogBackups < 0 {
-		return fmt.Errorf("LogBackups must be >= 0; got %d", cfg.LogBackups)
+		add("LogBackups must be >= 0; got %d", cfg.LogBackups)
 	}
 
-	repoURL := strings.TrimSpace(cfg.Database.RepoURL)
-	if repoURL == "" || (!strings.HasPrefix(repoURL, "http://") && !strings.HasPrefix(repoURL, "https://")) {
-		return fmt.Errorf("Database.RepoURL must be a valid URL starting with http:// or https://; got %q", cfg.Database.RepoURL)
+	columns := make([]string, 0, len(cfg.ColumnWidths))
+	for name := range cfg.ColumnWidths {
+		columns = append(columns, name)
+	}
+	sort.Strings(columns)
+	for _, name := range columns {
+		if w := cfg.ColumnWidths[name]; w <= 0 {
+			add("ColumnWidths[%q] must be > 0; got %g", name, w)
+		}
+	}
+
+	if cfg.RowHeight < 0 {
+		add("RowHeight must be >= 0; got %g", cfg.RowHeight)
+	}
+
+	if cfg.TextScale != 0 && (cfg.TextScale < 0.5 || cfg.TextScale > 3) {
+		add("TextScale must be 0 or between 0.5 and 3; got %g", cfg.TextScale)
+	}
+
+	if err := checkSourceURL(cfg.Database.RepoURL); err != nil {
+		add("Database.RepoURL must be a valid URL starting with http:// or https://; got %q", cfg.Database.RepoURL)
 	}
 
 	if cfg.Database.APIThrottle < 0 {
-		return fmt.Errorf("Database.APIThrottle must be >= 0; got %f", cfg.Database.APIThrottle)
+		add("Database.APIThrottle must be >= 0; got %f", cfg.Database.APIThrottle)
+	}
+
+	if cfg.Database.Parallelism < 0 || cfg.Database.Parallelism > maxParallelism {
+		add("Database.Parallelism must be between 0 and %d; got %d", maxParallelism, cfg.Database.Parallelism)
+	}
+
+	if f := cfg.Database.ProtectedPrefixesFile; f != "" {
+		if _, err := os.Stat(f); err != nil {
+			add("Database.ProtectedPrefixesFile %q cannot be read: %v", f, err)
+		}
+	}
+	for _, k := range cfg.Database.CollateralCriticalKinds {
+		switch k {
+		case "own", "partner", "service":
+		default:
+			add("Database.CollateralCriticalKinds: unknown kind %q (want own, partner or service)", k)
+		}
+	}
+
+	for i, r := range cfg.Database.TagRules {
+		hasTag := false
+		for _, t := range r.Tags {
+			hasTag = hasTag || strings.TrimSpace(t) != ""
+		}
+		if !hasTag {
+			add("Database.TagRules[%d] (%s) adds no tags", i, r.Name)
+		}
+		if len(r.Countries) == 0 && len(r.ASNs) == 0 && r.OrgRegex == "" && len(r.ScannerTypes) == 0 {
+			add("Database.TagRules[%d] (%s) needs at least one condition", i, r.Name)
+		}
+		if r.OrgRegex != "" {
+			if _, err := regexp.Compile(r.OrgRegex); err != nil {
+				add("Database.TagRules[%d] (%s) has an invalid OrgRegex: %v", i, r.Name, err)
+			}
+		}
+	}
+
+	if ref := cfg.Database.RepoRef; ref != "" && (strings.HasPrefix(ref, "-") || strings.ContainsAny(ref, " \t\n")) {
+		add("Database.RepoRef must be a commit, tag or branch name without spaces or a leading '-'; got %q", ref)
+	}
+
+	for i, src := range cfg.Database.ArchiveSources {
+		name := src
+		if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+			u, err := url.Parse(src)
+			if err != nil || u.Host == "" {
+				add("Database.ArchiveSources[%d] is not a valid URL: %q", i, src)
+				continue
+			}
+			name = u.Path
+		}
+		if !isArchiveName(name) {
+			add("Database.ArchiveSources[%d] (%s) must be a .zip, .tar, .tar.gz or .tgz archive", i, src)
+		}
+	}
+
+	for i, p := range cfg.Database.RDNSPatterns {
+		if strings.TrimSpace(p.Scanner) == "" || strings.TrimSpace(p.Pattern) == "" {
+			add("Database.RDNSPatterns[%d] needs a scanner and a pattern", i)
+		} else if _, err := path.Match(p.Pattern, ""); err != nil {
+			add("Database.RDNSPatterns[%d] (%s) has an invalid pattern: %v", i, p.Pattern, err)
+		}
+	}
+
+	for i, r := range cfg.Database.FeedTrust {
+		if strings.TrimSpace(r.Source) == "" {
+			add("Database.FeedTrust[%d] needs a source", i)
+		} else if _, err := path.Match(r.Source, ""); err != nil {
+			add("Database.FeedTrust[%d] (%s) has an invalid pattern: %v", i, r.Source, err)
+		}
+		if r.Level < 0 || r.Level > 100 {
+			add("Database.FeedTrust[%d] (%s) level must be between 0 and 100; got %d", i, r.Source, r.Level)
+		}
+	}
+
+	policy := cfg.Database.EnforcementPolicy
+	for _, l := range []struct {
+		name   string
+		values []string
+		valid  *regexp.Regexp
+		want   string
+	}{
+		{"ExcludeASNs", policy.ExcludeASNs, policyASN, "an ASN such as AS64500"},
+		{"OnlyASNs", policy.OnlyASNs, policyASN, "an ASN such as AS64500"},
+		{"ExcludeCountries", policy.ExcludeCountries, policyCountry, "a two-letter country code such as FR"},
+		{"OnlyCountries", policy.OnlyCountries, policyCountry, "a two-letter country code such as FR"},
+	} {
+		for _, v := range l.values {
+			if !l.valid.MatchString(strings.TrimSpace(v)) {
+				add("Database.EnforcementPolicy.%s: %q is not %s", l.name, v, l.want)
+			}
+		}
+	}
+
+	presets := map[string]bool{}
+	for i, p := range cfg.Database.ExportPresets {
+		if strings.TrimSpace(p.Name) == "" {
+			add("Database.ExportPresets[%d] needs a name", i)
+		} else if presets[p.Name] {
+			add("Database.ExportPresets[%d]: duplicate preset %s", i, p.Name)
+		}
+		presets[p.Name] = true
+		for j, r := range p.Redact {
+			if err := r.Validate(); err != nil {
+				add("Database.ExportPresets[%d] (%s) rule %d: %v", i, p.Name, j, err)
+			}
+		}
+	}
+	if d := cfg.Database.DefaultExportPreset; d != "" && !presets[d] {
+		add("Database.DefaultExportPreset: unknown preset %q", d)
+	}
+
+	switch strings.ToLower(cfg.Database.ConflictPolicy) {
+	case "", models.ConflictPolicyTrust, models.ConflictPolicyMajority:
+	default:
+		add("Database.ConflictPolicy: unknown policy %q (want trust or majority)", cfg.Database.ConflictPolicy)
+	}
+
+	switch strings.ToLower(cfg.Database.MinEnforcementConfidence) {
+	case "", "low", "medium", "high":
+	default:
+		add("Database.MinEnforcementConfidence: unknown confidence %q (want low, medium or high)", cfg.Database.MinEnforcementConfidence)
+	}
+
+	for i, l := range cfg.Database.PivotLinks {
+		if strings.TrimSpace(l.Name) == "" {
+			add("Database.PivotLinks[%d] needs a name", i)
+		}
+		if l.URL == "" || strings.HasPrefix(l.URL, "{registry_url}") {
+			continue
+		}
+		if u, err := url.Parse(l.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			add("Database.PivotLinks[%d] (%s) URL must be an http or https URL; got %q", i, l.Name, l.URL)
+		}
+	}
+
+	scanners := make([]string, 0, len(cfg.Database.OptOutURLs))
+	for name := range cfg.Database.OptOutURLs {
+		scanners = append(scanners, name)
+	}
+	sort.Strings(scanners)
+	for _, name := range scanners {
+		v := cfg.Database.OptOutURLs[name]
+		if v == "" {
+			continue
+		}
+		if u, err := url.Parse(v); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			add("Database.OptOutURLs[%s] must be an http or https URL; got %q", name, v)
+		}
+	}
+
+	switch strings.ToLower(cfg.Database.BroadPrefixPolicy) {
+	case "", "flag", "exclude", "off":
+	default:
+		add("Database.BroadPrefixPolicy must be flag, exclude or off; got %q", cfg.Database.BroadPrefixPolicy)
+	}
+	if cfg.Database.BroadPrefixV4 < 0 || cfg.Database.BroadPrefixV4 > 32 {
+		add("Database.BroadPrefixV4 must be between 0 and 32; got %d", cfg.Database.BroadPrefixV4)
+	}
+	if cfg.Database.BroadPrefixV6 < 0 || cfg.Database.BroadPrefixV6 > 128 {
+		add("Database.BroadPrefixV6 must be between 0 and 128; got %d", cfg.Database.BroadPrefixV6)
+	}
+
+	if cfg.Database.MaxRDAPCalls < 0 || cfg.Database.MaxGeoCalls < 0 || cfg.Database.MaxRunMinutes < 0 {
+		add("Database.MaxRDAPCalls, MaxGeoCalls and MaxRunMinutes must be >= 0")
+	}
+	if cfg.Database.GeoBackfillPerHour < 0 || cfg.Database.GeoBackfillPerHour > 3600 {
+		add("Database.GeoBackfillPerHour must be between 0 and 3600")
+	}
+
+	if cfg.Database.RDAPRegistryConcurrency < 0 || cfg.Database.RDAPRegistryConcurrency > maxParallelism {
+		add("Database.RDAPRegistryConcurrency must be between 0 and %d; got %d", maxParallelism, cfg.Database.RDAPRegistryConcurrency)
+	}
+	if cfg.Database.RDAPBootstrapDays < 0 {
+		add("Database.RDAPBootstrapDays must be >= 0")
+	}
+	if m := cfg.Database.AutoscaleMinWorkers; m < 0 || (m > 0 && m > cfg.Database.Parallelism) {
+		add("Database.AutoscaleMinWorkers must be between 0 and Parallelism (%d); got %d", cfg.Database.Parallelism, m)
+	}
+
+	if cfg.Database.MaxRetries < 0 || cfg.Database.MaxRetries > 10 {
+		add("Database.MaxRetries must be between 0 and 10; got %d", cfg.Database.MaxRetries)
+	}
+
+	if cfg.Database.BatchSize < 0 {
+		add("Database.BatchSize must be >= 0; got %d", cfg.Database.BatchSize)
+	}
+
+	if cfg.Database.Preset != "" {
+		if _, ok := PresetByName(cfg.Database.Preset); !ok {
+			add("Database.Preset must be one of %v; got %q", PresetNames(), cfg.Database.Preset)
+		}
+	}
+
+	if cfg.Database.CacheTTLHours < 0 {
+		add("Database.CacheTTLHours must be >= 0 (0 uses the 168h default); got %d", cfg.Database.CacheTTLHours)
+	}
+
+	for _, r := range cfg.Database.Registries {
+		if !knownRegistries[strings.ToLower(r)] {
+			add("Database.Registries entries must be one of arin, ripe, apnic, lacnic, afrinic; got %q", r)
+		}
+	}
+
+	for _, d := range []struct{ name, path string }{
+		{"Database.ResultsDir", cfg.Database.ResultsDir},
+		{"Database.LogsDir", cfg.Database.LogsDir},
+		{"Database.LocalPath", cfg.Database.LocalPath},
+	} {
+		if err := CheckWritableDir(d.path); err != nil {
+			add("%s %q is not usable: %v", d.name, d.path, err)
+		}
+	}
+
+	providers := append([]string{cfg.Database.GeoProvider}, cfg.Database.GeoProviders...)
+	for _, p := range providers {
+		switch p {
+		case "", "ip-api", "ipinfo", "ipdata":
+			// valid
+		case "maxmind":
+			if strings.TrimSpace(cfg.Database.MaxMindDB) == "" {
+				add("Database.MaxMindDB must be set when the maxmind geolocation provider is used")
+			}
+		default:
+			add("Database.GeoProvider/GeoProviders entries must be one of maxmind, ip-api, ipinfo, ipdata; got %q", p)
+		}
+	}
+
+	if strings.ContainsFunc(cfg.Database.UserAgent+cfg.Database.Contact, unicode.IsControl) {
+		add("Database.UserAgent and Database.Contact must not contain control characters")
+	}
+
+	for _, s := range cfg.Database.DNSServers {
+		host := strings.TrimSpace(s)
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if net.ParseIP(strings.Trim(host, "[]")) == nil {
+			add("Database.DNSServers entries must be an IP address with an optional port; got %q", s)
+		}
+	}
+	if cfg.Database.DoHURL != "" {
+		if u, err := url.Parse(cfg.Database.DoHURL); err != nil || u.Scheme != "https" || u.Host == "" {
+			add("Database.DoHURL must be an https:// URL; got %q", cfg.Database.DoHURL)
+		}
+	}
+
+	for _, u := range []struct{ name, url string }{
+		{"Database.MetricsInfluxURL", cfg.Database.MetricsInfluxURL},
+		{"Database.MetricsPushgatewayURL", cfg.Database.MetricsPushgatewayURL},
+		{"Database.ClickHouseURL", cfg.Database.ClickHouseURL},
+		{"Database.RemoteAPIURL", cfg.Database.RemoteAPIURL},
+		{"Database.SharedCacheURL", cfg.Database.SharedCacheURL},
+		{"Database.ExpiryWebhookURL", cfg.Database.ExpiryWebhookURL},
+	} {
+		if u.url != "" && checkSourceURL(u.url) != nil {
+			add("%s must be a valid URL starting with http:// or https://; got %q", u.name, u.url)
+		}
+	}
+
+	if t := cfg.Database.SQLTable; t != "" && !sqlIdentifier.MatchString(t) {
+		add("Database.SQLTable must be a plain SQL identifier (letters, digits, underscores); got %q", t)
+	}
+
+	if cfg.Database.IPInfoThrottle < 0 || cfg.Database.IPDataThrottle < 0 {
+		add("Database.IPInfoThrottle and Database.IPDataThrottle must be >= 0")
+	}
+
+	keys := map[string]bool{}
+	for i, u := range cfg.Database.APIUsers {
+		if u.Name == "" || u.Key == "" {
+			add("Database.APIUsers[%d] needs a name and a key", i)
+		}
+		switch u.Role {
+		case models.RoleViewer, models.RoleAnalyst, models.RoleAdmin:
+		default:
+			add("Database.APIUsers[%d].Role must be one of viewer, analyst, admin; got %q", i, u.Role)
+		}
+		if u.Key != "" && (keys[u.Key] || u.Key == cfg.Database.APIKey) {
+			add("Database.APIUsers[%d] (%s) reuses another user's key", i, u.Name)
+		}
+		keys[u.Key] = true
+	}
+
+	if cfg.Database.CandidateAfterRuns < 0 || cfg.Database.BlockAfterRuns < 0 || cfg.Database.RetireAfterRuns < 0 {
+		add("Database.CandidateAfterRuns, BlockAfterRuns and RetireAfterRuns must be >= 0")
+	}
+	if cfg.Database.BlockAfterRuns > 0 && cfg.Database.BlockAfterRuns < cfg.Database.CandidateAfterRuns {
+		add("Database.BlockAfterRuns (%d) must be >= Database.CandidateAfterRuns (%d)", cfg.Database.BlockAfterRuns, cfg.Database.CandidateAfterRuns)
+	}
+
+	if cfg.Database.RiskHalfLifeDays < 0 || cfg.Database.StaleAfterDays < 0 {
+		add("Database.RiskHalfLifeDays and StaleAfterDays must be >= 0")
+	}
+	if cfg.Database.ExpiryWarnDays < 0 {
+		add("Database.ExpiryWarnDays must be >= 0")
+	}
+	for _, s := range cfg.Database.Watchlist {
+		if _, _, err := net.ParseCIDR(s); err != nil && net.ParseIP(s) == nil {
+			add("Database.Watchlist entries must be an IP or CIDR; got %q", s)
+		}
 	}
 
+	if cfg.Database.AbuseIPDBReport && strings.TrimSpace(cfg.Database.AbuseIPDBKey) == "" {
+		add("Database.AbuseIPDBKey must be set when Database.AbuseIPDBReport is enabled")
+	}
+	if cfg.Database.AbuseIPDBDailyQuota < 0 {
+		add("Database.AbuseIPDBDailyQuota must be >= 0 (0 uses the 1000 default); got %d", cfg.Database.AbuseIPDBDailyQuota)
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
 	return nil
 }
+
+// checkSourceURL reports whether raw is an absolute http(s) URL with a host.
+// isArchiveName reports whether name ends like an archive the extractor
+// reads: .zip, .tar, .tar.gz or .tgz.
+func isArchiveName(name string) bool {
+	n := strings.ToLower(name)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(n, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkSourceURL(raw string) error {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("not an http(s) URL")
+	}
+	return nil
+}
+
+// CheckWritableDir verifies that path is, or can be created as, a writable
+// directory. An empty path is accepted (the default is used).
+func CheckWritableDir(path string) error {
+	if strings.TrimSpace(path) == "" {
+		return nil
+	}
+	dir := filepath.Clean(path)
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", dir)
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent // not created yet: check the nearest existing parent
+	}
+	f, err := os.CreateTemp(dir, ".write-test-*")
+	if err != nil {
+		return fmt.Errorf("%s is not writable", dir)
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
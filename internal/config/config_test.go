@@ -2,12 +2,13 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
-	"github.com/lia/liacheckscanner_go/internal/models"
+	"github.com/lia/liacheckscanner_go/pkg/models"
 )
 
 // newTestConfigManager creates a ConfigManager that stores its config
@@ -539,6 +540,51 @@ func TestValidate_LogBackupsZero_IsValid(t *testing.T) {
 	}
 }
 
+func TestValidate_TextScale(t *testing.T) {
+	for _, tt := range []struct {
+		scale float32
+		ok    bool
+	}{{0, true}, {1.5, true}, {0.2, false}, {4, false}} {
+		cfg := &models.AppConfig{
+			AppName:    "TestApp",
+			Version:    "1.0.0",
+			LogLevel:   "INFO",
+			MaxLogSize: 10,
+			TextScale:  tt.scale,
+			Database: models.DatabaseConfig{
+				RepoURL: "https://example.com",
+			},
+		}
+		if err := Validate(cfg); (err == nil) != tt.ok {
+			t.Errorf("Validate(TextScale=%g) = %v, want ok=%v", tt.scale, err, tt.ok)
+		}
+	}
+}
+
+func TestValidate_TableLayoutPreferences(t *testing.T) {
+	cfg := &models.AppConfig{
+		AppName:      "TestApp",
+		Version:      "1.0.0",
+		LogLevel:     "INFO",
+		MaxLogSize:   10,
+		ColumnWidths: map[string]float32{"ISP": 180, "ASN": 0},
+		RowHeight:    -1,
+		Database: models.DatabaseConfig{
+			RepoURL: "https://example.com",
+		},
+	}
+	err := Validate(cfg)
+	if err == nil {
+		t.Fatal("Validate() should reject a zero column width and a negative row height")
+	}
+	if !strings.Contains(err.Error(), `ColumnWidths["ASN"]`) || !strings.Contains(err.Error(), "RowHeight") {
+		t.Errorf("error should mention the ASN width and RowHeight, got: %v", err)
+	}
+	if strings.Contains(err.Error(), "ISP") {
+		t.Errorf("a positive width should be accepted, got: %v", err)
+	}
+}
+
 func TestValidate_EmptyRepoURL(t *testing.T) {
 	cfg := &models.AppConfig{
 		AppName:    "TestApp",
@@ -630,6 +676,689 @@ func TestValidate_ZeroAPIThrottle_IsValid(t *testing.T) {
 	}
 }
 
+func TestValidate_UnknownGeoProvider(t *testing.T) {
+	cfg := &models.AppConfig{
+		AppName:    "TestApp",
+		Version:    "1.0.0",
+		LogLevel:   "INFO",
+		MaxLogSize: 10,
+		LogBackups: 0,
+		Database: models.DatabaseConfig{
+			RepoURL:     "https://example.com",
+			GeoProvider: "maxmind-typo",
+		},
+	}
+	err := Validate(cfg)
+	if err == nil {
+		t.Fatal("Validate() should reject an unknown GeoProvider")
+	}
+	if !strings.Contains(err.Error(), "GeoProvider") {
+		t.Errorf("error should mention GeoProvider, got: %v", err)
+	}
+}
+
+func TestValidate_GeoProviderChain(t *testing.T) {
+	cfg := &models.AppConfig{
+		AppName:    "TestApp",
+		Version:    "1.0.0",
+		LogLevel:   "INFO",
+		MaxLogSize: 10,
+		LogBackups: 0,
+		Database: models.DatabaseConfig{
+			RepoURL:      "https://example.com",
+			GeoProviders: []string{"maxmind", "ip-api", "ipinfo"},
+		},
+	}
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "MaxMindDB") {
+		t.Fatalf("Validate() should require MaxMindDB for the maxmind provider, got: %v", err)
+	}
+
+	cfg.Database.MaxMindDB = "/var/lib/GeoIP/GeoLite2-City.mmdb"
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("Validate() should accept a valid chain, got: %v", err)
+	}
+
+	cfg.Database.GeoProviders = append(cfg.Database.GeoProviders, "nope")
+	if err := Validate(cfg); err == nil {
+		t.Fatal("Validate() should reject an unknown provider in the chain")
+	}
+}
+
+func TestValidate_GreylistDwellTimes(t *testing.T) {
+	cfg := &models.AppConfig{
+		AppName:    "TestApp",
+		Version:    "1.0.0",
+		LogLevel:   "INFO",
+		MaxLogSize: 10,
+		LogBackups: 0,
+		Database: models.DatabaseConfig{
+			RepoURL:            "https://example.com",
+			CandidateAfterRuns: 3,
+			BlockAfterRuns:     2,
+		},
+	}
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "BlockAfterRuns") {
+		t.Fatalf("Validate() should reject BlockAfterRuns < CandidateAfterRuns, got: %v", err)
+	}
+
+	cfg.Database.BlockAfterRuns = 5
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("Validate() should accept valid dwell times, got: %v", err)
+	}
+
+	cfg.Database.RetireAfterRuns = -1
+	if err := Validate(cfg); err == nil {
+		t.Fatal("Validate() should reject negative RetireAfterRuns")
+	}
+}
+
+func TestValidate_ReputationAging(t *testing.T) {
+	cfg := &models.AppConfig{
+		AppName:    "TestApp",
+		Version:    "1.0.0",
+		LogLevel:   "INFO",
+		MaxLogSize: 10,
+		LogBackups: 0,
+		Database: models.DatabaseConfig{
+			RepoURL:          "https://example.com",
+			RiskHalfLifeDays: 14,
+			StaleAfterDays:   60,
+		},
+	}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("Validate() should accept valid aging settings, got: %v", err)
+	}
+
+	cfg.Database.StaleAfterDays = -1
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "StaleAfterDays") {
+		t.Fatalf("Validate() should reject negative StaleAfterDays, got: %v", err)
+	}
+}
+
+func TestValidate_APIUsers(t *testing.T) {
+	cfg := &models.AppConfig{
+		AppName:    "TestApp",
+		Version:    "1.0.0",
+		LogLevel:   "INFO",
+		MaxLogSize: 10,
+		LogBackups: 0,
+		Database: models.DatabaseConfig{
+			RepoURL: "https://example.com",
+			APIUsers: []models.APIUser{
+				{Name: "alice", Key: "k1", Role: models.RoleAnalyst},
+				{Name: "bob", Key: "k2", Role: "superuser"},
+			},
+		},
+	}
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "Role") {
+		t.Fatalf("Validate() should reject an unknown role, got: %v", err)
+	}
+
+	cfg.Database.APIUsers[1].Role = models.RoleViewer
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("Validate() should accept valid API users, got: %v", err)
+	}
+
+	cfg.Database.APIUsers[1].Key = "k1"
+	if err := Validate(cfg); err == nil {
+		t.Fatal("Validate() should reject a duplicate API key")
+	}
+}
+
+func TestValidate_ReportsAllProblems(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "not-a-dir")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	cfg := &models.AppConfig{
+		AppName:    "TestApp",
+		Version:    "1.0.0",
+		LogLevel:   "INFO",
+		MaxLogSize: 10,
+		Database: models.DatabaseConfig{
+			RepoURL:       "https://",
+			Parallelism:   500,
+			CacheTTLHours: -1,
+			Registries:    []string{"arin", "iana"},
+			ResultsDir:    file,
+		},
+	}
+	err := Validate(cfg)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Validate() should return a *ValidationError, got: %v", err)
+	}
+	for _, field := range []string{"RepoURL", "Parallelism", "CacheTTLHours", "iana", "ResultsDir"} {
+		found := false
+		for _, p := range verr.Problems {
+			if strings.Contains(p, field) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("problems should mention %s: %v", field, verr.Problems)
+		}
+	}
+	if len(verr.Problems) != 5 {
+		t.Errorf("expected 5 problems, got %d: %v", len(verr.Problems), verr.Problems)
+	}
+}
+
+func TestValidate_AbuseIPDBReportNeedsKey(t *testing.T) {
+	cfg := &models.AppConfig{
+		AppName:    "TestApp",
+		Version:    "1.0.0",
+		LogLevel:   "INFO",
+		MaxLogSize: 10,
+		Database: models.DatabaseConfig{
+			RepoURL:         "https://example.com/repo",
+			AbuseIPDBReport: true,
+		},
+	}
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "AbuseIPDBKey") {
+		t.Fatalf("Validate() should require AbuseIPDBKey, got: %v", err)
+	}
+	cfg.Database.AbuseIPDBKey = "k"
+	if err := Validate(cfg); err != nil {
+		t.Errorf("Validate() with a key = %v, want nil", err)
+	}
+}
+
+func TestValidate_UserAgentControlCharacters(t *testing.T) {
+	cfg := &models.AppConfig{
+		AppName:    "TestApp",
+		Version:    "1.0.0",
+		LogLevel:   "INFO",
+		MaxLogSize: 10,
+		Database: models.DatabaseConfig{
+			RepoURL: "https://example.com/repo",
+			Contact: "noc@example.org\r\nX-Injected: 1",
+		},
+	}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "Database.Contact") {
+		t.Fatalf("Validate() should reject a contact with a line break, got: %v", err)
+	}
+	cfg.Database.Contact = "noc@example.org"
+	if err := Validate(cfg); err != nil {
+		t.Errorf("Validate() with a plain contact = %v, want nil", err)
+	}
+}
+
+func TestValidate_Resolver(t *testing.T) {
+	cfg := &models.AppConfig{
+		AppName:    "TestApp",
+		Version:    "1.0.0",
+		LogLevel:   "INFO",
+		MaxLogSize: 10,
+		Database: models.DatabaseConfig{
+			RepoURL:    "https://example.com/repo",
+			DNSServers: []string{"dns.example.org"},
+			DoHURL:     "http://dns.example/dns-query",
+		},
+	}
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "Database.DNSServers") || !strings.Contains(err.Error(), "Database.DoHURL") {
+		t.Fatalf("Validate() should reject a host name server and a plain HTTP DoH URL, got: %v", err)
+	}
+	cfg.Database.DNSServers = []string{"9.9.9.9", "10.0.0.53:5353", "[2620:fe::fe]:53"}
+	cfg.Database.DoHURL = "https://dns.example/dns-query"
+	if err := Validate(cfg); err != nil {
+		t.Errorf("Validate() with valid resolvers = %v, want nil", err)
+	}
+}
+
+func TestValidate_SQLTable(t *testing.T) {
+	cfg := &models.AppConfig{
+		AppName:    "TestApp",
+		Version:    "1.0.0",
+		LogLevel:   "INFO",
+		MaxLogSize: 10,
+		Database: models.DatabaseConfig{
+			RepoURL:  "https://example.com/repo",
+			SQLTable: "scanners; DROP TABLE users",
+		},
+	}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "Database.SQLTable") {
+		t.Fatalf("Validate() should reject a table name that is not an identifier, got: %v", err)
+	}
+	cfg.Database.SQLTable = "scanner_records_v2"
+	if err := Validate(cfg); err != nil {
+		t.Errorf("Validate() with a plain table name = %v, want nil", err)
+	}
+}
+
+func TestValidate_NegativeBudget(t *testing.T) {
+	cfg := &models.AppConfig{
+		AppName:    "TestApp",
+		Version:    "1.0.0",
+		LogLevel:   "INFO",
+		MaxLogSize: 10,
+		Database: models.DatabaseConfig{
+			RepoURL:      "https://example.com/repo",
+			MaxRDAPCalls: -1,
+		},
+	}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "Database.MaxRDAPCalls") {
+		t.Fatalf("Validate() should reject a negative budget, got: %v", err)
+	}
+	cfg.Database.MaxRDAPCalls = 500
+	cfg.Database.MaxRunMinutes = 30
+	if err := Validate(cfg); err != nil {
+		t.Errorf("Validate() with budgets = %v, want nil", err)
+	}
+}
+
+func TestValidate_CollateralCriticalKinds(t *testing.T) {
+	cfg := &models.AppConfig{
+		AppName:    "TestApp",
+		Version:    "1.0.0",
+		LogLevel:   "INFO",
+		MaxLogSize: 10,
+		Database: models.DatabaseConfig{
+			RepoURL:                 "https://example.com/repo",
+			CollateralCriticalKinds: []string{"own", "friends"},
+			ProtectedPrefixesFile:   filepath.Join(t.TempDir(), "missing.txt"),
+		},
+	}
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "Database.CollateralCriticalKinds") ||
+		!strings.Contains(err.Error(), "Database.ProtectedPrefixesFile") {
+		t.Fatalf("Validate() should reject an unknown kind and a missing file, got: %v", err)
+	}
+}
+
+func TestValidate_BroadPrefixPolicy(t *testing.T) {
+	cfg := &models.AppConfig{
+		AppName:    "TestApp",
+		Version:    "1.0.0",
+		LogLevel:   "INFO",
+		MaxLogSize: 10,
+		Database: models.DatabaseConfig{
+			RepoURL:           "https://example.com/repo",
+			BroadPrefixPolicy: "drop",
+			BroadPrefixV4:     33,
+		},
+	}
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "Database.BroadPrefixPolicy") || !strings.Contains(err.Error(), "Database.BroadPrefixV4") {
+		t.Fatalf("Validate() should reject an unknown policy and a /33 threshold, got: %v", err)
+	}
+	cfg.Database.BroadPrefixPolicy = "exclude"
+	cfg.Database.BroadPrefixV4 = 12
+	if err := Validate(cfg); err != nil {
+		t.Errorf("Validate() with an exclude policy = %v, want nil", err)
+	}
+}
+
+func TestValidate_TagRules(t *testing.T) {
+	cfg := &models.AppConfig{
+		AppName:    "TestApp",
+		Version:    "1.0.0",
+		LogLevel:   "INFO",
+		MaxLogSize: 10,
+		Database: models.DatabaseConfig{
+			RepoURL: "https://example.com/repo",
+			TagRules: []models.TagRule{
+				{Name: "empty", Tags: []string{" "}},
+				{Name: "regex", Tags: []string{"t"}, OrgRegex: "(unclosed"},
+			},
+		},
+	}
+	err := Validate(cfg)
+	if err == nil {
+		t.Fatal("Validate() should reject invalid tag rules")
+	}
+	for _, want := range []string{"TagRules[0] (empty) adds no tags", "TagRules[0] (empty) needs at least one condition", "TagRules[1] (regex) has an invalid OrgRegex"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() error %q does not mention %q", err, want)
+		}
+	}
+	cfg.Database.TagRules = []models.TagRule{{Name: "cn", Tags: []string{"watch"}, Countries: []string{"CN"}}}
+	if err := Validate(cfg); err != nil {
+		t.Errorf("Validate() with a valid rule = %v, want nil", err)
+	}
+}
+
+func TestValidate_PivotLinks(t *testing.T) {
+	cfg := &models.AppConfig{
+		AppName:    "TestApp",
+		Version:    "1.0.0",
+		LogLevel:   "INFO",
+		MaxLogSize: 10,
+		Database: models.DatabaseConfig{
+			RepoURL: "https://example.com/repo",
+			PivotLinks: []models.PivotLink{
+				{URL: "https://example.com/{ip}"},
+				{Name: "shell", URL: "file:///bin/sh?{ip}"},
+			},
+		},
+	}
+	err := Validate(cfg)
+	if err == nil {
+		t.Fatal("Validate() should reject invalid pivot links")
+	}
+	for _, want := range []string{"PivotLinks[0] needs a name", "PivotLinks[1] (shell) URL must be an http or https URL"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() error %q does not mention %q", err, want)
+		}
+	}
+	cfg.Database.PivotLinks = []models.PivotLink{
+		{Name: "GreyNoise", URL: "https://viz.greynoise.io/ip/{ip}"},
+		{Name: "Censys"},
+		{Name: "Whois", URL: "{registry_url}"},
+	}
+	if err := Validate(cfg); err != nil {
+		t.Errorf("Validate() with valid links = %v, want nil", err)
+	}
+}
+
+func TestValidate_OptOutURLs(t *testing.T) {
+	cfg := &models.AppConfig{
+		AppName:    "TestApp",
+		Version:    "1.0.0",
+		LogLevel:   "INFO",
+		MaxLogSize: 10,
+		Database: models.DatabaseConfig{
+			RepoURL:    "https://example.com/repo",
+			OptOutURLs: map[string]string{"censys": "", "acme": "mailto:optout@example.com"},
+		},
+	}
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "OptOutURLs[acme] must be an http or https URL") {
+		t.Fatalf("Validate() = %v, want an OptOutURLs[acme] error", err)
+	}
+	cfg.Database.OptOutURLs["acme"] = "https://example.com/optout?ip={ip}"
+	if err := Validate(cfg); err != nil {
+		t.Errorf("Validate() with valid opt-out URLs = %v, want nil", err)
+	}
+}
+
+func TestValidate_GeoBackfillPerHour(t *testing.T) {
+	cfg := &models.AppConfig{
+		AppName:    "TestApp",
+		Version:    "1.0.0",
+		LogLevel:   "INFO",
+		MaxLogSize: 10,
+		Database: models.DatabaseConfig{
+			RepoURL:            "https://example.com/repo",
+			GeoBackfillPerHour: -1,
+		},
+	}
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "GeoBackfillPerHour must be between 0 and 3600") {
+		t.Fatalf("Validate() = %v, want a GeoBackfillPerHour error", err)
+	}
+	cfg.Database.GeoBackfillPerHour = 60
+	if err := Validate(cfg); err != nil {
+		t.Errorf("Validate() with 60 per hour = %v, want nil", err)
+	}
+}
+
+func TestValidate_RDNSPatterns(t *testing.T) {
+	cfg := &models.AppConfig{
+		AppName:    "TestApp",
+		Version:    "1.0.0",
+		LogLevel:   "INFO",
+		MaxLogSize: 10,
+		Database: models.DatabaseConfig{
+			RepoURL: "https://example.com/repo",
+			RDNSPatterns: []models.RDNSPattern{
+				{Scanner: "acme", Pattern: "scan[-*.acme.example"},
+				{Pattern: "*.acme.example"},
+			},
+		},
+	}
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "RDNSPatterns[0] (scan[-*.acme.example) has an invalid pattern") ||
+		!strings.Contains(err.Error(), "RDNSPatterns[1] needs a scanner and a pattern") {
+		t.Fatalf("Validate() = %v, want both RDNSPatterns errors", err)
+	}
+	cfg.Database.RDNSPatterns = []models.RDNSPattern{{Scanner: "acme", Pattern: "scan-*.acme.example"}}
+	if err := Validate(cfg); err != nil {
+		t.Errorf("Validate() with a valid pattern = %v, want nil", err)
+	}
+}
+
+func TestValidate_MinEnforcementConfidence(t *testing.T) {
+	cfg := &models.AppConfig{Database: models.DatabaseConfig{MinEnforcementConfidence: "certain"}}
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), `MinEnforcementConfidence: unknown confidence "certain"`) {
+		t.Fatalf("Validate() = %v, want MinEnforcementConfidence error", err)
+	}
+	cfg.Database.MinEnforcementConfidence = "low"
+	if err := Validate(cfg); err != nil && strings.Contains(err.Error(), "MinEnforcementConfidence") {
+		t.Errorf("Validate() = %v, want low accepted", err)
+	}
+}
+
+func TestValidate_AutoscaleMinWorkers(t *testing.T) {
+	cfg := &models.AppConfig{Database: models.DatabaseConfig{Parallelism: 4, AutoscaleMinWorkers: 8}}
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "AutoscaleMinWorkers must be between 0 and Parallelism (4); got 8") {
+		t.Fatalf("Validate() = %v, want AutoscaleMinWorkers error", err)
+	}
+	cfg.Database.AutoscaleMinWorkers = 2
+	if err := Validate(cfg); err != nil && strings.Contains(err.Error(), "AutoscaleMinWorkers") {
+		t.Errorf("Validate() = %v, want 2 accepted", err)
+	}
+}
+
+func TestValidate_ArchiveSources(t *testing.T) {
+	cfg := &models.AppConfig{Database: models.DatabaseConfig{
+		ArchiveSources: []string{"https://example.com/feeds.tar.gz", "/srv/feeds/lists.zip", "https://example.com/feeds.json", "/srv/feeds/list.nft"},
+	}}
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "ArchiveSources[2] (https://example.com/feeds.json) must be a .zip") ||
+		!strings.Contains(err.Error(), "ArchiveSources[3] (/srv/feeds/list.nft) must be a .zip") {
+		t.Fatalf("Validate() = %v, want the two non-archive sources reported", err)
+	}
+	if strings.Contains(err.Error(), "ArchiveSources[0]") || strings.Contains(err.Error(), "ArchiveSources[1]") {
+		t.Errorf("Validate() = %v, want the archives accepted", err)
+	}
+}
+
+func TestValidate_RepoRef(t *testing.T) {
+	for _, ref := range []string{"--upload-pack=evil", "v1 .2"} {
+		cfg := &models.AppConfig{Database: models.DatabaseConfig{RepoRef: ref}}
+		if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "Database.RepoRef") {
+			t.Errorf("Validate(RepoRef=%q) = %v, want RepoRef error", ref, err)
+		}
+	}
+	for _, ref := range []string{"", "v1.2", "0123456789abcdef", "release/2024"} {
+		cfg := &models.AppConfig{Database: models.DatabaseConfig{RepoRef: ref}}
+		if err := Validate(cfg); err != nil && strings.Contains(err.Error(), "RepoRef") {
+			t.Errorf("Validate(RepoRef=%q) = %v, want accepted", ref, err)
+		}
+	}
+}
+
+func TestValidate_FeedTrust(t *testing.T) {
+	cfg := &models.AppConfig{Database: models.DatabaseConfig{
+		FeedTrust: []models.FeedTrust{
+			{Source: "shodan.nft", Level: 80},
+			{Source: "", Level: 10},
+			{Source: "[", Level: 10},
+			{Source: "partner-*.zip", Level: 101},
+		},
+		ConflictPolicy: "newest",
+	}}
+	err := Validate(cfg)
+	if err == nil {
+		t.Fatal("Validate() = nil, want errors")
+	}
+	for _, want := range []string{
+		"FeedTrust[1] needs a source",
+		"FeedTrust[2] ([) has an invalid pattern",
+		"FeedTrust[3] (partner-*.zip) level must be between 0 and 100; got 101",
+		`ConflictPolicy: unknown policy "newest"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v, want %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "FeedTrust[0]") {
+		t.Errorf("Validate() = %v, want FeedTrust[0] accepted", err)
+	}
+}
+
+func TestValidate_ExportPresets(t *testing.T) {
+	cfg := &models.AppConfig{Database: models.DatabaseConfig{
+		ExportPresets: []models.ExportPreset{
+			{Name: "partners", Redact: []models.RedactionRule{
+				{Field: "abuse_email", Action: models.RedactHash},
+				{Field: "notes", Action: models.RedactRemove},
+			}},
+			{Name: "partners"},
+			{Name: "public", Redact: []models.RedactionRule{
+				{Field: "email", Action: models.RedactHash},
+				{Field: "runs_seen", Action: models.RedactMask},
+				{Field: "domain", Action: "encrypt"},
+			}},
+		},
+		DefaultExportPreset: "internal",
+	}}
+	err := Validate(cfg)
+	if err == nil {
+		t.Fatal("Validate() = nil, want errors")
+	}
+	for _, want := range []string{
+		"ExportPresets[1]: duplicate preset partners",
+		`ExportPresets[2] (public) rule 0: unknown field "email"`,
+		`ExportPresets[2] (public) rule 1: field "runs_seen" is not text`,
+		`ExportPresets[2] (public) rule 2: unknown action "encrypt"`,
+		`DefaultExportPreset: unknown preset "internal"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v, want %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "ExportPresets[0]") {
+		t.Errorf("Validate() = %v, want ExportPresets[0] accepted", err)
+	}
+}
+
+func TestValidate_EnforcementPolicy(t *testing.T) {
+	cfg := &models.AppConfig{Database: models.DatabaseConfig{
+		EnforcementPolicy: models.EnforcementPolicy{
+			ExcludeASNs:      []string{"AS15169", "as13335", "64500", "Google"},
+			ExcludeCountries: []string{"FR", "France"},
+			OnlyCountries:    []string{"cn", "ru"},
+		},
+	}}
+	err := Validate(cfg)
+	if err == nil {
+		t.Fatal("Validate() = nil, want errors")
+	}
+	for _, want := range []string{
+		`EnforcementPolicy.ExcludeASNs: "Google" is not an ASN`,
+		`EnforcementPolicy.ExcludeCountries: "France" is not a two-letter country code`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v, want %q", err, want)
+		}
+	}
+	for _, accepted := range []string{`"AS15169"`, `"as13335"`, `"64500"`, `"FR"`, "OnlyCountries"} {
+		if strings.Contains(err.Error(), accepted) {
+			t.Errorf("Validate() = %v, want %s accepted", err, accepted)
+		}
+	}
+}
+
+func TestValidate_ExpiryWarnings(t *testing.T) {
+	cfg := &models.AppConfig{Database: models.DatabaseConfig{
+		Watchlist:        []string{"192.0.2.1", "198.51.100.0/24", "2001:db8::/32", "not-an-ip"},
+		ExpiryWarnDays:   -1,
+		ExpiryWebhookURL: "ftp://hooks.example.com",
+	}}
+	err := Validate(cfg)
+	if err == nil {
+		t.Fatal("Validate() = nil, want errors")
+	}
+	for _, want := range []string{
+		"ExpiryWarnDays must be >= 0",
+		`Watchlist entries must be an IP or CIDR; got "not-an-ip"`,
+		"ExpiryWebhookURL must be a valid URL",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v, want %q", err, want)
+		}
+	}
+	if strings.Count(err.Error(), "Watchlist") != 1 {
+		t.Errorf("Validate() = %v, want only not-an-ip rejected", err)
+	}
+}
+
+func TestValidate_RDAPBootstrapDays(t *testing.T) {
+	cfg := &models.AppConfig{Database: models.DatabaseConfig{RDAPBootstrapDays: -1}}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "RDAPBootstrapDays must be >= 0") {
+		t.Errorf("Validate() = %v, want RDAPBootstrapDays rejected", err)
+	}
+}
+
+func TestValidate_NewDirectoryIsAccepted(t *testing.T) {
+	cfg := &models.AppConfig{
+		AppName:    "TestApp",
+		Version:    "1.0.0",
+		LogLevel:   "INFO",
+		MaxLogSize: 10,
+		Database: models.DatabaseConfig{
+			RepoURL:    "https://example.com",
+			ResultsDir: filepath.Join(t.TempDir(), "results", "nested"),
+		},
+	}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("Validate() should accept a directory that can be created, got: %v", err)
+	}
+}
+
+func TestLoad_InvalidConfig_ReturnsConfigWithError(t *testing.T) {
+	cm := newTestConfigManager(t)
+	bad := []byte(`{"app_name":"BadApp","version":"1.0.0","log_level":"INFO","max_log_size":10,"database":{"repo_url":"https://example.com","parallelism":-2}}`)
+	if err := os.WriteFile(cm.configPath, bad, 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	cfg, err := cm.Load()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Load() should wrap a *ValidationError, got: %v", err)
+	}
+	if cfg == nil || cfg.AppName != "BadApp" {
+		t.Errorf("Load() should still return the parsed config, got %+v", cfg)
+	}
+}
+
+func TestApplyPreset(t *testing.T) {
+	for _, name := range PresetNames() {
+		cfg := &models.AppConfig{
+			AppName:    "TestApp",
+			Version:    "1.0.0",
+			LogLevel:   "INFO",
+			MaxLogSize: 10,
+			Database:   models.DatabaseConfig{RepoURL: "https://example.com"},
+		}
+		if err := ApplyPreset(&cfg.Database, name); err != nil {
+			t.Fatalf("ApplyPreset(%q): %v", name, err)
+		}
+		p, _ := PresetByName(name)
+		if cfg.Database.Parallelism != p.Parallelism || cfg.Database.MaxRetries != p.MaxRetries ||
+			cfg.Database.BatchSize != p.BatchSize || cfg.Database.Preset != name {
+			t.Errorf("preset %q not applied: %+v", name, cfg.Database)
+		}
+		if err := Validate(cfg); err != nil {
+			t.Errorf("preset %q should produce a valid config, got: %v", name, err)
+		}
+	}
+
+	var db models.DatabaseConfig
+	if err := ApplyPreset(&db, "turbo"); err == nil {
+		t.Error("ApplyPreset should reject an unknown preset")
+	}
+}
+
 func TestLoad_InvalidConfig_ReturnsValidationError(t *testing.T) {
 	cm := newTestConfigManager(t)
 
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// Preset is a named set of performance settings that fit together.
+type Preset struct {
+	Name        string  // identifier used in config and on the command line
+	Label       string  // display name
+	Parallelism int     // enrichment workers
+	APIThrottle float64 // seconds between requests per worker
+	MaxRetries  int     // retries on network errors, 429 and 5xx
+	BatchSize   int     // records between progress checkpoints
+}
+
+// Presets lists the built-in performance profiles.
+var Presets = []Preset{
+	{Name: "home", Label: "Home connection", Parallelism: 2, APIThrottle: 1.0, MaxRetries: 3, BatchSize: 10},
+	{Name: "server", Label: "Server", Parallelism: 8, APIThrottle: 0.25, MaxRetries: 5, BatchSize: 50},
+	{Name: "registry-friendly", Label: "Registry-friendly", Parallelism: 1, APIThrottle: 2.0, MaxRetries: 2, BatchSize: 10},
+}
+
+// PresetByName returns the preset with the given name.
+func PresetByName(name string) (Preset, bool) {
+	for _, p := range Presets {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Preset{}, false
+}
+
+// PresetNames returns the names of the built-in presets.
+func PresetNames() []string {
+	names := make([]string, len(Presets))
+	for i, p := range Presets {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// ApplyPreset overwrites the performance settings of db with the named
+// preset and records its name in db.Preset.
+func ApplyPreset(db *models.DatabaseConfig, name string) error {
+	p, ok := PresetByName(name)
+	if !ok {
+		return fmt.Errorf("unknown preset %q; expected one of %v", name, PresetNames())
+	}
+	db.Parallelism = p.Parallelism
+	db.APIThrottle = p.APIThrottle
+	db.MaxRetries = p.MaxRetries
+	db.BatchSize = p.BatchSize
+	db.Preset = p.Name
+	return nil
+}
@@ -0,0 +1,310 @@
+// Package diagnostics writes crash reports for panics recovered at goroutine
+// boundaries and builds the diagnostics bundle attached to bug reports.
+// Secrets are removed from every configuration it writes.
+package diagnostics
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/internal/logger"
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// crashLogLines is the number of recent log entries included in a crash report.
+const crashLogLines = 200
+
+// redacted replaces secret values in reports and bundles.
+const redacted = "REDACTED"
+
+// Reporter writes crash reports and diagnostics bundles for one application.
+type Reporter struct {
+	config *models.AppConfig
+	logger *logger.Logger
+}
+
+// New creates a Reporter. The logs directory is read from cfg each time a
+// report is written, so a directory changed at runtime is honored.
+func New(cfg *models.AppConfig, log *logger.Logger) *Reporter {
+	return &Reporter{config: cfg, logger: log}
+}
+
+// logsDir returns the configured logs directory, or ./logs when unset.
+func (r *Reporter) logsDir() string {
+	if r.config == nil || r.config.Database.LogsDir == "" {
+		return "./logs"
+	}
+	return r.config.Database.LogsDir
+}
+
+// CrashDir returns the directory crash reports are written to.
+func (r *Reporter) CrashDir() string {
+	return filepath.Join(r.logsDir(), "crash")
+}
+
+// Recover must be deferred at the top of a goroutine. It recovers a panic,
+// writes a crash report, and logs where the report is, so the rest of the
+// application keeps running.
+func (r *Reporter) Recover(component string) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	r.HandlePanic(component, v, debug.Stack())
+}
+
+// HandlePanic records a panic recovered elsewhere, such as in the
+// extractor's worker pool.
+func (r *Reporter) HandlePanic(component string, value interface{}, stack []byte) {
+	path, err := r.WriteCrashReport(component, value, stack)
+	if r.logger == nil {
+		return
+	}
+	if err != nil {
+		r.logger.Critical(component, fmt.Sprintf("panic: %v (crash report failed: %v)", value, err))
+		return
+	}
+	r.logger.Critical(component, fmt.Sprintf("panic: %v - crash report: %s", value, path))
+}
+
+// WriteCrashReport writes a text report with the panic value, the stack, the
+// version, the configuration without secrets, and the last log entries. It
+// returns the report path.
+func (r *Reporter) WriteCrashReport(component string, value interface{}, stack []byte) (string, error) {
+	dir := r.CrashDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("creating crash directory: %w", err)
+	}
+	now := time.Now()
+	path := filepath.Join(dir, fmt.Sprintf("crash_%s_%09d.txt", now.Format("20060102_150405"), now.Nanosecond()))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "LiaCheckScanner crash report\n")
+	fmt.Fprintf(&b, "Time:      %s\n", now.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Component: %s\n", component)
+	fmt.Fprintf(&b, "Panic:     %v\n", value)
+	for k, v := range runtimeInfo(r.config) {
+		fmt.Fprintf(&b, "%-10s %v\n", k+":", v)
+	}
+	fmt.Fprintf(&b, "\n== Stack ==\n%s\n", stack)
+
+	fmt.Fprintf(&b, "\n== Configuration (secrets removed) ==\n")
+	if r.config != nil {
+		cfg, _ := json.MarshalIndent(RedactConfig(r.config), "", "  ")
+		b.Write(cfg)
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "\n== Last %d log entries ==\n", crashLogLines)
+	if r.logger != nil {
+		for _, e := range r.logger.GetRecentEntries(crashLogLines) {
+			fmt.Fprintf(&b, "%s [%s] %s: %s\n", e.Timestamp.Format(time.RFC3339), e.Level, e.Component, e.Message)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return "", fmt.Errorf("writing crash report: %w", err)
+	}
+	return path, nil
+}
+
+// CreateBundle zips the logs directory (including crash reports), the
+// configuration without secrets, and run metadata into dest. extra is merged
+// into metadata.json, e.g. the number of loaded records.
+func (r *Reporter) CreateBundle(dest string, extra map[string]interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("creating bundle directory: %w", err)
+	}
+	f, err := os.Create(dest)
+	if err != nil {
+		return fmt.Errorf("creating bundle: %w", err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+
+	meta := runtimeInfo(r.config)
+	meta["created_at"] = time.Now().Format(time.RFC3339)
+	for k, v := range extra {
+		meta[k] = v
+	}
+	if err := writeJSON(zw, "metadata.json", meta); err != nil {
+		return err
+	}
+	if r.config != nil {
+		if err := writeJSON(zw, "config.json", RedactConfig(r.config)); err != nil {
+			return err
+		}
+	}
+
+	logsDir := r.logsDir()
+	err = filepath.Walk(logsDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == logsDir {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(logsDir, path)
+		if err != nil {
+			return err
+		}
+		w, err := zw.Create(filepath.ToSlash(filepath.Join("logs", rel)))
+		if err != nil {
+			return err
+		}
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(w, src)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("adding logs to bundle: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("writing bundle: %w", err)
+	}
+	return nil
+}
+
+// secrets returns the secret fields of db.
+func secrets(db *models.DatabaseConfig) []*string {
+	return []*string{&db.APIKey, &db.IPAPIKey, &db.IPInfoToken, &db.IPDataKey, &db.AbuseIPDBKey, &db.MetricsInfluxToken,
+		&db.PostgresDSN, &db.RemoteAPIKey, &db.SharedCacheKey}
+}
+
+// credentialURLs returns the URL fields of db, whose userinfo may hold a
+// password.
+func credentialURLs(db *models.DatabaseConfig) []*string {
+	return []*string{&db.RepoURL, &db.DoHURL, &db.MetricsInfluxURL, &db.MetricsPushgatewayURL, &db.ClickHouseURL,
+		&db.RemoteAPIURL, &db.SharedCacheURL, &db.ExpiryWebhookURL}
+}
+
+// urlPassword returns the password of the userinfo of raw, if any.
+func urlPassword(raw string) (*url.URL, string, bool) {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return nil, "", false
+	}
+	password, ok := u.User.Password()
+	return u, password, ok
+}
+
+// withURLPassword returns u with password as the password of its userinfo.
+func withURLPassword(u *url.URL, password string) string {
+	u.User = url.UserPassword(u.User.Username(), password)
+	return u.String()
+}
+
+// RedactConfig returns a copy of cfg with every key and token replaced.
+func RedactConfig(cfg *models.AppConfig) models.AppConfig {
+	out := *cfg
+	db := &out.Database
+	for _, s := range secrets(db) {
+		if *s != "" {
+			*s = redacted
+		}
+	}
+	for _, s := range credentialURLs(db) {
+		if u, _, ok := urlPassword(*s); ok {
+			*s = withURLPassword(u, redacted)
+		}
+	}
+	if len(db.APIUsers) > 0 {
+		users := make([]models.APIUser, len(db.APIUsers))
+		for i, u := range db.APIUsers {
+			u.Key = redacted
+			users[i] = u
+		}
+		db.APIUsers = users
+	}
+	return out
+}
+
+// RestoreRedacted puts back in db the secrets RedactConfig replaced, from
+// current, so a redacted configuration can be edited and sent back. API
+// users are matched by name. It fails when a replaced secret has no value
+// to restore.
+func RestoreRedacted(db *models.DatabaseConfig, current models.DatabaseConfig) error {
+	saved := secrets(&current)
+	for i, s := range secrets(db) {
+		if *s != redacted {
+			continue
+		}
+		if *saved[i] == "" {
+			return fmt.Errorf("a secret is set to %q but none is configured", redacted)
+		}
+		*s = *saved[i]
+	}
+	savedURLs := credentialURLs(&current)
+	for i, s := range credentialURLs(db) {
+		u, password, ok := urlPassword(*s)
+		if !ok || password != redacted {
+			continue
+		}
+		_, password, ok = urlPassword(*savedURLs[i])
+		if !ok {
+			return fmt.Errorf("%s has the password %q but none is configured", u.Redacted(), redacted)
+		}
+		*s = withURLPassword(u, password)
+	}
+	db.APIUsers = append([]models.APIUser(nil), db.APIUsers...)
+	for i, u := range db.APIUsers {
+		if u.Key != redacted {
+			continue
+		}
+		restored := false
+		for _, cur := range current.APIUsers {
+			if cur.Name == u.Name && cur.Key != "" {
+				db.APIUsers[i].Key = cur.Key
+				restored = true
+				break
+			}
+		}
+		if !restored {
+			return fmt.Errorf("API user %q has the key %q but no key is configured for it", u.Name, redacted)
+		}
+	}
+	return nil
+}
+
+// runtimeInfo describes the build and host for reports.
+func runtimeInfo(cfg *models.AppConfig) map[string]interface{} {
+	info := map[string]interface{}{
+		"go":         runtime.Version(),
+		"os":         runtime.GOOS + "/" + runtime.GOARCH,
+		"goroutines": runtime.NumGoroutine(),
+	}
+	if cfg != nil {
+		info["version"] = cfg.Version
+	}
+	return info
+}
+
+// writeJSON adds v to the archive as an indented JSON file.
+func writeJSON(zw *zip.Writer, name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding %s: %w", name, err)
+	}
+	w, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("adding %s: %w", name, err)
+	}
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,233 @@
+package diagnostics
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/lia/liacheckscanner_go/internal/logger"
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// TestMain runs the tests from a temporary directory, so the logs and data
+// files written relative to the working directory stay out of the tree.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "diagnostics-test-")
+	if err != nil {
+		panic(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// newTestReporter returns a Reporter whose logs directory is temporary and
+// whose configuration carries one secret of each kind.
+func newTestReporter(t *testing.T) (*Reporter, *models.AppConfig) {
+	t.Helper()
+	dir := t.TempDir()
+	log := logger.NewLogger()
+	t.Cleanup(func() { log.Close() })
+	if err := log.SetLogsDir(dir); err != nil {
+		t.Fatalf("SetLogsDir: %v", err)
+	}
+	cfg := &models.AppConfig{
+		AppName: "Test",
+		Version: "9.9.9",
+		Database: models.DatabaseConfig{
+			LogsDir:            dir,
+			APIKey:             "admin-secret",
+			IPAPIKey:           "ipapi-secret",
+			IPInfoToken:        "ipinfo-secret",
+			IPDataKey:          "ipdata-secret",
+			AbuseIPDBKey:       "abuseipdb-secret",
+			APIUsers:           []models.APIUser{{Name: "alice", Key: "alice-secret", Role: models.RoleAnalyst}},
+			MetricsInfluxToken: "influx-secret",
+			PostgresDSN:        "postgres://soc:pg-secret@db/scanners",
+			ClickHouseURL:      "http://soc:ch-secret@ch:8123/?database=scanners",
+			RemoteAPIKey:       "remote-secret",
+			SharedCacheKey:     "shared-secret",
+		},
+	}
+	return New(cfg, log), cfg
+}
+
+// assertNoSecrets fails when text contains any of the test secrets.
+func assertNoSecrets(t *testing.T, name, text string) {
+	t.Helper()
+	for _, s := range []string{"admin-secret", "ipapi-secret", "ipinfo-secret", "ipdata-secret", "abuseipdb-secret", "alice-secret", "influx-secret",
+		"pg-secret", "ch-secret", "remote-secret", "shared-secret"} {
+		if strings.Contains(text, s) {
+			t.Errorf("%s leaks %q", name, s)
+		}
+	}
+}
+
+// ----- RedactConfig -----
+
+func TestRedactConfig_LeavesOriginalUntouched(t *testing.T) {
+	_, cfg := newTestReporter(t)
+	out := RedactConfig(cfg)
+	if out.Database.IPAPIKey != redacted || out.Database.APIUsers[0].Key != redacted {
+		t.Errorf("secrets not redacted: %+v", out.Database)
+	}
+	if cfg.Database.IPAPIKey != "ipapi-secret" || cfg.Database.APIUsers[0].Key != "alice-secret" {
+		t.Error("RedactConfig must not modify its argument")
+	}
+	if out.Database.APIUsers[0].Name != "alice" {
+		t.Errorf("user name should be kept, got %q", out.Database.APIUsers[0].Name)
+	}
+}
+
+func TestRestoreRedacted_RoundTrip(t *testing.T) {
+	_, cfg := newTestReporter(t)
+	out := RedactConfig(cfg)
+	if got := out.Database.ClickHouseURL; got != "http://soc:REDACTED@ch:8123/?database=scanners" {
+		t.Errorf("clickhouse_url = %q, want only its password redacted", got)
+	}
+	db := out.Database
+	if err := RestoreRedacted(&db, cfg.Database); err != nil {
+		t.Fatalf("RestoreRedacted: %v", err)
+	}
+	if db.PostgresDSN != cfg.Database.PostgresDSN || db.ClickHouseURL != cfg.Database.ClickHouseURL ||
+		db.APIUsers[0].Key != "alice-secret" || db.IPInfoToken != "ipinfo-secret" {
+		t.Errorf("restored config = %+v", db)
+	}
+
+	db = out.Database
+	db.APIUsers[0].Name = "bob"
+	if err := RestoreRedacted(&db, cfg.Database); err == nil {
+		t.Error("a redacted key of an unknown user should be refused")
+	}
+}
+
+// TestRedactConfig_CoversEverySecretField fails when a DatabaseConfig field
+// named like a credential (a key, token, password or DSN), or the password
+// of a URL field, survives RedactConfig: add new ones to secrets or
+// credentialURLs.
+func TestRedactConfig_CoversEverySecretField(t *testing.T) {
+	credential := regexp.MustCompile(`(Key|Token|Password|Secret|DSN)$`)
+	var db models.DatabaseConfig
+	v := reflect.ValueOf(&db).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		switch {
+		case v.Field(i).Kind() != reflect.String:
+		case strings.HasSuffix(name, "URL"):
+			v.Field(i).SetString("https://user:" + name + "-secret@example.com/")
+		case credential.MatchString(name):
+			v.Field(i).SetString(name + "-secret")
+		}
+	}
+	out := RedactConfig(&models.AppConfig{Database: db})
+	b, err := json.Marshal(out.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, leak := range regexp.MustCompile(`\w+-secret`).FindAllString(string(b), -1) {
+		t.Errorf("RedactConfig leaves %s", strings.TrimSuffix(leak, "-secret"))
+	}
+
+	restored := out.Database
+	if err := RestoreRedacted(&restored, db); err != nil || !reflect.DeepEqual(restored, db) {
+		t.Errorf("RestoreRedacted = %v, want the original configuration back", err)
+	}
+}
+
+// ----- Crash reports -----
+
+func TestRecover_WritesCrashReport(t *testing.T) {
+	rep, cfg := newTestReporter(t)
+	rep.logger.Info("Test", "last message before the crash")
+
+	func() {
+		defer rep.Recover("Test")
+		panic("boom")
+	}()
+
+	files, err := filepath.Glob(filepath.Join(cfg.Database.LogsDir, "crash", "crash_*.txt"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected one crash report, got %v (%v)", files, err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	report := string(data)
+	for _, want := range []string{"Panic:     boom", "Component: Test", "9.9.9", "diagnostics_test.go", "last message before the crash"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("crash report missing %q", want)
+		}
+	}
+	assertNoSecrets(t, "crash report", report)
+}
+
+func TestRecover_NoPanicWritesNothing(t *testing.T) {
+	rep, cfg := newTestReporter(t)
+	func() {
+		defer rep.Recover("Test")
+	}()
+	if _, err := os.Stat(filepath.Join(cfg.Database.LogsDir, "crash")); !os.IsNotExist(err) {
+		t.Errorf("crash directory should not exist without a panic, err=%v", err)
+	}
+}
+
+// ----- Diagnostics bundle -----
+
+func TestCreateBundle(t *testing.T) {
+	rep, _ := newTestReporter(t)
+	rep.logger.Info("Test", "bundled message")
+	if _, err := rep.WriteCrashReport("Test", "earlier crash", []byte("stack")); err != nil {
+		t.Fatalf("WriteCrashReport: %v", err)
+	}
+
+	dest := filepath.Join(t.TempDir(), "bundle.zip")
+	if err := rep.CreateBundle(dest, map[string]interface{}{"records": 42}); err != nil {
+		t.Fatalf("CreateBundle: %v", err)
+	}
+
+	zr, err := zip.OpenReader(dest)
+	if err != nil {
+		t.Fatalf("OpenReader: %v", err)
+	}
+	defer zr.Close()
+	contents := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Open %s: %v", f.Name, err)
+		}
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		contents[f.Name] = string(b)
+		assertNoSecrets(t, f.Name, string(b))
+	}
+
+	if !strings.Contains(contents["metadata.json"], `"records": 42`) || !strings.Contains(contents["metadata.json"], `"version": "9.9.9"`) {
+		t.Errorf("metadata.json = %s", contents["metadata.json"])
+	}
+	if !strings.Contains(contents["config.json"], redacted) {
+		t.Errorf("config.json should contain redacted secrets: %s", contents["config.json"])
+	}
+	var haveLog, haveCrash bool
+	for name, body := range contents {
+		if strings.HasPrefix(name, "logs/liacheckscanner_") && strings.Contains(body, "bundled message") {
+			haveLog = true
+		}
+		if strings.HasPrefix(name, "logs/crash/crash_") {
+			haveCrash = true
+		}
+	}
+	if !haveLog || !haveCrash {
+		t.Errorf("bundle should contain the log file and the crash report, got %v", zr.File)
+	}
+}
@@ -0,0 +1,112 @@
+// Package gui provides the graphical user interface for LiaCheckScanner.
+// This file contains the accessibility settings: text scaling through a
+// theme wrapper and the plain labels mode that removes emoji from the UI.
+package gui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/lia/liacheckscanner_go/internal/logger"
+)
+
+// TextScaleLabels are the text size choices of the Configuration tab.
+var TextScaleLabels = []string{"100%", "125%", "150%", "175%", "200%"}
+
+// textScaleValues maps TextScaleLabels to AppConfig.TextScale values.
+var textScaleValues = map[string]float32{"100%": 1, "125%": 1.25, "150%": 1.5, "175%": 1.75, "200%": 2}
+
+// TextScaleLabel returns the choice shown for a configured scale, "100%"
+// when it is unset or not one of the choices.
+func TextScaleLabel(scale float32) string {
+	for label, v := range textScaleValues {
+		if v == scale {
+			return label
+		}
+	}
+	return "100%"
+}
+
+// scaledTheme enlarges the text sizes of the wrapped theme.
+type scaledTheme struct {
+	fyne.Theme
+	scale float32
+}
+
+// Size scales the text sizes and the icons shown next to text, leaving
+// paddings and other sizes unchanged.
+func (t scaledTheme) Size(name fyne.ThemeSizeName) float32 {
+	size := t.Theme.Size(name)
+	switch name {
+	case theme.SizeNameText, theme.SizeNameHeadingText, theme.SizeNameSubHeadingText,
+		theme.SizeNameCaptionText, theme.SizeNameInlineIcon:
+		return size * t.scale
+	}
+	return size
+}
+
+// applyTextScale installs the configured text size.
+func (a *App) applyTextScale() {
+	scale := a.config.TextScale
+	if scale <= 0 {
+		scale = 1
+	}
+	var th fyne.Theme = theme.DefaultTheme()
+	if scale != 1 {
+		th = scaledTheme{Theme: th, scale: scale}
+	}
+	a.fyneApp.Settings().SetTheme(th)
+}
+
+// text returns s as shown in the UI: without emoji in plain labels mode.
+func (a *App) text(s string) string {
+	if a.config.PlainLabels {
+		return logger.StripEmoji(s)
+	}
+	return s
+}
+
+// showInformation is dialog.ShowInformation honouring plain labels mode.
+func (a *App) showInformation(title, message string, parent fyne.Window) {
+	dialog.ShowInformation(a.text(title), a.text(message), parent)
+}
+
+// applyPlainLabels removes emoji from the text of obj and everything it
+// contains, when plain labels mode is on. Select options are left alone as
+// the code matches on them.
+func (a *App) applyPlainLabels(obj fyne.CanvasObject) {
+	if !a.config.PlainLabels || obj == nil {
+		return
+	}
+	switch o := obj.(type) {
+	case *fyne.Container:
+		for _, child := range o.Objects {
+			a.applyPlainLabels(child)
+		}
+	case *container.AppTabs:
+		for _, item := range o.Items {
+			item.Text = a.text(item.Text)
+			a.applyPlainLabels(item.Content)
+		}
+		o.Refresh()
+	case *container.Scroll:
+		a.applyPlainLabels(o.Content)
+	case *widget.Label:
+		o.SetText(a.text(o.Text))
+	case *widget.Button:
+		o.SetText(a.text(o.Text))
+	case *widget.Check:
+		o.Text = a.text(o.Text)
+		o.Refresh()
+	case *widget.Entry:
+		o.SetPlaceHolder(a.text(o.PlaceHolder))
+	case *widget.Select:
+		o.PlaceHolder = a.text(o.PlaceHolder)
+		o.Refresh()
+	case *widget.Hyperlink:
+		o.SetText(a.text(o.Text))
+	}
+}
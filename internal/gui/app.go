@@ -3,10 +3,14 @@
 package gui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"fyne.io/fyne/v2"
@@ -16,9 +20,14 @@ import (
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 
-	"github.com/lia/liacheckscanner_go/internal/extractor"
+	"github.com/lia/liacheckscanner_go/internal/config"
+	"github.com/lia/liacheckscanner_go/internal/diagnostics"
 	"github.com/lia/liacheckscanner_go/internal/logger"
-	"github.com/lia/liacheckscanner_go/internal/models"
+	"github.com/lia/liacheckscanner_go/internal/server"
+	"github.com/lia/liacheckscanner_go/internal/store"
+	"github.com/lia/liacheckscanner_go/pkg/events"
+	"github.com/lia/liacheckscanner_go/pkg/extractor"
+	"github.com/lia/liacheckscanner_go/pkg/models"
 )
 
 // App represents the main application structure, managing the GUI, data, and user interactions.
@@ -27,59 +36,135 @@ type App struct {
 	mainWindow fyne.Window
 	logger     *logger.Logger
 	config     *models.AppConfig
-	extractor  *extractor.Extractor
+	extractor  Backend
+	events     *events.Bus
+	server     *server.Server // REST API, nil unless Database.EnableAPI
+	crash      *diagnostics.Reporter
+	store      *store.Store // SQLite copy of data, nil when it cannot be opened
 	data       []models.ScannerData
+	stats      *DatasetStats // dashboard counters, kept in step with data
+
+	// The dataset shown from the record store is read into data only when
+	// an action needs all of it (see dataset); stored is set until then.
+	// storedSums fingerprints the records last written to the store, so
+	// saveStore writes only the records that changed.
+	dataMu     sync.Mutex
+	stored     bool
+	storedSums map[string]uint64
+
+	// Sample loaded instead of the whole dataset, nil otherwise: data then
+	// holds its records and is neither stored nor saved as a run
+	sample *extractor.Sample
+
+	// Main tabs, whose content is built on first selection (see buildTab)
+	tabs        *container.AppTabs
+	tabsMu      sync.Mutex
+	tabBuilders map[*container.TabItem]func() fyne.CanvasObject
+	dataOnce    sync.Once // first load of data, see loadDataOnce
 
 	// UI Components
-	dataTable    *widget.Table
-	statusBar    *widget.Label
-	statsLabel   *widget.Label
-	headerLabels []*widget.Label
+	records    *recordTable // Database tab table over data
+	statusBar  *widget.Label
+	statsLabel *widget.Label
+	rateLabel  *widget.Label // dashboard request rates per provider
+
+	// Badge listing the watched or enforced IPs about to be retired
+	expiryBtn      *widget.Button
+	expiryWarnings []extractor.ExpiryWarning
+
+	// Progress widgets driven by RecordsEnriched events
+	progress       *widget.ProgressBar
+	progressDetail *widget.Label
+	rateStatus     *widget.Label // request rates summed up, under the progress
 
 	// Search components
-	searchEntry        *widget.Entry
-	searchResultsTable *widget.Table
-	enrichmentText     *widget.Entry
-	searchStatsLabel   *widget.Label
-	searchResults      []models.ScannerData
-
-	// Pagination
-	itemsPerPage   int
-	currentPage    int
-	totalPages     int
-	paginationInfo *widget.Label
-
-	// Selection
-	selectedRow  int
-	selectedRows map[int]bool
-
-	// RDAP enrichment function
+	searchEntry      *widget.Entry
+	searchTable      *recordTable // Search tab table over searchResults
+	enrichmentText   *widget.Entry
+	searchStatsLabel *widget.Label
+	searchResults    []models.ScannerData
+
+	// Logs tab filters ("All" disables a filter)
+	logDisplay      *widget.Entry
+	logLevelFilter  string
+	logModuleFilter string
+	// Recording level selects of the Config and Logs tabs, kept in sync
+	// by setLogLevel
+	logLevelSelects []*widget.Select
+
+	// RDAP enrichment function, and the job it starts with a given tracker
 	startRDAPEnrichment func(int)
+	runRDAPJob          func(*models.RDAPProgressTracker, int)
+
+	// Operations announced with setBusy that have not ended yet
+	busy atomic.Int32
+
+	// Context shared by the running extractions and enrichments, canceled
+	// by the ⛔ Annuler button (see runContext)
+	runMu     sync.Mutex
+	runCtx    context.Context
+	cancelRun context.CancelFunc
 }
 
 // NewApp creates a new App instance, initializing the GUI window, extractor, and user interface.
 func NewApp(config *models.AppConfig, logger *logger.Logger) *App {
+	return NewAppWithBackend(config, logger, extractor.NewExtractor(config.Database, logger))
+}
+
+// NewAppWithBackend creates an App driving backend instead of an extractor
+// of its own, e.g. one serving canned data or another instance.
+func NewAppWithBackend(config *models.AppConfig, logger *logger.Logger, backend Backend) *App {
 	fyneApp := app.New()
 	fyneApp.SetIcon(theme.ComputerIcon())
+	return newApp(fyneApp, config, logger, backend)
+}
 
+// newApp builds the App on fyneApp, which tests replace with Fyne's
+// headless test application.
+func newApp(fyneApp fyne.App, config *models.AppConfig, logger *logger.Logger, backend Backend) *App {
 	app := &App{
-		fyneApp: fyneApp,
-		logger:  logger,
-		config:  config,
-		// Initialize pagination with sensible defaults
-		itemsPerPage: 100,
-		currentPage:  1,
-		totalPages:   1,
-		selectedRow:  -1,
-		selectedRows: make(map[int]bool),
-	}
-
-	app.mainWindow = fyneApp.NewWindow("🔍 LiaCheckScanner")
+		fyneApp:     fyneApp,
+		logger:      logger,
+		config:      config,
+		stats:       NewDatasetStats(nil),
+		crash:       diagnostics.New(config, logger),
+		tabBuilders: map[*container.TabItem]func() fyne.CanvasObject{},
+	}
+
+	app.applyTextScale()
+	app.mainWindow = fyneApp.NewWindow(app.text("🔍 LiaCheckScanner"))
 	app.mainWindow.Resize(fyne.NewSize(1600, 1000)) // Larger window for better UX
 	app.mainWindow.CenterOnScreen()
 
-	// Initialize extractor
-	app.extractor = extractor.NewExtractor(config.Database, logger)
+	// Subscribe to the progress events of the backend
+	app.extractor = backend
+	app.events = app.extractor.Events()
+	app.events.Subscribe(logger.HandleEvent)
+	app.events.Subscribe(app.handleEvent)
+	app.extractor.SetPanicHandler(app.crash.HandlePanic)
+
+	// Optional REST API sharing the extractor's stores
+	if ext, ok := backend.(*extractor.Extractor); config.Database.EnableAPI && !ok {
+		logger.Warning("GUI", "API server not started: it serves the local extractor only")
+	} else if config.Database.EnableAPI {
+		app.server = server.New(config, ext, logger)
+		if err := app.server.Start(); err != nil {
+			logger.Error("GUI", "API server failed to start: "+err.Error())
+			app.server = nil
+		}
+	}
+
+	// Records persisted between sessions and searched with SQL
+	if s, err := store.Open(config.Database.StorePath); err != nil {
+		logger.Warning("GUI", "Record store unavailable, records kept in memory only: "+err.Error())
+	} else {
+		app.store = s
+	}
+
+	// Record tables shared by the Database and Search tabs
+	app.records = app.newRecordTable(app.dataset, app.enrichListed, "page")
+	app.records.count, app.records.fetch = app.storeCount, app.storePage
+	app.searchTable = app.newRecordTable(func() []models.ScannerData { return app.searchResults }, app.enrichSearchResult, "search")
 
 	// Create the interface
 	app.createUI()
@@ -90,18 +175,21 @@ func NewApp(config *models.AppConfig, logger *logger.Logger) *App {
 // createUI builds the complete user interface
 // It creates tabs, widgets, and sets up the layout
 func (a *App) createUI() {
-	// Create main tabs
+	// Create main tabs; only the dashboard is built before it is shown
 	tabs := container.NewAppTabs(
-		container.NewTabItem("📊 Dashboard", a.createDashboardTab()),
-		container.NewTabItem("🗄️ Database", a.createDatabaseTab()),
-		container.NewTabItem("🔍 Search", a.createSearchTab()),
-		container.NewTabItem("⚙️ Configuration", a.createConfigTab()),
-		container.NewTabItem("📋 Logs", a.createLogsTab()),
+		container.NewTabItem(tabDashboard, a.createDashboardTab()),
+		a.newLazyTab(tabDatabase, a.createDatabaseTab),
+		a.newLazyTab(tabSearch, a.createSearchTab),
+		a.newLazyTab(tabRuns, a.createRunsTab),
+		a.newLazyTab(tabConfig, a.createConfigTab),
+		a.newLazyTab(tabLogs, a.createLogsTab),
 	)
+	a.tabs = tabs
 
 	// Set tab properties for better UX
 	tabs.SetTabLocation(container.TabLocationTop)
 	tabs.SelectTabIndex(0) // Start with dashboard
+	tabs.OnSelected = a.onTabSelected
 
 	// Create status bar
 	a.statusBar = widget.NewLabel("🟢 Ready")
@@ -117,14 +205,18 @@ func (a *App) createUI() {
 		tabs,
 	)
 
+	a.applyPlainLabels(mainContainer)
 	a.mainWindow.SetContent(mainContainer)
 	a.mainWindow.Show()
 
-	// Load existing data - try CSV first, then extract if needed
-	go func() {
-		a.logger.Info("GUI", "🔍 Initializing data...")
-		a.loadData() // This will try CSV first, then auto-extract if needed
-	}()
+	if err := config.Validate(a.config); err != nil {
+		a.showConfigProblems(err)
+	}
+
+	// Data is loaded when the Database tab is first shown (see loadDataOnce)
+	go a.runGeoBackfill()
+	go a.runAutoUpdate()
+	go a.runRateMetrics()
 }
 
 // createDashboardTab creates the main dashboard with statistics and overview
@@ -144,9 +236,14 @@ func (a *App) createDashboardTab() fyne.CanvasObject {
 	statsTitle := widget.NewLabel("📈 Real-time Statistics")
 	statsTitle.TextStyle = fyne.TextStyle{Bold: true}
 
-	a.statsLabel = widget.NewLabel("Loading statistics...")
+	a.statsLabel = widget.NewLabel("Open the Database tab or refresh to load the records")
 	a.statsLabel.TextStyle = fyne.TextStyle{Bold: true}
 
+	// Request rates, redrawn by runRateMetrics
+	ratesTitle := widget.NewLabel("🚦 Request Rates")
+	ratesTitle.TextStyle = fyne.TextStyle{Bold: true}
+	a.rateLabel = widget.NewLabel("")
+
 	// Quick actions
 	actionsTitle := widget.NewLabel("⚡ Quick Actions")
 	actionsTitle.TextStyle = fyne.TextStyle{Bold: true}
@@ -156,15 +253,16 @@ func (a *App) createDashboardTab() fyne.CanvasObject {
 		a.refreshData()
 	})
 
+	sampleBtn := widget.NewButton("🎲 Load Sample", func() {
+		a.chooseSample()
+	})
+
 	exportBtn := widget.NewButton("📤 Export All", func() {
 		a.exportAllData()
 	})
 
 	searchBtn := widget.NewButton("🔍 Advanced Search", func() {
-		// Switch to search tab
-		if tabs := a.mainWindow.Content().(*fyne.Container).Objects[0].(*container.AppTabs); tabs != nil {
-			tabs.SelectTabIndex(2) // Search tab
-		}
+		a.selectTab(tabSearch)
 	})
 
 	// Professional info section
@@ -184,9 +282,13 @@ func (a *App) createDashboardTab() fyne.CanvasObject {
 		statsTitle,
 		a.statsLabel,
 		widget.NewSeparator(),
+		ratesTitle,
+		a.rateLabel,
+		widget.NewSeparator(),
 		actionsTitle,
 		container.NewHBox(
 			refreshBtn,
+			sampleBtn,
 			exportBtn,
 			searchBtn,
 		),
@@ -201,55 +303,35 @@ func (a *App) createDashboardTab() fyne.CanvasObject {
 // updatePagination updates pagination state and refreshes the interface
 // It calculates page numbers, validates current page, and updates the display
 func (a *App) updatePagination() {
-	totalPages, validPage, startIndex, endIndex := CalculatePagination(
-		len(a.data), a.itemsPerPage, a.currentPage,
-	)
-	a.totalPages = totalPages
-	a.currentPage = validPage
-
-	// Update pagination info
-	if a.paginationInfo != nil {
-		a.paginationInfo.SetText(fmt.Sprintf("Page %d of %d (%d-%d of %d records)",
-			a.currentPage, a.totalPages, startIndex+1, endIndex, len(a.data)))
-	}
-
-	// Refresh table
-	if a.dataTable != nil {
-		a.dataTable.Refresh()
-		// Re-apply widths/heights for current page
-		a.applyTableLayout()
-	}
-
+	a.records.Refresh()
 	a.logger.Info("GUI", fmt.Sprintf("📄 Pagination updated: page %d/%d (%d records)",
-		a.currentPage, a.totalPages, len(a.data)))
+		a.records.currentPage, a.records.totalPages, a.records.size()))
 }
 
-// loadData loads data from CSV file or triggers extraction if none valid
-// It prioritizes loading from the latest CSV file in the results directory
+// loadData shows the dataset of the record store, else loads the newest
+// CSV file in the results directory, else triggers an extraction
 func (a *App) loadData() {
-	// Try to load from CSV files (newest first)
-	csvFiles, err := filepath.Glob("results/*.csv")
-	if err == nil && len(csvFiles) > 0 {
-		// Sort by modification time (newest first)
-		sort.Slice(csvFiles, func(i, j int) bool {
-			infoI, _ := os.Stat(csvFiles[i])
-			infoJ, _ := os.Stat(csvFiles[j])
-			return infoI.ModTime().After(infoJ.ModTime())
-		})
+	if a.store != nil {
+		if n, err := a.store.Count(store.Query{}); err != nil {
+			a.logger.Warning("GUI", "Record store not read: "+err.Error())
+		} else if n > 0 {
+			a.sample = nil
+			a.showStored()
+			a.logger.Info("GUI", fmt.Sprintf("✅ %d records shown from %s", n, a.store.Path()))
+			return
+		}
+	}
 
+	// Try to load from CSV files (newest first)
+	if csvFiles := a.csvFilesNewestFirst(); len(csvFiles) > 0 {
 		for _, f := range csvFiles {
 			a.logger.Info("GUI", "📂 Loading data from: "+f)
 			if data, err := a.loadFromCSV(f); err == nil && len(data) > 0 {
-				a.data = data
-				a.currentPage = 1
+				a.setData(data)
 				a.logger.Info("GUI", fmt.Sprintf("✅ %d records loaded from %s", len(a.data), f))
-				if a.dataTable != nil {
-					a.dataTable.Refresh()
-					// Apply column/row layout after load
-					a.applyTableLayout()
+				if w := a.verifyDataset(f); w != "" {
+					a.showInformation("Intégrité", "⚠️ "+w, a.mainWindow)
 				}
-				a.updatePagination()
-				a.updateStats()
 				return
 			} else if err != nil {
 				a.logger.Warning("GUI", "CSV load error for "+f+": "+err.Error())
@@ -260,33 +342,611 @@ func (a *App) loadData() {
 	// No valid CSV: trigger extraction automatically
 	a.logger.Warning("GUI", "No valid CSV found; running extraction...")
 	go func() {
-		if _, err := a.extractor.ExtractData(); err != nil {
+		defer a.crash.Recover("GUI")
+		if err := a.extractAndQueue(); err != nil {
 			a.logger.Error("GUI", "Extraction failed: "+err.Error())
 			dialog.ShowError(err, a.mainWindow)
-			return
 		}
-		// Reload after extraction
-		a.logger.Info("GUI", "Reloading data after extraction...")
-		a.loadData()
-		if a.dataTable != nil {
-			a.dataTable.Refresh()
-			// Apply column/row layout after load
-			a.applyTableLayout()
+	}()
+}
+
+// csvFilesNewestFirst returns the CSV files of the results directory,
+// newest first
+func (a *App) csvFilesNewestFirst() []string {
+	csvFiles, err := filepath.Glob(filepath.Join(a.resultsDir(), "*.csv"))
+	if err != nil {
+		return nil
+	}
+	// Sort by modification time (newest first)
+	sort.Slice(csvFiles, func(i, j int) bool {
+		infoI, _ := os.Stat(csvFiles[i])
+		infoJ, _ := os.Stat(csvFiles[j])
+		return infoI.ModTime().After(infoJ.ModTime())
+	})
+	return csvFiles
+}
+
+// offerJobResume asks whether to resume the jobs the previous session left
+// unfinished: the RDAP association of the whole dataset, and the jobs whose
+// state the extractor kept (see extractor.JobState).
+func (a *App) offerJobResume() {
+	tracker := a.extractor.LoadProgressTracker()
+	if !tracker.Completed && len(tracker.ProcessedIPs) > 0 && a.recordCount() > 0 && a.startRDAPEnrichment != nil {
+		dialog.ShowConfirm("Reprise RDAP",
+			fmt.Sprintf("L'association RDAP de l'ensemble du dataset a été interrompue (%d/%d IPs traitées).\n\nSouhaitez-vous la reprendre?",
+				tracker.ProcessedRecords, tracker.TotalRecords),
+			func(resume bool) {
+				if !resume {
+					_ = a.extractor.ClearProgressTracker()
+					return
+				}
+				a.startRDAPEnrichment(tracker.ProcessedRecords)
+			}, a.mainWindow)
+	}
+
+	jobs, err := a.extractor.InterruptedJobs()
+	if err != nil {
+		a.logger.Warning("GUI", "Interrupted jobs not checked: "+err.Error())
+		return
+	}
+	for _, job := range jobs {
+		job := job
+		a.logger.Warning("GUI", "Interrupted job found: "+JobLabel(job))
+		dialog.ShowConfirm("Reprise",
+			fmt.Sprintf("Un traitement a été interrompu:\n%s\n\nSouhaitez-vous le reprendre?", JobLabel(job)),
+			func(resume bool) {
+				if !resume {
+					_ = a.extractor.ClearJobState(job.Kind)
+					return
+				}
+				a.resumeJob(job)
+			}, a.mainWindow)
+	}
+}
+
+// resumeJob runs an interrupted job again on its IPs. A bulk re-enrichment
+// resumes on the dataset records with those IPs; an enrichment run replaces
+// the dataset with its result, as the CLI -resume flag does.
+func (a *App) resumeJob(job extractor.JobState) {
+	a.logger.Info("GUI", "🔄 Resuming "+JobLabel(job))
+	if job.Kind == extractor.JobBulkEnrich {
+		ips := map[string]bool{}
+		for _, ip := range job.IPs {
+			ips[ip] = true
 		}
-		a.updatePagination()
-		a.updateStats()
+		keys := map[string]bool{}
+		for _, item := range a.dataset() {
+			if ips[item.IPOrCIDR] {
+				keys[RecordKey(item)] = true
+			}
+		}
+		a.bulkEnrich(keys)
+		return
+	}
+	a.setBusy(true, "Reprise de l'enrichissement...")
+	go func() {
+		defer a.crash.Recover("GUI")
+		data, err := a.extractor.EnrichIPs(a.runContext(), job.IPs)
+		a.setBusy(false, "")
+		if err != nil {
+			dialog.ShowError(err, a.mainWindow)
+			return
+		}
+		a.setData(data)
+		a.showInformation("Reprise", fmt.Sprintf("✅ %d enregistrements enrichis", len(data))+a.saveBulkRun(), a.mainWindow)
 	}()
 }
 
-// setBusy updates statusBar with a busy/ready message
-func (a *App) setBusy(busy bool, message string) {
-	if a.statusBar == nil {
+// setData replaces the dataset shown by the GUI and the API server, with
+// annotations and honeypot hits applied, and makes it the content of the
+// record store. The locked records of the dataset replaced are kept as they
+// were.
+func (a *App) setData(data []models.ScannerData) {
+	a.sample = nil
+	a.useData(extractor.KeepLocked(a.dataset(), data))
+	a.replaceStore()
+	a.resetView()
+}
+
+// editData replaces the records shown after an edit of many of them, such
+// as a deletion, and writes the records changed to the record store unless
+// a sample is loaded
+func (a *App) editData(data []models.ScannerData) {
+	a.useData(data)
+	a.saveStore()
+	a.resetView()
+}
+
+// errSampleLoaded is returned when saving a sample as the whole dataset
+var errSampleLoaded = errors.New("a sample is loaded, not the whole dataset; use Refresh Data to load it")
+
+// saveRun saves the dataset as a new run, unless a sample is loaded
+func (a *App) saveRun() (string, error) {
+	if a.sample != nil {
+		return "", errSampleLoaded
+	}
+	return a.extractor.SaveRun(a.dataset())
+}
+
+// replaceStore makes the dataset the whole content of the record store, for
+// a new dataset; a sample would replace the stored dataset, so it is not
+// written
+func (a *App) replaceStore() {
+	if a.store == nil || a.sample != nil {
+		return
+	}
+	if err := a.store.Replace(a.data); err != nil {
+		a.logger.Warning("GUI", "Record store not updated: "+err.Error())
+		return
+	}
+	a.storedSums = RecordSums(a.data)
+}
+
+// saveStore writes to the record store the records of the dataset added or
+// changed since they were stored, and deletes the records removed from the
+// dataset, after changes to many records. Nothing is written while the
+// dataset is not read from the store, nor while a sample is loaded.
+func (a *App) saveStore() {
+	if a.store == nil || a.sample != nil || a.stored {
+		return
+	}
+	changed, removed, sums := StoreChanges(a.storedSums, a.data)
+	if len(removed) > 0 {
+		if err := a.store.Delete(removed...); err != nil {
+			a.logger.Warning("GUI", "Record store not updated: "+err.Error())
+			return
+		}
+	}
+	if len(changed) > 0 {
+		if err := a.store.Upsert(changed...); err != nil {
+			a.logger.Warning("GUI", "Record store not updated: "+err.Error())
+			return
+		}
+	}
+	a.storedSums = sums
+	a.logger.Debug("GUI", fmt.Sprintf("Record store: %d records written, %d deleted", len(changed), len(removed)))
+}
+
+// storeRecords writes changed or added records to the record store
+func (a *App) storeRecords(items ...models.ScannerData) {
+	if a.store == nil {
+		return
+	}
+	if err := a.store.Upsert(items...); err != nil {
+		a.logger.Warning("GUI", "Record store not updated: "+err.Error())
+		return
+	}
+	if a.storedSums != nil {
+		for key, sum := range RecordSums(items) {
+			a.storedSums[key] = sum
+		}
+	}
+}
+
+// updateRecord copies item over the records of the dataset with its key,
+// when the dataset is read, and writes it to the record store
+func (a *App) updateRecord(item models.ScannerData) {
+	key := RecordKey(item)
+	for i := range a.data {
+		if RecordKey(a.data[i]) == key {
+			a.data[i] = item
+		}
+	}
+	a.storeRecords(item)
+}
+
+// updateRecordsOf applies fn to the records of ip, in the dataset or, while
+// it is not read, in the record store, reports the change to the dashboard
+// counters, writes the records to the store and returns them
+func (a *App) updateRecordsOf(ip string, fn func(*models.ScannerData)) []models.ScannerData {
+	var updated []models.ScannerData
+	if a.stored {
+		items, err := a.store.Search(store.Query{Text: ip})
+		if err != nil {
+			a.logger.Warning("GUI", "Record store not read: "+err.Error())
+			return nil
+		}
+		for i := range items {
+			if items[i].IPOrCIDR == ip {
+				old := items[i]
+				fn(&items[i])
+				a.stats.Replace(old, items[i])
+				updated = append(updated, items[i])
+			}
+		}
+	} else {
+		for i := range a.data {
+			if a.data[i].IPOrCIDR == ip {
+				old := a.data[i]
+				fn(&a.data[i])
+				a.stats.Replace(old, a.data[i])
+				updated = append(updated, a.data[i])
+			}
+		}
+	}
+	if len(updated) > 0 {
+		a.storeRecords(updated...)
+	}
+	return updated
+}
+
+// addRecord appends item to the dataset, when it is read, and to the record
+// store
+func (a *App) addRecord(item models.ScannerData) {
+	a.dataMu.Lock()
+	if !a.stored {
+		a.data = append(a.data, item)
+	}
+	a.dataMu.Unlock()
+	a.stats.Add(item)
+	a.storeRecords(item)
+}
+
+// errNoStore is returned by searchStore when the record store is not open
+var errNoStore = errors.New("no record store")
+
+// searchStore returns the records of the store matching q. The store holds
+// the whole dataset, so it is not searched while a sample is loaded: the
+// error is then errSampleLoaded.
+func (a *App) searchStore(q store.Query) ([]models.ScannerData, error) {
+	if a.store == nil {
+		return nil, errNoStore
+	}
+	if a.sample != nil {
+		return nil, errSampleLoaded
+	}
+	return a.store.Search(q)
+}
+
+// storeChunk is how many records are read from the record store at once
+// when going over all of them
+const storeChunk = 5000
+
+// eachStored calls fn on the records of the store in dataset order,
+// storeChunk records at a time
+func (a *App) eachStored(fn func([]models.ScannerData)) error {
+	for offset := 0; ; offset += storeChunk {
+		items, err := a.store.Search(store.Query{Offset: offset, Limit: storeChunk})
+		if err != nil {
+			return err
+		}
+		if len(items) > 0 {
+			fn(items)
+		}
+		if len(items) < storeChunk {
+			return nil
+		}
+	}
+}
+
+// dataset returns the whole dataset. While the Database tab pages through
+// the record store, the dataset is read from the store the first time an
+// action needs all of it, e.g. an export or the RDAP job over the dataset.
+func (a *App) dataset() []models.ScannerData {
+	a.dataMu.Lock()
+	defer a.dataMu.Unlock()
+	if !a.stored {
+		return a.data
+	}
+	data, err := a.store.All()
+	if err != nil {
+		a.logger.Warning("GUI", "Record store not read: "+err.Error())
+		return nil
+	}
+	// Fingerprinted as stored, so the next save writes what preparing changed
+	sums := RecordSums(data)
+	a.prepare(data)
+	a.data, a.stored, a.storedSums = data, false, sums
+	a.logger.Info("GUI", fmt.Sprintf("%d records read from %s", len(data), a.store.Path()))
+	return a.data
+}
+
+// recordCount returns the number of records of the dataset, counted in the
+// record store while the dataset is not read
+func (a *App) recordCount() int {
+	if !a.stored {
+		return len(a.data)
+	}
+	n, err := a.store.Count(store.Query{})
+	if err != nil {
+		a.logger.Warning("GUI", "Record store not read: "+err.Error())
+	}
+	return n
+}
+
+// prepare applies to data the annotations, locks, honeypot hits, aging and
+// policies of the extractor, and attributes the unknown scanners
+func (a *App) prepare(data []models.ScannerData) {
+	a.preparePage(data)
+	if err := a.extractor.ApplyAging(data, time.Now()); err != nil {
+		a.logger.Warning("GUI", "Reputation aging not applied: "+err.Error())
+	}
+	a.extractor.ApplyPrefixPolicy(data)
+	a.extractor.ApplyEnforcementPolicy(data)
+	a.extractor.AttributeScanners(data)
+}
+
+// preparePage applies to records read from the store the annotations,
+// locks and honeypot hits, which change between two saves. The aging,
+// policies and attribution are kept as the dataset was stored with them,
+// since attribution learns from the whole dataset.
+func (a *App) preparePage(data []models.ScannerData) {
+	if err := a.extractor.ApplyAnnotations(data); err != nil {
+		a.logger.Warning("GUI", "Annotations not applied: "+err.Error())
+	}
+	if err := a.extractor.ApplyLocks(data); err != nil {
+		a.logger.Warning("GUI", "Record locks not applied: "+err.Error())
+	}
+	if err := a.extractor.ApplyHits(data); err != nil {
+		a.logger.Warning("GUI", "Honeypot hits not applied: "+err.Error())
+	}
+}
+
+// storeCount returns the number of records of the record store, and false
+// when the Database tab shows data instead: no store or a sample loaded
+func (a *App) storeCount() (int, bool) {
+	if a.store == nil || a.sample != nil {
+		return 0, false
+	}
+	n, err := a.store.Count(store.Query{})
+	if err != nil {
+		a.logger.Warning("GUI", "Record store not read: "+err.Error())
+		return 0, false
+	}
+	return n, true
+}
+
+// storePage reads limit records of the record store from offset on, for
+// the page of the Database tab
+func (a *App) storePage(offset, limit int) ([]models.ScannerData, error) {
+	items, err := a.store.Search(store.Query{Offset: offset, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	a.preparePage(items)
+	return items, nil
+}
+
+// showData replaces the dataset shown by the GUI and the API server, with
+// annotations and honeypot hits applied
+func (a *App) showData(data []models.ScannerData) {
+	a.useData(data)
+	a.resetView()
+}
+
+// showStored shows the dataset of the record store without reading it in
+// memory: the Database tab pages through the store, and the counters are
+// computed going over it. The API server serves the whole dataset, so it
+// is read when the server runs.
+func (a *App) showStored() {
+	a.dataMu.Lock()
+	a.data, a.stored, a.storedSums = nil, true, nil
+	a.dataMu.Unlock()
+	a.stats.Reset(nil)
+	if err := a.eachStored(func(items []models.ScannerData) { a.stats.Add(items...) }); err != nil {
+		a.logger.Warning("GUI", "Record store not read: "+err.Error())
+	}
+	if a.server != nil {
+		a.server.SetRecords(a.dataset())
+	}
+	a.resetView()
+}
+
+// useData makes data, with annotations and honeypot hits applied, the
+// dataset of the GUI and the API server
+func (a *App) useData(data []models.ScannerData) {
+	a.prepare(data)
+	a.dataMu.Lock()
+	a.data, a.stored = data, false
+	a.dataMu.Unlock()
+	a.stats.Reset(data)
+	// The API keeps serving the whole dataset while a sample is explored
+	if a.server != nil && a.sample == nil {
+		a.server.SetRecords(data)
+	}
+}
+
+// resetView shows the first page of the dataset, its counters and its
+// expiry badge
+func (a *App) resetView() {
+	a.records.currentPage = 1
+	a.records.resetSelection()
+	a.updatePagination()
+	a.updateStats()
+	a.checkExpiry()
+}
+
+// checkExpiry shows the expiry badge when IPs on the watchlist or in the
+// enforcement list are about to be retired, and sends them to the expiry
+// webhook
+func (a *App) checkExpiry() {
+	warnings, err := a.extractor.ExpiryWarnings(a.expiryRecords(), time.Now())
+	if err != nil {
+		a.logger.Warning("GUI", "Expiry check failed: "+err.Error())
+		return
+	}
+	a.expiryWarnings = warnings
+	if a.expiryBtn != nil {
+		if len(warnings) == 0 {
+			a.expiryBtn.Hide()
+		} else {
+			a.expiryBtn.SetText(a.text(fmt.Sprintf("⏳ Expirations (%d)", len(warnings))))
+			a.expiryBtn.Show()
+		}
+	}
+	if len(warnings) == 0 {
 		return
 	}
+	go func() {
+		defer a.crash.Recover("GUI")
+		if _, err := a.extractor.NotifyExpiry(a.runContext(), warnings); err != nil {
+			a.logger.Warning("GUI", "Expiry webhook failed: "+err.Error())
+		}
+	}()
+}
+
+// expiryRecords returns the records the expiry check needs: the dataset,
+// or while it is not read the most recent stored record of each IP
+func (a *App) expiryRecords() []models.ScannerData {
+	if !a.stored {
+		return a.data
+	}
+	latest := map[string]models.ScannerData{}
+	err := a.eachStored(func(items []models.ScannerData) {
+		for _, item := range items {
+			if cur, ok := latest[item.IPOrCIDR]; !ok || item.LastSeen.After(cur.LastSeen) {
+				latest[item.IPOrCIDR] = models.ScannerData{IPOrCIDR: item.IPOrCIDR, ScannerName: item.ScannerName, LastSeen: item.LastSeen}
+			}
+		}
+	})
+	if err != nil {
+		a.logger.Warning("GUI", "Record store not read: "+err.Error())
+	}
+	out := make([]models.ScannerData, 0, len(latest))
+	for _, item := range latest {
+		out = append(out, item)
+	}
+	return out
+}
+
+// extractAndQueue extracts and saves the base records, shows them at once
+// and queues them on the background RDAP job, which fills the fields in
+// row by row. Nothing is queued in extraction-only mode. With a remote API
+// configured the dataset is pulled from it instead (see pullRemote).
+func (a *App) extractAndQueue() error {
+	if a.config.Database.RemoteAPIURL != "" {
+		return a.pullRemote()
+	}
+	data, err := a.extractor.ExtractBaseRecords(a.runContext())
+	if err != nil {
+		return err
+	}
+	a.setData(data)
+	a.logger.Info("GUI", fmt.Sprintf("✅ %d base records extracted", len(data)))
+	if a.config.Database.SkipEnrichment || a.startRDAPEnrichment == nil {
+		return nil
+	}
+	// A new dataset: progress left by an earlier job no longer applies
+	_ = a.extractor.ClearProgressTracker()
+	a.logger.Info("GUI", "⏳ RDAP enrichment queued in the background")
+	a.startRDAPEnrichment(0)
+	return nil
+}
+
+// pullRemote updates the dataset from the remote API, fetching only the
+// records changed since the last pull, and saves it for the next start.
+func (a *App) pullRemote() error {
+	data, received, err := a.extractor.SyncRemote(a.runContext(), a.dataset())
+	if err != nil {
+		return err
+	}
+	a.setData(data)
+	if _, err := a.extractor.SaveRun(data); err != nil {
+		a.logger.Warning("GUI", "CSV save error: "+err.Error())
+	}
+	a.logger.Info("GUI", fmt.Sprintf("🌐 %d updated records pulled from %s (%d in total)", received, a.config.Database.RemoteAPIURL, len(data)))
+	return nil
+}
+
+// setBusy announces the start or end of a GUI-driven operation on the event bus
+func (a *App) setBusy(busy bool, message string) {
 	if busy {
-		a.statusBar.SetText("⏳ " + message)
+		a.busy.Add(1)
+		a.events.Publish(events.Event{Type: events.RunStarted, Source: "GUI", Message: message})
 	} else {
-		a.statusBar.SetText("🟢 Ready")
+		a.busy.Add(-1)
+		a.events.Publish(events.Event{Type: events.RunCompleted, Source: "GUI"})
+	}
+}
+
+// runActive reports whether an operation of the GUI, an extraction or an
+// enrichment is running
+func (a *App) runActive() bool {
+	return a.busy.Load() > 0 || a.extractor.RunActive()
+}
+
+// newRDAPTracker returns the progress tracker of an RDAP job starting over
+// the whole dataset
+func (a *App) newRDAPTracker() *models.RDAPProgressTracker {
+	return &models.RDAPProgressTracker{
+		TotalRecords: a.recordCount(),
+		ProcessedIPs: []string{},
+		StartedAt:    time.Now().Format(time.RFC3339),
+		Workers:      a.config.Database.Parallelism,
+		Throttle:     a.config.Database.APIThrottle,
+		Completed:    false,
+	}
+}
+
+// runContext returns the context of the running operations, starting a new
+// one once the previous was canceled.
+func (a *App) runContext() context.Context {
+	a.runMu.Lock()
+	defer a.runMu.Unlock()
+	if a.runCtx == nil || a.runCtx.Err() != nil {
+		a.runCtx, a.cancelRun = context.WithCancel(context.Background())
+	}
+	return a.runCtx
+}
+
+// cancelRuns cancels every operation started with runContext, aborting the
+// requests in flight; the jobs keep their progress so they can be resumed.
+func (a *App) cancelRuns() {
+	a.runMu.Lock()
+	defer a.runMu.Unlock()
+	if a.cancelRun != nil {
+		a.cancelRun()
+		a.logger.Info("GUI", "⛔ Run canceled")
+	}
+}
+
+// handleEvent reflects bus events in the status bar and progress widgets
+func (a *App) handleEvent(ev events.Event) {
+	switch ev.Type {
+	case events.RunStarted:
+		a.setStatus("⏳ " + ev.Message)
+	case events.SourceProgress:
+		if a.progress != nil && ev.Total > 0 {
+			a.progress.SetValue(float64(ev.Count) / float64(ev.Total))
+		}
+		if a.progressDetail != nil {
+			a.progressDetail.SetText(a.text("Git - " + ev.Message))
+		}
+	case events.SourceSynced:
+		a.setStatus("⏳ Sources synchronisées")
+	case events.RecordsParsed:
+		a.setStatus(fmt.Sprintf("⏳ %d IPs extraites", ev.Count))
+	case events.RecordsEnriched:
+		if a.progress != nil && ev.Total > 0 {
+			a.progress.SetValue(float64(ev.Count) / float64(ev.Total))
+		}
+		if a.progressDetail != nil {
+			a.progressDetail.SetText(a.text(fmt.Sprintf("RDAP %d/%d - %s", ev.Count, ev.Total, ev.Message)))
+		}
+	case events.RecordUpdated:
+		if a.records != nil {
+			a.records.refreshRecord(ev.Message)
+		}
+		if a.searchTable != nil {
+			a.searchTable.refreshRecord(ev.Message)
+		}
+	case events.Warning:
+		if a.progressDetail != nil {
+			a.progressDetail.SetText(a.text("⚠️ " + ev.Message))
+		}
+	case events.RunCompleted:
+		a.setStatus("🟢 Ready")
+	case events.RunFailed:
+		a.setStatus("❌ " + ev.Message)
+	case events.ConfigApplied:
+		a.setStatus("⚙️ Configuration appliquée")
+	}
+}
+
+// setStatus updates the status bar text if it has been created
+func (a *App) setStatus(text string) {
+	if a.statusBar != nil {
+		a.statusBar.SetText(a.text(text))
 	}
 }
 
@@ -294,7 +954,9 @@ func (a *App) setBusy(busy bool, message string) {
 // It attempts to load from CSV files first, then falls back to extraction
 func (a *App) loadExistingData() error {
 	// Use the new loadData function that loads from CSV or extracts
-	a.loadData()
+	if !a.loadDataOnce() {
+		a.loadData()
+	}
 	return nil
 }
 
@@ -303,14 +965,9 @@ func (a *App) loadExistingData() error {
 func (a *App) refreshData() {
 	a.logger.Info("GUI", "🔄 Refreshing data...")
 
-	// Reload data
-	a.loadData()
-
-	// Refresh interface
-	if a.dataTable != nil {
-		a.dataTable.Refresh()
-		// Apply column/row layout after load
-		a.applyTableLayout()
+	// Reload data, unless this is the first load of the session
+	if !a.loadDataOnce() {
+		a.loadData()
 	}
 
 	// Update pagination
@@ -319,13 +976,15 @@ func (a *App) refreshData() {
 	// Update statistics
 	a.updateStats()
 
-	a.logger.Info("GUI", fmt.Sprintf("✅ %d records displayed", len(a.data)))
+	a.logger.Info("GUI", fmt.Sprintf("✅ %d records displayed", a.recordCount()))
 }
 
 // updateStats updates the statistics display with current data information
-// It provides real-time statistics for the professional dashboard
+// It reads the incremental counters, so it is cheap enough to call after
+// every record update
 func (a *App) updateStats() {
 	if a.statsLabel != nil {
+		s := a.stats.Snapshot()
 		stats := fmt.Sprintf(`📊 Real-time Statistics:
 • Total Records: %d
 • Unique IPs: %d
@@ -333,41 +992,113 @@ func (a *App) updateStats() {
 • Scanners: %d
 • High Risk: %d
 • Last Updated: %s`,
-			len(a.data),
-			a.countUniqueIPs(),
-			a.countUniqueCountries(),
-			a.countUniqueScanners(),
-			a.countHighRisk(),
+			s.Total,
+			s.UniqueIPs,
+			s.Countries,
+			s.Scanners,
+			s.HighRisk,
 			time.Now().Format("2006-01-02 15:04:05"))
+		if a.sample != nil {
+			stats = SampleNotice(*a.sample) + "\n" + stats
+		}
 
 		a.statsLabel.SetText(stats)
 	}
 }
 
-// countUniqueIPs counts unique IP addresses in the dataset
-func (a *App) countUniqueIPs() int { return CountUniqueIPs(a.data) }
+// enrichRecord enriches a.data[idx] and reports the change to the
+// dashboard counters
+func (a *App) enrichRecord(ctx context.Context, idx int, delayMs int) error {
+	item := &a.data[idx]
+	old := *item
+	err := a.extractor.EnrichRecordWithDelay(ctx, item, delayMs)
+	a.stats.Replace(old, *item)
+	a.storeRecords(*item)
+	a.publishRecordUpdated(item.IPOrCIDR)
+	return err
+}
 
-// countUniqueCountries counts unique countries in the dataset
-func (a *App) countUniqueCountries() int { return CountUniqueCountries(a.data) }
+// publishRecordUpdated announces that the records for ip changed, so the
+// tables redraw those rows as soon as they are enriched
+func (a *App) publishRecordUpdated(ip string) {
+	a.events.Publish(events.Event{Type: events.RecordUpdated, Source: "GUI", Message: ip})
+}
 
-// countUniqueScanners counts unique scanners in the dataset
-func (a *App) countUniqueScanners() int { return CountUniqueScanners(a.data) }
+// enrichListed enriches the record at idx of the Database table, which
+// pages through the record store unless a sample is loaded, and copies the
+// result to the dataset when it is read
+func (a *App) enrichListed(ctx context.Context, idx int, delayMs int) error {
+	if !a.records.paged() {
+		return a.enrichRecord(ctx, idx, delayMs)
+	}
+	item, ok := a.records.record(idx)
+	if !ok {
+		return fmt.Errorf("no record %d in the record store", idx)
+	}
+	old := item
+	err := a.extractor.EnrichRecordWithDelay(ctx, &item, delayMs)
+	a.stats.Replace(old, item)
+	a.updateRecord(item)
+	a.publishRecordUpdated(item.IPOrCIDR)
+	return err
+}
 
-// countHighRisk counts high-risk entries in the dataset
-func (a *App) countHighRisk() int { return CountHighRisk(a.data) }
+// enrichSearchResult enriches a.searchResults[idx] and copies the result to
+// the matching dataset records, which the search results were copied from
+func (a *App) enrichSearchResult(ctx context.Context, idx int, delayMs int) error {
+	item := &a.searchResults[idx]
+	old := *item
+	err := a.extractor.EnrichRecordWithDelay(ctx, item, delayMs)
+	a.stats.Replace(old, *item)
+	a.updateRecord(*item)
+	a.publishRecordUpdated(item.IPOrCIDR)
+	return err
+}
+
+// resultsDir returns the configured results directory, or ./results when unset
+func (a *App) resultsDir() string {
+	if a.config.Database.ResultsDir == "" {
+		return "./results"
+	}
+	return a.config.Database.ResultsDir
+}
 
 // loadFromCSV loads data from a CSV file using header-based mapping
 func (a *App) loadFromCSV(filename string) ([]models.ScannerData, error) {
 	return LoadCSVData(filename)
 }
 
-// Run starts the application and enters the main event loop.
+// verifyDataset checks a result file against the hash recorded when it was
+// written and returns the warning to show, "" when it is intact or was not
+// hashed. The warning is logged too.
+func (a *App) verifyDataset(filename string) string {
+	check, err := extractor.VerifyDataset(filename)
+	if err != nil {
+		a.logger.Warning("GUI", err.Error())
+		return ""
+	}
+	w := check.Warning()
+	if w != "" {
+		a.logger.Warning("GUI", w)
+	}
+	return w
+}
+
+// Run starts the application and enters the main event loop. A panic in a
+// UI callback is written to a crash report before the application exits.
 func (a *App) Run() {
+	defer a.crash.Recover("GUI")
 	a.fyneApp.Run()
 }
 
 // Shutdown gracefully shuts down the application.
 func (a *App) Shutdown() {
 	a.logger.Info("GUI", "Shutting down application gracefully")
+	if a.server != nil {
+		_ = a.server.Close()
+	}
+	if a.store != nil {
+		_ = a.store.Close()
+	}
 	a.fyneApp.Quit()
 }
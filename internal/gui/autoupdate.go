@@ -0,0 +1,102 @@
+// Package gui provides the graphical user interface for LiaCheckScanner.
+// This file contains the scheduled update of the scanner repository.
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+
+	"github.com/lia/liacheckscanner_go/pkg/extractor"
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// autoUpdateCheck is how often the scheduler checks whether an update is
+// due, so a change of AutoUpdate or UpdateInterval applies within a minute.
+const autoUpdateCheck = time.Minute
+
+// errUpdateSkipped is returned by autoUpdate while another run is active.
+var errUpdateSkipped = errors.New("scheduled update skipped: a run is already active")
+
+// runAutoUpdate runs autoUpdate every UpdateInterval hours while AutoUpdate
+// is set, for the lifetime of the app. An update that is skipped or fails
+// waits for the next period.
+func (a *App) runAutoUpdate() {
+	defer a.crash.Recover("GUI")
+	last := time.Now()
+	for {
+		time.Sleep(autoUpdateCheck)
+		every := extractor.UpdateEvery(a.config.Database)
+		if every <= 0 || time.Since(last) < every {
+			continue
+		}
+		last = time.Now()
+		if err := a.autoUpdate(); err != nil {
+			a.logger.Warning("GUI", "Scheduled update: "+err.Error())
+		}
+	}
+}
+
+// autoUpdate updates the repository and extracts the base records again.
+// The records already enriched keep their enrichment (see
+// extractor.CarryEnrichment) and the others are queued on the background
+// RDAP job; with a remote API configured the dataset is pulled from it
+// instead. It returns errUpdateSkipped while another run is active, and
+// errSampleLoaded while a sample is explored.
+func (a *App) autoUpdate() error {
+	if a.runActive() {
+		return errUpdateSkipped
+	}
+	if a.sample != nil {
+		return errSampleLoaded
+	}
+	a.logger.Info("GUI", "🔄 Scheduled update started")
+	before := a.dataset()
+	if a.config.Database.RemoteAPIURL != "" {
+		if err := a.pullRemote(); err != nil {
+			return err
+		}
+		a.notifyUpdate(before, 0)
+		return nil
+	}
+	data, err := a.extractor.ExtractBaseRecords(a.runContext())
+	if err != nil {
+		return err
+	}
+	data, pending := extractor.CarryEnrichment(before, data)
+	a.setData(data)
+	a.notifyUpdate(before, len(pending))
+	if len(pending) == 0 || a.config.Database.SkipEnrichment || a.runRDAPJob == nil {
+		return nil
+	}
+
+	// The job skips the IPs of the carried records
+	pendingIPs := make(map[string]bool, len(pending))
+	for _, i := range pending {
+		pendingIPs[data[i].IPOrCIDR] = true
+	}
+	tracker := a.newRDAPTracker()
+	tracker.ProcessedIPSet = map[string]struct{}{}
+	for _, item := range data {
+		if !pendingIPs[item.IPOrCIDR] {
+			tracker.ProcessedIPs = append(tracker.ProcessedIPs, item.IPOrCIDR)
+			tracker.ProcessedIPSet[item.IPOrCIDR] = struct{}{}
+		}
+	}
+	_ = a.extractor.ClearProgressTracker()
+	a.logger.Info("GUI", fmt.Sprintf("⏳ %d new records queued for RDAP enrichment", len(pending)))
+	a.runRDAPJob(tracker, 0)
+	return nil
+}
+
+// notifyUpdate reports the dataset a scheduled update replaced before
+// with, in the log, the status bar and a desktop notification.
+func (a *App) notifyUpdate(before []models.ScannerData, pending int) {
+	diff := extractor.DiffDatasets(before, a.data)
+	msg := AutoUpdateSummary(len(a.data), len(diff.Added), len(diff.Removed), pending)
+	a.logger.Info("GUI", "🔄 "+msg)
+	a.setStatus("🔄 " + msg)
+	a.fyneApp.SendNotification(fyne.NewNotification("LiaCheckScanner", msg))
+}
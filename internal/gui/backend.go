@@ -0,0 +1,126 @@
+// Package gui provides the graphical user interface for LiaCheckScanner.
+// This file declares the Backend the GUI drives: the extractor by default,
+// or any other implementation given to NewAppWithBackend.
+package gui
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/events"
+	"github.com/lia/liacheckscanner_go/pkg/extractor"
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// Backend is what the GUI needs from the extractor, one interface per tab
+// or panel. The GUI makes no network request of its own: extraction,
+// enrichment and lookups all go through the Backend, so they can be served
+// by canned data or by another instance.
+type Backend interface {
+	SettingsBackend
+	DashboardBackend
+	SearchBackend
+	DatabaseBackend
+	EnforcementBackend
+	RunsBackend
+	RecordBackend
+}
+
+// SettingsBackend applies the Configuration tab and wires the App to the
+// backend's events and panics.
+type SettingsBackend interface {
+	ApplyConfig(config models.DatabaseConfig)
+	Events() *events.Bus
+	SetPanicHandler(h extractor.PanicHandler)
+	SelfTest() []extractor.SelfTestResult
+}
+
+// DashboardBackend runs the extractions and enrichments started from the
+// Dashboard, and keeps their progress so interrupted jobs can be resumed.
+type DashboardBackend interface {
+	ExtractBaseRecords(ctx context.Context) ([]models.ScannerData, error)
+	EnrichRecordWithDelay(ctx context.Context, data *models.ScannerData, delayMs int) error
+	EnrichIPs(ctx context.Context, ips []string) ([]models.ScannerData, error)
+	EnrichPeeringDB(ctx context.Context, data []models.ScannerData) int
+	SyncRemote(ctx context.Context, local []models.ScannerData) ([]models.ScannerData, int, error)
+	RunActive() bool
+	RateMetrics() extractor.RateReport
+
+	LoadProgressTracker() *models.RDAPProgressTracker
+	SaveProgressTracker(tracker *models.RDAPProgressTracker) error
+	ClearProgressTracker() error
+	IsIPProcessed(ip string, tracker *models.RDAPProgressTracker) bool
+	InterruptedJobs() ([]extractor.JobState, error)
+	SaveJobState(s *extractor.JobState) error
+	ClearJobState(kind string) error
+}
+
+// SearchBackend serves the single IP and ASN lookups of the Search tab.
+type SearchBackend interface {
+	LookupRDAP(ctx context.Context, ip string) (models.ScannerData, error)
+	LookupGeo(ctx context.Context, ip string) (extractor.GeoResult, error)
+	LookupInternetDB(ctx context.Context, ip string) (extractor.InternetDBResult, error)
+	NextGeoBackfill(data []models.ScannerData) int
+	BackfillGeo(ctx context.Context, item *models.ScannerData) (bool, error)
+	ExpandASN(ctx context.Context, asn string, data []models.ScannerData) (*extractor.ASNExpansion, error)
+	SaveASNPrefixes(exp *extractor.ASNExpansion, filename string) error
+}
+
+// DatabaseBackend prepares the dataset of the Database tab and keeps the
+// annotations, locks and honeypot hits added from it.
+type DatabaseBackend interface {
+	ApplyAnnotations(data []models.ScannerData) error
+	ApplyHits(data []models.ScannerData) error
+	ApplyAging(data []models.ScannerData, now time.Time) error
+	ApplyPrefixPolicy(data []models.ScannerData)
+	ApplyEnforcementPolicy(data []models.ScannerData) []extractor.PolicyViolation
+	ApplyTagRules(data []models.ScannerData)
+	AttributeScanners(data []models.ScannerData) int
+	AddAnnotation(ip, author string, tags []string, note string) (models.Annotation, error)
+	ApplyLocks(data []models.ScannerData) error
+	LockRecords(ips []string, author, reason string) (int, error)
+	UnlockRecords(ips []string) (int, error)
+	ImportHits(r io.Reader) (int, error)
+}
+
+// EnforcementBackend serves the greylisting, expiry and blocklist approval
+// actions of the Database tab.
+type EnforcementBackend interface {
+	LifecycleEntries() (map[string]models.LifecycleEntry, error)
+	SetLifecycleState(ip string, state models.LifecycleState, pinned bool) error
+	ExpiryWarnings(data []models.ScannerData, now time.Time) ([]extractor.ExpiryWarning, error)
+	NotifyExpiry(ctx context.Context, warnings []extractor.ExpiryWarning) (int, error)
+	EnforcementDelta(data []models.ScannerData) (extractor.EnforcementDelta, error)
+	ApproveEnforcement(data []models.ScannerData, approver string) (extractor.ApprovalRecord, error)
+	AllowlistIPs(ips []string, kind, label string) (int, error)
+}
+
+// RunsBackend serves the Runs tab and the exports.
+type RunsBackend interface {
+	SaveRun(data []models.ScannerData) (string, error)
+	Runs() ([]string, error)
+	DiffRunFiles(from, to string) (extractor.RunDiff, error)
+	SaveRunDiff(d extractor.RunDiff, filename string) error
+	Export(data []models.ScannerData, name string) error
+	SaveRunMetadata(name string, data []models.ScannerData) error
+	SaveToXLSX(data []models.ScannerData, filename string) error
+	ExportWithTemplate(data []models.ScannerData, filename, template string) error
+	ExportPresetNames() []string
+	RedactForExport(data []models.ScannerData, preset string) ([]models.ScannerData, error)
+	PushMetrics(ctx context.Context, data []models.ScannerData) error
+}
+
+// RecordBackend serves the record detail panel: pivots, dossiers and
+// AbuseIPDB reports.
+type RecordBackend interface {
+	PivotURLs(item models.ScannerData) []models.PivotLink
+	OptOutURL(item models.ScannerData) (string, bool)
+	BuildDossier(ip string, data []models.ScannerData) (extractor.Dossier, error)
+	SaveDossier(d extractor.Dossier, filename string) (string, error)
+	ReportToAbuseIPDB(ctx context.Context, data []models.ScannerData) (extractor.AbuseReportSummary, error)
+	AbuseIPDBQuotaLeft() (int, error)
+}
+
+// The extractor is the Backend of NewApp.
+var _ Backend = (*extractor.Extractor)(nil)
@@ -0,0 +1,76 @@
+// Package gui provides the graphical user interface for LiaCheckScanner.
+// This file contains the background geolocation backfill of the loaded
+// records.
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/extractor"
+)
+
+const (
+	// geoBackfillIdle is how long the backfill waits before checking again
+	// while it is disabled.
+	geoBackfillIdle = time.Minute
+	// geoBackfillSaveEvery is how many backfilled records separate two
+	// saves of the dataset; it is also saved when nothing is left to fill.
+	geoBackfillSaveEvery = 500
+)
+
+// runGeoBackfill looks up the records of the dataset missing geolocation,
+// one every hour/GeoBackfillPerHour, for the lifetime of the app. Nothing is
+// looked up while an enrichment runs (see extractor.BackfillGeo).
+func (a *App) runGeoBackfill() {
+	defer a.crash.Recover("GUI")
+	filled := 0
+	for {
+		perHour := a.config.Database.GeoBackfillPerHour
+		if perHour <= 0 {
+			time.Sleep(geoBackfillIdle)
+			continue
+		}
+		time.Sleep(time.Hour / time.Duration(perHour))
+
+		data := a.dataset()
+		idx := a.extractor.NextGeoBackfill(data)
+		if idx < 0 {
+			if filled > 0 {
+				a.saveBackfilledRun(filled)
+				filled = 0
+			}
+			continue
+		}
+		item := &data[idx]
+		old := *item
+		ok, err := a.extractor.BackfillGeo(a.runContext(), item)
+		switch {
+		case errors.Is(err, extractor.ErrBackfillPaused):
+			continue
+		case err != nil:
+			a.logger.Debug("GUI", "Geo backfill: "+err.Error())
+			continue
+		case !ok:
+			continue
+		}
+		a.stats.Replace(old, *item)
+		a.storeRecords(*item)
+		a.publishRecordUpdated(item.IPOrCIDR)
+		if filled++; filled%geoBackfillSaveEvery == 0 {
+			a.saveBackfilledRun(filled)
+			filled = 0
+		}
+	}
+}
+
+// saveBackfilledRun saves the dataset after n records were backfilled.
+func (a *App) saveBackfilledRun(n int) {
+	path, err := a.saveRun()
+	if err != nil {
+		a.logger.Warning("GUI", "Run not saved after geo backfill: "+err.Error())
+		return
+	}
+	a.logger.Info("GUI", fmt.Sprintf("🌍 Geo backfill: %d records completed, run saved to %s", n, path))
+}
@@ -0,0 +1,293 @@
+// Package gui provides the graphical user interface for LiaCheckScanner.
+// This file contains the bulk actions applied to the selected records of a
+// table, or to all its records when none is selected.
+package gui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/lia/liacheckscanner_go/pkg/extractor"
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// Bulk actions offered by the bulk actions dialog.
+const (
+	bulkTag       = "Appliquer un tag"
+	bulkRisk      = "Forcer le niveau de risque"
+	bulkAllowlist = "Ajouter à la liste d'autorisation"
+	bulkEnrich    = "Relancer l'enrichissement RDAP"
+	bulkDelete    = "Supprimer"
+	bulkLock      = "Verrouiller (enquête en cours)"
+	bulkUnlock    = "Déverrouiller"
+)
+
+// showBulkActions lets the user pick an action for the selected records of
+// t, or for every record of t when none is selected, and runs it once the
+// summary is confirmed.
+func (t *recordTable) showBulkActions() {
+	a := t.app
+	items := t.selectedRecords()
+	scope := "sélection"
+	if len(items) == 0 {
+		items = append([]models.ScannerData(nil), t.rows()...)
+		scope = "enregistrements affichés"
+	}
+	if len(items) == 0 {
+		a.showInformation("Actions groupées", "Aucun enregistrement", a.mainWindow)
+		return
+	}
+
+	tagEntry := widget.NewEntry()
+	tagEntry.SetPlaceHolder("Tag")
+	riskSelect := widget.NewSelect([]string{"High", "Medium", "Low", "Unknown"}, nil)
+	riskSelect.SetSelected("High")
+	kindSelect := widget.NewSelect([]string{extractor.ProtectedService, extractor.ProtectedPartner, extractor.ProtectedOwn}, nil)
+	kindSelect.SetSelected(extractor.ProtectedService)
+	reasonEntry := widget.NewEntry()
+	reasonEntry.SetPlaceHolder("Motif (ticket, enquête...)")
+	inputs := map[string]fyne.CanvasObject{bulkTag: tagEntry, bulkRisk: riskSelect, bulkAllowlist: kindSelect, bulkLock: reasonEntry}
+	for _, w := range inputs {
+		w.Hide()
+	}
+	actionSelect := widget.NewSelect([]string{bulkTag, bulkRisk, bulkAllowlist, bulkEnrich, bulkDelete, bulkLock, bulkUnlock}, func(action string) {
+		for name, w := range inputs {
+			if name == action {
+				w.Show()
+			} else {
+				w.Hide()
+			}
+		}
+	})
+	actionSelect.SetSelected(bulkTag)
+
+	content := container.NewVBox(
+		widget.NewLabel(fmt.Sprintf("%d enregistrements (%s)", len(items), scope)),
+		actionSelect, tagEntry, riskSelect, kindSelect, reasonEntry,
+	)
+	dialog.ShowCustomConfirm(a.text("🧰 Actions groupées"), "Continuer", "Annuler", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		action := actionSelect.Selected
+		if action == bulkTag && strings.TrimSpace(tagEntry.Text) == "" {
+			a.showInformation("Actions groupées", "Saisis un tag", a.mainWindow)
+			return
+		}
+		label := action
+		switch action {
+		case bulkTag:
+			label += ": " + strings.TrimSpace(tagEntry.Text)
+		case bulkRisk:
+			label += ": " + riskSelect.Selected
+		case bulkAllowlist:
+			label += " (" + kindSelect.Selected + "), retirées du blocage"
+		case bulkLock:
+			if reason := strings.TrimSpace(reasonEntry.Text); reason != "" {
+				label += ": " + reason
+			}
+		case bulkDelete, bulkEnrich:
+			if n := CountLocked(items); n > 0 {
+				label += fmt.Sprintf(" (%d verrouillés ignorés)", n)
+			}
+		}
+		dialog.ShowConfirm(a.text("🧰 Actions groupées"), BulkSummary(label, items), func(ok bool) {
+			if !ok {
+				return
+			}
+			a.runBulkAction(action, items, strings.TrimSpace(tagEntry.Text), riskSelect.Selected, kindSelect.Selected, strings.TrimSpace(reasonEntry.Text))
+		}, a.mainWindow)
+	}, a.mainWindow)
+}
+
+// runBulkAction applies action to items in the dataset and the search
+// results, and persists the change.
+func (a *App) runBulkAction(action string, items []models.ScannerData, tag, risk, kind, reason string) {
+	keys := RecordKeys(items)
+	var ips []string
+	seen := map[string]bool{}
+	for _, item := range items {
+		if !seen[item.IPOrCIDR] {
+			seen[item.IPOrCIDR] = true
+			ips = append(ips, item.IPOrCIDR)
+		}
+	}
+
+	data := a.dataset()
+	author := os.Getenv("USER")
+	if author == "" {
+		author = "gui"
+	}
+	var msg string
+	switch action {
+	case bulkTag:
+		for _, ip := range ips {
+			if _, err := a.extractor.AddAnnotation(ip, author, []string{tag}, ""); err != nil {
+				dialog.ShowError(err, a.mainWindow)
+				return
+			}
+		}
+		BulkTag(a.searchResults, keys, tag)
+		msg = fmt.Sprintf("✅ Tag %q appliqué à %d enregistrements", tag, BulkTag(data, keys, tag))
+	case bulkRisk:
+		BulkSetRisk(a.searchResults, keys, risk)
+		n := BulkSetRisk(data, keys, risk)
+		a.stats.Reset(data)
+		a.updateStats()
+		msg = fmt.Sprintf("✅ Risque %s appliqué à %d enregistrements", risk, n)
+		msg += a.saveBulkRun()
+	case bulkAllowlist:
+		n, err := a.extractor.AllowlistIPs(ips, kind, "bulk")
+		if err != nil {
+			dialog.ShowError(err, a.mainWindow)
+			return
+		}
+		for _, ip := range ips {
+			if err := a.extractor.SetLifecycleState(ip, models.StateRetired, true); err != nil {
+				dialog.ShowError(err, a.mainWindow)
+				return
+			}
+		}
+		for _, records := range [][]models.ScannerData{data, a.searchResults} {
+			for i := range records {
+				if seen[records[i].IPOrCIDR] {
+					records[i].State = models.StateRetired
+				}
+			}
+		}
+		msg = fmt.Sprintf("✅ %d entrées ajoutées aux préfixes protégés\n%d IPs retirées du blocage", n, len(ips))
+	case bulkEnrich:
+		a.bulkEnrich(keys)
+		return
+	case bulkDelete:
+		locked := CountLocked(items)
+		a.searchResults = BulkDelete(a.searchResults, keys)
+		a.editData(BulkDelete(data, keys))
+		msg = fmt.Sprintf("✅ %d enregistrements supprimés", len(items)-locked)
+		if locked > 0 {
+			msg += fmt.Sprintf("\n🔒 %d verrouillés conservés", locked)
+		}
+		msg += a.saveBulkRun()
+	case bulkLock, bulkUnlock:
+		var n int
+		var err error
+		if action == bulkLock {
+			n, err = a.extractor.LockRecords(ips, author, reason)
+			msg = fmt.Sprintf("🔒 %d IPs verrouillées", n)
+		} else {
+			n, err = a.extractor.UnlockRecords(ips)
+			msg = fmt.Sprintf("🔓 %d IPs déverrouillées", n)
+		}
+		if err != nil {
+			dialog.ShowError(err, a.mainWindow)
+			return
+		}
+		for _, records := range [][]models.ScannerData{data, a.searchResults} {
+			if err := a.extractor.ApplyLocks(records); err != nil {
+				a.logger.Warning("GUI", "Record locks not applied: "+err.Error())
+			}
+		}
+	}
+	a.saveStore()
+	if a.server != nil {
+		a.server.SetRecords(a.data)
+	}
+	a.logger.Info("GUI", fmt.Sprintf("Bulk action %q on %d records", action, len(items)))
+	a.refreshTables()
+	a.showInformation("Actions groupées", msg, a.mainWindow)
+}
+
+// saveBulkRun saves the dataset after a bulk change and returns the line
+// to add to the result message.
+func (a *App) saveBulkRun() string {
+	path, err := a.saveRun()
+	if err != nil {
+		a.logger.Warning("GUI", "Run not saved after bulk action: "+err.Error())
+		return "\n⚠️ Run non sauvegardé: " + err.Error()
+	}
+	return "\nRun: " + path
+}
+
+// bulkCheckpointEvery is how many re-enriched records separate two saves
+// of the bulk re-enrichment job state.
+const bulkCheckpointEvery = 25
+
+// bulkEnrich queues the dataset records with a key in keys for RDAP
+// enrichment in the background, at the configured throttle.
+func (a *App) bulkEnrich(keys map[string]bool) {
+	a.setBusy(true, "Enrichissement groupé en cours...")
+	// Kept until the end so the job can be resumed after a crash
+	job := &extractor.JobState{Kind: extractor.JobBulkEnrich, StartedAt: time.Now().UTC()}
+	data := a.dataset()
+	for _, item := range data {
+		if keys[RecordKey(item)] && item.Lock == nil {
+			job.IPs = append(job.IPs, item.IPOrCIDR)
+		}
+	}
+	if err := a.extractor.SaveJobState(job); err != nil {
+		a.logger.Warning("GUI", err.Error())
+	}
+	ctx := a.runContext()
+	go func() {
+		defer a.crash.Recover("GUI")
+		done := 0
+		for i := 0; i < len(data); i++ {
+			// Locked records are not enriched again
+			if !keys[RecordKey(data[i])] || data[i].Lock != nil {
+				continue
+			}
+			if ctx.Err() != nil {
+				// The job state is kept so the rest can be resumed
+				job.Done = done
+				_ = a.extractor.SaveJobState(job)
+				a.setBusy(false, "")
+				a.refreshTables()
+				a.showInformation("Actions groupées", fmt.Sprintf("⛔ Annulé après %d enregistrements, reprise possible au prochain démarrage", done), a.mainWindow)
+				return
+			}
+			if err := a.enrichRecord(ctx, i, int(a.config.Database.APIThrottle*1000)); err != nil {
+				a.logger.Warning("GUI", fmt.Sprintf("RDAP enrich error for %s: %v", data[i].IPOrCIDR, err))
+			}
+			done++
+			a.updateStats()
+			if done%bulkCheckpointEvery == 0 {
+				job.Done = done
+				_ = a.extractor.SaveJobState(job)
+			}
+		}
+		if err := a.extractor.ClearJobState(job.Kind); err != nil {
+			a.logger.Warning("GUI", err.Error())
+		}
+		enriched := map[string]models.ScannerData{}
+		for _, item := range data {
+			if keys[RecordKey(item)] {
+				enriched[RecordKey(item)] = item
+			}
+		}
+		for i := range a.searchResults {
+			if item, ok := enriched[RecordKey(a.searchResults[i])]; ok {
+				a.searchResults[i] = item
+			}
+		}
+		a.setBusy(false, "")
+		a.refreshTables()
+		a.showInformation("Actions groupées", fmt.Sprintf("✅ %d enregistrements réenrichis", done), a.mainWindow)
+	}()
+}
+
+// refreshTables redraws the Database and Search tables after the records
+// changed.
+func (a *App) refreshTables() {
+	a.records.Refresh()
+	if a.searchTable != nil {
+		a.searchTable.resetSelection()
+		a.searchTable.Refresh()
+	}
+}
@@ -0,0 +1,95 @@
+// Package gui provides the graphical user interface for LiaCheckScanner.
+// This file contains the comparison windows, which show a stored run next
+// to the main window, with its differences from the loaded dataset.
+package gui
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/lia/liacheckscanner_go/pkg/extractor"
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// openCompareWindow asks for a stored CSV run and opens it in a window of
+// its own. Several windows can be open at once.
+func (a *App) openCompareWindow() {
+	d := dialog.NewFileOpen(func(r fyne.URIReadCloser, err error) {
+		if err != nil || r == nil {
+			return
+		}
+		r.Close()
+		data, err := LoadCSVData(r.URI().Path())
+		if err != nil {
+			dialog.ShowError(err, a.mainWindow)
+			return
+		}
+		source := ""
+		if meta, err := extractor.ReadRunMetadata(r.URI().Path()); err == nil {
+			source = RunSourceLabel(meta)
+		}
+		if w := a.verifyDataset(r.URI().Path()); w != "" {
+			source = strings.TrimSpace(source + "\n⚠️ " + w)
+		}
+		a.showCompareWindow(r.URI().Name(), data, source)
+	}, a.mainWindow)
+	d.SetFilter(storage.NewExtensionFileFilter([]string{".csv"}))
+	if dir, err := storage.ListerForURI(storage.NewFileURI(a.resultsDir())); err == nil {
+		d.SetLocation(dir)
+	}
+	d.Show()
+}
+
+// showCompareWindow shows data, read from name, in a new window: the
+// records in a table, and their differences from the dataset loaded in the
+// main window, taken as the newer side. source describes the upstream state
+// the run was built from, "" when unknown.
+func (a *App) showCompareWindow(name string, data []models.ScannerData, source string) {
+	w := a.fyneApp.NewWindow(a.text("🪟 " + name))
+
+	table := a.newRecordTable(func() []models.ScannerData { return data }, nil, "compare")
+	table.Refresh()
+	header := container.NewVBox(widget.NewLabel(fmt.Sprintf("%s: %d records", name, len(data))))
+	if source != "" {
+		header.Add(widget.NewLabel(source))
+	}
+	header.Add(table.paginationControls())
+	datasetView := container.NewBorder(
+		header,
+		nil, nil, nil, table.view(500),
+	)
+
+	summary := widget.NewLabel("")
+	var lines []string
+	list := widget.NewList(
+		func() int { return len(lines) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(i widget.ListItemID, o fyne.CanvasObject) { o.(*widget.Label).SetText(lines[i]) },
+	)
+	refreshDiff := func() {
+		diff := extractor.DiffDatasets(data, a.dataset())
+		lines = DiffLines(diff)
+		summary.SetText(fmt.Sprintf("%s -> dataset chargé: %d ajoutés, %d supprimés, %d modifiés",
+			name, len(diff.Added), len(diff.Removed), len(diff.Changed)))
+		list.Refresh()
+	}
+	refreshDiff()
+	refreshBtn := widget.NewButton("🔄 Recalculer", refreshDiff)
+	diffView := container.NewBorder(container.NewHBox(summary, refreshBtn), nil, nil, nil, list)
+
+	tabs := container.NewAppTabs(
+		container.NewTabItem("📋 Dataset", datasetView),
+		container.NewTabItem("🔀 Diff", diffView),
+	)
+	a.applyPlainLabels(tabs)
+	w.SetContent(tabs)
+	w.Resize(fyne.NewSize(1200, 800))
+	w.Show()
+	a.logger.Info("GUI", fmt.Sprintf("Comparison window opened on %s (%d records)", name, len(data)))
+}
@@ -4,16 +4,22 @@
 package gui
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
+	"net/url"
+	"path/filepath"
 	"strings"
-	"time"
 
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
 
-	"github.com/lia/liacheckscanner_go/internal/models"
+	"github.com/lia/liacheckscanner_go/internal/config"
+	"github.com/lia/liacheckscanner_go/internal/updates"
+	"github.com/lia/liacheckscanner_go/pkg/extractor"
+	"github.com/lia/liacheckscanner_go/pkg/models"
 )
 
 // performRealIPEnrichment orchestrates real IP enrichment using multiple APIs
@@ -32,8 +38,9 @@ func (a *App) performRealIPEnrichment(ip string) string {
 	result += a.simulateReputationLookup(ip)
 	result += "\n"
 
-	result += a.simulatePortScan(ip)
-	result += "\n"
+	r, err := a.lookupPorts(ip)
+	result += InternetDBText(ip, r, err)
+	result += "\n\n"
 
 	result += a.simulateThreatIntelligence(ip)
 	result += "\n"
@@ -43,168 +50,60 @@ func (a *App) performRealIPEnrichment(ip string) string {
 	return result
 }
 
-// performRealRDAPLookup performs real RDAP lookup using multiple registries
+// performRealRDAPLookup looks ip up in the RDAP registries through the
+// backend, the registry owning it first
 func (a *App) performRealRDAPLookup(ip string) string {
-	endpoints := []string{
-		"https://rdap.arin.net/registry/ip/", // North America
-		"https://rdap.ripe.net/ip/",          // Europe, Middle East, parts of Central Asia
-		"https://rdap.apnic.net/ip/",         // Asia Pacific
-		"https://rdap.lacnic.net/rdap/ip/",   // Latin America and Caribbean
-		"https://rdap.afrinic.net/rdap/ip/",  // Africa
-	}
-
-	client := &http.Client{Timeout: 12 * time.Second}
-	for _, base := range endpoints {
-		url := base + ip
-		resp, err := client.Get(url)
-		if err != nil {
-			continue
-		}
-		body, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil || resp.StatusCode != http.StatusOK {
-			continue
-		}
-
-		var data map[string]interface{}
-		if err := json.Unmarshal(body, &data); err != nil {
-			continue
-		}
-
-		get := func(m map[string]interface{}, key string) string {
-			if v, ok := m[key]; ok && v != nil {
-				return fmt.Sprintf("%v", v)
-			}
-			return ""
-		}
-
-		// CIDR or range
-		cidr := ""
-		if c0, ok := data["cidr0_cidrs"].([]interface{}); ok && len(c0) > 0 {
-			if first, ok := c0[0].(map[string]interface{}); ok {
-				v := strings.TrimSpace(fmt.Sprintf("%v/%v", first["v4prefix"], first["length"]))
-				if v != "/" {
-					cidr = v
-				}
-			}
-		}
-		if cidr == "" {
-			start := get(data, "startAddress")
-			end := get(data, "endAddress")
-			if start != "" && end != "" {
-				cidr = fmt.Sprintf("%s - %s", start, end)
-			}
-		}
-
-		name := get(data, "name")
-		handle := get(data, "handle")
-		country := get(data, "country")
-		objectClass := get(data, "objectClassName")
-
-		contacts := []string{}
-		if ents, ok := data["entities"].([]interface{}); ok {
-			for _, e := range ents {
-				if em, ok := e.(map[string]interface{}); ok {
-					role := ""
-					if roles, ok := em["roles"].([]interface{}); ok && len(roles) > 0 {
-						role = fmt.Sprintf("%v", roles[0])
-					}
-					org := get(em, "handle")
-					email := ""
-					if vcard, ok := em["vcardArray"].([]interface{}); ok && len(vcard) > 1 {
-						if rows, ok := vcard[1].([]interface{}); ok {
-							for _, row := range rows {
-								if r, ok := row.([]interface{}); ok && len(r) > 2 {
-									if fmt.Sprintf("%v", r[0]) == "email" {
-										if vals, ok := r[3].([]interface{}); ok && len(vals) > 0 {
-											email = fmt.Sprintf("%v", vals[0])
-										}
-									}
-								}
-							}
-						}
-					}
-					if role != "" || org != "" || email != "" {
-						contacts = append(contacts, strings.TrimSpace(fmt.Sprintf("%s %s %s", role, org, email)))
-					}
-				}
-			}
-		}
-
-		regDate, lastChanged := "", ""
-		if evs, ok := data["events"].([]interface{}); ok {
-			for _, ev := range evs {
-				if em, ok := ev.(map[string]interface{}); ok {
-					action := get(em, "eventAction")
-					date := get(em, "eventDate")
-					if action == "registration" {
-						regDate = date
-					} else if action == "last changed" {
-						lastChanged = date
-					}
-				}
-			}
-		}
+	r, err := a.extractor.LookupRDAP(a.runContext(), ip)
+	if err != nil {
+		// Fallback if all RDAPs fail
+		return fmt.Sprintf("🏢 RDAP Information:\n• IP: %s\n• Status: unavailable (%s)", ip, err)
+	}
 
-		b := &strings.Builder{}
-		fmt.Fprintf(b, "🏢 RDAP Information:\n")
-		fmt.Fprintf(b, "• Object: %s (%s)\n", name, objectClass)
-		fmt.Fprintf(b, "• Handle: %s\n", handle)
-		fmt.Fprintf(b, "• Country: %s\n", country)
-		if cidr != "" {
-			fmt.Fprintf(b, "• Network: %s\n", cidr)
-		}
-		if regDate != "" || lastChanged != "" {
-			fmt.Fprintf(b, "• Registration: %s | Last Changed: %s\n", regDate, lastChanged)
-		}
-		if len(contacts) > 0 {
-			fmt.Fprintf(b, "• Contacts: %s\n", strings.Join(contacts, "; "))
-		}
-		return b.String()
+	network := r.RDAPCIDR
+	if network == "" && r.StartAddress != "" && r.EndAddress != "" {
+		network = fmt.Sprintf("%s - %s", r.StartAddress, r.EndAddress)
+	}
+	var contacts []string
+	if r.AbuseEmail != "" {
+		contacts = append(contacts, "abuse "+r.AbuseEmail)
+	}
+	if r.TechEmail != "" {
+		contacts = append(contacts, "technical "+r.TechEmail)
 	}
 
-	// Fallback if all RDAPs fail
-	return fmt.Sprintf("🏢 RDAP Information:\n• IP: %s\n• Status: unavailable (RDAP endpoints unreachable)", ip)
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "🏢 RDAP Information:\n")
+	fmt.Fprintf(b, "• Object: %s (%s)\n", r.RDAPName, r.RDAPType)
+	fmt.Fprintf(b, "• Handle: %s\n", r.RDAPHandle)
+	fmt.Fprintf(b, "• Registry: %s\n", r.Registry)
+	if network != "" {
+		fmt.Fprintf(b, "• Network: %s\n", network)
+	}
+	if !r.EventRegistration.IsZero() || !r.EventLastChanged.IsZero() {
+		fmt.Fprintf(b, "• Registration: %s | Last Changed: %s\n", models.FormatTimestamp(r.EventRegistration), models.FormatTimestamp(r.EventLastChanged))
+	}
+	if len(contacts) > 0 {
+		fmt.Fprintf(b, "• Contacts: %s\n", strings.Join(contacts, "; "))
+	}
+	return b.String()
 }
 
 // performRealGeolocationLookup performs real geolocation lookup
 func (a *App) performRealGeolocationLookup(ip string) string {
-	client := &http.Client{Timeout: 8 * time.Second}
-	url := fmt.Sprintf("http://ip-api.com/json/%s?fields=status,country,city,isp,org,as,query,lat,lon,timezone,reverse,continentCode,countryCode,regionName", ip)
-	resp, err := client.Get(url)
+	g, err := a.extractor.LookupGeo(a.runContext(), ip)
 	if err != nil {
 		return "🌍 Geolocation: unavailable"
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return "🌍 Geolocation: unavailable"
-	}
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "🌍 Geolocation: unavailable"
-	}
-	var g map[string]interface{}
-	if err := json.Unmarshal(body, &g); err != nil {
-		return "🌍 Geolocation: unavailable"
-	}
-	get := func(key string) string {
-		if v, ok := g[key]; ok && v != nil {
-			return fmt.Sprintf("%v", v)
-		}
-		return ""
-	}
 	b := &strings.Builder{}
 	fmt.Fprintf(b, "🌍 Geolocation:\n")
-	fmt.Fprintf(b, "• Country: %s (%s)\n", get("country"), get("countryCode"))
-	fmt.Fprintf(b, "• City: %s\n", get("city"))
-	fmt.Fprintf(b, "• ISP: %s\n", get("isp"))
-	fmt.Fprintf(b, "• Org/AS: %s / %s\n", get("org"), get("as"))
-	fmt.Fprintf(b, "• Timezone: %s\n", get("timezone"))
-	fmt.Fprintf(b, "• Reverse DNS: %s\n", get("reverse"))
-	lat := get("lat")
-	lon := get("lon")
-	if lat != "" && lon != "" {
-		fmt.Fprintf(b, "• Coordinates: %s, %s\n", lat, lon)
+	fmt.Fprintf(b, "• Country: %s (%s)\n", g.Country, g.CountryCode)
+	fmt.Fprintf(b, "• City: %s\n", g.City)
+	fmt.Fprintf(b, "• ISP: %s\n", g.ISP)
+	fmt.Fprintf(b, "• AS: %s\n", g.ASN)
+	fmt.Fprintf(b, "• Timezone: %s\n", g.Timezone)
+	fmt.Fprintf(b, "• Reverse DNS: %s\n", g.ReverseDNS)
+	if g.Latitude != 0 || g.Longitude != 0 {
+		fmt.Fprintf(b, "• Coordinates: %g, %g\n", g.Latitude, g.Longitude)
 	}
 	return b.String()
 }
@@ -214,11 +113,6 @@ func (a *App) simulateReputationLookup(ip string) string {
 	return fmt.Sprintf("🔍 Reputation Analysis:\n• Threat Score: 75/100\n• Blacklist Status: Clean\n• Reputation: Good")
 }
 
-// simulatePortScan simulates port scanning
-func (a *App) simulatePortScan(ip string) string {
-	return fmt.Sprintf("🔌 Port Scan Results:\n• Open Ports: 22, 80, 443\n• Common Services: SSH, HTTP, HTTPS\n• Security Status: Standard")
-}
-
 // simulateThreatIntelligence simulates threat intelligence lookup
 func (a *App) simulateThreatIntelligence(ip string) string {
 	return fmt.Sprintf("⚠️ Threat Intelligence:\n• Known Threats: None detected\n• Malware History: Clean\n• Botnet Activity: None")
@@ -234,19 +128,380 @@ func (a *App) displaySearchStatistics(results []models.ScannerData) {
 	stats := fmt.Sprintf("📊 Search Statistics:\n• Total Results: %d\n• By Country: %d\n• By Scanner: %d\n• By Risk: %d",
 		len(results), a.countUniqueCountriesInResults(results), a.countUniqueScannersInResults(results), a.countRiskLevelsInResults(results))
 
-	dialog.ShowInformation("Search Statistics", stats, a.mainWindow)
+	a.showInformation("Search Statistics", stats, a.mainWindow)
+}
+
+// showConfigProblems lists every configuration problem found by
+// config.Validate in a single dialog. Other errors are shown as-is.
+func (a *App) showConfigProblems(err error) {
+	var verr *config.ValidationError
+	if !errors.As(err, &verr) {
+		dialog.ShowError(err, a.mainWindow)
+		return
+	}
+	text := ""
+	for _, p := range verr.Problems {
+		text += "• " + p + "\n"
+	}
+	list := widget.NewMultiLineEntry()
+	list.SetText(text)
+	list.Wrapping = fyne.TextWrapWord
+	list.Disable()
+	scroll := container.NewScroll(list)
+	scroll.SetMinSize(fyne.NewSize(600, 250))
+	title := widget.NewLabel(a.text(fmt.Sprintf("⚠️ %d problème(s) dans la configuration:", len(verr.Problems))))
+	dialog.NewCustom("Configuration", "OK", container.NewBorder(title, nil, nil, nil, scroll), a.mainWindow).Show()
+}
+
+// CheckForUpdates asks checker for a release newer than current in the
+// background. A newer release is announced in a dialog with its release
+// notes; failures are only logged, the check never blocks the UI.
+func (a *App) CheckForUpdates(checker *updates.Checker, current string) {
+	go func() {
+		defer a.crash.Recover("GUI")
+		rel, err := checker.Check(current)
+		if err != nil {
+			a.logger.Info("GUI", "Update check failed: "+err.Error())
+			return
+		}
+		if rel == nil {
+			a.logger.Debug("GUI", "No newer release than "+current)
+			return
+		}
+		a.logger.Info("GUI", fmt.Sprintf("New release available: %s (running %s)", rel.TagName, current))
+		a.setStatus("🆕 Version " + rel.TagName + " disponible")
+
+		notes := widget.NewRichTextFromMarkdown(rel.Body)
+		notes.Wrapping = fyne.TextWrapWord
+		scroll := container.NewScroll(notes)
+		scroll.SetMinSize(fyne.NewSize(600, 300))
+		title := widget.NewLabel(a.text(fmt.Sprintf("🆕 %s est disponible (version actuelle %s)", rel.TagName, current)))
+		title.TextStyle = fyne.TextStyle{Bold: true}
+		var bottom fyne.CanvasObject
+		if u, err := url.Parse(rel.HTMLURL); err == nil && rel.HTMLURL != "" {
+			bottom = widget.NewHyperlink("Ouvrir la page de la release", u)
+		}
+		dialog.NewCustom("Mise à jour", "Plus tard", container.NewBorder(title, bottom, nil, nil, scroll), a.mainWindow).Show()
+	}()
+}
+
+// runSelfTest runs the connectivity self-test in the background and shows
+// the pass/fail checklist
+func (a *App) runSelfTest() {
+	a.setStatus("⏳ Self-test en cours...")
+	go func() {
+		defer a.crash.Recover("GUI")
+		results := a.extractor.SelfTest()
+		a.logger.Info("GUI", fmt.Sprintf("Self-test: %d checks, passed=%v", len(results), extractor.SelfTestPassed(results)))
+
+		rows := container.NewVBox()
+		for _, r := range results {
+			mark := "✅"
+			if !r.OK {
+				mark = "❌"
+			}
+			if a.config.PlainLabels {
+				// The mark carries the result, so spell it out
+				mark = "[OK]"
+				if !r.OK {
+					mark = "[FAIL]"
+				}
+			}
+			line := widget.NewLabel(fmt.Sprintf("%s %s — %s", mark, r.Name, r.Detail))
+			line.Wrapping = fyne.TextWrapWord
+			rows.Add(line)
+		}
+		scroll := container.NewScroll(rows)
+		scroll.SetMinSize(fyne.NewSize(600, 300))
+		title := widget.NewLabel(a.text("🩺 Self-test: tous les contrôles sont passés"))
+		if !extractor.SelfTestPassed(results) {
+			title.SetText(a.text("🩺 Self-test: des contrôles ont échoué"))
+		}
+		a.setStatus("🟢 Ready")
+		dialog.NewCustom("Self-test", "OK", container.NewBorder(title, nil, nil, nil, scroll), a.mainWindow).Show()
+	}()
 }
 
 // clearSearchResults clears search results and resets the interface
 func (a *App) clearSearchResults() {
-	a.searchResults = nil
-	if a.searchResultsTable != nil {
-		a.searchResultsTable.Refresh()
-	}
+	a.setSearchResults(nil)
 	if a.searchStatsLabel != nil {
-		a.searchStatsLabel.SetText("📈 Statistics: 0 results")
+		a.searchStatsLabel.SetText(a.text("📈 Statistics: 0 results"))
 	}
 	if a.enrichmentText != nil {
 		a.enrichmentText.SetText("")
 	}
 }
+
+// reportToAbuseIPDB confirms and submits the blocked IPs to AbuseIPDB
+func (a *App) reportToAbuseIPDB() {
+	if !a.config.Database.AbuseIPDBReport {
+		a.showInformation("AbuseIPDB", "Le signalement AbuseIPDB est désactivé.\nActivez-le dans l'onglet Configuration.", a.mainWindow)
+		return
+	}
+	blocked := len(extractor.Enforceable(a.dataset()))
+	if blocked == 0 {
+		a.showInformation("AbuseIPDB", "Aucune IP bloquée à signaler", a.mainWindow)
+		return
+	}
+	left, err := a.extractor.AbuseIPDBQuotaLeft()
+	if err != nil {
+		dialog.ShowError(err, a.mainWindow)
+		return
+	}
+	msg := fmt.Sprintf("Signaler %d IPs bloquées à AbuseIPDB ?\n%d signalements restants aujourd'hui.\nLes IPs signalées depuis moins de 24h sont ignorées.", blocked, left)
+	dialog.ShowConfirm(a.text("🚨 AbuseIPDB"), msg, func(ok bool) {
+		if !ok {
+			return
+		}
+		a.setBusy(true, "Signalement AbuseIPDB en cours...")
+		go func() {
+			defer a.crash.Recover("GUI")
+			defer a.setBusy(false, "")
+			sum, err := a.extractor.ReportToAbuseIPDB(a.runContext(), a.dataset())
+			if err != nil {
+				dialog.ShowError(err, a.mainWindow)
+				return
+			}
+			text := fmt.Sprintf("✅ %d IPs signalées\n%d ignorées, %d échecs\n%d signalements restants aujourd'hui", sum.Reported, sum.Skipped, sum.Failed, sum.QuotaLeft)
+			if sum.QuotaExhausted {
+				text += "\n⚠️ Quota atteint : les IPs restantes seront signalées plus tard"
+			}
+			a.showInformation("AbuseIPDB", text, a.mainWindow)
+		}()
+	}, a.mainWindow)
+}
+
+// importHits imports a honeypot hits feed (CSV ip,timestamp,port or JSON)
+// and correlates the stored hits with the loaded dataset
+func (a *App) importHits() {
+	d := dialog.NewFileOpen(func(r fyne.URIReadCloser, err error) {
+		if err != nil || r == nil {
+			return
+		}
+		a.setBusy(true, "Import des hits honeypot...")
+		go func() {
+			defer a.crash.Recover("GUI")
+			defer a.setBusy(false, "")
+			defer r.Close()
+			added, err := a.extractor.ImportHits(r)
+			if err != nil {
+				dialog.ShowError(err, a.mainWindow)
+				return
+			}
+			data := a.dataset()
+			if err := a.extractor.ApplyHits(data); err != nil {
+				dialog.ShowError(err, a.mainWindow)
+				return
+			}
+			a.saveStore()
+			if a.server != nil {
+				a.server.SetRecords(data)
+			}
+			a.records.Refresh()
+			seen := len(extractor.SeenAttacking(data))
+			a.logger.Info("GUI", fmt.Sprintf("🍯 %d new honeypot hits imported, %d records seen attacking", added, seen))
+			a.showInformation("🍯 Honeypot hits", fmt.Sprintf("✅ %d nouveaux hits importés\n%d IPs du dataset vous ont attaqué", added, seen), a.mainWindow)
+		}()
+	}, a.mainWindow)
+	d.Show()
+}
+
+// showDuplicatesDialog lists the duplicate records of the loaded dataset and
+// merges the groups left ticked, saving the result as a new run. A stored
+// CSV dataset can be deduplicated from the same dialog.
+func (a *App) showDuplicatesDialog() {
+	fileBtn := widget.NewButton("📂 Dédoublonner un fichier CSV...", func() {
+		a.deduplicateFile()
+	})
+	data := a.dataset()
+	groups := extractor.FindDuplicates(data)
+	if len(groups) == 0 {
+		content := container.NewVBox(widget.NewLabel("Aucun doublon dans le dataset chargé"), fileBtn)
+		dialog.ShowCustom(a.text("🧹 Doublons"), "Fermer", content, a.mainWindow)
+		return
+	}
+	labels := make([]string, len(groups))
+	for i, g := range groups {
+		labels[i] = DuplicateLabel(data, g)
+	}
+	checks := widget.NewCheckGroup(labels, nil)
+	checks.SetSelected(labels)
+	scroll := container.NewScroll(checks)
+	scroll.SetMinSize(fyne.NewSize(600, 300))
+	header := widget.NewLabel(fmt.Sprintf("%d groupes de doublons. Les groupes cochés sont fusionnés dans l'enregistrement le plus récent.", len(groups)))
+	content := container.NewBorder(header, fileBtn, nil, nil, scroll)
+	dialog.ShowCustomConfirm(a.text("🧹 Doublons"), "Fusionner", "Annuler", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		ticked := map[string]bool{}
+		for _, l := range checks.Selected {
+			ticked[l] = true
+		}
+		var merge []extractor.DuplicateGroup
+		for i, g := range groups {
+			if ticked[labels[i]] {
+				merge = append(merge, g)
+			}
+		}
+		if len(merge) == 0 {
+			return
+		}
+		before := len(data)
+		a.editData(extractor.MergeDuplicates(data, merge))
+		msg := fmt.Sprintf("✅ %d groupes fusionnés, %d enregistrements supprimés", len(merge), before-len(a.data))
+		if name, err := a.saveRun(); err != nil {
+			a.logger.Warning("GUI", "Run not saved after merging duplicates: "+err.Error())
+		} else {
+			msg += "\nRun: " + name
+		}
+		a.logger.Info("GUI", fmt.Sprintf("Merged %d duplicate groups", len(merge)))
+		a.showInformation("Doublons", msg, a.mainWindow)
+	}, a.mainWindow)
+}
+
+// deduplicateFile merges every duplicate of a stored CSV dataset and writes
+// the result next to the other runs as <name>_dedup.csv.
+func (a *App) deduplicateFile() {
+	d := dialog.NewFileOpen(func(r fyne.URIReadCloser, err error) {
+		if err != nil || r == nil {
+			return
+		}
+		r.Close()
+		data, err := LoadCSVData(r.URI().Path())
+		if err != nil {
+			dialog.ShowError(err, a.mainWindow)
+			return
+		}
+		groups := extractor.FindDuplicates(data)
+		if len(groups) == 0 {
+			a.showInformation("Doublons", "Aucun doublon dans "+r.URI().Name(), a.mainWindow)
+			return
+		}
+		merged := extractor.MergeDuplicates(data, groups)
+		name := strings.TrimSuffix(r.URI().Name(), filepath.Ext(r.URI().Name())) + "_dedup.csv"
+		if err := a.extractor.Export(merged, name); err != nil {
+			dialog.ShowError(err, a.mainWindow)
+			return
+		}
+		a.showInformation("Doublons", fmt.Sprintf("✅ %d groupes fusionnés, %d enregistrements supprimés\nCSV: %s",
+			len(groups), len(data)-len(merged), name), a.mainWindow)
+	}, a.mainWindow)
+	d.SetFilter(storage.NewExtensionFileFilter([]string{".csv"}))
+	d.Show()
+}
+
+// showTagRulesDialog edits the auto-tagging rules. Each change is validated,
+// saved to the configuration and applied to the loaded dataset.
+func (a *App) showTagRulesDialog() {
+	rules := append([]models.TagRule(nil), a.config.Database.TagRules...)
+	selected := -1
+	list := widget.NewList(
+		func() int { return len(rules) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(i widget.ListItemID, o fyne.CanvasObject) { o.(*widget.Label).SetText(tagRuleSummary(rules[i])) },
+	)
+	list.OnSelected = func(i widget.ListItemID) { selected = i }
+
+	save := func(next []models.TagRule) {
+		prev := a.config.Database.TagRules
+		a.config.Database.TagRules = next
+		if err := config.Validate(a.config); err != nil {
+			a.config.Database.TagRules = prev
+			a.showConfigProblems(err)
+			return
+		}
+		cm := config.NewConfigManager()
+		_, _ = cm.Load()
+		if err := cm.Save(a.config); err != nil {
+			a.config.Database.TagRules = prev
+			dialog.ShowError(err, a.mainWindow)
+			return
+		}
+		a.extractor.ApplyConfig(a.config.Database)
+		data := a.dataset()
+		a.extractor.ApplyTagRules(data)
+		a.saveStore()
+		if a.server != nil {
+			a.server.SetRecords(data)
+		}
+		a.records.Refresh()
+		rules = append([]models.TagRule(nil), next...)
+		list.UnselectAll()
+		selected = -1
+		list.Refresh()
+	}
+
+	edit := func(index int) {
+		var r models.TagRule
+		if index >= 0 {
+			r = rules[index]
+		}
+		name := widget.NewEntry()
+		name.SetText(r.Name)
+		tags := widget.NewEntry()
+		tags.SetText(strings.Join(r.Tags, ", "))
+		countries := widget.NewEntry()
+		countries.SetPlaceHolder("CN, RU")
+		countries.SetText(strings.Join(r.Countries, ", "))
+		asns := widget.NewEntry()
+		asns.SetPlaceHolder("AS4134, AS16276")
+		asns.SetText(strings.Join(r.ASNs, ", "))
+		org := widget.NewEntry()
+		org.SetPlaceHolder("(?i)cloud|hosting")
+		org.SetText(r.OrgRegex)
+		types := widget.NewEntry()
+		types.SetPlaceHolder("shodan, censys")
+		types.SetText(strings.Join(r.ScannerTypes, ", "))
+		items := []*widget.FormItem{
+			widget.NewFormItem("Name", name),
+			widget.NewFormItem("Tags", tags),
+			widget.NewFormItem("Countries", countries),
+			widget.NewFormItem("ASNs", asns),
+			widget.NewFormItem("Org regex", org),
+			widget.NewFormItem("Scanner types", types),
+		}
+		form := dialog.NewForm(a.text("🏷️ Règle de tag"), "OK", "Annuler", items, func(ok bool) {
+			if !ok {
+				return
+			}
+			rule := models.TagRule{
+				Name:         strings.TrimSpace(name.Text),
+				Tags:         splitList(tags.Text),
+				Countries:    splitList(countries.Text),
+				ASNs:         splitList(asns.Text),
+				OrgRegex:     strings.TrimSpace(org.Text),
+				ScannerTypes: splitList(types.Text),
+			}
+			next := append([]models.TagRule(nil), rules...)
+			if index >= 0 {
+				next[index] = rule
+			} else {
+				next = append(next, rule)
+			}
+			save(next)
+		}, a.mainWindow)
+		form.Resize(fyne.NewSize(500, 380))
+		form.Show()
+	}
+
+	addBtn := widget.NewButton("➕ Ajouter", func() { edit(-1) })
+	editBtn := widget.NewButton("✏️ Modifier", func() {
+		if selected >= 0 {
+			edit(selected)
+		}
+	})
+	deleteBtn := widget.NewButton("🗑️ Supprimer", func() {
+		if selected < 0 {
+			return
+		}
+		next := append(append([]models.TagRule(nil), rules[:selected]...), rules[selected+1:]...)
+		save(next)
+	})
+	help := widget.NewLabel("Each rule adds its tags to the records matching all of its conditions.\nRules run on extraction and re-enrichment, and on the loaded data when saved.")
+	content := container.NewBorder(help, container.NewHBox(addBtn, editBtn, deleteBtn), nil, nil, list)
+	d := dialog.NewCustom(a.text("🏷️ Règles de tag automatiques"), "Fermer", content, a.mainWindow)
+	d.Resize(fyne.NewSize(700, 450))
+	d.Show()
+}
@@ -12,22 +12,154 @@ import (
 	"strings"
 	"time"
 
+	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
 
-	"github.com/lia/liacheckscanner_go/internal/models"
+	"github.com/lia/liacheckscanner_go/pkg/extractor"
+	"github.com/lia/liacheckscanner_go/pkg/models"
 )
 
-// exportAllData exports all data to a CSV file with professional formatting
-// It creates a timestamped file in the results directory
+// exportFormatDefault is the format selector entry for the application's own
+// CSV layout
+const exportFormatDefault = "LiaCheckScanner CSV"
+
+// chooseExportFormat asks for the export format, the risk, scanner type and
+// country filters, whether to anonymize and, when presets are configured,
+// the redaction preset, then calls export with "" for the application's
+// layout or with the name of an export template, and with the records to
+// write
+func (a *App) chooseExportFormat(title string, records []models.ScannerData, export func(template string, records []models.ScannerData)) {
+	templates := extractor.ExportTemplates()
+	options := []string{exportFormatDefault}
+	for _, t := range templates {
+		options = append(options, t.Description)
+	}
+	formatSelect := widget.NewSelect(options, nil)
+	formatSelect.SetSelected(exportFormatDefault)
+	riskSelect := widget.NewSelect(append([]string{exportFilterAll}, ExportRiskLevels...), nil)
+	riskSelect.SetSelected(exportFilterAll)
+	typeSelect := widget.NewSelect(append([]string{exportFilterAll}, ExportScannerTypes()...), nil)
+	typeSelect.SetSelected(exportFilterAll)
+	countryEntry := widget.NewEntry()
+	countryEntry.SetPlaceHolder("All, or country codes: CN,RU")
+	anonCheck := widget.NewCheck(a.text("🕶️ Anonymize for external sharing (emails, host names, notes)"), nil)
+	filters := container.NewGridWithColumns(3,
+		container.NewVBox(widget.NewLabel("Risk Level:"), riskSelect),
+		container.NewVBox(widget.NewLabel("Scanner Type:"), typeSelect),
+		container.NewVBox(widget.NewLabel("Countries:"), countryEntry),
+	)
+	content := container.NewVBox(widget.NewLabel(a.text("📑 Format:")), formatSelect, filters, anonCheck)
+	var presetSelect *widget.Select
+	if names := a.extractor.ExportPresetNames(); len(names) > 0 {
+		options, selected := ExportPresetOptions(names, a.config.Database.DefaultExportPreset)
+		presetSelect = widget.NewSelect(options, nil)
+		presetSelect.SetSelected(selected)
+		content.Add(widget.NewLabel(a.text("🧹 Redaction preset:")))
+		content.Add(presetSelect)
+	}
+	dialog.ShowCustomConfirm(a.text(title), "Export", "Cancel", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		name := ""
+		for _, t := range templates {
+			if t.Description == formatSelect.Selected {
+				name = t.Name
+			}
+		}
+		filter := ExportFilterFor(riskSelect.Selected, typeSelect.Selected, countryEntry.Text)
+		if !filter.Empty() {
+			records = filter.Apply(records)
+			a.logger.Info("GUI", fmt.Sprintf("🎯 Export filtered: %d records", len(records)))
+		}
+		if anonCheck.Checked {
+			records = extractor.Anonymize(records)
+			a.logger.Info("GUI", "🕶️ Export anonymized")
+		}
+		preset := ""
+		if presetSelect != nil {
+			preset = ExportPresetFor(presetSelect.Selected)
+		}
+		redacted, err := a.extractor.RedactForExport(records, preset)
+		if err != nil {
+			dialog.ShowError(err, a.mainWindow)
+			return
+		}
+		records = redacted
+		export(name, records)
+	}, a.mainWindow)
+}
+
+// exportWithTemplate writes records with an export template to a timestamped
+// file in the results directory
+func (a *App) exportWithTemplate(records []models.ScannerData, prefix, template string) {
+	tmpl, ok := extractor.LookupExportTemplate(template)
+	if !ok {
+		dialog.ShowError(fmt.Errorf("unknown export template %q", template), a.mainWindow)
+		return
+	}
+	if tmpl.Enforcement {
+		records = extractor.Enforceable(records)
+	}
+	timestamp := time.Now().Format("2006-01-02_15-04-05")
+	filename := fmt.Sprintf("%s_%s_%s%s", prefix, tmpl.Name, timestamp, tmpl.Extension)
+	if err := a.extractor.ExportWithTemplate(records, filename, tmpl.Name); err != nil {
+		dialog.ShowError(err, a.mainWindow)
+		return
+	}
+	path := filepath.Join(a.resultsDir(), filename)
+	a.logger.Info("GUI", fmt.Sprintf("✅ %d records exported (%s) to %s", len(records), tmpl.Description, path))
+	a.showInformation("Export Success", fmt.Sprintf("✅ %s export written to:\n%s", tmpl.Description, path), a.mainWindow)
+}
+
+// exportAllData asks for the export format and exports all data
 func (a *App) exportAllData() {
-	if len(a.data) == 0 {
-		dialog.ShowInformation("Export", "⚠️ No data to export", a.mainWindow)
+	data := a.dataset()
+	if len(data) == 0 {
+		a.showInformation("Export", "⚠️ No data to export", a.mainWindow)
 		return
 	}
+	a.chooseExportFormat("📤 Export All", data, func(template string, records []models.ScannerData) {
+		if template != "" {
+			a.exportWithTemplate(records, "liacheckscanner_export", template)
+			return
+		}
+		a.writeAllDataCSV(records)
+	})
+}
 
+// exportXLSX writes all data, through the default export preset, to a
+// timestamped Excel workbook in the results directory, with one sheet per
+// scanner
+func (a *App) exportXLSX() {
+	data := a.dataset()
+	if len(data) == 0 {
+		a.showInformation("Export", "⚠️ No data to export", a.mainWindow)
+		return
+	}
+	records, err := a.extractor.RedactForExport(data, "")
+	if err != nil {
+		dialog.ShowError(err, a.mainWindow)
+		return
+	}
+	timestamp := time.Now().Format("2006-01-02_15-04-05")
+	filename := fmt.Sprintf("liacheckscanner_export_%s.xlsx", timestamp)
+	if err := a.extractor.SaveToXLSX(records, filename); err != nil {
+		dialog.ShowError(err, a.mainWindow)
+		return
+	}
+	path := filepath.Join(a.resultsDir(), filename)
+	a.logger.Info("GUI", fmt.Sprintf("✅ %d records exported to %s", len(records), path))
+	a.showInformation("Export Success", fmt.Sprintf("✅ Excel workbook written to:\n%s", path), a.mainWindow)
+}
+
+// writeAllDataCSV exports records to a CSV file with professional formatting
+// It creates a timestamped file in the results directory
+func (a *App) writeAllDataCSV(records []models.ScannerData) {
 	// Generate professional filename
 	timestamp := time.Now().Format("2006-01-02_15-04-05")
-	filename := fmt.Sprintf("results/liacheckscanner_export_%s.csv", timestamp)
+	filename := filepath.Join(a.resultsDir(), fmt.Sprintf("liacheckscanner_export_%s.csv", timestamp))
 
 	// Create CSV file
 	file, err := os.Create(filename)
@@ -45,7 +177,7 @@ func (a *App) exportAllData() {
 	writer.Write(headers)
 
 	// Export data
-	for _, item := range a.data {
+	for _, item := range records {
 		row := []string{
 			item.IPOrCIDR,
 			item.ScannerName,
@@ -61,8 +193,8 @@ func (a *App) exportAllData() {
 		writer.Write(row)
 	}
 
-	a.logger.Info("GUI", fmt.Sprintf("✅ %d records exported to %s", len(a.data), filename))
-	dialog.ShowInformation("Export Success", fmt.Sprintf("✅ %d records exported to:\n%s", len(a.data), filename), a.mainWindow)
+	a.logger.Info("GUI", fmt.Sprintf("✅ %d records exported to %s", len(records), filename))
+	a.showInformation("Export Success", fmt.Sprintf("✅ %d records exported to:\n%s", len(records), filename), a.mainWindow)
 }
 
 // exportSelected exports selected data with professional confirmation
@@ -76,14 +208,15 @@ func (a *App) exportSelected() {
 
 // exportSelectedData performs the actual export of selected data
 func (a *App) exportSelectedData() {
+	data := a.dataset()
 	selectedRows := a.getSelectedRows()
 	if len(selectedRows) == 0 {
-		dialog.ShowInformation("Export", "No records selected for export", a.mainWindow)
+		a.showInformation("Export", "No records selected for export", a.mainWindow)
 		return
 	}
 
 	timestamp := time.Now().Format("2006-01-02_15-04-05")
-	filename := fmt.Sprintf("results/selected_export_%s.csv", timestamp)
+	filename := filepath.Join(a.resultsDir(), fmt.Sprintf("selected_export_%s.csv", timestamp))
 
 	file, err := os.Create(filename)
 	if err != nil {
@@ -101,8 +234,8 @@ func (a *App) exportSelectedData() {
 
 	// Export selected data
 	for _, index := range selectedRows {
-		if index < len(a.data) {
-			item := a.data[index]
+		if index < len(data) {
+			item := data[index]
 			row := []string{
 				item.IPOrCIDR,
 				item.ScannerName,
@@ -118,7 +251,7 @@ func (a *App) exportSelectedData() {
 	}
 
 	a.logger.Info("GUI", fmt.Sprintf("✅ %d selected records exported to %s", len(selectedRows), filename))
-	dialog.ShowInformation("Export Success", fmt.Sprintf("✅ %d records exported to:\n%s", len(selectedRows), filename), a.mainWindow)
+	a.showInformation("Export Success", fmt.Sprintf("✅ %d records exported to:\n%s", len(selectedRows), filename), a.mainWindow)
 }
 
 // getSelectedRows returns indices of selected rows (simulated for now)
@@ -126,8 +259,8 @@ func (a *App) getSelectedRows() []int {
 	// For now, return first 500 rows as a simulation
 	// In a real implementation, this would track actual user selection
 	maxRows := 500
-	if len(a.data) < maxRows {
-		maxRows = len(a.data)
+	if n := a.recordCount(); n < maxRows {
+		maxRows = n
 	}
 
 	var selected []int
@@ -137,15 +270,25 @@ func (a *App) getSelectedRows() []int {
 	return selected
 }
 
-// exportSearchResults exports search results to CSV
+// exportSearchResults asks for the export format and exports search results
 func (a *App) exportSearchResults() {
 	if len(a.searchResults) == 0 {
-		dialog.ShowInformation("Export", "No search results to export", a.mainWindow)
+		a.showInformation("Export", "No search results to export", a.mainWindow)
 		return
 	}
+	a.chooseExportFormat("📤 Export Results", a.searchResults, func(template string, records []models.ScannerData) {
+		if template != "" {
+			a.exportWithTemplate(records, "search_results", template)
+			return
+		}
+		a.writeSearchResultsCSV(records)
+	})
+}
 
+// writeSearchResultsCSV exports search results to CSV
+func (a *App) writeSearchResultsCSV(records []models.ScannerData) {
 	timestamp := time.Now().Format("2006-01-02_15-04-05")
-	filename := fmt.Sprintf("results/search_results_%s.csv", timestamp)
+	filename := filepath.Join(a.resultsDir(), fmt.Sprintf("search_results_%s.csv", timestamp))
 
 	file, err := os.Create(filename)
 	if err != nil {
@@ -160,7 +303,7 @@ func (a *App) exportSearchResults() {
 	headers := []string{"IP/CIDR", "Scanner", "Type", "Country", "ISP", "Risk", "Score", "Last Seen"}
 	writer.Write(headers)
 
-	for _, item := range a.searchResults {
+	for _, item := range records {
 		row := []string{
 			item.IPOrCIDR,
 			item.ScannerName,
@@ -174,8 +317,8 @@ func (a *App) exportSearchResults() {
 		writer.Write(row)
 	}
 
-	a.logger.Info("GUI", fmt.Sprintf("✅ %d search results exported to %s", len(a.searchResults), filename))
-	dialog.ShowInformation("Export Success", fmt.Sprintf("✅ %d search results exported to:\n%s", len(a.searchResults), filename), a.mainWindow)
+	a.logger.Info("GUI", fmt.Sprintf("✅ %d search results exported to %s", len(records), filename))
+	a.showInformation("Export Success", fmt.Sprintf("✅ %d search results exported to:\n%s", len(records), filename), a.mainWindow)
 }
 
 // exportLogs exports logs to file (placeholder implementation)
@@ -185,7 +328,7 @@ func (a *App) exportLogs() {
 
 	// Implementation would export actual logs
 	a.logger.Info("GUI", "Logs exported to: "+filename)
-	dialog.ShowInformation("Export Success", "Logs exported to:\n"+filename, a.mainWindow)
+	a.showInformation("Export Success", "Logs exported to:\n"+filename, a.mainWindow)
 }
 
 // zipDirectory zips a directory to the given zip file
@@ -0,0 +1,522 @@
+package gui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/lia/liacheckscanner_go/internal/logger"
+	"github.com/lia/liacheckscanner_go/internal/store"
+	"github.com/lia/liacheckscanner_go/pkg/extractor"
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// -------------------------------------------------------
+// Headless harness
+// -------------------------------------------------------
+
+// newTestApp builds the App on Fyne's headless test driver and a
+// MockBackend, in a temporary working directory, with data injected as the
+// loaded dataset. The first load is marked done, so showing the Database
+// tab never starts an extraction.
+func newTestApp(t *testing.T, data []models.ScannerData) *App {
+	t.Helper()
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	cfg := &models.AppConfig{Database: models.DatabaseConfig{
+		RepoURL:    "https://example.com/repo",
+		LocalPath:  filepath.Join(dir, "repo"),
+		ResultsDir: filepath.Join(dir, "results"),
+		LogsDir:    filepath.Join(dir, "logs"),
+		StorePath:  filepath.Join(dir, "records.db"),
+	}}
+	if err := os.MkdirAll(cfg.Database.ResultsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	fyneApp := test.NewApp()
+	t.Cleanup(fyneApp.Quit)
+	log := logger.NewLogger()
+	a := newApp(fyneApp, cfg, log, NewMockBackend(cfg.Database, log, nil))
+	if a.store != nil {
+		t.Cleanup(func() { a.store.Close() })
+	}
+	a.dataOnce.Do(func() {})
+	if data != nil {
+		// Saved to the record store too, as a load does, for the searches
+		a.setData(data)
+	}
+	return a
+}
+
+// testRecords returns n records with distinct IPs.
+func testRecords(n int) []models.ScannerData {
+	data := make([]models.ScannerData, n)
+	for i := range data {
+		data[i] = models.ScannerData{
+			ID:          fmt.Sprintf("%d", i+1),
+			IPOrCIDR:    fmt.Sprintf("10.0.%d.%d", i/256, i%256),
+			ScannerName: "Shodan",
+			ScannerType: models.ScannerTypeShodan,
+			CountryCode: "US",
+			RiskLevel:   "High",
+			LastSeen:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		}
+	}
+	return data
+}
+
+// children returns the objects obj directly holds, asking widgets for the
+// objects of their renderer. Every item of a tab container is included,
+// selected or not.
+func children(obj fyne.CanvasObject) []fyne.CanvasObject {
+	switch o := obj.(type) {
+	case *fyne.Container:
+		return o.Objects
+	case *container.AppTabs:
+		var out []fyne.CanvasObject
+		for _, item := range o.Items {
+			out = append(out, item.Content)
+		}
+		return out
+	case *container.Scroll:
+		return []fyne.CanvasObject{o.Content}
+	case fyne.Widget:
+		return test.WidgetRenderer(o).Objects()
+	}
+	return nil
+}
+
+// findButton returns the first button under obj whose text contains text.
+func findButton(t *testing.T, obj fyne.CanvasObject, text string) *widget.Button {
+	t.Helper()
+	var found *widget.Button
+	var walk func(fyne.CanvasObject)
+	walk = func(o fyne.CanvasObject) {
+		if found != nil || o == nil {
+			return
+		}
+		if b, ok := o.(*widget.Button); ok && strings.Contains(b.Text, text) {
+			found = b
+			return
+		}
+		for _, c := range children(o) {
+			walk(c)
+		}
+	}
+	walk(obj)
+	if found == nil {
+		t.Fatalf("no button %q", text)
+	}
+	return found
+}
+
+// showTab selects the main tab titled title and returns its content.
+func showTab(t *testing.T, a *App, title string) fyne.CanvasObject {
+	t.Helper()
+	item := a.tabItem(title)
+	if item == nil {
+		t.Fatalf("no tab %q", title)
+	}
+	a.tabs.Select(item)
+	return item.Content
+}
+
+func TestHarness_AllTabsBuild(t *testing.T) {
+	tabs := []string{tabDashboard, tabDatabase, tabSearch, tabRuns, tabConfig, tabLogs}
+	for _, tc := range []struct {
+		name string
+		data []models.ScannerData
+	}{
+		{"no data", nil},
+		{"data", testRecords(3)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := newTestApp(t, tc.data)
+			if len(a.tabBuilders) != len(tabs)-1 {
+				t.Errorf("%d tabs built at startup, want the dashboard only", len(tabs)-len(a.tabBuilders))
+			}
+			for _, title := range tabs {
+				if _, ok := showTab(t, a, title).(*widget.Label); ok {
+					t.Errorf("tab %s still shows its placeholder", title)
+				}
+			}
+			if len(a.tabBuilders) != 0 {
+				t.Errorf("%d tabs never built", len(a.tabBuilders))
+			}
+			if a.startRDAPEnrichment == nil {
+				t.Error("Database tab did not set up the RDAP job")
+			}
+		})
+	}
+}
+
+func TestHarness_Pagination(t *testing.T) {
+	a := newTestApp(t, testRecords(250))
+	db := showTab(t, a, tabDatabase)
+	a.records.Refresh()
+	if a.records.totalPages != 3 {
+		t.Fatalf("totalPages = %d, want 3", a.records.totalPages)
+	}
+
+	test.Tap(findButton(t, db, "Next"))
+	if a.records.currentPage != 2 {
+		t.Errorf("after Next, page %d, want 2", a.records.currentPage)
+	}
+	test.Tap(findButton(t, db, "Last"))
+	test.Tap(findButton(t, db, "Next"))
+	if a.records.currentPage != 3 {
+		t.Errorf("Next on the last page moved to page %d", a.records.currentPage)
+	}
+	if start, end := a.records.pageBounds(); start != 200 || end != 250 {
+		t.Errorf("last page bounds = %d-%d, want 200-250", start, end)
+	}
+	test.Tap(findButton(t, db, "First"))
+	test.Tap(findButton(t, db, "Previous"))
+	if a.records.currentPage != 1 {
+		t.Errorf("Previous on the first page moved to page %d", a.records.currentPage)
+	}
+
+	// Fewer records than the current page used to show
+	a.editData(testRecords(0))
+	test.Tap(findButton(t, db, "Last"))
+	test.Tap(findButton(t, db, "Next"))
+	if a.records.currentPage != 1 || a.records.totalPages != 1 {
+		t.Errorf("empty data: page %d of %d, want 1 of 1", a.records.currentPage, a.records.totalPages)
+	}
+}
+
+func TestHarness_StorePaging(t *testing.T) {
+	a := newTestApp(t, testRecords(250))
+	db := showTab(t, a, tabDatabase)
+
+	// A restart shows the stored dataset without reading all of it
+	a.loadData()
+	if a.data != nil || !a.stored {
+		t.Fatalf("%d records read from the store to show it", len(a.data))
+	}
+	if !a.records.paged() || a.records.totalPages != 3 || len(a.records.pageItems) != 100 {
+		t.Fatalf("paged %v, %d pages, %d records read, want 3 pages of 100", a.records.paged(), a.records.totalPages, len(a.records.pageItems))
+	}
+	if got := a.stats.Snapshot().Total; got != 250 {
+		t.Errorf("dashboard counts %d records, want 250", got)
+	}
+	test.Tap(findButton(t, db, "Last"))
+	if item, ok := a.records.record(210); !ok || item.IPOrCIDR != "10.0.0.210" {
+		t.Errorf("record 210 = %s, want 10.0.0.210", item.IPOrCIDR)
+	}
+	if len(a.records.pageItems) != 50 {
+		t.Errorf("last page read %d records, want 50", len(a.records.pageItems))
+	}
+
+	// Another writer changes a record the edit below does not touch: only
+	// the record edited is written back
+	other := testRecords(8)[7]
+	other.RDAPName = "WRITTEN ELSEWHERE"
+	if err := a.store.Upsert(other); err != nil {
+		t.Fatal(err)
+	}
+	a.records.selectedRows[5] = true
+	a.runBulkAction(bulkRisk, a.records.selectedRecords(), "", "Low", "", "")
+	if a.stored || len(a.data) != 250 {
+		t.Fatalf("bulk action on %d records, want the dataset of 250", len(a.data))
+	}
+	stored, err := a.store.Search(store.Query{Text: "10.0.0."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 250 || stored[5].RiskLevel != "Low" || stored[7].RDAPName != "WRITTEN ELSEWHERE" {
+		t.Errorf("stored after the edit: %d records, risk %s, record 7 %q", len(stored), stored[5].RiskLevel, stored[7].RDAPName)
+	}
+}
+
+func TestHarness_Search(t *testing.T) {
+	a := newTestApp(t, testRecords(300))
+	search := showTab(t, a, tabSearch)
+
+	a.performAdvancedSearch("10.0.1.", "All Countries", "All Scanners", "All Risk Levels", ConfidenceLabels[0], false, extractor.TimeWindow{})
+	if len(a.searchResults) != 44 {
+		t.Errorf("search 10.0.1. found %d records, want 44", len(a.searchResults))
+	}
+	test.Tap(findButton(t, search, "Clear Results"))
+	if len(a.searchResults) != 0 {
+		t.Errorf("%d results left after Clear", len(a.searchResults))
+	}
+
+	// The button reads the form as it is left by Clear
+	test.Tap(findButton(t, search, "Perform Search"))
+
+	empty := newTestApp(t, nil)
+	test.Tap(findButton(t, showTab(t, empty, tabSearch), "Perform Search"))
+	if len(empty.searchResults) != 0 {
+		t.Errorf("search over no data found %d records", len(empty.searchResults))
+	}
+}
+
+func TestHarness_ExportHandlers(t *testing.T) {
+	empty := newTestApp(t, nil)
+	empty.exportAllData()
+	empty.exportSearchResults()
+
+	a := newTestApp(t, testRecords(5))
+	a.exportAllData()
+	a.writeAllDataCSV(a.data)
+	a.setSearchResults(a.data[:2])
+	a.writeSearchResultsCSV(a.searchResults)
+	a.exportWithTemplate(a.data, "liacheckscanner_export", "misp")
+
+	for _, pattern := range []string{"liacheckscanner_export_*.csv", "search_results_*.csv", "liacheckscanner_export_misp_*"} {
+		matches, _ := filepath.Glob(filepath.Join(a.resultsDir(), pattern))
+		if len(matches) != 1 {
+			t.Errorf("%s: %d files written, want 1", pattern, len(matches))
+		}
+	}
+}
+
+func TestHarness_MockBackend(t *testing.T) {
+	a := newTestApp(t, nil)
+	a.config.Database.SkipEnrichment = true
+	mock := a.extractor.(*MockBackend)
+	mock.Records = testRecords(4)
+	mock.Enriched["10.0.0.1"] = models.ScannerData{
+		RDAPName:    "EXAMPLE-NET",
+		RDAPCIDR:    "10.0.0.0/24",
+		AbuseEmail:  "abuse@example.net",
+		CountryCode: "FR",
+	}
+
+	if err := a.extractAndQueue(); err != nil {
+		t.Fatalf("extractAndQueue: %v", err)
+	}
+	if len(a.data) != 4 {
+		t.Fatalf("%d records extracted, want the 4 canned ones", len(a.data))
+	}
+
+	if err := a.enrichRecord(context.Background(), 1, 0); err != nil {
+		t.Fatalf("enrichRecord: %v", err)
+	}
+	if got := a.data[1]; got.RDAPName != "EXAMPLE-NET" || got.CountryCode != "FR" || got.ID != "2" {
+		t.Errorf("enriched record = %+v, want the canned RDAP data with its own ID", got)
+	}
+	if err := a.enrichRecord(context.Background(), 2, 0); err == nil {
+		t.Error("enriching an IP without canned data succeeded")
+	}
+
+	if got := a.performRealRDAPLookup("10.0.0.1"); !strings.Contains(got, "10.0.0.0/24") || !strings.Contains(got, "abuse@example.net") {
+		t.Errorf("RDAP lookup shows:\n%s", got)
+	}
+	if got := a.performRealRDAPLookup("192.0.2.1"); !strings.Contains(got, "unavailable") {
+		t.Errorf("RDAP lookup of an unknown IP shows:\n%s", got)
+	}
+}
+
+func TestHarness_Ports(t *testing.T) {
+	a := newTestApp(t, testRecords(3))
+	mock := a.extractor.(*MockBackend)
+	mock.Enriched["10.0.0.1"] = models.ScannerData{OpenPorts: []int{22, 443}, Hostnames: []string{"scan.example.net"}}
+
+	got := a.performRealIPEnrichment("10.0.0.1")
+	if !strings.Contains(got, "Open Ports: 22, 443") || strings.Contains(got, "SSH, HTTP, HTTPS") {
+		t.Errorf("IP enrichment shows:\n%s", got)
+	}
+	if r := a.data[1]; len(r.OpenPorts) != 2 || r.Hostnames[0] != "scan.example.net" || r.Provenance[models.ProvenancePorts] == "" {
+		t.Errorf("dataset record not updated: %+v", r)
+	}
+	if len(a.data[0].OpenPorts) != 0 {
+		t.Errorf("other record updated: %+v", a.data[0])
+	}
+	if got := a.performRealIPEnrichment("192.0.2.1"); !strings.Contains(got, "unavailable") {
+		t.Errorf("lookup of an unknown IP shows:\n%s", got)
+	}
+}
+
+func TestHarness_Sample(t *testing.T) {
+	a := newTestApp(t, testRecords(300))
+	if err := NewMockBackend(a.config.Database, a.logger, nil).SaveToCSV(testRecords(300), "run_liacheckscanner.csv"); err != nil {
+		t.Fatal(err)
+	}
+	files := a.csvFilesNewestFirst()
+	if len(files) != 1 {
+		t.Fatalf("%d runs found, want 1", len(files))
+	}
+	s, err := extractor.SampleCSVFile(files[0], 25, extractor.SampleRandom, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.showSample(s)
+
+	if len(a.data) != 25 || a.stats.Snapshot().Total != 25 {
+		t.Errorf("%d records shown, %d counted, want the 25 sampled", len(a.data), a.stats.Snapshot().Total)
+	}
+	if !strings.Contains(a.statsLabel.Text, "25 of 300") {
+		t.Errorf("statistics do not say they reflect a sample:\n%s", a.statsLabel.Text)
+	}
+	if _, err := a.saveRun(); err != errSampleLoaded {
+		t.Errorf("saveRun with a sample loaded: %v, want errSampleLoaded", err)
+	}
+	// The search covers the sample, not the whole dataset of the store
+	a.performAdvancedSearch("10.0.", "All Countries", "All Scanners", "All Risk Levels", ConfidenceLabels[0], false, extractor.TimeWindow{})
+	if len(a.searchResults) != 25 {
+		t.Errorf("search over the sample found %d records, want 25", len(a.searchResults))
+	}
+
+	// An edit keeps the sample; loading a dataset replaces it
+	a.editData(a.data[:20])
+	if a.sample == nil {
+		t.Error("editing the sample forgot it was a sample")
+	}
+	a.setData(testRecords(3))
+	if a.sample != nil || strings.Contains(a.statsLabel.Text, "Sample") {
+		t.Error("loading a dataset kept the sample notice")
+	}
+}
+
+func TestHarness_AutoUpdate(t *testing.T) {
+	old := testRecords(3)
+	for i := range old[:2] {
+		old[i].RDAPName = "NET-" + old[i].IPOrCIDR
+		old[i].Provenance = map[string]string{models.ProvenanceRDAP: "arin@2024-01-01"}
+	}
+	a := newTestApp(t, old)
+	mock := a.extractor.(*MockBackend)
+	// 10.0.0.1 stays, 10.0.0.2 was never enriched, 10.0.0.0 is gone and
+	// 10.0.0.3 is new
+	mock.Records = testRecords(4)[1:]
+
+	if err := a.autoUpdate(); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.data) != 3 {
+		t.Fatalf("%d records after the update, want 3", len(a.data))
+	}
+	if a.data[0].RDAPName != "NET-10.0.0.1" {
+		t.Errorf("enrichment of 10.0.0.1 not carried over: %+v", a.data[0])
+	}
+	if a.data[1].RDAPName != "" || a.data[2].RDAPName != "" {
+		t.Error("records never enriched got an RDAP name")
+	}
+	if !strings.Contains(a.statusBar.Text, "+1, -1") {
+		t.Errorf("status bar = %q, want the added and removed counts", a.statusBar.Text)
+	}
+
+	a.setBusy(true, "test")
+	if err := a.autoUpdate(); err != errUpdateSkipped {
+		t.Errorf("update during a run: %v, want errUpdateSkipped", err)
+	}
+	a.setBusy(false, "")
+}
+
+func TestHarness_RateMetrics(t *testing.T) {
+	a := newTestApp(t, testRecords(1))
+	a.updateRateMetrics()
+	if a.rateLabel == nil || !strings.Contains(a.rateLabel.Text, "No request sent yet") {
+		t.Errorf("dashboard rates = %v, want no request yet", a.rateLabel)
+	}
+	if a.rateStatus != nil && !strings.HasPrefix(a.rateStatus.Text, "🚦 0 req/min") {
+		t.Errorf("Database tab rates = %q", a.rateStatus.Text)
+	}
+}
+
+func TestHarness_TailLogs(t *testing.T) {
+	a := newTestApp(t, testRecords(1))
+	display := widget.NewMultiLineEntry()
+	done := make(chan struct{})
+	go a.tailLogs(display, container.NewScroll(display), done)
+	defer close(done)
+
+	a.logger.Info("GUI", "tail me")
+	deadline := time.Now().Add(3 * logViewerInterval)
+	for !strings.Contains(display.Text, "tail me") {
+		if time.Now().After(deadline) {
+			t.Fatalf("live tail did not show the new entry: %q", display.Text)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestHarness_VerifyDataset(t *testing.T) {
+	a := newTestApp(t, testRecords(1))
+	name, err := a.extractor.SaveRun(testRecords(3))
+	if err != nil {
+		t.Fatalf("SaveRun: %v", err)
+	}
+	path := filepath.Join(a.resultsDir(), name)
+	if w := a.verifyDataset(path); w != "" {
+		t.Errorf("verifyDataset of an intact run = %q", w)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("203.0.113.7\n")
+	f.Close()
+	if w := a.verifyDataset(path); !strings.Contains(w, "modified outside") {
+		t.Errorf("verifyDataset after an edit = %q", w)
+	}
+}
+
+func TestHarness_LogLevelSelects(t *testing.T) {
+	a := newTestApp(t, testRecords(1))
+	a.createConfigTab()
+	a.createLogsTab()
+	if len(a.logLevelSelects) != 2 {
+		t.Fatalf("%d log level selects, want the Config and Logs tab ones", len(a.logLevelSelects))
+	}
+	a.logLevelSelects[0].SetSelected("WARNING")
+	if a.logger.GetLogLevel() != models.LogLevelWarning || a.config.LogLevel != "WARNING" {
+		t.Errorf("level = %s, config %q; want WARNING", a.logger.GetLogLevel(), a.config.LogLevel)
+	}
+	if got := a.logLevelSelects[1].Selected; got != "WARNING" {
+		t.Errorf("Logs tab select = %q, want WARNING", got)
+	}
+	a.logger.Info("GUI", "hidden below WARNING")
+	for _, e := range a.logger.GetEntries() {
+		if e.Message == "hidden below WARNING" {
+			t.Error("an INFO message was recorded at level WARNING")
+		}
+	}
+}
+
+func TestHarness_LockRecords(t *testing.T) {
+	a := newTestApp(t, testRecords(3))
+	locked := a.data[0]
+	a.runBulkAction(bulkLock, a.data[:1], "", "", "", "case 42")
+	if a.data[0].Lock == nil || a.data[0].Lock.Reason != "case 42" {
+		t.Fatalf("record not locked: %+v", a.data[0].Lock)
+	}
+
+	a.runBulkAction(bulkDelete, append([]models.ScannerData(nil), a.data...), "", "", "", "")
+	if len(a.data) != 1 || a.data[0].IPOrCIDR != locked.IPOrCIDR {
+		t.Fatalf("after deleting all: %d records, want the locked one", len(a.data))
+	}
+
+	// A new dataset does not replace the locked record
+	fresh := testRecords(3)
+	fresh[0].RDAPName = "REPLACED"
+	a.setData(fresh)
+	if a.data[0].RDAPName == "REPLACED" || a.data[0].Lock == nil {
+		t.Errorf("locked record replaced: %+v", a.data[0])
+	}
+
+	a.runBulkAction(bulkUnlock, a.data[:1], "", "", "", "")
+	if a.data[0].Lock != nil {
+		t.Error("record still locked")
+	}
+}
@@ -4,14 +4,22 @@
 package gui
 
 import (
-	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/lia/liacheckscanner_go/internal/models"
+	"github.com/lia/liacheckscanner_go/internal/logger"
+	"github.com/lia/liacheckscanner_go/internal/store"
+	"github.com/lia/liacheckscanner_go/pkg/extractor"
+	"github.com/lia/liacheckscanner_go/pkg/models"
 )
 
 // CountUniqueIPs returns the number of distinct IP/CIDR values in data.
@@ -63,6 +71,219 @@ func CountRiskLevels(data []models.ScannerData) int {
 	return len(unique)
 }
 
+// DatasetStats keeps per-IP, per-country, per-scanner and per-risk counters
+// for the loaded dataset, so the dashboard reads its figures in constant time
+// instead of walking every record on each refresh. Callers report each change
+// to the dataset (Reset, Add, Replace); it is safe for concurrent use, e.g. by
+// enrichment workers.
+type DatasetStats struct {
+	mu        sync.RWMutex
+	total     int
+	ips       map[string]int
+	countries map[string]int
+	scanners  map[string]int
+	risks     map[string]int
+}
+
+// StatsSnapshot is a consistent copy of the DatasetStats figures.
+type StatsSnapshot struct {
+	Total      int
+	UniqueIPs  int
+	Countries  int
+	Scanners   int
+	HighRisk   int
+	RiskLevels int
+}
+
+// NewDatasetStats returns counters initialized from data.
+func NewDatasetStats(data []models.ScannerData) *DatasetStats {
+	s := &DatasetStats{}
+	s.Reset(data)
+	return s
+}
+
+// Reset recounts from data, after the whole dataset was replaced.
+func (s *DatasetStats) Reset(data []models.ScannerData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.total = 0
+	s.ips = make(map[string]int, len(data))
+	s.countries = make(map[string]int)
+	s.scanners = make(map[string]int)
+	s.risks = make(map[string]int)
+	for i := range data {
+		s.add(&data[i])
+	}
+}
+
+// Add counts records appended to the dataset.
+func (s *DatasetStats) Add(records ...models.ScannerData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range records {
+		s.add(&records[i])
+	}
+}
+
+// Replace accounts for a record changed in place, e.g. by enrichment:
+// old is a copy taken before the change, updated the record after it.
+func (s *DatasetStats) Replace(old, updated models.ScannerData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remove(&old)
+	s.add(&updated)
+}
+
+// Snapshot returns the current figures.
+func (s *DatasetStats) Snapshot() StatsSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return StatsSnapshot{
+		Total:      s.total,
+		UniqueIPs:  len(s.ips),
+		Countries:  len(s.countries),
+		Scanners:   len(s.scanners),
+		HighRisk:   s.risks["High"],
+		RiskLevels: len(s.risks),
+	}
+}
+
+func (s *DatasetStats) add(r *models.ScannerData) {
+	s.total++
+	s.ips[r.IPOrCIDR]++
+	if r.CountryCode != "" {
+		s.countries[r.CountryCode]++
+	}
+	s.scanners[r.ScannerName]++
+	s.risks[r.RiskLevel]++
+}
+
+func (s *DatasetStats) remove(r *models.ScannerData) {
+	s.total--
+	decrement(s.ips, r.IPOrCIDR)
+	if r.CountryCode != "" {
+		decrement(s.countries, r.CountryCode)
+	}
+	decrement(s.scanners, r.ScannerName)
+	decrement(s.risks, r.RiskLevel)
+}
+
+// decrement lowers a counter and drops the key when it reaches zero, so
+// len(m) stays the number of distinct values.
+func decrement(m map[string]int, key string) {
+	if m[key] <= 1 {
+		delete(m, key)
+		return
+	}
+	m[key]--
+}
+
+// RecordColumns are the column headers of the record tables.
+var RecordColumns = []string{"IP/CIDR", "Scanner", "Type", "Country", "ISP", "Organization", "RDAP Name", "RDAP Handle", "ASN", "Reverse", "Risk", "Score", "Domain", "Last Seen", "Hits", "Continent", "Region"}
+
+// OptionalColumns are the RecordColumns hidden unless listed in
+// AppConfig.ExtraColumns.
+var OptionalColumns = []string{"Continent", "Region"}
+
+// VisibleColumns returns the indexes in RecordColumns of the columns shown:
+// every column but the OptionalColumns missing from extra, in order.
+func VisibleColumns(extra []string) []int {
+	shown := map[string]bool{}
+	for _, name := range extra {
+		shown[name] = true
+	}
+	optional := map[string]bool{}
+	for _, name := range OptionalColumns {
+		optional[name] = true
+	}
+	var cols []int
+	for col, name := range RecordColumns {
+		if !optional[name] || shown[name] {
+			cols = append(cols, col)
+		}
+	}
+	return cols
+}
+
+// Record table sizing: cells are padded by ColumnPadding around their text
+// and rows are DefaultRowHeight high unless the user set a height.
+const (
+	ColumnPadding    = 28
+	DefaultRowHeight = 30
+)
+
+// ColumnLayout returns the width of each RecordColumns column. A column the
+// user resized keeps the width stored in pinned under its header; the others
+// fit the widest of their header and their cells in rows[start:end], as
+// measured by measure, plus ColumnPadding.
+func ColumnLayout(rows []models.ScannerData, start, end int, pinned map[string]float32, measure func(string) float32) []float32 {
+	if end > len(rows) {
+		end = len(rows)
+	}
+	widths := make([]float32, len(RecordColumns))
+	for col, header := range RecordColumns {
+		if w, ok := pinned[header]; ok && w > 0 {
+			widths[col] = w
+			continue
+		}
+		maxw := measure(header)
+		for i := start; i < end; i++ {
+			if w := measure(RecordCell(rows[i], col)); w > maxw {
+				maxw = w
+			}
+		}
+		widths[col] = maxw + ColumnPadding
+	}
+	return widths
+}
+
+// RecordCell returns the text shown in column col of RecordColumns for item.
+func RecordCell(item models.ScannerData, col int) string {
+	switch col {
+	case 0:
+		if item.Lock != nil {
+			return "🔒 " + item.IPOrCIDR
+		}
+		return item.IPOrCIDR
+	case 1:
+		return item.ScannerName
+	case 2:
+		return string(item.ScannerType)
+	case 3:
+		return item.CountryCode
+	case 4:
+		return item.ISP
+	case 5:
+		return item.Organization
+	case 6:
+		return item.RDAPName
+	case 7:
+		return item.RDAPHandle
+	case 8:
+		return item.ASN
+	case 9:
+		return item.ReverseDNS
+	case 10:
+		return item.RiskLevel
+	case 11:
+		return fmt.Sprintf("%d", item.AbuseConfidenceScore)
+	case 12:
+		return item.Domain
+	case 13:
+		return item.LastSeen.Format("2006-01-02")
+	case 14:
+		if item.HitCount == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%d", item.HitCount)
+	case 15:
+		return item.Continent
+	case 16:
+		return item.Region
+	}
+	return ""
+}
+
 // FilterAdvancedSearch filters data by query string, country, scanner, and risk level.
 // Filter values "All Countries", "All Scanners", "All Risk Levels" match everything.
 // The query is matched case-insensitively against IPOrCIDR and ScannerName.
@@ -86,6 +307,191 @@ func FilterAdvancedSearch(data []models.ScannerData, query, country, scanner, ri
 	return results
 }
 
+// ConfidenceLabels are the attribution confidence filter choices of the
+// Search tab; the first matches everything.
+var ConfidenceLabels = []string{"All Confidence Levels", "High", "Medium", "Low", "None"}
+
+// FilterConfidence keeps the records of data whose attribution confidence is
+// label ("None" for unattributed records). The first of ConfidenceLabels or
+// an empty label keeps everything.
+func FilterConfidence(data []models.ScannerData, label string) []models.ScannerData {
+	if label == "" || label == ConfidenceLabels[0] {
+		return data
+	}
+	want := strings.ToLower(label)
+	if label == "None" {
+		want = ""
+	}
+	var out []models.ScannerData
+	for _, item := range data {
+		if item.AttributionConfidence == want {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// StoreQuery returns the record store query of the Search tab filters, the
+// same records FilterAdvancedSearch and FilterConfidence keep.
+func StoreQuery(query, country, scanner, risk, confidence string) store.Query {
+	q := store.Query{Text: query}
+	if country != "All Countries" {
+		q.Country = country
+	}
+	if scanner != "All Scanners" {
+		q.Scanner = scanner
+	}
+	if risk != "All Risk Levels" {
+		q.Risk = risk
+	}
+	switch confidence {
+	case "", ConfidenceLabels[0]:
+	case "None":
+		q.Confidence = "none"
+	default:
+		q.Confidence = confidence
+	}
+	return q
+}
+
+// AttributionLabel describes the scanner attribution of item for the record
+// details, or "" when it has none.
+func AttributionLabel(item models.ScannerData) string {
+	var label string
+	switch {
+	case item.AttributedScanner == "" && item.AttributionConfidence == "":
+		return ""
+	case item.AttributedScanner == "":
+		label = fmt.Sprintf("Attribution confidence: %s (listed by the feed)", item.AttributionConfidence)
+	case item.AttributionConfidence == models.ConfidenceLow:
+		label = fmt.Sprintf("Attributed to: %s (ASN heuristic, low confidence)", item.AttributedScanner)
+	default:
+		label = fmt.Sprintf("Attributed to: %s (reverse DNS, %s confidence)", item.AttributedScanner, item.AttributionConfidence)
+	}
+	if item.ConfidenceHold {
+		label += " - held back from enforcement exports"
+	}
+	return label
+}
+
+// FeedTrustLabel gives the trust level of the feed that named the scanner
+// of item and, when feeds disagreed, why it won; "" for records built
+// before feed trust levels.
+func FeedTrustLabel(item models.ScannerData) string {
+	if item.FeedTrust == 0 && item.AttributionReason == "" {
+		return ""
+	}
+	label := fmt.Sprintf("Feed trust: %d", item.FeedTrust)
+	if item.AttributionReason != "" {
+		label += "\nFeed conflict: " + item.AttributionReason
+	}
+	return label
+}
+
+// PortsLabel lists the open ports, host names and CPEs InternetDB found on
+// item; "" when it has none.
+func PortsLabel(item models.ScannerData) string {
+	var lines []string
+	if len(item.OpenPorts) > 0 {
+		lines = append(lines, "Open Ports: "+models.FormatPorts(item.OpenPorts))
+	}
+	if len(item.Hostnames) > 0 {
+		lines = append(lines, "Hostnames: "+strings.Join(item.Hostnames, ", "))
+	}
+	if len(item.CPEs) > 0 {
+		lines = append(lines, "CPEs: "+strings.Join(item.CPEs, ", "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// InternetDBText formats the InternetDB lookup of ip for the IP enrichment
+// panel.
+func InternetDBText(ip string, r extractor.InternetDBResult, err error) string {
+	if err != nil {
+		return fmt.Sprintf("🔌 Open Ports (Shodan InternetDB):\n• IP: %s\n• Status: unavailable (%s)", ip, err)
+	}
+	if r.Empty() {
+		return "🔌 Open Ports (Shodan InternetDB):\n• No open port known for " + ip
+	}
+	label := PortsLabel(models.ScannerData{OpenPorts: r.Ports, Hostnames: r.Hostnames, CPEs: r.CPEs})
+	return "🔌 Open Ports (Shodan InternetDB):\n• " + strings.ReplaceAll(label, "\n", "\n• ")
+}
+
+// Date filter choices of the Search tab.
+var (
+	DateFieldLabels  = []string{"Any date", "Registered", "Last changed", "First seen", "Last seen"}
+	DateWindowLabels = []string{"7 days", "30 days", "90 days", "365 days"}
+)
+
+// SearchTimeWindow maps the Search tab date filter to a time window. "Any
+// date" or an unknown label returns the zero window, which matches everything.
+func SearchTimeWindow(fieldLabel, windowLabel string) extractor.TimeWindow {
+	fields := map[string]extractor.TimeField{
+		"Registered":   extractor.TimeFieldRegistered,
+		"Last changed": extractor.TimeFieldLastChanged,
+		"First seen":   extractor.TimeFieldFirstSeen,
+		"Last seen":    extractor.TimeFieldLastSeen,
+	}
+	field, ok := fields[fieldLabel]
+	if !ok {
+		return extractor.TimeWindow{}
+	}
+	days, err := strconv.Atoi(strings.TrimSuffix(windowLabel, " days"))
+	if err != nil || days <= 0 {
+		return extractor.TimeWindow{}
+	}
+	return extractor.TimeWindow{Field: field, Within: time.Duration(days) * 24 * time.Hour}
+}
+
+// ResolveASNQuery returns the normalized ASN for query, which is either an
+// ASN ("AS15169", "15169") or the IP/CIDR of a record whose ASN is known.
+func ResolveASNQuery(data []models.ScannerData, query string) (string, error) {
+	query = strings.TrimSpace(query)
+	if asn, err := extractor.NormalizeASN(query); err == nil {
+		return asn, nil
+	}
+	for _, item := range data {
+		if item.IPOrCIDR == query {
+			if item.ASN == "" {
+				return "", fmt.Errorf("no ASN known for %s", query)
+			}
+			return extractor.NormalizeASN(item.ASN)
+		}
+	}
+	return "", fmt.Errorf("%q is neither an ASN nor an IP in the dataset", query)
+}
+
+// FilterByIPs returns the records whose IPOrCIDR is in ips, in dataset order.
+func FilterByIPs(data []models.ScannerData, ips []string) []models.ScannerData {
+	want := make(map[string]bool, len(ips))
+	for _, ip := range ips {
+		want[ip] = true
+	}
+	var out []models.ScannerData
+	for _, item := range data {
+		if want[item.IPOrCIDR] {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// PageRowsFor returns the table rows showing ip among the records from start
+// to end (exclusive) of rows. Row 0 is the header, so the record at start is
+// row 1.
+func PageRowsFor(rows []models.ScannerData, start, end int, ip string) []int {
+	if end > len(rows) {
+		end = len(rows)
+	}
+	var out []int
+	for i := start; i < end; i++ {
+		if rows[i].IPOrCIDR == ip {
+			out = append(out, i-start+1)
+		}
+	}
+	return out
+}
+
 // CalculatePagination computes pagination values from data length, items per page,
 // and the requested current page. It returns totalPages, the clamped validPage,
 // startIdx, and endIdx (exclusive).
@@ -116,124 +522,659 @@ func CalculatePagination(dataLen, itemsPerPage, currentPage int) (totalPages, va
 }
 
 // LoadCSVData reads a CSV file with header-based column mapping and returns
-// a slice of ScannerData. Returns an error if the file cannot be opened,
-// parsed, or contains fewer than 2 rows (header + at least one data row).
+// a slice of ScannerData (see extractor.ReadCSVFile).
 func LoadCSVData(filename string) ([]models.ScannerData, error) {
-	file, err := os.Open(filename)
+	return extractor.ReadCSVFile(filename)
+}
+
+// LogModules lists the modules offered by the Logs tab module filter.
+var LogModules = []string{"All", "Extractor", "GUI", "Scheduler", "API", "CLI", "Main"}
+
+// LogModule returns the Logs tab module of a log entry component. The REST
+// server logs as "Server" and is shown as "API".
+func LogModule(component string) string {
+	if component == "Server" {
+		return "API"
+	}
+	return component
+}
+
+// FilterLogEntries keeps the entries at or above minLevel that belong to
+// module. An empty value or "All" disables the corresponding filter.
+func FilterLogEntries(entries []models.LogEntry, minLevel, module string) []models.LogEntry {
+	var out []models.LogEntry
+	for _, e := range entries {
+		if minLevel != "" && minLevel != "All" && !logger.LevelEnabled(e.Level, models.LogLevel(minLevel)) {
+			continue
+		}
+		if module != "" && module != "All" && LogModule(e.Component) != module {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// FormatLogEntries renders log entries one per line, oldest first.
+func FormatLogEntries(entries []models.LogEntry) string {
+	var b strings.Builder
+	for _, e := range entries {
+		fmt.Fprintf(&b, "%s [%s] %s: %s\n", e.Timestamp.Format("15:04:05"), e.Level, LogModule(e.Component), e.Message)
+	}
+	return b.String()
+}
+
+// ListLogFiles returns the .log files in dir, newest first.
+func ListLogFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
+	type logFile struct {
+		path string
+		mod  time.Time
+	}
+	var files []logFile
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".log" {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, logFile{filepath.Join(dir, e.Name()), info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].mod.After(files[j].mod) })
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.path
+	}
+	return paths, nil
+}
+
+// logTailBytes bounds how much of a log file ReadLogTail reads.
+const logTailBytes = 1 << 20
 
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
+// ReadLogTail returns the last maxLines lines of the file at path, reading
+// at most the final megabyte. It also returns the file size so callers can
+// tell whether the file grew since the previous read.
+func ReadLogTail(path string, maxLines int) ([]string, int64, error) {
+	f, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	offset := info.Size() - logTailBytes
+	if offset < 0 {
+		offset = 0
+	}
+	buf := make([]byte, info.Size()-offset)
+	if _, err := f.ReadAt(buf, offset); err != nil && err != io.EOF {
+		return nil, 0, err
+	}
+	lines := strings.Split(strings.TrimRight(string(buf), "\n"), "\n")
+	if offset > 0 && len(lines) > 0 {
+		lines = lines[1:] // first line is partial
+	}
+	if len(lines) == 1 && lines[0] == "" {
+		lines = nil
 	}
+	if maxLines > 0 && len(lines) > maxLines {
+		lines = lines[len(lines)-maxLines:]
+	}
+	return lines, info.Size(), nil
+}
 
-	if len(records) < 2 {
-		return nil, fmt.Errorf("insufficient data in CSV file")
+// parseLogLine decodes a JSON log file line; it reports false for lines
+// written by the standard library logger.
+func parseLogLine(line string) (models.LogEntry, bool) {
+	var e models.LogEntry
+	if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &e) != nil || e.Level == "" {
+		return e, false
 	}
+	return e, true
+}
 
-	// Build header index map
-	headers := records[0]
-	index := func(name string) int {
-		for i, h := range headers {
-			if strings.EqualFold(strings.TrimSpace(h), strings.TrimSpace(name)) {
-				return i
-			}
+// FormatLogLine renders a JSON log file line like the in-memory entries;
+// lines written by the standard library logger are returned unchanged.
+func FormatLogLine(line string) string {
+	e, ok := parseLogLine(line)
+	if !ok {
+		return line
+	}
+	return fmt.Sprintf("%s [%s] %s: %s", e.Timestamp.Format("2006-01-02 15:04:05"), e.Level, LogModule(e.Component), e.Message)
+}
+
+// FilterLogLines keeps the log file lines whose entry passes
+// FilterLogEntries with minLevel and module. Lines that are not JSON
+// entries, such as panic traces, are always kept.
+func FilterLogLines(lines []string, minLevel, module string) []string {
+	var out []string
+	for _, l := range lines {
+		if e, ok := parseLogLine(l); ok && len(FilterLogEntries([]models.LogEntry{e}, minLevel, module)) == 0 {
+			continue
 		}
-		return -1
-	}
-
-	ipIdx := index("IP/CIDR")
-	scannerNameIdx := index("Scanner Name")
-	scannerTypeIdx := index("Scanner Type")
-	countryCodeIdx := index("Country Code")
-	ispIdx := index("ISP")
-	orgIdx := index("Organization")
-	rdapNameIdx := index("RDAP Name")
-	rdapHandleIdx := index("RDAP Handle")
-	rdapCIDRIdx := index("RDAP CIDR")
-	registryIdx := index("RDAP Registry")
-	asnIdx := index("ASN")
-	asNameIdx := index("AS Name")
-	reverseIdx := index("Reverse DNS")
-	riskIdx := index("Risk Level")
-	scoreIdx := index("Abuse Confidence Score")
-	domainIdx := index("Domain")
-	lastSeenIdx := index("Last Seen")
-	tagsIdx := index("Tags")
-	notesIdx := index("Notes")
-	parentHandleIdx := index("Parent Handle")
-	eventRegIdx := index("Event Registration")
-	eventChangedIdx := index("Event Last Changed")
-	startAddrIdx := index("Start Address")
-	endAddrIdx := index("End Address")
-	ipVersionIdx := index("IP Version")
-	rdapTypeIdx := index("RDAP Type")
-	abuseEmailIdx := index("Abuse Email")
-	techEmailIdx := index("Tech Email")
-
-	var data []models.ScannerData
-	for _, record := range records[1:] {
-		item := models.ScannerData{}
-		get := func(idx int) string {
-			if idx >= 0 && idx < len(record) {
-				return record[idx]
-			}
-			return ""
+		out = append(out, l)
+	}
+	return out
+}
+
+// SearchLogLines returns the indexes of the lines containing query,
+// ignoring case. An empty query matches nothing.
+func SearchLogLines(lines []string, query string) []int {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil
+	}
+	var hits []int
+	for i, l := range lines {
+		if strings.Contains(strings.ToLower(l), query) {
+			hits = append(hits, i)
 		}
+	}
+	return hits
+}
 
-		item.IPOrCIDR = get(ipIdx)
-		item.ScannerName = get(scannerNameIdx)
-		if v := get(scannerTypeIdx); v != "" {
-			item.ScannerType = models.ScannerType(v)
-		}
-		item.CountryCode = get(countryCodeIdx)
-		item.ISP = get(ispIdx)
-		item.Organization = get(orgIdx)
-		item.RDAPName = get(rdapNameIdx)
-		item.RDAPHandle = get(rdapHandleIdx)
-		item.RDAPCIDR = get(rdapCIDRIdx)
-		item.Registry = get(registryIdx)
-		item.StartAddress = get(startAddrIdx)
-		item.EndAddress = get(endAddrIdx)
-		item.IPVersion = get(ipVersionIdx)
-		item.RDAPType = get(rdapTypeIdx)
-		item.ParentHandle = get(parentHandleIdx)
-		item.EventRegistration = get(eventRegIdx)
-		item.EventLastChanged = get(eventChangedIdx)
-		item.ASN = get(asnIdx)
-		item.ASName = get(asNameIdx)
-		item.ReverseDNS = get(reverseIdx)
-		item.RiskLevel = get(riskIdx)
-		if v := get(scoreIdx); v != "" {
-			if score, err := strconv.Atoi(v); err == nil {
-				item.AbuseConfidenceScore = score
-			}
+// NextErrorLine returns the index of the first ERROR or CRITICAL line after
+// from, wrapping around to the start, or -1 when there is none.
+func NextErrorLine(lines []string, from int) int {
+	isError := func(l string) bool {
+		return strings.Contains(l, "[ERROR]") || strings.Contains(l, "[CRITICAL]")
+	}
+	for i := 1; i <= len(lines); i++ {
+		idx := (from + i) % len(lines)
+		if idx < 0 {
+			idx += len(lines)
 		}
-		item.Domain = get(domainIdx)
-		if v := get(lastSeenIdx); v != "" {
-			if t, err := time.Parse("2006-01-02 15:04:05", v); err == nil {
-				item.LastSeen = t
-			} else {
-				item.LastSeen = time.Now()
-			}
+		if isError(lines[idx]) {
+			return idx
+		}
+	}
+	return -1
+}
+
+// splitList splits a comma separated entry, dropping empty items.
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// tagRuleSummary describes a rule in one line for the rules list.
+func tagRuleSummary(r models.TagRule) string {
+	var conds []string
+	if len(r.Countries) > 0 {
+		conds = append(conds, "country "+strings.Join(r.Countries, "/"))
+	}
+	if len(r.ASNs) > 0 {
+		conds = append(conds, "ASN "+strings.Join(r.ASNs, "/"))
+	}
+	if r.OrgRegex != "" {
+		conds = append(conds, "org ~ "+r.OrgRegex)
+	}
+	if len(r.ScannerTypes) > 0 {
+		conds = append(conds, "type "+strings.Join(r.ScannerTypes, "/"))
+	}
+	return fmt.Sprintf("%s: +%s if %s", r.Name, strings.Join(r.Tags, ", "), strings.Join(conds, " and "))
+}
+
+// RecordKey identifies a record across the dataset and the search results,
+// which are copies of dataset records.
+func RecordKey(item models.ScannerData) string {
+	return item.IPOrCIDR + "|" + item.ScannerName
+}
+
+// RecordKeys returns the set of keys of items.
+func RecordKeys(items []models.ScannerData) map[string]bool {
+	keys := make(map[string]bool, len(items))
+	for _, item := range items {
+		keys[RecordKey(item)] = true
+	}
+	return keys
+}
+
+// RecordSums fingerprints the records of data by key, to tell which ones
+// changed since they were written to the record store.
+func RecordSums(data []models.ScannerData) map[string]uint64 {
+	sums := make(map[string]uint64, len(data))
+	for _, item := range data {
+		h := fnv.New64a()
+		// A record that cannot be encoded keeps sum 0 and is always written
+		if raw, err := json.Marshal(item); err == nil {
+			h.Write(raw)
+			sums[RecordKey(item)] = h.Sum64()
 		} else {
-			item.LastSeen = time.Now()
+			sums[RecordKey(item)] = 0
+		}
+	}
+	return sums
+}
+
+// StoreChanges compares data with the fingerprints of the records last
+// written to the store (see RecordSums). It returns the records added or
+// changed since, the keys of the stored records data no longer has, and the
+// fingerprints of data.
+func StoreChanges(stored map[string]uint64, data []models.ScannerData) (changed []models.ScannerData, removed []string, sums map[string]uint64) {
+	sums = RecordSums(data)
+	for _, item := range data {
+		key := RecordKey(item)
+		if old, ok := stored[key]; !ok || old != sums[key] || sums[key] == 0 {
+			changed = append(changed, item)
+		}
+	}
+	for key := range stored {
+		if _, ok := sums[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	sort.Strings(removed)
+	return changed, removed, sums
+}
+
+// BulkSummary describes the records a bulk action is about to change: how
+// many records and IPs, and the first IPs.
+func BulkSummary(action string, items []models.ScannerData) string {
+	seen := map[string]bool{}
+	var ips []string
+	for _, item := range items {
+		if !seen[item.IPOrCIDR] {
+			seen[item.IPOrCIDR] = true
+			ips = append(ips, item.IPOrCIDR)
+		}
+	}
+	const shown = 10
+	more := ""
+	if len(ips) > shown {
+		more = fmt.Sprintf(" (+%d)", len(ips)-shown)
+		ips = ips[:shown]
+	}
+	return fmt.Sprintf("%s\n%d records, %d IPs\n%s%s", action, len(items), len(seen), strings.Join(ips, ", "), more)
+}
+
+// BulkTag adds tag to the records of data whose key is in keys and returns
+// how many changed.
+func BulkTag(data []models.ScannerData, keys map[string]bool, tag string) int {
+	changed := 0
+	for i := range data {
+		if !keys[RecordKey(data[i])] {
+			continue
+		}
+		has := false
+		for _, t := range data[i].Tags {
+			has = has || t == tag
+		}
+		if !has {
+			data[i].Tags = append(data[i].Tags, tag)
+			changed++
+		}
+	}
+	return changed
+}
+
+// BulkSetRisk sets the risk level of the records of data whose key is in
+// keys.
+func BulkSetRisk(data []models.ScannerData, keys map[string]bool, level string) int {
+	changed := 0
+	for i := range data {
+		if keys[RecordKey(data[i])] && data[i].RiskLevel != level {
+			data[i].RiskLevel = level
+			changed++
+		}
+	}
+	return changed
+}
+
+// BulkDelete returns data without the records whose key is in keys, locked
+// records excepted.
+func BulkDelete(data []models.ScannerData, keys map[string]bool) []models.ScannerData {
+	out := make([]models.ScannerData, 0, len(data))
+	for _, item := range data {
+		if !keys[RecordKey(item)] || item.Lock != nil {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// CountLocked returns how many records of data are locked.
+func CountLocked(data []models.ScannerData) int {
+	n := 0
+	for _, item := range data {
+		if item.Lock != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// LockLabel describes the lock of item for the details dialog, "" when it
+// is not locked.
+func LockLabel(item models.ScannerData) string {
+	if item.Lock == nil {
+		return ""
+	}
+	label := fmt.Sprintf("Locked for investigation by %s since %s", item.Lock.Author, item.Lock.LockedAt)
+	if item.Lock.Reason != "" {
+		label += ": " + item.Lock.Reason
+	}
+	return label + " (aging, re-enrichment and deletion skip it)"
+}
+
+// DuplicateLabel describes a group of duplicates of data for the
+// deduplication dialog: its key, record count, IDs and the IP spellings.
+func DuplicateLabel(data []models.ScannerData, g extractor.DuplicateGroup) string {
+	var ids, forms []string
+	for _, i := range g.Indices {
+		if id := data[i].ID; id != "" {
+			ids = append(ids, id)
+		}
+		f := strings.TrimSpace(data[i].IPOrCIDR)
+		known := false
+		for _, k := range forms {
+			known = known || k == f
+		}
+		if !known {
+			forms = append(forms, f)
+		}
+	}
+	label := fmt.Sprintf("%s: %d records", g.Key, len(g.Indices))
+	if len(ids) > 0 {
+		label += " (IDs " + strings.Join(ids, ", ") + ")"
+	}
+	if len(forms) > 1 {
+		label += " as " + strings.Join(forms, ", ")
+	}
+	return label
+}
+
+// DiffLines renders a dataset diff one record per line: "+" added, "-"
+// removed and "~" changed with the fields that differ.
+func DiffLines(d extractor.DatasetDiff) []string {
+	lines := make([]string, 0, len(d.Added)+len(d.Removed)+len(d.Changed))
+	for _, item := range d.Added {
+		lines = append(lines, fmt.Sprintf("+ %s (%s)", item.IPOrCIDR, item.ScannerName))
+	}
+	for _, item := range d.Removed {
+		lines = append(lines, fmt.Sprintf("- %s (%s)", item.IPOrCIDR, item.ScannerName))
+	}
+	for _, c := range d.Changed {
+		changes := make([]string, len(c.Changes))
+		for i, f := range c.Changes {
+			changes[i] = fmt.Sprintf("%s %q -> %q", f.Field, f.Before, f.After)
 		}
-		if v := get(tagsIdx); v != "" {
-			if ts := strings.TrimSpace(v); ts != "" {
-				item.Tags = strings.Split(ts, ",")
+		lines = append(lines, fmt.Sprintf("~ %s (%s): %s", c.After.IPOrCIDR, c.After.ScannerName, strings.Join(changes, ", ")))
+	}
+	return lines
+}
+
+// exportFilterAll is the filter choice of the export dialog accepting
+// every record.
+const exportFilterAll = "All"
+
+// ExportRiskLevels are the risk levels offered by the export dialog.
+var ExportRiskLevels = []string{"High", "Medium", "Low", "Unknown"}
+
+// ExportScannerTypes returns the scanner types offered by the export dialog.
+func ExportScannerTypes() []string {
+	return []string{
+		string(models.ScannerTypeShodan), string(models.ScannerTypeCensys), string(models.ScannerTypeBinaryEdge),
+		string(models.ScannerTypeRapid7), string(models.ScannerTypeShadowServer), string(models.ScannerTypeOther),
+		string(models.ScannerTypeUnknown),
+	}
+}
+
+// ExportFilterFor builds the export filter of the choices of the export
+// dialog: a risk level, a scanner type, either of them "All", and a
+// comma-separated list of country codes, empty for all.
+func ExportFilterFor(risk, scannerType, countries string) extractor.ExportFilter {
+	if risk == exportFilterAll {
+		risk = ""
+	}
+	if scannerType == exportFilterAll {
+		scannerType = ""
+	}
+	return extractor.ParseExportFilter(risk, scannerType, countries)
+}
+
+// exportPresetNone is the preset choice of the export dialog redacting
+// nothing, offered only when no default preset is configured.
+const exportPresetNone = "None"
+
+// ExportPresetOptions returns the preset choices of the export dialog and
+// the one selected first: the default preset when there is one, as it is
+// then enforced, else "None".
+func ExportPresetOptions(names []string, defaultPreset string) ([]string, string) {
+	if defaultPreset != "" {
+		return names, defaultPreset
+	}
+	return append([]string{exportPresetNone}, names...), exportPresetNone
+}
+
+// ExportPresetFor returns the preset name of a choice of the export dialog,
+// "" for "None".
+func ExportPresetFor(choice string) string {
+	if choice == exportPresetNone {
+		return ""
+	}
+	return choice
+}
+
+// RunLabel returns the name of a run saved by SaveRun without its common
+// suffix, leaving its date and time.
+func RunLabel(name string) string {
+	return strings.TrimSuffix(name, "_liacheckscanner.csv")
+}
+
+// DiffFileName returns the name of the export of the differences between
+// the runs from and to, with the extension of format.
+func DiffFileName(from, to, format string) string {
+	return fmt.Sprintf("diff_%s_to_%s.%s", RunLabel(from), RunLabel(to), format)
+}
+
+// RunSourceLabel describes the upstream state a stored run was built from,
+// or "" when its metadata records none.
+func RunSourceLabel(meta models.RunMetadata) string {
+	var parts []string
+	if s := meta.Source; s != nil && s.Commit != "" {
+		commit := s.Commit
+		if len(commit) > 12 {
+			commit = commit[:12]
+		}
+		label := fmt.Sprintf("Source: %s @ %s", s.RepoURL, commit)
+		if s.Ref != "" {
+			label += fmt.Sprintf(" (pinned to %s)", s.Ref)
+		}
+		parts = append(parts, label)
+	}
+	for _, ar := range meta.Archives {
+		sum := ar.SHA256
+		if len(sum) > 12 {
+			sum = sum[:12]
+		}
+		parts = append(parts, fmt.Sprintf("Archive: %s sha256:%s", ar.Source, sum))
+	}
+	return strings.Join(parts, "\n")
+}
+
+// JobLabel describes an interrupted job for the resume prompt.
+func JobLabel(job extractor.JobState) string {
+	name := "Enrichissement"
+	if job.Kind == extractor.JobBulkEnrich {
+		name = "Réenrichissement groupé"
+	}
+	return fmt.Sprintf("%s du %s: %d/%d IPs traitées", name,
+		job.StartedAt.Local().Format("2006-01-02 15:04"), job.Done, len(job.IPs))
+}
+
+// SampleModeLabels lists the sampling modes offered by Load Sample, in the
+// order of extractor.SampleRandom and extractor.SampleStratified.
+var SampleModeLabels = []string{"Random", "Stratified by scanner"}
+
+// SampleModeFor returns the extractor sampling mode of a SampleModeLabels
+// entry, random for any other label.
+func SampleModeFor(label string) string {
+	if label == SampleModeLabels[1] {
+		return extractor.SampleStratified
+	}
+	return extractor.SampleRandom
+}
+
+// ParseSampleSize parses the sample size typed in Load Sample.
+func ParseSampleSize(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("sample size %q: want a positive number of records", s)
+	}
+	return n, nil
+}
+
+// SampleNotice warns that the records shown are a sample, so that counts
+// and charts are not read as those of the dataset.
+func SampleNotice(s extractor.Sample) string {
+	mode := "random"
+	if s.Mode == extractor.SampleStratified {
+		mode = "stratified by scanner"
+	}
+	if !s.Partial() {
+		return fmt.Sprintf("🎲 Sample (%s) of the whole dataset: all %d records", mode, s.Total)
+	}
+	return fmt.Sprintf("⚠️ Sample (%s): %d of %d records, statistics reflect the sample only", mode, len(s.Records), s.Total)
+}
+
+// AutoUpdateSummary describes the dataset a scheduled update loaded: its
+// size, the records added and removed since the previous one, and those
+// queued for enrichment.
+func AutoUpdateSummary(total, added, removed, pending int) string {
+	msg := fmt.Sprintf("Scheduled update: %d records (+%d, -%d)", total, added, removed)
+	if pending > 0 {
+		msg += fmt.Sprintf(", %d to enrich", pending)
+	}
+	return msg
+}
+
+// GroupByOptions are the fields the records can be grouped by in the
+// Geoloc breakdown.
+var GroupByOptions = []string{"Continent", "Region", "Country", "Scanner", "Risk"}
+
+// GroupCount is the number of records sharing a value of the grouped field.
+type GroupCount struct {
+	Value string
+	Count int
+}
+
+// GroupRecords counts the records of data per value of field, one of
+// GroupByOptions, most frequent first and by value on ties. Records without
+// a value are counted under "Unknown"; regions are qualified by their
+// country code, as two countries can have regions of the same name.
+func GroupRecords(data []models.ScannerData, field string) []GroupCount {
+	counts := map[string]int{}
+	for _, item := range data {
+		var v string
+		switch field {
+		case "Continent":
+			v = item.Continent
+		case "Region":
+			if v = item.Region; v != "" && item.CountryCode != "" {
+				v += ", " + item.CountryCode
 			}
+		case "Country":
+			v = item.CountryCode
+		case "Scanner":
+			v = item.ScannerName
+		case "Risk":
+			v = item.RiskLevel
+		}
+		if v == "" {
+			v = "Unknown"
+		}
+		counts[v]++
+	}
+	groups := make([]GroupCount, 0, len(counts))
+	for v, n := range counts {
+		groups = append(groups, GroupCount{Value: v, Count: n})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
 		}
-		item.Notes = get(notesIdx)
-		item.AbuseEmail = get(abuseEmailIdx)
-		item.TechEmail = get(techEmailIdx)
+		return groups[i].Value < groups[j].Value
+	})
+	return groups
+}
+
+// GroupText formats groups as the lines of the Geoloc breakdown by field.
+func GroupText(field string, groups []GroupCount) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Répartition par %s:\n", strings.ToLower(field))
+	for _, g := range groups {
+		fmt.Fprintf(&b, "- %s: %d\n", g.Value, g.Count)
+	}
+	return b.String()
+}
+
+// ThrottleSummary tells whether api_throttle holds the enrichment workers
+// back, from the throttle part of r.
+func ThrottleSummary(r extractor.RateReport) string {
+	switch {
+	case r.ThrottlePerMinute <= 0:
+		return "Throttle: off"
+	case r.ThrottleWaiting > 0:
+		return fmt.Sprintf("⚠️ Throttle is the bottleneck: %d workers waiting (max %.0f req/min)", r.ThrottleWaiting, r.ThrottlePerMinute)
+	}
+	return fmt.Sprintf("Throttle: max %.0f req/min, no worker waiting", r.ThrottlePerMinute)
+}
 
-		data = append(data, item)
+// ProviderRateText formats the activity of one provider at now, e.g.
+// "rdap.arin.net: 42 req/min, 2/4 slots free, 3 × 429 (5 min)".
+func ProviderRateText(p extractor.ProviderRate, now time.Time) string {
+	parts := []string{fmt.Sprintf("%d req/min", p.PerMinute)}
+	if p.Limit > 0 {
+		parts = append(parts, fmt.Sprintf("%d/%d slots free", p.Remaining, p.Limit))
+	} else {
+		parts = append(parts, fmt.Sprintf("%d in flight", p.InFlight))
 	}
+	if p.RateLimited > 0 {
+		parts = append(parts, fmt.Sprintf("%d × 429 (5 min)", p.RateLimited))
+	}
+	if p.RestingUntil.After(now) {
+		parts = append(parts, "paused until "+p.RestingUntil.Format("15:04:05"))
+	}
+	return p.Host + ": " + strings.Join(parts, ", ")
+}
 
-	return data, nil
+// RateReportText formats r for the dashboard: the throttle summary, then a
+// line per provider.
+func RateReportText(r extractor.RateReport, now time.Time) string {
+	lines := []string{ThrottleSummary(r)}
+	if len(r.Providers) == 0 {
+		lines = append(lines, "• No request sent yet")
+	}
+	for _, p := range r.Providers {
+		lines = append(lines, "• "+ProviderRateText(p, now))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// RateStatusLine sums r up on one line for the Database tab, e.g.
+// "🚦 120 req/min, 3 × 429 · Throttle: off".
+func RateStatusLine(r extractor.RateReport) string {
+	perMinute, limited := 0, 0
+	for _, p := range r.Providers {
+		perMinute += p.PerMinute
+		limited += p.RateLimited
+	}
+	line := fmt.Sprintf("🚦 %d req/min", perMinute)
+	if limited > 0 {
+		line += fmt.Sprintf(", %d × 429", limited)
+	}
+	return line + " · " + ThrottleSummary(r)
 }
@@ -2,12 +2,17 @@ package gui
 
 import (
 	"encoding/csv"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
-	"github.com/lia/liacheckscanner_go/internal/models"
+	"github.com/lia/liacheckscanner_go/internal/store"
+	"github.com/lia/liacheckscanner_go/pkg/extractor"
+	"github.com/lia/liacheckscanner_go/pkg/models"
 )
 
 // -------------------------------------------------------
@@ -158,6 +163,100 @@ func TestCountRiskLevels(t *testing.T) {
 	}
 }
 
+// -------------------------------------------------------
+// DatasetStats
+// -------------------------------------------------------
+
+func TestDatasetStats_MatchesFullCount(t *testing.T) {
+	data := []models.ScannerData{
+		{IPOrCIDR: "1.1.1.1", ScannerName: "Shodan", CountryCode: "US", RiskLevel: "High"},
+		{IPOrCIDR: "1.1.1.1", ScannerName: "Shodan", CountryCode: "US", RiskLevel: "High"},
+		{IPOrCIDR: "2.2.2.2", ScannerName: "Censys", CountryCode: "", RiskLevel: "Low"},
+	}
+	s := NewDatasetStats(data)
+	want := StatsSnapshot{
+		Total:      len(data),
+		UniqueIPs:  CountUniqueIPs(data),
+		Countries:  CountUniqueCountries(data),
+		Scanners:   CountUniqueScanners(data),
+		HighRisk:   CountHighRisk(data),
+		RiskLevels: CountRiskLevels(data),
+	}
+	if got := s.Snapshot(); got != want {
+		t.Errorf("Snapshot() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDatasetStats_AddAndReplace(t *testing.T) {
+	data := []models.ScannerData{
+		{IPOrCIDR: "1.1.1.1", ScannerName: "Shodan", RiskLevel: "Low"},
+	}
+	s := NewDatasetStats(data)
+
+	added := models.ScannerData{IPOrCIDR: "3.3.3.3", ScannerName: "User"}
+	s.Add(added)
+	data = append(data, added)
+
+	// Enrichment fills the country and raises the risk in place.
+	old := data[0]
+	data[0].CountryCode = "FR"
+	data[0].RiskLevel = "High"
+	s.Replace(old, data[0])
+
+	got := s.Snapshot()
+	if got.Total != 2 || got.UniqueIPs != 2 || got.Countries != 1 || got.Scanners != 2 || got.HighRisk != 1 {
+		t.Errorf("Snapshot() = %+v after Add and Replace", got)
+	}
+	// "Low" has no record left and must no longer count as a distinct level.
+	if got.RiskLevels != CountRiskLevels(data) {
+		t.Errorf("RiskLevels = %d, want %d", got.RiskLevels, CountRiskLevels(data))
+	}
+
+	s.Reset(nil)
+	if got := s.Snapshot(); got != (StatsSnapshot{}) {
+		t.Errorf("Snapshot() after Reset(nil) = %+v, want zero", got)
+	}
+}
+
+// -------------------------------------------------------
+// RecordCell
+// -------------------------------------------------------
+
+func TestRecordCell_CoversEveryColumn(t *testing.T) {
+	item := models.ScannerData{
+		IPOrCIDR: "1.2.3.4", ScannerName: "Shodan", ScannerType: models.ScannerTypeOther,
+		CountryCode: "US", ISP: "isp", Organization: "org", RDAPName: "NET", RDAPHandle: "H-1",
+		ASN: "AS1", ReverseDNS: "a.example", RiskLevel: "High", AbuseConfidenceScore: 42,
+		Domain: "example.com", LastSeen: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), HitCount: 3,
+		Continent: "North America", Region: "California",
+	}
+	want := []string{"1.2.3.4", "Shodan", string(models.ScannerTypeOther), "US", "isp", "org", "NET", "H-1", "AS1", "a.example", "High", "42", "example.com", "2024-05-01", "3", "North America", "California"}
+	if len(RecordColumns) != len(want) {
+		t.Fatalf("len(RecordColumns) = %d, want %d", len(RecordColumns), len(want))
+	}
+	for col, w := range want {
+		if got := RecordCell(item, col); got != w {
+			t.Errorf("RecordCell(col %d, %s) = %q, want %q", col, RecordColumns[col], got, w)
+		}
+	}
+	if got := RecordCell(item, len(RecordColumns)); got != "" {
+		t.Errorf("RecordCell(out of range) = %q, want empty", got)
+	}
+}
+
+func TestRecordCell_MarksLockedRecords(t *testing.T) {
+	item := models.ScannerData{IPOrCIDR: "1.2.3.4", Lock: &models.RecordLock{Author: "alice", LockedAt: "2026-10-17T08:00:00Z", Reason: "case 42"}}
+	if got := RecordCell(item, 0); got != "🔒 1.2.3.4" {
+		t.Errorf("RecordCell(locked) = %q", got)
+	}
+	if got := LockLabel(item); !strings.Contains(got, "alice") || !strings.Contains(got, "case 42") {
+		t.Errorf("LockLabel = %q", got)
+	}
+	if LockLabel(models.ScannerData{}) != "" {
+		t.Error("LockLabel of an unlocked record should be empty")
+	}
+}
+
 // -------------------------------------------------------
 // FilterAdvancedSearch
 // -------------------------------------------------------
@@ -218,6 +317,68 @@ func TestFilterAdvancedSearch_CaseInsensitive(t *testing.T) {
 	}
 }
 
+// -------------------------------------------------------
+// TextScaleLabel
+// -------------------------------------------------------
+
+func TestTextScaleLabel_RoundTripsChoices(t *testing.T) {
+	for _, label := range TextScaleLabels {
+		if got := TextScaleLabel(textScaleValues[label]); got != label {
+			t.Errorf("TextScaleLabel(%v) = %q, want %q", textScaleValues[label], got, label)
+		}
+	}
+	if got := TextScaleLabel(0); got != "100%" {
+		t.Errorf("TextScaleLabel(0) = %q, want 100%%", got)
+	}
+}
+
+// -------------------------------------------------------
+// ColumnLayout
+// -------------------------------------------------------
+
+func TestColumnLayout_KeepsUserWidthsAndFitsOthers(t *testing.T) {
+	rows := []models.ScannerData{
+		{IPOrCIDR: "192.0.2.1", ISP: "Short"},
+		{IPOrCIDR: "192.0.2.2", ISP: "A much longer ISP name"},
+	}
+	measure := func(s string) float32 { return float32(len(s)) }
+	widths := ColumnLayout(rows, 0, 2, map[string]float32{"IP/CIDR": 250}, measure)
+	if len(widths) != len(RecordColumns) {
+		t.Fatalf("got %d widths, want one per column (%d)", len(widths), len(RecordColumns))
+	}
+	if widths[0] != 250 {
+		t.Errorf("pinned IP/CIDR width = %v, want 250", widths[0])
+	}
+	if want := float32(len("A much longer ISP name") + ColumnPadding); widths[4] != want {
+		t.Errorf("ISP width = %v, want %v (widest cell + padding)", widths[4], want)
+	}
+	// Only the visible page counts
+	if want := float32(len("Short") + ColumnPadding); ColumnLayout(rows, 0, 1, nil, measure)[4] != want {
+		t.Errorf("ISP width on first page should fit %q", "Short")
+	}
+}
+
+// -------------------------------------------------------
+// PageRowsFor
+// -------------------------------------------------------
+
+func TestPageRowsFor_MatchesRowsOfCurrentPage(t *testing.T) {
+	rows := []models.ScannerData{
+		{IPOrCIDR: "192.0.2.1"}, {IPOrCIDR: "192.0.2.2"}, {IPOrCIDR: "192.0.2.1"}, {IPOrCIDR: "192.0.2.1"},
+	}
+	// Page holding records 1..2: record 2 is table row 2 (row 0 is the header)
+	if got := PageRowsFor(rows, 1, 3, "192.0.2.1"); len(got) != 1 || got[0] != 2 {
+		t.Errorf("PageRowsFor(page 1..3) = %v, want [2]", got)
+	}
+	if got := PageRowsFor(rows, 0, 2, "192.0.2.9"); len(got) != 0 {
+		t.Errorf("PageRowsFor(unknown IP) = %v, want none", got)
+	}
+	// Records may be replaced between the page computation and the event
+	if got := PageRowsFor(rows[:2], 0, 4, "192.0.2.1"); len(got) != 1 || got[0] != 1 {
+		t.Errorf("PageRowsFor(shrunk rows) = %v, want [1]", got)
+	}
+}
+
 // -------------------------------------------------------
 // CalculatePagination
 // -------------------------------------------------------
@@ -287,7 +448,11 @@ func TestLoadCSVData_ValidFile(t *testing.T) {
 			"85", "42", "Data Center", "example.com",
 			"2024-06-15 12:00:00", "2024-06-15 12:00:00",
 			"extracted,shodan", "note1", "High",
-			"2024-06-15 12:00:00", "abuse@test.com", "tech@test.com"},
+			"2024-06-15 12:00:00", "abuse@test.com", "tech@test.com",
+			"Example Net", "NSP", "1-5Gbps", "Abuse: NOC <abuse@test.com>",
+			"blocked", "4", "OLDNET (H0)", "Ashburn",
+			"22, 443", "scan.example.com", "cpe:/a:openbsd:openssh", "23", "", "",
+			"North America", "NA", "Virginia"},
 	}
 	path := writeCSVFile(t, dir, "test.csv", rows)
 
@@ -304,6 +469,12 @@ func TestLoadCSVData_ValidFile(t *testing.T) {
 	if data[0].CountryCode != "US" {
 		t.Errorf("Country: want %q, got %q", "US", data[0].CountryCode)
 	}
+	if data[0].City != "Ashburn" {
+		t.Errorf("City: want %q, got %q", "Ashburn", data[0].City)
+	}
+	if len(data[0].OpenPorts) != 2 || len(data[0].Hostnames) != 1 || len(data[0].CPEs) != 1 {
+		t.Errorf("InternetDB fields: got ports %v, hostnames %v, CPEs %v", data[0].OpenPorts, data[0].Hostnames, data[0].CPEs)
+	}
 	if data[0].AbuseConfidenceScore != 85 {
 		t.Errorf("Score: want 85, got %d", data[0].AbuseConfidenceScore)
 	}
@@ -313,6 +484,38 @@ func TestLoadCSVData_ValidFile(t *testing.T) {
 	if len(data[0].Tags) != 2 {
 		t.Errorf("Tags: want 2, got %d", len(data[0].Tags))
 	}
+	if data[0].NetworkType != "NSP" {
+		t.Errorf("NetworkType: want %q, got %q", "NSP", data[0].NetworkType)
+	}
+	if data[0].State != models.StateBlocked || data[0].RunsSeen != 4 {
+		t.Errorf("Lifecycle: want blocked/4, got %q/%d", data[0].State, data[0].RunsSeen)
+	}
+	if data[0].PreviousOwner != "OLDNET (H0)" {
+		t.Errorf("PreviousOwner: want %q, got %q", "OLDNET (H0)", data[0].PreviousOwner)
+	}
+	if data[0].Continent != "North America" || data[0].ContinentCode != "NA" || data[0].Region != "Virginia" {
+		t.Errorf("Continent/Region: got %q/%q/%q", data[0].Continent, data[0].ContinentCode, data[0].Region)
+	}
+}
+
+func TestLoadCSVData_NormalizesCountryAndASN(t *testing.T) {
+	dir := t.TempDir()
+	rows := [][]string{
+		{"IP/CIDR", "Country Code", "ASN", "AS Name"},
+		{"1.2.3.4", "USA", "AS123 Example Net", ""},
+	}
+	path := writeCSVFile(t, dir, "legacy.csv", rows)
+
+	data, err := LoadCSVData(path)
+	if err != nil {
+		t.Fatalf("LoadCSVData: %v", err)
+	}
+	if data[0].CountryCode != "US" || data[0].CountryName != "United States" {
+		t.Errorf("Country: got %q/%q, want US/United States", data[0].CountryCode, data[0].CountryName)
+	}
+	if data[0].ASN != "AS123" || data[0].ASName != "Example Net" {
+		t.Errorf("ASN: got %q/%q, want AS123/Example Net", data[0].ASN, data[0].ASName)
+	}
 }
 
 func TestLoadCSVData_MissingFile(t *testing.T) {
@@ -335,3 +538,635 @@ func TestLoadCSVData_HeaderOnly(t *testing.T) {
 		t.Errorf("Expected 'insufficient data' error, got: %v", err)
 	}
 }
+
+// -------------------------------------------------------
+// ResolveASNQuery / FilterByIPs
+// -------------------------------------------------------
+
+func TestResolveASNQuery(t *testing.T) {
+	data := []models.ScannerData{
+		{IPOrCIDR: "192.0.2.1", ASN: "AS64500 Example"},
+		{IPOrCIDR: "192.0.2.2"},
+	}
+	tests := []struct {
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{"AS15169", "AS15169", false},
+		{" 15169 ", "AS15169", false},
+		{"192.0.2.1", "AS64500", false},
+		{"192.0.2.2", "", true},
+		{"198.51.100.1", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ResolveASNQuery(data, tt.query)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ResolveASNQuery(%q) = %q, %v; want %q (err=%v)", tt.query, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestFilterByIPs(t *testing.T) {
+	data := []models.ScannerData{{IPOrCIDR: "a"}, {IPOrCIDR: "b"}, {IPOrCIDR: "c"}}
+	got := FilterByIPs(data, []string{"c", "a", "z"})
+	if len(got) != 2 || got[0].IPOrCIDR != "a" || got[1].IPOrCIDR != "c" {
+		t.Errorf("FilterByIPs = %v, want [a c]", got)
+	}
+}
+
+func TestFilterLogEntries(t *testing.T) {
+	entries := []models.LogEntry{
+		{Level: models.LogLevelDebug, Component: "Extractor", Message: "GET https://rdap.arin.net/registry/ip/192.0.2.1"},
+		{Level: models.LogLevelInfo, Component: "GUI", Message: "ready"},
+		{Level: models.LogLevelError, Component: "Server", Message: "boom"},
+		{Level: models.LogLevelWarning, Component: "Extractor", Message: "slow"},
+	}
+	if got := FilterLogEntries(entries, "All", "All"); len(got) != 4 {
+		t.Errorf("All/All kept %d entries, want 4", len(got))
+	}
+	if got := FilterLogEntries(entries, "WARNING", ""); len(got) != 2 {
+		t.Errorf("WARNING kept %d entries, want 2", len(got))
+	}
+	got := FilterLogEntries(entries, "All", "Extractor")
+	if len(got) != 2 || got[0].Message != "GET https://rdap.arin.net/registry/ip/192.0.2.1" || got[1].Message != "slow" {
+		t.Errorf("Extractor module = %v", got)
+	}
+	got = FilterLogEntries(entries, "INFO", "API")
+	if len(got) != 1 || got[0].Component != "Server" {
+		t.Errorf("API module = %v, want the Server entry", got)
+	}
+}
+
+func TestFormatLogEntries(t *testing.T) {
+	out := FormatLogEntries([]models.LogEntry{{Level: models.LogLevelError, Component: "Server", Message: "boom"}})
+	if !strings.Contains(out, "[ERROR] API: boom") || strings.Count(out, "\n") != 1 {
+		t.Errorf("FormatLogEntries = %q", out)
+	}
+}
+
+func TestReadLogTail(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "liacheckscanner_2024-01-01.log")
+	content := `{"timestamp":"2024-01-01T10:00:00Z","level":"INFO","component":"GUI","message":"ready"}
+plain line from the standard logger
+{"timestamp":"2024-01-01T10:00:01Z","level":"ERROR","component":"Server","message":"boom"}
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lines, size, err := ReadLogTail(path, 2)
+	if err != nil {
+		t.Fatalf("ReadLogTail: %v", err)
+	}
+	if size != int64(len(content)) || len(lines) != 2 || lines[0] != "plain line from the standard logger" {
+		t.Fatalf("ReadLogTail = %q, %d", lines, size)
+	}
+	if got := FormatLogLine(lines[1]); !strings.Contains(got, "[ERROR] API: boom") {
+		t.Errorf("FormatLogLine = %q", got)
+	}
+	if got := FormatLogLine(lines[0]); got != lines[0] {
+		t.Errorf("plain lines should be unchanged, got %q", got)
+	}
+
+	files, err := ListLogFiles(dir)
+	if err != nil || len(files) != 1 || files[0] != path {
+		t.Errorf("ListLogFiles = %v, %v", files, err)
+	}
+}
+
+func TestFilterLogLines(t *testing.T) {
+	lines := []string{
+		`{"timestamp":"2024-01-01T10:00:00Z","level":"INFO","component":"GUI","message":"ready"}`,
+		"plain line from the standard logger",
+		`{"timestamp":"2024-01-01T10:00:01Z","level":"ERROR","component":"Server","message":"boom"}`,
+		`{"timestamp":"2024-01-01T10:00:02Z","level":"WARNING","component":"Extractor","message":"slow"}`,
+	}
+	if got := FilterLogLines(lines, "All", "All"); len(got) != 4 {
+		t.Errorf("no filter kept %d lines, want 4", len(got))
+	}
+	got := FilterLogLines(lines, "WARNING", "All")
+	if len(got) != 3 || got[0] != lines[1] {
+		t.Errorf("WARNING filter = %q, want the plain line, boom and slow", got)
+	}
+	got = FilterLogLines(lines, "All", "API")
+	if len(got) != 2 || got[1] != lines[2] {
+		t.Errorf("API filter = %q, want the plain line and boom", got)
+	}
+}
+
+func TestSearchAndNextErrorLine(t *testing.T) {
+	lines := []string{"[INFO] GUI: Ready", "[ERROR] API: boom", "[INFO] Extractor: ready again", "[CRITICAL] Main: down"}
+	if hits := SearchLogLines(lines, "READY"); len(hits) != 2 || hits[0] != 0 || hits[1] != 2 {
+		t.Errorf("SearchLogLines = %v, want [0 2]", hits)
+	}
+	if hits := SearchLogLines(lines, " "); hits != nil {
+		t.Errorf("blank query should match nothing, got %v", hits)
+	}
+	if got := NextErrorLine(lines, -1); got != 1 {
+		t.Errorf("first error = %d, want 1", got)
+	}
+	if got := NextErrorLine(lines, 1); got != 3 {
+		t.Errorf("next error = %d, want 3", got)
+	}
+	if got := NextErrorLine(lines, 3); got != 1 {
+		t.Errorf("wrapped error = %d, want 1", got)
+	}
+	if got := NextErrorLine(lines[:1], -1); got != -1 {
+		t.Errorf("no error = %d, want -1", got)
+	}
+}
+
+// -------------------------------------------------------
+// SearchTimeWindow
+// -------------------------------------------------------
+
+func TestSearchTimeWindow_MapsLabels(t *testing.T) {
+	w := SearchTimeWindow("Registered", "90 days")
+	if w.Field != extractor.TimeFieldRegistered || w.Within != 90*24*time.Hour {
+		t.Errorf("SearchTimeWindow(Registered, 90 days) = %+v", w)
+	}
+	if w := SearchTimeWindow("Any date", "90 days"); w.Field != "" {
+		t.Errorf("Any date = %+v, want zero window", w)
+	}
+	for _, label := range DateFieldLabels[1:] {
+		if w := SearchTimeWindow(label, DateWindowLabels[0]); w.Field == "" || w.Within != 7*24*time.Hour {
+			t.Errorf("SearchTimeWindow(%q, %q) = %+v", label, DateWindowLabels[0], w)
+		}
+	}
+}
+
+// -------------------------------------------------------
+// Tag rules
+// -------------------------------------------------------
+
+func TestSplitList_DropsBlanks(t *testing.T) {
+	got := splitList(" CN, ,RU ,")
+	if len(got) != 2 || got[0] != "CN" || got[1] != "RU" {
+		t.Errorf("splitList = %q, want [CN RU]", got)
+	}
+}
+
+func TestTagRuleSummary(t *testing.T) {
+	r := models.TagRule{Name: "cloud", Tags: []string{"cloud", "review"}, Countries: []string{"US"}, OrgRegex: "(?i)amazon"}
+	want := "cloud: +cloud, review if country US and org ~ (?i)amazon"
+	if got := tagRuleSummary(r); got != want {
+		t.Errorf("tagRuleSummary = %q, want %q", got, want)
+	}
+}
+
+// -------------------------------------------------------
+// Bulk actions
+// -------------------------------------------------------
+
+func TestBulkSummary_CountsRecordsAndIPs(t *testing.T) {
+	items := []models.ScannerData{
+		{IPOrCIDR: "192.0.2.1", ScannerName: "a"},
+		{IPOrCIDR: "192.0.2.1", ScannerName: "b"},
+		{IPOrCIDR: "192.0.2.2", ScannerName: "a"},
+	}
+	got := BulkSummary("Supprimer", items)
+	want := "Supprimer\n3 records, 2 IPs\n192.0.2.1, 192.0.2.2"
+	if got != want {
+		t.Errorf("BulkSummary = %q, want %q", got, want)
+	}
+
+	var many []models.ScannerData
+	for i := 0; i < 12; i++ {
+		many = append(many, models.ScannerData{IPOrCIDR: fmt.Sprintf("10.0.0.%d", i)})
+	}
+	if got := BulkSummary("x", many); !strings.HasSuffix(got, "10.0.0.9 (+2)") {
+		t.Errorf("BulkSummary of 12 IPs = %q, want the first 10 and (+2)", got)
+	}
+}
+
+func TestBulkActions_OnlyTouchKeyedRecords(t *testing.T) {
+	data := []models.ScannerData{
+		{IPOrCIDR: "192.0.2.1", ScannerName: "a", RiskLevel: "Low"},
+		{IPOrCIDR: "192.0.2.1", ScannerName: "b", RiskLevel: "Low", Tags: []string{"keep"}},
+		{IPOrCIDR: "192.0.2.2", ScannerName: "a", RiskLevel: "Low"},
+	}
+	keys := RecordKeys(data[1:2])
+
+	if n := BulkTag(data, keys, "keep"); n != 0 {
+		t.Errorf("BulkTag with an existing tag changed %d records, want 0", n)
+	}
+	if n := BulkTag(data, keys, "review"); n != 1 || len(data[0].Tags) != 0 || len(data[1].Tags) != 2 {
+		t.Errorf("BulkTag changed %d records, data = %+v", n, data)
+	}
+	if n := BulkSetRisk(data, keys, "High"); n != 1 || data[0].RiskLevel != "Low" || data[1].RiskLevel != "High" {
+		t.Errorf("BulkSetRisk changed %d records, data = %+v", n, data)
+	}
+	rest := BulkDelete(data, keys)
+	if len(rest) != 2 || rest[0].ScannerName != "a" || rest[1].IPOrCIDR != "192.0.2.2" {
+		t.Errorf("BulkDelete = %+v", rest)
+	}
+}
+
+func TestBulkDelete_KeepsLockedRecords(t *testing.T) {
+	data := []models.ScannerData{
+		{IPOrCIDR: "192.0.2.1", ScannerName: "a", Lock: &models.RecordLock{Author: "alice"}},
+		{IPOrCIDR: "192.0.2.2", ScannerName: "a"},
+	}
+	rest := BulkDelete(data, RecordKeys(data))
+	if len(rest) != 1 || rest[0].IPOrCIDR != "192.0.2.1" {
+		t.Errorf("BulkDelete = %+v, want the locked record kept", rest)
+	}
+	if n := CountLocked(data); n != 1 {
+		t.Errorf("CountLocked = %d, want 1", n)
+	}
+}
+
+func TestStoreChanges_OnlyChangedRecords(t *testing.T) {
+	data := []models.ScannerData{
+		{IPOrCIDR: "192.0.2.1", ScannerName: "a", RiskLevel: "Low"},
+		{IPOrCIDR: "192.0.2.2", ScannerName: "a", RiskLevel: "Low"},
+		{IPOrCIDR: "192.0.2.3", ScannerName: "a", RiskLevel: "Low"},
+	}
+	stored := RecordSums(data)
+
+	edited := append([]models.ScannerData(nil), data[1:]...)
+	edited[0].RiskLevel = "High"
+	edited = append(edited, models.ScannerData{IPOrCIDR: "192.0.2.4", ScannerName: "a"})
+	changed, removed, sums := StoreChanges(stored, edited)
+	if len(changed) != 2 || changed[0].IPOrCIDR != "192.0.2.2" || changed[1].IPOrCIDR != "192.0.2.4" {
+		t.Errorf("changed = %+v, want the edited and the added record", changed)
+	}
+	if len(removed) != 1 || removed[0] != "192.0.2.1|a" {
+		t.Errorf("removed = %v, want 192.0.2.1|a", removed)
+	}
+	if changed, removed, _ := StoreChanges(sums, edited); len(changed) != 0 || len(removed) != 0 {
+		t.Errorf("nothing changed since: %d written, %d removed", len(changed), len(removed))
+	}
+}
+
+// -------------------------------------------------------
+// Deduplication
+// -------------------------------------------------------
+
+func TestDuplicateLabel(t *testing.T) {
+	data := []models.ScannerData{
+		{ID: "1", IPOrCIDR: "2001:DB8::1", ScannerName: "shodan"},
+		{ID: "2", IPOrCIDR: "2001:db8::1", ScannerName: "shodan"},
+		{ID: "3", IPOrCIDR: "2001:db8::1", ScannerName: "shodan"},
+	}
+	groups := extractor.FindDuplicates(data)
+	if len(groups) != 1 {
+		t.Fatalf("FindDuplicates = %+v, want 1 group", groups)
+	}
+	want := "2001:db8::1 shodan: 3 records (IDs 1, 2, 3) as 2001:DB8::1, 2001:db8::1"
+	if got := DuplicateLabel(data, groups[0]); got != want {
+		t.Errorf("DuplicateLabel = %q, want %q", got, want)
+	}
+}
+
+// -------------------------------------------------------
+// Comparison window
+// -------------------------------------------------------
+
+func TestDiffLines(t *testing.T) {
+	d := extractor.DiffDatasets(
+		[]models.ScannerData{{IPOrCIDR: "192.0.2.1", ScannerName: "shodan", RiskLevel: "Low"}, {IPOrCIDR: "192.0.2.2", ScannerName: "censys"}},
+		[]models.ScannerData{{IPOrCIDR: "192.0.2.1", ScannerName: "shodan", RiskLevel: "High"}, {IPOrCIDR: "192.0.2.3", ScannerName: "shodan"}},
+	)
+	want := []string{
+		"+ 192.0.2.3 (shodan)",
+		"- 192.0.2.2 (censys)",
+		`~ 192.0.2.1 (shodan): risk "Low" -> "High"`,
+	}
+	got := DiffLines(d)
+	if len(got) != len(want) {
+		t.Fatalf("DiffLines = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+// -------------------------------------------------------
+// Interrupted jobs
+// -------------------------------------------------------
+
+func TestJobLabel(t *testing.T) {
+	job := extractor.JobState{
+		Kind:      extractor.JobBulkEnrich,
+		StartedAt: time.Date(2024, 5, 1, 9, 30, 0, 0, time.Local),
+		Done:      25,
+		IPs:       make([]string, 40),
+	}
+	if got, want := JobLabel(job), "Réenrichissement groupé du 2024-05-01 09:30: 25/40 IPs traitées"; got != want {
+		t.Errorf("JobLabel = %q, want %q", got, want)
+	}
+	job.Kind = extractor.JobEnrich
+	if got := JobLabel(job); !strings.HasPrefix(got, "Enrichissement du ") {
+		t.Errorf("JobLabel(enrich) = %q", got)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Attribution confidence
+// ---------------------------------------------------------------------------
+
+func TestFilterConfidence(t *testing.T) {
+	data := []models.ScannerData{
+		{IPOrCIDR: "192.0.2.1", AttributionConfidence: models.ConfidenceHigh},
+		{IPOrCIDR: "192.0.2.2", AttributionConfidence: models.ConfidenceLow},
+		{IPOrCIDR: "192.0.2.3"},
+	}
+	if got := FilterConfidence(data, ConfidenceLabels[0]); len(got) != 3 {
+		t.Errorf("all levels = %d records, want 3", len(got))
+	}
+	if got := FilterConfidence(data, "Low"); len(got) != 1 || got[0].IPOrCIDR != "192.0.2.2" {
+		t.Errorf("Low = %+v", got)
+	}
+	if got := FilterConfidence(data, "None"); len(got) != 1 || got[0].IPOrCIDR != "192.0.2.3" {
+		t.Errorf("None = %+v", got)
+	}
+}
+
+func TestAttributionLabel(t *testing.T) {
+	cases := []struct {
+		item models.ScannerData
+		want string
+	}{
+		{models.ScannerData{}, ""},
+		{models.ScannerData{AttributionConfidence: models.ConfidenceHigh}, "Attribution confidence: high (listed by the feed)"},
+		{models.ScannerData{AttributedScanner: "censys", AttributionConfidence: models.ConfidenceMedium},
+			"Attributed to: censys (reverse DNS, medium confidence)"},
+		{models.ScannerData{AttributedScanner: "shodan", AttributionConfidence: models.ConfidenceLow, ConfidenceHold: true},
+			"Attributed to: shodan (ASN heuristic, low confidence) - held back from enforcement exports"},
+	}
+	for _, c := range cases {
+		if got := AttributionLabel(c.item); got != c.want {
+			t.Errorf("AttributionLabel(%+v) = %q, want %q", c.item, got, c.want)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Run metadata
+// ---------------------------------------------------------------------------
+
+func TestRunSourceLabel(t *testing.T) {
+	if got := RunSourceLabel(models.RunMetadata{Records: 3}); got != "" {
+		t.Errorf("RunSourceLabel without source = %q, want empty", got)
+	}
+	meta := models.RunMetadata{
+		Source:   &models.SourceRevision{RepoURL: "https://example.com/repo", Ref: "v1.2", Commit: "0123456789abcdef0123"},
+		Archives: []models.ArchiveRevision{{Source: "/srv/feeds.zip", SHA256: "ffeeddccbbaa99887766"}},
+	}
+	want := "Source: https://example.com/repo @ 0123456789ab (pinned to v1.2)\nArchive: /srv/feeds.zip sha256:ffeeddccbbaa"
+	if got := RunSourceLabel(meta); got != want {
+		t.Errorf("RunSourceLabel = %q, want %q", got, want)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Feed trust
+// ---------------------------------------------------------------------------
+
+func TestFeedTrustLabel(t *testing.T) {
+	if got := FeedTrustLabel(models.ScannerData{}); got != "" {
+		t.Errorf("FeedTrustLabel of an old record = %q, want empty", got)
+	}
+	if got := FeedTrustLabel(models.ScannerData{FeedTrust: 80}); got != "Feed trust: 80" {
+		t.Errorf("FeedTrustLabel = %q", got)
+	}
+	item := models.ScannerData{FeedTrust: 80, AttributionReason: "shodan by shodan.nft (trust 80) over censys by censys.nft (trust 50): most trusted feed"}
+	want := "Feed trust: 80\nFeed conflict: shodan by shodan.nft (trust 80) over censys by censys.nft (trust 50): most trusted feed"
+	if got := FeedTrustLabel(item); got != want {
+		t.Errorf("FeedTrustLabel = %q, want %q", got, want)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Record store queries
+// ---------------------------------------------------------------------------
+
+func TestStoreQuery(t *testing.T) {
+	if got := StoreQuery("", "All Countries", "All Scanners", "All Risk Levels", ConfidenceLabels[0]); got != (store.Query{}) {
+		t.Errorf("StoreQuery without filters = %+v, want the zero query", got)
+	}
+	want := store.Query{Text: "192.0", Country: "US", Scanner: "shodan", Risk: "high", Confidence: "none"}
+	if got := StoreQuery("192.0", "US", "shodan", "high", "None"); got != want {
+		t.Errorf("StoreQuery = %+v, want %+v", got, want)
+	}
+	if got := StoreQuery("", "All Countries", "All Scanners", "All Risk Levels", "High"); got.Confidence != "High" {
+		t.Errorf("StoreQuery confidence = %q, want High", got.Confidence)
+	}
+}
+
+// -------------------------------------------------------
+// RunLabel / DiffFileName
+// -------------------------------------------------------
+
+func TestDiffFileName(t *testing.T) {
+	got := DiffFileName("2024-01-01_00-00-00_liacheckscanner.csv", "2024-01-02_10-30-00_liacheckscanner.csv", "json")
+	if want := "diff_2024-01-01_00-00-00_to_2024-01-02_10-30-00.json"; got != want {
+		t.Errorf("DiffFileName = %q, want %q", got, want)
+	}
+	if got := RunLabel("other.csv"); got != "other.csv" {
+		t.Errorf("RunLabel(other.csv) = %q, want it unchanged", got)
+	}
+}
+
+// -------------------------------------------------------
+// ExportFilterFor
+// -------------------------------------------------------
+
+func TestExportFilterFor(t *testing.T) {
+	data := []models.ScannerData{
+		{IPOrCIDR: "192.0.2.1", RiskLevel: "High", ScannerType: models.ScannerTypeShodan, CountryCode: "CN"},
+		{IPOrCIDR: "192.0.2.2", RiskLevel: "Low", ScannerType: models.ScannerTypeShodan, CountryCode: "RU"},
+		{IPOrCIDR: "192.0.2.3", RiskLevel: "High", ScannerType: models.ScannerTypeCensys, CountryCode: "us"},
+	}
+	if f := ExportFilterFor("All", "All", " "); !f.Empty() {
+		t.Errorf("All/All/blank gave filter %+v, want none", f)
+	}
+	if got := ExportFilterFor("High", "All", "cn, US").Apply(data); len(got) != 2 || got[0].IPOrCIDR != "192.0.2.1" || got[1].IPOrCIDR != "192.0.2.3" {
+		t.Errorf("High in CN,US kept %v", got)
+	}
+	if got := ExportFilterFor("All", "shodan", "").Apply(data); len(got) != 2 {
+		t.Errorf("shodan kept %d records, want 2", len(got))
+	}
+}
+
+func TestExportPresetOptions(t *testing.T) {
+	opts, sel := ExportPresetOptions([]string{"partners", "public"}, "")
+	if len(opts) != 3 || opts[0] != "None" || sel != "None" {
+		t.Errorf("without default: %v, %q", opts, sel)
+	}
+	if ExportPresetFor(sel) != "" || ExportPresetFor("public") != "public" {
+		t.Errorf("ExportPresetFor(%q) = %q", sel, ExportPresetFor(sel))
+	}
+	opts, sel = ExportPresetOptions([]string{"partners", "public"}, "public")
+	if len(opts) != 2 || sel != "public" {
+		t.Errorf("with default public: %v, %q; None must not be offered", opts, sel)
+	}
+}
+
+func TestPortsLabel(t *testing.T) {
+	if PortsLabel(models.ScannerData{}) != "" {
+		t.Error("record without ports has a label")
+	}
+	item := models.ScannerData{OpenPorts: []int{22, 443}, CPEs: []string{"cpe:/a:openbsd:openssh"}}
+	if got := PortsLabel(item); got != "Open Ports: 22, 443\nCPEs: cpe:/a:openbsd:openssh" {
+		t.Errorf("PortsLabel = %q", got)
+	}
+	if got := InternetDBText("192.0.2.1", extractor.InternetDBResult{Ports: []int{80}}, nil); !strings.Contains(got, "• Open Ports: 80") {
+		t.Errorf("InternetDBText = %q", got)
+	}
+	if got := InternetDBText("192.0.2.1", extractor.InternetDBResult{}, nil); !strings.Contains(got, "No open port known") {
+		t.Errorf("InternetDBText without data = %q", got)
+	}
+	if got := InternetDBText("192.0.2.1", extractor.InternetDBResult{}, errors.New("timeout")); !strings.Contains(got, "unavailable (timeout)") {
+		t.Errorf("InternetDBText on error = %q", got)
+	}
+}
+
+// -------------------------------------------------------
+// Sampling
+// -------------------------------------------------------
+
+func TestParseSampleSize(t *testing.T) {
+	if n, err := ParseSampleSize(" 5000 "); err != nil || n != 5000 {
+		t.Errorf("ParseSampleSize(5000) = %d, %v", n, err)
+	}
+	for _, s := range []string{"", "0", "-3", "ten"} {
+		if _, err := ParseSampleSize(s); err == nil {
+			t.Errorf("ParseSampleSize(%q) accepted", s)
+		}
+	}
+}
+
+func TestSampleNotice(t *testing.T) {
+	if SampleModeFor(SampleModeLabels[1]) != extractor.SampleStratified || SampleModeFor("") != extractor.SampleRandom {
+		t.Error("SampleModeFor does not map the labels")
+	}
+	s := extractor.Sample{Records: make([]models.ScannerData, 10), Total: 500, Mode: extractor.SampleStratified}
+	if got := SampleNotice(s); !strings.Contains(got, "10 of 500") || !strings.Contains(got, "stratified") || !strings.Contains(got, "reflect the sample") {
+		t.Errorf("SampleNotice = %q", got)
+	}
+	s.Total = 10
+	if got := SampleNotice(s); !strings.Contains(got, "all 10 records") {
+		t.Errorf("SampleNotice of the whole dataset = %q", got)
+	}
+}
+
+func TestAutoUpdateSummary(t *testing.T) {
+	if got := AutoUpdateSummary(10, 2, 1, 0); got != "Scheduled update: 10 records (+2, -1)" {
+		t.Errorf("AutoUpdateSummary = %q", got)
+	}
+	if got := AutoUpdateSummary(10, 2, 1, 3); !strings.HasSuffix(got, ", 3 to enrich") {
+		t.Errorf("AutoUpdateSummary = %q, want the records to enrich", got)
+	}
+}
+
+// -------------------------------------------------------
+// VisibleColumns / GroupRecords / GroupText
+// -------------------------------------------------------
+
+func TestVisibleColumns(t *testing.T) {
+	base := len(RecordColumns) - len(OptionalColumns)
+	if got := VisibleColumns(nil); len(got) != base {
+		t.Fatalf("VisibleColumns(nil) = %v, want the %d base columns", got, base)
+	}
+	got := VisibleColumns([]string{"Region"})
+	if len(got) != base+1 || RecordColumns[got[len(got)-1]] != "Region" {
+		t.Errorf("VisibleColumns(Region) = %v, want the base columns then Region", got)
+	}
+	if got := VisibleColumns(OptionalColumns); len(got) != len(RecordColumns) {
+		t.Errorf("VisibleColumns(all) = %v, want every column", got)
+	}
+}
+
+func TestGroupRecords(t *testing.T) {
+	data := []models.ScannerData{
+		{Continent: "Europe", Region: "Bavaria", CountryCode: "DE"},
+		{Continent: "Europe", Region: "Brittany", CountryCode: "FR"},
+		{Continent: "Asia", Region: "Tokyo", CountryCode: "JP"},
+		{},
+	}
+	got := GroupRecords(data, "Continent")
+	want := []GroupCount{{"Europe", 2}, {"Asia", 1}, {"Unknown", 1}}
+	if len(got) != len(want) {
+		t.Fatalf("GroupRecords(Continent) = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("GroupRecords(Continent)[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if got := GroupRecords(data, "Region"); got[0].Value != "Bavaria, DE" {
+		t.Errorf("GroupRecords(Region)[0] = %v, want Bavaria, DE", got[0])
+	}
+}
+
+func TestGroupText(t *testing.T) {
+	got := GroupText("Continent", []GroupCount{{"Europe", 2}, {"Unknown", 1}})
+	want := "Répartition par continent:\n- Europe: 2\n- Unknown: 1\n"
+	if got != want {
+		t.Errorf("GroupText() = %q, want %q", got, want)
+	}
+}
+
+// -------------------------------------------------------
+// ThrottleSummary / ProviderRateText / RateReportText / RateStatusLine
+// -------------------------------------------------------
+
+func TestThrottleSummary(t *testing.T) {
+	tests := []struct {
+		report extractor.RateReport
+		want   string
+	}{
+		{extractor.RateReport{}, "Throttle: off"},
+		{extractor.RateReport{ThrottlePerMinute: 60}, "Throttle: max 60 req/min, no worker waiting"},
+		{extractor.RateReport{ThrottlePerMinute: 60, ThrottleWaiting: 3}, "⚠️ Throttle is the bottleneck: 3 workers waiting (max 60 req/min)"},
+	}
+	for _, tt := range tests {
+		if got := ThrottleSummary(tt.report); got != tt.want {
+			t.Errorf("ThrottleSummary(%+v) = %q, want %q", tt.report, got, tt.want)
+		}
+	}
+}
+
+func TestProviderRateText(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	p := extractor.ProviderRate{Host: "rdap.arin.net", PerMinute: 42, InFlight: 2, Limit: 4, Remaining: 2,
+		RateLimited: 3, RestingUntil: now.Add(time.Minute)}
+	want := "rdap.arin.net: 42 req/min, 2/4 slots free, 3 × 429 (5 min), paused until 12:01:00"
+	if got := ProviderRateText(p, now); got != want {
+		t.Errorf("ProviderRateText() = %q, want %q", got, want)
+	}
+	p = extractor.ProviderRate{Host: "ip-api.com", PerMinute: 10, InFlight: 1, Remaining: -1}
+	if got := ProviderRateText(p, now); got != "ip-api.com: 10 req/min, 1 in flight" {
+		t.Errorf("ProviderRateText(no cap) = %q", got)
+	}
+}
+
+func TestRateReportText(t *testing.T) {
+	now := time.Now()
+	if got := RateReportText(extractor.RateReport{}, now); got != "Throttle: off\n• No request sent yet" {
+		t.Errorf("RateReportText(empty) = %q", got)
+	}
+	r := extractor.RateReport{Providers: []extractor.ProviderRate{
+		{Host: "a.example", PerMinute: 1, Remaining: -1},
+		{Host: "b.example", PerMinute: 2, Remaining: -1},
+	}}
+	if got := strings.Count(RateReportText(r, now), "\n• "); got != 2 {
+		t.Errorf("RateReportText has %d provider lines, want 2", got)
+	}
+}
+
+func TestRateStatusLine(t *testing.T) {
+	r := extractor.RateReport{ThrottlePerMinute: 60, Providers: []extractor.ProviderRate{
+		{Host: "a.example", PerMinute: 100, RateLimited: 2},
+		{Host: "b.example", PerMinute: 20, RateLimited: 1},
+	}}
+	want := "🚦 120 req/min, 3 × 429 · Throttle: max 60 req/min, no worker waiting"
+	if got := RateStatusLine(r); got != want {
+		t.Errorf("RateStatusLine() = %q, want %q", got, want)
+	}
+}
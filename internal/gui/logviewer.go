@@ -0,0 +1,244 @@
+// Package gui provides the graphical user interface for LiaCheckScanner.
+// This file contains the log file viewer of the Logs tab: file list, live
+// tail, level and module filters, text search with highlighting, and jump
+// to errors.
+package gui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// logViewerMaxLines bounds the number of lines kept in the file viewer
+const logViewerMaxLines = 5000
+
+// logViewerInterval is how often a followed log file is checked for new lines
+const logViewerInterval = 2 * time.Second
+
+// logsDir returns the configured logs directory, or ./logs when unset
+func (a *App) logsDir() string {
+	if a.config.Database.LogsDir == "" {
+		return "./logs"
+	}
+	return a.config.Database.LogsDir
+}
+
+// LogLevels lists the levels offered by the Logs tab level filters.
+var LogLevels = []string{"All", "DEBUG", "INFO", "WARNING", "ERROR", "CRITICAL"}
+
+// RecordLevels lists the levels the logger can be set to record from.
+var RecordLevels = []string{"DEBUG", "INFO", "WARNING", "ERROR"}
+
+// createLogFilesView builds the viewer for the log files in the logs directory
+func (a *App) createLogFilesView() fyne.CanvasObject {
+	var (
+		raw      []string // last lines of the selected file
+		lines    []string // formatted lines of raw passing the filters
+		hits     []int    // indexes of lines matching the search
+		hitIdx   = -1     // position in hits of the current match
+		query    string
+		minLevel = "All"
+		module   = "All"
+		current  string // path of the selected file
+		lastSize int64
+		stop     chan struct{}
+	)
+
+	status := widget.NewLabel("")
+	list := widget.NewList(
+		func() int { return len(lines) },
+		func() fyne.CanvasObject { return widget.NewRichText() },
+		func(id widget.ListItemID, o fyne.CanvasObject) {
+			rt := o.(*widget.RichText)
+			rt.Segments = highlightSegments(lines[id], query)
+			rt.Refresh()
+		},
+	)
+
+	// show formats the lines of raw passing the level and module filters
+	show := func() {
+		if current == "" {
+			return
+		}
+		kept := FilterLogLines(raw, minLevel, module)
+		lines = make([]string, len(kept))
+		for i, l := range kept {
+			lines[i] = FormatLogLine(l)
+		}
+		hits = SearchLogLines(lines, query)
+		hitIdx = -1
+		list.Refresh()
+		status.SetText(a.text(fmt.Sprintf("📄 %s - %d/%d lignes", filepath.Base(current), len(lines), len(raw))))
+	}
+
+	load := func(keepPosition bool) {
+		if current == "" {
+			return
+		}
+		tail, size, err := ReadLogTail(current, logViewerMaxLines)
+		if err != nil {
+			status.SetText(a.text("❌ " + err.Error()))
+			return
+		}
+		if keepPosition && size == lastSize {
+			return
+		}
+		lastSize = size
+		raw = tail
+		show()
+		if keepPosition {
+			list.ScrollToBottom()
+		}
+	}
+
+	fileSelect := widget.NewSelect(nil, func(path string) {
+		current = path
+		lastSize = -1
+		load(false)
+		list.ScrollToBottom()
+	})
+	fileSelect.PlaceHolder = "Select a log file..."
+	refreshFiles := func() {
+		files, err := ListLogFiles(a.logsDir())
+		if err != nil {
+			status.SetText(a.text("❌ " + err.Error()))
+			return
+		}
+		fileSelect.Options = files
+		fileSelect.Refresh()
+		if current == "" && len(files) > 0 {
+			fileSelect.SetSelected(files[0]) // newest is the active file
+		}
+	}
+	refreshFiles()
+
+	levelSelect := widget.NewSelect(LogLevels, func(level string) {
+		minLevel = level
+		show()
+	})
+	levelSelect.SetSelected(minLevel)
+	moduleSelect := widget.NewSelect(LogModules, func(m string) {
+		module = m
+		show()
+	})
+	moduleSelect.SetSelected(module)
+
+	searchEntry := widget.NewEntry()
+	searchEntry.SetPlaceHolder("Search in file...")
+	searchEntry.OnChanged = func(text string) {
+		query = strings.TrimSpace(text)
+		hits = SearchLogLines(lines, query)
+		hitIdx = -1
+		list.Refresh()
+		if query != "" {
+			status.SetText(a.text(fmt.Sprintf("🔍 %d correspondances", len(hits))))
+		}
+	}
+	nextMatchBtn := widget.NewButton("⏭️ Suivant", func() {
+		if len(hits) == 0 {
+			return
+		}
+		hitIdx = (hitIdx + 1) % len(hits)
+		list.Select(hits[hitIdx])
+		list.ScrollTo(hits[hitIdx])
+		status.SetText(a.text(fmt.Sprintf("🔍 %d/%d", hitIdx+1, len(hits))))
+	})
+
+	lastError := -1
+	nextErrorBtn := widget.NewButton("🚨 Erreur suivante", func() {
+		idx := NextErrorLine(lines, lastError)
+		if idx < 0 {
+			status.SetText(a.text("✅ Aucune erreur dans ce fichier"))
+			return
+		}
+		lastError = idx
+		list.Select(idx)
+		list.ScrollTo(idx)
+	})
+
+	// Follow the active file: reload it whenever it grows
+	followCheck := widget.NewCheck("▶️ Suivre (tail)", func(on bool) {
+		if !on {
+			if stop != nil {
+				close(stop)
+				stop = nil
+			}
+			return
+		}
+		if stop != nil {
+			return
+		}
+		stop = make(chan struct{})
+		go func(done chan struct{}) {
+			defer a.crash.Recover("GUI")
+			ticker := time.NewTicker(logViewerInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					load(true)
+				}
+			}
+		}(stop)
+	})
+
+	refreshBtn := widget.NewButton("🔄 Fichiers", func() {
+		refreshFiles()
+		load(false)
+	})
+
+	controls := container.NewVBox(
+		container.NewBorder(nil, nil, nil, container.NewHBox(refreshBtn, followCheck), fileSelect),
+		container.NewGridWithColumns(2,
+			container.NewBorder(nil, nil, widget.NewLabel("🔍 Niveau"), nil, levelSelect),
+			container.NewBorder(nil, nil, widget.NewLabel("🧩 Module"), nil, moduleSelect),
+		),
+		container.NewBorder(nil, nil, nil, container.NewHBox(nextMatchBtn, nextErrorBtn), searchEntry),
+		status,
+	)
+	return container.NewBorder(controls, nil, nil, nil, list)
+}
+
+// highlightSegments splits line into rich text segments, emphasizing every
+// case-insensitive occurrence of query
+func highlightSegments(line, query string) []widget.RichTextSegment {
+	plain := func(text string) *widget.TextSegment {
+		return &widget.TextSegment{Text: text, Style: widget.RichTextStyle{Inline: true, TextStyle: fyne.TextStyle{Monospace: true}}}
+	}
+	if query == "" {
+		return []widget.RichTextSegment{plain(line)}
+	}
+	var segs []widget.RichTextSegment
+	lower, q := strings.ToLower(line), strings.ToLower(query)
+	if len(lower) != len(line) {
+		lower, q = line, query // lowering changed byte offsets: match exactly
+	}
+	for {
+		i := strings.Index(lower, q)
+		if i < 0 {
+			break
+		}
+		if i > 0 {
+			segs = append(segs, plain(line[:i]))
+		}
+		segs = append(segs, &widget.TextSegment{Text: line[i : i+len(q)], Style: widget.RichTextStyle{
+			Inline:    true,
+			ColorName: theme.ColorNamePrimary,
+			TextStyle: fyne.TextStyle{Monospace: true, Bold: true},
+		}})
+		line, lower = line[i+len(q):], lower[i+len(q):]
+	}
+	if line != "" {
+		segs = append(segs, plain(line))
+	}
+	return segs
+}
@@ -0,0 +1,190 @@
+package gui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lia/liacheckscanner_go/internal/logger"
+	"github.com/lia/liacheckscanner_go/pkg/extractor"
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// MockBackend is a Backend for testing the GUI offline. It embeds an Extractor, which handles the files (runs, exports, annotations,
+// job state), and overrides every call that would reach the network with
+// canned data: extraction returns Records, and enrichment and lookups copy
+// the fields of the Enriched record with the same IP.
+type MockBackend struct {
+	*extractor.Extractor
+
+	// Records returned by ExtractBaseRecords and SyncRemote
+	Records []models.ScannerData
+	// Enriched records by IP, copied by enrichment and lookups; an IP
+	// missing from it fails like an IP no registry knows
+	Enriched map[string]models.ScannerData
+}
+
+// NewMockBackend returns a MockBackend over records, with an Extractor
+// working in the directories of cfg. Enrichment has no data until
+// Enriched is filled.
+func NewMockBackend(cfg models.DatabaseConfig, log *logger.Logger, records []models.ScannerData) *MockBackend {
+	return &MockBackend{
+		Extractor: extractor.NewExtractor(cfg, log),
+		Records:   records,
+		Enriched:  map[string]models.ScannerData{},
+	}
+}
+
+// enriched returns the canned record of ip.
+func (m *MockBackend) enriched(ip string) (models.ScannerData, error) {
+	r, ok := m.Enriched[ip]
+	if !ok {
+		return models.ScannerData{}, fmt.Errorf("mock backend: no enrichment for %s", ip)
+	}
+	return r, nil
+}
+
+// ExtractBaseRecords returns a copy of Records.
+func (m *MockBackend) ExtractBaseRecords(ctx context.Context) ([]models.ScannerData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return append([]models.ScannerData(nil), m.Records...), nil
+}
+
+// SyncRemote returns a copy of Records, all counted as received.
+func (m *MockBackend) SyncRemote(ctx context.Context, local []models.ScannerData) ([]models.ScannerData, int, error) {
+	return append([]models.ScannerData(nil), m.Records...), len(m.Records), nil
+}
+
+// EnrichRecordWithDelay replaces data with its Enriched record, keeping
+// its ID and IP. The delay is ignored.
+func (m *MockBackend) EnrichRecordWithDelay(ctx context.Context, data *models.ScannerData, delayMs int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r, err := m.enriched(data.IPOrCIDR)
+	if err != nil {
+		return err
+	}
+	r.ID, r.IPOrCIDR = data.ID, data.IPOrCIDR
+	*data = r
+	return nil
+}
+
+// EnrichIPs returns the Enriched record of each IP, or a bare record for
+// the IPs without one.
+func (m *MockBackend) EnrichIPs(ctx context.Context, ips []string) ([]models.ScannerData, error) {
+	out := make([]models.ScannerData, 0, len(ips))
+	for i, ip := range ips {
+		if err := ctx.Err(); err != nil {
+			return out, err
+		}
+		r := models.ScannerData{IPOrCIDR: ip}
+		if e, err := m.enriched(ip); err == nil {
+			r = e
+		}
+		r.ID = fmt.Sprintf("%d", i+1)
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// EnrichPeeringDB leaves data alone.
+func (m *MockBackend) EnrichPeeringDB(ctx context.Context, data []models.ScannerData) int {
+	return 0
+}
+
+// LookupRDAP returns the Enriched record of ip.
+func (m *MockBackend) LookupRDAP(ctx context.Context, ip string) (models.ScannerData, error) {
+	return m.enriched(ip)
+}
+
+// LookupGeo returns the geolocation fields of the Enriched record of ip.
+func (m *MockBackend) LookupGeo(ctx context.Context, ip string) (extractor.GeoResult, error) {
+	r, err := m.enriched(ip)
+	if err != nil {
+		return extractor.GeoResult{}, err
+	}
+	return extractor.GeoResult{
+		CountryCode:   r.CountryCode,
+		Country:       r.CountryName,
+		City:          r.City,
+		ISP:           r.ISP,
+		Continent:     r.Continent,
+		ContinentCode: r.ContinentCode,
+		Region:        r.Region,
+		ASN:           strings.TrimSpace(r.ASN + " " + r.ASName),
+		ReverseDNS:    r.ReverseDNS,
+	}, nil
+}
+
+// LookupInternetDB returns the open ports, host names and CPEs of the
+// Enriched record of ip.
+func (m *MockBackend) LookupInternetDB(ctx context.Context, ip string) (extractor.InternetDBResult, error) {
+	r, err := m.enriched(ip)
+	if err != nil {
+		return extractor.InternetDBResult{}, err
+	}
+	return extractor.InternetDBResult{Ports: r.OpenPorts, Hostnames: r.Hostnames, CPEs: r.CPEs}, nil
+}
+
+// BackfillGeo copies the geolocation fields of the Enriched record of the
+// IP of item, reporting whether there was one.
+func (m *MockBackend) BackfillGeo(ctx context.Context, item *models.ScannerData) (bool, error) {
+	r, err := m.enriched(item.IPOrCIDR)
+	if err != nil {
+		return false, nil
+	}
+	item.CountryName, item.CountryCode, item.City, item.ISP = r.CountryName, r.CountryCode, r.City, r.ISP
+	item.Continent, item.ContinentCode, item.Region = r.Continent, r.ContinentCode, r.Region
+	return true, nil
+}
+
+// ExpandASN lists the RDAP networks of the Enriched records with the ASN
+// asn as its prefixes.
+func (m *MockBackend) ExpandASN(ctx context.Context, asn string, data []models.ScannerData) (*extractor.ASNExpansion, error) {
+	norm, err := extractor.NormalizeASN(asn)
+	if err != nil {
+		return nil, err
+	}
+	exp := &extractor.ASNExpansion{ASN: norm}
+	for _, r := range m.Enriched {
+		if a, err := extractor.NormalizeASN(r.ASN); err == nil && a == norm && r.RDAPCIDR != "" {
+			exp.Prefixes = append(exp.Prefixes, r.RDAPCIDR)
+		}
+	}
+	for _, item := range data {
+		if a, err := extractor.NormalizeASN(item.ASN); err == nil && a == norm {
+			exp.MatchingIPs = append(exp.MatchingIPs, item.IPOrCIDR)
+		}
+	}
+	return exp, nil
+}
+
+// SelfTest reports a single passing check.
+func (m *MockBackend) SelfTest() []extractor.SelfTestResult {
+	return []extractor.SelfTestResult{{Name: "Mock backend", OK: true, Detail: fmt.Sprintf("%d canned records", len(m.Records))}}
+}
+
+// NotifyExpiry sends nothing.
+func (m *MockBackend) NotifyExpiry(ctx context.Context, warnings []extractor.ExpiryWarning) (int, error) {
+	return 0, nil
+}
+
+// PushMetrics sends nothing.
+func (m *MockBackend) PushMetrics(ctx context.Context, data []models.ScannerData) error {
+	return nil
+}
+
+// ReportToAbuseIPDB fails: the mock reports nowhere.
+func (m *MockBackend) ReportToAbuseIPDB(ctx context.Context, data []models.ScannerData) (extractor.AbuseReportSummary, error) {
+	return extractor.AbuseReportSummary{}, fmt.Errorf("mock backend: AbuseIPDB reporting unavailable")
+}
+
+// AbuseIPDBQuotaLeft fails: the mock reports nowhere.
+func (m *MockBackend) AbuseIPDBQuotaLeft() (int, error) {
+	return 0, fmt.Errorf("mock backend: AbuseIPDB reporting unavailable")
+}
+
+var _ Backend = (*MockBackend)(nil)
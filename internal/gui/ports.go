@@ -0,0 +1,56 @@
+// Package gui provides the graphical user interface for LiaCheckScanner.
+// This file contains the open port lookup against Shodan's InternetDB.
+package gui
+
+import (
+	"fmt"
+	"net"
+
+	"fyne.io/fyne/v2/dialog"
+
+	"github.com/lia/liacheckscanner_go/pkg/extractor"
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// lookupPorts looks ip up in InternetDB and copies the open ports, host
+// names and CPEs found to the dataset and search result records of ip
+func (a *App) lookupPorts(ip string) (extractor.InternetDBResult, error) {
+	r, err := a.extractor.LookupInternetDB(a.runContext(), ip)
+	if err != nil {
+		a.logger.Warning("GUI", fmt.Sprintf("InternetDB lookup failed for %s: %v", ip, err))
+		return r, err
+	}
+	updated := a.updateRecordsOf(ip, r.Apply)
+	for i := range a.searchResults {
+		if a.searchResults[i].IPOrCIDR == ip {
+			r.Apply(&a.searchResults[i])
+		}
+	}
+	if len(updated) > 0 {
+		a.publishRecordUpdated(ip)
+	}
+	a.logger.Info("GUI", fmt.Sprintf("🔌 InternetDB %s: %d open ports, %d records updated", ip, len(r.Ports), len(updated)))
+	return r, nil
+}
+
+// refreshPorts looks the IP of item up in InternetDB, then shows its
+// details again with the ports found
+func (a *App) refreshPorts(item models.ScannerData, done func()) {
+	if net.ParseIP(item.IPOrCIDR) == nil {
+		dialog.ShowError(fmt.Errorf("InternetDB only knows single IP addresses, not %s", item.IPOrCIDR), a.mainWindow)
+		return
+	}
+	a.setBusy(true, "InternetDB en cours...")
+	go func() {
+		defer a.crash.Recover("GUI")
+		r, err := a.lookupPorts(item.IPOrCIDR)
+		a.setBusy(false, "")
+		if err != nil {
+			dialog.ShowError(err, a.mainWindow)
+			return
+		}
+		r.Apply(&item)
+		done()
+		a.showRecordDetails(item)
+	}()
+}
@@ -0,0 +1,34 @@
+// Package gui provides the graphical user interface for LiaCheckScanner.
+// This file contains the live display of the request rates per provider.
+package gui
+
+import (
+	"time"
+)
+
+// rateMetricsInterval is how often the request rates are redrawn.
+const rateMetricsInterval = 2 * time.Second
+
+// runRateMetrics redraws the request rates of the dashboard and the
+// Database tab every rateMetricsInterval, for the lifetime of the app.
+func (a *App) runRateMetrics() {
+	defer a.crash.Recover("GUI")
+	ticker := time.NewTicker(rateMetricsInterval)
+	defer ticker.Stop()
+	a.updateRateMetrics()
+	for range ticker.C {
+		a.updateRateMetrics()
+	}
+}
+
+// updateRateMetrics shows the current request rates in the widgets built
+// so far.
+func (a *App) updateRateMetrics() {
+	r := a.extractor.RateMetrics()
+	if a.rateLabel != nil {
+		a.rateLabel.SetText(a.text(RateReportText(r, time.Now())))
+	}
+	if a.rateStatus != nil {
+		a.rateStatus.SetText(a.text(RateStatusLine(r)))
+	}
+}
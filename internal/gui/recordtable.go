@@ -0,0 +1,749 @@
+// Package gui provides the graphical user interface for LiaCheckScanner.
+// This file contains the record table shared by the Database and Search tabs:
+// header row, column sizing, pagination, selection, details and RDAP actions.
+package gui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/lia/liacheckscanner_go/internal/config"
+	"github.com/lia/liacheckscanner_go/pkg/extractor"
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// recordTable is a paginated table of scanner records. The records are read
+// through rows on every refresh, so the table follows the slice it shows
+// (the dataset or the search results) as it is replaced or enriched. When
+// count and fetch are set and count succeeds, the table pages through them
+// instead, reading only the records of the current page.
+type recordTable struct {
+	app  *App
+	rows func() []models.ScannerData
+	// enrich runs RDAP enrichment on the record at idx and propagates the change
+	enrich func(ctx context.Context, idx int, delayMs int) error
+	// csvPrefix names the CSV written after enriching a page
+	csvPrefix string
+
+	// count returns the number of records to page through, and false when
+	// the table shows rows() instead; fetch reads limit records from offset
+	count func() (int, bool)
+	fetch func(offset, limit int) ([]models.ScannerData, error)
+	// paging is set while the table pages through fetch; pageItems then
+	// holds the records of the current page, from pageStart, out of total
+	paging    bool
+	pageItems []models.ScannerData
+	pageStart int
+	total     int
+
+	table *widget.Table
+	info  *widget.Label
+
+	itemsPerPage int
+	currentPage  int
+	totalPages   int
+
+	// selectedRow is the index of the last clicked row, or -1;
+	// selectedRows accumulates clicked rows for "Export Selected" and the bulk actions
+	selectedRow  int
+	selectedRows map[int]bool
+}
+
+// newRecordTable creates a record table over rows with 100 records per page.
+func (a *App) newRecordTable(rows func() []models.ScannerData, enrich func(ctx context.Context, idx int, delayMs int) error, csvPrefix string) *recordTable {
+	t := &recordTable{
+		app:          a,
+		rows:         rows,
+		enrich:       enrich,
+		csvPrefix:    csvPrefix,
+		itemsPerPage: 100,
+		currentPage:  1,
+		totalPages:   1,
+		selectedRow:  -1,
+		selectedRows: make(map[int]bool),
+	}
+	t.info = widget.NewLabel("Page 1 of 1 (0-0 of 0 records)")
+	t.info.TextStyle = fyne.TextStyle{Bold: true}
+
+	t.table = widget.NewTable(
+		func() (int, int) {
+			start, end := t.pageBounds()
+			// +1 pour la ligne d'en-tête
+			return end - start + 1, len(t.columns())
+		},
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Wrapping = fyne.TextWrapOff
+			label.Alignment = fyne.TextAlignLeading
+			return label
+		},
+		func(i widget.TableCellID, o fyne.CanvasObject) {
+			label := o.(*widget.Label)
+			if i.Row == 0 {
+				// Ligne d'en-tête
+				label.TextStyle = fyne.TextStyle{Bold: true}
+				label.Alignment = fyne.TextAlignCenter
+				label.SetText(RecordColumns[t.column(i.Col)])
+				return
+			}
+			label.TextStyle = fyne.TextStyle{Bold: false}
+			label.Alignment = fyne.TextAlignLeading
+			if item, ok := t.record(t.rowIndex(i.Row)); ok {
+				label.SetText(RecordCell(item, t.column(i.Col)))
+			} else {
+				label.SetText("")
+			}
+		},
+	)
+	t.table.OnSelected = func(id widget.TableCellID) {
+		idx := t.rowIndex(id.Row)
+		if id.Row > 0 && idx < t.size() {
+			t.selectedRow = idx
+			t.selectedRows[idx] = true
+		}
+	}
+	t.applyLayout()
+	return t
+}
+
+// columns returns the indexes in RecordColumns of the columns shown, in
+// table order (see VisibleColumns).
+func (t *recordTable) columns() []int {
+	return VisibleColumns(t.app.config.ExtraColumns)
+}
+
+// column converts a table column into an index in RecordColumns, or -1.
+func (t *recordTable) column(col int) int {
+	if cols := t.columns(); col >= 0 && col < len(cols) {
+		return cols[col]
+	}
+	return -1
+}
+
+// size returns the number of records the table pages through.
+func (t *recordTable) size() int {
+	if t.paging {
+		return t.total
+	}
+	return len(t.rows())
+}
+
+// paged reports whether the table pages through fetch rather than rows().
+func (t *recordTable) paged() bool {
+	return t.paging
+}
+
+// load reads the records of the current page through fetch, or leaves the
+// table on rows() when count is unset or fails.
+func (t *recordTable) load() {
+	t.paging, t.pageItems, t.pageStart = false, nil, 0
+	if t.count == nil {
+		return
+	}
+	n, ok := t.count()
+	if !ok {
+		return
+	}
+	t.paging, t.total = true, n
+	start, end := t.pageBounds()
+	if end <= start {
+		return
+	}
+	items, err := t.fetch(start, end-start)
+	if err != nil {
+		t.app.logger.Warning("GUI", "Records page not read: "+err.Error())
+	}
+	t.pageItems, t.pageStart = items, start
+}
+
+// record returns the record at idx, read through fetch when the table pages
+// through it and idx is not on the current page.
+func (t *recordTable) record(idx int) (models.ScannerData, bool) {
+	if !t.paging {
+		rows := t.rows()
+		if idx < 0 || idx >= len(rows) {
+			return models.ScannerData{}, false
+		}
+		return rows[idx], true
+	}
+	if i := idx - t.pageStart; i >= 0 && i < len(t.pageItems) {
+		return t.pageItems[i], true
+	}
+	if idx < 0 || idx >= t.total {
+		return models.ScannerData{}, false
+	}
+	items, err := t.fetch(idx, 1)
+	if err != nil || len(items) == 0 {
+		return models.ScannerData{}, false
+	}
+	return items[0], true
+}
+
+// pageRecords returns the records of the current page.
+func (t *recordTable) pageRecords() []models.ScannerData {
+	if t.paging {
+		return t.pageItems
+	}
+	start, end := t.pageBounds()
+	return t.rows()[start:end]
+}
+
+// pageBounds returns the range of records shown on the current page.
+func (t *recordTable) pageBounds() (start, end int) {
+	_, _, start, end = CalculatePagination(t.size(), t.itemsPerPage, t.currentPage)
+	return start, end
+}
+
+// rowIndex converts a table row (row 0 is the header) into a record index.
+func (t *recordTable) rowIndex(row int) int {
+	start, _ := t.pageBounds()
+	return start + row - 1
+}
+
+// selected returns the selected record index, or false when no row of the
+// current records is selected.
+func (t *recordTable) selected() (int, bool) {
+	if t.selectedRow < 0 || t.selectedRow >= t.size() {
+		return 0, false
+	}
+	return t.selectedRow, true
+}
+
+// selectedRecords returns the rows clicked since the last reset.
+func (t *recordTable) selectedRecords() []models.ScannerData {
+	var out []models.ScannerData
+	for idx, sel := range t.selectedRows {
+		if !sel {
+			continue
+		}
+		if item, ok := t.record(idx); ok {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// resetSelection forgets the selected rows, e.g. when the records are replaced.
+func (t *recordTable) resetSelection() {
+	t.selectedRow = -1
+	t.selectedRows = make(map[int]bool)
+	t.table.UnselectAll()
+}
+
+// Refresh recomputes the pages, reads the current one, redraws the table and
+// resizes its columns.
+func (t *recordTable) Refresh() {
+	t.load()
+	n := t.size()
+	totalPages, validPage, start, end := CalculatePagination(n, t.itemsPerPage, t.currentPage)
+	t.totalPages = totalPages
+	if validPage != t.currentPage {
+		t.currentPage = validPage
+		t.load()
+	}
+	t.info.SetText(fmt.Sprintf("Page %d of %d (%d-%d of %d records)",
+		t.currentPage, t.totalPages, start+1, end, n))
+	t.table.Refresh()
+	t.applyLayout()
+}
+
+// refreshRecord redraws the rows of the current page showing ip, leaving the
+// rest of the table and the column widths alone.
+func (t *recordTable) refreshRecord(ip string) {
+	page := t.pageRecords()
+	rows := PageRowsFor(page, 0, len(page), ip)
+	if t.paging && len(rows) > 0 {
+		// The page holds copies of the stored records: read them again
+		t.load()
+	}
+	for _, row := range rows {
+		for col := range t.columns() {
+			t.table.RefreshItem(widget.TableCellID{Row: row, Col: col})
+		}
+	}
+}
+
+// applyLayout sets the column widths and row heights: columns the user
+// resized keep their width, the others fit the visible page
+func (t *recordTable) applyLayout() {
+	style := fyne.TextStyle{}
+	page := t.pageRecords()
+	measure := func(s string) float32 {
+		return fyne.MeasureText(s, theme.TextSize(), style).Width
+	}
+	widths := ColumnLayout(page, 0, len(page), t.app.config.ColumnWidths, measure)
+	for col, c := range t.columns() {
+		t.table.SetColumnWidth(col, widths[c])
+	}
+	height := t.app.config.RowHeight
+	if height <= 0 {
+		height = DefaultRowHeight
+	}
+	for r := 0; r <= len(page); r++ {
+		t.table.SetRowHeight(r, height)
+	}
+}
+
+// setColumnWidth resizes column col of RecordColumns in both record tables
+// and remembers the width as a user choice
+func (a *App) setColumnWidth(col int, width float32) {
+	if a.config.ColumnWidths == nil {
+		a.config.ColumnWidths = map[string]float32{}
+	}
+	a.config.ColumnWidths[RecordColumns[col]] = width
+	for _, t := range []*recordTable{a.records, a.searchTable} {
+		if t == nil {
+			continue
+		}
+		for i, c := range t.columns() {
+			if c == col {
+				t.table.SetColumnWidth(i, width)
+			}
+		}
+	}
+}
+
+// setColumnShown shows or hides the optional column name in both record
+// tables
+func (a *App) setColumnShown(name string, shown bool) {
+	var extra []string
+	for _, c := range a.config.ExtraColumns {
+		if c != name {
+			extra = append(extra, c)
+		}
+	}
+	if shown {
+		extra = append(extra, name)
+	}
+	a.config.ExtraColumns = extra
+	for _, t := range []*recordTable{a.records, a.searchTable} {
+		if t != nil {
+			t.Refresh()
+		}
+	}
+}
+
+// applyTableLayouts re-applies the layout preferences to both record tables
+func (a *App) applyTableLayouts() {
+	for _, t := range []*recordTable{a.records, a.searchTable} {
+		if t != nil {
+			t.applyLayout()
+		}
+	}
+}
+
+// showColumnSettings lets the user resize the record table columns and rows
+// and show the optional columns. The choices apply to both tables and are
+// saved in the configuration when the dialog closes; a column set back to
+// "Auto" fits its content again.
+func (a *App) showColumnSettings() {
+	widthLabel := widget.NewLabel("Auto")
+	widthSlider := widget.NewSlider(40, 600)
+	widthSlider.Step = 4
+	colSelect := widget.NewSelect(RecordColumns, nil)
+	// syncing is set while the slider follows the selected column, so that
+	// selecting a column does not pin its width
+	syncing := false
+	colSelect.OnChanged = func(name string) {
+		w, ok := a.config.ColumnWidths[name]
+		if !ok {
+			widthLabel.SetText("Auto")
+			return
+		}
+		syncing = true
+		widthSlider.SetValue(float64(w))
+		syncing = false
+		widthLabel.SetText(fmt.Sprintf("%.0f px", w))
+	}
+	widthSlider.OnChanged = func(v float64) {
+		col := colSelect.SelectedIndex()
+		if syncing || col < 0 {
+			return
+		}
+		a.setColumnWidth(col, float32(v))
+		widthLabel.SetText(fmt.Sprintf("%.0f px", v))
+	}
+	autoBtn := widget.NewButton("↺ Auto", func() {
+		if name := colSelect.Selected; name != "" {
+			delete(a.config.ColumnWidths, name)
+			widthLabel.SetText("Auto")
+			a.applyTableLayouts()
+		}
+	})
+
+	height := a.config.RowHeight
+	if height <= 0 {
+		height = DefaultRowHeight
+	}
+	rowLabel := widget.NewLabel(fmt.Sprintf("%.0f px", height))
+	rowSlider := widget.NewSlider(20, 120)
+	rowSlider.Step = 2
+	rowSlider.SetValue(float64(height))
+	rowSlider.OnChanged = func(v float64) {
+		if syncing {
+			return
+		}
+		a.config.RowHeight = float32(v)
+		rowLabel.SetText(fmt.Sprintf("%.0f px", v))
+		a.applyTableLayouts()
+	}
+
+	resetBtn := widget.NewButton("↺ Tout réinitialiser", func() {
+		a.config.ColumnWidths = nil
+		a.config.RowHeight = 0
+		widthLabel.SetText("Auto")
+		syncing = true
+		rowSlider.SetValue(DefaultRowHeight)
+		syncing = false
+		rowLabel.SetText(fmt.Sprintf("%d px", DefaultRowHeight))
+		a.applyTableLayouts()
+	})
+
+	optional := container.NewHBox()
+	for _, name := range OptionalColumns {
+		name := name
+		check := widget.NewCheck(name, func(on bool) { a.setColumnShown(name, on) })
+		for _, c := range a.config.ExtraColumns {
+			if c == name {
+				check.SetChecked(true)
+			}
+		}
+		optional.Add(check)
+	}
+
+	content := container.NewVBox(
+		widget.NewLabel("Column"),
+		container.NewBorder(nil, nil, nil, container.NewHBox(widthLabel, autoBtn), colSelect),
+		widthSlider,
+		widget.NewLabel("Row height"),
+		container.NewBorder(nil, nil, nil, rowLabel, rowSlider),
+		widget.NewLabel("Optional columns"),
+		optional,
+		resetBtn,
+	)
+	a.applyPlainLabels(content)
+	d := dialog.NewCustom(a.text("📐 Colonnes"), "Close", content, a.mainWindow)
+	d.SetOnClosed(func() {
+		if err := config.NewConfigManager().Save(a.config); err != nil {
+			a.logger.Warning("GUI", "Saving table layout failed: "+err.Error())
+		}
+	})
+	d.Resize(fyne.NewSize(420, 0))
+	d.Show()
+}
+
+// paginationControls returns the page size selector and navigation buttons.
+func (t *recordTable) paginationControls() fyne.CanvasObject {
+	itemsPerPageSelect := widget.NewSelect([]string{"25", "50", "100", "250", "500", "1000", "All"}, func(value string) {
+		if value == "" {
+			return
+		}
+		if value == "All" {
+			t.itemsPerPage = t.size()
+		} else {
+			t.itemsPerPage, _ = strconv.Atoi(value)
+		}
+		t.currentPage = 1
+		t.Refresh()
+	})
+	itemsPerPageSelect.SetSelected("100")
+
+	firstPageBtn := widget.NewButton("⏮️ First", func() {
+		t.currentPage = 1
+		t.Refresh()
+	})
+	prevPageBtn := widget.NewButton("◀️ Previous", func() {
+		if t.currentPage > 1 {
+			t.currentPage--
+			t.Refresh()
+		}
+	})
+	nextPageBtn := widget.NewButton("▶️ Next", func() {
+		if t.currentPage < t.totalPages {
+			t.currentPage++
+			t.Refresh()
+		}
+	})
+	lastPageBtn := widget.NewButton("⏭️ Last", func() {
+		t.currentPage = t.totalPages
+		t.Refresh()
+	})
+
+	// Go to specific page with validation
+	pageEntry := widget.NewEntry()
+	pageEntry.SetPlaceHolder("Enter page number...")
+	goToPageBtn := widget.NewButton("🔍 Go", func() {
+		if pageStr := pageEntry.Text; pageStr != "" {
+			if page, err := strconv.Atoi(pageStr); err == nil && page > 0 && page <= t.totalPages {
+				t.currentPage = page
+				t.Refresh()
+				pageEntry.SetText("")
+			} else {
+				t.app.showInformation("Invalid Page", "Please enter a valid page number", t.app.mainWindow)
+			}
+		}
+	})
+
+	return container.NewHBox(
+		container.NewVBox(widget.NewLabel("Records per page:"), itemsPerPageSelect),
+		container.NewVBox(firstPageBtn, prevPageBtn),
+		container.NewVBox(t.info, container.NewHBox(pageEntry, goToPageBtn)),
+		container.NewVBox(nextPageBtn, lastPageBtn),
+	)
+}
+
+// actions returns the buttons acting on the selected row or the current page.
+func (t *recordTable) actions() []fyne.CanvasObject {
+	a := t.app
+	detailsBtn := widget.NewButton("ℹ️ RDAP Details", func() {
+		idx, ok := t.selected()
+		if !ok {
+			a.showInformation("RDAP", "Sélectionne une ligne d'abord", a.mainWindow)
+			return
+		}
+		if item, ok := t.record(idx); ok {
+			a.showRecordDetails(item)
+		}
+	})
+
+	enrichRowBtn := widget.NewButton("🌍 RDAP (ligne)", func() {
+		idx, ok := t.selected()
+		if !ok {
+			a.showInformation("RDAP", "Sélectionne une ligne d'abord", a.mainWindow)
+			return
+		}
+		item, ok := t.record(idx)
+		if !ok {
+			return
+		}
+		go func() {
+			defer a.crash.Recover("GUI")
+			if err := t.enrich(a.runContext(), idx, 0); err != nil {
+				a.logger.Warning("GUI", fmt.Sprintf("RDAP enrich error for %s: %v", item.IPOrCIDR, err))
+			}
+			a.updateStats()
+			if item, ok := t.record(idx); ok {
+				a.showRecordDetails(item)
+			}
+		}()
+	})
+
+	enrichPageBtn := widget.NewButton("🌍 Associer RDAP (page)", func() {
+		start, end := t.pageBounds()
+		a.setBusy(true, "RDAP (page) en cours...")
+		ctx := a.runContext()
+		go func() {
+			defer a.crash.Recover("GUI")
+			for i := start; i < end; i++ {
+				// The records may be replaced meanwhile, e.g. by a new search
+				item, ok := t.record(i)
+				if !ok || ctx.Err() != nil {
+					break
+				}
+				if err := t.enrich(ctx, i, int(a.config.Database.APIThrottle*1000)); err != nil {
+					a.logger.Warning("GUI", fmt.Sprintf("RDAP enrich error for %s: %v", item.IPOrCIDR, err))
+				}
+				a.updateStats()
+			}
+			ts := time.Now().Format("2006-01-02_15-04-05")
+			filename := fmt.Sprintf("%s_enriched_%s.csv", t.csvPrefix, ts)
+			_ = a.extractor.Export(t.rows(), filename)
+			a.setBusy(false, "")
+			a.showInformation("RDAP", "Page enrichie (RDAP)\nCSV: "+filename, a.mainWindow)
+		}()
+	})
+
+	var pivotBtn *widget.Button
+	pivotBtn = widget.NewButton("🔗 Pivot", func() {
+		idx, ok := t.selected()
+		if !ok {
+			a.showInformation("Pivot", "Sélectionne une ligne d'abord", a.mainWindow)
+			return
+		}
+		if item, ok := t.record(idx); ok {
+			a.showPivotMenu(item, pivotBtn)
+		}
+	})
+
+	dossierBtn := widget.NewButton("🗂️ Dossier", func() {
+		idx, ok := t.selected()
+		if !ok {
+			a.showInformation("Dossier", "Sélectionne une ligne d'abord", a.mainWindow)
+			return
+		}
+		if item, ok := t.record(idx); ok {
+			a.generateDossier(item.IPOrCIDR)
+		}
+	})
+
+	clearSelectionBtn := widget.NewButton("☐ Clear selection", func() {
+		t.resetSelection()
+	})
+
+	columnsBtn := widget.NewButton("📐 Colonnes", func() {
+		a.showColumnSettings()
+	})
+
+	bulkBtn := widget.NewButton("🧰 Actions groupées", func() {
+		t.showBulkActions()
+	})
+
+	return []fyne.CanvasObject{detailsBtn, enrichRowBtn, enrichPageBtn, pivotBtn, dossierBtn, clearSelectionBtn, bulkBtn, columnsBtn}
+}
+
+// showPivotMenu lists under anchor the external tools item can be opened
+// in (see extractor.PivotURLs) and opens the chosen one in the browser.
+func (a *App) showPivotMenu(item models.ScannerData, anchor fyne.CanvasObject) {
+	var items []*fyne.MenuItem
+	for _, l := range a.extractor.PivotURLs(item) {
+		link := l
+		items = append(items, fyne.NewMenuItem(link.Name, func() {
+			u, err := url.Parse(link.URL)
+			if err == nil {
+				err = a.fyneApp.OpenURL(u)
+			}
+			if err != nil {
+				a.logger.Warning("GUI", fmt.Sprintf("Cannot open %s: %v", link.URL, err))
+			}
+		}))
+	}
+	if len(items) == 0 {
+		a.showInformation("Pivot", "Aucun lien pour cet enregistrement", a.mainWindow)
+		return
+	}
+	pos := fyne.CurrentApp().Driver().AbsolutePositionForObject(anchor).Add(fyne.NewPos(0, anchor.Size().Height))
+	widget.ShowPopUpMenuAtPosition(fyne.NewMenu("", items...), a.mainWindow.Canvas(), pos)
+}
+
+// generateDossier writes the printable dossier of ip to the results
+// directory and opens it in the browser, from which it can be printed or
+// saved as PDF.
+func (a *App) generateDossier(ip string) {
+	d, err := a.extractor.BuildDossier(ip, a.dataset())
+	if err != nil {
+		dialog.ShowError(err, a.mainWindow)
+		return
+	}
+	ts := time.Now().Format("2006-01-02_15-04-05")
+	name := strings.NewReplacer("/", "_", ":", "-").Replace(ip)
+	path, err := a.extractor.SaveDossier(d, fmt.Sprintf("dossier_%s_%s.html", name, ts))
+	if err != nil {
+		dialog.ShowError(err, a.mainWindow)
+		return
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if err := a.fyneApp.OpenURL(&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}); err != nil {
+		a.showInformation("Dossier", "Dossier: "+path, a.mainWindow)
+	}
+}
+
+// view returns the table in a horizontal scroll sized for its 14 columns.
+func (t *recordTable) view(minHeight float32) fyne.CanvasObject {
+	// Vertical scroll is handled by widget.Table
+	hscroll := container.NewHScroll(t.table)
+	hscroll.SetMinSize(fyne.NewSize(1800, minHeight))
+	return hscroll
+}
+
+// showRecordDetails shows the RDAP, ASN, PeeringDB and lifecycle fields of
+// a record with its full JSON.
+func (a *App) showRecordDetails(item models.ScannerData) {
+	details := fmt.Sprintf(`IP: %s\nName: %s\nHandle: %s\nCIDR: %s\nRegistry: %s\nStart: %s\nEnd: %s\nIP Version: %s\nType: %s\nParent: %s\nReg: %s\nChanged: %s\nASN: %s\nAS Name: %s\nReverse: %s\nAbuse: %s\nTech: %s\nPeeringDB: %s\nNetwork Type: %s\nTraffic: %s\nPeeringDB Contacts: %s\nState: %s (runs: %d)`,
+		item.IPOrCIDR, item.RDAPName, item.RDAPHandle, item.RDAPCIDR, item.Registry,
+		item.StartAddress, item.EndAddress, item.IPVersion, item.RDAPType, item.ParentHandle,
+		models.FormatTimestamp(item.EventRegistration), models.FormatTimestamp(item.EventLastChanged), item.ASN, item.ASName, item.ReverseDNS,
+		item.AbuseEmail, item.TechEmail,
+		item.PeeringDBName, item.NetworkType, item.TrafficLevel, item.PeeringDBContacts,
+		item.State, item.RunsSeen,
+	)
+	if len(item.Provenance) > 0 {
+		details += "\nProvenance: " + models.FormatProvenance(item.Provenance)
+	}
+	if label := AttributionLabel(item); label != "" {
+		details += "\n" + label
+	}
+	if label := FeedTrustLabel(item); label != "" {
+		details += "\n" + label
+	}
+	if label := LockLabel(item); label != "" {
+		details += "\n" + label
+	}
+	if item.PreviousOwner != "" {
+		details += fmt.Sprintf("\nOwnership changed: %s -> %s", item.PreviousOwner, extractor.OwnerLabel(item.RDAPName, item.RDAPHandle))
+	}
+	if item.RiskDecay > 0 {
+		details += fmt.Sprintf("\nRisk decay: %.0f%%", item.RiskDecay*100)
+		if item.Stale {
+			details += " (stale: excluded from enforcement exports)"
+		}
+	}
+	switch item.BroadPrefix {
+	case models.BroadPrefixFlagged:
+		details += "\nBroad prefix: flagged for review before enforcement"
+	case models.BroadPrefixExcluded:
+		details += "\nBroad prefix: excluded from enforcement exports"
+	}
+	if item.PolicyHold != "" {
+		details += "\nEnforcement policy: " + item.PolicyHold + " (excluded from enforcement exports)"
+	}
+	if item.HitCount > 0 {
+		details += fmt.Sprintf("\nHoneypot hits: %d (last: %s)", item.HitCount, item.LastHit.Format("2006-01-02 15:04:05"))
+	}
+	if len(item.TargetPorts) > 0 {
+		details += "\nTarget ports: " + models.FormatPorts(item.TargetPorts)
+	}
+	if label := PortsLabel(item); label != "" {
+		details += "\n" + label
+	}
+	jsonRaw, _ := json.MarshalIndent(item, "", "  ")
+	content := container.NewVBox(
+		widget.NewLabel("RDAP Details"),
+		widget.NewMultiLineEntry(),
+	)
+	ml := content.Objects[1].(*widget.Entry)
+	ml.MultiLine = true
+	ml.SetText(details + "\n\nJSON:\n" + string(jsonRaw))
+	ml.Disable()
+	if u, ok := a.extractor.OptOutURL(item); ok {
+		content.Add(widget.NewButton(a.text("🚫 Opt-out "+item.ScannerName), func() {
+			a.openOptOut(item, u)
+		}))
+	}
+	d := dialog.NewCustom("RDAP Details", "Close", container.NewScroll(content), a.mainWindow)
+	if net.ParseIP(item.IPOrCIDR) != nil {
+		content.Add(widget.NewButton(a.text("🔌 Open Ports (InternetDB)"), func() {
+			a.refreshPorts(item, d.Hide)
+		}))
+	}
+	d.Show()
+}
+
+// openOptOut copies the IP or range of item to the clipboard, ready to paste
+// in the removal form, and opens the opt-out page of its scanner.
+func (a *App) openOptOut(item models.ScannerData, page string) {
+	a.mainWindow.Clipboard().SetContent(item.IPOrCIDR)
+	u, err := url.Parse(page)
+	if err == nil {
+		err = a.fyneApp.OpenURL(u)
+	}
+	if err != nil {
+		a.showInformation("Opt-out", fmt.Sprintf("%s copié\n%s", item.IPOrCIDR, page), a.mainWindow)
+		return
+	}
+	a.logger.Info("GUI", fmt.Sprintf("Opt-out page opened for %s (%s copied)", item.ScannerName, item.IPOrCIDR))
+}
@@ -0,0 +1,91 @@
+// Package gui provides the graphical user interface for LiaCheckScanner.
+// This file contains the Runs tab, which compares two runs saved in the
+// results directory and exports their differences.
+package gui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/lia/liacheckscanner_go/pkg/extractor"
+)
+
+// createRunsTab creates the tab listing the IPs added, removed and changed
+// between two saved runs, by default the latest and the one before.
+func (a *App) createRunsTab() fyne.CanvasObject {
+	var diff *extractor.RunDiff
+	var lines []string
+
+	summary := widget.NewLabel("")
+	list := widget.NewList(
+		func() int { return len(lines) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(i widget.ListItemID, o fyne.CanvasObject) { o.(*widget.Label).SetText(lines[i]) },
+	)
+	fromSelect := widget.NewSelect(nil, nil)
+	toSelect := widget.NewSelect(nil, nil)
+
+	compare := func() {
+		diff, lines = nil, nil
+		if fromSelect.Selected == "" || toSelect.Selected == "" {
+			summary.SetText("Au moins deux runs sauvegardés sont nécessaires")
+			list.Refresh()
+			return
+		}
+		d, err := a.extractor.DiffRunFiles(fromSelect.Selected, toSelect.Selected)
+		if err != nil {
+			summary.SetText("⚠️ " + err.Error())
+			list.Refresh()
+			return
+		}
+		diff, lines = &d, DiffLines(d.DatasetDiff)
+		summary.SetText(fmt.Sprintf("%s -> %s: %d ajoutés, %d supprimés, %d modifiés",
+			RunLabel(d.From), RunLabel(d.To), len(d.Added), len(d.Removed), len(d.Changed)))
+		list.Refresh()
+	}
+	fromSelect.OnChanged = func(string) { compare() }
+	toSelect.OnChanged = func(string) { compare() }
+
+	// reload lists the saved runs again and selects the two latest
+	reload := func() {
+		runs, err := a.extractor.Runs()
+		if err != nil {
+			a.logger.Warning("GUI", "Runs not listed: "+err.Error())
+		}
+		fromSelect.Options, toSelect.Options = runs, runs
+		fromSelect.Selected, toSelect.Selected = "", ""
+		if len(runs) >= 2 {
+			fromSelect.Selected, toSelect.Selected = runs[len(runs)-2], runs[len(runs)-1]
+		}
+		fromSelect.Refresh()
+		toSelect.Refresh()
+		compare()
+	}
+	reload()
+
+	export := func(format string) {
+		if diff == nil {
+			a.showInformation("Runs", "Aucune comparaison à exporter", a.mainWindow)
+			return
+		}
+		name := DiffFileName(diff.From, diff.To, format)
+		if err := a.extractor.SaveRunDiff(*diff, name); err != nil {
+			dialog.ShowError(err, a.mainWindow)
+			return
+		}
+		a.showInformation("Runs", fmt.Sprintf("✅ Différences exportées\n%s: %s", format, name), a.mainWindow)
+	}
+
+	controls := container.NewHBox(
+		widget.NewLabel("Du run"), fromSelect,
+		widget.NewLabel("au run"), toSelect,
+		widget.NewButton("🔄 Actualiser", reload),
+		widget.NewButton("📤 Export CSV", func() { export("csv") }),
+		widget.NewButton("📤 Export JSON", func() { export("json") }),
+	)
+	return container.NewBorder(container.NewVBox(controls, summary), nil, nil, nil, list)
+}
@@ -0,0 +1,76 @@
+// Package gui provides the graphical user interface for LiaCheckScanner.
+// This file contains the sample loader, which explores a few records of a
+// dataset too large for the machine.
+package gui
+
+import (
+	"errors"
+	"fmt"
+
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/lia/liacheckscanner_go/pkg/extractor"
+)
+
+// defaultSampleSize is the sample size first offered.
+const defaultSampleSize = 10000
+
+// chooseSample asks for the size and mode of a sample of the newest run,
+// then loads it in place of the dataset.
+func (a *App) chooseSample() {
+	sizeEntry := widget.NewEntry()
+	sizeEntry.SetText(fmt.Sprintf("%d", defaultSampleSize))
+	modeSelect := widget.NewSelect(SampleModeLabels, nil)
+	modeSelect.SetSelected(SampleModeLabels[0])
+	content := container.NewVBox(
+		widget.NewLabel("Records:"), sizeEntry,
+		widget.NewLabel("Mode:"), modeSelect,
+		widget.NewLabel("Statistics, searches and exports then cover the sample only.\nRefresh Data loads the whole dataset again."),
+	)
+	dialog.ShowCustomConfirm(a.text("🎲 Load Sample"), "Load", "Cancel", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		n, err := ParseSampleSize(sizeEntry.Text)
+		if err != nil {
+			dialog.ShowError(err, a.mainWindow)
+			return
+		}
+		a.loadSample(n, SampleModeFor(modeSelect.Selected))
+	}, a.mainWindow)
+}
+
+// loadSample draws n records of the newest run with mode, reading the file
+// row by row, and shows them instead of the dataset.
+func (a *App) loadSample(n int, mode string) {
+	files := a.csvFilesNewestFirst()
+	if len(files) == 0 {
+		dialog.ShowError(errors.New("no run in the results directory to sample"), a.mainWindow)
+		return
+	}
+	a.setBusy(true, "Chargement de l'échantillon...")
+	go func() {
+		defer a.crash.Recover("GUI")
+		s, err := extractor.SampleCSVFile(files[0], n, mode, nil)
+		a.setBusy(false, "")
+		if err != nil {
+			a.logger.Error("GUI", "Sample not loaded: "+err.Error())
+			dialog.ShowError(err, a.mainWindow)
+			return
+		}
+		a.showSample(s)
+		a.logger.Info("GUI", fmt.Sprintf("🎲 %d of %d records of %s loaded (%s sample)", len(s.Records), s.Total, files[0], s.Mode))
+	}()
+}
+
+// showSample shows the records of s instead of the dataset. The first load
+// of the session is marked done, so opening the Database tab keeps the
+// sample.
+func (a *App) showSample(s extractor.Sample) {
+	a.dataOnce.Do(func() {})
+	a.sample = &s
+	a.showData(s.Records)
+	a.setStatus(SampleNotice(s))
+}
@@ -1,22 +1,23 @@
 // Package gui provides the graphical user interface for LiaCheckScanner.
-// This file contains table creation, column headers, data binding, and layout.
+// This file contains the Database tab and its enrichment, lifecycle and export actions.
 package gui
 
 import (
-	"encoding/json"
 	"fmt"
 	"io"
-	"strconv"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
-	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 
-	"github.com/lia/liacheckscanner_go/internal/models"
+	"github.com/lia/liacheckscanner_go/pkg/events"
+	"github.com/lia/liacheckscanner_go/pkg/extractor"
+	"github.com/lia/liacheckscanner_go/pkg/models"
 )
 
 // createDatabaseTab creates the database tab with pagination and professional table display
@@ -30,259 +31,130 @@ func (a *App) createDatabaseTab() fyne.CanvasObject {
 	// Pagination controls with professional styling
 	paginationLabel := widget.NewLabel("📄 Advanced Pagination")
 	paginationLabel.TextStyle = fyne.TextStyle{Bold: true}
+	paginationControls := a.records.paginationControls()
 
-	// Items per page selector with more options
-	itemsPerPageSelect := widget.NewSelect([]string{"25", "50", "100", "250", "500", "1000", "All"}, func(value string) {
-		if value != "" {
-			if value == "All" {
-				a.itemsPerPage = len(a.data)
-			} else {
-				itemsPerPage, _ := strconv.Atoi(value)
-				a.itemsPerPage = itemsPerPage
+	// Action buttons
+	updateBtn := widget.NewButton("🔄 Mettre à jour", func() {
+		go func() {
+			defer a.crash.Recover("GUI")
+			a.setBusy(true, "Extraction en cours...")
+			err := a.extractAndQueue()
+			a.setBusy(false, "")
+			if err != nil {
+				a.logger.Warning("GUI", "Extraction error: "+err.Error())
+				dialog.ShowError(err, a.mainWindow)
+				return
 			}
-			a.currentPage = 1
-			a.updatePagination()
-		}
-	})
-	itemsPerPageSelect.SetSelected("100")
-
-	// Professional pagination info
-	a.paginationInfo = widget.NewLabel("Page 1 of 1 (0-0 of 0 records)")
-	a.paginationInfo.TextStyle = fyne.TextStyle{Bold: true}
-
-	// Navigation buttons with professional icons
-	firstPageBtn := widget.NewButton("⏮️ First", func() {
-		a.currentPage = 1
-		a.updatePagination()
+			msg := "Extraction terminée et données rechargées"
+			if !a.config.Database.SkipEnrichment {
+				msg += "\nEnrichissement RDAP en arrière-plan"
+			}
+			a.showInformation("Mise à jour", msg, a.mainWindow)
+		}()
 	})
 
-	prevPageBtn := widget.NewButton("◀️ Previous", func() {
-		if a.currentPage > 1 {
-			a.currentPage--
-			a.updatePagination()
+	associatePeeringDBBtn := widget.NewButton("🏢 Associer PeeringDB", func() {
+		if a.recordCount() == 0 {
+			a.showInformation("PeeringDB", "Aucune donnée chargée", a.mainWindow)
+			return
 		}
+		a.setBusy(true, "PeeringDB en cours...")
+		go func() {
+			defer a.crash.Recover("GUI")
+			data := a.dataset()
+			n := a.extractor.EnrichPeeringDB(a.runContext(), data)
+			a.saveStore()
+			a.records.Refresh()
+			ts := time.Now().Format("2006-01-02_15-04-05")
+			filename := fmt.Sprintf("peeringdb_enriched_%s.csv", ts)
+			_ = a.extractor.Export(data, filename)
+			a.setBusy(false, "")
+			a.showInformation("PeeringDB", fmt.Sprintf("%d enregistrements enrichis (PeeringDB)\nCSV: %s", n, filename), a.mainWindow)
+		}()
 	})
 
-	nextPageBtn := widget.NewButton("▶️ Next", func() {
-		if a.currentPage < a.totalPages {
-			a.currentPage++
-			a.updatePagination()
+	greylistBtn := widget.NewButton("🚦 Greylist", func() {
+		idx, ok := a.records.selected()
+		if !ok {
+			a.showInformation("Greylist", "Sélectionne une ligne d'abord", a.mainWindow)
+			return
 		}
-	})
-
-	lastPageBtn := widget.NewButton("⏭️ Last", func() {
-		a.currentPage = a.totalPages
-		a.updatePagination()
-	})
-
-	// Go to specific page with validation
-	pageEntry := widget.NewEntry()
-	pageEntry.SetPlaceHolder("Enter page number...")
-
-	goToPageBtn := widget.NewButton("🔍 Go", func() {
-		if pageStr := pageEntry.Text; pageStr != "" {
-			if page, err := strconv.Atoi(pageStr); err == nil && page > 0 && page <= a.totalPages {
-				a.currentPage = page
-				a.updatePagination()
-				pageEntry.SetText("")
-			} else {
-				dialog.ShowInformation("Invalid Page", "Please enter a valid page number", a.mainWindow)
-			}
+		item, ok := a.records.record(idx)
+		if !ok {
+			return
 		}
-	})
-
-	// Professional pagination controls layout
-	paginationControls := container.NewHBox(
-		container.NewVBox(
-			widget.NewLabel("Records per page:"),
-			itemsPerPageSelect,
-		),
-		container.NewVBox(
-			firstPageBtn,
-			prevPageBtn,
-		),
-		container.NewVBox(
-			a.paginationInfo,
-			container.NewHBox(
-				pageEntry,
-				goToPageBtn,
-			),
-		),
-		container.NewVBox(
-			nextPageBtn,
-			lastPageBtn,
-		),
-	)
-
-	// Table headers
-	headers := []string{"IP/CIDR", "Scanner", "Type", "Country", "ISP", "Organization", "RDAP Name", "RDAP Handle", "ASN", "Reverse", "Risk", "Score", "Domain", "Last Seen"}
-
-	// Table with styling (14 columns)
-	a.dataTable = widget.NewTable(
-		func() (int, int) {
-			startIndex := (a.currentPage - 1) * a.itemsPerPage
-			endIndex := startIndex + a.itemsPerPage
-			if endIndex > len(a.data) {
-				endIndex = len(a.data)
-			}
-			pageData := a.data[startIndex:endIndex]
-			// +1 pour la ligne d'en-tête
-			return len(pageData) + 1, 14
-		},
-		func() fyne.CanvasObject {
-			label := widget.NewLabel("")
-			label.Wrapping = fyne.TextWrapOff
-			label.Alignment = fyne.TextAlignLeading
-			return label
-		},
-		func(i widget.TableCellID, o fyne.CanvasObject) {
-			label := o.(*widget.Label)
-			if i.Row == 0 {
-				// Ligne d'en-tête
-				label.TextStyle = fyne.TextStyle{Bold: true}
-				label.Alignment = fyne.TextAlignCenter
-				label.SetText(headers[i.Col])
+		states := []string{
+			string(models.StateObserved), string(models.StateCandidate),
+			string(models.StateBlocked), string(models.StateRetired),
+		}
+		stateSelect := widget.NewSelect(states, nil)
+		if item.State != "" {
+			stateSelect.SetSelected(string(item.State))
+		} else {
+			stateSelect.SetSelected(string(models.StateObserved))
+		}
+		pinCheck := widget.NewCheck("📌 Figer (ne plus changer automatiquement)", nil)
+		pinCheck.SetChecked(true)
+		form := container.NewVBox(
+			widget.NewLabel("IP: "+item.IPOrCIDR),
+			stateSelect,
+			pinCheck,
+		)
+		dialog.ShowCustomConfirm("Greylist", "Appliquer", "Annuler", form, func(ok bool) {
+			if !ok {
 				return
 			}
-			label.TextStyle = fyne.TextStyle{Bold: false}
-			label.Alignment = fyne.TextAlignLeading
-			startIndex := (a.currentPage - 1) * a.itemsPerPage
-			realIndex := startIndex + (i.Row - 1)
-			if realIndex >= 0 && realIndex < len(a.data) {
-				item := a.data[realIndex]
-				switch i.Col {
-				case 0:
-					label.SetText(item.IPOrCIDR)
-				case 1:
-					label.SetText(item.ScannerName)
-				case 2:
-					label.SetText(string(item.ScannerType))
-				case 3:
-					label.SetText(item.CountryCode)
-				case 4:
-					label.SetText(item.ISP)
-				case 5:
-					label.SetText(item.Organization)
-				case 6:
-					label.SetText(item.RDAPName)
-				case 7:
-					label.SetText(item.RDAPHandle)
-				case 8:
-					label.SetText(item.ASN)
-				case 9:
-					label.SetText(item.ReverseDNS)
-				case 10:
-					label.SetText(item.RiskLevel)
-				case 11:
-					label.SetText(fmt.Sprintf("%d", item.AbuseConfidenceScore))
-				case 12:
-					label.SetText(item.Domain)
-				case 13:
-					label.SetText(item.LastSeen.Format("2006-01-02"))
-				}
+			state := models.LifecycleState(stateSelect.Selected)
+			if err := a.extractor.SetLifecycleState(item.IPOrCIDR, state, pinCheck.Checked); err != nil {
+				dialog.ShowError(err, a.mainWindow)
+				return
 			}
-		},
-	)
-	// Apply layout once created
-	a.applyTableLayout()
-	// Horizontal scroll (vertical scroll is handled by widget.Table)
-	hscroll := container.NewHScroll(a.dataTable)
-	hscroll.SetMinSize(fyne.NewSize(1800, 700))
-
-	// Track selection
-	a.dataTable.OnSelected = func(id widget.TableCellID) {
-		startIndex := (a.currentPage - 1) * a.itemsPerPage
-		realIndex := startIndex + id.Row
-		if realIndex >= 0 && realIndex < len(a.data) {
-			a.selectedRow = realIndex
-		}
-	}
+			item.State = state
+			a.updateRecord(item)
+			a.records.Refresh()
+		}, a.mainWindow)
+	})
 
-	// RDAP Details button
-	rdapDetailsBtn := widget.NewButton("ℹ️ RDAP Details", func() {
-		if a.selectedRow < 0 || a.selectedRow >= len(a.data) {
-			dialog.ShowInformation("RDAP", "Sélectionne une ligne d'abord", a.mainWindow)
+	publishBtn := widget.NewButton("✅ Publier blocage", func() {
+		delta, err := a.extractor.EnforcementDelta(a.dataset())
+		if err != nil {
+			dialog.ShowError(err, a.mainWindow)
 			return
 		}
-		item := a.data[a.selectedRow]
-		// Build details view
-		details := fmt.Sprintf(`IP: %s\nName: %s\nHandle: %s\nCIDR: %s\nRegistry: %s\nStart: %s\nEnd: %s\nIP Version: %s\nType: %s\nParent: %s\nReg: %s\nChanged: %s\nASN: %s\nAS Name: %s\nReverse: %s\nAbuse: %s\nTech: %s`,
-			item.IPOrCIDR, item.RDAPName, item.RDAPHandle, item.RDAPCIDR, item.Registry,
-			item.StartAddress, item.EndAddress, item.IPVersion, item.RDAPType, item.ParentHandle,
-			item.EventRegistration, item.EventLastChanged, item.ASN, item.ASName, item.ReverseDNS,
-			item.AbuseEmail, item.TechEmail,
-		)
-		jsonRaw, _ := json.MarshalIndent(item, "", "  ")
-		content := container.NewVBox(
-			widget.NewLabel("RDAP Details"),
-			widget.NewMultiLineEntry(),
-		)
-		ml := content.Objects[1].(*widget.Entry)
-		ml.MultiLine = true
-		ml.SetText(details + "\n\nJSON:\n" + string(jsonRaw))
-		ml.Disable()
-		d := dialog.NewCustom("RDAP Details", "Close", container.NewScroll(content), a.mainWindow)
-		d.Show()
+		a.showApprovalDialog(delta)
 	})
 
-	// Professional scroll container with larger size
-	scrollContainer := container.NewScroll(a.dataTable)
-	scrollContainer.SetMinSize(fyne.NewSize(1400, 700))
+	a.expiryBtn = widget.NewButton("⏳ Expirations", func() {
+		a.showExpiryDialog()
+	})
+	a.expiryBtn.Importance = widget.WarningImportance
+	a.expiryBtn.Hide()
 
-	// Action buttons
-	updateBtn := widget.NewButton("🔄 Mettre à jour", func() {
-		go func() {
-			a.setBusy(true, "Extraction en cours...")
-			if _, err := a.extractor.ExtractData(); err != nil {
-				a.logger.Warning("GUI", "Extraction error: "+err.Error())
-				dialog.ShowError(err, a.mainWindow)
-			} else {
-				a.refreshData()
-				dialog.ShowInformation("Mise à jour", "Extraction terminée et données rechargées", a.mainWindow)
-			}
-			a.setBusy(false, "")
-		}()
+	reportAbuseBtn := widget.NewButton("🚨 Signaler AbuseIPDB", func() {
+		a.reportToAbuseIPDB()
 	})
 
-	associateRDAPBtn := widget.NewButton("🌍 Associer RDAP (page)", func() {
-		startIndex := (a.currentPage - 1) * a.itemsPerPage
-		endIndex := startIndex + a.itemsPerPage
-		if endIndex > len(a.data) {
-			endIndex = len(a.data)
-		}
-		a.setBusy(true, "RDAP (page) en cours...")
-		go func() {
-			for i := startIndex; i < endIndex; i++ {
-				item := &a.data[i]
-				ip := item.IPOrCIDR
-				if err := a.extractor.EnrichRecordWithDelay(item, int(a.config.Database.APIThrottle*1000)); err != nil {
-					a.logger.Warning("GUI", fmt.Sprintf("RDAP enrich error for %s: %v", ip, err))
-				}
-				if a.dataTable != nil {
-					a.dataTable.Refresh()
-				}
-			}
-			ts := time.Now().Format("2006-01-02_15-04-05")
-			filename := fmt.Sprintf("page_enriched_%s.csv", ts)
-			_ = a.extractor.SaveToCSV(a.data, filename)
-			a.setBusy(false, "")
-			dialog.ShowInformation("RDAP", "Page enrichie (RDAP)\nCSV: "+filename, a.mainWindow)
-		}()
+	duplicatesBtn := widget.NewButton("🧹 Doublons", func() {
+		a.showDuplicatesDialog()
 	})
 
-	// Progress and cancel controls
-	progress := widget.NewProgressBar()
-	progress.Min = 0
-	progress.Max = 1
-	progress.SetValue(0)
-	progressDetail := widget.NewLabel("")
-	cancel := false
-	cancelBtn := widget.NewButton("⛔ Annuler", func() { cancel = true })
+	compareBtn := widget.NewButton("🪟 Comparer un run", func() {
+		a.openCompareWindow()
+	})
+
+	// Progress and cancel controls (updated from RecordsEnriched events)
+	a.progress = widget.NewProgressBar()
+	a.progress.Min = 0
+	a.progress.Max = 1
+	a.progress.SetValue(0)
+	a.progressDetail = widget.NewLabel("")
+	a.rateStatus = widget.NewLabel("")
+	cancelBtn := widget.NewButton("⛔ Annuler", a.cancelRuns)
 
 	// Update layout (add parallelism + resume capability)
 	associateRDAPAllBtn := widget.NewButton("🌍 Associer RDAP (tout)", func() {
-		if len(a.data) == 0 {
-			dialog.ShowInformation("RDAP", "Aucune donnée chargée", a.mainWindow)
+		if a.recordCount() == 0 {
+			a.showInformation("RDAP", "Aucune donnée chargée", a.mainWindow)
 			return
 		}
 
@@ -313,37 +185,41 @@ func (a *App) createDatabaseTab() fyne.CanvasObject {
 
 	// Add a separate function to handle the actual enrichment
 	a.startRDAPEnrichment = func(startFrom int) {
-		cancel = false
-		a.setBusy(true, "RDAP (tout) en cours...")
-
 		// Initialize or resume tracker
 		tracker := a.extractor.LoadProgressTracker()
 		if tracker == nil || startFrom == 0 {
-			tracker = &models.RDAPProgressTracker{
-				TotalRecords: len(a.data),
-				ProcessedIPs: []string{},
-				StartedAt:    time.Now().Format(time.RFC3339),
-				Workers:      a.config.Database.Parallelism,
-				Throttle:     a.config.Database.APIThrottle,
-				Completed:    false,
-			}
+			tracker = a.newRDAPTracker()
 		}
+		a.runRDAPJob(tracker, startFrom)
+	}
+
+	// The job itself, over the records from startFrom on that tracker has
+	// not processed yet
+	a.runRDAPJob = func(tracker *models.RDAPProgressTracker, startFrom int) {
+		ctx := a.runContext()
+		a.setBusy(true, "RDAP (tout) en cours...")
 
 		go func() {
+			defer a.crash.Recover("GUI")
 			defer func() {
 				a.setBusy(false, "")
 			}()
 
-			total := float64(len(a.data))
+			data := a.dataset()
+			total := float64(len(data))
 			workers := a.config.Database.Parallelism
 			if workers < 1 {
 				workers = 1
 			}
+			batch := a.config.Database.BatchSize
+			if batch < 1 {
+				batch = 10
+			}
 
 			// Create tasks only for unprocessed items
-			tasks := make(chan int, len(a.data))
-			for i := startFrom; i < len(a.data); i++ {
-				ip := a.data[i].IPOrCIDR
+			tasks := make(chan int, len(data))
+			for i := startFrom; i < len(data); i++ {
+				ip := data[i].IPOrCIDR
 				if !a.extractor.IsIPProcessed(ip, tracker) {
 					tasks <- i
 				}
@@ -361,29 +237,37 @@ func (a *App) createDatabaseTab() fyne.CanvasObject {
 
 			for w := 0; w < workers; w++ {
 				go func() {
+					defer a.crash.Recover("GUI")
 					defer func() { done <- struct{}{} }()
 					for idx := range tasks {
-						if cancel {
+						if ctx.Err() != nil {
 							break
 						}
 						<-ticker.C
-						ip := a.data[idx].IPOrCIDR
-						_ = a.extractor.EnrichRecordWithDelay(&a.data[idx], 0)
+						ip := data[idx].IPOrCIDR
+						_ = a.enrichRecord(ctx, idx, 0)
+						if ctx.Err() != nil {
+							// Not processed: the resumed job enriches it again
+							break
+						}
 
 						// Update tracker
 						tracker.ProcessedRecords = idx + 1
 						tracker.ProcessedIPs = append(tracker.ProcessedIPs, ip)
 
-						// Save progress every 10 records
-						if len(tracker.ProcessedIPs)%10 == 0 {
+						// Save progress every batch of records
+						if len(tracker.ProcessedIPs)%batch == 0 {
 							_ = a.extractor.SaveProgressTracker(tracker)
 						}
 
-						progress.SetValue(float64(idx+1) / total)
-						progressDetail.SetText(fmt.Sprintf("RDAP %d/%d - %s (registry: %s)", idx+1, int(total), ip, a.data[idx].Registry))
-						if idx%50 == 0 && a.dataTable != nil {
-							a.dataTable.Refresh()
-						}
+						a.events.Publish(events.Event{
+							Type:    events.RecordsEnriched,
+							Source:  "GUI",
+							Message: fmt.Sprintf("%s (registry: %s)", ip, data[idx].Registry),
+							Count:   idx + 1,
+							Total:   int(total),
+						})
+						a.updateStats()
 					}
 				}()
 			}
@@ -391,6 +275,14 @@ func (a *App) createDatabaseTab() fyne.CanvasObject {
 			for w := 0; w < workers; w++ {
 				<-done
 			}
+			// Rows were redrawn one by one; resize the columns to the new values
+			a.records.Refresh()
+			if ctx.Err() != nil {
+				// Progress is kept so the next run offers to resume
+				_ = a.extractor.SaveProgressTracker(tracker)
+				a.showInformation("RDAP", fmt.Sprintf("⛔ Annulé après %d IPs, reprise possible au prochain lancement", len(tracker.ProcessedIPs)), a.mainWindow)
+				return
+			}
 
 			// Mark as completed and save final state
 			tracker.Completed = true
@@ -398,15 +290,24 @@ func (a *App) createDatabaseTab() fyne.CanvasObject {
 
 			ts := time.Now().Format("2006-01-02_15-04-05")
 			filename := fmt.Sprintf("full_enriched_%s.csv", ts)
-			if err := a.extractor.SaveToCSV(a.data, filename); err != nil {
+			if err := a.extractor.Export(data, filename); err != nil {
 				a.logger.Warning("GUI", "CSV save error: "+err.Error())
 				dialog.ShowError(err, a.mainWindow)
 			} else {
 				a.logger.Info("GUI", "✅ Full RDAP associated and saved: "+filename)
-				dialog.ShowInformation("RDAP", "✅ RDAP associé sur l'ensemble du dataset\nCSV: "+filename, a.mainWindow)
+				// Which registries answered, and what to change in Registries
+				report := extractor.BuildRegistryReport(data, a.config.Database).Lines()
+				for _, line := range report {
+					a.logger.Info("GUI", line)
+				}
+				a.showInformation("RDAP", "✅ RDAP associé sur l'ensemble du dataset\nCSV: "+filename+"\n\n"+strings.Join(report, "\n"), a.mainWindow)
 
 				// Clean up progress file on successful completion
 				_ = a.extractor.ClearProgressTracker()
+
+				if err := a.extractor.PushMetrics(a.runContext(), data); err != nil {
+					a.logger.Warning("GUI", "Metrics push failed: "+err.Error())
+				}
 			}
 		}()
 	}
@@ -415,50 +316,39 @@ func (a *App) createDatabaseTab() fyne.CanvasObject {
 		a.exportAllData()
 	})
 
+	exportXLSXBtn := widget.NewButton("📊 Export XLSX", func() {
+		a.exportXLSX()
+	})
+
 	exportSelectedBtn := widget.NewButton("📤 Export Selected", func() {
-		// Collect selected
-		var rows []models.ScannerData
-		for idx, sel := range a.selectedRows {
-			if sel && idx < len(a.data) {
-				rows = append(rows, a.data[idx])
-			}
-		}
+		rows := a.records.selectedRecords()
 		if len(rows) == 0 {
-			dialog.ShowInformation("Export", "No rows selected", a.mainWindow)
+			a.showInformation("Export", "No rows selected", a.mainWindow)
 			return
 		}
-		ts := time.Now().Format("2006-01-02_15-04-05")
-		filename := fmt.Sprintf("results/selected_export_%s.csv", ts)
-		if err := a.extractor.SaveToCSV(rows, filename); err != nil {
-			dialog.ShowError(err, a.mainWindow)
-			return
-		}
-		dialog.ShowInformation("Export", "✅ Exported "+fmt.Sprintf("%d", len(rows))+" rows to\n"+filename, a.mainWindow)
+		a.chooseExportFormat("📤 Export Selected", rows, func(template string, rows []models.ScannerData) {
+			if template != "" {
+				a.exportWithTemplate(rows, "selected_export", template)
+				return
+			}
+			ts := time.Now().Format("2006-01-02_15-04-05")
+			// Export writes into the configured results directory itself
+			filename := fmt.Sprintf("selected_export_%s.csv", ts)
+			if err := a.extractor.Export(rows, filename); err != nil {
+				dialog.ShowError(err, a.mainWindow)
+				return
+			}
+			a.showInformation("Export", "✅ Exported "+fmt.Sprintf("%d", len(rows))+" rows to\n"+filepath.Join(a.resultsDir(), filename), a.mainWindow)
+		})
 	})
 
 	geolocBtn := widget.NewButton("🌍 Geoloc", func() {
-		// Aggregate by continent
-		counts := map[string]int{}
-		max := len(a.data)
-		if max > 2000 {
-			max = 2000
-		} // limiter pour éviter trop d'appels
-		for i := 0; i < max; i++ {
-			ip := a.data[i].IPOrCIDR
-			if ip == "" {
-				continue
-			}
-			cont, _, _, _, err := a.extractor.GeoLookupContinent(ip)
-			if err != nil {
-				continue
-			}
-			counts[cont]++
-		}
-		// Build view
-		text := "Répartition par continent (échantillon):\n"
-		for k, v := range counts {
-			text += fmt.Sprintf("- %s: %d\n", k, v)
-		}
+		// Breakdown of the records by the geography they were enriched with
+		breakdown := widget.NewMultiLineEntry()
+		breakdown.Disable()
+		groupSelect := widget.NewSelect(GroupByOptions, func(field string) {
+			breakdown.SetText(GroupText(field, GroupRecords(a.dataset(), field)))
+		})
 		// Import block
 		entry := widget.NewMultiLineEntry()
 		entry.SetPlaceHolder("Collez vos IPs (une par ligne) ou importez un fichier...")
@@ -480,38 +370,37 @@ func (a *App) createDatabaseTab() fyne.CanvasObject {
 				if ip == "" {
 					continue
 				}
-				item := models.ScannerData{IPOrCIDR: ip, ScannerName: "User", ScannerType: models.ScannerTypeOther, LastSeen: now}
-				a.data = append(a.data, item)
+				a.addRecord(models.ScannerData{IPOrCIDR: ip, ScannerName: "User", ScannerType: models.ScannerTypeOther, LastSeen: now})
 			}
 			a.updatePagination()
-			if a.dataTable != nil {
-				a.dataTable.Refresh()
-				// Apply column/row layout after load
-				a.applyTableLayout()
-			}
-			dialog.ShowInformation("Geoloc", "IPs ajoutées", a.mainWindow)
+			a.updateStats()
+			a.showInformation("Geoloc", "IPs ajoutées", a.mainWindow)
 		})
 		content := container.NewVBox(
-			widget.NewLabel("Geolocalisation (par continent)"),
-			widget.NewMultiLineEntry(),
+			widget.NewLabel("Geolocalisation"),
+			container.NewBorder(nil, nil, widget.NewLabel("Grouper par"), nil, groupSelect),
+			breakdown,
 			container.NewHBox(importFileBtn, applyBtn),
 		)
-		ml := content.Objects[1].(*widget.Entry)
-		ml.MultiLine = true
-		ml.SetText(text)
-		ml.Disable()
+		groupSelect.SetSelected(GroupByOptions[0])
 		dialog.NewCustom("Geoloc", "Fermer", container.NewScroll(content), a.mainWindow).Show()
 	})
 
 	// Button layout
-	buttonsContainer := container.NewHBox(
-		updateBtn,
-		associateRDAPBtn,
-		associateRDAPAllBtn,
+	buttonsContainer := container.NewHBox(updateBtn, associateRDAPAllBtn)
+	buttonsContainer.Objects = append(buttonsContainer.Objects, a.records.actions()...)
+	buttonsContainer.Objects = append(buttonsContainer.Objects,
+		associatePeeringDBBtn,
+		greylistBtn,
+		publishBtn,
+		a.expiryBtn,
+		reportAbuseBtn,
+		duplicatesBtn,
+		compareBtn,
 		cancelBtn,
-		rdapDetailsBtn,
 		geolocBtn,
 		exportBtn,
+		exportXLSXBtn,
 		exportSelectedBtn,
 	)
 
@@ -521,83 +410,122 @@ func (a *App) createDatabaseTab() fyne.CanvasObject {
 		buttonsContainer,
 		paginationLabel,
 		paginationControls,
-		progress,
-		progressDetail,
-		hscroll,
+		a.progress,
+		a.progressDetail,
+		a.rateStatus,
+		a.records.view(700),
 	)
 
 	return container.NewScroll(databaseContainer)
 }
 
-// applyTableLayout sets column widths and row heights to avoid overlap
-func (a *App) applyTableLayout() {
-	if a.dataTable == nil {
+// showExpiryDialog lists the IPs about to be retired and offers to pin
+// them in their current state, to keep them
+func (a *App) showExpiryDialog() {
+	warnings := a.expiryWarnings
+	if len(warnings) == 0 {
+		a.showInformation("Expirations", "Aucune IP surveillée ou publiée n'est sur le point d'expirer", a.mainWindow)
 		return
 	}
-	// Headers matching columns
-	headers := []string{"IP/CIDR", "Scanner", "Type", "Country", "ISP", "Organization", "RDAP Name", "RDAP Handle", "ASN", "Reverse", "Risk", "Score", "Domain", "Last Seen"}
-	style := fyne.TextStyle{}
-	startIndex := (a.currentPage - 1) * a.itemsPerPage
-	endIndex := startIndex + a.itemsPerPage
-	if endIndex > len(a.data) {
-		endIndex = len(a.data)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d IPs surveillées ou publiées vont être retirées:\n\n", len(warnings))
+	for _, w := range warnings {
+		b.WriteString(w.String() + "\n")
 	}
-	// Compute max width per column on visible page (with padding)
-	for col := 0; col < 14; col++ {
-		maxw := fyne.MeasureText(headers[col], theme.TextSize(), style).Width
-		for i := startIndex; i < endIndex; i++ {
-			item := a.data[i]
-			var txt string
-			switch col {
-			case 0:
-				txt = item.IPOrCIDR
-			case 1:
-				txt = item.ScannerName
-			case 2:
-				txt = string(item.ScannerType)
-			case 3:
-				txt = item.CountryCode
-			case 4:
-				txt = item.ISP
-			case 5:
-				txt = item.Organization
-			case 6:
-				txt = item.RDAPName
-			case 7:
-				txt = item.RDAPHandle
-			case 8:
-				txt = item.ASN
-			case 9:
-				txt = item.ReverseDNS
-			case 10:
-				txt = item.RiskLevel
-			case 11:
-				txt = fmt.Sprintf("%d", item.AbuseConfidenceScore)
-			case 12:
-				txt = item.Domain
-			case 13:
-				txt = item.LastSeen.Format("2006-01-02")
+	b.WriteString("\nSi ce retrait est voulu, fermez. Sinon, épinglez ces IPs pour les conserver.")
+	review := widget.NewMultiLineEntry()
+	review.SetText(b.String())
+	review.Disable()
+	scroll := container.NewScroll(review)
+	scroll.SetMinSize(fyne.NewSize(500, 300))
+
+	dialog.ShowCustomConfirm(a.text("⏳ Expirations"), "Épingler", "Fermer", scroll, func(keep bool) {
+		if !keep {
+			return
+		}
+		entries, err := a.extractor.LifecycleEntries()
+		if err != nil {
+			dialog.ShowError(err, a.mainWindow)
+			return
+		}
+		for _, w := range warnings {
+			state := entries[w.IP].State
+			if state == "" {
+				state = models.StateObserved
+				if w.Enforced {
+					state = models.StateBlocked
+				}
 			}
-			w := fyne.MeasureText(txt, theme.TextSize(), style).Width
-			if w > maxw {
-				maxw = w
+			if err := a.extractor.SetLifecycleState(w.IP, state, true); err != nil {
+				dialog.ShowError(err, a.mainWindow)
+				return
 			}
 		}
-		// Apply width with padding to avoid clipping/overlap
-		a.dataTable.SetColumnWidth(col, maxw+28)
-		if col < len(a.headerLabels) {
-			a.headerLabels[col].Resize(fyne.NewSize(maxw+28, a.headerLabels[col].MinSize().Height))
-		}
-		if col < len(a.headerLabels) {
-			a.headerLabels[col].Resize(fyne.NewSize(maxw+28, a.headerLabels[col].MinSize().Height))
+		a.showInformation("Expirations", fmt.Sprintf("✅ %d IPs épinglées", len(warnings)), a.mainWindow)
+		a.checkExpiry()
+	}, a.mainWindow)
+}
+
+// showApprovalDialog presents the enforcement delta for review and, once an
+// approver confirms, records the approval and exports the blocked records.
+func (a *App) showApprovalDialog(delta extractor.EnforcementDelta) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d IPs à publier (+%d / -%d)\n\n", delta.Total, len(delta.Added), len(delta.Removed))
+	for _, ip := range delta.Added {
+		b.WriteString("+ " + ip + "\n")
+	}
+	for _, ip := range delta.Removed {
+		b.WriteString("- " + ip + "\n")
+	}
+	for _, c := range delta.OwnershipChanged {
+		fmt.Fprintf(&b, "~ %s: propriétaire changé %s -> %s\n", c.IP, c.Previous, c.Current)
+	}
+	for _, m := range delta.Collateral {
+		mark := "!"
+		if m.Critical {
+			mark = "!!"
 		}
+		fmt.Fprintf(&b, "%s %s chevauche le préfixe protégé %s (%s) %s\n", mark, m.IP, m.Prefix, m.Kind, m.Label)
+	}
+	for _, v := range delta.PolicyHeld {
+		fmt.Fprintf(&b, "x %s retenue par la politique: %s\n", v.IP, v.Reason)
 	}
-	// Ensure visible rows have enough height
-	visible := endIndex - startIndex
-	if visible < 0 {
-		visible = 0
+	if critical := delta.CriticalCollateral(); len(critical) > 0 {
+		fmt.Fprintf(&b, "\nPublication refusée: %d entrées bloqueraient des préfixes critiques\n", len(critical))
 	}
-	for r := 0; r < visible; r++ {
-		a.dataTable.SetRowHeight(r, 30)
+	if delta.Empty() {
+		b.WriteString("Aucun changement depuis la dernière publication\n")
 	}
+	review := widget.NewMultiLineEntry()
+	review.SetText(b.String())
+	review.Disable()
+	approverEntry := widget.NewEntry()
+	approverEntry.SetText(os.Getenv("USER"))
+	approverEntry.SetPlaceHolder("Approbateur")
+	scroll := container.NewScroll(review)
+	scroll.SetMinSize(fyne.NewSize(500, 300))
+	content := container.NewBorder(nil, container.NewVBox(widget.NewLabel(a.text("👤 Approuvé par:")), approverEntry), nil, nil, scroll)
+
+	dialog.ShowCustomConfirm(a.text("✅ Publication du blocage"), "Approuver", "Annuler", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		approver := strings.TrimSpace(approverEntry.Text)
+		if _, err := a.extractor.ApproveEnforcement(a.dataset(), approver); err != nil {
+			dialog.ShowError(err, a.mainWindow)
+			return
+		}
+		ts := time.Now().Format("2006-01-02_15-04-05")
+		filename := fmt.Sprintf("enforcement_%s.csv", ts)
+		enforceable := extractor.Enforceable(a.dataset())
+		if err := a.extractor.Export(enforceable, filename); err != nil {
+			dialog.ShowError(err, a.mainWindow)
+			return
+		}
+		if err := a.extractor.SaveRunMetadata(filename, enforceable); err != nil {
+			a.logger.Warning("GUI", "Run metadata not written: "+err.Error())
+		}
+		a.logger.Info("GUI", fmt.Sprintf("Enforcement export approved by %s: %s", approver, filename))
+		a.showInformation("Publication", fmt.Sprintf("✅ %d IPs publiées\nCSV: %s", delta.Total, filename), a.mainWindow)
+	}, a.mainWindow)
 }
@@ -0,0 +1,104 @@
+// Package gui provides the graphical user interface for LiaCheckScanner.
+// This file builds the main tabs lazily: a tab's widgets are created the
+// first time it is selected, and the dataset is loaded the first time the
+// Database tab is shown or an extraction is requested.
+package gui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Titles of the main tabs, also used to find them in a.tabs
+const (
+	tabDashboard = "📊 Dashboard"
+	tabDatabase  = "🗄️ Database"
+	tabSearch    = "🔍 Search"
+	tabRuns      = "🔀 Runs"
+	tabConfig    = "⚙️ Configuration"
+	tabLogs      = "📋 Logs"
+)
+
+// newLazyTab returns a tab showing an empty placeholder until buildTab
+// replaces it with the content returned by build.
+func (a *App) newLazyTab(title string, build func() fyne.CanvasObject) *container.TabItem {
+	item := container.NewTabItem(title, widget.NewLabel(""))
+	a.tabBuilders[item] = build
+	return item
+}
+
+// buildTab builds the content of item if it has not been built yet. It
+// can be called from any goroutine.
+func (a *App) buildTab(item *container.TabItem) {
+	a.tabsMu.Lock()
+	build, ok := a.tabBuilders[item]
+	delete(a.tabBuilders, item)
+	a.tabsMu.Unlock()
+	if !ok {
+		return
+	}
+	content := build()
+	a.applyPlainLabels(content)
+	item.Content = content
+	if a.tabs != nil {
+		a.tabs.Refresh()
+	}
+}
+
+// tabItem returns the main tab titled title, nil if there is none.
+func (a *App) tabItem(title string) *container.TabItem {
+	if a.tabs == nil {
+		return nil
+	}
+	for _, item := range a.tabs.Items {
+		if item.Text == title || item.Text == a.text(title) {
+			return item
+		}
+	}
+	return nil
+}
+
+// ensureTab builds the main tab titled title, so that the widgets and
+// callbacks it sets up exist before they are used.
+func (a *App) ensureTab(title string) {
+	if item := a.tabItem(title); item != nil {
+		a.buildTab(item)
+	}
+}
+
+// selectTab builds and shows the main tab titled title.
+func (a *App) selectTab(title string) {
+	if item := a.tabItem(title); item != nil {
+		a.buildTab(item)
+		a.tabs.Select(item)
+	}
+}
+
+// onTabSelected builds the selected tab and, for the Database tab, starts
+// the first load of the dataset.
+func (a *App) onTabSelected(item *container.TabItem) {
+	a.buildTab(item)
+	if item == a.tabItem(tabDatabase) {
+		go func() {
+			defer a.crash.Recover("GUI")
+			a.loadDataOnce()
+		}()
+	}
+}
+
+// loadDataOnce runs the first load of the session, then offers to resume
+// the jobs the previous session left unfinished. It reports whether this
+// call did the load; later calls return false at once.
+func (a *App) loadDataOnce() bool {
+	first := false
+	a.dataOnce.Do(func() {
+		first = true
+		// The RDAP job the load may queue is set up by the Database tab
+		a.ensureTab(tabDatabase)
+		a.logger.Info("GUI", "🔍 Initializing data...")
+		a.loadData() // This will try CSV first, then auto-extract if needed
+		a.offerJobResume()
+	})
+	return first
+}
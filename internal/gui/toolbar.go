@@ -4,7 +4,9 @@
 package gui
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -16,6 +18,9 @@ import (
 	"fyne.io/fyne/v2/widget"
 
 	"github.com/lia/liacheckscanner_go/internal/config"
+	"github.com/lia/liacheckscanner_go/internal/logger"
+	"github.com/lia/liacheckscanner_go/pkg/extractor"
+	"github.com/lia/liacheckscanner_go/pkg/models"
 )
 
 // createSearchTab creates the advanced search tab with professional features
@@ -46,15 +51,35 @@ func (a *App) createSearchTab() fyne.CanvasObject {
 	riskFilter := widget.NewSelect([]string{"All Risk Levels", "High", "Medium", "Low", "Unknown"}, nil)
 	riskFilter.SetSelected("All Risk Levels")
 
+	confidenceFilter := widget.NewSelect(ConfidenceLabels, nil)
+	confidenceFilter.SetSelected(ConfidenceLabels[0])
+
+	seenAttackingCheck := widget.NewCheck("🎯 Seen attacking me", nil)
+
+	dateFieldFilter := widget.NewSelect(DateFieldLabels, nil)
+	dateFieldFilter.SetSelected(DateFieldLabels[0])
+	dateWindowFilter := widget.NewSelect(DateWindowLabels, nil)
+	dateWindowFilter.SetSelected("90 days")
+
 	// Professional action buttons
 	searchBtn := widget.NewButton("🔍 Perform Search", func() {
-		a.performAdvancedSearch(searchEntry.Text, countryFilter.Selected, scannerFilter.Selected, riskFilter.Selected)
+		window := SearchTimeWindow(dateFieldFilter.Selected, dateWindowFilter.Selected)
+		a.performAdvancedSearch(searchEntry.Text, countryFilter.Selected, scannerFilter.Selected, riskFilter.Selected,
+			confidenceFilter.Selected, seenAttackingCheck.Checked, window)
+	})
+
+	hitsBtn := widget.NewButton("🍯 Import Hits", func() {
+		a.importHits()
 	})
 
 	enrichBtn := widget.NewButton("🌍 Enrich IP Data", func() {
 		a.enrichIPData(searchEntry.Text)
 	})
 
+	asnBtn := widget.NewButton("🧭 ASN Prefixes", func() {
+		a.expandASN(searchEntry.Text)
+	})
+
 	exportBtn := widget.NewButton("📤 Export Results", func() {
 		a.exportSearchResults()
 	})
@@ -64,6 +89,10 @@ func (a *App) createSearchTab() fyne.CanvasObject {
 		countryFilter.SetSelected("All Countries")
 		scannerFilter.SetSelected("All Scanners")
 		riskFilter.SetSelected("All Risk Levels")
+		confidenceFilter.SetSelected(ConfidenceLabels[0])
+		seenAttackingCheck.SetChecked(false)
+		dateFieldFilter.SetSelected(DateFieldLabels[0])
+		dateWindowFilter.SetSelected("90 days")
 		a.clearSearchResults()
 	})
 
@@ -72,12 +101,18 @@ func (a *App) createSearchTab() fyne.CanvasObject {
 		container.NewVBox(widget.NewLabel("Country:"), countryFilter),
 		container.NewVBox(widget.NewLabel("Scanner:"), scannerFilter),
 		container.NewVBox(widget.NewLabel("Risk Level:"), riskFilter),
+		container.NewVBox(widget.NewLabel("Attribution:"), confidenceFilter),
+		container.NewVBox(widget.NewLabel("Honeypots:"), seenAttackingCheck),
+		container.NewVBox(widget.NewLabel("Date:"), dateFieldFilter),
+		container.NewVBox(widget.NewLabel("Within the last:"), dateWindowFilter),
 	)
 
 	// Professional button layout
 	buttonsContainer := container.NewHBox(
 		searchBtn,
 		enrichBtn,
+		asnBtn,
+		hitsBtn,
 		exportBtn,
 		clearBtn,
 	)
@@ -86,49 +121,8 @@ func (a *App) createSearchTab() fyne.CanvasObject {
 	resultsLabel := widget.NewLabel("📊 Search Results")
 	resultsLabel.TextStyle = fyne.TextStyle{Bold: true}
 
-	// Professional results table
-	a.searchResultsTable = widget.NewTable(
-		func() (int, int) {
-			return len(a.searchResults), 8
-		},
-		func() fyne.CanvasObject {
-			return widget.NewLabel("")
-		},
-		func(i widget.TableCellID, o fyne.CanvasObject) {
-			label := o.(*widget.Label)
-			if i.Row < len(a.searchResults) {
-				item := a.searchResults[i.Row]
-				switch i.Col {
-				case 0:
-					label.SetText(item.IPOrCIDR)
-				case 1:
-					label.SetText(item.ScannerName)
-				case 2:
-					label.SetText(string(item.ScannerType))
-				case 3:
-					label.SetText(item.CountryCode)
-				case 4:
-					label.SetText(item.ISP)
-				case 5:
-					label.SetText(item.RiskLevel)
-				case 6:
-					label.SetText(fmt.Sprintf("%d", item.AbuseConfidenceScore))
-				case 7:
-					label.SetText(item.LastSeen.Format("2006-01-02"))
-				}
-			}
-		},
-	)
-
-	// Professional results headers
-	resultsHeaders := []string{"IP/CIDR", "Scanner", "Type", "Country", "ISP", "Risk", "Score", "Last Seen"}
-	resultsHeaderContainer := container.NewHBox()
-	for _, header := range resultsHeaders {
-		headerLabel := widget.NewLabel(header)
-		headerLabel.TextStyle = fyne.TextStyle{Bold: true}
-		headerLabel.Alignment = fyne.TextAlignCenter
-		resultsHeaderContainer.Add(headerLabel)
-	}
+	// Results share the Database table: pagination, selection, details, RDAP
+	resultsActions := container.NewHBox(a.searchTable.actions()...)
 
 	// Professional enrichment section
 	enrichmentLabel := widget.NewLabel("🌍 Real-time IP Enrichment")
@@ -152,8 +146,9 @@ func (a *App) createSearchTab() fyne.CanvasObject {
 		filtersContainer,
 		buttonsContainer,
 		resultsLabel,
-		resultsHeaderContainer,
-		container.NewScroll(a.searchResultsTable),
+		a.searchTable.paginationControls(),
+		resultsActions,
+		a.searchTable.view(400),
 		enrichmentLabel,
 		a.enrichmentText,
 		a.searchStatsLabel,
@@ -178,10 +173,14 @@ func (a *App) createConfigTab() fyne.CanvasObject {
 	resultsEntry := widget.NewEntry()
 	resultsEntry.SetText(a.config.Database.ResultsDir)
 	resultsEntry.SetPlaceHolder("Results directory...")
+	resultsRow := a.dirField(resultsEntry)
 
 	logsEntry := widget.NewEntry()
 	logsEntry.SetText(a.config.Database.LogsDir)
 	logsEntry.SetPlaceHolder("Logs directory...")
+	logsRow := a.dirField(logsEntry)
+	// Applied as soon as it is chosen, like the one of the Logs tab
+	logLevelSelect := a.logLevelSelect()
 
 	// Repository configuration
 	repoTitle := widget.NewLabel("📥 Repository Settings")
@@ -191,9 +190,21 @@ func (a *App) createConfigTab() fyne.CanvasObject {
 	repoURLEntry.SetText(a.config.Database.RepoURL)
 	repoURLEntry.SetPlaceHolder("Repository URL...")
 
+	// Thin client: pull the dataset from another instance's API
+	remoteURLEntry := widget.NewEntry()
+	remoteURLEntry.SetPlaceHolder("Extract locally (e.g. https://scanner.example:8088)")
+	remoteURLEntry.SetText(a.config.Database.RemoteAPIURL)
+	remoteKeyEntry := widget.NewPasswordEntry()
+	remoteKeyEntry.SetPlaceHolder("Remote API key")
+	remoteKeyEntry.SetText(a.config.Database.RemoteAPIKey)
+
 	localPathEntry := widget.NewEntry()
 	localPathEntry.SetText(a.config.Database.LocalPath)
 	localPathEntry.SetPlaceHolder("Local repository path...")
+	localPathRow := a.dirField(localPathEntry)
+	// Fetch the last commit only; ignored for a pinned ref
+	shallowCheck := widget.NewCheck("Shallow clone (last commit only)", nil)
+	shallowCheck.SetChecked(a.config.Database.ShallowClone)
 
 	// Throttling configuration
 	throttleTitle := widget.NewLabel("⏱️ RDAP/Geo Throttle (ms)")
@@ -202,6 +213,40 @@ func (a *App) createConfigTab() fyne.CanvasObject {
 	throttleEntry.SetPlaceHolder("e.g. 500")
 	throttleEntry.SetText(fmt.Sprintf("%d", int(a.config.Database.APIThrottle*1000)))
 
+	// ip-api.com pro key (switches geolocation to the HTTPS endpoint)
+	ipAPIKeyEntry := widget.NewPasswordEntry()
+	ipAPIKeyEntry.SetPlaceHolder("Optional ip-api.com pro key (HTTPS)")
+	ipAPIKeyEntry.SetText(a.config.Database.IPAPIKey)
+
+	// Geolocation provider and its credentials
+	geoTitle := widget.NewLabel("🌍 Geolocation Provider")
+	geoTitle.TextStyle = fyne.TextStyle{Bold: true}
+	geoSelect := widget.NewSelect([]string{"ip-api", "ipinfo", "ipdata"}, func(string) {})
+	if a.config.Database.GeoProvider == "" {
+		geoSelect.SetSelected("ip-api")
+	} else {
+		geoSelect.SetSelected(a.config.Database.GeoProvider)
+	}
+	ipInfoTokenEntry := widget.NewPasswordEntry()
+	ipInfoTokenEntry.SetPlaceHolder("ipinfo.io token")
+	ipInfoTokenEntry.SetText(a.config.Database.IPInfoToken)
+	ipDataKeyEntry := widget.NewPasswordEntry()
+	ipDataKeyEntry.SetPlaceHolder("ipdata.co API key")
+	ipDataKeyEntry.SetText(a.config.Database.IPDataKey)
+
+	// Operator contact sent in the User-Agent of every lookup
+	contactEntry := widget.NewEntry()
+	contactEntry.SetPlaceHolder("noc@example.org or https://example.org/contact")
+	contactEntry.SetText(a.config.Database.Contact)
+
+	// Resolver for reverse lookups and downloads in restricted networks
+	dnsServersEntry := widget.NewEntry()
+	dnsServersEntry.SetPlaceHolder("System resolver (e.g. 10.0.0.53, 9.9.9.9:53)")
+	dnsServersEntry.SetText(strings.Join(a.config.Database.DNSServers, ", "))
+	dohEntry := widget.NewEntry()
+	dohEntry.SetPlaceHolder("https://dns.example/dns-query")
+	dohEntry.SetText(a.config.Database.DoHURL)
+
 	// Parallelism configuration
 	parTitle := widget.NewLabel("🧵 Parallelism (workers)")
 	parTitle.TextStyle = fyne.TextStyle{Bold: true}
@@ -212,6 +257,29 @@ func (a *App) createConfigTab() fyne.CanvasObject {
 	}
 	parEntry.SetText(fmt.Sprintf("%d", a.config.Database.Parallelism))
 
+	// Performance preset: fills throttle and parallelism, retries and batch
+	// size are applied on save
+	presetTitle := widget.NewLabel("🚀 Performance Preset")
+	presetTitle.TextStyle = fyne.TextStyle{Bold: true}
+	var presetLabels []string
+	for _, p := range config.Presets {
+		presetLabels = append(presetLabels, p.Label)
+	}
+	selectedPreset := a.config.Database.Preset
+	presetSelect := widget.NewSelect(presetLabels, func(label string) {
+		for _, p := range config.Presets {
+			if p.Label == label {
+				selectedPreset = p.Name
+				throttleEntry.SetText(fmt.Sprintf("%d", int(p.APIThrottle*1000)))
+				parEntry.SetText(fmt.Sprintf("%d", p.Parallelism))
+			}
+		}
+	})
+	presetSelect.PlaceHolder = "Custom"
+	if p, ok := config.PresetByName(a.config.Database.Preset); ok {
+		presetSelect.SetSelected(p.Label)
+	}
+
 	// RDAP Registries selection
 	rTitle := widget.NewLabel("🌐 RDAP Registries")
 	rTitle.TextStyle = fyne.TextStyle{Bold: true}
@@ -233,17 +301,107 @@ func (a *App) createConfigTab() fyne.CanvasObject {
 		regChecks = append(regChecks, chk)
 	}
 
+	// Protected prefixes checked before publishing the blocklist
+	protectedEntry := widget.NewEntry()
+	protectedEntry.SetPlaceHolder("Own, partner and known-good prefixes (one per line)")
+	protectedEntry.SetText(a.config.Database.ProtectedPrefixesFile)
+	criticalKinds := widget.NewCheckGroup([]string{"own", "partner", "service"}, nil)
+	criticalKinds.Horizontal = true
+	criticalKinds.SetSelected(a.config.Database.CollateralCriticalKinds)
+
+	// AbuseIPDB reporting of blocked IPs (opt-in)
+	abuseTitle := widget.NewLabel("🚨 AbuseIPDB Reporting")
+	abuseTitle.TextStyle = fyne.TextStyle{Bold: true}
+	abuseCheck := widget.NewCheck("Report blocked IPs to AbuseIPDB", nil)
+	abuseCheck.SetChecked(a.config.Database.AbuseIPDBReport)
+	abuseKeyEntry := widget.NewPasswordEntry()
+	abuseKeyEntry.SetPlaceHolder("AbuseIPDB API key")
+	abuseKeyEntry.SetText(a.config.Database.AbuseIPDBKey)
+	abuseQuotaEntry := widget.NewEntry()
+	abuseQuotaEntry.SetPlaceHolder("Reports per day (default 1000)")
+	if a.config.Database.AbuseIPDBDailyQuota > 0 {
+		abuseQuotaEntry.SetText(fmt.Sprintf("%d", a.config.Database.AbuseIPDBDailyQuota))
+	}
+
+	// Per-run enrichment budgets, empty for none
+	optionalIntEntry := func(placeholder string, value int) *widget.Entry {
+		entry := widget.NewEntry()
+		entry.SetPlaceHolder(placeholder)
+		if value > 0 {
+			entry.SetText(fmt.Sprintf("%d", value))
+		}
+		return entry
+	}
+	rdapBudgetEntry := optionalIntEntry("Max RDAP calls", a.config.Database.MaxRDAPCalls)
+	geoBudgetEntry := optionalIntEntry("Max geolocation calls", a.config.Database.MaxGeoCalls)
+	minutesBudgetEntry := optionalIntEntry("Max minutes", a.config.Database.MaxRunMinutes)
+	backfillEntry := optionalIntEntry("Records per hour (empty for off)", a.config.Database.GeoBackfillPerHour)
+
+	// Ranges too broad to enforce: flagged or excluded from enforcement exports
+	broadPolicySelect := widget.NewSelect([]string{"flag", "exclude", "off"}, nil)
+	broadPolicySelect.SetSelected("flag")
+	if a.config.Database.BroadPrefixPolicy != "" {
+		broadPolicySelect.SetSelected(a.config.Database.BroadPrefixPolicy)
+	}
+	broadV4Entry := optionalIntEntry("IPv4 threshold (default 16)", a.config.Database.BroadPrefixV4)
+	broadV6Entry := optionalIntEntry("IPv6 threshold (default 32)", a.config.Database.BroadPrefixV6)
+
+	// Extraction-only runs skip RDAP and geolocation
+	skipEnrichCheck := widget.NewCheck("⚡ Extraction only (skip RDAP and geolocation)", nil)
+	skipEnrichCheck.SetChecked(a.config.Database.SkipEnrichment)
+
+	// Sources and dates of the enriched fields in CSV exports
+	provenanceCheck := widget.NewCheck("🧾 Export provenance (source and date of each enriched field group)", nil)
+	provenanceCheck.SetChecked(a.config.Database.ExportProvenance)
+
+	// Startup check for a newer release
+	updateCheck := widget.NewCheck("🆕 Check for new releases at startup", nil)
+	updateCheck.SetChecked(!a.config.DisableUpdateCheck)
+
+	// Accessibility: text size and labels without emoji
+	a11yTitle := widget.NewLabel("♿ Accessibility")
+	a11yTitle.TextStyle = fyne.TextStyle{Bold: true}
+	textScaleSelect := widget.NewSelect(TextScaleLabels, nil)
+	textScaleSelect.SetSelected(TextScaleLabel(a.config.TextScale))
+	plainCheck := widget.NewCheck("Plain labels (no emoji in the interface and logs)", nil)
+	plainCheck.SetChecked(a.config.PlainLabels)
+
 	// Save button update for registries
 	saveBtn := widget.NewButton("💾 Save Configuration", func() {
 		// Update configuration
 		a.config.Database.RepoURL = repoURLEntry.Text
-		a.config.Database.LocalPath = localPathEntry.Text
+		a.config.Database.RemoteAPIURL = strings.TrimSpace(remoteURLEntry.Text)
+		a.config.Database.RemoteAPIKey = strings.TrimSpace(remoteKeyEntry.Text)
+		a.config.Database.LocalPath = strings.TrimSpace(localPathEntry.Text)
+		a.config.Database.ShallowClone = shallowCheck.Checked
+		a.config.Database.ResultsDir = strings.TrimSpace(resultsEntry.Text)
+		if a.config.Database.ResultsDir == "" {
+			a.config.Database.ResultsDir = "./results"
+		}
+		a.config.Database.LogsDir = strings.TrimSpace(logsEntry.Text)
+		if a.config.Database.LogsDir == "" {
+			a.config.Database.LogsDir = "./logs"
+		}
 		if ms, err := strconv.Atoi(strings.TrimSpace(throttleEntry.Text)); err == nil && ms >= 0 {
 			a.config.Database.APIThrottle = float64(ms) / 1000.0
 		}
 		if p, err := strconv.Atoi(strings.TrimSpace(parEntry.Text)); err == nil && p > 0 {
 			a.config.Database.Parallelism = p
 		}
+		a.config.Database.IPAPIKey = strings.TrimSpace(ipAPIKeyEntry.Text)
+		a.config.Database.GeoProvider = geoSelect.Selected
+		a.config.Database.IPInfoToken = strings.TrimSpace(ipInfoTokenEntry.Text)
+		a.config.Database.IPDataKey = strings.TrimSpace(ipDataKeyEntry.Text)
+		a.config.Database.Contact = strings.TrimSpace(contactEntry.Text)
+		a.config.Database.DNSServers = nil
+		for _, s := range strings.Split(dnsServersEntry.Text, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				a.config.Database.DNSServers = append(a.config.Database.DNSServers, s)
+			}
+		}
+		a.config.Database.DoHURL = strings.TrimSpace(dohEntry.Text)
+		a.config.Database.ProtectedPrefixesFile = strings.TrimSpace(protectedEntry.Text)
+		a.config.Database.CollateralCriticalKinds = append([]string(nil), criticalKinds.Selected...)
 		// registries
 		var regs []string
 		for i, r := range allRegs {
@@ -255,14 +413,66 @@ func (a *App) createConfigTab() fyne.CanvasObject {
 			regs = allRegs
 		}
 		a.config.Database.Registries = regs
+		a.config.Database.SkipEnrichment = skipEnrichCheck.Checked
+		a.config.Database.ExportProvenance = provenanceCheck.Checked
+		a.config.DisableUpdateCheck = !updateCheck.Checked
+		a.config.TextScale = textScaleValues[textScaleSelect.Selected]
+		wasPlain := a.config.PlainLabels
+		a.config.PlainLabels = plainCheck.Checked
+		a.config.Database.AbuseIPDBReport = abuseCheck.Checked
+		a.config.Database.AbuseIPDBKey = strings.TrimSpace(abuseKeyEntry.Text)
+		optionalInt := func(entry *widget.Entry) int {
+			if n, err := strconv.Atoi(strings.TrimSpace(entry.Text)); err == nil && n > 0 {
+				return n
+			}
+			return 0
+		}
+		a.config.Database.MaxRDAPCalls = optionalInt(rdapBudgetEntry)
+		a.config.Database.MaxGeoCalls = optionalInt(geoBudgetEntry)
+		a.config.Database.MaxRunMinutes = optionalInt(minutesBudgetEntry)
+		a.config.Database.GeoBackfillPerHour = optionalInt(backfillEntry)
+		a.config.Database.BroadPrefixPolicy = broadPolicySelect.Selected
+		a.config.Database.BroadPrefixV4 = optionalInt(broadV4Entry)
+		a.config.Database.BroadPrefixV6 = optionalInt(broadV6Entry)
+		a.config.Database.AbuseIPDBDailyQuota = 0
+		if q, err := strconv.Atoi(strings.TrimSpace(abuseQuotaEntry.Text)); err == nil && q > 0 {
+			a.config.Database.AbuseIPDBDailyQuota = q
+		}
+		// preset: keep it only if throttle and parallelism were not edited afterwards
+		if p, ok := config.PresetByName(selectedPreset); ok &&
+			p.Parallelism == a.config.Database.Parallelism && p.APIThrottle == a.config.Database.APIThrottle {
+			_ = config.ApplyPreset(&a.config.Database, p.Name)
+		} else {
+			a.config.Database.Preset = ""
+		}
+		if err := config.Validate(a.config); err != nil {
+			a.showConfigProblems(err)
+			return
+		}
 		// Save
 		cm := config.NewConfigManager()
 		_, _ = cm.Load()
 		if err := cm.Save(a.config); err != nil {
 			dialog.ShowError(err, a.mainWindow)
-		} else {
-			dialog.ShowInformation("Success", "Configuration saved successfully", a.mainWindow)
+			return
+		}
+		// Apply the new settings now instead of on the next start
+		a.extractor.ApplyConfig(a.config.Database)
+		a.applyTextScale()
+		a.logger.SetPlainLabels(a.config.PlainLabels)
+		a.applyPlainLabels(a.mainWindow.Content())
+		if wasPlain && !a.config.PlainLabels {
+			a.logger.Info("GUI", "Plain labels disabled: restart to show the emoji again")
 		}
+		if err := a.logger.SetLogsDir(a.config.Database.LogsDir); err != nil {
+			dialog.ShowError(err, a.mainWindow)
+			return
+		}
+		a.showInformation("Success", "Configuration saved successfully", a.mainWindow)
+	})
+
+	selfTestBtn := widget.NewButton("🩺 Run self-test", func() {
+		a.runSelfTest()
 	})
 
 	resetBtn := widget.NewButton("🔄 Reset to Defaults", func() {
@@ -271,6 +481,8 @@ func (a *App) createConfigTab() fyne.CanvasObject {
 				// Reset to defaults
 				repoURLEntry.SetText("https://github.com/MDMCK10/internet-scanners")
 				localPathEntry.SetText("./internet-scanners")
+				resultsEntry.SetText("./results")
+				logsEntry.SetText("./logs")
 			}
 		}, a.mainWindow)
 	})
@@ -281,11 +493,15 @@ func (a *App) createConfigTab() fyne.CanvasObject {
 		dbTitle,
 		container.NewVBox(
 			widget.NewLabel("Results Directory:"),
-			resultsEntry,
+			resultsRow,
 		),
 		container.NewVBox(
 			widget.NewLabel("Logs Directory:"),
-			logsEntry,
+			logsRow,
+		),
+		container.NewVBox(
+			widget.NewLabel("Log level:"),
+			logLevelSelect,
 		),
 		repoTitle,
 		container.NewVBox(
@@ -294,16 +510,63 @@ func (a *App) createConfigTab() fyne.CanvasObject {
 		),
 		container.NewVBox(
 			widget.NewLabel("Local Path:"),
-			localPathEntry,
+			localPathRow,
+		),
+		shallowCheck,
+		container.NewVBox(
+			widget.NewLabel("Remote LiaCheckScanner API (instead of extracting):"),
+			remoteURLEntry,
+			remoteKeyEntry,
+		),
+		container.NewVBox(
+			presetTitle,
+			presetSelect,
 		),
 		container.NewVBox(
 			throttleTitle,
 			throttleEntry,
 		),
+		geoTitle,
+		geoSelect,
+		container.NewVBox(
+			widget.NewLabel("ip-api.com Pro Key:"),
+			ipAPIKeyEntry,
+		),
+		container.NewVBox(
+			widget.NewLabel("ipinfo.io Token:"),
+			ipInfoTokenEntry,
+		),
+		container.NewVBox(
+			widget.NewLabel("ipdata.co API Key:"),
+			ipDataKeyEntry,
+		),
+		container.NewVBox(
+			widget.NewLabel("Operator contact (User-Agent):"),
+			contactEntry,
+		),
+		container.NewVBox(
+			widget.NewLabel("DNS Servers (comma separated):"),
+			dnsServersEntry,
+		),
+		container.NewVBox(
+			widget.NewLabel("DNS-over-HTTPS URL (overrides DNS servers):"),
+			dohEntry,
+		),
 		container.NewVBox(
 			parTitle,
 			parEntry,
 		),
+		container.NewVBox(
+			widget.NewLabel("Per-run budgets (empty for no limit):"),
+			container.NewGridWithColumns(3, rdapBudgetEntry, geoBudgetEntry, minutesBudgetEntry),
+		),
+		container.NewVBox(
+			widget.NewLabel("🌍 Background geolocation backfill of old records:"),
+			backfillEntry,
+		),
+		widget.NewButton("🏷️ Auto-tagging rules...", func() { a.showTagRulesDialog() }),
+		skipEnrichCheck,
+		provenanceCheck,
 		rTitle,
 		container.NewGridWithColumns(3, func() []fyne.CanvasObject {
 			items := []fyne.CanvasObject{}
@@ -312,15 +575,85 @@ func (a *App) createConfigTab() fyne.CanvasObject {
 			}
 			return items
 		}()...),
+		container.NewVBox(
+			widget.NewLabel("Protected prefixes file (checked before publishing):"),
+			protectedEntry,
+			widget.NewLabel("Refuse publishing when these kinds match:"),
+			criticalKinds,
+		),
+		container.NewVBox(
+			widget.NewLabel("Prefixes broader than (bits) in enforcement exports:"),
+			container.NewGridWithColumns(3, broadPolicySelect, broadV4Entry, broadV6Entry),
+		),
+		abuseTitle,
+		abuseCheck,
+		container.NewVBox(
+			widget.NewLabel("AbuseIPDB API Key:"),
+			abuseKeyEntry,
+		),
+		container.NewVBox(
+			widget.NewLabel("Daily report quota:"),
+			abuseQuotaEntry,
+		),
+		updateCheck,
+		a11yTitle,
+		container.NewVBox(
+			widget.NewLabel("Text size:"),
+			textScaleSelect,
+		),
+		plainCheck,
 		container.NewHBox(
 			saveBtn,
 			resetBtn,
+			selfTestBtn,
 		),
 	)
 
 	return container.NewScroll(configContainer)
 }
 
+// dirField wraps a directory entry with a folder picker button and a status
+// label that reports whether the path exists and is writable
+func (a *App) dirField(entry *widget.Entry) fyne.CanvasObject {
+	status := widget.NewLabel("")
+	check := func(path string) {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			status.SetText(a.text("ℹ️ Default directory"))
+			return
+		}
+		if err := config.CheckWritableDir(path); err != nil {
+			status.SetText(a.text("❌ " + err.Error()))
+			return
+		}
+		if _, err := os.Stat(path); err != nil {
+			status.SetText(a.text("🆕 Will be created"))
+			return
+		}
+		status.SetText(a.text("✅ Writable"))
+	}
+	entry.OnChanged = check
+	check(entry.Text)
+
+	browseBtn := widget.NewButton("📁", func() {
+		dialog.ShowFolderOpen(func(uri fyne.ListableURI, err error) {
+			if err != nil {
+				dialog.ShowError(err, a.mainWindow)
+				return
+			}
+			if uri == nil {
+				return
+			}
+			entry.SetText(uri.Path())
+		}, a.mainWindow)
+	})
+
+	return container.NewVBox(
+		container.NewBorder(nil, nil, nil, browseBtn, entry),
+		status,
+	)
+}
+
 // createLogsTab creates the logs tab with professional log viewing
 // Returns a CanvasObject containing the logs interface
 func (a *App) createLogsTab() fyne.CanvasObject {
@@ -329,20 +662,34 @@ func (a *App) createLogsTab() fyne.CanvasObject {
 	title.TextStyle = fyne.TextStyle{Bold: true}
 	title.Alignment = fyne.TextAlignCenter
 
+	// Professional log display
+	logDisplay := widget.NewMultiLineEntry()
+	logDisplay.SetPlaceHolder("System logs will appear here...")
+	logDisplay.Disable()
+	a.logDisplay = logDisplay
+
 	// Log level filter
 	levelLabel := widget.NewLabel("🔍 Log Level Filter")
 	levelLabel.TextStyle = fyne.TextStyle{Bold: true}
 
-	levelFilter := widget.NewSelect([]string{"All", "INFO", "WARNING", "ERROR"}, func(level string) {
+	levelFilter := widget.NewSelect(LogLevels, func(level string) {
 		// Filter logs by level
 		a.filterLogs(level)
 	})
 	levelFilter.SetSelected("All")
 
-	// Professional log display
-	logDisplay := widget.NewMultiLineEntry()
-	logDisplay.SetPlaceHolder("System logs will appear here...")
-	logDisplay.Disable()
+	// Module filter
+	moduleLabel := widget.NewLabel("🧩 Module Filter")
+	moduleLabel.TextStyle = fyne.TextStyle{Bold: true}
+	moduleFilter := widget.NewSelect(LogModules, func(module string) {
+		a.filterLogsByModule(module)
+	})
+	moduleFilter.SetSelected("All")
+
+	// Recording level: what the logger keeps at all, applied immediately
+	recordLabel := widget.NewLabel("🎚️ Niveau d'enregistrement")
+	recordLabel.TextStyle = fyne.TextStyle{Bold: true}
+	recordSelect := a.logLevelSelect()
 
 	// Professional action buttons
 	refreshBtn := widget.NewButton("🔄 Refresh Logs", func() {
@@ -356,64 +703,127 @@ func (a *App) createLogsTab() fyne.CanvasObject {
 	exportZipBtn := widget.NewButton("📦 Export Logs (ZIP)", func() {
 		ts := time.Now().Format("20060102_150405")
 		zipPath := filepath.Join("build", fmt.Sprintf("logs_%s.zip", ts))
-		if err := a.zipDirectory("logs", zipPath); err != nil {
+		if err := a.zipDirectory(a.logsDir(), zipPath); err != nil {
+			dialog.ShowError(err, a.mainWindow)
+			return
+		}
+		a.showInformation("Logs", "Exported to "+zipPath, a.mainWindow)
+	})
+
+	bundleBtn := widget.NewButton("🧰 Create diagnostics bundle", func() {
+		ts := time.Now().Format("20060102_150405")
+		bundlePath := filepath.Join("build", fmt.Sprintf("diagnostics_%s.zip", ts))
+		err := a.crash.CreateBundle(bundlePath, map[string]interface{}{
+			"records":     a.recordCount(),
+			"log_level":   string(a.logger.GetLogLevel()),
+			"api_enabled": a.server != nil,
+		})
+		if err != nil {
 			dialog.ShowError(err, a.mainWindow)
 			return
 		}
-		dialog.ShowInformation("Logs", "Exported to "+zipPath, a.mainWindow)
+		a.logger.Info("GUI", "Diagnostics bundle created: "+bundlePath)
+		a.showInformation("Diagnostics", "✅ Bundle created (secrets removed):\n"+bundlePath, a.mainWindow)
 	})
 
 	clearBtn := widget.NewButton("🗑️ Clear Display", func() {
 		logDisplay.SetText("")
 	})
 
-	// Professional layout
-	logsContainer := container.NewVBox(
-		title,
-		levelLabel,
-		levelFilter,
+	// Live tail: redraw the entries whenever new ones are logged
+	logScroll := container.NewScroll(logDisplay)
+	var stop chan struct{}
+	liveCheck := widget.NewCheck("▶️ Live", func(on bool) {
+		if !on {
+			if stop != nil {
+				close(stop)
+				stop = nil
+			}
+			return
+		}
+		if stop != nil {
+			return
+		}
+		stop = make(chan struct{})
+		go a.tailLogs(logDisplay, logScroll, stop)
+	})
+
+	// Professional layout: current session entries, and the files on disk
+	sessionContainer := container.NewVBox(
+		container.NewGridWithColumns(3,
+			container.NewVBox(levelLabel, levelFilter),
+			container.NewVBox(moduleLabel, moduleFilter),
+			container.NewVBox(recordLabel, recordSelect),
+		),
 		container.NewHBox(
 			refreshBtn,
 			exportBtn,
 			exportZipBtn,
+			bundleBtn,
 			clearBtn,
+			liveCheck,
 		),
-		container.NewScroll(logDisplay),
+		logScroll,
+	)
+
+	logTabs := container.NewAppTabs(
+		container.NewTabItem("🧠 Session", container.NewScroll(sessionContainer)),
+		container.NewTabItem("📂 Fichiers", a.createLogFilesView()),
 	)
 
-	return container.NewScroll(logsContainer)
+	return container.NewBorder(title, nil, nil, nil, logTabs)
 }
 
-// performAdvancedSearch performs advanced search with multiple criteria
-func (a *App) performAdvancedSearch(query, country, scanner, risk string) {
-	results := FilterAdvancedSearch(a.data, query, country, scanner, risk)
-	a.searchResults = results
-	if a.searchResultsTable != nil {
-		a.searchResultsTable.Refresh()
+// performAdvancedSearch performs advanced search with multiple criteria;
+// confidence keeps the records attributed with that confidence, seenAttacking
+// only the IPs that hit the user's honeypots and window the records whose
+// chosen date is recent enough
+func (a *App) performAdvancedSearch(query, country, scanner, risk, confidence string, seenAttacking bool, window extractor.TimeWindow) {
+	results, err := a.searchStore(StoreQuery(query, country, scanner, risk, confidence))
+	if err != nil {
+		if !errors.Is(err, errNoStore) && !errors.Is(err, errSampleLoaded) {
+			a.logger.Warning("GUI", "Record store search failed, searching in memory: "+err.Error())
+		}
+		// The loaded records: the sample explored, or the whole dataset
+		results = FilterConfidence(FilterAdvancedSearch(a.dataset(), query, country, scanner, risk), confidence)
 	}
+	if seenAttacking {
+		results = extractor.SeenAttacking(results)
+	}
+	results = window.Filter(results, time.Now())
+	a.setSearchResults(results)
 
 	// Update search statistics
 	if a.searchStatsLabel != nil {
-		a.searchStatsLabel.SetText(fmt.Sprintf("📈 Search Results: %d records found", len(results)))
+		a.searchStatsLabel.SetText(a.text(fmt.Sprintf("📈 Search Results: %d records found", len(results))))
 	}
 
 	a.displaySearchStatistics(results)
 }
 
+// setSearchResults replaces the search results and shows their first page
+func (a *App) setSearchResults(results []models.ScannerData) {
+	a.searchResults = results
+	a.searchTable.currentPage = 1
+	a.searchTable.resetSelection()
+	a.searchTable.Refresh()
+}
+
 // enrichIPData performs IP enrichment with real APIs
 func (a *App) enrichIPData(query string) {
 	if query == "" {
-		dialog.ShowInformation("Enrichment", "Please enter an IP address to enrich", a.mainWindow)
+		a.showInformation("Enrichment", "Please enter an IP address to enrich", a.mainWindow)
 		return
 	}
 
 	// Show loading message
 	if a.enrichmentText != nil {
-		a.enrichmentText.SetText("🔄 Enriching IP data... Please wait...")
+		a.enrichmentText.SetText(a.text("🔄 Enriching IP data... Please wait..."))
 	}
 
 	// Run enrichment in background
 	go func() {
+		defer a.crash.Recover("GUI")
 		result := a.performRealIPEnrichment(query)
 		if a.enrichmentText != nil {
 			a.enrichmentText.SetText(result)
@@ -421,14 +831,134 @@ func (a *App) enrichIPData(query string) {
 	}()
 }
 
-// filterLogs filters logs by level (placeholder implementation)
+// expandASN fetches the prefixes announced by the ASN in query (or by the ASN
+// of the IP in query), shows the dataset records inside them as search
+// results, and offers to export the prefix list for blocking.
+func (a *App) expandASN(query string) {
+	data := a.dataset()
+	asn, err := ResolveASNQuery(data, query)
+	if err != nil {
+		dialog.ShowError(err, a.mainWindow)
+		return
+	}
+
+	if a.enrichmentText != nil {
+		a.enrichmentText.SetText(a.text("🔄 Fetching prefixes announced by " + asn + "..."))
+	}
+
+	go func() {
+		defer a.crash.Recover("GUI")
+		exp, err := a.extractor.ExpandASN(a.runContext(), asn, data)
+		if err != nil {
+			a.logger.Error("GUI", "ASN expansion failed: "+err.Error())
+			if a.enrichmentText != nil {
+				a.enrichmentText.SetText(a.text("❌ " + err.Error()))
+			}
+			return
+		}
+
+		a.setSearchResults(FilterByIPs(data, exp.MatchingIPs))
+		if a.searchStatsLabel != nil {
+			a.searchStatsLabel.SetText(a.text(fmt.Sprintf("📈 %s: %d prefixes, %d dataset records", exp.ASN, len(exp.Prefixes), len(a.searchResults))))
+		}
+		if a.enrichmentText != nil {
+			a.enrichmentText.SetText(a.text(fmt.Sprintf("🧭 %s announces %d prefixes:\n%s", exp.ASN, len(exp.Prefixes), strings.Join(exp.Prefixes, "\n"))))
+		}
+
+		if len(exp.Prefixes) == 0 {
+			return
+		}
+		msg := fmt.Sprintf("%s announces %d prefixes covering %d dataset records.\nExport the prefix list for blocking?", exp.ASN, len(exp.Prefixes), len(exp.MatchingIPs))
+		dialog.ShowConfirm("ASN Prefixes", msg, func(ok bool) {
+			if !ok {
+				return
+			}
+			filename := fmt.Sprintf("asn_%s_prefixes_%s.txt", exp.ASN, time.Now().Format("20060102_150405"))
+			if err := a.extractor.SaveASNPrefixes(exp, filename); err != nil {
+				dialog.ShowError(err, a.mainWindow)
+				return
+			}
+			a.showInformation("ASN Prefixes", "Exported to "+filepath.Join(a.config.Database.ResultsDir, filename), a.mainWindow)
+		}, a.mainWindow)
+	}()
+}
+
+// filterLogs sets the minimum level shown in the Logs tab and redraws it
 func (a *App) filterLogs(level string) {
-	// Implementation would filter logs by level
-	a.logger.Info("GUI", "Filtering logs by level: "+level)
+	a.logLevelFilter = level
+	if a.logDisplay != nil {
+		a.refreshLogs(a.logDisplay)
+	}
+}
+
+// filterLogsByModule sets the module shown in the Logs tab and redraws it
+func (a *App) filterLogsByModule(module string) {
+	a.logModuleFilter = module
+	if a.logDisplay != nil {
+		a.refreshLogs(a.logDisplay)
+	}
 }
 
-// refreshLogs refreshes the log display (placeholder implementation)
+// refreshLogs shows the in-memory log entries matching the level and module filters
 func (a *App) refreshLogs(logDisplay *widget.Entry) {
-	// Implementation would load and display actual logs
-	logDisplay.SetText("📋 System Logs\n• Application started\n• Data loaded successfully\n• Interface ready")
+	entries := FilterLogEntries(a.logger.GetEntries(), a.logLevelFilter, a.logModuleFilter)
+	logDisplay.SetText(FormatLogEntries(entries))
+}
+
+// tailLogs redraws logDisplay and scrolls to its end whenever entries are
+// logged, checking every logViewerInterval until done is closed
+func (a *App) tailLogs(logDisplay *widget.Entry, scroll *container.Scroll, done chan struct{}) {
+	defer a.crash.Recover("GUI")
+	ticker := time.NewTicker(logViewerInterval)
+	defer ticker.Stop()
+	var last time.Time
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			entries := a.logger.GetRecentEntries(1)
+			if len(entries) == 0 || !entries[0].Timestamp.After(last) {
+				continue
+			}
+			last = entries[0].Timestamp
+			a.refreshLogs(logDisplay)
+			scroll.ScrollToBottom()
+		}
+	}
+}
+
+// logLevelSelect returns a select of the logger's recording level, which
+// changes it through setLogLevel
+func (a *App) logLevelSelect() *widget.Select {
+	s := widget.NewSelect(RecordLevels, nil)
+	s.SetSelected(string(a.logger.GetLogLevel()))
+	s.OnChanged = a.setLogLevel
+	a.logLevelSelects = append(a.logLevelSelects, s)
+	return s
+}
+
+// setLogLevel changes the logger's recording level at runtime and stores it
+// in the configuration so it survives a restart. The level selects of the
+// Config and Logs tabs show the new level.
+func (a *App) setLogLevel(value string) {
+	level, err := logger.ParseLevel(value)
+	if err != nil {
+		dialog.ShowError(err, a.mainWindow)
+		return
+	}
+	if level == a.logger.GetLogLevel() && a.config.LogLevel == string(level) {
+		return
+	}
+	a.logger.SetLogLevel(level)
+	a.config.LogLevel = string(level)
+	for _, s := range a.logLevelSelects {
+		if s.Selected != string(level) {
+			s.SetSelected(string(level))
+		}
+	}
+	a.logger.Info("GUI", "Niveau de log: "+string(level))
+	if err := config.NewConfigManager().Save(a.config); err != nil {
+		a.logger.Warning("GUI", "Saving log level failed: "+err.Error())
+	}
 }
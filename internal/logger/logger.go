@@ -8,10 +8,12 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/lia/liacheckscanner_go/internal/models"
+	"github.com/lia/liacheckscanner_go/pkg/events"
+	"github.com/lia/liacheckscanner_go/pkg/models"
 )
 
 // Logger provides structured, leveled logging with file output and automatic log rotation.
@@ -22,6 +24,8 @@ type Logger struct {
 	entries  []models.LogEntry
 	maxSize  int // MB
 	backups  int
+	// plain drops emoji from messages and the console level prefix
+	plain bool
 }
 
 // NewLogger creates a new Logger that writes to both stdout and a daily log file in the logs directory.
@@ -70,27 +74,112 @@ func (l *Logger) GetLogLevel() models.LogLevel {
 	return l.logLevel
 }
 
-// shouldLog vérifie si le message doit être loggé selon le niveau
-func (l *Logger) shouldLog(level models.LogLevel) bool {
-	levels := map[models.LogLevel]int{
-		models.LogLevelDebug:    0,
-		models.LogLevelInfo:     1,
-		models.LogLevelWarning:  2,
-		models.LogLevelError:    3,
-		models.LogLevelCritical: 4,
+// SetPlainLabels turns plain labels mode on or off. In plain mode emoji are
+// removed from recorded messages and from the console output, for screen
+// readers and log parsers.
+func (l *Logger) SetPlainLabels(plain bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.plain = plain
+}
+
+// emojiRanges are the code points treated as emoji by StripEmoji: the
+// pictographs and symbols used to decorate labels, and the joiners and
+// selectors that compose them.
+var emojiRanges = [][2]rune{
+	{0x1F000, 0x1FAFF}, // pictographs, emoticons, transport
+	{0x2600, 0x27BF},   // miscellaneous symbols and dingbats
+	{0x2300, 0x23FF},   // technical symbols such as ⏳ ⏮
+	{0x2B00, 0x2BFF},   // arrows and stars such as ⭐
+	{0x2194, 0x2199},   // emoji arrows ↔ ↕ ↖ ↗ ↘ ↙
+	{0x21A9, 0x21AA},   // ↩ ↪
+	{0x21BA, 0x21BA},   // ↺
+	{0x2139, 0x2139},   // ℹ
+	{0x25B6, 0x25B6},   // ▶
+	{0x25C0, 0x25C0},   // ◀
+	{0x200D, 0x200D},   // zero width joiner
+	{0x20E3, 0x20E3},   // combining keycap
+	{0xFE0F, 0xFE0F},   // emoji presentation selector
+	{0xE0020, 0xE007F}, // tags
+}
+
+func isEmoji(r rune) bool {
+	for _, rg := range emojiRanges {
+		if r >= rg[0] && r <= rg[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// StripEmoji removes emoji from s together with the space that followed
+// each, so "🔍 Search" becomes "Search". Other text is left unchanged.
+func StripEmoji(s string) string {
+	var b strings.Builder
+	dropSpace := false
+	changed := false
+	for _, r := range s {
+		if isEmoji(r) {
+			dropSpace, changed = true, true
+			continue
+		}
+		if dropSpace && r == ' ' {
+			dropSpace = false
+			continue
+		}
+		dropSpace = false
+		b.WriteRune(r)
+	}
+	if !changed {
+		return s
 	}
+	return strings.TrimSpace(b.String())
+}
 
-	return levels[level] >= levels[l.logLevel]
+// levelRanks orders the log levels from most to least verbose.
+var levelRanks = map[models.LogLevel]int{
+	models.LogLevelDebug:    0,
+	models.LogLevelInfo:     1,
+	models.LogLevelWarning:  2,
+	models.LogLevelError:    3,
+	models.LogLevelCritical: 4,
+}
+
+// ParseLevel converts a configuration value such as "debug" or "WARNING"
+// into a LogLevel. An empty string yields INFO.
+func ParseLevel(s string) (models.LogLevel, error) {
+	if strings.TrimSpace(s) == "" {
+		return models.LogLevelInfo, nil
+	}
+	level := models.LogLevel(strings.ToUpper(strings.TrimSpace(s)))
+	if _, ok := levelRanks[level]; !ok {
+		return models.LogLevelInfo, fmt.Errorf("unknown log level %q", s)
+	}
+	return level, nil
+}
+
+// LevelEnabled reports whether a message at level passes the min threshold.
+func LevelEnabled(level, min models.LogLevel) bool {
+	return levelRanks[level] >= levelRanks[min]
+}
+
+// shouldLog vérifie si le message doit être loggé selon le niveau
+// (l.mu doit être verrouille)
+func (l *Logger) shouldLog(level models.LogLevel) bool {
+	return LevelEnabled(level, l.logLevel)
 }
 
 // log enregistre un message de log
 func (l *Logger) log(level models.LogLevel, component, message string, data map[string]interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
 	if !l.shouldLog(level) {
 		return
 	}
-
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	if l.plain {
+		message = StripEmoji(message)
+	}
 
 	entry := models.LogEntry{
 		Timestamp: time.Now(),
@@ -123,7 +212,11 @@ func (l *Logger) log(level models.LogLevel, component, message string, data map[
 	}
 
 	// Afficher dans la console
-	fmt.Printf("%s [%s] %s: %s\n", emoji, level, component, message)
+	if l.plain {
+		fmt.Printf("[%s] %s: %s\n", level, component, message)
+	} else {
+		fmt.Printf("%s [%s] %s: %s\n", emoji, level, component, message)
+	}
 
 	// Écrire dans le fichier JSON
 	if l.logFile != nil {
@@ -182,6 +275,17 @@ func (l *Logger) Critical(component, message string, data ...map[string]interfac
 	l.log(models.LogLevelCritical, component, message, dataMap)
 }
 
+// HandleEvent records a bus event as a debug-level structured entry. It is
+// meant to be subscribed to an events.Bus; producers already log their own
+// human-readable messages, so events are kept at DEBUG to avoid duplicates.
+func (l *Logger) HandleEvent(ev events.Event) {
+	l.Debug(ev.Source, fmt.Sprintf("event %s %s", ev.Type, ev.Message), map[string]interface{}{
+		"type":  string(ev.Type),
+		"count": ev.Count,
+		"total": ev.Total,
+	})
+}
+
 // GetEntries returns a copy of all in-memory log entries.
 func (l *Logger) GetEntries() []models.LogEntry {
 	l.mu.Lock()
@@ -285,6 +389,33 @@ func (l *Logger) cleanupOldLogs(logsDir string) {
 	}
 }
 
+// SetLogsDir moves file logging to a daily log file in dir, creating the
+// directory if needed. The previous file is closed only once the new one is
+// open, so a failure leaves logging unchanged.
+func (l *Logger) SetLogsDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating logs directory: %w", err)
+	}
+	logPath := filepath.Join(dir, fmt.Sprintf("liacheckscanner_%s.log", time.Now().Format("2006-01-02")))
+	file, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return fmt.Errorf("opening log file: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.logFile != nil && l.logFile.Name() == file.Name() {
+		file.Close()
+		return nil
+	}
+	if l.logFile != nil {
+		l.logFile.Close()
+	}
+	l.logFile = file
+	log.SetOutput(io.MultiWriter(os.Stdout, file))
+	return nil
+}
+
 // Close closes the underlying log file and releases resources.
 func (l *Logger) Close() error {
 	l.mu.Lock()
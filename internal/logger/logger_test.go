@@ -8,9 +8,25 @@ import (
 	"testing"
 	"time"
 
-	"github.com/lia/liacheckscanner_go/internal/models"
+	"github.com/lia/liacheckscanner_go/pkg/events"
+	"github.com/lia/liacheckscanner_go/pkg/models"
 )
 
+// TestMain runs the tests from a temporary directory, so the logs and data
+// files written relative to the working directory stay out of the tree.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "logger-test-")
+	if err != nil {
+		panic(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 // TestLoggerCreation tests the creation of a Logger instance
 func TestLoggerCreation(t *testing.T) {
 	logger := NewLogger()
@@ -431,3 +447,106 @@ func TestCleanupOldLogs_ZeroBackups_NoOp(t *testing.T) {
 func formatString(format string, a ...interface{}) string {
 	return strings.ReplaceAll(format, "%d", "0")
 }
+
+func TestHandleEvent_RecordsDebugEntry(t *testing.T) {
+	l := NewLogger()
+	l.SetLogLevel(models.LogLevelDebug)
+	l.ClearEntries()
+
+	bus := events.NewBus()
+	bus.Subscribe(l.HandleEvent)
+	bus.Publish(events.Event{Type: events.RecordsEnriched, Source: "Extractor", Count: 5, Total: 10})
+
+	entries := l.GetEntries()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	e := entries[0]
+	if e.Level != models.LogLevelDebug || e.Component != "Extractor" {
+		t.Errorf("Unexpected entry: %+v", e)
+	}
+	if e.Data["count"] != 5 || e.Data["total"] != 10 {
+		t.Errorf("Event counters not recorded: %v", e.Data)
+	}
+}
+
+// TestSetLogsDir tests moving file logging to another directory
+func TestSetLogsDir(t *testing.T) {
+	logger := NewLogger()
+	defer logger.Close()
+
+	dir := filepath.Join(t.TempDir(), "custom", "logs")
+	if err := logger.SetLogsDir(dir); err != nil {
+		t.Fatalf("SetLogsDir: %v", err)
+	}
+	logger.Info("Test", "written to the custom directory")
+
+	files, err := filepath.Glob(filepath.Join(dir, "liacheckscanner_*.log"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected one log file in %s, got %v (%v)", dir, files, err)
+	}
+	content, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !strings.Contains(string(content), "written to the custom directory") {
+		t.Errorf("log file does not contain the message: %q", content)
+	}
+
+	// A directory that cannot be created leaves logging unchanged.
+	blocker := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := logger.SetLogsDir(filepath.Join(blocker, "logs")); err == nil {
+		t.Error("SetLogsDir should fail when the path is below a file")
+	}
+}
+
+// TestParseLevel tests parsing configured log levels
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    models.LogLevel
+		wantErr bool
+	}{
+		{"", models.LogLevelInfo, false},
+		{"debug", models.LogLevelDebug, false},
+		{" WARNING ", models.LogLevelWarning, false},
+		{"verbose", models.LogLevelInfo, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.in)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseLevel(%q) = %q, %v; want %q (err=%v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestStripEmoji(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"🔍 Search", "Search"},
+		{"⚙️ Configuration appliquée", "Configuration appliquée"},
+		{"RDAP 3/10 - ✅ done", "RDAP 3/10 - done"},
+		{"ℹ️ Default directory", "Default directory"},
+		{"• Version: 1.0.0", "• Version: 1.0.0"},
+		{"Plain text", "Plain text"},
+		{"a → b", "a → b"},
+		{"↺ Reset", "Reset"},
+	}
+	for _, tt := range tests {
+		if got := StripEmoji(tt.in); got != tt.want {
+			t.Errorf("StripEmoji(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSetPlainLabels_StripsRecordedMessages(t *testing.T) {
+	logger := NewLogger()
+	logger.SetPlainLabels(true)
+	logger.Info("GUI", "✅ 12 records loaded")
+	entries := logger.GetEntries()
+	if got := entries[len(entries)-1].Message; got != "12 records loaded" {
+		t.Errorf("plain message = %q, want %q", got, "12 records loaded")
+	}
+}
@@ -0,0 +1,264 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+const (
+	// enrichSyncMax is the largest list of IPs POST /api/enrich enriches
+	// before answering; longer lists, or "async": true, start a job.
+	enrichSyncMax = 25
+	// enrichMaxIPs is the largest list of IPs accepted in one request.
+	enrichMaxIPs = 1000
+	// enrichJobsKept is how many jobs are kept for polling; the oldest
+	// finished jobs are dropped first.
+	enrichJobsKept = 100
+	// enrichJobsPerUser and enrichJobsRunning cap the jobs running at once
+	// for one user and for the whole server; more are answered 429.
+	enrichJobsPerUser = 2
+	enrichJobsRunning = 8
+)
+
+// Status of an enrichment job.
+const (
+	jobRunning  = "running"
+	jobDone     = "done"
+	jobCanceled = "canceled"
+)
+
+// enrichResult is the answer to a list of IPs: the enriched records, and
+// the error of each IP whose enrichment failed. A failed record is still
+// returned with the fields that were filled.
+type enrichResult struct {
+	Results []models.ScannerData `json:"results,omitempty"`
+	Errors  map[string]string    `json:"errors,omitempty"`
+}
+
+// enrichJob is an enrichment of a list of IPs running in the background,
+// polled with GET /api/enrich/jobs/<id>.
+type enrichJob struct {
+	ID         string     `json:"job"`
+	Status     string     `json:"status"`
+	Owner      string     `json:"owner"`
+	Done       int        `json:"done"`
+	Total      int        `json:"total"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	URL        string     `json:"url"`
+	enrichResult
+
+	cancel context.CancelFunc
+}
+
+// enrichJobs holds the jobs of a server.
+type enrichJobs struct {
+	mu   sync.Mutex
+	jobs map[string]*enrichJob
+}
+
+// parseEnrichIPs trims, validates and deduplicates the IPs of a request.
+func parseEnrichIPs(ips []string) ([]string, error) {
+	seen := map[string]bool{}
+	var out []string
+	for _, ip := range ips {
+		ip = strings.TrimSpace(ip)
+		if ip == "" || seen[ip] {
+			continue
+		}
+		if net.ParseIP(ip) == nil {
+			if _, _, err := net.ParseCIDR(ip); err != nil {
+				return nil, fmt.Errorf("%q is neither an IP address nor a CIDR", ip)
+			}
+		}
+		seen[ip] = true
+		out = append(out, ip)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no IP to enrich")
+	}
+	return out, nil
+}
+
+// newJobID returns a random job ID.
+func newJobID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// enrichList enriches arbitrary IPs, in the dataset or not, with the
+// configured throttle, calling progress after each. Records are built like
+// those of a run, so IPs listed by a scanner are attributed to it.
+func (s *Server) enrichList(ctx context.Context, ips []string, progress func(done int)) enrichResult {
+	res := enrichResult{Results: s.ext.BuildBaseRecords(ips)}
+	done, errs := s.ext.EnrichRecords(ctx, res.Results, progress)
+	for i, err := range errs {
+		if res.Errors == nil {
+			res.Errors = map[string]string{}
+		}
+		res.Errors[res.Results[i].IPOrCIDR] = err.Error()
+	}
+	res.Results = res.Results[:done]
+	return res
+}
+
+// handleEnrichIPs answers POST /api/enrich with a list of IPs: enriched
+// before answering when there are at most enrichSyncMax, else in a job whose
+// reference is returned with 202 Accepted.
+func (s *Server) handleEnrichIPs(w http.ResponseWriter, r *http.Request, ips []string, async bool) {
+	ips, err := parseEnrichIPs(ips)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(ips) > enrichMaxIPs {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("at most %d IPs per request", enrichMaxIPs))
+		return
+	}
+	user := userFrom(r).Name
+	if !async && len(ips) <= enrichSyncMax {
+		res := s.enrichList(r.Context(), ips, nil)
+		s.logger.Info("Server", fmt.Sprintf("%s enriched %d IPs, %d failed", user, len(ips), len(res.Errors)))
+		writeJSON(w, http.StatusOK, res)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(s.baseCtx)
+	job := &enrichJob{
+		ID:        newJobID(),
+		Status:    jobRunning,
+		Owner:     user,
+		Total:     len(ips),
+		StartedAt: time.Now().UTC(),
+		cancel:    cancel,
+	}
+	job.URL = "/api/enrich/jobs/" + job.ID
+	if err := s.jobs.add(job); err != nil {
+		cancel()
+		writeError(w, http.StatusTooManyRequests, err.Error())
+		return
+	}
+	s.logger.Info("Server", fmt.Sprintf("%s started enrichment job %s on %d IPs", user, job.ID, len(ips)))
+	go func() {
+		defer s.crash.Recover("Server")
+		defer cancel()
+		res := s.enrichList(ctx, ips, func(done int) {
+			s.jobs.mu.Lock()
+			job.Done = done
+			s.jobs.mu.Unlock()
+		})
+		s.jobs.mu.Lock()
+		job.enrichResult = res
+		finished := time.Now().UTC()
+		job.FinishedAt = &finished
+		job.Status = jobDone
+		if ctx.Err() != nil {
+			job.Status = jobCanceled
+		}
+		s.jobs.mu.Unlock()
+		s.logger.Info("Server", fmt.Sprintf("Enrichment job %s %s: %d/%d IPs, %d failed", job.ID, job.Status, len(res.Results), job.Total, len(res.Errors)))
+	}()
+	w.Header().Set("Location", job.URL)
+	writeJSON(w, http.StatusAccepted, s.jobs.view(job))
+}
+
+// handleEnrichJob returns (GET) or cancels (DELETE) the enrichment job of
+// /api/enrich/jobs/<id>. Results are included once the job is finished.
+// Only the user who started the job or an admin may do either.
+func (s *Server) handleEnrichJob(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/enrich/jobs/")
+	job := s.jobs.get(id)
+	if job == nil {
+		writeError(w, http.StatusNotFound, "unknown job "+id)
+		return
+	}
+	user := userFrom(r)
+	if user.Name != job.Owner && !user.Role.Allows(models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "only the owner of job "+id+" or an admin can access it")
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.jobs.view(job))
+	case http.MethodDelete:
+		job.cancel()
+		s.logger.Info("Server", fmt.Sprintf("%s canceled enrichment job %s", user.Name, id))
+		writeJSON(w, http.StatusOK, s.jobs.view(job))
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// add registers job, dropping the oldest finished jobs beyond
+// enrichJobsKept. It fails when its owner already runs enrichJobsPerUser
+// jobs, or the server enrichJobsRunning.
+func (j *enrichJobs) add(job *enrichJob) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	running, owned := 0, 0
+	for _, other := range j.jobs {
+		if other.Status == jobRunning {
+			running++
+			if other.Owner == job.Owner {
+				owned++
+			}
+		}
+	}
+	if owned >= enrichJobsPerUser {
+		return fmt.Errorf("%s already runs %d enrichment jobs; wait for one to finish", job.Owner, owned)
+	}
+	if running >= enrichJobsRunning {
+		return fmt.Errorf("%d enrichment jobs already running; retry later", running)
+	}
+	if j.jobs == nil {
+		j.jobs = map[string]*enrichJob{}
+	}
+	j.jobs[job.ID] = job
+	if len(j.jobs) <= enrichJobsKept {
+		return nil
+	}
+	var finished []*enrichJob
+	for _, other := range j.jobs {
+		if other.Status != jobRunning {
+			finished = append(finished, other)
+		}
+	}
+	sort.Slice(finished, func(a, b int) bool { return finished[a].StartedAt.Before(finished[b].StartedAt) })
+	for _, old := range finished {
+		if len(j.jobs) <= enrichJobsKept {
+			break
+		}
+		delete(j.jobs, old.ID)
+	}
+	return nil
+}
+
+// get returns the job with id, or nil.
+func (j *enrichJobs) get(id string) *enrichJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.jobs[id]
+}
+
+// view returns a copy of job safe to encode while it runs; results are
+// left out until it is finished.
+func (j *enrichJobs) view(job *enrichJob) enrichJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	v := *job
+	if v.Status == jobRunning {
+		v.enrichResult = enrichResult{}
+	}
+	return v
+}
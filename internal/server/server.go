@@ -0,0 +1,639 @@
+// Package server provides the optional REST API exposing the scanner dataset
+// and the analyst annotations. It is started when Database.EnableAPI is set.
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/internal/config"
+	"github.com/lia/liacheckscanner_go/internal/diagnostics"
+	"github.com/lia/liacheckscanner_go/internal/logger"
+	"github.com/lia/liacheckscanner_go/pkg/extractor"
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// DefaultListen is the listen address used when Database.APIListen is empty.
+const DefaultListen = "127.0.0.1:8088"
+
+// maxBodyBytes caps the JSON bodies read by decodeBody: a full batch of
+// shared cache entries takes a few hundred kilobytes.
+const maxBodyBytes = 8 << 20
+
+// Server serves the REST API.
+type Server struct {
+	logger *logger.Logger
+	ext    *extractor.Extractor
+	addr   string
+
+	mu      sync.RWMutex
+	config  *models.AppConfig
+	records []models.ScannerData
+
+	// crash writes a report for panics in request handlers.
+	crash *diagnostics.Reporter
+
+	// saveConfig persists configuration changes (replaced in tests).
+	saveConfig func(*models.AppConfig) error
+
+	httpServer *http.Server
+	listener   net.Listener
+
+	// Enrichment jobs started by POST /api/enrich, canceled by Close
+	jobs       enrichJobs
+	baseCtx    context.Context
+	cancelJobs context.CancelFunc
+}
+
+// New creates a Server for the given configuration. Annotations and
+// enrichment go through ext; configuration changes made by admins are saved
+// and applied to cfg.
+func New(cfg *models.AppConfig, ext *extractor.Extractor, log *logger.Logger) *Server {
+	addr := cfg.Database.APIListen
+	if addr == "" {
+		addr = DefaultListen
+	}
+	s := &Server{
+		logger: log,
+		ext:    ext,
+		addr:   addr,
+		config: cfg,
+		crash:  diagnostics.New(cfg, log),
+		saveConfig: func(c *models.AppConfig) error {
+			return config.NewConfigManager().Save(c)
+		},
+	}
+	s.baseCtx, s.cancelJobs = context.WithCancel(context.Background())
+	return s
+}
+
+// SetRecords replaces the dataset served by the API.
+func (s *Server) SetRecords(data []models.ScannerData) {
+	cp := make([]models.ScannerData, len(data))
+	copy(cp, data)
+	s.mu.Lock()
+	s.records = cp
+	s.mu.Unlock()
+}
+
+// Handler returns the API routes.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/health", s.require(models.RoleViewer, s.handleHealth))
+	mux.HandleFunc("/api/records", s.require(models.RoleViewer, s.handleRecords))
+	mux.HandleFunc("/api/annotations", s.handleAnnotations)
+	mux.HandleFunc("/api/hits", s.handleHits)
+	mux.HandleFunc("/api/cache", s.handleCache)
+	mux.HandleFunc("/api/cache/lookup", s.require(models.RoleViewer, s.handleCacheLookup))
+	mux.HandleFunc("/api/enrich", s.require(models.RoleAnalyst, s.handleEnrich))
+	mux.HandleFunc("/api/enrich/jobs/", s.require(models.RoleAnalyst, s.handleEnrichJob))
+	mux.HandleFunc("/api/config", s.require(models.RoleAdmin, s.handleConfig))
+	mux.HandleFunc("/api/publish", s.require(models.RoleAdmin, s.handlePublish))
+	return s.recoverPanics(s.authenticate(mux))
+}
+
+// recoverPanics turns a panicking request into a 500 response and a crash
+// report instead of net/http's stderr trace.
+func (s *Server) recoverPanics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if v := recover(); v != nil {
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+				s.crash.HandlePanic("Server", v, debug.Stack())
+				writeError(w, http.StatusInternalServerError, "internal error")
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Start begins listening and serves requests in the background. It refuses
+// to listen beyond the loopback interface without API credentials, which
+// would give every client on the network the admin role.
+func (s *Server) Start() error {
+	s.mu.RLock()
+	db := s.config.Database
+	s.mu.RUnlock()
+	if err := checkExposure(s.addr, db); err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", s.addr, err)
+	}
+	s.listener = ln
+	s.httpServer = &http.Server{Handler: s.Handler()}
+	go func() {
+		defer s.crash.Recover("Server")
+		if err := s.httpServer.Serve(ln); err != nil && err != http.ErrServerClosed {
+			s.logger.Error("Server", "API server stopped: "+err.Error())
+		}
+	}()
+	s.logger.Info("Server", "API server listening on "+ln.Addr().String())
+	return nil
+}
+
+// Addr returns the address the server listens on, or the configured one
+// before Start.
+func (s *Server) Addr() string {
+	if s.listener != nil {
+		return s.listener.Addr().String()
+	}
+	return s.addr
+}
+
+// Close stops the server and its enrichment jobs.
+func (s *Server) Close() error {
+	s.cancelJobs()
+	if s.httpServer == nil {
+		return nil
+	}
+	return s.httpServer.Close()
+}
+
+// checkExposure returns an error when addr is reachable from other hosts
+// and db configures no API credentials.
+func checkExposure(addr string, db models.DatabaseConfig) error {
+	if db.APIKey != "" || len(db.APIUsers) > 0 || isLoopback(addr) {
+		return nil
+	}
+	return fmt.Errorf("refusing to serve the API on %s without api_key or api_users: set credentials or listen on 127.0.0.1", addr)
+}
+
+// isLoopback reports whether the listen address addr only accepts local
+// connections.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// sameKey compares API keys in constant time.
+func sameKey(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+type userKey struct{}
+
+// anonymous is the user of every request when no credentials are
+// configured, which Start only allows on the loopback interface.
+var anonymous = models.APIUser{Name: "anonymous", Role: models.RoleAdmin}
+
+// userFrom returns the authenticated user of r.
+func userFrom(r *http.Request) models.APIUser {
+	if u, ok := r.Context().Value(userKey{}).(models.APIUser); ok {
+		return u
+	}
+	return models.APIUser{}
+}
+
+// lookupUser maps an API key to its user. The legacy APIKey is an admin key.
+// With no credentials configured at all, every request is anonymous admin.
+func (s *Server) lookupUser(key string) (models.APIUser, bool) {
+	s.mu.RLock()
+	db := s.config.Database
+	s.mu.RUnlock()
+	if db.APIKey == "" && len(db.APIUsers) == 0 {
+		return anonymous, true
+	}
+	if key == "" {
+		return models.APIUser{}, false
+	}
+	if db.APIKey != "" && sameKey(key, db.APIKey) {
+		return models.APIUser{Name: "admin", Key: key, Role: models.RoleAdmin}, true
+	}
+	for _, u := range db.APIUsers {
+		if u.Key != "" && sameKey(key, u.Key) {
+			return u, true
+		}
+	}
+	return models.APIUser{}, false
+}
+
+// authenticate resolves the Bearer token or X-API-Key header to a user.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("X-API-Key")
+		if key == "" {
+			key = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		}
+		user, ok := s.lookupUser(key)
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "invalid or missing API key")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, user)))
+	})
+}
+
+// require rejects requests whose user role is below role.
+func (s *Server) require(role models.Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if user := userFrom(r); !user.Role.Allows(role) {
+			s.logger.Warning("Server", fmt.Sprintf("%s (%s) denied %s %s", user.Name, user.Role, r.Method, r.URL.Path))
+			writeError(w, http.StatusForbidden, fmt.Sprintf("requires the %s role", role))
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// snapshot returns a copy of the served records safe to modify.
+func (s *Server) snapshot() []models.ScannerData {
+	s.mu.RLock()
+	data := make([]models.ScannerData, len(s.records))
+	copy(data, s.records)
+	s.mu.RUnlock()
+	for i := range data {
+		data[i].Tags = append([]string(nil), data[i].Tags...)
+	}
+	return data
+}
+
+// handleRecords returns the dataset with annotations applied. With
+// ?updated_since=<RFC 3339> only the records updated after that time are
+// returned; the X-Server-Time header gives the value for the next call.
+func (s *Server) handleRecords(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var since time.Time
+	if v := r.URL.Query().Get("updated_since"); v != "" {
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "updated_since must be an RFC 3339 time")
+			return
+		}
+		since = t
+	}
+	now := time.Now().UTC()
+	data := s.snapshot()
+	// Annotations bump UpdatedAt, so they are applied before filtering
+	if err := s.ext.ApplyAnnotations(data); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !since.IsZero() {
+		updated := data[:0]
+		for _, item := range data {
+			if item.UpdatedAt.After(since) {
+				updated = append(updated, item)
+			}
+		}
+		data = updated
+	}
+	if err := s.ext.ApplyLocks(data); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := s.ext.ApplyHits(data); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set(extractor.ServerTimeHeader, now.Format(time.RFC3339Nano))
+	writeJSON(w, http.StatusOK, data)
+}
+
+// annotationRequest is the body of POST /api/annotations.
+type annotationRequest struct {
+	IP     string   `json:"ip"`
+	Author string   `json:"author"`
+	Tags   []string `json:"tags"`
+	Note   string   `json:"note"`
+}
+
+// handleAnnotations lists annotations (GET, optional ?ip=; viewer) or adds
+// one (POST; analyst). Authenticated users are recorded as the author.
+func (s *Server) handleAnnotations(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.require(models.RoleViewer, s.listAnnotations)(w, r)
+	case http.MethodPost:
+		s.require(models.RoleAnalyst, s.addAnnotation)(w, r)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) listAnnotations(w http.ResponseWriter, r *http.Request) {
+	anns, err := s.ext.Annotations(r.URL.Query().Get("ip"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if anns == nil {
+		anns = []models.Annotation{}
+	}
+	writeJSON(w, http.StatusOK, anns)
+}
+
+func (s *Server) addAnnotation(w http.ResponseWriter, r *http.Request) {
+	var req annotationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	author := req.Author
+	if user := userFrom(r); user != anonymous {
+		author = user.Name
+	}
+	ann, err := s.ext.AddAnnotation(req.IP, author, req.Tags, req.Note)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, ann)
+}
+
+// handleHits lists the stored honeypot hits (GET; viewer) or imports a hits
+// feed (POST; analyst). The body is any layout accepted by
+// extractor.ParseHits: a JSON array, JSON lines or CSV.
+func (s *Server) handleHits(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.require(models.RoleViewer, s.listHits)(w, r)
+	case http.MethodPost:
+		s.require(models.RoleAnalyst, s.addHits)(w, r)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) listHits(w http.ResponseWriter, r *http.Request) {
+	hits, err := s.ext.Hits()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if hits == nil {
+		hits = []models.Hit{}
+	}
+	writeJSON(w, http.StatusOK, hits)
+}
+
+func (s *Server) addHits(w http.ResponseWriter, r *http.Request) {
+	added, err := s.ext.ImportHits(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.logger.Info("Server", fmt.Sprintf("%s imported %d honeypot hits", userFrom(r).Name, added))
+	writeJSON(w, http.StatusCreated, map[string]int{"added": added})
+}
+
+// cacheRequest is the body of the shared RDAP cache endpoints.
+type cacheRequest struct {
+	IPs     []string                         `json:"ips,omitempty"`
+	Entries map[string]models.RDAPCacheEntry `json:"entries,omitempty"`
+}
+
+// handleCache serves the RDAP cache shared by a team: GET ?ip= returns one
+// entry (viewer), POST {"entries"} stores the lookups of another instance
+// (analyst).
+func (s *Server) handleCache(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.require(models.RoleViewer, s.getCacheEntry)(w, r)
+	case http.MethodPost:
+		s.require(models.RoleAnalyst, s.storeCacheEntries)(w, r)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) getCacheEntry(w http.ResponseWriter, r *http.Request) {
+	ip := r.URL.Query().Get("ip")
+	entry, ok := s.ext.CacheEntries([]string{ip})[ip]
+	if !ok {
+		writeError(w, http.StatusNotFound, "no cache entry for "+ip)
+		return
+	}
+	writeJSON(w, http.StatusOK, entry)
+}
+
+func (s *Server) storeCacheEntries(w http.ResponseWriter, r *http.Request) {
+	var req cacheRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeBodyError(w, err, "body must be {\"entries\": {ip: entry}}")
+		return
+	}
+	stored, err := s.ext.StoreCacheEntries(req.Entries)
+	if err != nil {
+		s.logger.Error("Server", err.Error())
+		writeError(w, http.StatusInternalServerError, "RDAP cache not saved")
+		return
+	}
+	s.logger.Info("Server", fmt.Sprintf("%s shared %d RDAP cache entries", userFrom(r).Name, stored))
+	writeJSON(w, http.StatusOK, map[string]int{"stored": stored})
+}
+
+// handleCacheLookup returns the cache entries of several IPs at once (POST
+// {"ips"}), so a run checks the shared cache in a few requests.
+func (s *Server) handleCacheLookup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req cacheRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeBodyError(w, err, "body must be {\"ips\": [...]}")
+		return
+	}
+	writeJSON(w, http.StatusOK, cacheRequest{Entries: s.ext.CacheEntries(req.IPs)})
+}
+
+// handleEnrich runs RDAP/geo enrichment on one served record (POST {"ip"}),
+// or on a list of arbitrary IPs (POST {"ips": [...]}, see handleEnrichIPs).
+func (s *Server) handleEnrich(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req struct {
+		IP    string   `json:"ip"`
+		IPs   []string `json:"ips"`
+		Async bool     `json:"async"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || (req.IP == "" && req.IPs == nil) {
+		writeError(w, http.StatusBadRequest, "body must be {\"ip\": \"...\"} or {\"ips\": [...]}")
+		return
+	}
+	if req.IPs != nil {
+		s.handleEnrichIPs(w, r, req.IPs, req.Async)
+		return
+	}
+	s.mu.RLock()
+	idx := -1
+	for i := range s.records {
+		if s.records[i].IPOrCIDR == req.IP {
+			idx = i
+			break
+		}
+	}
+	var item models.ScannerData
+	if idx >= 0 {
+		item = s.records[idx]
+	}
+	s.mu.RUnlock()
+	if idx < 0 {
+		writeError(w, http.StatusNotFound, "unknown IP "+req.IP)
+		return
+	}
+	if err := s.ext.EnrichRecordWithDelay(r.Context(), &item, -1); errors.Is(err, extractor.ErrRecordLocked) {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	} else if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	s.mu.Lock()
+	if idx < len(s.records) && s.records[idx].IPOrCIDR == item.IPOrCIDR {
+		s.records[idx] = item
+	}
+	s.mu.Unlock()
+	s.logger.Info("Server", fmt.Sprintf("%s enriched %s", userFrom(r).Name, req.IP))
+	writeJSON(w, http.StatusOK, item)
+}
+
+// handleConfig returns (GET) or replaces (PUT) the database configuration.
+// A PUT is validated and saved to config/config.json before being applied to
+// the running extractor.
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.RLock()
+		redacted := diagnostics.RedactConfig(s.config)
+		s.mu.RUnlock()
+		writeJSON(w, http.StatusOK, redacted.Database)
+	case http.MethodPut:
+		// The body replaces the database section: it is decoded into a new
+		// value, so the live configuration is untouched until it is valid
+		var db models.DatabaseConfig
+		if err := json.NewDecoder(r.Body).Decode(&db); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+			return
+		}
+		s.mu.RLock()
+		next := *s.config
+		s.mu.RUnlock()
+		if err := diagnostics.RestoreRedacted(&db, next.Database); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := checkExposure(s.addr, db); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		next.Database = db
+		if err := config.Validate(&next); err != nil {
+			var verr *config.ValidationError
+			if errors.As(err, &verr) {
+				writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error(), "problems": verr.Problems})
+				return
+			}
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := s.saveConfig(&next); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		s.mu.Lock()
+		*s.config = next
+		s.mu.Unlock()
+		s.ext.ApplyConfig(next.Database)
+		s.logger.Info("Server", userFrom(r).Name+" updated the configuration")
+		writeJSON(w, http.StatusOK, diagnostics.RedactConfig(&next).Database)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handlePublish reports the enforcement delta and collateral matches
+// without publishing (GET), or approves and exports the blocked records
+// (POST). Publishing is refused with 409 when the list blocks a critical
+// protected prefix.
+func (s *Server) handlePublish(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	data := s.snapshot()
+	delta, err := s.ext.EnforcementDelta(data)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if r.Method == http.MethodGet {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"delta": delta, "publishable": len(delta.CriticalCollateral()) == 0})
+		return
+	}
+	rec, err := s.ext.ApproveEnforcement(data, userFrom(r).Name)
+	if errors.Is(err, extractor.ErrCriticalCollateral) {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{"error": err.Error(), "delta": delta})
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	filename := fmt.Sprintf("enforcement_%s.csv", time.Now().Format("2006-01-02_15-04-05"))
+	enforceable := extractor.Enforceable(data)
+	if err := s.ext.Export(enforceable, filename); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := s.ext.SaveRunMetadata(filename, enforceable); err != nil {
+		s.logger.Warning("Server", "Run metadata not written: "+err.Error())
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"approval": rec, "delta": delta, "file": filename})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// decodeBody decodes the JSON body of r into v, reading at most
+// maxBodyBytes.
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	return json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(v)
+}
+
+// writeBodyError answers a body decodeBody failed on: 413 when it was too
+// large, else 400 with usage.
+func writeBodyError(w http.ResponseWriter, err error, usage string) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("body larger than %d bytes", maxBodyBytes))
+		return
+	}
+	writeError(w, http.StatusBadRequest, usage)
+}
@@ -0,0 +1,588 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/internal/logger"
+	"github.com/lia/liacheckscanner_go/pkg/extractor"
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// newTestServer returns a server whose stores live in a temporary working
+// directory and whose configuration changes are kept in memory.
+func newTestServer(t *testing.T, cfg models.DatabaseConfig) *Server {
+	t.Helper()
+	dir := t.TempDir()
+	origDir, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Chdir: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(origDir) })
+
+	log := logger.NewLogger()
+	app := &models.AppConfig{AppName: "Test", Version: "1.0.0", LogLevel: "INFO", MaxLogSize: 10, Database: cfg}
+	srv := New(app, extractor.NewExtractor(cfg, log), log)
+	srv.saveConfig = func(*models.AppConfig) error { return nil }
+	return srv
+}
+
+func do(t *testing.T, h http.Handler, method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// -------------------------------------------------------
+// Annotations
+// -------------------------------------------------------
+
+func TestAnnotations_MultipleAuthors(t *testing.T) {
+	srv := newTestServer(t, models.DatabaseConfig{})
+	srv.SetRecords([]models.ScannerData{{IPOrCIDR: "192.0.2.1", Tags: []string{"extracted"}}})
+	h := srv.Handler()
+
+	for _, body := range []string{
+		`{"ip":"192.0.2.1","author":"alice","tags":["botnet"]}`,
+		`{"ip":"192.0.2.1","author":"bob","tags":["botnet","mirai"],"note":"seen on honeypot"}`,
+	} {
+		if rec := do(t, h, http.MethodPost, "/api/annotations", body, nil); rec.Code != http.StatusCreated {
+			t.Fatalf("POST status = %d, body %s", rec.Code, rec.Body.String())
+		}
+	}
+	if rec := do(t, h, http.MethodPost, "/api/annotations", `{"ip":"192.0.2.1","tags":["x"]}`, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("annotation without author: status = %d, want 400", rec.Code)
+	}
+
+	rec := do(t, h, http.MethodGet, "/api/annotations?ip=192.0.2.1", "", nil)
+	var anns []models.Annotation
+	if err := json.Unmarshal(rec.Body.Bytes(), &anns); err != nil {
+		t.Fatalf("decoding annotations: %v", err)
+	}
+	if len(anns) != 2 || anns[0].Author != "alice" || anns[1].Author != "bob" || anns[1].CreatedAt == "" {
+		t.Errorf("annotations = %+v", anns)
+	}
+
+	rec = do(t, h, http.MethodGet, "/api/records", "", nil)
+	var records []models.ScannerData
+	if err := json.Unmarshal(rec.Body.Bytes(), &records); err != nil {
+		t.Fatalf("decoding records: %v", err)
+	}
+	if len(records) != 1 || strings.Join(records[0].Tags, ",") != "extracted,botnet,mirai" {
+		t.Errorf("merged tags = %v, want extracted,botnet,mirai", records[0].Tags)
+	}
+	if len(records[0].Annotations) != 2 {
+		t.Errorf("record annotations = %d, want 2", len(records[0].Annotations))
+	}
+}
+
+// -------------------------------------------------------
+// Honeypot hits
+// -------------------------------------------------------
+
+func TestHits_ImportedAndCorrelated(t *testing.T) {
+	srv := newTestServer(t, models.DatabaseConfig{})
+	srv.SetRecords([]models.ScannerData{{IPOrCIDR: "192.0.2.1"}, {IPOrCIDR: "198.51.100.0/24"}, {IPOrCIDR: "203.0.113.9"}})
+	h := srv.Handler()
+
+	feed := "ip,timestamp,port\n192.0.2.1,2024-05-01T10:00:00Z,22\n198.51.100.7,1714557600,443\n192.0.2.1,2024-05-02T10:00:00Z,23\n"
+	rec := do(t, h, http.MethodPost, "/api/hits", feed, nil)
+	if rec.Code != http.StatusCreated || !strings.Contains(rec.Body.String(), `"added":3`) {
+		t.Fatalf("POST status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(t, h, http.MethodPost, "/api/hits", "not-an-ip,yesterday\n10.0.0.1,yesterday\n", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid feed: status = %d, want 400", rec.Code)
+	}
+
+	rec = do(t, h, http.MethodGet, "/api/records", "", nil)
+	var records []models.ScannerData
+	if err := json.Unmarshal(rec.Body.Bytes(), &records); err != nil {
+		t.Fatalf("decoding records: %v", err)
+	}
+	if records[0].HitCount != 2 || !records[0].LastHit.Equal(time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("exact IP: hits = %d, last = %v", records[0].HitCount, records[0].LastHit)
+	}
+	if records[1].HitCount != 1 || records[2].HitCount != 0 {
+		t.Errorf("range hits = %d, unrelated hits = %d; want 1 and 0", records[1].HitCount, records[2].HitCount)
+	}
+}
+
+func TestRecords_UpdatedSince(t *testing.T) {
+	srv := newTestServer(t, models.DatabaseConfig{})
+	old := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	srv.SetRecords([]models.ScannerData{
+		{ID: "a", IPOrCIDR: "192.0.2.1", UpdatedAt: old},
+		{ID: "b", IPOrCIDR: "192.0.2.2", UpdatedAt: old.Add(48 * time.Hour)},
+	})
+	h := srv.Handler()
+
+	rec := do(t, h, http.MethodGet, "/api/records?updated_since=2024-05-02T00:00:00Z", "", nil)
+	var records []models.ScannerData
+	if err := json.Unmarshal(rec.Body.Bytes(), &records); err != nil {
+		t.Fatalf("decoding records: %v", err)
+	}
+	if len(records) != 1 || records[0].ID != "b" {
+		t.Errorf("updated_since returned %+v", records)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, rec.Header().Get(extractor.ServerTimeHeader)); err != nil {
+		t.Errorf("%s header: %v", extractor.ServerTimeHeader, err)
+	}
+	if rec := do(t, h, http.MethodGet, "/api/records?updated_since=yesterday", "", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid updated_since: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestCache_SharedBetweenInstances(t *testing.T) {
+	srv := newTestServer(t, models.DatabaseConfig{})
+	h := srv.Handler()
+
+	cached := time.Now().UTC().Add(-time.Hour).Format(time.RFC3339)
+	body := `{"entries":{"192.0.2.1":{"rdap_name":"NET-A","registry":"arin","cached_at":"` + cached + `"}}}`
+	rec := do(t, h, http.MethodPost, "/api/cache", body, nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"stored":1`) {
+		t.Fatalf("POST status = %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	rec = do(t, h, http.MethodPost, "/api/cache/lookup", `{"ips":["192.0.2.1","192.0.2.9"]}`, nil)
+	var resp cacheRequest
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding lookup: %v", err)
+	}
+	if len(resp.Entries) != 1 || resp.Entries["192.0.2.1"].RDAPName != "NET-A" {
+		t.Errorf("lookup = %+v", resp.Entries)
+	}
+	if rec := do(t, h, http.MethodGet, "/api/cache?ip=192.0.2.9", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown IP: status = %d, want 404", rec.Code)
+	}
+
+	// An entry dated in the future is taken as cached now
+	future := time.Now().UTC().Add(24 * time.Hour).Format(time.RFC3339)
+	body = `{"entries":{"192.0.2.2":{"rdap_name":"NET-B","cached_at":"` + future + `"}}}`
+	if rec := do(t, h, http.MethodPost, "/api/cache", body, nil); rec.Code != http.StatusOK {
+		t.Fatalf("POST future entry: status = %d", rec.Code)
+	}
+	var entry models.RDAPCacheEntry
+	_ = json.Unmarshal(do(t, h, http.MethodGet, "/api/cache?ip=192.0.2.2", "", nil).Body.Bytes(), &entry)
+	if entry.RDAPName != "NET-B" || entry.CachedAt.After(time.Now()) {
+		t.Errorf("future entry = %+v, want it dated now at the latest", entry)
+	}
+
+	large := `{"ips":["` + strings.Repeat("1", maxBodyBytes) + `"]}`
+	if rec := do(t, h, http.MethodPost, "/api/cache/lookup", large, nil); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body: status = %d, want 413", rec.Code)
+	}
+}
+
+func TestAuthenticate_RequiresAPIKey(t *testing.T) {
+	srv := newTestServer(t, models.DatabaseConfig{APIKey: "secret"})
+	h := srv.Handler()
+
+	if rec := do(t, h, http.MethodGet, "/api/health", "", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("no key: status = %d, want 401", rec.Code)
+	}
+	if rec := do(t, h, http.MethodGet, "/api/health", "", map[string]string{"Authorization": "Bearer secret"}); rec.Code != http.StatusOK {
+		t.Errorf("bearer key: status = %d, want 200", rec.Code)
+	}
+	if rec := do(t, h, http.MethodGet, "/api/health", "", map[string]string{"X-API-Key": "secret"}); rec.Code != http.StatusOK {
+		t.Errorf("X-API-Key: status = %d, want 200", rec.Code)
+	}
+}
+
+// -------------------------------------------------------
+// Roles
+// -------------------------------------------------------
+
+func TestRoles_EnforcedPerEndpoint(t *testing.T) {
+	srv := newTestServer(t, models.DatabaseConfig{
+		RepoURL: "https://example.com",
+		APIUsers: []models.APIUser{
+			{Name: "vic", Key: "v", Role: models.RoleViewer},
+			{Name: "ana", Key: "a", Role: models.RoleAnalyst},
+			{Name: "adm", Key: "x", Role: models.RoleAdmin},
+		},
+	})
+	srv.SetRecords([]models.ScannerData{{IPOrCIDR: "192.0.2.1", State: models.StateBlocked}})
+	h := srv.Handler()
+
+	annotate := `{"ip":"192.0.2.1","author":"spoofed","tags":["t"]}`
+	cases := []struct {
+		method, path, body, key string
+		want                    int
+	}{
+		{http.MethodGet, "/api/records", "", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/records", "", "v", http.StatusOK},
+		{http.MethodGet, "/api/annotations", "", "v", http.StatusOK},
+		{http.MethodPost, "/api/annotations", annotate, "v", http.StatusForbidden},
+		{http.MethodPost, "/api/annotations", annotate, "a", http.StatusCreated},
+		{http.MethodPost, "/api/enrich", `{"ip":"192.0.2.1"}`, "v", http.StatusForbidden},
+		{http.MethodGet, "/api/enrich/jobs/x", "", "v", http.StatusForbidden},
+		{http.MethodGet, "/api/enrich/jobs/x", "", "a", http.StatusNotFound},
+		{http.MethodPost, "/api/cache/lookup", `{"ips":["192.0.2.1"]}`, "v", http.StatusOK},
+		{http.MethodPost, "/api/cache", `{"entries":{}}`, "v", http.StatusForbidden},
+		{http.MethodPost, "/api/cache", `{"entries":{}}`, "a", http.StatusOK},
+		{http.MethodGet, "/api/config", "", "a", http.StatusForbidden},
+		{http.MethodPost, "/api/publish", "", "a", http.StatusForbidden},
+		{http.MethodGet, "/api/config", "", "x", http.StatusOK},
+		{http.MethodPut, "/api/config", `{"repo_url":"https://example.com","api_throttle":-1}`, "x", http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		rec := do(t, h, c.method, c.path, c.body, map[string]string{"X-API-Key": c.key})
+		if rec.Code != c.want {
+			t.Errorf("%s %s as %q: status = %d, want %d (%s)", c.method, c.path, c.key, rec.Code, c.want, rec.Body.String())
+		}
+	}
+
+	anns, _ := srv.ext.Annotations("192.0.2.1")
+	if len(anns) != 1 || anns[0].Author != "ana" {
+		t.Errorf("annotation author = %+v, want the authenticated user", anns)
+	}
+}
+
+func TestConfig_RedactedAndReplacedOnlyWhenValid(t *testing.T) {
+	srv := newTestServer(t, models.DatabaseConfig{
+		RepoURL:     "https://example.com",
+		IPInfoToken: "tok-secret",
+		Registries:  []string{"ripe", "apnic"},
+		APIUsers:    []models.APIUser{{Name: "adm", Key: "key-secret", Role: models.RoleAdmin}},
+	})
+	h := srv.Handler()
+	auth := map[string]string{"X-API-Key": "key-secret"}
+
+	rec := do(t, h, http.MethodGet, "/api/config", "", auth)
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "secret") {
+		t.Fatalf("GET = %d %s, want the secrets redacted", rec.Code, rec.Body.String())
+	}
+	redacted := rec.Body.String()
+
+	rejected := `{"repo_url":"https://example.com","registries":["bogus"],"api_throttle":-1}`
+	if rec := do(t, h, http.MethodPut, "/api/config", rejected, auth); rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid PUT status = %d (%s)", rec.Code, rec.Body.String())
+	}
+	if got := srv.config.Database.Registries; len(got) != 2 || got[0] != "ripe" {
+		t.Errorf("live registries = %v after a rejected PUT", got)
+	}
+
+	// The redacted configuration sent back keeps the stored secrets
+	edited := strings.Replace(redacted, `"api_throttle":0`, `"api_throttle":5`, 1)
+	if rec := do(t, h, http.MethodPut, "/api/config", edited, auth); rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "secret") {
+		t.Fatalf("PUT = %d %s", rec.Code, rec.Body.String())
+	}
+	db := srv.config.Database
+	if db.IPInfoToken != "tok-secret" || db.APIUsers[0].Key != "key-secret" || db.APIThrottle != 5 {
+		t.Errorf("saved config = token %q, key %q, throttle %v", db.IPInfoToken, db.APIUsers[0].Key, db.APIThrottle)
+	}
+}
+
+func TestStart_RefusesExposedAPIWithoutCredentials(t *testing.T) {
+	for _, c := range []struct {
+		listen string
+		db     models.DatabaseConfig
+		ok     bool
+	}{
+		{"127.0.0.1:0", models.DatabaseConfig{}, true},
+		{"0.0.0.0:0", models.DatabaseConfig{}, false},
+		{":0", models.DatabaseConfig{}, false},
+		{"0.0.0.0:0", models.DatabaseConfig{APIKey: "k"}, true},
+	} {
+		c.db.APIListen = c.listen
+		srv := newTestServer(t, c.db)
+		err := srv.Start()
+		if (err == nil) != c.ok {
+			t.Errorf("Start on %s (key %q): err = %v", c.listen, c.db.APIKey, err)
+		}
+		srv.Close()
+	}
+}
+
+func TestPublish_RecordsAdminAsApprover(t *testing.T) {
+	srv := newTestServer(t, models.DatabaseConfig{
+		RepoURL:    "https://example.com",
+		ResultsDir: "results",
+		APIUsers:   []models.APIUser{{Name: "adm", Key: "x", Role: models.RoleAdmin}},
+	})
+	srv.SetRecords([]models.ScannerData{{IPOrCIDR: "192.0.2.1", State: models.StateBlocked}})
+
+	rec := do(t, srv.Handler(), http.MethodPost, "/api/publish", "", map[string]string{"Authorization": "Bearer x"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("publish status = %d (%s)", rec.Code, rec.Body.String())
+	}
+	approvals, err := srv.ext.Approvals()
+	if err != nil || len(approvals) != 1 || approvals[0].Approver != "adm" || approvals[0].Total != 1 {
+		t.Errorf("approvals = %+v, %v", approvals, err)
+	}
+}
+
+func TestPublish_DryRunAndCollateralRefusal(t *testing.T) {
+	protected := filepath.Join(t.TempDir(), "protected.txt")
+	if err := os.WriteFile(protected, []byte("192.0.2.0/24 own Office\n"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	srv := newTestServer(t, models.DatabaseConfig{
+		RepoURL:                 "https://example.com",
+		ResultsDir:              "results",
+		APIUsers:                []models.APIUser{{Name: "adm", Key: "x", Role: models.RoleAdmin}},
+		ProtectedPrefixesFile:   protected,
+		CollateralCriticalKinds: []string{"own"},
+	})
+	srv.SetRecords([]models.ScannerData{{IPOrCIDR: "192.0.2.1", State: models.StateBlocked}})
+	auth := map[string]string{"Authorization": "Bearer x"}
+
+	rec := do(t, srv.Handler(), http.MethodGet, "/api/publish", "", auth)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"publishable":false`) ||
+		!strings.Contains(rec.Body.String(), `"critical":true`) {
+		t.Fatalf("dry run = %d %s, want the critical match and publishable false", rec.Code, rec.Body.String())
+	}
+	rec = do(t, srv.Handler(), http.MethodPost, "/api/publish", "", auth)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("publish status = %d (%s), want 409", rec.Code, rec.Body.String())
+	}
+	if approvals, _ := srv.ext.Approvals(); len(approvals) != 0 {
+		t.Errorf("refused publication recorded: %+v", approvals)
+	}
+}
+
+// -------------------------------------------------------
+// Enrichment service
+// -------------------------------------------------------
+
+// stubEnricher fills CountryCode, fails for 192.0.2.66 and waits for
+// release when it is set.
+type stubEnricher struct{ release chan struct{} }
+
+func (e stubEnricher) Enrich(ctx context.Context, d *models.ScannerData) error {
+	if e.release != nil {
+		select {
+		case <-e.release:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if d.IPOrCIDR == "192.0.2.66" {
+		return errors.New("registry unavailable")
+	}
+	d.CountryCode = "FR"
+	return nil
+}
+
+func TestEnrich_IPList(t *testing.T) {
+	srv := newTestServer(t, models.DatabaseConfig{})
+	srv.ext.SetEnricher(stubEnricher{})
+	h := srv.Handler()
+
+	rec := do(t, h, http.MethodPost, "/api/enrich", `{"ips":["192.0.2.1"," 192.0.2.66","192.0.2.1","2001:db8::/48"]}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (%s)", rec.Code, rec.Body.String())
+	}
+	var res enrichResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Results) != 3 || res.Results[0].CountryCode != "FR" || res.Results[2].IPOrCIDR != "2001:db8::/48" {
+		t.Errorf("results = %+v, want the 3 distinct IPs enriched", res.Results)
+	}
+	if len(res.Errors) != 1 || res.Errors["192.0.2.66"] == "" {
+		t.Errorf("errors = %v, want the failure of 192.0.2.66", res.Errors)
+	}
+
+	for body, want := range map[string]int{
+		`{"ips":[]}`:                 http.StatusBadRequest,
+		`{"ips":["not-an-ip"]}`:      http.StatusBadRequest,
+		`{}`:                         http.StatusBadRequest,
+		`{"ips":["192.0.2.1"],"x":}`: http.StatusBadRequest,
+	} {
+		if rec := do(t, h, http.MethodPost, "/api/enrich", body, nil); rec.Code != want {
+			t.Errorf("%s: status = %d, want %d", body, rec.Code, want)
+		}
+	}
+	many := make([]string, enrichMaxIPs+1)
+	for i := range many {
+		many[i] = fmt.Sprintf("10.0.%d.%d", i/256, i%256)
+	}
+	body, _ := json.Marshal(map[string][]string{"ips": many})
+	if rec := do(t, h, http.MethodPost, "/api/enrich", string(body), nil); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("%d IPs: status = %d, want 413", len(many), rec.Code)
+	}
+}
+
+func TestEnrich_LockedRecord(t *testing.T) {
+	srv := newTestServer(t, models.DatabaseConfig{})
+	srv.ext.SetEnricher(stubEnricher{})
+	srv.SetRecords([]models.ScannerData{{IPOrCIDR: "192.0.2.1"}})
+	if _, err := srv.ext.LockRecords([]string{"192.0.2.1"}, "alice", "case 42"); err != nil {
+		t.Fatal(err)
+	}
+	h := srv.Handler()
+
+	if rec := do(t, h, http.MethodPost, "/api/enrich", `{"ip":"192.0.2.1"}`, nil); rec.Code != http.StatusConflict {
+		t.Errorf("status = %d (%s), want 409 for a locked record", rec.Code, rec.Body.String())
+	}
+}
+
+func TestEnrich_Job(t *testing.T) {
+	srv := newTestServer(t, models.DatabaseConfig{})
+	release := make(chan struct{})
+	srv.ext.SetEnricher(stubEnricher{release: release})
+	h := srv.Handler()
+
+	ips := make([]string, enrichSyncMax+1)
+	for i := range ips {
+		ips[i] = fmt.Sprintf("198.51.100.%d", i+1)
+	}
+	body, _ := json.Marshal(map[string][]string{"ips": ips})
+	rec := do(t, h, http.MethodPost, "/api/enrich", string(body), nil)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d (%s), want 202", rec.Code, rec.Body.String())
+	}
+	var job enrichJob
+	if err := json.Unmarshal(rec.Body.Bytes(), &job); err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != jobRunning || job.Total != len(ips) || rec.Header().Get("Location") != job.URL {
+		t.Fatalf("job = %+v, Location %q", job, rec.Header().Get("Location"))
+	}
+
+	close(release)
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Status == jobRunning && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		rec = do(t, h, http.MethodGet, job.URL, "", nil)
+		job = enrichJob{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if job.Status != jobDone || job.Done != len(ips) || len(job.Results) != len(ips) || job.Results[0].CountryCode != "FR" || job.FinishedAt == nil {
+		t.Errorf("finished job = %+v", job)
+	}
+	if rec := do(t, h, http.MethodGet, "/api/enrich/jobs/unknown", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown job: status = %d, want 404", rec.Code)
+	}
+
+	// A job can be canceled, and Close stops the others
+	srv.ext.SetEnricher(stubEnricher{release: make(chan struct{})})
+	rec = do(t, h, http.MethodPost, "/api/enrich", `{"ips":["192.0.2.1"],"async":true}`, nil)
+	var canceled enrichJob
+	_ = json.Unmarshal(rec.Body.Bytes(), &canceled)
+	do(t, h, http.MethodDelete, canceled.URL, "", nil)
+	deadline = time.Now().Add(5 * time.Second)
+	for canceled.Status != jobCanceled && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		_ = json.Unmarshal(do(t, h, http.MethodGet, canceled.URL, "", nil).Body.Bytes(), &canceled)
+	}
+	if canceled.Status != jobCanceled {
+		t.Errorf("canceled job status = %s", canceled.Status)
+	}
+}
+
+func TestEnrich_JobCancelOwnerOrAdmin(t *testing.T) {
+	srv := newTestServer(t, models.DatabaseConfig{
+		RepoURL: "https://example.com",
+		APIUsers: []models.APIUser{
+			{Name: "ana", Key: "a", Role: models.RoleAnalyst},
+			{Name: "bob", Key: "b", Role: models.RoleAnalyst},
+			{Name: "adm", Key: "x", Role: models.RoleAdmin},
+		},
+	})
+	srv.ext.SetEnricher(stubEnricher{release: make(chan struct{})})
+	defer srv.Close()
+	h := srv.Handler()
+
+	start := func() enrichJob {
+		rec := do(t, h, http.MethodPost, "/api/enrich", `{"ips":["192.0.2.1"],"async":true}`, map[string]string{"X-API-Key": "a"})
+		var job enrichJob
+		if err := json.Unmarshal(rec.Body.Bytes(), &job); err != nil || job.URL == "" {
+			t.Fatalf("start job: %d %s", rec.Code, rec.Body.String())
+		}
+		return job
+	}
+
+	job := start()
+	if rec := do(t, h, http.MethodGet, job.URL, "", map[string]string{"X-API-Key": "b"}); rec.Code != http.StatusForbidden {
+		t.Errorf("status read by another analyst: status = %d, want 403", rec.Code)
+	}
+	if rec := do(t, h, http.MethodGet, job.URL, "", map[string]string{"X-API-Key": "x"}); rec.Code != http.StatusOK {
+		t.Errorf("status read by an admin: status = %d, want 200", rec.Code)
+	}
+	if rec := do(t, h, http.MethodDelete, job.URL, "", map[string]string{"X-API-Key": "b"}); rec.Code != http.StatusForbidden {
+		t.Errorf("cancel by another analyst: status = %d, want 403", rec.Code)
+	}
+	if rec := do(t, h, http.MethodDelete, job.URL, "", map[string]string{"X-API-Key": "a"}); rec.Code != http.StatusOK {
+		t.Errorf("cancel by the owner: status = %d, want 200", rec.Code)
+	}
+	job = start()
+	if rec := do(t, h, http.MethodDelete, job.URL, "", map[string]string{"X-API-Key": "x"}); rec.Code != http.StatusOK {
+		t.Errorf("cancel by an admin: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestEnrich_JobLimits(t *testing.T) {
+	srv := newTestServer(t, models.DatabaseConfig{
+		RepoURL: "https://example.com",
+		APIUsers: []models.APIUser{
+			{Name: "ana", Key: "a", Role: models.RoleAnalyst},
+			{Name: "bob", Key: "b", Role: models.RoleAnalyst},
+		},
+	})
+	srv.ext.SetEnricher(stubEnricher{release: make(chan struct{})})
+	defer srv.Close()
+	h := srv.Handler()
+
+	start := func(key string) int {
+		return do(t, h, http.MethodPost, "/api/enrich", `{"ips":["192.0.2.1"],"async":true}`, map[string]string{"X-API-Key": key}).Code
+	}
+	for i := 0; i < enrichJobsPerUser; i++ {
+		if code := start("a"); code != http.StatusAccepted {
+			t.Fatalf("job %d: status = %d, want 202", i+1, code)
+		}
+	}
+	if code := start("a"); code != http.StatusTooManyRequests {
+		t.Errorf("job over the per-user limit: status = %d, want 429", code)
+	}
+	if code := start("b"); code != http.StatusAccepted {
+		t.Errorf("job of another user: status = %d, want 202", code)
+	}
+
+	var jobs enrichJobs
+	for i := 0; i < enrichJobsRunning; i++ {
+		if err := jobs.add(&enrichJob{ID: fmt.Sprint(i), Owner: fmt.Sprint("user", i), Status: jobRunning}); err != nil {
+			t.Fatalf("add %d: %v", i, err)
+		}
+	}
+	if err := jobs.add(&enrichJob{ID: "over", Owner: "new", Status: jobRunning}); err == nil {
+		t.Error("add over the server limit should fail")
+	}
+}
+
+// -------------------------------------------------------
+// Panics
+// -------------------------------------------------------
+
+func TestRecoverPanics_Returns500AndWritesReport(t *testing.T) {
+	srv := newTestServer(t, models.DatabaseConfig{LogsDir: "logs"})
+	h := srv.recoverPanics(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("handler exploded")
+	}))
+
+	rec := do(t, h, http.MethodGet, "/api/records", "", nil)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rec.Code)
+	}
+	files, _ := filepath.Glob(filepath.Join("logs", "crash", "crash_*.txt"))
+	if len(files) != 1 {
+		t.Errorf("expected one crash report, got %v", files)
+	}
+}
@@ -0,0 +1,281 @@
+// Package store persists scanner records in a SQLite database, indexed on
+// IP, scanner, country and ASN, so the GUI restarts on the last dataset
+// without re-reading CSV files and searches it without scanning every
+// record.
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// DefaultPath is the database used when no store_path is configured.
+var DefaultPath = filepath.Join("build", "data", "records.db")
+
+// schema creates the records table. seq keeps the order of the dataset;
+// record is the JSON of the whole record, the other columns are copies of
+// its fields for the indexes.
+const schema = `
+CREATE TABLE IF NOT EXISTS records (
+	seq        INTEGER PRIMARY KEY,
+	key        TEXT NOT NULL,
+	ip         TEXT NOT NULL,
+	scanner    TEXT NOT NULL,
+	country    TEXT NOT NULL,
+	asn        TEXT NOT NULL,
+	risk       TEXT NOT NULL,
+	confidence TEXT NOT NULL,
+	record     TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS records_key ON records(key);
+CREATE INDEX IF NOT EXISTS records_ip ON records(ip);
+CREATE INDEX IF NOT EXISTS records_scanner ON records(scanner);
+CREATE INDEX IF NOT EXISTS records_country ON records(country);
+CREATE INDEX IF NOT EXISTS records_asn ON records(asn);
+`
+
+// Store is a SQLite database of scanner records. It is safe for concurrent
+// use.
+type Store struct {
+	db   *sql.DB
+	path string
+}
+
+// Open opens the database at path, creating it and its directory if needed.
+func Open(path string) (*Store, error) {
+	if path == "" {
+		path = DefaultPath
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("creating store directory: %w", err)
+	}
+	db, err := sql.Open("sqlite3", path+"?_journal_mode=WAL&_busy_timeout=5000")
+	if err != nil {
+		return nil, fmt.Errorf("opening store %s: %w", path, err)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating store schema in %s: %w", path, err)
+	}
+	return &Store{db: db, path: path}, nil
+}
+
+// Path returns the file of the database.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Key identifies a record: its IP or CIDR and its scanner.
+func Key(item models.ScannerData) string {
+	return item.IPOrCIDR + "|" + item.ScannerName
+}
+
+// Replace makes data the whole content of the store, in one transaction.
+func (s *Store) Replace(data []models.ScannerData) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM records`); err != nil {
+			return err
+		}
+		stmt, err := tx.Prepare(`INSERT INTO records (seq, key, ip, scanner, country, asn, risk, confidence, record) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for i, item := range data {
+			args, err := columns(item)
+			if err != nil {
+				return err
+			}
+			if _, err := stmt.Exec(append([]any{i}, args...)...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Upsert updates the records with the keys of items, and appends the items
+// no record has the key of.
+func (s *Store) Upsert(items ...models.ScannerData) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		for _, item := range items {
+			args, err := columns(item)
+			if err != nil {
+				return err
+			}
+			res, err := tx.Exec(`UPDATE records SET key = ?, ip = ?, scanner = ?, country = ?, asn = ?, risk = ?, confidence = ?, record = ? WHERE key = ?`,
+				append(args, Key(item))...)
+			if err != nil {
+				return err
+			}
+			if n, _ := res.RowsAffected(); n > 0 {
+				continue
+			}
+			if _, err := tx.Exec(`INSERT INTO records (seq, key, ip, scanner, country, asn, risk, confidence, record)
+				VALUES ((SELECT COALESCE(MAX(seq), -1) + 1 FROM records), ?, ?, ?, ?, ?, ?, ?, ?)`, args...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Delete removes the records with the given keys.
+func (s *Store) Delete(keys ...string) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		for _, k := range keys {
+			if _, err := tx.Exec(`DELETE FROM records WHERE key = ?`, k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Query selects records. Empty fields match everything; Text matches a
+// substring of the IP or the scanner name, ignoring case. Limit 0 returns
+// every match.
+type Query struct {
+	Text       string
+	Country    string
+	Scanner    string
+	ASN        string
+	Risk       string
+	Confidence string // "none" for unattributed records
+	Offset     int
+	Limit      int
+}
+
+// where returns the WHERE clause of q and its arguments.
+func (q Query) where() (string, []any) {
+	var conds []string
+	var args []any
+	if q.Text != "" {
+		pattern := "%" + escapeLike(strings.ToLower(q.Text)) + "%"
+		conds = append(conds, `(lower(ip) LIKE ? ESCAPE '\' OR lower(scanner) LIKE ? ESCAPE '\')`)
+		args = append(args, pattern, pattern)
+	}
+	for _, c := range []struct{ col, val string }{
+		{"country", q.Country},
+		{"scanner", q.Scanner},
+		{"asn", normalizeASN(q.ASN)},
+		{"risk", q.Risk},
+	} {
+		if c.val != "" {
+			conds = append(conds, c.col+" = ?")
+			args = append(args, c.val)
+		}
+	}
+	switch strings.ToLower(q.Confidence) {
+	case "":
+	case "none":
+		conds = append(conds, "confidence = ''")
+	default:
+		conds = append(conds, "confidence = ?")
+		args = append(args, strings.ToLower(q.Confidence))
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// Count returns how many records match q, ignoring its Offset and Limit.
+func (s *Store) Count(q Query) (int, error) {
+	where, args := q.where()
+	var n int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM records`+where, args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("counting records: %w", err)
+	}
+	return n, nil
+}
+
+// Search returns the records matching q in dataset order.
+func (s *Store) Search(q Query) ([]models.ScannerData, error) {
+	where, args := q.where()
+	query := `SELECT record FROM records` + where + ` ORDER BY seq`
+	if q.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d OFFSET %d", q.Limit, max(q.Offset, 0))
+	} else if q.Offset > 0 {
+		query += fmt.Sprintf(" LIMIT -1 OFFSET %d", q.Offset)
+	}
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("searching records: %w", err)
+	}
+	defer rows.Close()
+	var out []models.ScannerData
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			return nil, fmt.Errorf("reading record: %w", err)
+		}
+		var item models.ScannerData
+		if err := json.Unmarshal([]byte(raw), &item); err != nil {
+			return nil, fmt.Errorf("decoding record: %w", err)
+		}
+		out = append(out, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("searching records: %w", err)
+	}
+	return out, nil
+}
+
+// All returns every record in dataset order.
+func (s *Store) All() ([]models.ScannerData, error) {
+	return s.Search(Query{})
+}
+
+// inTx runs fn in a transaction, committed when fn returns nil.
+func (s *Store) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("starting store transaction: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		return errors.Join(fmt.Errorf("writing records: %w", err), tx.Rollback())
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing records: %w", err)
+	}
+	return nil
+}
+
+// columns returns the column values of item after seq, in schema order.
+func columns(item models.ScannerData) ([]any, error) {
+	raw, err := json.Marshal(item)
+	if err != nil {
+		return nil, fmt.Errorf("encoding record %s: %w", item.IPOrCIDR, err)
+	}
+	return []any{Key(item), item.IPOrCIDR, item.ScannerName, item.CountryCode, normalizeASN(item.ASN),
+		item.RiskLevel, strings.ToLower(item.AttributionConfidence), string(raw)}, nil
+}
+
+// normalizeASN spells an ASN "AS<number>", whatever the case or prefix.
+func normalizeASN(asn string) string {
+	asn = strings.ToUpper(strings.TrimSpace(asn))
+	if asn == "" || strings.HasPrefix(asn, "AS") {
+		return asn
+	}
+	return "AS" + asn
+}
+
+// escapeLike escapes the LIKE wildcards of s.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,138 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// openTestStore opens a store in a temporary directory, closed with the test.
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), "data", "records.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func testRecords() []models.ScannerData {
+	return []models.ScannerData{
+		{IPOrCIDR: "192.0.2.1", ScannerName: "shodan", CountryCode: "US", ASN: "AS10439", RiskLevel: "high", AttributionConfidence: models.ConfidenceHigh, Tags: []string{"extracted"}},
+		{IPOrCIDR: "198.51.100.0/24", ScannerName: "censys", CountryCode: "US", ASN: "398324", RiskLevel: "medium"},
+		{IPOrCIDR: "2001:db8::1", ScannerName: "shodan", CountryCode: "NL", ASN: "as10439", RiskLevel: "high", AttributionConfidence: models.ConfidenceHigh},
+		{IPOrCIDR: "203.0.113.5", ScannerName: "other", CountryCode: "FR", RiskLevel: "unknown"},
+	}
+}
+
+func TestReplaceAndAll_KeepOrderAndFields(t *testing.T) {
+	s := openTestStore(t)
+	if err := s.Replace(testRecords()); err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+	all, err := s.All()
+	if err != nil {
+		t.Fatalf("All: %v", err)
+	}
+	if len(all) != 4 || all[1].IPOrCIDR != "198.51.100.0/24" || all[0].Tags[0] != "extracted" {
+		t.Fatalf("All = %+v", all)
+	}
+
+	if err := s.Replace(testRecords()[:1]); err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+	if n, _ := s.Count(Query{}); n != 1 {
+		t.Errorf("Count after a second Replace = %d, want 1", n)
+	}
+}
+
+func TestSearch_Filters(t *testing.T) {
+	s := openTestStore(t)
+	if err := s.Replace(testRecords()); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name string
+		q    Query
+		want []string
+	}{
+		{"text on IP", Query{Text: "2001:DB8"}, []string{"2001:db8::1"}},
+		{"text on scanner", Query{Text: "CEN"}, []string{"198.51.100.0/24"}},
+		{"LIKE wildcards are literal", Query{Text: "%"}, nil},
+		{"country", Query{Country: "US"}, []string{"192.0.2.1", "198.51.100.0/24"}},
+		{"scanner and country", Query{Scanner: "shodan", Country: "NL"}, []string{"2001:db8::1"}},
+		{"ASN in any spelling", Query{ASN: "10439"}, []string{"192.0.2.1", "2001:db8::1"}},
+		{"risk", Query{Risk: "unknown"}, []string{"203.0.113.5"}},
+		{"no confidence", Query{Confidence: "none"}, []string{"198.51.100.0/24", "203.0.113.5"}},
+		{"page", Query{Offset: 1, Limit: 2}, []string{"198.51.100.0/24", "2001:db8::1"}},
+	} {
+		got, err := s.Search(tc.q)
+		if err != nil {
+			t.Fatalf("%s: Search: %v", tc.name, err)
+		}
+		var ips []string
+		for _, item := range got {
+			ips = append(ips, item.IPOrCIDR)
+		}
+		if len(ips) != len(tc.want) {
+			t.Errorf("%s: Search = %v, want %v", tc.name, ips, tc.want)
+			continue
+		}
+		for i := range ips {
+			if ips[i] != tc.want[i] {
+				t.Errorf("%s: Search = %v, want %v", tc.name, ips, tc.want)
+				break
+			}
+		}
+	}
+	if n, err := s.Count(Query{Country: "US", Limit: 1}); err != nil || n != 2 {
+		t.Errorf("Count = %d, %v; want 2 whatever the limit", n, err)
+	}
+}
+
+func TestUpsertAndDelete(t *testing.T) {
+	s := openTestStore(t)
+	if err := s.Replace(testRecords()); err != nil {
+		t.Fatal(err)
+	}
+	changed := testRecords()[1]
+	changed.CountryCode = "DE"
+	added := models.ScannerData{IPOrCIDR: "192.0.2.99", ScannerName: "rapid7", CountryCode: "GB"}
+	if err := s.Upsert(changed, added); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+	all, _ := s.All()
+	if len(all) != 5 || all[1].CountryCode != "DE" || all[4].IPOrCIDR != "192.0.2.99" {
+		t.Fatalf("All after Upsert = %+v", all)
+	}
+
+	if err := s.Delete(Key(changed), Key(added)); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if n, _ := s.Count(Query{}); n != 3 {
+		t.Errorf("Count after Delete = %d, want 3", n)
+	}
+}
+
+func TestOpen_ReopensExistingData(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.db")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Replace(testRecords()); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	s, err = Open(path)
+	if err != nil {
+		t.Fatalf("reopening: %v", err)
+	}
+	defer s.Close()
+	if n, _ := s.Count(Query{}); n != 4 {
+		t.Errorf("Count after reopening = %d, want 4", n)
+	}
+}
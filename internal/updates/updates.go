@@ -0,0 +1,106 @@
+// Package updates checks the GitHub releases API for a version newer than
+// the running one. The check is made once at startup unless
+// disable_update_check is set in the configuration.
+package updates
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LatestReleaseURL is the GitHub API endpoint of the latest release.
+const LatestReleaseURL = "https://api.github.com/repos/mo0ogly/LiaCheckScanner_Go/releases/latest"
+
+// Release is the subset of a GitHub release shown to the user.
+type Release struct {
+	TagName     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	Body        string    `json:"body"` // release notes (Markdown)
+	HTMLURL     string    `json:"html_url"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// Checker queries a releases endpoint.
+type Checker struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewChecker returns a Checker for the project's GitHub releases with a
+// short timeout, so a slow network never delays the user.
+func NewChecker() *Checker {
+	return &Checker{URL: LatestReleaseURL, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Latest fetches the latest published release.
+func (c *Checker) Latest() (*Release, error) {
+	req, err := http.NewRequest(http.MethodGet, c.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("building release request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching latest release: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching latest release: HTTP %d", resp.StatusCode)
+	}
+	var rel Release
+	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
+		return nil, fmt.Errorf("decoding release: %w", err)
+	}
+	return &rel, nil
+}
+
+// Check returns the latest release when it is newer than current, or nil
+// when current is up to date.
+func (c *Checker) Check(current string) (*Release, error) {
+	rel, err := c.Latest()
+	if err != nil {
+		return nil, err
+	}
+	if !NewerThan(rel.TagName, current) {
+		return nil, nil
+	}
+	return rel, nil
+}
+
+// NewerThan reports whether version a is greater than b. Both are dotted
+// numeric versions with an optional "v" prefix; a pre-release or build
+// suffix ("-rc1", "+meta") is ignored. Missing components count as zero.
+func NewerThan(a, b string) bool {
+	pa, pb := parseVersion(a), parseVersion(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			return x > y
+		}
+	}
+	return false
+}
+
+// parseVersion splits "v1.2.3-rc1" into [1 2 3]. Non-numeric parts count as 0.
+func parseVersion(v string) []int {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	var parts []int
+	for _, p := range strings.Split(v, ".") {
+		n, _ := strconv.Atoi(p)
+		parts = append(parts, n)
+	}
+	return parts
+}
@@ -0,0 +1,63 @@
+package updates
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewerThan(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"v1.1.0", "1.0.0", true},
+		{"1.0.10", "1.0.9", true},
+		{"v1.0.0", "1.0.0", false},
+		{"1.0", "1.0.0", false},
+		{"1.0.1", "1.0", true},
+		{"v2.0.0-rc1", "1.9.9", true},
+		{"0.9.0", "1.0.0", false},
+	}
+	for _, tt := range tests {
+		if got := NewerThan(tt.a, tt.b); got != tt.want {
+			t.Errorf("NewerThan(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func newTestChecker(t *testing.T, status int, body string) *Checker {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return &Checker{URL: srv.URL, Client: srv.Client()}
+}
+
+func TestCheck_NewerRelease(t *testing.T) {
+	c := newTestChecker(t, http.StatusOK, `{"tag_name":"v1.2.0","name":"1.2.0","body":"- faster RDAP","html_url":"https://example.com/r"}`)
+	rel, err := c.Check("1.0.0")
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if rel == nil || rel.TagName != "v1.2.0" || rel.Body != "- faster RDAP" {
+		t.Errorf("Check = %+v, want the v1.2.0 release", rel)
+	}
+}
+
+func TestCheck_UpToDate(t *testing.T) {
+	c := newTestChecker(t, http.StatusOK, `{"tag_name":"v1.0.0"}`)
+	rel, err := c.Check("1.0.0")
+	if err != nil || rel != nil {
+		t.Errorf("Check = %+v, %v; want nil, nil", rel, err)
+	}
+}
+
+func TestCheck_HTTPError(t *testing.T) {
+	c := newTestChecker(t, http.StatusForbidden, `{"message":"rate limited"}`)
+	if _, err := c.Check("1.0.0"); err == nil {
+		t.Error("Check should fail on HTTP 403")
+	}
+}
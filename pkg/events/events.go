@@ -0,0 +1,108 @@
+// Package events provides a small publish/subscribe bus used to report
+// pipeline progress (runs, source syncs, enrichment counts, warnings) to the
+// GUI, the logger and the CLI progress reporter, without coupling the
+// producer to them.
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Type identifies the kind of event published on the bus.
+type Type string
+
+const (
+	// RunStarted is published when an extraction or enrichment run begins.
+	RunStarted Type = "run_started"
+	// SourceSynced is published once the upstream sources are up to date.
+	SourceSynced Type = "source_synced"
+	// SourceProgress reports a progress line of a repository clone or
+	// fetch in Message; Count of Total objects are done when the line gives them.
+	SourceProgress Type = "source_progress"
+	// RecordsParsed is published after IPs were parsed from the sources; Count holds the number of IPs.
+	RecordsParsed Type = "records_parsed"
+	// RecordsEnriched reports enrichment progress; Count of Total records are done.
+	RecordsEnriched Type = "records_enriched"
+	// RecordUpdated is published after a single record changed, e.g. once it
+	// was enriched; Message holds its IP or CIDR.
+	RecordUpdated Type = "record_updated"
+	// Warning reports a non-fatal problem during a run.
+	Warning Type = "warning"
+	// RunCompleted is published when a run finishes successfully; Count holds the number of records.
+	RunCompleted Type = "run_completed"
+	// RunFailed is published when a run aborts with an error.
+	RunFailed Type = "run_failed"
+	// ConfigApplied is published after new settings took effect without a restart.
+	ConfigApplied Type = "config_applied"
+)
+
+// Event is a single structured progress notification.
+type Event struct {
+	Type    Type      `json:"type"`
+	Time    time.Time `json:"time"`
+	Source  string    `json:"source"`
+	Message string    `json:"message,omitempty"`
+	Count   int       `json:"count,omitempty"`
+	Total   int       `json:"total,omitempty"`
+}
+
+// Handler receives published events. Handlers are called synchronously on
+// the publishing goroutine and must not block.
+type Handler func(Event)
+
+// subscription is a Handler with the ID its unsubscribe function removes.
+type subscription struct {
+	id int
+	h  Handler
+}
+
+// Bus dispatches events to all current subscribers, in the order they
+// subscribed. The zero value is not usable; create buses with NewBus.
+type Bus struct {
+	mu     sync.RWMutex
+	nextID int
+	subs   []subscription
+}
+
+// NewBus creates an empty event bus.
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers h for all future events and returns a function that
+// removes the subscription.
+func (b *Bus) Subscribe(h Handler) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.nextID
+	b.nextID++
+	b.subs = append(b.subs, subscription{id: id, h: h})
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for i, sub := range b.subs {
+			if sub.id == id {
+				b.subs = append(b.subs[:i:i], b.subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish delivers ev to every subscriber in subscription order. A zero
+// Time is set to now. Publishing on a nil bus is a no-op.
+func (b *Bus) Publish(ev Event) {
+	if b == nil {
+		return
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	b.mu.RLock()
+	subs := b.subs
+	b.mu.RUnlock()
+	for _, sub := range subs {
+		sub.h(ev)
+	}
+}
@@ -0,0 +1,90 @@
+package events
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestBus_PublishReachesAllSubscribers(t *testing.T) {
+	bus := NewBus()
+	var a, b []Event
+	bus.Subscribe(func(ev Event) { a = append(a, ev) })
+	bus.Subscribe(func(ev Event) { b = append(b, ev) })
+
+	bus.Publish(Event{Type: RunStarted, Source: "test"})
+
+	if len(a) != 1 || len(b) != 1 {
+		t.Fatalf("subscribers received %d and %d events, want 1 each", len(a), len(b))
+	}
+	if a[0].Type != RunStarted || a[0].Source != "test" {
+		t.Errorf("unexpected event: %+v", a[0])
+	}
+	if a[0].Time.IsZero() {
+		t.Error("Publish should stamp a zero Time")
+	}
+}
+
+func TestBus_Unsubscribe(t *testing.T) {
+	bus := NewBus()
+	count := 0
+	unsubscribe := bus.Subscribe(func(Event) { count++ })
+
+	bus.Publish(Event{Type: Warning})
+	unsubscribe()
+	bus.Publish(Event{Type: Warning})
+
+	if count != 1 {
+		t.Errorf("handler called %d times, want 1", count)
+	}
+}
+
+func TestBus_DeliversInSubscriptionOrder(t *testing.T) {
+	bus := NewBus()
+	var order []int
+	var unsubscribe []func()
+	for i := 0; i < 5; i++ {
+		i := i
+		unsubscribe = append(unsubscribe, bus.Subscribe(func(Event) { order = append(order, i) }))
+	}
+	unsubscribe[1]()
+	bus.Subscribe(func(Event) { order = append(order, 5) })
+
+	for run := 0; run < 20; run++ {
+		order = nil
+		bus.Publish(Event{Type: Warning})
+		if fmt.Sprint(order) != "[0 2 3 4 5]" {
+			t.Fatalf("delivery order = %v, want [0 2 3 4 5]", order)
+		}
+	}
+}
+
+func TestBus_NilPublishIsNoop(t *testing.T) {
+	var bus *Bus
+	bus.Publish(Event{Type: RunCompleted})
+}
+
+func TestBus_ConcurrentPublish(t *testing.T) {
+	bus := NewBus()
+	var mu sync.Mutex
+	total := 0
+	bus.Subscribe(func(ev Event) {
+		mu.Lock()
+		total += ev.Count
+		mu.Unlock()
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bus.Publish(Event{Type: RecordsEnriched, Count: 1})
+		}()
+	}
+	wg.Wait()
+
+	if total != 50 {
+		t.Errorf("total = %d, want 50", total)
+	}
+}
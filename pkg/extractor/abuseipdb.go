@@ -0,0 +1,245 @@
+package extractor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// abuseIPDBReportURL is the AbuseIPDB single-IP report endpoint.
+const abuseIPDBReportURL = "https://api.abuseipdb.com/api/v2/report"
+
+// defaultAbuseIPDBQuota is the daily report limit of a free AbuseIPDB account.
+const defaultAbuseIPDBQuota = 1000
+
+// abuseIPDBReportInterval is the minimum time between two reports of one IP.
+const abuseIPDBReportInterval = 24 * time.Hour
+
+// AbuseIPDBCategories returns the categories reported for a scanner type:
+// the configured mapping, or 14 (Port Scan) for types it does not list.
+func AbuseIPDBCategories(t models.ScannerType, mapping map[string]string) string {
+	if c := strings.TrimSpace(mapping[string(t)]); c != "" {
+		return c
+	}
+	return abuseIPDBPortScan
+}
+
+// AbuseReportSummary is the outcome of one AbuseIPDB reporting run.
+type AbuseReportSummary struct {
+	Reported       int  `json:"reported"`
+	Skipped        int  `json:"skipped"` // reported less than 24h ago, or a range
+	Failed         int  `json:"failed"`
+	QuotaLeft      int  `json:"quota_left"`
+	QuotaExhausted bool `json:"quota_exhausted"` // some IPs were left for the next day
+}
+
+// abuseReportState is the on-disk record of what was reported and of the
+// reports made on the current UTC day.
+type abuseReportState struct {
+	Day      string            `json:"day"`
+	Count    int               `json:"count"`
+	Reported map[string]string `json:"reported"` // IP -> RFC 3339 time of the last report
+}
+
+// abuseReportFile returns the path of the AbuseIPDB report store.
+func (e *Extractor) abuseReportFile() string {
+	if e.abuseReportPath != "" {
+		return e.abuseReportPath
+	}
+	return filepath.Join("build", "data", "abuseipdb_reports.json")
+}
+
+func (e *Extractor) loadAbuseReports() (*abuseReportState, error) {
+	state := &abuseReportState{Reported: map[string]string{}}
+	b, err := os.ReadFile(e.abuseReportFile())
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading AbuseIPDB report store: %w", err)
+	}
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, fmt.Errorf("decoding AbuseIPDB report store: %w", err)
+	}
+	if state.Reported == nil {
+		state.Reported = map[string]string{}
+	}
+	return state, nil
+}
+
+func (e *Extractor) saveAbuseReports(state *abuseReportState) error {
+	path := e.abuseReportFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating AbuseIPDB report directory: %w", err)
+	}
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding AbuseIPDB report store: %w", err)
+	}
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		return fmt.Errorf("writing AbuseIPDB report store: %w", err)
+	}
+	return nil
+}
+
+// AbuseIPDBQuotaLeft returns the number of reports still allowed today.
+func (e *Extractor) AbuseIPDBQuotaLeft() (int, error) {
+	state, err := e.loadAbuseReports()
+	if err != nil {
+		return 0, err
+	}
+	return abuseQuotaLeft(state, e.settings(), time.Now()), nil
+}
+
+// abuseQuotaLeft returns the reports left on now's UTC day.
+func abuseQuotaLeft(state *abuseReportState, cfg models.DatabaseConfig, now time.Time) int {
+	quota := cfg.AbuseIPDBDailyQuota
+	if quota <= 0 {
+		quota = defaultAbuseIPDBQuota
+	}
+	if state.Day != now.UTC().Format("2006-01-02") {
+		return quota
+	}
+	if left := quota - state.Count; left > 0 {
+		return left
+	}
+	return 0
+}
+
+// ReportToAbuseIPDB submits the blocked records of data to AbuseIPDB, one
+// report per IP. Reporting must be enabled with abuseipdb_report. An IP is
+// reported at most once every 24 hours, ranges are skipped, and the run
+// stops when the daily quota is used up or AbuseIPDB answers 429; the
+// remaining IPs are reported by a later run, as are those left when ctx is
+// canceled.
+func (e *Extractor) ReportToAbuseIPDB(ctx context.Context, data []models.ScannerData) (AbuseReportSummary, error) {
+	var sum AbuseReportSummary
+	cfg := e.settings()
+	if !cfg.AbuseIPDBReport {
+		return sum, fmt.Errorf("AbuseIPDB reporting is disabled (set abuseipdb_report)")
+	}
+	if cfg.AbuseIPDBKey == "" {
+		return sum, fmt.Errorf("AbuseIPDB reporting needs abuseipdb_key")
+	}
+	state, err := e.loadAbuseReports()
+	if err != nil {
+		return sum, err
+	}
+	now := time.Now()
+	today := now.UTC().Format("2006-01-02")
+	if state.Day != today {
+		state.Day, state.Count = today, 0
+	}
+	// Forget reports old enough to be made again
+	for ip, at := range state.Reported {
+		if t, err := time.Parse(time.RFC3339, at); err != nil || now.Sub(t) >= abuseIPDBReportInterval {
+			delete(state.Reported, ip)
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, item := range Enforceable(data) {
+		ip, ok := abuseIPDBAddress(item.IPOrCIDR)
+		if !ok || seen[ip] || state.Reported[ip] != "" {
+			sum.Skipped++
+			continue
+		}
+		seen[ip] = true
+		if ctx.Err() != nil {
+			break
+		}
+		if abuseQuotaLeft(state, cfg, now) == 0 {
+			sum.QuotaExhausted = true
+			break
+		}
+		status, err := e.postAbuseReport(ctx, cfg.AbuseIPDBKey, ip, AbuseIPDBCategories(item.ScannerType, cfg.AbuseIPDBCategories), abuseIPDBComment(item), item.LastSeen)
+		if status == http.StatusTooManyRequests {
+			e.logger.Warning("Extractor", "Quota AbuseIPDB atteint (HTTP 429), reprise au prochain lancement")
+			sum.QuotaExhausted = true
+			break
+		}
+		if err != nil {
+			e.logger.Warning("Extractor", fmt.Sprintf("Signalement AbuseIPDB echoue pour %s: %v", ip, err))
+			sum.Failed++
+			continue
+		}
+		state.Count++
+		state.Reported[ip] = now.UTC().Format(time.RFC3339)
+		sum.Reported++
+	}
+
+	sum.QuotaLeft = abuseQuotaLeft(state, cfg, now)
+	if err := e.saveAbuseReports(state); err != nil {
+		return sum, err
+	}
+	e.logger.Info("Extractor", fmt.Sprintf("AbuseIPDB: %d IPs signalees, %d ignorees, %d echecs, quota restant %d",
+		sum.Reported, sum.Skipped, sum.Failed, sum.QuotaLeft))
+	return sum, nil
+}
+
+// postAbuseReport sends one report and returns the HTTP status, or 0 when
+// the request failed before a response.
+func (e *Extractor) postAbuseReport(ctx context.Context, key, ip, categories, comment string, seen time.Time) (int, error) {
+	endpoint := e.abuseIPDBURL
+	if endpoint == "" {
+		endpoint = abuseIPDBReportURL
+	}
+	form := url.Values{"ip": {ip}, "categories": {categories}, "comment": {comment}}
+	if !seen.IsZero() {
+		form.Set("timestamp", seen.UTC().Format(time.RFC3339))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return 0, fmt.Errorf("building AbuseIPDB request: %w", err)
+	}
+	req.Header.Set("Key", key)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := e.apiClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("AbuseIPDB request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return resp.StatusCode, fmt.Errorf("AbuseIPDB http %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return resp.StatusCode, nil
+}
+
+// abuseIPDBAddress returns the single address of ipOrCIDR; AbuseIPDB
+// rejects ranges, so only /32 and /128 prefixes are accepted.
+func abuseIPDBAddress(ipOrCIDR string) (string, bool) {
+	ip := ipOrCIDR
+	if i := strings.IndexByte(ip, '/'); i >= 0 {
+		if bits := ip[i+1:]; bits != "32" && bits != "128" {
+			return "", false
+		}
+		ip = ip[:i]
+	}
+	return ip, ip != ""
+}
+
+// abuseIPDBComment describes a record for an AbuseIPDB report.
+func abuseIPDBComment(item models.ScannerData) string {
+	comment := fmt.Sprintf("Internet scanner %s (%s)", item.ScannerName, item.ScannerType)
+	if item.ASN != "" {
+		comment += ", " + item.ASN
+	}
+	if len(item.TargetPorts) > 0 {
+		comment += ", probing ports " + models.FormatPorts(item.TargetPorts)
+	}
+	if len(comment) > abuseIPDBMaxComment {
+		comment = comment[:abuseIPDBMaxComment]
+	}
+	return comment
+}
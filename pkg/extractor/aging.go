@@ -0,0 +1,85 @@
+package extractor
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// Default reputation aging, in days.
+const (
+	defaultRiskHalfLifeDays = 30
+	defaultStaleAfterDays   = 90
+)
+
+// agingDays returns the configured risk half-life and stale grace period,
+// falling back to the defaults.
+func (e *Extractor) agingDays() (halfLife, staleAfter int) {
+	cfg := e.settings()
+	halfLife, staleAfter = cfg.RiskHalfLifeDays, cfg.StaleAfterDays
+	if halfLife <= 0 {
+		halfLife = defaultRiskHalfLifeDays
+	}
+	if staleAfter <= 0 {
+		staleAfter = defaultStaleAfterDays
+	}
+	return halfLife, staleAfter
+}
+
+// RiskDecay returns the share of a record's risk lost since lastSeen: 0 when
+// just seen, 0.5 after one half-life, approaching 1 as the record ages.
+func RiskDecay(lastSeen, now time.Time, halfLife time.Duration) float64 {
+	if lastSeen.IsZero() || halfLife <= 0 || !now.After(lastSeen) {
+		return 0
+	}
+	return 1 - math.Pow(0.5, float64(now.Sub(lastSeen))/float64(halfLife))
+}
+
+// DecayedScore returns the AbuseIPDB confidence score of item weighted by
+// its age (see ApplyAging).
+func DecayedScore(item models.ScannerData) int {
+	return int(math.Round(float64(item.AbuseConfidenceScore) * (1 - item.RiskDecay)))
+}
+
+// ApplyAging sets RiskDecay and Stale on every record from the last time its
+// IP was seen, in the record or in the greylisting history, whichever is
+// later. Records unseen for longer than StaleAfterDays are stale and left out
+// of enforcement exports; pinned (manually overridden) IPs never are. Locked
+// records keep their decay and staleness.
+func (e *Extractor) ApplyAging(data []models.ScannerData, now time.Time) error {
+	entries, err := e.LifecycleEntries()
+	if err != nil {
+		return err
+	}
+	locks, err := e.RecordLocks()
+	if err != nil {
+		return err
+	}
+	halfLifeDays, staleAfterDays := e.agingDays()
+	halfLife := time.Duration(halfLifeDays) * 24 * time.Hour
+	grace := time.Duration(staleAfterDays) * 24 * time.Hour
+	stale := 0
+	for i := range data {
+		if _, ok := locks[data[i].IPOrCIDR]; ok {
+			continue
+		}
+		last := data[i].LastSeen
+		entry, tracked := entries[data[i].IPOrCIDR]
+		if tracked {
+			if t, err := models.ParseTimestamp(entry.LastSeen); err == nil && t.After(last) {
+				last = t
+			}
+		}
+		data[i].RiskDecay = RiskDecay(last, now, halfLife)
+		data[i].Stale = !last.IsZero() && now.Sub(last) > grace && !(tracked && entry.Pinned)
+		if data[i].Stale {
+			stale++
+		}
+	}
+	if stale > 0 {
+		e.logger.Info("Extractor", fmt.Sprintf("Vieillissement: %d enregistrements non vus depuis plus de %d jours, exclus des exports de blocage", stale, staleAfterDays))
+	}
+	return nil
+}
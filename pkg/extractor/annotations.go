@@ -0,0 +1,196 @@
+package extractor
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// annotationFile returns the path of the annotation store.
+func (e *Extractor) annotationFile() string {
+	if e.annotationPath != "" {
+		return e.annotationPath
+	}
+	return filepath.Join("build", "data", "annotations.json")
+}
+
+func (e *Extractor) loadAnnotations() ([]models.Annotation, error) {
+	b, err := os.ReadFile(e.annotationFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading annotation store: %w", err)
+	}
+	var anns []models.Annotation
+	if err := json.Unmarshal(b, &anns); err != nil {
+		return nil, fmt.Errorf("decoding annotation store: %w", err)
+	}
+	return anns, nil
+}
+
+func (e *Extractor) saveAnnotations(anns []models.Annotation) error {
+	path := e.annotationFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating annotation directory: %w", err)
+	}
+	b, err := json.MarshalIndent(anns, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding annotation store: %w", err)
+	}
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		return fmt.Errorf("writing annotation store: %w", err)
+	}
+	return nil
+}
+
+// newAnnotationID returns a random identifier for an annotation.
+func newAnnotationID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// AddAnnotation stores a tag/note on ip attributed to author. It is safe for
+// concurrent use. The stored annotation, with ID and timestamp, is returned.
+func (e *Extractor) AddAnnotation(ip, author string, tags []string, note string) (models.Annotation, error) {
+	ip, author, note = strings.TrimSpace(ip), strings.TrimSpace(author), strings.TrimSpace(note)
+	if ip == "" || author == "" {
+		return models.Annotation{}, fmt.Errorf("ip and author are required")
+	}
+	var cleaned []string
+	for _, t := range tags {
+		if t = strings.TrimSpace(t); t != "" {
+			cleaned = append(cleaned, t)
+		}
+	}
+	if len(cleaned) == 0 && note == "" {
+		return models.Annotation{}, fmt.Errorf("annotation needs at least one tag or a note")
+	}
+	ann := models.Annotation{
+		ID:        newAnnotationID(),
+		IP:        ip,
+		Author:    author,
+		Tags:      cleaned,
+		Note:      note,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339Nano),
+	}
+
+	e.annotationMu.Lock()
+	defer e.annotationMu.Unlock()
+	anns, err := e.loadAnnotations()
+	if err != nil {
+		return models.Annotation{}, err
+	}
+	if err := e.saveAnnotations(append(anns, ann)); err != nil {
+		return models.Annotation{}, err
+	}
+	e.logger.Info("Extractor", fmt.Sprintf("Annotation ajoutee sur %s par %s", ip, author))
+	return ann, nil
+}
+
+// Annotations returns the stored annotations for ip, oldest first. An empty
+// ip returns every annotation.
+func (e *Extractor) Annotations(ip string) ([]models.Annotation, error) {
+	e.annotationMu.Lock()
+	anns, err := e.loadAnnotations()
+	e.annotationMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	var out []models.Annotation
+	for _, a := range anns {
+		if ip == "" || a.IP == ip {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+// MergeAnnotations adds annotations from another store (e.g. another
+// analyst's export). Annotations already present by ID are skipped, so merging
+// is idempotent and order-independent. It returns the number added.
+func (e *Extractor) MergeAnnotations(other []models.Annotation) (int, error) {
+	e.annotationMu.Lock()
+	defer e.annotationMu.Unlock()
+	anns, err := e.loadAnnotations()
+	if err != nil {
+		return 0, err
+	}
+	merged := mergeAnnotations(anns, other)
+	added := len(merged) - len(anns)
+	if added == 0 {
+		return 0, nil
+	}
+	return added, e.saveAnnotations(merged)
+}
+
+// mergeAnnotations returns the union of a and b by ID, ordered by creation time.
+func mergeAnnotations(a, b []models.Annotation) []models.Annotation {
+	seen := make(map[string]bool, len(a)+len(b))
+	var out []models.Annotation
+	for _, list := range [][]models.Annotation{a, b} {
+		for _, ann := range list {
+			if ann.ID == "" || seen[ann.ID] {
+				continue
+			}
+			seen[ann.ID] = true
+			out = append(out, ann)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt < out[j].CreatedAt })
+	return out
+}
+
+// ApplyAnnotations attaches the stored annotations to the matching records
+// and adds their tags to each record's Tags. Applying twice has no further
+// effect.
+func (e *Extractor) ApplyAnnotations(data []models.ScannerData) error {
+	anns, err := e.Annotations("")
+	if err != nil {
+		return err
+	}
+	byIP := map[string][]models.Annotation{}
+	for _, a := range anns {
+		byIP[a.IP] = append(byIP[a.IP], a)
+	}
+	for i := range data {
+		list := byIP[data[i].IPOrCIDR]
+		if len(list) == 0 {
+			continue
+		}
+		data[i].Annotations = list
+		for _, a := range list {
+			// An annotation updates the record, for the delta syncs of
+			// remote instances
+			if at, err := time.Parse(time.RFC3339Nano, a.CreatedAt); err == nil && at.After(data[i].UpdatedAt) {
+				data[i].UpdatedAt = at
+			}
+			for _, tag := range a.Tags {
+				if !containsString(data[i].Tags, tag) {
+					data[i].Tags = append(data[i].Tags, tag)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package extractor
+
+import (
+	"math"
+	"strings"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// anonymizedCoordinateDecimals is the precision kept by RoundCoordinate:
+// one decimal is about 11 km, enough for a map but not for a street.
+const anonymizedCoordinateDecimals = 1
+
+// Anonymize returns copies of data that can be shared outside the team:
+// contact emails keep only their domain, host names (reverse DNS, domain,
+// InternetDB host names) lose their first label, coordinates are rounded
+// with RoundCoordinate, and PeeringDB contacts, notes and annotations
+// (which name analysts) are removed. data is left untouched.
+func Anonymize(data []models.ScannerData) []models.ScannerData {
+	out := make([]models.ScannerData, len(data))
+	for i, item := range data {
+		item.AbuseEmail = emailDomain(item.AbuseEmail)
+		item.TechEmail = emailDomain(item.TechEmail)
+		item.ReverseDNS = truncateHost(item.ReverseDNS)
+		item.Domain = truncateHost(item.Domain)
+		if item.Hostnames != nil {
+			hosts := make([]string, len(item.Hostnames))
+			for i, h := range item.Hostnames {
+				hosts[i] = truncateHost(h)
+			}
+			item.Hostnames = hosts
+		}
+		item.Latitude = RoundCoordinate(item.Latitude)
+		item.Longitude = RoundCoordinate(item.Longitude)
+		item.PeeringDBContacts = ""
+		item.Notes = ""
+		item.Annotations = nil
+		out[i] = item
+	}
+	return out
+}
+
+// AnonymizeGeo strips the reverse DNS of a geolocation result and rounds its
+// coordinates with RoundCoordinate.
+func AnonymizeGeo(g GeoResult) GeoResult {
+	g.ReverseDNS = truncateHost(g.ReverseDNS)
+	g.Latitude = RoundCoordinate(g.Latitude)
+	g.Longitude = RoundCoordinate(g.Longitude)
+	return g
+}
+
+// RoundCoordinate rounds a latitude or longitude to one decimal.
+func RoundCoordinate(v float64) float64 {
+	scale := math.Pow(10, anonymizedCoordinateDecimals)
+	return math.Round(v*scale) / scale
+}
+
+// emailDomain reduces "abuse@example.net" to "@example.net".
+func emailDomain(email string) string {
+	if at := strings.LastIndex(email, "@"); at >= 0 {
+		return email[at:]
+	}
+	return ""
+}
+
+// truncateHost replaces the first label of a host name with "*", so
+// "host-192-0-2-1.isp.example" becomes "*.isp.example". Names of two labels
+// or less are kept, as they name an organization rather than a host.
+func truncateHost(host string) string {
+	host = strings.TrimSuffix(strings.TrimSpace(host), ".")
+	if strings.Count(host, ".") < 2 {
+		return host
+	}
+	return "*" + host[strings.Index(host, "."):]
+}
@@ -0,0 +1,214 @@
+package extractor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// EnforcementDelta is the change an enforcement export would publish compared
+// to the last approved one.
+type EnforcementDelta struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Total   int      `json:"total"` // size of the list once published
+
+	// OwnershipChanged lists the blocked records whose RDAP owner changed
+	// at their last lookup, for review before publishing
+	OwnershipChanged []OwnershipChange `json:"ownership_changed,omitempty"`
+
+	// Collateral lists the entries of the list overlapping the protected
+	// prefixes (see ProtectedPrefixesFile)
+	Collateral []CollateralMatch `json:"collateral,omitempty"`
+
+	// PolicyHeld lists the blocked records the enforcement policy keeps
+	// out of the list
+	PolicyHeld []PolicyViolation `json:"policy_held,omitempty"`
+}
+
+// Empty reports whether publishing would change nothing.
+func (d EnforcementDelta) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0
+}
+
+// CriticalCollateral returns the collateral matches that refuse publishing.
+func (d EnforcementDelta) CriticalCollateral() []CollateralMatch {
+	var out []CollateralMatch
+	for _, m := range d.Collateral {
+		if m.Critical {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// ApprovalRecord is one entry of the approval audit log.
+type ApprovalRecord struct {
+	Approver   string `json:"approver"`
+	ApprovedAt string `json:"approved_at"`
+	Added      int    `json:"added"`
+	Removed    int    `json:"removed"`
+	Total      int    `json:"total"`
+}
+
+// approvalState is the on-disk record of the last published list and its approvals.
+type approvalState struct {
+	Published []string         `json:"published"`
+	Approvals []ApprovalRecord `json:"approvals"`
+}
+
+// approvalFile returns the path of the approval store.
+func (e *Extractor) approvalFile() string {
+	if e.approvalPath != "" {
+		return e.approvalPath
+	}
+	return filepath.Join("build", "data", "approvals.json")
+}
+
+func (e *Extractor) loadApprovals() (*approvalState, error) {
+	state := &approvalState{}
+	b, err := os.ReadFile(e.approvalFile())
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading approval store: %w", err)
+	}
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, fmt.Errorf("decoding approval store: %w", err)
+	}
+	return state, nil
+}
+
+// lockApprovals takes the lock shared by the instances on the approval
+// store and reads it, so an approval saved before unlock is appended to
+// those of the other instances rather than replacing them.
+func (e *Extractor) lockApprovals() (state *approvalState, unlock func(), err error) {
+	path := e.approvalFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, nil, fmt.Errorf("creating approval directory: %w", err)
+	}
+	unlock, err = lockDataFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("writing approval store: %w", err)
+	}
+	state, err = e.loadApprovals()
+	if err != nil {
+		unlock()
+		return nil, nil, err
+	}
+	return state, unlock, nil
+}
+
+// saveApprovals writes state as the approval store. Its lock must be held
+// (see lockApprovals).
+func (e *Extractor) saveApprovals(state *approvalState) error {
+	if err := writeJSONAtomic(e.approvalFile(), state); err != nil {
+		return fmt.Errorf("writing approval store: %w", err)
+	}
+	return nil
+}
+
+// enforcedIPs returns the sorted, de-duplicated IPs of the enforceable records.
+func enforcedIPs(data []models.ScannerData) []string {
+	seen := map[string]bool{}
+	var ips []string
+	for _, item := range Enforceable(data) {
+		if item.IPOrCIDR == "" || seen[item.IPOrCIDR] {
+			continue
+		}
+		seen[item.IPOrCIDR] = true
+		ips = append(ips, item.IPOrCIDR)
+	}
+	sort.Strings(ips)
+	return ips
+}
+
+// EnforcementDelta compares the blocked records in data with the last
+// approved enforcement list and checks the list against the protected
+// prefixes. It publishes nothing, so it doubles as a dry run. The
+// enforcement policy is evaluated on data first (see
+// ApplyEnforcementPolicy), so the list follows the current policy.
+func (e *Extractor) EnforcementDelta(data []models.ScannerData) (EnforcementDelta, error) {
+	state, err := e.loadApprovals()
+	if err != nil {
+		return EnforcementDelta{}, err
+	}
+	held := e.ApplyEnforcementPolicy(data)
+	next := enforcedIPs(data)
+	prev := make(map[string]bool, len(state.Published))
+	for _, ip := range state.Published {
+		prev[ip] = true
+	}
+	collateral, err := e.collateral(next)
+	if err != nil {
+		return EnforcementDelta{}, err
+	}
+	delta := EnforcementDelta{Total: len(next), OwnershipChanged: OwnershipChanges(Enforceable(data)), Collateral: collateral}
+	delta.PolicyHeld = held
+	for _, ip := range next {
+		if !prev[ip] {
+			delta.Added = append(delta.Added, ip)
+		}
+		delete(prev, ip)
+	}
+	for ip := range prev {
+		delta.Removed = append(delta.Removed, ip)
+	}
+	sort.Strings(delta.Removed)
+	return delta, nil
+}
+
+// ApproveEnforcement records that approver confirmed publishing the blocked
+// records in data: the list becomes the new baseline for EnforcementDelta and
+// the approval is appended to the audit log. It fails with
+// ErrCriticalCollateral, recording nothing, when the list blocks a critical
+// protected prefix.
+func (e *Extractor) ApproveEnforcement(data []models.ScannerData, approver string) (ApprovalRecord, error) {
+	if approver == "" {
+		return ApprovalRecord{}, fmt.Errorf("approver is required")
+	}
+	delta, err := e.EnforcementDelta(data)
+	if err != nil {
+		return ApprovalRecord{}, err
+	}
+	if critical := delta.CriticalCollateral(); len(critical) > 0 {
+		e.logger.Warning("Extractor", fmt.Sprintf("Publication refusee: %d entrees bloqueraient des prefixes proteges (%s dans %s)",
+			len(critical), critical[0].IP, critical[0].Prefix))
+		return ApprovalRecord{}, fmt.Errorf("%w: %d matches, first %s in %s (%s)",
+			ErrCriticalCollateral, len(critical), critical[0].IP, critical[0].Prefix, critical[0].Kind)
+	}
+	state, unlock, err := e.lockApprovals()
+	if err != nil {
+		return ApprovalRecord{}, err
+	}
+	defer unlock()
+	rec := ApprovalRecord{
+		Approver:   approver,
+		ApprovedAt: time.Now().Format(time.RFC3339),
+		Added:      len(delta.Added),
+		Removed:    len(delta.Removed),
+		Total:      delta.Total,
+	}
+	state.Published = enforcedIPs(data)
+	state.Approvals = append(state.Approvals, rec)
+	if err := e.saveApprovals(state); err != nil {
+		return ApprovalRecord{}, err
+	}
+	e.logger.Info("Extractor", fmt.Sprintf("Export d'application approuve par %s: +%d -%d (%d IPs)", approver, rec.Added, rec.Removed, rec.Total))
+	return rec, nil
+}
+
+// Approvals returns the approval audit log, oldest first.
+func (e *Extractor) Approvals() ([]ApprovalRecord, error) {
+	state, err := e.loadApprovals()
+	if err != nil {
+		return nil, err
+	}
+	return state.Approvals, nil
+}
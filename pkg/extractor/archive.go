@@ -0,0 +1,264 @@
+package extractor
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Size caps of the archive sources: a whole archive, and each file read
+// from it into memory.
+const (
+	maxArchiveSize      = 256 << 20
+	maxArchiveEntrySize = 64 << 20
+)
+
+// IsArchive reports whether name is a .zip, .tar, .tar.gz or .tgz archive.
+func IsArchive(name string) bool {
+	return archiveKind(name) != ""
+}
+
+// archiveKind returns "zip", "tar" or "tgz" from the extension of name, or
+// "" for another file.
+func archiveKind(name string) string {
+	n := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(n, ".zip"):
+		return "zip"
+	case strings.HasSuffix(n, ".tar.gz"), strings.HasSuffix(n, ".tgz"):
+		return "tgz"
+	case strings.HasSuffix(n, ".tar"):
+		return "tar"
+	}
+	return ""
+}
+
+// archiveMagic returns "zip", "tar" or "tgz" from the first bytes of an
+// archive, or "" when they match none. A tar file written without the
+// ustar header is not recognized.
+func archiveMagic(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")), bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		return "zip"
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		return "tgz"
+	case len(head) >= 262 && string(head[257:262]) == "ustar":
+		return "tar"
+	}
+	return ""
+}
+
+// fileArchiveKind returns the kind of the archive at file from its first
+// bytes, or from its extension when they match no kind.
+func fileArchiveKind(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", fmt.Errorf("opening archive: %w", err)
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", fmt.Errorf("reading archive %s: %w", file, err)
+	}
+	if kind := archiveMagic(head[:n]); kind != "" {
+		return kind, nil
+	}
+	return archiveKind(file), nil
+}
+
+// isRemoteSource reports whether an archive source is an http(s) URL.
+func isRemoteSource(src string) bool {
+	return strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://")
+}
+
+// archiveDir returns the directory the downloaded archive sources are kept in.
+func (e *Extractor) archiveDir() string {
+	if e.archivePath != "" {
+		return e.archivePath
+	}
+	return filepath.Join("build", "data", "archives")
+}
+
+// archiveLocalPath returns where the archive source src is read from: src
+// itself for a local file, its downloaded copy for a URL. The copy keeps
+// the file name of the URL when it has an archive extension; its kind is
+// read from its content anyway.
+func (e *Extractor) archiveLocalPath(src string) string {
+	if !isRemoteSource(src) {
+		return src
+	}
+	sum := sha256.Sum256([]byte(src))
+	name := "archive"
+	if u, err := url.Parse(src); err == nil && IsArchive(u.Path) {
+		name = path.Base(u.Path)
+	}
+	return filepath.Join(e.archiveDir(), hex.EncodeToString(sum[:8])+"-"+name)
+}
+
+// syncArchives downloads the archive sources given by URL, replacing the
+// previous copies. A failed download is logged and keeps the previous copy,
+// if any. Only the context's error is returned, when ctx is done.
+func (e *Extractor) syncArchives(ctx context.Context) error {
+	for _, src := range e.settings().ArchiveSources {
+		if !isRemoteSource(src) {
+			continue
+		}
+		if err := e.downloadArchive(ctx, src); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			e.logger.Warning("Extractor", fmt.Sprintf("Archive %s non telechargee: %v", redactURL(src), err))
+		}
+	}
+	return nil
+}
+
+// downloadArchive fetches src into its local copy.
+func (e *Extractor) downloadArchive(ctx context.Context, src string) error {
+	resp, err := e.httpGetWithRetry(ctx, src)
+	if err != nil {
+		return fmt.Errorf("downloading archive %s: %w", redactURL(src), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("downloading archive %s: HTTP %d", redactURL(src), resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxArchiveSize+1))
+	if err != nil {
+		return fmt.Errorf("downloading archive %s: %w", redactURL(src), err)
+	}
+	if len(body) > maxArchiveSize {
+		return fmt.Errorf("archive %s is larger than %d MiB", redactURL(src), maxArchiveSize>>20)
+	}
+	// An error page served with 200 must not replace the previous copy
+	if archiveMagic(body) == "" {
+		if u, err := url.Parse(src); err != nil || archiveKind(u.Path) != "tar" {
+			return fmt.Errorf("archive %s is not a zip, tar or gzip file (Content-Type %q)", redactURL(src), resp.Header.Get("Content-Type"))
+		}
+	}
+	dst := e.archiveLocalPath(src)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("creating archive directory: %w", err)
+	}
+	tmp := dst + ".tmp"
+	if err := os.WriteFile(tmp, body, 0644); err != nil {
+		return fmt.Errorf("writing archive %s: %w", dst, err)
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		return fmt.Errorf("writing archive %s: %w", dst, err)
+	}
+	e.logger.Info("Extractor", fmt.Sprintf("Archive telechargee: %s (%d octets)", redactURL(src), len(body)))
+	return nil
+}
+
+// walkArchive calls fn with the name and the content of each .nft file of
+// the archive at file, read into memory; nothing is written to disk.
+func walkArchive(file string, fn func(name string, r io.Reader) error) error {
+	kind, err := fileArchiveKind(file)
+	if err != nil {
+		return err
+	}
+	if kind == "" {
+		return fmt.Errorf("%s is not a .zip, .tar, .tar.gz or .tgz archive", file)
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return fmt.Errorf("opening archive: %w", err)
+	}
+	if info.Size() > maxArchiveSize {
+		return fmt.Errorf("archive %s is larger than %d MiB", file, maxArchiveSize>>20)
+	}
+	if kind == "zip" {
+		zr, err := zip.OpenReader(file)
+		if err != nil {
+			return fmt.Errorf("opening archive %s: %w", file, err)
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() || !strings.HasSuffix(strings.ToLower(f.Name), ".nft") {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return fmt.Errorf("reading %s in %s: %w", f.Name, file, err)
+			}
+			err = readArchiveEntry(f.Name, rc, fn)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	fh, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("opening archive: %w", err)
+	}
+	defer fh.Close()
+	var r io.Reader = fh
+	if kind == "tgz" {
+		gz, err := gzip.NewReader(fh)
+		if err != nil {
+			return fmt.Errorf("opening archive %s: %w", file, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading archive %s: %w", file, err)
+		}
+		if hdr.Typeflag != tar.TypeReg || !strings.HasSuffix(strings.ToLower(hdr.Name), ".nft") {
+			continue
+		}
+		if err := readArchiveEntry(hdr.Name, tr, fn); err != nil {
+			return err
+		}
+	}
+}
+
+// readArchiveEntry reads one archive file into memory, up to
+// maxArchiveEntrySize, and passes it to fn.
+func readArchiveEntry(name string, r io.Reader, fn func(name string, r io.Reader) error) error {
+	b, err := io.ReadAll(io.LimitReader(r, maxArchiveEntrySize+1))
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", name, err)
+	}
+	if len(b) > maxArchiveEntrySize {
+		return fmt.Errorf("%s is larger than %d MiB", name, maxArchiveEntrySize>>20)
+	}
+	return fn(name, bytes.NewReader(b))
+}
+
+// archiveSources returns the local paths of the configured archive sources
+// that can be read.
+func (e *Extractor) archiveSources() []string {
+	var out []string
+	for _, src := range e.settings().ArchiveSources {
+		p := e.archiveLocalPath(src)
+		if _, err := os.Stat(p); err != nil {
+			e.logger.Warning("Extractor", fmt.Sprintf("Archive %s indisponible: %v", redactURL(src), err))
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
@@ -0,0 +1,171 @@
+package extractor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// ripeStatPrefixesURL is the RIPEstat announced-prefixes endpoint; the ASN is appended.
+const ripeStatPrefixesURL = "https://stat.ripe.net/data/announced-prefixes/data.json?resource="
+
+// ASNExpansion lists the prefixes announced by an ASN and the dataset records
+// that fall inside them.
+type ASNExpansion struct {
+	ASN         string   // normalized, e.g. "AS15169"
+	Prefixes    []string // announced prefixes in CIDR notation
+	MatchingIPs []string // dataset IPs/CIDRs inside a prefix or tagged with the ASN
+}
+
+// NormalizeASN turns "AS15169", "as15169", "15169" or "AS15169 Google LLC"
+// into the canonical "AS15169" form.
+func NormalizeASN(s string) (string, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty ASN")
+	}
+	num := strings.TrimPrefix(strings.ToUpper(fields[0]), "AS")
+	n, err := strconv.ParseUint(num, 10, 32)
+	if err != nil || n == 0 {
+		return "", fmt.Errorf("invalid ASN %q", s)
+	}
+	return fmt.Sprintf("AS%d", n), nil
+}
+
+// FetchASNPrefixes returns the prefixes currently announced by asn, as seen by RIPEstat.
+func (e *Extractor) FetchASNPrefixes(ctx context.Context, asn string) ([]string, error) {
+	norm, err := NormalizeASN(asn)
+	if err != nil {
+		return nil, err
+	}
+	base := e.ripeStatURL
+	if base == "" {
+		base = ripeStatPrefixesURL
+	}
+	resp, err := e.httpGetWithRetry(ctx, base+url.QueryEscape(norm))
+	if err != nil {
+		return nil, fmt.Errorf("fetching prefixes for %s: %w", norm, err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading RIPEstat response for %s: %w", norm, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("RIPEstat http %d for %s", resp.StatusCode, norm)
+	}
+
+	var r struct {
+		Status string `json:"status"`
+		Data   struct {
+			Prefixes []struct {
+				Prefix string `json:"prefix"`
+			} `json:"prefixes"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, fmt.Errorf("unmarshaling RIPEstat response for %s: %w", norm, err)
+	}
+	if r.Status != "" && r.Status != "ok" {
+		return nil, fmt.Errorf("RIPEstat status %s for %s", r.Status, norm)
+	}
+
+	prefixes := make([]string, 0, len(r.Data.Prefixes))
+	for _, p := range r.Data.Prefixes {
+		if p.Prefix != "" {
+			prefixes = append(prefixes, p.Prefix)
+		}
+	}
+	e.logger.Info("Extractor", fmt.Sprintf("%d prefixes annonces par %s", len(prefixes), norm))
+	return prefixes, nil
+}
+
+// MatchASN returns the IPOrCIDR of every record that lies inside one of
+// prefixes or whose ASN field is asn.
+func MatchASN(data []models.ScannerData, asn string, prefixes []string) []string {
+	nets := make([]*net.IPNet, 0, len(prefixes))
+	for _, p := range prefixes {
+		if _, n, err := net.ParseCIDR(p); err == nil {
+			nets = append(nets, n)
+		}
+	}
+
+	var matches []string
+	for _, rec := range data {
+		if recASN, err := NormalizeASN(rec.ASN); err == nil && recASN == asn {
+			matches = append(matches, rec.IPOrCIDR)
+			continue
+		}
+		ip := net.ParseIP(rec.IPOrCIDR)
+		if ip == nil {
+			if base, _, err := net.ParseCIDR(rec.IPOrCIDR); err == nil {
+				ip = base
+			}
+		}
+		if ip == nil {
+			continue
+		}
+		for _, n := range nets {
+			if n.Contains(ip) {
+				matches = append(matches, rec.IPOrCIDR)
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// ExpandASN fetches the prefixes announced by asn and flags the dataset records they cover.
+func (e *Extractor) ExpandASN(ctx context.Context, asn string, data []models.ScannerData) (*ASNExpansion, error) {
+	norm, err := NormalizeASN(asn)
+	if err != nil {
+		return nil, err
+	}
+	prefixes, err := e.FetchASNPrefixes(ctx, norm)
+	if err != nil {
+		return nil, err
+	}
+	return &ASNExpansion{
+		ASN:         norm,
+		Prefixes:    prefixes,
+		MatchingIPs: MatchASN(data, norm, prefixes),
+	}, nil
+}
+
+// SaveASNPrefixes writes the expansion's prefixes, one per line, to a text
+// file in the results directory so they can be fed to a blocklist.
+func (e *Extractor) SaveASNPrefixes(exp *ASNExpansion, filename string) error {
+	if err := os.MkdirAll(e.settings().ResultsDir, 0755); err != nil {
+		return fmt.Errorf("creating results directory: %w", err)
+	}
+
+	filePath := filepath.Join(e.settings().ResultsDir, filename)
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("creating prefix file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	fmt.Fprintf(w, "# %s announced prefixes (%d), generated %s\n", exp.ASN, len(exp.Prefixes), time.Now().Format(time.RFC3339))
+	for _, p := range exp.Prefixes {
+		fmt.Fprintln(w, p)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("writing prefix file %s: %w", filePath, err)
+	}
+
+	e.logger.Info("Extractor", fmt.Sprintf("Prefixes %s sauvegardes: %s", exp.ASN, filePath))
+	return nil
+}
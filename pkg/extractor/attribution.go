@@ -0,0 +1,158 @@
+package extractor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// asnHeuristicMinRecords is how many records of a scanner must share an ASN,
+// with no record of another scanner in it, before the ASN heuristic
+// attributes the unnamed records of that ASN to the scanner.
+const asnHeuristicMinRecords = 3
+
+// asnTagPrefix prefixes the tag of the records attributed by the ASN
+// heuristic.
+const asnTagPrefix = "asn:"
+
+// ConfidenceRank orders the attribution confidences: 0 for none or an
+// unknown value, then low, medium and high.
+func ConfidenceRank(c string) int {
+	switch strings.ToLower(strings.TrimSpace(c)) {
+	case models.ConfidenceLow:
+		return 1
+	case models.ConfidenceMedium:
+		return 2
+	case models.ConfidenceHigh:
+		return 3
+	}
+	return 0
+}
+
+// ValidConfidence reports whether c is a confidence min_enforcement_confidence
+// accepts ("" stands for the default, medium).
+func ValidConfidence(c string) bool {
+	return c == "" || ConfidenceRank(c) > 0
+}
+
+// AtLeastConfidence returns the records of data attributed with confidence
+// min or higher.
+func AtLeastConfidence(data []models.ScannerData, min string) []models.ScannerData {
+	rank := ConfidenceRank(min)
+	var out []models.ScannerData
+	for _, item := range data {
+		if ConfidenceRank(item.AttributionConfidence) >= rank {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// ASNScanners maps the ASNs of data to the known scanner owning them: an ASN
+// is taken when at least three records of one scanner are in it and no
+// record of another known scanner is.
+func (e *Extractor) ASNScanners(data []models.ScannerData) map[string]string {
+	counts := map[string]map[string]int{}
+	for _, item := range data {
+		asn := strings.ToUpper(strings.TrimSpace(item.ASN))
+		if asn == "" || e.getScannerType(item.ScannerName) == models.ScannerTypeOther {
+			continue
+		}
+		if counts[asn] == nil {
+			counts[asn] = map[string]int{}
+		}
+		counts[asn][strings.ToLower(item.ScannerName)]++
+	}
+	out := map[string]string{}
+	for asn, byScanner := range counts {
+		if len(byScanner) != 1 {
+			continue
+		}
+		for scanner, n := range byScanner {
+			if n >= asnHeuristicMinRecords {
+				out[asn] = scanner
+			}
+		}
+	}
+	return out
+}
+
+// minEnforcementConfidence returns the configured min_enforcement_confidence,
+// medium when unset or invalid.
+func (e *Extractor) minEnforcementConfidence() string {
+	c := strings.ToLower(strings.TrimSpace(e.settings().MinEnforcementConfidence))
+	if ConfidenceRank(c) == 0 {
+		return models.ConfidenceMedium
+	}
+	return c
+}
+
+// AttributeScanners sets the scanner attribution of the records of data and
+// how confident it is. Records whose feed names a known scanner are high.
+// The others (type other) are attributed to the scanner their reverse DNS
+// points to (medium), else to the scanner owning their ASN (low): it sets
+// AttributedScanner, the "rdns:<scanner>" or "asn:<scanner>" tag and, for a
+// known scanner, its type. Attributions that no longer match are removed.
+// Records below min_enforcement_confidence get ConfidenceHold. It returns
+// the number of attributed records.
+func (e *Extractor) AttributeScanners(data []models.ScannerData) int {
+	patterns := e.RDNSPatterns(data)
+	owners := e.ASNScanners(data)
+	minRank := ConfidenceRank(e.minEnforcementConfidence())
+	n, held := 0, 0
+	for i := range data {
+		item := &data[i]
+		if e.getScannerType(item.ScannerName) != models.ScannerTypeOther {
+			item.AttributionConfidence = models.ConfidenceHigh
+			item.ConfidenceHold = ConfidenceRank(item.AttributionConfidence) < minRank
+			if item.ConfidenceHold {
+				held++
+			}
+			continue
+		}
+		scanner, ok := ClassifyRDNS(item.ReverseDNS, patterns)
+		confidence, prefix := models.ConfidenceMedium, rdnsTagPrefix
+		if ok && strings.EqualFold(scanner, item.ScannerName) {
+			ok = false
+		}
+		if !ok {
+			scanner, ok = owners[strings.ToUpper(strings.TrimSpace(item.ASN))]
+			confidence, prefix = models.ConfidenceLow, asnTagPrefix
+		}
+		if !ok && item.AttributedScanner == "" && item.AttributionConfidence == "" {
+			continue
+		}
+		tags := item.Tags[:0:0]
+		for _, t := range item.Tags {
+			if !strings.HasPrefix(t, rdnsTagPrefix) && !strings.HasPrefix(t, asnTagPrefix) {
+				tags = append(tags, t)
+			}
+		}
+		item.Tags = tags
+		item.AttributedScanner = ""
+		item.AttributionConfidence = ""
+		item.ConfidenceHold = false
+		item.ScannerType = models.ScannerTypeOther
+		if !ok {
+			continue
+		}
+		item.AttributedScanner = scanner
+		item.AttributionConfidence = confidence
+		item.ConfidenceHold = ConfidenceRank(confidence) < minRank
+		item.Tags = append(item.Tags, prefix+scanner)
+		item.ScannerType = e.getScannerType(scanner)
+		n++
+		if item.ConfidenceHold {
+			held++
+		}
+	}
+	if n > 0 {
+		e.logger.Info("Extractor", fmt.Sprintf("Attribution: %d enregistrements attribues a un scanner connu (rDNS ou ASN)", n))
+	}
+	if held > 0 {
+		e.logger.Info("Extractor", fmt.Sprintf("%d enregistrements sous la confiance minimale (%s) exclus de l'application",
+			held, e.minEnforcementConfidence()))
+	}
+	return n
+}
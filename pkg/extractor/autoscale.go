@@ -0,0 +1,157 @@
+package extractor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"sync"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// Autoscaling of the requests in flight to each provider: every
+// autoscaleWindow responses (or as soon as autoscaleMaxErrors of them are
+// 429 or 5xx) the limit of the provider is halved when more than
+// autoscaleHighRate of them failed, and raised by one when fewer than
+// autoscaleLowRate did.
+const (
+	autoscaleWindow    = 20
+	autoscaleMaxErrors = 4
+	autoscaleHighRate  = 0.2
+	autoscaleLowRate   = 0.05
+)
+
+// hostLimit is the adaptive concurrency limit of one provider.
+type hostLimit struct {
+	cond     *sync.Cond
+	limit    int
+	inFlight int
+	ok       int
+	failed   int
+}
+
+// autoscaler caps the requests in flight to each provider (URL host)
+// between min and max, adapting the cap to the provider's 429/5xx rate.
+type autoscaler struct {
+	min, max int
+	mu       sync.Mutex
+	hosts    map[string]*hostLimit
+	// onChange is called, without the lock held, when a limit changes.
+	onChange func(host string, from, to int)
+}
+
+// newAutoscaler returns an autoscaler keeping each provider between min
+// (at least 1) and max requests in flight, starting at max.
+func newAutoscaler(min, max int) *autoscaler {
+	if max < 1 {
+		max = 1
+	}
+	if min < 1 {
+		min = 1
+	}
+	if min > max {
+		min = max
+	}
+	return &autoscaler{min: min, max: max, hosts: map[string]*hostLimit{}}
+}
+
+// acquire blocks until a request to host is allowed and returns the
+// function recording its HTTP status (0 for a network error) and giving the
+// slot back. It gives up with ctx's error when ctx is done first.
+func (a *autoscaler) acquire(ctx context.Context, host string) (release func(status int), err error) {
+	a.mu.Lock()
+	h, ok := a.hosts[host]
+	if !ok {
+		h = &hostLimit{cond: sync.NewCond(&a.mu), limit: a.max}
+		a.hosts[host] = h
+	}
+	if h.inFlight >= h.limit {
+		// Wake the waiters when ctx is done, for this one to give up
+		stop := context.AfterFunc(ctx, func() {
+			a.mu.Lock()
+			h.cond.Broadcast()
+			a.mu.Unlock()
+		})
+		defer stop()
+	}
+	for h.inFlight >= h.limit {
+		if err := ctx.Err(); err != nil {
+			a.mu.Unlock()
+			return nil, err
+		}
+		h.cond.Wait()
+	}
+	h.inFlight++
+	a.mu.Unlock()
+
+	return func(status int) {
+		a.mu.Lock()
+		h.inFlight--
+		if status == http.StatusTooManyRequests || status >= 500 {
+			h.failed++
+		} else if status > 0 {
+			h.ok++
+		}
+		from := h.limit
+		if n := h.ok + h.failed; n >= autoscaleWindow || h.failed >= autoscaleMaxErrors {
+			rate := float64(h.failed) / float64(n)
+			switch {
+			case rate > autoscaleHighRate:
+				h.limit = max(a.min, h.limit/2)
+			case rate < autoscaleLowRate:
+				h.limit = min(a.max, h.limit+1)
+			}
+			h.ok, h.failed = 0, 0
+		}
+		to := h.limit
+		h.cond.Broadcast()
+		a.mu.Unlock()
+		if from != to && a.onChange != nil {
+			a.onChange(host, from, to)
+		}
+	}, nil
+}
+
+// limits returns the current limit of each provider seen so far.
+func (a *autoscaler) limits() map[string]int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make(map[string]int, len(a.hosts))
+	for host, h := range a.hosts {
+		out[host] = h.limit
+	}
+	return out
+}
+
+// requestHost returns the host a request URL is autoscaled under.
+func requestHost(rawURL string) string {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.Host
+}
+
+// newAutoscalerFor builds the autoscaler of config: between
+// AutoscaleMinWorkers and Parallelism, or nil when DisableAutoscale is set.
+func (e *Extractor) newAutoscalerFor(config models.DatabaseConfig) *autoscaler {
+	if config.DisableAutoscale {
+		return nil
+	}
+	a := newAutoscaler(config.AutoscaleMinWorkers, config.Parallelism)
+	a.onChange = func(host string, from, to int) {
+		e.logger.Info("Extractor", fmt.Sprintf("Parallelisme effectif vers %s: %d -> %d", host, from, to))
+	}
+	return a
+}
+
+// EffectiveParallelism returns the requests currently allowed in flight to
+// each provider contacted so far, by host; nil when autoscaling is disabled.
+func (e *Extractor) EffectiveParallelism() map[string]int {
+	a := e.scaler()
+	if a == nil {
+		return nil
+	}
+	return a.limits()
+}
@@ -0,0 +1,80 @@
+package extractor
+
+import (
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// DefaultUpdateInterval is the period of the scheduled update when
+// UpdateInterval is not set.
+const DefaultUpdateInterval = 24 * time.Hour
+
+// UpdateEvery returns the period of the scheduled update of the repository:
+// UpdateInterval hours, or DefaultUpdateInterval when it is not positive.
+// It returns 0 while AutoUpdate is off.
+func UpdateEvery(config models.DatabaseConfig) time.Duration {
+	if !config.AutoUpdate {
+		return 0
+	}
+	if config.UpdateInterval <= 0 {
+		return DefaultUpdateInterval
+	}
+	return time.Duration(config.UpdateInterval) * time.Hour
+}
+
+// beginRun marks an extraction as running until the returned function is
+// called.
+func (e *Extractor) beginRun() (end func()) {
+	e.runs.Add(1)
+	return func() { e.runs.Add(-1) }
+}
+
+// RunActive reports whether an extraction or an enrichment is running, so
+// a scheduled update can be skipped instead of running alongside it.
+func (e *Extractor) RunActive() bool {
+	return e.runs.Load() > 0 || e.enrichments.Load() > 0
+}
+
+// RDAPEnriched reports whether an RDAP lookup filled item.
+func RDAPEnriched(item models.ScannerData) bool {
+	return item.Provenance[models.ProvenanceRDAP] != ""
+}
+
+// CarryEnrichment returns fresh, the base records of a new extraction, with
+// the enriched records of previous matching them (same canonical IP and
+// scanner) carried over, and the indexes of the fresh records left to
+// enrich. A carried record takes the feed fields of the new run (ID, source
+// file, target ports, attribution, lifecycle state and last seen) and keeps
+// the rest, so only the IPs new to the feeds, or never enriched, are looked
+// up again.
+func CarryEnrichment(previous, fresh []models.ScannerData) ([]models.ScannerData, []int) {
+	enriched := make(map[string]models.ScannerData, len(previous))
+	for _, item := range previous {
+		if RDAPEnriched(item) {
+			enriched[dedupKey(item)] = item
+		}
+	}
+	out := make([]models.ScannerData, len(fresh))
+	var pending []int
+	for i, item := range fresh {
+		prev, ok := enriched[dedupKey(item)]
+		if !ok {
+			out[i] = item
+			pending = append(pending, i)
+			continue
+		}
+		prev.ID = item.ID
+		prev.ScannerType = item.ScannerType
+		prev.SourceFile = item.SourceFile
+		prev.TargetPorts = item.TargetPorts
+		prev.FeedTrust = item.FeedTrust
+		prev.AttributionReason = item.AttributionReason
+		prev.State = item.State
+		prev.RunsSeen = item.RunsSeen
+		prev.LastSeen = item.LastSeen
+		prev.ExportDate = item.ExportDate
+		out[i] = prev
+	}
+	return out, pending
+}
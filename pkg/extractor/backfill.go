@@ -0,0 +1,158 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// geoBackfillGrace is how long the geo backfill stays paused after an
+// enrichment ends, so it does not slip in between the records of a bulk
+// enrichment made of single-record calls.
+const geoBackfillGrace = time.Minute
+
+// ErrBackfillPaused is returned by BackfillGeo while an enrichment is
+// running or has just ended.
+var ErrBackfillPaused = errors.New("geo backfill paused by a running enrichment")
+
+// beginEnrichment marks an enrichment as running until the returned
+// function is called.
+func (e *Extractor) beginEnrichment() (end func()) {
+	e.enrichments.Add(1)
+	return func() {
+		e.lastEnrichment.Store(time.Now().UnixNano())
+		e.enrichments.Add(-1)
+	}
+}
+
+// EnrichmentActive reports whether an enrichment is running or ended less
+// than a minute ago.
+func (e *Extractor) EnrichmentActive() bool {
+	if e.enrichments.Load() > 0 {
+		return true
+	}
+	last := e.lastEnrichment.Load()
+	return last != 0 && time.Since(time.Unix(0, last)) < geoBackfillGrace
+}
+
+// NeedsGeoBackfill reports whether item lacks its country or city.
+func NeedsGeoBackfill(item models.ScannerData) bool {
+	return item.CountryCode == "" || item.City == ""
+}
+
+// NextGeoBackfill returns the index in data of the record to backfill next:
+// the least recently updated record missing geolocation that BackfillGeo
+// has not tried yet and that is not locked, or -1 when there is none.
+func (e *Extractor) NextGeoBackfill(data []models.ScannerData) int {
+	locks, _ := e.RecordLocks()
+	e.backfillMu.Lock()
+	defer e.backfillMu.Unlock()
+	next := -1
+	for i, item := range data {
+		if !NeedsGeoBackfill(item) || e.backfillTried[CanonicalIP(item.IPOrCIDR)] {
+			continue
+		}
+		if _, ok := locks[item.IPOrCIDR]; ok {
+			continue
+		}
+		if next < 0 || item.UpdatedAt.Before(data[next].UpdatedAt) {
+			next = i
+		}
+	}
+	return next
+}
+
+// BackfillGeo looks up the geolocation of item and fills its empty country,
+// continent, region, city, ISP, ASN and reverse DNS fields, leaving the
+// others as they are. The RDAP cache entry of the IP, if any, is filled the
+// same way, so the next enrichment keeps the values. Each record is tried
+// once per Extractor, whatever the outcome. It returns whether a field was
+// filled, or ErrBackfillPaused without a lookup while EnrichmentActive.
+// Cancelling ctx aborts the lookup.
+func (e *Extractor) BackfillGeo(ctx context.Context, item *models.ScannerData) (bool, error) {
+	if e.EnrichmentActive() {
+		return false, ErrBackfillPaused
+	}
+	e.backfillMu.Lock()
+	if e.backfillTried == nil {
+		e.backfillTried = map[string]bool{}
+	}
+	e.backfillTried[CanonicalIP(item.IPOrCIDR)] = true
+	e.backfillMu.Unlock()
+
+	ip := strings.TrimSpace(item.IPOrCIDR)
+	if addr, _, err := net.ParseCIDR(ip); err == nil {
+		ip = addr.String()
+	}
+	if rl := e.limiter(); rl != nil {
+		if err := rl.WaitContext(ctx); err != nil {
+			return false, err
+		}
+	}
+	g, err := e.geoProvider().Lookup(ctx, ip)
+	if err != nil {
+		return false, fmt.Errorf("geo lookup for %s: %w", item.IPOrCIDR, err)
+	}
+	if !fillGeo(item, g) {
+		return false, nil
+	}
+	now := time.Now()
+	item.UpdatedAt = now
+	item.SetProvenance(models.ProvenanceGeo, geoSourceLabel(g, e.geoProvider().Name()), now)
+
+	cache := e.loadRDAPCache()
+	if entry, ok := cache.Entries[item.IPOrCIDR]; ok {
+		for _, f := range []struct {
+			dst *string
+			src string
+		}{
+			{&entry.CountryCode, item.CountryCode}, {&entry.CountryName, item.CountryName},
+			{&entry.City, item.City}, {&entry.ISP, item.ISP}, {&entry.ASN, item.ASN},
+			{&entry.ASName, item.ASName}, {&entry.ReverseDNS, item.ReverseDNS},
+			{&entry.Continent, item.Continent}, {&entry.ContinentCode, item.ContinentCode},
+			{&entry.Region, item.Region},
+		} {
+			fillString(f.dst, f.src)
+		}
+		if entry.Latitude == 0 && entry.Longitude == 0 {
+			entry.Latitude, entry.Longitude = item.Latitude, item.Longitude
+		}
+		cache.Entries[item.IPOrCIDR] = entry
+		cache.save()
+	}
+	e.logger.Debug("Extractor", "Geolocalisation completee en arriere-plan: "+item.IPOrCIDR)
+	return true, nil
+}
+
+// fillGeo sets the empty geolocation fields of item from g and reports
+// whether one was set.
+func fillGeo(item *models.ScannerData, g GeoResult) bool {
+	asName := ""
+	if parts := strings.SplitN(g.ASN, " ", 2); len(parts) == 2 {
+		asName = parts[1]
+	}
+	filled := false
+	for _, f := range []struct {
+		dst *string
+		src string
+	}{
+		{&item.CountryCode, g.CountryCode}, {&item.CountryName, g.Country}, {&item.City, g.City},
+		{&item.ISP, g.ISP}, {&item.ASN, g.ASN}, {&item.ASName, asName}, {&item.ReverseDNS, g.ReverseDNS},
+		{&item.Continent, g.Continent}, {&item.ContinentCode, g.ContinentCode}, {&item.Region, g.Region},
+	} {
+		if *f.dst == "" && f.src != "" {
+			*f.dst = f.src
+			filled = true
+		}
+	}
+	if !item.HasLocation() && (g.Latitude != 0 || g.Longitude != 0) {
+		item.Latitude, item.Longitude = g.Latitude, g.Longitude
+		filled = true
+	}
+	return filled
+}
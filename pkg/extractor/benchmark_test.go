@@ -4,12 +4,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
 	"testing"
 	"time"
 
 	"github.com/lia/liacheckscanner_go/internal/logger"
-	"github.com/lia/liacheckscanner_go/internal/models"
+	"github.com/lia/liacheckscanner_go/pkg/models"
 )
 
 // newSilentLogger creates a logger with CRITICAL level so that benchmark
@@ -81,12 +80,11 @@ func BenchmarkExtractIPsFromNFTFile(b *testing.B) {
 	}
 
 	ext := newBenchExtractor(b, dir)
-	ipv4Re := regexp.MustCompile(`\b(?:[0-9]{1,3}\.){3}[0-9]{1,3}(?:/\d{1,2})?\b`)
-	ipv6Re := regexp.MustCompile(`(?:[a-fA-F0-9]{0,4}:){2,7}[a-fA-F0-9]{0,4}(?:/\d{1,3})?`)
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := ext.extractIPsFromNFTFile(nftFile, ipv4Re, ipv6Re)
+		_, err := ext.extractIPsFromNFTFile(nftFile, feedIPv4Regex, feedIPv6Regex)
 		if err != nil {
 			b.Fatalf("extractIPsFromNFTFile: %v", err)
 		}
@@ -108,6 +106,7 @@ func BenchmarkParseFilesForIPs(b *testing.B) {
 
 	ext := newBenchExtractor(b, dir)
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		_, err := ext.parseFilesForIPs(dir)
@@ -117,6 +116,31 @@ func BenchmarkParseFilesForIPs(b *testing.B) {
 	}
 }
 
+// BenchmarkParseFilesForIPs_LargeTree benchmarks parsing 50 .nft files of
+// 20000 IPs each, then mapping the IPs to their scanners, as an extraction
+// does. Allocations grow with the IPs found, not with the lines or files read.
+func BenchmarkParseFilesForIPs_LargeTree(b *testing.B) {
+	dir := b.TempDir()
+	for j := 0; j < 50; j++ {
+		name := filepath.Join(dir, fmt.Sprintf("scanner_%d.nft", j))
+		if err := os.WriteFile(name, []byte(generateNFTFileContent(20000)), 0644); err != nil {
+			b.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	ext := newBenchExtractor(b, dir)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ips, err := ext.parseFilesForIPs(dir)
+		if err != nil {
+			b.Fatalf("parseFilesForIPs: %v", err)
+		}
+		ext.mapIPsToScanners(ips)
+	}
+}
+
 // BenchmarkGetScannerType benchmarks looking up the scanner type by name.
 func BenchmarkGetScannerType(b *testing.B) {
 	dir := b.TempDir()
@@ -175,7 +199,7 @@ func BenchmarkApplyCache(b *testing.B) {
 			ASN:         fmt.Sprintf("AS%d", 10000+i),
 			AbuseEmail:  "abuse@bench.com",
 			TechEmail:   "tech@bench.com",
-			CachedAt:    time.Now().Format(time.RFC3339),
+			CachedAt:    time.Now(),
 		}
 	}
 
@@ -0,0 +1,260 @@
+package extractor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultBootstrapURL is where IANA publishes the RDAP bootstrap files of
+// the IPv4 and IPv6 address space (RFC 9224).
+const defaultBootstrapURL = "https://data.iana.org/rdap/"
+
+const (
+	// defaultRDAPBootstrapDays is how long a downloaded bootstrap table is
+	// used before it is downloaded again, when RDAPBootstrapDays is not set.
+	defaultRDAPBootstrapDays = 7
+	// bootstrapRetry is how long a failed download waits before the next;
+	// meanwhile the table on disk, if any, is used however old.
+	bootstrapRetry = time.Hour
+)
+
+// bootstrapFile is an IANA RDAP bootstrap file: each service pairs a list
+// of ranges with the RDAP base URLs of the registry serving them.
+type bootstrapFile struct {
+	Publication string       `json:"publication"`
+	Services    [][][]string `json:"services"`
+}
+
+// rdapBootstrap maps the IP ranges of the bootstrap files to their RDAP
+// base URLs. It is cached in build/data/rdap_bootstrap.json.
+type rdapBootstrap struct {
+	FetchedAt time.Time           `json:"fetched_at"`
+	Ranges    map[string][]string `json:"ranges"`
+
+	// ranges parsed, the most specific first
+	index []bootstrapRange
+}
+
+// bootstrapRange is one parsed range of an rdapBootstrap.
+type bootstrapRange struct {
+	prefix netip.Prefix
+	urls   []string
+}
+
+// buildIndex parses the ranges of b for endpointFor. Unparsable ranges are
+// skipped.
+func (b *rdapBootstrap) buildIndex() {
+	b.index = b.index[:0]
+	for r, urls := range b.Ranges {
+		p, err := netip.ParsePrefix(r)
+		if err != nil || len(urls) == 0 {
+			continue
+		}
+		b.index = append(b.index, bootstrapRange{prefix: p.Masked(), urls: urls})
+	}
+	sort.Slice(b.index, func(i, j int) bool { return b.index[i].prefix.Bits() > b.index[j].prefix.Bits() })
+}
+
+// endpointFor returns the RDAP IP lookup URL of the registry owning ip, an
+// address or a CIDR, and false when no range of b holds it.
+func (b *rdapBootstrap) endpointFor(ip string) (string, bool) {
+	var target netip.Prefix
+	if p, err := netip.ParsePrefix(ip); err == nil {
+		target = p.Masked()
+	} else if a, err := netip.ParseAddr(ip); err == nil {
+		target = netip.PrefixFrom(a.Unmap(), a.Unmap().BitLen())
+	} else {
+		return "", false
+	}
+	for _, r := range b.index {
+		if r.prefix.Bits() <= target.Bits() && r.prefix.Contains(target.Addr()) {
+			return bootstrapEndpoint(r.urls), true
+		}
+	}
+	return "", false
+}
+
+// bootstrapRegistryDomains maps the domains of the bootstrap URLs to the
+// registries of rdapRegistryURLs, whose endpoints and cooldowns are used
+// for them.
+var bootstrapRegistryDomains = map[string]string{
+	"arin.net":    "arin",
+	"ripe.net":    "ripe",
+	"apnic.net":   "apnic",
+	"lacnic.net":  "lacnic",
+	"afrinic.net": "afrinic",
+}
+
+// bootstrapEndpoint returns the RDAP IP lookup URL of a bootstrap service,
+// preferring its HTTPS base URL. A known registry gets its usual endpoint.
+func bootstrapEndpoint(urls []string) string {
+	base := urls[0]
+	for _, u := range urls {
+		if strings.HasPrefix(u, "https://") {
+			base = u
+			break
+		}
+	}
+	if u, err := url.Parse(base); err == nil {
+		host := strings.ToLower(u.Hostname())
+		for domain, registry := range bootstrapRegistryDomains {
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				return rdapRegistryURLs[registry]
+			}
+		}
+	}
+	return strings.TrimSuffix(base, "/") + "/ip/"
+}
+
+// rdapBootstrapFile returns the path of the cached bootstrap table.
+func (e *Extractor) rdapBootstrapFile() string {
+	if e.rdapBootstrapPath != "" {
+		return e.rdapBootstrapPath
+	}
+	return filepath.Join("build", "data", "rdap_bootstrap.json")
+}
+
+// rdapBootstrapTable returns the bootstrap table, downloading it again once
+// RDAPBootstrapDays old. It returns nil when the bootstrap is disabled or
+// was never downloaded; RDAP lookups then try every registry in turn.
+func (e *Extractor) rdapBootstrapTable(ctx context.Context) *rdapBootstrap {
+	cfg := e.settings()
+	// Tests pointing RDAP at local servers opt in with rdapBootstrapURL
+	if cfg.DisableRDAPBootstrap || (len(e.rdapEndpoints) > 0 && e.rdapBootstrapURL == "") {
+		return nil
+	}
+	maxAge := time.Duration(cfg.RDAPBootstrapDays) * 24 * time.Hour
+	if cfg.RDAPBootstrapDays <= 0 {
+		maxAge = defaultRDAPBootstrapDays * 24 * time.Hour
+	}
+
+	e.bootstrapMu.Lock()
+	defer e.bootstrapMu.Unlock()
+	now := time.Now()
+	if e.bootstrap == nil {
+		if b, err := e.loadRDAPBootstrap(); err == nil {
+			e.bootstrap = b
+		}
+	}
+	if e.bootstrap != nil && now.Sub(e.bootstrap.FetchedAt) < maxAge {
+		return e.bootstrap
+	}
+	if now.Sub(e.bootstrapTried) < bootstrapRetry {
+		return e.bootstrap
+	}
+
+	b, err := e.fetchRDAPBootstrap(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			e.bootstrapTried = now
+			e.logger.Warning("Extractor", "Bootstrap RDAP IANA indisponible, tous les registres seront interroges: "+err.Error())
+		}
+		return e.bootstrap
+	}
+	e.bootstrap = b
+	if err := e.saveRDAPBootstrap(b); err != nil {
+		e.logger.Warning("Extractor", err.Error())
+	}
+	e.logger.Info("Extractor", fmt.Sprintf("Bootstrap RDAP IANA charge: %d plages", len(b.Ranges)))
+	return b
+}
+
+// fetchRDAPBootstrap downloads the IPv4 and IPv6 bootstrap files.
+func (e *Extractor) fetchRDAPBootstrap(ctx context.Context) (*rdapBootstrap, error) {
+	base := e.rdapBootstrapURL
+	if base == "" {
+		base = defaultBootstrapURL
+	}
+	b := &rdapBootstrap{FetchedAt: time.Now().UTC(), Ranges: map[string][]string{}}
+	for _, name := range []string{"ipv4.json", "ipv6.json"} {
+		resp, err := e.httpGet(ctx, base+name, true)
+		if err != nil {
+			return nil, fmt.Errorf("downloading RDAP bootstrap %s: %w", name, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading RDAP bootstrap %s: %w", name, err)
+		}
+		if resp.StatusCode != 200 {
+			return nil, fmt.Errorf("downloading RDAP bootstrap %s: HTTP %d", name, resp.StatusCode)
+		}
+		var f bootstrapFile
+		if err := json.Unmarshal(body, &f); err != nil {
+			return nil, fmt.Errorf("decoding RDAP bootstrap %s: %w", name, err)
+		}
+		for _, svc := range f.Services {
+			if len(svc) < 2 {
+				continue
+			}
+			for _, r := range svc[0] {
+				b.Ranges[r] = svc[1]
+			}
+		}
+	}
+	b.buildIndex()
+	return b, nil
+}
+
+// loadRDAPBootstrap reads the cached bootstrap table.
+func (e *Extractor) loadRDAPBootstrap() (*rdapBootstrap, error) {
+	raw, err := os.ReadFile(e.rdapBootstrapFile())
+	if err != nil {
+		return nil, err
+	}
+	var b rdapBootstrap
+	if err := json.Unmarshal(raw, &b); err != nil {
+		return nil, fmt.Errorf("decoding RDAP bootstrap cache: %w", err)
+	}
+	b.buildIndex()
+	return &b, nil
+}
+
+// saveRDAPBootstrap writes b to the bootstrap cache.
+func (e *Extractor) saveRDAPBootstrap(b *rdapBootstrap) error {
+	path := e.rdapBootstrapFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating RDAP bootstrap directory: %w", err)
+	}
+	raw, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding RDAP bootstrap: %w", err)
+	}
+	if err := os.WriteFile(path, raw, 0644); err != nil {
+		return fmt.Errorf("writing RDAP bootstrap: %w", err)
+	}
+	return nil
+}
+
+// rdapEndpointsFor returns the RDAP base URLs to query for ip: the one of
+// the registry the bootstrap table gives as owner first, then the other
+// configured registries, in case it does not answer. Without a table, or
+// when the owner is not among the configured registries, the order is that
+// of rdapEndpointList.
+func (e *Extractor) rdapEndpointsFor(ctx context.Context, ip string) []string {
+	endpoints := e.rdapEndpointList()
+	b := e.rdapBootstrapTable(ctx)
+	if b == nil {
+		return endpoints
+	}
+	owner, ok := b.endpointFor(ip)
+	if !ok {
+		return endpoints
+	}
+	for i, base := range endpoints {
+		if base == owner {
+			ordered := append([]string{owner}, endpoints[:i]...)
+			return append(ordered, endpoints[i+1:]...)
+		}
+	}
+	return endpoints
+}
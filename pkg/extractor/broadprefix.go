@@ -0,0 +1,77 @@
+package extractor
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// Default thresholds under which a prefix is too broad to enforce.
+const (
+	defaultBroadPrefixV4 = 16
+	defaultBroadPrefixV6 = 32
+)
+
+// Broad prefix policies.
+const (
+	BroadPrefixFlag    = "flag"    // mark broad prefixes but enforce them
+	BroadPrefixExclude = "exclude" // keep broad prefixes out of enforcement exports
+	BroadPrefixOff     = "off"     // no check
+)
+
+// broadPrefixPolicy returns the configured policy and the shortest IPv4 and
+// IPv6 prefix lengths enforced as is, falling back to the defaults.
+func (e *Extractor) broadPrefixPolicy() (policy string, v4, v6 int) {
+	cfg := e.settings()
+	policy, v4, v6 = strings.ToLower(cfg.BroadPrefixPolicy), cfg.BroadPrefixV4, cfg.BroadPrefixV6
+	if policy == "" {
+		policy = BroadPrefixFlag
+	}
+	if v4 <= 0 {
+		v4 = defaultBroadPrefixV4
+	}
+	if v6 <= 0 {
+		v6 = defaultBroadPrefixV6
+	}
+	return policy, v4, v6
+}
+
+// IsBroadPrefix reports whether s is a CIDR shorter than /v4 for IPv4 or
+// /v6 for IPv6. Single IPs are never broad.
+func IsBroadPrefix(s string, v4, v6 int) bool {
+	_, n, err := net.ParseCIDR(strings.TrimSpace(s))
+	if err != nil {
+		return false
+	}
+	ones, bits := n.Mask.Size()
+	if bits == 32 {
+		return ones < v4
+	}
+	return ones < v6
+}
+
+// ApplyPrefixPolicy sets BroadPrefix on the records whose prefix is broader
+// than BroadPrefixV4 or BroadPrefixV6: flagged, or excluded from
+// enforcement exports under the exclude policy. The records stay in the
+// dataset either way.
+func (e *Extractor) ApplyPrefixPolicy(data []models.ScannerData) {
+	policy, v4, v6 := e.broadPrefixPolicy()
+	mark := models.BroadPrefixFlagged
+	if policy == BroadPrefixExclude {
+		mark = models.BroadPrefixExcluded
+	}
+	broad := 0
+	for i := range data {
+		data[i].BroadPrefix = ""
+		if policy != BroadPrefixOff && IsBroadPrefix(data[i].IPOrCIDR, v4, v6) {
+			data[i].BroadPrefix = mark
+			broad++
+		}
+	}
+	if broad > 0 {
+		e.logger.Info("Extractor", fmt.Sprintf("%d prefixes plus larges que /%d (IPv4) ou /%d (IPv6): %s",
+			broad, v4, v6, mark))
+	}
+}
@@ -0,0 +1,163 @@
+package extractor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/events"
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// errBudgetExhausted is returned by enrichUsingCache once a run budget is
+// spent: the record is left unenriched rather than queried.
+var errBudgetExhausted = errors.New("enrichment budget exhausted")
+
+// runBudget caps the RDAP calls, geolocation calls and duration of one
+// enrichment run. A zero limit is unlimited. Budgets are checked before each
+// record, so lookups already started finish and the counts may end a few
+// calls over.
+type runBudget struct {
+	maxRDAP, maxGeo int64
+	deadline        time.Time
+
+	rdapCalls, geoCalls int64
+}
+
+// newRunBudget returns the budget of a run started at start, or nil when
+// config sets none.
+func newRunBudget(config models.DatabaseConfig, start time.Time) *runBudget {
+	if config.MaxRDAPCalls <= 0 && config.MaxGeoCalls <= 0 && config.MaxRunMinutes <= 0 {
+		return nil
+	}
+	b := &runBudget{maxRDAP: int64(config.MaxRDAPCalls), maxGeo: int64(config.MaxGeoCalls)}
+	if config.MaxRunMinutes > 0 {
+		b.deadline = start.Add(time.Duration(config.MaxRunMinutes) * time.Minute)
+	}
+	return b
+}
+
+// exhausted returns why the budget is spent, or "" while it is not.
+func (b *runBudget) exhausted(now time.Time) string {
+	switch {
+	case b == nil:
+		return ""
+	case b.maxRDAP > 0 && atomic.LoadInt64(&b.rdapCalls) >= b.maxRDAP:
+		return fmt.Sprintf("max_rdap_calls (%d) reached", b.maxRDAP)
+	case b.maxGeo > 0 && atomic.LoadInt64(&b.geoCalls) >= b.maxGeo:
+		return fmt.Sprintf("max_geo_calls (%d) reached", b.maxGeo)
+	case !b.deadline.IsZero() && !now.Before(b.deadline):
+		return "max_run_minutes elapsed"
+	}
+	return ""
+}
+
+// check returns errBudgetExhausted, with the reason, once b is spent.
+func (b *runBudget) check() error {
+	if reason := b.exhausted(time.Now()); reason != "" {
+		return fmt.Errorf("%w: %s", errBudgetExhausted, reason)
+	}
+	return nil
+}
+
+func (b *runBudget) spendRDAP() {
+	if b != nil {
+		atomic.AddInt64(&b.rdapCalls, 1)
+	}
+}
+
+func (b *runBudget) spendGeo() {
+	if b != nil {
+		atomic.AddInt64(&b.geoCalls, 1)
+	}
+}
+
+// BudgetStop records an enrichment run stopped by its budget: why, the
+// calls made and the IPs left unenriched. Those IPs are enriched by the next
+// run, while the ones done come from the RDAP cache at no cost.
+type BudgetStop struct {
+	StoppedAt time.Time `json:"stopped_at"`
+	Reason    string    `json:"reason"`
+	RDAPCalls int64     `json:"rdap_calls"`
+	GeoCalls  int64     `json:"geo_calls"`
+	Remaining []string  `json:"remaining"`
+}
+
+// budgetStopFile returns the path of the last budget stop record.
+func (e *Extractor) budgetStopFile() string {
+	if e.budgetStopPath != "" {
+		return e.budgetStopPath
+	}
+	return filepath.Join("build", "data", "enrichment_remaining.json")
+}
+
+// LastBudgetStop returns the record of the last run stopped by its budget,
+// or nil when the last run completed.
+func (e *Extractor) LastBudgetStop() (*BudgetStop, error) {
+	b, err := os.ReadFile(e.budgetStopFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading budget stop record: %w", err)
+	}
+	var stop BudgetStop
+	if err := json.Unmarshal(b, &stop); err != nil {
+		return nil, fmt.Errorf("decoding budget stop record: %w", err)
+	}
+	return &stop, nil
+}
+
+// saveBudgetStop writes stop, or removes the previous record when stop is
+// nil.
+func (e *Extractor) saveBudgetStop(stop *BudgetStop) error {
+	path := e.budgetStopFile()
+	if stop == nil {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("removing budget stop record: %w", err)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating budget stop directory: %w", err)
+	}
+	b, err := json.MarshalIndent(stop, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding budget stop record: %w", err)
+	}
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		return fmt.Errorf("writing budget stop record: %w", err)
+	}
+	return nil
+}
+
+// recordBudgetStop logs and stores the IPs at indices unspent, left
+// unenriched by a run stopped by budget, or clears the previous record when
+// the run completed.
+func (e *Extractor) recordBudgetStop(budget *runBudget, ips []string, unspent []int) {
+	var stop *BudgetStop
+	if len(unspent) > 0 {
+		sort.Ints(unspent)
+		stop = &BudgetStop{
+			StoppedAt: time.Now().UTC(),
+			Reason:    budget.exhausted(time.Now()),
+			RDAPCalls: atomic.LoadInt64(&budget.rdapCalls),
+			GeoCalls:  atomic.LoadInt64(&budget.geoCalls),
+		}
+		for _, i := range unspent {
+			stop.Remaining = append(stop.Remaining, ips[i])
+		}
+		msg := fmt.Sprintf("Budget d'enrichissement epuise (%s): %d IPs non enrichies, reprises au prochain run",
+			stop.Reason, len(stop.Remaining))
+		e.logger.Warning("Extractor", msg)
+		e.publish(events.Warning, msg, len(stop.Remaining), len(ips))
+	}
+	if err := e.saveBudgetStop(stop); err != nil {
+		e.logger.Warning("Extractor", err.Error())
+	}
+}
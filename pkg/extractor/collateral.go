@@ -0,0 +1,213 @@
+package extractor
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+// Kinds of protected prefixes.
+const (
+	ProtectedOwn     = "own"     // the user's own prefixes
+	ProtectedPartner = "partner" // partners and customers
+	ProtectedService = "service" // known-good third-party services
+)
+
+// ErrCriticalCollateral is returned by ApproveEnforcement when the list to
+// publish would block a protected prefix of a critical kind.
+var ErrCriticalCollateral = errors.New("enforcement list blocks critical protected prefixes")
+
+// ProtectedPrefix is one entry of the protected prefixes file.
+type ProtectedPrefix struct {
+	Net   *net.IPNet
+	Kind  string
+	Label string
+}
+
+// CollateralMatch is a blocked IP or range overlapping a protected prefix.
+type CollateralMatch struct {
+	IP       string `json:"ip"`
+	Prefix   string `json:"prefix"`
+	Kind     string `json:"kind"`
+	Label    string `json:"label,omitempty"`
+	Critical bool   `json:"critical"`
+}
+
+// parseNet reads an IP or CIDR as a network, a single IP being a /32 or /128.
+func parseNet(s string) (*net.IPNet, error) {
+	if _, n, err := net.ParseCIDR(s); err == nil {
+		return n, nil
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP or CIDR %q", s)
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return &net.IPNet{IP: v4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// ParseProtectedPrefixes reads a protected prefixes list: one IP or CIDR per
+// line, optionally followed by its kind (own, partner or service; own when
+// left out) and a label. Blank lines and # comments are skipped.
+//
+//	203.0.113.0/24  own      Head office
+//	198.51.100.7    partner  Acme SFTP
+func ParseProtectedPrefixes(r io.Reader) ([]ProtectedPrefix, error) {
+	var out []ProtectedPrefix
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := sc.Text()
+		if i := strings.Index(text, "#"); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		n, err := parseNet(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		p := ProtectedPrefix{Net: n, Kind: ProtectedOwn}
+		if len(fields) > 1 {
+			p.Kind = strings.ToLower(fields[1])
+			if !ValidProtectedKind(p.Kind) {
+				return nil, fmt.Errorf("line %d: unknown kind %q (want own, partner or service)", line, fields[1])
+			}
+			p.Label = strings.Join(fields[2:], " ")
+		}
+		out = append(out, p)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("reading protected prefixes: %w", err)
+	}
+	return out, nil
+}
+
+// ValidProtectedKind reports whether kind is a protected prefix kind.
+func ValidProtectedKind(kind string) bool {
+	switch kind {
+	case ProtectedOwn, ProtectedPartner, ProtectedService:
+		return true
+	}
+	return false
+}
+
+// overlaps reports whether two networks share any address.
+func overlaps(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// CollateralMatches returns the entries of ips, the list to publish, that
+// overlap a protected prefix. Matches of a kind listed in critical are
+// flagged critical. Entries that are not IPs or CIDRs are ignored.
+func CollateralMatches(ips []string, protected []ProtectedPrefix, critical []string) []CollateralMatch {
+	isCritical := map[string]bool{}
+	for _, k := range critical {
+		isCritical[strings.ToLower(strings.TrimSpace(k))] = true
+	}
+	var out []CollateralMatch
+	for _, ip := range ips {
+		n, err := parseNet(strings.TrimSpace(ip))
+		if err != nil {
+			continue
+		}
+		for _, p := range protected {
+			if overlaps(n, p.Net) {
+				out = append(out, CollateralMatch{
+					IP:       ip,
+					Prefix:   p.Net.String(),
+					Kind:     p.Kind,
+					Label:    p.Label,
+					Critical: isCritical[p.Kind],
+				})
+			}
+		}
+	}
+	return out
+}
+
+// collateral checks ips against the configured protected prefixes file.
+// It returns nothing when no file is configured.
+func (e *Extractor) collateral(ips []string) ([]CollateralMatch, error) {
+	cfg := e.settings()
+	if cfg.ProtectedPrefixesFile == "" {
+		return nil, nil
+	}
+	f, err := os.Open(cfg.ProtectedPrefixesFile)
+	if err != nil {
+		return nil, fmt.Errorf("opening protected prefixes: %w", err)
+	}
+	defer f.Close()
+	protected, err := ParseProtectedPrefixes(f)
+	if err != nil {
+		return nil, err
+	}
+	return CollateralMatches(ips, protected, cfg.CollateralCriticalKinds), nil
+}
+
+// AllowlistIPs appends ips to the protected prefixes file as kind, with an
+// optional label, so later enforcement lists are checked against them. IPs
+// already covered by an entry of the file are skipped. It returns the number
+// of entries added.
+func (e *Extractor) AllowlistIPs(ips []string, kind, label string) (int, error) {
+	path := e.settings().ProtectedPrefixesFile
+	if path == "" {
+		return 0, fmt.Errorf("no protected prefixes file configured")
+	}
+	if !ValidProtectedKind(kind) {
+		return 0, fmt.Errorf("unknown kind %q (want own, partner or service)", kind)
+	}
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, fmt.Errorf("reading protected prefixes: %w", err)
+	}
+	protected, err := ParseProtectedPrefixes(bytes.NewReader(existing))
+	if err != nil {
+		return 0, err
+	}
+
+	var lines []string
+	for _, ip := range ips {
+		ip = strings.TrimSpace(ip)
+		n, err := parseNet(ip)
+		if err != nil {
+			return 0, err
+		}
+		covered := false
+		for _, p := range protected {
+			ones, _ := n.Mask.Size()
+			pOnes, _ := p.Net.Mask.Size()
+			covered = covered || (p.Net.Contains(n.IP) && pOnes <= ones)
+		}
+		if covered {
+			continue
+		}
+		protected = append(protected, ProtectedPrefix{Net: n, Kind: kind, Label: label})
+		lines = append(lines, strings.TrimSpace(fmt.Sprintf("%s %s %s", ip, kind, label)))
+	}
+	if len(lines) == 0 {
+		return 0, nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("opening protected prefixes: %w", err)
+	}
+	defer f.Close()
+	text := strings.Join(lines, "\n") + "\n"
+	if len(existing) > 0 && existing[len(existing)-1] != '\n' {
+		text = "\n" + text
+	}
+	if _, err := f.WriteString(text); err != nil {
+		return 0, fmt.Errorf("writing protected prefixes: %w", err)
+	}
+	e.logger.Info("Extractor", fmt.Sprintf("%d IPs ajoutees aux prefixes proteges (%s)", len(lines), kind))
+	return len(lines), nil
+}
@@ -0,0 +1,194 @@
+package extractor
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// CanonicalIP returns the canonical form of an IP or CIDR: IPv6 addresses
+// compressed and lower-cased, ranges reduced to their network address.
+// Anything else is returned trimmed.
+func CanonicalIP(s string) string {
+	s = strings.TrimSpace(s)
+	if _, n, err := net.ParseCIDR(s); err == nil {
+		return n.String()
+	}
+	if ip := net.ParseIP(s); ip != nil {
+		return ip.String()
+	}
+	return s
+}
+
+// DuplicateGroup is a set of records for the same IP or range and scanner.
+type DuplicateGroup struct {
+	// Key is the canonical IP and the scanner name
+	Key string
+	// Indices are the positions of the records in the dataset, in order
+	Indices []int
+}
+
+// dedupKey identifies the records FindDuplicates considers the same. A
+// record per scanner listing an IP is expected and is not a duplicate.
+func dedupKey(item models.ScannerData) string {
+	return CanonicalIP(item.IPOrCIDR) + " " + strings.ToLower(item.ScannerName)
+}
+
+// FindDuplicates returns the groups of records of data with the same
+// canonical IP and scanner, such as the same IP under two IDs or two
+// spellings of an IPv6 address, in the order of their first record.
+func FindDuplicates(data []models.ScannerData) []DuplicateGroup {
+	byKey := map[string]int{}
+	var groups []DuplicateGroup
+	for i, item := range data {
+		k := dedupKey(item)
+		if g, ok := byKey[k]; ok {
+			groups[g].Indices = append(groups[g].Indices, i)
+			continue
+		}
+		byKey[k] = len(groups)
+		groups = append(groups, DuplicateGroup{Key: k, Indices: []int{i}})
+	}
+	out := groups[:0]
+	for _, g := range groups {
+		if len(g.Indices) > 1 {
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+// fillString sets *dst to src when *dst is empty.
+func fillString(dst *string, src string) {
+	if *dst == "" {
+		*dst = src
+	}
+}
+
+// MergeDuplicateRecords merges duplicates of one record. The most recently
+// updated record is kept and its empty enrichment fields are filled from
+// the others; tags, annotations and provenance are merged, first seen is
+// the earliest and last seen the latest. The IP is written in canonical
+// form.
+func MergeDuplicateRecords(records []models.ScannerData) models.ScannerData {
+	sorted := append([]models.ScannerData(nil), records...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].UpdatedAt.After(sorted[j].UpdatedAt) })
+	m := sorted[0]
+	m.IPOrCIDR = CanonicalIP(m.IPOrCIDR)
+	m.Tags = append([]string(nil), m.Tags...)
+	m.Annotations = append([]models.Annotation(nil), m.Annotations...)
+	provenance := make(map[string]string, len(m.Provenance))
+	for g, p := range m.Provenance {
+		provenance[g] = p
+	}
+	m.Provenance = provenance
+	for _, o := range sorted[1:] {
+		for _, f := range []struct {
+			dst *string
+			src string
+		}{
+			{&m.CountryCode, o.CountryCode}, {&m.CountryName, o.CountryName}, {&m.ISP, o.ISP},
+			{&m.Organization, o.Organization}, {&m.UsageType, o.UsageType}, {&m.Domain, o.Domain},
+			{&m.RDAPName, o.RDAPName}, {&m.RDAPHandle, o.RDAPHandle}, {&m.RDAPCIDR, o.RDAPCIDR},
+			{&m.Registry, o.Registry}, {&m.StartAddress, o.StartAddress}, {&m.EndAddress, o.EndAddress},
+			{&m.IPVersion, o.IPVersion}, {&m.RDAPType, o.RDAPType}, {&m.ParentHandle, o.ParentHandle},
+			{&m.ASN, o.ASN}, {&m.ASName, o.ASName}, {&m.ReverseDNS, o.ReverseDNS},
+			{&m.PeeringDBName, o.PeeringDBName}, {&m.NetworkType, o.NetworkType},
+			{&m.TrafficLevel, o.TrafficLevel}, {&m.PeeringDBContacts, o.PeeringDBContacts},
+			{&m.AbuseEmail, o.AbuseEmail}, {&m.TechEmail, o.TechEmail}, {&m.Notes, o.Notes},
+		} {
+			fillString(f.dst, f.src)
+		}
+		if m.EventRegistration.IsZero() {
+			m.EventRegistration = o.EventRegistration
+		}
+		if m.EventLastChanged.IsZero() {
+			m.EventLastChanged = o.EventLastChanged
+		}
+		if !o.FirstSeen.IsZero() && (m.FirstSeen.IsZero() || o.FirstSeen.Before(m.FirstSeen)) {
+			m.FirstSeen = o.FirstSeen
+		}
+		if o.LastSeen.After(m.LastSeen) {
+			m.LastSeen = o.LastSeen
+		}
+		if o.RunsSeen > m.RunsSeen {
+			m.RunsSeen = o.RunsSeen
+		}
+		if o.AbuseConfidenceScore > m.AbuseConfidenceScore {
+			m.AbuseConfidenceScore = o.AbuseConfidenceScore
+		}
+		if len(m.OpenPorts) == 0 && len(m.Hostnames) == 0 && len(m.CPEs) == 0 {
+			m.OpenPorts, m.Hostnames, m.CPEs = o.OpenPorts, o.Hostnames, o.CPEs
+		}
+		m.TargetPorts = models.MergePorts(m.TargetPorts, o.TargetPorts)
+		if !m.HasLocation() {
+			m.Latitude, m.Longitude = o.Latitude, o.Longitude
+		}
+		if m.ContinentCode == "" {
+			m.Continent, m.ContinentCode = o.Continent, o.ContinentCode
+		}
+		if m.Region == "" {
+			m.Region = o.Region
+		}
+		for _, t := range o.Tags {
+			if !containsString(m.Tags, t) {
+				m.Tags = append(m.Tags, t)
+			}
+		}
+		for _, a := range o.Annotations {
+			known := false
+			for _, b := range m.Annotations {
+				known = known || a.ID == b.ID
+			}
+			if !known {
+				m.Annotations = append(m.Annotations, a)
+			}
+		}
+		for g, p := range o.Provenance {
+			if _, ok := m.Provenance[g]; !ok {
+				m.Provenance[g] = p
+			}
+		}
+	}
+	return m
+}
+
+// MergeDuplicates returns data with each of groups merged into its first
+// record (see MergeDuplicateRecords), the other records of the group removed.
+func MergeDuplicates(data []models.ScannerData, groups []DuplicateGroup) []models.ScannerData {
+	merged := map[int]models.ScannerData{}
+	drop := map[int]bool{}
+	for _, g := range groups {
+		records := make([]models.ScannerData, 0, len(g.Indices))
+		for _, i := range g.Indices {
+			records = append(records, data[i])
+			drop[i] = true
+		}
+		merged[g.Indices[0]] = MergeDuplicateRecords(records)
+	}
+	out := make([]models.ScannerData, 0, len(data))
+	for i, item := range data {
+		if m, ok := merged[i]; ok {
+			out = append(out, m)
+		} else if !drop[i] {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// Deduplicate merges every group of duplicates of data and logs how many
+// records were removed.
+func (e *Extractor) Deduplicate(data []models.ScannerData) []models.ScannerData {
+	groups := FindDuplicates(data)
+	if len(groups) == 0 {
+		return data
+	}
+	out := MergeDuplicates(data, groups)
+	e.logger.Info("Extractor", fmt.Sprintf("Doublons: %d groupes fusionnes, %d enregistrements supprimes",
+		len(groups), len(data)-len(out)))
+	return out
+}
@@ -0,0 +1,224 @@
+package extractor
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// FieldChange is one field of a record that differs between two datasets.
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// RecordChange is a record present in both datasets with different values.
+type RecordChange struct {
+	Before  models.ScannerData `json:"before"`
+	After   models.ScannerData `json:"after"`
+	Changes []FieldChange      `json:"changes"`
+}
+
+// DatasetDiff lists the records added, removed and changed from one dataset
+// to another.
+type DatasetDiff struct {
+	Added   []models.ScannerData `json:"added"`
+	Removed []models.ScannerData `json:"removed"`
+	Changed []RecordChange       `json:"changed"`
+}
+
+// Empty reports whether both datasets hold the same records.
+func (d DatasetDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// diffFields returns the compared fields of item, in display order.
+func diffFields(item models.ScannerData) [][2]string {
+	return [][2]string{
+		{"risk", item.RiskLevel},
+		{"state", string(item.State)},
+		{"country", item.CountryCode},
+		{"asn", item.ASN},
+		{"rdap_name", item.RDAPName},
+		{"rdap_handle", item.RDAPHandle},
+		{"rdap_cidr", item.RDAPCIDR},
+		{"abuse_email", item.AbuseEmail},
+		{"abuse_score", strconv.Itoa(item.AbuseConfidenceScore)},
+		{"tags", strings.Join(item.Tags, ",")},
+	}
+}
+
+// DiffDatasets compares before with after. Records are matched by canonical
+// IP and scanner, as for FindDuplicates; a record is changed when its risk,
+// state, country, ASN, RDAP owner, abuse contact or score, or tags differ.
+// Added and removed records keep the order of their dataset.
+func DiffDatasets(before, after []models.ScannerData) DatasetDiff {
+	old := make(map[string]models.ScannerData, len(before))
+	for _, item := range before {
+		old[dedupKey(item)] = item
+	}
+	var d DatasetDiff
+	seen := make(map[string]bool, len(after))
+	for _, item := range after {
+		k := dedupKey(item)
+		seen[k] = true
+		prev, ok := old[k]
+		if !ok {
+			d.Added = append(d.Added, item)
+			continue
+		}
+		var changes []FieldChange
+		pf, af := diffFields(prev), diffFields(item)
+		for i := range af {
+			if pf[i][1] != af[i][1] {
+				changes = append(changes, FieldChange{Field: af[i][0], Before: pf[i][1], After: af[i][1]})
+			}
+		}
+		if len(changes) > 0 {
+			d.Changed = append(d.Changed, RecordChange{Before: prev, After: item, Changes: changes})
+		}
+	}
+	for _, item := range before {
+		if !seen[dedupKey(item)] {
+			d.Removed = append(d.Removed, item)
+		}
+	}
+	return d
+}
+
+// WriteCSV writes d as CSV with the columns change, ip, scanner, field,
+// before and after: one row per added or removed record, with no field, and
+// one per field of each changed record.
+func (d DatasetDiff) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	rows := [][]string{{"change", "ip", "scanner", "field", "before", "after"}}
+	for _, item := range d.Added {
+		rows = append(rows, []string{"added", item.IPOrCIDR, item.ScannerName, "", "", ""})
+	}
+	for _, item := range d.Removed {
+		rows = append(rows, []string{"removed", item.IPOrCIDR, item.ScannerName, "", "", ""})
+	}
+	for _, c := range d.Changed {
+		for _, f := range c.Changes {
+			rows = append(rows, []string{"changed", c.After.IPOrCIDR, c.After.ScannerName, f.Field, f.Before, f.After})
+		}
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return fmt.Errorf("writing diff CSV: %w", err)
+	}
+	return nil
+}
+
+// runFileSuffix ends the names SaveRun gives its CSV files.
+const runFileSuffix = "_liacheckscanner.csv"
+
+// RunDiff is the difference between two runs saved by SaveRun.
+type RunDiff struct {
+	From string `json:"from"` // older run file name
+	To   string `json:"to"`   // newer run file name
+	DatasetDiff
+}
+
+// Runs returns the names of the runs saved by SaveRun in the results
+// directory, oldest first.
+func (e *Extractor) Runs() ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(e.settings().ResultsDir, "*"+runFileSuffix))
+	if err != nil {
+		return nil, fmt.Errorf("listing runs: %w", err)
+	}
+	names := make([]string, len(paths))
+	for i, p := range paths {
+		names[i] = filepath.Base(p)
+	}
+	// The names start with the run time, so they sort chronologically
+	sort.Strings(names)
+	return names, nil
+}
+
+// DiffRuns compares the latest run saved by SaveRun with the one before:
+// the IPs newly added, removed and changed (see DiffDatasets).
+func (e *Extractor) DiffRuns() (RunDiff, error) {
+	runs, err := e.Runs()
+	if err != nil {
+		return RunDiff{}, err
+	}
+	if len(runs) < 2 {
+		return RunDiff{}, fmt.Errorf("need two saved runs to compare, found %d in %s", len(runs), e.settings().ResultsDir)
+	}
+	return e.DiffRunFiles(runs[len(runs)-2], runs[len(runs)-1])
+}
+
+// DiffRunFiles compares the runs from and to, given by name in the results
+// directory or by path.
+func (e *Extractor) DiffRunFiles(from, to string) (RunDiff, error) {
+	before, err := ReadCSVFile(e.runPath(from))
+	if err != nil {
+		return RunDiff{}, fmt.Errorf("reading run %s: %w", from, err)
+	}
+	after, err := ReadCSVFile(e.runPath(to))
+	if err != nil {
+		return RunDiff{}, fmt.Errorf("reading run %s: %w", to, err)
+	}
+	return RunDiff{From: from, To: to, DatasetDiff: DiffDatasets(before, after)}, nil
+}
+
+// runPath returns the file of run: a bare name is looked up in the results
+// directory, a path is used as is.
+func (e *Extractor) runPath(run string) string {
+	if filepath.Base(run) != run {
+		return run
+	}
+	return filepath.Join(e.settings().ResultsDir, run)
+}
+
+// WriteRunDiff writes d to w as "csv" (see DatasetDiff.WriteCSV) or "json".
+func WriteRunDiff(w io.Writer, d RunDiff, format string) error {
+	switch format {
+	case "csv":
+		return d.WriteCSV(w)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(d); err != nil {
+			return fmt.Errorf("encoding diff JSON: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported diff format %q (want csv or json)", format)
+}
+
+// SaveRunDiff writes d to filename in the results directory, as JSON when
+// the name ends with .json and as CSV otherwise.
+func (e *Extractor) SaveRunDiff(d RunDiff, filename string) error {
+	dir := e.settings().ResultsDir
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating results directory: %w", err)
+	}
+	path := filepath.Join(dir, filename)
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating diff file %s: %w", path, err)
+	}
+	format := "csv"
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		format = "json"
+	}
+	if err := WriteRunDiff(f, d, format); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing diff file %s: %w", path, err)
+	}
+	e.logger.Info("Extractor", "Differences sauvegardees: "+path)
+	return nil
+}
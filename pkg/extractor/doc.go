@@ -0,0 +1,25 @@
+// Package extractor turns public internet-scanner lists into enriched
+// ScannerData records.
+//
+// It synchronizes the upstream scanner repository, parses its .nft files for
+// IPv4/IPv6 addresses, maps each address to the scanner that lists it, and
+// enriches records with RDAP registry data, geolocation and reverse DNS.
+// Results can be written to CSV or JSON.
+//
+// The package has no GUI dependency and can be embedded by other Go programs:
+//
+//	ext := extractor.NewExtractor(models.DatabaseConfig{
+//		LocalPath:  "./data/internet-scanners",
+//		ResultsDir: "./results",
+//	}, nil)
+//	ips, err := ext.ExtractIPsOnly(ctx)
+//	data, err := ext.EnrichIPs(ctx, ips)
+//	err = ext.SaveToJSON(data, "scanners.json")
+//
+// ExtractData chains these steps and also saves a timestamped CSV;
+// SetAutoSave(false) leaves writing the result to the caller. Cancelling
+// ctx stops a run cleanly, aborting the requests in flight.
+//
+// Each pipeline stage is described by an interface (SourceSyncer, Parser,
+// Enricher, Store, Exporter) and can be replaced individually.
+package extractor
@@ -0,0 +1,219 @@
+package extractor
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// maxDossierHits caps the hits listed in a dossier; the total is always shown.
+const maxDossierHits = 50
+
+// Dossier gathers what is known about one IP or range for a ticket or an
+// escalation: the records of every feed listing it, its greylisting history,
+// the analysts' annotations and the honeypot hits.
+type Dossier struct {
+	IP          string
+	GeneratedAt time.Time
+	// Records are the dataset records for IP, one per feed listing it
+	Records     []models.ScannerData
+	Lifecycle   *models.LifecycleEntry
+	Annotations []models.Annotation
+	// Hits are the hits from IP or inside its range, newest first, and
+	// HitTotal their number before the list was capped
+	Hits     []models.Hit
+	HitTotal int
+}
+
+// BuildDossier compiles the dossier of ip from data and the stored
+// greylisting history, annotations and hits.
+func (e *Extractor) BuildDossier(ip string, data []models.ScannerData) (Dossier, error) {
+	ip = strings.TrimSpace(ip)
+	n, err := parseNet(ip)
+	if err != nil {
+		return Dossier{}, err
+	}
+	d := Dossier{IP: ip, GeneratedAt: time.Now()}
+	for _, item := range data {
+		if item.IPOrCIDR == ip {
+			d.Records = append(d.Records, item)
+		}
+	}
+	if len(d.Records) == 0 {
+		return Dossier{}, fmt.Errorf("%s is not in the dataset", ip)
+	}
+
+	entries, err := e.LifecycleEntries()
+	if err != nil {
+		return Dossier{}, err
+	}
+	if entry, ok := entries[ip]; ok {
+		d.Lifecycle = &entry
+	}
+	if d.Annotations, err = e.Annotations(ip); err != nil {
+		return Dossier{}, err
+	}
+	hits, err := e.Hits()
+	if err != nil {
+		return Dossier{}, err
+	}
+	for _, h := range hits {
+		if hip := net.ParseIP(h.IP); hip != nil && n.Contains(hip) {
+			d.Hits = append(d.Hits, h)
+		}
+	}
+	sort.SliceStable(d.Hits, func(i, j int) bool { return d.Hits[i].Timestamp.After(d.Hits[j].Timestamp) })
+	d.HitTotal = len(d.Hits)
+	if len(d.Hits) > maxDossierHits {
+		d.Hits = d.Hits[:maxDossierHits]
+	}
+	return d, nil
+}
+
+// Record returns the first record of the dossier, which carries the
+// enrichment shared by every feed listing the IP.
+func (d Dossier) Record() models.ScannerData {
+	if len(d.Records) == 0 {
+		return models.ScannerData{}
+	}
+	return d.Records[0]
+}
+
+var dossierFuncs = template.FuncMap{
+	"ts": models.FormatTimestamp,
+	"join": func(s []string) string {
+		return strings.Join(s, ", ")
+	},
+	"provenance": models.FormatProvenance,
+	"ports":      models.FormatPorts,
+	"owner":      OwnerLabel,
+}
+
+var dossierTemplate = template.Must(template.New("dossier").Funcs(dossierFuncs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Dossier {{.IP}}</title>
+<style>
+body { font-family: sans-serif; font-size: 11pt; margin: 2em; color: #111; }
+h1 { font-size: 18pt; margin-bottom: 0; }
+h2 { font-size: 13pt; border-bottom: 1px solid #999; margin-top: 1.5em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; vertical-align: top; padding: 2px 8px 2px 0; border-bottom: 1px solid #ddd; }
+th { width: 12em; font-weight: bold; }
+thead th { width: auto; }
+.meta { color: #555; }
+@media print { body { margin: 0; } h2 { page-break-after: avoid; } tr { page-break-inside: avoid; } }
+</style>
+</head>
+<body>
+{{with .Record}}
+<h1>{{.IPOrCIDR}}</h1>
+<p class="meta">Generated {{ts $.GeneratedAt}} by LiaCheckScanner</p>
+
+<h2>Summary</h2>
+<table>
+<tr><th>State</th><td>{{.State}}{{with $.Lifecycle}}{{if .Pinned}} (pinned){{end}}{{end}}</td></tr>
+<tr><th>Risk</th><td>{{.RiskLevel}}{{if .Stale}} (stale){{end}}</td></tr>
+<tr><th>Listed by</th><td>{{len $.Records}} feeds</td></tr>
+<tr><th>Honeypot hits</th><td>{{$.HitTotal}}{{if $.HitTotal}} (last: {{ts .LastHit}}){{end}}</td></tr>
+<tr><th>Tags</th><td>{{join .Tags}}</td></tr>
+{{if .TargetPorts}}<tr><th>Target ports</th><td>{{ports .TargetPorts}}</td></tr>{{end}}
+{{if .BroadPrefix}}<tr><th>Broad prefix</th><td>{{.BroadPrefix}}</td></tr>{{end}}
+</table>
+
+<h2>Enrichment</h2>
+<table>
+<tr><th>RDAP name</th><td>{{.RDAPName}}</td></tr>
+<tr><th>RDAP handle</th><td>{{.RDAPHandle}}</td></tr>
+<tr><th>Network</th><td>{{.RDAPCIDR}} ({{.StartAddress}} - {{.EndAddress}})</td></tr>
+<tr><th>Registry</th><td>{{.Registry}}</td></tr>
+<tr><th>Registered</th><td>{{ts .EventRegistration}}</td></tr>
+<tr><th>Last changed</th><td>{{ts .EventLastChanged}}</td></tr>
+<tr><th>ASN</th><td>{{.ASN}} {{.ASName}}</td></tr>
+<tr><th>Country</th><td>{{.CountryName}} ({{.CountryCode}})</td></tr>
+<tr><th>ISP</th><td>{{.ISP}}</td></tr>
+<tr><th>Reverse DNS</th><td>{{.ReverseDNS}}</td></tr>
+<tr><th>Abuse contact</th><td>{{.AbuseEmail}}</td></tr>
+<tr><th>Tech contact</th><td>{{.TechEmail}}</td></tr>
+<tr><th>PeeringDB</th><td>{{.PeeringDBName}} {{.NetworkType}} {{.TrafficLevel}}</td></tr>
+<tr><th>Abuse score</th><td>{{.AbuseConfidenceScore}} ({{.AbuseReports}} reports)</td></tr>
+{{if .Provenance}}<tr><th>Sources</th><td>{{provenance .Provenance}}</td></tr>{{end}}
+</table>
+
+<h2>History</h2>
+<table>
+{{with $.Lifecycle}}
+<tr><th>First seen</th><td>{{.FirstSeen}}</td></tr>
+<tr><th>Last seen</th><td>{{.LastSeen}}</td></tr>
+<tr><th>Consecutive runs</th><td>{{.RunsSeen}} seen, {{.MissedRuns}} missed</td></tr>
+{{else}}
+<tr><th>Greylisting</th><td>No history recorded</td></tr>
+{{end}}
+{{if .PreviousOwner}}<tr><th>Ownership changed</th><td>{{.PreviousOwner}} -> {{owner .RDAPName .RDAPHandle}}</td></tr>{{end}}
+</table>
+{{end}}
+
+<h2>Feed membership</h2>
+<table>
+<thead><tr><th>Scanner</th><th>Type</th><th>Source</th><th>First seen</th><th>Last seen</th></tr></thead>
+{{range .Records}}<tr><td>{{.ScannerName}}</td><td>{{.ScannerType}}</td><td>{{.SourceFile}}</td><td>{{ts .FirstSeen}}</td><td>{{ts .LastSeen}}</td></tr>
+{{end}}
+</table>
+
+{{if .Annotations}}
+<h2>Annotations</h2>
+<table>
+<thead><tr><th>Date</th><th>Author</th><th>Tags</th><th>Note</th></tr></thead>
+{{range .Annotations}}<tr><td>{{.CreatedAt}}</td><td>{{.Author}}</td><td>{{join .Tags}}</td><td>{{.Note}}</td></tr>
+{{end}}
+</table>
+{{end}}
+
+{{if .Hits}}
+<h2>Honeypot hits</h2>
+{{if gt .HitTotal (len .Hits)}}<p class="meta">Latest {{len .Hits}} of {{.HitTotal}}</p>{{end}}
+<table>
+<thead><tr><th>Time</th><th>Source</th><th>Port</th></tr></thead>
+{{range .Hits}}<tr><td>{{ts .Timestamp}}</td><td>{{.IP}}</td><td>{{if .Port}}{{.Port}}{{end}}</td></tr>
+{{end}}
+</table>
+{{end}}
+</body>
+</html>
+`))
+
+// WriteDossierHTML writes d as a printable HTML page.
+func WriteDossierHTML(w io.Writer, d Dossier) error {
+	if err := dossierTemplate.Execute(w, d); err != nil {
+		return fmt.Errorf("rendering dossier: %w", err)
+	}
+	return nil
+}
+
+// SaveDossier writes d as HTML to filename in the results directory and
+// returns its path.
+func (e *Extractor) SaveDossier(d Dossier, filename string) (string, error) {
+	if err := os.MkdirAll(e.settings().ResultsDir, 0755); err != nil {
+		return "", fmt.Errorf("creating results directory: %w", err)
+	}
+	filePath := filepath.Join(e.settings().ResultsDir, filename)
+	file, err := os.Create(filePath)
+	if err != nil {
+		return "", fmt.Errorf("creating dossier %s: %w", filePath, err)
+	}
+	defer file.Close()
+	if err := WriteDossierHTML(file, d); err != nil {
+		return "", err
+	}
+	e.logger.Info("Extractor", fmt.Sprintf("Dossier %s sauvegarde: %s", d.IP, filePath))
+	return filePath, nil
+}
@@ -0,0 +1,244 @@
+package extractor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// defaultExpiryWarnDays is how long before an IP goes stale its expiry is
+// announced, when ExpiryWarnDays is not set.
+const defaultExpiryWarnDays = 7
+
+// ExpiryWarning is an IP on the watchlist or in the published enforcement
+// list that aging or greylisting is about to retire.
+type ExpiryWarning struct {
+	IP       string    `json:"ip"`
+	Scanner  string    `json:"scanner,omitempty"`
+	Watched  bool      `json:"watched"`  // on the watchlist
+	Enforced bool      `json:"enforced"` // in the last approved enforcement list
+	LastSeen time.Time `json:"last_seen"`
+
+	// StaleAt is when the IP leaves enforcement exports for not being seen
+	// (see ApplyAging), zero when that is not within the warning period
+	StaleAt time.Time `json:"stale_at,omitempty"`
+
+	// RetiresNextRun is set when one more run without the IP retires it
+	// (see UpdateLifecycle)
+	RetiresNextRun bool `json:"retires_next_run,omitempty"`
+}
+
+// String describes w for logs and the GUI.
+func (w ExpiryWarning) String() string {
+	var refs []string
+	if w.Watched {
+		refs = append(refs, "watchlist")
+	}
+	if w.Enforced {
+		refs = append(refs, "enforcement list")
+	}
+	var when []string
+	if !w.StaleAt.IsZero() {
+		when = append(when, "stale on "+w.StaleAt.Format("2006-01-02"))
+	}
+	if w.RetiresNextRun {
+		when = append(when, "retired at the next run without it")
+	}
+	s := w.IP
+	if w.Scanner != "" {
+		s += " (" + w.Scanner + ")"
+	}
+	return fmt.Sprintf("%s [%s]: %s", s, strings.Join(refs, ", "), strings.Join(when, ", "))
+}
+
+// expiryWarnDays returns the configured warning period, falling back to the
+// default.
+func (e *Extractor) expiryWarnDays() int {
+	if days := e.settings().ExpiryWarnDays; days > 0 {
+		return days
+	}
+	return defaultExpiryWarnDays
+}
+
+// ExpiryWarnings returns the IPs on the watchlist or in the last approved
+// enforcement list that go stale within ExpiryWarnDays of now, or that the
+// next run without them retires, sorted by IP. IPs already stale or
+// retired, and pinned IPs, are left out: they are not about to change.
+func (e *Extractor) ExpiryWarnings(data []models.ScannerData, now time.Time) ([]ExpiryWarning, error) {
+	entries, err := e.LifecycleEntries()
+	if err != nil {
+		return nil, err
+	}
+	approvals, err := e.loadApprovals()
+	if err != nil {
+		return nil, err
+	}
+	var watchlist []*net.IPNet
+	for _, s := range e.settings().Watchlist {
+		if n, err := parseNet(strings.TrimSpace(s)); err == nil {
+			watchlist = append(watchlist, n)
+		}
+	}
+	watched := func(ip string) bool {
+		n, err := parseNet(ip)
+		if err != nil {
+			return false
+		}
+		for _, w := range watchlist {
+			if overlaps(w, n) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Every IP of interest, with its most recent record when data has one
+	candidates := map[string]*ExpiryWarning{}
+	add := func(ip string) *ExpiryWarning {
+		w, ok := candidates[ip]
+		if !ok {
+			w = &ExpiryWarning{IP: ip}
+			candidates[ip] = w
+		}
+		return w
+	}
+	for _, ip := range approvals.Published {
+		add(ip).Enforced = true
+	}
+	for _, item := range data {
+		if item.IPOrCIDR == "" {
+			continue
+		}
+		w, ok := candidates[item.IPOrCIDR]
+		if !ok && !watched(item.IPOrCIDR) {
+			continue
+		}
+		if !ok {
+			w = add(item.IPOrCIDR)
+		}
+		if item.LastSeen.After(w.LastSeen) {
+			w.LastSeen = item.LastSeen
+			w.Scanner = item.ScannerName
+		}
+	}
+	for ip := range entries {
+		if _, ok := candidates[ip]; !ok && watched(ip) {
+			add(ip)
+		}
+	}
+
+	_, staleAfterDays := e.agingDays()
+	grace := time.Duration(staleAfterDays) * 24 * time.Hour
+	warn := time.Duration(e.expiryWarnDays()) * 24 * time.Hour
+	_, _, retireRuns := e.dwellRuns()
+	var out []ExpiryWarning
+	for ip, w := range candidates {
+		w.Watched = watched(ip)
+		entry, tracked := entries[ip]
+		if tracked {
+			if entry.Pinned || entry.State == models.StateRetired {
+				continue
+			}
+			if t, err := models.ParseTimestamp(entry.LastSeen); err == nil && t.After(w.LastSeen) {
+				w.LastSeen = t
+			}
+			w.RetiresNextRun = entry.MissedRuns+1 >= retireRuns && entry.MissedRuns > 0
+		}
+		if !w.LastSeen.IsZero() {
+			staleAt := w.LastSeen.Add(grace)
+			if staleAt.After(now) && staleAt.Sub(now) <= warn {
+				w.StaleAt = staleAt
+			}
+		}
+		if !w.StaleAt.IsZero() || w.RetiresNextRun {
+			out = append(out, *w)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].IP < out[j].IP })
+	return out, nil
+}
+
+// expiryNoticeFile returns the path of the record of expiries already sent
+// to the webhook.
+func (e *Extractor) expiryNoticeFile() string {
+	if e.expiryNoticePath != "" {
+		return e.expiryNoticePath
+	}
+	return filepath.Join("build", "data", "expiry_notices.json")
+}
+
+// expiryNoticeKey identifies the expiry w announces, so the webhook hears of
+// each deadline once.
+func expiryNoticeKey(w ExpiryWarning) string {
+	key := w.IP + "|" + w.StaleAt.Format("2006-01-02")
+	if w.RetiresNextRun {
+		key += "|retire"
+	}
+	return key
+}
+
+// NotifyExpiry POSTs the warnings the ExpiryWebhookURL has not been sent
+// yet as one JSON document, {"event": "records_expiring", "warnings": [...]},
+// and returns how many were sent. It does nothing without a webhook.
+func (e *Extractor) NotifyExpiry(ctx context.Context, warnings []ExpiryWarning) (int, error) {
+	hook := e.settings().ExpiryWebhookURL
+	if hook == "" || len(warnings) == 0 {
+		return 0, nil
+	}
+	sent := map[string]bool{}
+	if b, err := os.ReadFile(e.expiryNoticeFile()); err == nil {
+		var keys []string
+		if err := json.Unmarshal(b, &keys); err != nil {
+			return 0, fmt.Errorf("decoding expiry notices: %w", err)
+		}
+		for _, k := range keys {
+			sent[k] = true
+		}
+	} else if !os.IsNotExist(err) {
+		return 0, fmt.Errorf("reading expiry notices: %w", err)
+	}
+
+	var fresh []ExpiryWarning
+	var keys []string
+	for _, w := range warnings {
+		k := expiryNoticeKey(w)
+		keys = append(keys, k)
+		if !sent[k] {
+			fresh = append(fresh, w)
+		}
+	}
+	if len(fresh) > 0 {
+		body, err := json.Marshal(map[string]any{"event": "records_expiring", "warnings": fresh})
+		if err != nil {
+			return 0, fmt.Errorf("encoding expiry notice: %w", err)
+		}
+		header := http.Header{"Content-Type": {"application/json"}}
+		if err := e.sendHTTP(ctx, http.MethodPost, hook, header, string(body)); err != nil {
+			return 0, fmt.Errorf("sending expiry notice: %w", err)
+		}
+		e.logger.Info("Extractor", fmt.Sprintf("Expirations notifiees: %d IPs", len(fresh)))
+	}
+
+	// Only the current warnings are kept, so a deadline that moves is sent again
+	path := e.expiryNoticeFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, fmt.Errorf("creating expiry notice directory: %w", err)
+	}
+	b, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return 0, fmt.Errorf("encoding expiry notices: %w", err)
+	}
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		return 0, fmt.Errorf("writing expiry notices: %w", err)
+	}
+	return len(fresh), nil
+}
@@ -0,0 +1,70 @@
+package extractor
+
+import (
+	"strings"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// ExportFilter selects the records to export, e.g. before generating a
+// firewall blocklist. Each field lists the accepted values, compared
+// case-insensitively; an empty field accepts every record.
+type ExportFilter struct {
+	RiskLevels   []string // RiskLevel, e.g. "High"
+	ScannerTypes []string // ScannerType, e.g. "shodan"
+	Countries    []string // CountryCode, e.g. "FR"
+}
+
+// ParseExportFilter builds an ExportFilter from comma-separated lists, as
+// given to the -risk, -scanner-type and -country flags.
+func ParseExportFilter(risks, scannerTypes, countries string) ExportFilter {
+	split := func(s string) []string {
+		var out []string
+		for _, v := range strings.Split(s, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				out = append(out, v)
+			}
+		}
+		return out
+	}
+	return ExportFilter{RiskLevels: split(risks), ScannerTypes: split(scannerTypes), Countries: split(countries)}
+}
+
+// Empty reports whether f accepts every record.
+func (f ExportFilter) Empty() bool {
+	return len(f.RiskLevels) == 0 && len(f.ScannerTypes) == 0 && len(f.Countries) == 0
+}
+
+// Match reports whether item meets every criterion of f.
+func (f ExportFilter) Match(item models.ScannerData) bool {
+	return matchesAny(f.RiskLevels, item.RiskLevel) &&
+		matchesAny(f.ScannerTypes, string(item.ScannerType)) &&
+		matchesAny(f.Countries, item.CountryCode)
+}
+
+// Apply returns the records of data that f accepts.
+func (f ExportFilter) Apply(data []models.ScannerData) []models.ScannerData {
+	if f.Empty() {
+		return data
+	}
+	var out []models.ScannerData
+	for _, item := range data {
+		if f.Match(item) {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// matchesAny reports whether value is one of values, or values is empty.
+func matchesAny(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
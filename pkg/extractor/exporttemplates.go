@@ -0,0 +1,202 @@
+package extractor
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// abuseIPDBPortScan is the AbuseIPDB category reported for scanner IPs.
+const abuseIPDBPortScan = "14"
+
+// abuseIPDBMaxComment is the longest comment AbuseIPDB accepts.
+const abuseIPDBMaxComment = 1024
+
+// ExportTemplate maps records to the column layout expected by a
+// third-party tool.
+type ExportTemplate struct {
+	Name        string
+	Description string
+	// Extension of the exported file, including the dot
+	Extension string
+	// Header is the first line, or nil when the format has none
+	Header []string
+	// Row returns the columns for one record, or nil to skip it
+	Row func(models.ScannerData) []string
+	// Write, when set, writes the whole export instead of Header and Row,
+	// for formats that are not tables (see firewallTemplates), and returns
+	// the number of entries written
+	Write func(w io.Writer, data []models.ScannerData) (int, error)
+	// Enforcement marks the templates that block the exported IPs; they
+	// only write the Enforceable records
+	Enforcement bool
+}
+
+// exportTemplates lists the templates in the order they are offered.
+var exportTemplates = append([]ExportTemplate{
+	abuseIPDBTemplate(nil),
+	{
+		Name:        "misp",
+		Description: "MISP freetext import (one indicator per line)",
+		Extension:   ".txt",
+		Write:       writeMISP,
+	},
+	{
+		Name:        "splunk",
+		Description: "Splunk lookup table CSV",
+		Extension:   ".csv",
+		Header:      []string{"ip", "scanner", "scanner_type", "country", "asn", "organization", "risk", "score", "state", "tags", "last_seen", "target_ports"},
+		Row: func(item models.ScannerData) []string {
+			return []string{
+				item.IPOrCIDR, item.ScannerName, string(item.ScannerType), item.CountryCode,
+				item.ASN, item.Organization, item.RiskLevel, fmt.Sprintf("%d", item.AbuseConfidenceScore),
+				string(item.State), strings.Join(item.Tags, ";"), formatTemplateTime(item.LastSeen),
+				strings.ReplaceAll(models.FormatPorts(item.TargetPorts), ", ", ";"),
+			}
+		},
+	},
+	geoJSONTemplate,
+	kmlTemplate,
+}, firewallTemplates...)
+
+// ExportTemplates returns the available export templates.
+func ExportTemplates() []ExportTemplate {
+	return append([]ExportTemplate(nil), exportTemplates...)
+}
+
+// ExportTemplateNames returns the template names, for flags and selectors.
+func ExportTemplateNames() []string {
+	names := make([]string, len(exportTemplates))
+	for i, t := range exportTemplates {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// LookupExportTemplate returns the template called name (case-insensitive).
+func LookupExportTemplate(name string) (ExportTemplate, bool) {
+	for _, t := range exportTemplates {
+		if strings.EqualFold(t.Name, name) {
+			return t, true
+		}
+	}
+	return ExportTemplate{}, false
+}
+
+// WriteTemplate writes data to w in the layout of tmpl and returns the
+// number of records written.
+func WriteTemplate(w io.Writer, data []models.ScannerData, tmpl ExportTemplate) (int, error) {
+	if tmpl.Write != nil {
+		return tmpl.Write(w, data)
+	}
+	writer := csv.NewWriter(w)
+	if tmpl.Header != nil {
+		if err := writer.Write(tmpl.Header); err != nil {
+			return 0, fmt.Errorf("writing %s header: %w", tmpl.Name, err)
+		}
+	}
+	n := 0
+	for _, item := range data {
+		row := tmpl.Row(item)
+		if row == nil {
+			continue
+		}
+		if err := writer.Write(row); err != nil {
+			return n, fmt.Errorf("writing %s row for %s: %w", tmpl.Name, item.IPOrCIDR, err)
+		}
+		n++
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return n, fmt.Errorf("writing %s export: %w", tmpl.Name, err)
+	}
+	return n, nil
+}
+
+// ExportWithTemplate writes data with the named template to filename in the
+// results directory.
+func (e *Extractor) ExportWithTemplate(data []models.ScannerData, filename, template string) error {
+	tmpl, ok := LookupExportTemplate(template)
+	if !ok {
+		return fmt.Errorf("unknown export template %q (available: %s)", template, strings.Join(ExportTemplateNames(), ", "))
+	}
+	cfg := e.settings()
+	if tmpl.Name == "abuseipdb" {
+		tmpl = abuseIPDBTemplate(cfg.AbuseIPDBCategories)
+	}
+	dir := cfg.ResultsDir
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating results directory: %w", err)
+	}
+	filePath := filepath.Join(dir, filename)
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("creating export file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	n, err := WriteTemplate(file, data, tmpl)
+	if err != nil {
+		return err
+	}
+	e.logger.Info("Extractor", fmt.Sprintf("Export %s: %d enregistrements dans %s", tmpl.Name, n, filePath))
+	return nil
+}
+
+// abuseIPDBTemplate is the AbuseIPDB bulk report layout with categories
+// taken from mapping (see AbuseIPDBCategories). Ranges other than /32 and
+// /128 are skipped, as AbuseIPDB rejects them.
+func abuseIPDBTemplate(mapping map[string]string) ExportTemplate {
+	return ExportTemplate{
+		Name:        "abuseipdb",
+		Description: "AbuseIPDB bulk report CSV",
+		Extension:   ".csv",
+		Header:      []string{"IP", "Categories", "ReportDate", "Comment"},
+		Row: func(item models.ScannerData) []string {
+			ip, ok := abuseIPDBAddress(item.IPOrCIDR)
+			if !ok {
+				return nil
+			}
+			return []string{ip, AbuseIPDBCategories(item.ScannerType, mapping), formatTemplateTime(item.LastSeen), abuseIPDBComment(item)}
+		},
+	}
+}
+
+// writeMISP writes one indicator per line for MISP's freetext import: the
+// IP or CIDR of each record, followed by an "ip:port" line per target port
+// of single addresses, which MISP imports as ip|port attributes.
+func writeMISP(w io.Writer, data []models.ScannerData) (int, error) {
+	n := 0
+	for _, item := range data {
+		if _, err := fmt.Fprintln(w, item.IPOrCIDR); err != nil {
+			return n, err
+		}
+		n++
+		ip := net.ParseIP(item.IPOrCIDR)
+		if ip == nil {
+			continue
+		}
+		for _, p := range item.TargetPorts {
+			if _, err := fmt.Fprintln(w, net.JoinHostPort(ip.String(), strconv.Itoa(p))); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// formatTemplateTime formats t as RFC 3339 in UTC, or "" when unset.
+func formatTemplateTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,521 @@
+package extractor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/events"
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// Logger is the logging contract the Extractor reports progress and errors to.
+// The application's *logger.Logger satisfies it; library users can plug in
+// their own implementation or pass nil to discard messages.
+type Logger interface {
+	Debug(component, message string, data ...map[string]interface{})
+	Info(component, message string, data ...map[string]interface{})
+	Warning(component, message string, data ...map[string]interface{})
+	Error(component, message string, data ...map[string]interface{})
+}
+
+// nopLogger discards every message.
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, string, ...map[string]interface{})   {}
+func (nopLogger) Info(string, string, ...map[string]interface{})    {}
+func (nopLogger) Warning(string, string, ...map[string]interface{}) {}
+func (nopLogger) Error(string, string, ...map[string]interface{})   {}
+
+// Extractor handles data extraction from scanner repositories and enrichment via RDAP and geolocation APIs.
+type Extractor struct {
+	logger      Logger
+	config      models.DatabaseConfig
+	apiClient   *http.Client
+	rateLimiter *RateLimiter
+	events      *events.Bus
+	// registrySlots caps concurrent RDAP requests per registry.
+	registrySlots *registrySemaphore
+	// cooldowns holds the RDAP registries resting after a 429.
+	cooldowns *registryCooldown
+	// configMu guards config, rateLimiter, registrySlots, geo, dns and tagRules,
+	// which ApplyConfig replaces while enrichment may be running.
+	configMu sync.RWMutex
+
+	// rdapEndpoints overrides the default RDAP registry URLs (for testing).
+	rdapEndpoints []string
+	// rdapBootstrapURL overrides the IANA RDAP bootstrap location (for testing).
+	rdapBootstrapURL string
+	// rdapBootstrapPath overrides the bootstrap table cache location (for testing).
+	rdapBootstrapPath string
+	// bootstrap is the IANA RDAP bootstrap table, nil until loaded, and
+	// bootstrapTried when its last download failed (see rdapBootstrapTable).
+	bootstrapMu    sync.Mutex
+	bootstrap      *rdapBootstrap
+	bootstrapTried time.Time
+	// geoBaseURL overrides the default ip-api.com base URL (for testing).
+	geoBaseURL string
+	// peeringDBURL overrides the PeeringDB network API URL (for testing).
+	peeringDBURL string
+	// internetDBURL overrides the Shodan InternetDB base URL (for testing).
+	internetDBURL string
+	// lifecyclePath overrides the greylisting store location (for testing).
+	lifecyclePath string
+	// approvalPath overrides the enforcement approval store location (for testing).
+	approvalPath string
+	// expiryNoticePath overrides the sent expiry notice record location (for testing).
+	expiryNoticePath string
+	// annotationPath overrides the annotation store location (for testing).
+	annotationPath string
+	// annotationMu serializes annotation store writes from concurrent API requests.
+	annotationMu sync.Mutex
+	// lockPath overrides the record lock store location (for testing).
+	lockPath string
+	// lockMu guards locks, the record lock store once read (nil before),
+	// and locksStamp, the modification time and size of the file read.
+	lockMu     sync.Mutex
+	locks      map[string]models.RecordLock
+	locksStamp fileStamp
+	// ripeStatURL overrides the RIPEstat announced-prefixes URL (for testing).
+	ripeStatURL string
+	// abuseIPDBURL overrides the AbuseIPDB report endpoint (for testing).
+	abuseIPDBURL string
+	// abuseReportPath overrides the AbuseIPDB report store location (for testing).
+	abuseReportPath string
+	// hitsPath overrides the honeypot hit store location (for testing).
+	hitsPath string
+	// hitsMu serializes hit store writes from concurrent API requests.
+	hitsMu sync.Mutex
+	// cacheMu serializes RDAP cache access from concurrent API requests and
+	// guards served, the cache they read once loaded (nil before), and
+	// servedStamp, the version of the file it was read from.
+	cacheMu     sync.Mutex
+	served      *rdapCache
+	servedStamp fileStamp
+	// remoteSyncPath overrides the remote API sync cursor location (for testing).
+	remoteSyncPath string
+	// budgetStopPath overrides the budget stop record location (for testing).
+	budgetStopPath string
+	// jobStatePath overrides the job state directory (for testing).
+	jobStatePath string
+	// archivePath overrides the downloaded archive directory (for testing).
+	archivePath string
+	// revision is the upstream state the last sync left the repository at.
+	revision atomic.Pointer[models.SourceRevision]
+	// budget is the budget of the enrichment run in progress, nil when none.
+	budget atomic.Pointer[runBudget]
+	// geo is the geolocation provider selected by config.GeoProvider.
+	geo GeoProvider
+	// dns is the resolver selected by config.DNSServers or config.DoHURL,
+	// nil for the system resolver.
+	dns *net.Resolver
+	// tagRules are the compiled config.TagRules.
+	tagRules []tagRule
+	// autoscale adapts the requests in flight to each provider to its error
+	// rate, nil when config.DisableAutoscale is set.
+	autoscale *autoscaler
+	// metrics records the requests sent to each provider (see RateMetrics).
+	metrics *requestMetrics
+	// plaintextGeoOnce limits the free-endpoint HTTP warning to one per Extractor.
+	plaintextGeoOnce sync.Once
+	// onPanic receives panics recovered in the enrichment workers.
+	onPanic PanicHandler
+	// noAutoSave stops ExtractData from saving its result (see SetAutoSave).
+	noAutoSave bool
+	// enrichments counts the enrichments running and lastEnrichment is when
+	// the last one ended, in Unix nanoseconds; the geo backfill waits for them.
+	enrichments    atomic.Int32
+	lastEnrichment atomic.Int64
+	// runs counts the extractions running (see RunActive).
+	runs atomic.Int32
+	// backfillTried holds the IPs BackfillGeo has looked up.
+	backfillMu    sync.Mutex
+	backfillTried map[string]bool
+	// feeds is the last parse of the feed files, reused to map its IPs to
+	// their scanners without parsing the files again (see scanFeeds).
+	feedsMu sync.Mutex
+	feeds   *parsedFeeds
+
+	// Pipeline stages; each defaults to the Extractor itself.
+	syncer   SourceSyncer
+	parser   Parser
+	enricher Enricher
+	store    Store
+	exporter Exporter
+}
+
+// NewExtractor creates a new Extractor with the given database configuration and logger.
+// A nil logger discards all log output.
+func NewExtractor(config models.DatabaseConfig, logger Logger) *Extractor {
+	if logger == nil {
+		logger = nopLogger{}
+	}
+	e := &Extractor{
+		logger:        logger,
+		config:        config,
+		rateLimiter:   throttleRateLimiter(config.APIThrottle),
+		registrySlots: newRegistrySemaphore(config.RDAPRegistryConcurrency),
+		cooldowns:     newRegistryCooldown(),
+		metrics:       newRequestMetrics(),
+		events:        events.NewBus(),
+	}
+	e.apiClient = &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &userAgentTransport{e: e, base: e.newTransport()},
+	}
+	e.syncer = e
+	e.parser = e
+	e.enricher = e
+	e.store = e
+	e.exporter = e
+	e.geo = e.newGeoProvider(config)
+	e.dns = newResolver(config)
+	e.tagRules = e.compileTagRules(config.TagRules)
+	e.autoscale = e.newAutoscalerFor(config)
+	return e
+}
+
+// throttleRateLimiter builds a rate limiter from APIThrottle, which is
+// expressed as seconds between requests (e.g. 1 means 1 req/s, 0.5 means
+// 2 req/s).
+func throttleRateLimiter(throttle float64) *RateLimiter {
+	var rps float64
+	if throttle > 0 {
+		rps = 1.0 / throttle
+	}
+	return NewRateLimiter(rps)
+}
+
+// ApplyConfig replaces the extractor's configuration and rebuilds what was
+// derived from it: the rate limiter, the per-registry request cap, the
+// per-provider autoscaler, the geolocation provider chain, and the RDAP
+// registry list and worker count read by the next lookups. Requests already
+// waiting on the old rate limiter finish at the old rate; the registry cap
+// and the autoscaler are only rebuilt when their bounds change. A
+// ConfigApplied event is published once the new settings are in place.
+func (e *Extractor) ApplyConfig(config models.DatabaseConfig) {
+	e.configMu.Lock()
+	e.config = config
+	e.rateLimiter = throttleRateLimiter(config.APIThrottle)
+	if slots := newRegistrySemaphore(config.RDAPRegistryConcurrency); slots.limit != e.registrySlots.limit {
+		e.registrySlots = slots
+	}
+	e.geo = e.newGeoProvider(config)
+	e.dns = newResolver(config)
+	e.tagRules = e.compileTagRules(config.TagRules)
+	if next := e.newAutoscalerFor(config); next == nil || e.autoscale == nil ||
+		next.min != e.autoscale.min || next.max != e.autoscale.max {
+		e.autoscale = next
+	}
+	e.configMu.Unlock()
+	// The archive sources may have changed
+	e.forgetFeeds()
+
+	e.logger.Info("Extractor", fmt.Sprintf("Configuration appliquee: %d workers, throttle %.3fs, registres %v",
+		config.Parallelism, config.APIThrottle, config.Registries))
+	e.publish(events.ConfigApplied, "configuration applied", 0, 0)
+}
+
+// settings returns a copy of the current configuration.
+func (e *Extractor) settings() models.DatabaseConfig {
+	e.configMu.RLock()
+	defer e.configMu.RUnlock()
+	return e.config
+}
+
+// limiter returns the current shared rate limiter.
+func (e *Extractor) limiter() *RateLimiter {
+	e.configMu.RLock()
+	defer e.configMu.RUnlock()
+	return e.rateLimiter
+}
+
+// scaler returns the current per-provider autoscaler, nil when disabled.
+func (e *Extractor) scaler() *autoscaler {
+	e.configMu.RLock()
+	defer e.configMu.RUnlock()
+	return e.autoscale
+}
+
+// registryLimits returns the current per-registry RDAP request cap.
+func (e *Extractor) registryLimits() *registrySemaphore {
+	e.configMu.RLock()
+	defer e.configMu.RUnlock()
+	return e.registrySlots
+}
+
+// Events returns the bus on which the extractor publishes run progress.
+func (e *Extractor) Events() *events.Bus {
+	return e.events
+}
+
+// SetEventBus makes the extractor publish on bus, so several components can
+// share one bus. Passing nil installs a fresh private bus.
+func (e *Extractor) SetEventBus(bus *events.Bus) {
+	if bus == nil {
+		bus = events.NewBus()
+	}
+	e.events = bus
+}
+
+// PanicHandler receives a panic recovered at one of the extractor's goroutine
+// boundaries, with the stack of the panicking goroutine.
+type PanicHandler func(component string, value interface{}, stack []byte)
+
+// SetPanicHandler installs h to be told about panics recovered while
+// enriching records. The record that panicked is reported as failed and the
+// batch continues. Passing nil only logs the panic.
+func (e *Extractor) SetPanicHandler(h PanicHandler) {
+	e.onPanic = h
+}
+
+// enrichSafely runs enrich on data, turning a panic into an error for that
+// record.
+func (e *Extractor) enrichSafely(enrich func(*models.ScannerData) error, data *models.ScannerData) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			if e.onPanic != nil {
+				e.onPanic("Extractor", v, debug.Stack())
+			}
+			err = fmt.Errorf("panic: %v", v)
+		}
+	}()
+	return enrich(data)
+}
+
+// publish sends an event originating from the extractor.
+func (e *Extractor) publish(t events.Type, message string, count, total int) {
+	e.events.Publish(events.Event{Type: t, Source: "Extractor", Message: message, Count: count, Total: total})
+}
+
+// localPath returns the configured local repository path or its default.
+func (e *Extractor) localPath() string {
+	if p := e.settings().LocalPath; p != "" {
+		return p
+	}
+	return "./data/internet-scanners"
+}
+
+// ExtractData runs the whole pipeline: it clones or updates the configured
+// repository, parses .nft files for IPs, enriches them (EnrichIPs), records
+// the run in the greylisting lifecycle and, unless SetAutoSave(false) was
+// called, saves the result to a timestamped CSV (SaveRun). With
+// SkipEnrichment set the enrichment step is replaced by BuildBaseRecords,
+// which writes a minimal dataset in seconds. Cancelling ctx stops the run
+// where it is, aborting the requests in flight, and returns the context's
+// error; nothing is saved.
+func (e *Extractor) ExtractData(ctx context.Context) ([]models.ScannerData, error) {
+	return e.extract(ctx, !e.settings().SkipEnrichment)
+}
+
+// ExtractBaseRecords runs ExtractData without the enrichment step: the base
+// records are mapped to their scanners, recorded in the lifecycle and saved
+// right away, whatever SkipEnrichment says. Callers show them at once and
+// enrich them in the background, record by record.
+func (e *Extractor) ExtractBaseRecords(ctx context.Context) ([]models.ScannerData, error) {
+	return e.extract(ctx, false)
+}
+
+// extract runs the pipeline behind ExtractData and ExtractBaseRecords.
+func (e *Extractor) extract(ctx context.Context, enrich bool) ([]models.ScannerData, error) {
+	defer e.beginRun()()
+	e.logger.Info("Extractor", "Debut de l'extraction des donnees")
+	e.publish(events.RunStarted, "Extraction des donnees...", 0, 0)
+
+	if err := e.syncer.Sync(ctx); err != nil {
+		e.publish(events.RunFailed, err.Error(), 0, 0)
+		return nil, err
+	}
+	e.publish(events.SourceSynced, e.localPath(), 0, 0)
+
+	scanners, err := e.parser.ParseIPs(e.localPath())
+	if ctx.Err() != nil {
+		e.publish(events.RunFailed, ctx.Err().Error(), 0, 0)
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		e.logger.Error("Extractor", "Erreur lors du parsing: "+err.Error())
+		e.publish(events.RunFailed, err.Error(), 0, 0)
+		return nil, fmt.Errorf("parse failed: %w", err)
+	}
+
+	if len(scanners) == 0 {
+		e.logger.Error("Extractor", "Aucune IP trouvee")
+		e.publish(events.RunFailed, "no IPs found in repository", 0, 0)
+		return nil, fmt.Errorf("no IPs found in repository")
+	}
+
+	e.logger.Info("Extractor", fmt.Sprintf("%d IPs uniques extraites au total", len(scanners)))
+	e.publish(events.RecordsParsed, "", len(scanners), len(scanners))
+
+	var enrichedData []models.ScannerData
+	if !enrich {
+		e.logger.Info("Extractor", "Enrichissement desactive: extraction seule")
+		enrichedData = e.BuildBaseRecords(scanners)
+	} else {
+		enrichedData, err = e.EnrichIPs(ctx, scanners)
+		if err != nil {
+			e.logger.Error("Extractor", "Erreur lors de l'enrichissement: "+err.Error())
+			e.publish(events.RunFailed, err.Error(), 0, 0)
+			return nil, fmt.Errorf("enrichment failed: %w", err)
+		}
+		e.logger.Info("Extractor", fmt.Sprintf("%d enregistrements enrichis", len(enrichedData)))
+	}
+
+	if err := e.UpdateLifecycle(enrichedData); err != nil {
+		e.logger.Warning("Extractor", "Erreur lors de la mise a jour du greylisting: "+err.Error())
+		e.publish(events.Warning, "lifecycle update failed: "+err.Error(), 0, 0)
+	}
+	e.ApplyPrefixPolicy(enrichedData)
+	e.ApplyEnforcementPolicy(enrichedData)
+	e.AttributeScanners(enrichedData)
+	e.ApplyTagRules(enrichedData)
+
+	if !e.noAutoSave {
+		if _, err := e.SaveRun(enrichedData); err != nil {
+			e.logger.Warning("Extractor", "Erreur lors de la sauvegarde CSV: "+err.Error())
+			e.publish(events.Warning, "CSV export failed: "+err.Error(), 0, 0)
+		}
+	}
+
+	// Base records of a run enriched later are pushed by the caller
+	if enrich || e.settings().SkipEnrichment {
+		if err := e.PushMetrics(ctx, enrichedData); err != nil {
+			e.logger.Warning("Extractor", "Erreur lors de l'envoi des metriques: "+err.Error())
+			e.publish(events.Warning, "metrics push failed: "+err.Error(), 0, 0)
+		}
+	}
+
+	e.logger.Info("Extractor", fmt.Sprintf("Extraction terminee: %d enregistrements", len(enrichedData)))
+	e.publish(events.RunCompleted, "Extraction terminee", len(enrichedData), len(enrichedData))
+	return enrichedData, nil
+}
+
+// EnrichIPs builds a record for each IP, mapped to the scanner that lists it,
+// and enriches the records with the configured Enricher on Parallelism
+// workers. Apart from the RDAP cache nothing is written; pass the result to
+// SaveRun, Export or SaveToJSON to keep it. Cancelling ctx stops the run
+// and returns the context's error (see JobState to resume it).
+func (e *Extractor) EnrichIPs(ctx context.Context, ips []string) ([]models.ScannerData, error) {
+	return e.enrichData(ctx, ips)
+}
+
+// SaveRun exports data with the configured Exporter under a timestamped
+// name, "2006-01-02_15-04-05_liacheckscanner.csv", and returns that name.
+// The run metadata (see RunMetadata) is saved next to it.
+func (e *Extractor) SaveRun(data []models.ScannerData) (string, error) {
+	name := fmt.Sprintf("%s_liacheckscanner.csv", time.Now().Format("2006-01-02_15-04-05"))
+	if err := e.exporter.Export(data, name); err != nil {
+		return "", err
+	}
+	if err := e.SaveRunMetadata(name, data); err != nil {
+		e.logger.Warning("Extractor", err.Error())
+	}
+	e.logger.Info("Extractor", "Sauvegarde en CSV: "+name)
+	return name, nil
+}
+
+// ExtractIPsOnly clones or updates the repository and parses .nft files,
+// returning only the unique IP list without performing any enrichment.
+func (e *Extractor) ExtractIPsOnly(ctx context.Context) ([]string, error) {
+	if err := e.syncer.Sync(ctx); err != nil {
+		return nil, err
+	}
+
+	return e.parser.ParseIPs(e.localPath())
+}
+
+// BuildBaseRecords creates ScannerData records from the given IP list,
+// mapping each IP to its scanner source but without RDAP enrichment.
+func (e *Extractor) BuildBaseRecords(ips []string) []models.ScannerData {
+	ipToScanner := e.mapIPsToScanners(ips)
+	now := time.Now()
+	var records []models.ScannerData
+	for i, ip := range ips {
+		records = append(records, e.buildRecord(i, ip, ipToScanner[ip], now))
+	}
+	return records
+}
+
+// EnrichRecordWithDelay enriches a single scanner record, applying the specified delay in milliseconds.
+// Cancelling ctx aborts the lookups in flight. A locked record is left as it
+// is and ErrRecordLocked returned.
+func (e *Extractor) EnrichRecordWithDelay(ctx context.Context, data *models.ScannerData, delayMs int) error {
+	if e.IsLocked(data.IPOrCIDR) {
+		return fmt.Errorf("%s: %w", data.IPOrCIDR, ErrRecordLocked)
+	}
+	defer e.beginEnrichment()()
+	if delayMs >= 0 {
+		e.configMu.Lock()
+		prev := e.config.APIThrottle
+		override := float64(delayMs) / 1000.0
+		e.config.APIThrottle = override
+		e.configMu.Unlock()
+		defer func() {
+			e.configMu.Lock()
+			// Keep a value installed by ApplyConfig in the meantime.
+			if e.config.APIThrottle == override {
+				e.config.APIThrottle = prev
+			}
+			e.configMu.Unlock()
+		}()
+	}
+	return e.enricher.Enrich(ctx, data)
+}
+
+// EnrichRecords enriches records in turn with the configured throttle,
+// calling progress after each, and returns the errors of the records that
+// failed by index. The built-in enricher loads the RDAP cache once and saves
+// it once for the whole batch. Cancelling ctx stops before the next record;
+// the number of records processed is returned.
+func (e *Extractor) EnrichRecords(ctx context.Context, records []models.ScannerData, progress func(done int)) (int, map[int]error) {
+	defer e.beginEnrichment()()
+	enrich := e.enricher.Enrich
+	if e.enricher == Enricher(e) {
+		ips := make([]string, len(records))
+		for i := range records {
+			ips[i] = records[i].IPOrCIDR
+		}
+		cache := e.loadRDAPCache()
+		e.pullSharedCache(ctx, cache, ips)
+		defer func() {
+			if err := cache.save(); err != nil {
+				e.logger.Warning("Extractor", fmt.Sprintf("Sauvegarde du cache RDAP: %v", err))
+			}
+			e.pushSharedCache(ctx, cache)
+		}()
+		enrich = func(ctx context.Context, data *models.ScannerData) error {
+			return e.enrichUsingCache(ctx, data, cache)
+		}
+	}
+
+	var errs map[int]error
+	done := 0
+	for i := range records {
+		if ctx.Err() != nil {
+			break
+		}
+		var err error
+		if e.IsLocked(records[i].IPOrCIDR) {
+			err = fmt.Errorf("%s: %w", records[i].IPOrCIDR, ErrRecordLocked)
+		} else {
+			err = enrich(ctx, &records[i])
+		}
+		if err != nil {
+			if errs == nil {
+				errs = map[int]error{}
+			}
+			errs[i] = err
+		}
+		done++
+		if progress != nil {
+			progress(done)
+		}
+	}
+	return done, errs
+}
@@ -0,0 +1,84 @@
+package extractor
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// FeedTrustLevel returns the trust level of the feed source (a SourceFile):
+// that of the first feed_trust rule matching it, else
+// models.DefaultFeedTrust. A feed inside an archive, "archive:path", also
+// matches the rules given for the archive or for the file name.
+func (e *Extractor) FeedTrustLevel(source string) int {
+	return feedTrustLevel(e.settings().FeedTrust, source)
+}
+
+// feedTrustLevel is FeedTrustLevel under rules.
+func feedTrustLevel(rules []models.FeedTrust, source string) int {
+	candidates := []string{source}
+	if archive, entry, ok := strings.Cut(source, ":"); ok {
+		candidates = append(candidates, archive, path.Base(entry))
+	}
+	for _, r := range rules {
+		for _, c := range candidates {
+			if ok, _ := path.Match(r.Source, c); ok {
+				return r.Level
+			}
+		}
+	}
+	return models.DefaultFeedTrust
+}
+
+// resolveAttribution picks, under policy, the feed an IP listed by all of
+// claims is attributed to. When the feeds name different scanners, the
+// Reason of the result says which feeds lost and why.
+func resolveAttribution(claims []ScannerInfo, policy string) ScannerInfo {
+	sort.SliceStable(claims, func(i, j int) bool {
+		if claims[i].Trust != claims[j].Trust {
+			return claims[i].Trust > claims[j].Trust
+		}
+		return claims[i].SourceFile < claims[j].SourceFile
+	})
+	votes := map[string]int{}
+	for _, c := range claims {
+		votes[c.Name]++
+	}
+	best := claims[0]
+	if len(votes) < 2 {
+		return best
+	}
+	if strings.ToLower(policy) == models.ConflictPolicyMajority {
+		// claims are sorted by trust, so the most trusted feed wins a tie
+		for _, c := range claims {
+			if votes[c.Name] > votes[best.Name] {
+				best = c
+			}
+		}
+	}
+
+	var losers []string
+	topVotes, topTrust := 0, -1
+	for _, c := range claims {
+		if c.Name == best.Name {
+			continue
+		}
+		losers = append(losers, fmt.Sprintf("%s by %s (trust %d)", c.Name, c.SourceFile, c.Trust))
+		topVotes = max(topVotes, votes[c.Name])
+		topTrust = max(topTrust, c.Trust)
+	}
+	var why string
+	switch {
+	case votes[best.Name] > topVotes && strings.ToLower(policy) == models.ConflictPolicyMajority:
+		why = fmt.Sprintf("named by %d of %d feeds", votes[best.Name], len(claims))
+	case best.Trust > topTrust:
+		why = "most trusted feed"
+	default:
+		why = "equal trust, first feed by name"
+	}
+	best.Reason = fmt.Sprintf("%s by %s (trust %d) over %s: %s", best.Name, best.SourceFile, best.Trust, strings.Join(losers, ", "), why)
+	return best
+}
@@ -0,0 +1,84 @@
+package extractor
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrFileLocked is returned when a data file stays locked by another
+// instance of the application longer than fileLockTimeout.
+var ErrFileLocked = errors.New("data file locked by another instance")
+
+// fileLockTimeout is how long a write waits for another instance to release
+// a data file. A var so tests can shorten it.
+var fileLockTimeout = 10 * time.Second
+
+const (
+	// fileLockStale is the age past which a lock file is taken to be left
+	// by a crashed instance and removed: writes hold it for milliseconds.
+	fileLockStale = time.Minute
+	// fileLockRetry is the pause between two attempts to take a lock.
+	fileLockRetry = 50 * time.Millisecond
+)
+
+// lockDataFile takes the lock shared by all the instances of the
+// application on the data file at path, "<path>.lock" created exclusively,
+// and returns the function releasing it. The lock file names its holder,
+// for the error returned when it is not released in time, with a nonce
+// telling this lock from the next ones taken on the same file.
+func lockDataFile(path string) (unlock func(), err error) {
+	lockPath := path + ".lock"
+	deadline := time.Now().Add(fileLockTimeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			host, _ := os.Hostname()
+			id := fmt.Sprintf("pid %d on %s since %s (%s)\n", os.Getpid(), host, time.Now().UTC().Format(time.RFC3339), newLockNonce())
+			f.WriteString(id)
+			f.Close()
+			return func() { removeLockIf(lockPath, []byte(id)) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("locking %s: %w", path, err)
+		}
+		holder, rerr := os.ReadFile(lockPath)
+		if info, serr := os.Stat(lockPath); rerr == nil && serr == nil && time.Since(info.ModTime()) > fileLockStale {
+			removeLockIf(lockPath, holder)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s: %w (%s)", path, ErrFileLocked, strings.TrimSpace(string(holder)))
+		}
+		time.Sleep(fileLockRetry)
+	}
+}
+
+// removeLockIf removes the lock file at lockPath if it still holds holder.
+// The file is first moved aside, which is atomic, and checked there, so a
+// lock another instance took in the meantime is put back rather than
+// deleted.
+func removeLockIf(lockPath string, holder []byte) {
+	aside := lockPath + "." + newLockNonce()
+	if err := os.Rename(lockPath, aside); err != nil {
+		return
+	}
+	if got, err := os.ReadFile(aside); err == nil && !bytes.Equal(got, holder) {
+		// Fails only if yet another instance took the lock since
+		_ = os.Link(aside, lockPath)
+	}
+	os.Remove(aside)
+}
+
+// newLockNonce returns a random hexadecimal string.
+func newLockNonce() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
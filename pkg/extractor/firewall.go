@@ -0,0 +1,223 @@
+package extractor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// Names of the table, sets and chains the firewall exports create. Loading
+// an export again replaces its previous content.
+const (
+	firewallTable = "liacheckscanner"
+	firewallSetV4 = "liacheckscanner_v4"
+	firewallSetV6 = "liacheckscanner_v6"
+	firewallChain = "LIACHECKSCANNER"
+)
+
+// firewallTemplates are the export templates writing ready-to-load
+// firewall rules that drop the traffic of the exported IPs. Only the
+// Enforceable records are written, whatever the caller selected.
+var firewallTemplates = []ExportTemplate{
+	{
+		Name:        "nftables",
+		Description: "nftables script (nft -f) with IPv4 and IPv6 sets",
+		Extension:   ".nft",
+		Write:       writeNftables,
+		Enforcement: true,
+	},
+	{
+		Name:        "ipset",
+		Description: "ipset restore file (ipset restore <file)",
+		Extension:   ".ipset",
+		Write:       writeIPSet,
+		Enforcement: true,
+	},
+	{
+		Name:        "iptables",
+		Description: "iptables/ip6tables shell script",
+		Extension:   ".sh",
+		Write:       writeIPTables,
+		Enforcement: true,
+	},
+}
+
+// firewallPrefixes returns the canonical prefixes of the Enforceable
+// records of data, IPv4 and IPv6 apart, sorted and without duplicates:
+// addresses become /32 or /128, IPv4-mapped addresses IPv4 and host bits
+// are cleared. The records left out are counted in held, entries that are
+// neither an address nor a prefix in skipped.
+func firewallPrefixes(data []models.ScannerData) (v4, v6 []netip.Prefix, skipped, held int) {
+	enforceable := Enforceable(data)
+	held = len(data) - len(enforceable)
+	seen := map[netip.Prefix]bool{}
+	for _, item := range enforceable {
+		s := strings.TrimSpace(item.IPOrCIDR)
+		var p netip.Prefix
+		if pfx, err := netip.ParsePrefix(s); err == nil {
+			p = pfx.Masked()
+			if p.Addr().Is4In6() && p.Bits() >= 96 {
+				p = netip.PrefixFrom(p.Addr().Unmap(), p.Bits()-96)
+			}
+		} else if addr, err := netip.ParseAddr(s); err == nil {
+			addr = addr.Unmap().WithZone("")
+			p = netip.PrefixFrom(addr, addr.BitLen())
+		} else {
+			skipped++
+			continue
+		}
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		if p.Addr().Is4() {
+			v4 = append(v4, p)
+		} else {
+			v6 = append(v6, p)
+		}
+	}
+	less := func(ps []netip.Prefix) func(i, j int) bool {
+		return func(i, j int) bool {
+			if c := ps[i].Addr().Compare(ps[j].Addr()); c != 0 {
+				return c < 0
+			}
+			return ps[i].Bits() < ps[j].Bits()
+		}
+	}
+	sort.Slice(v4, less(v4))
+	sort.Slice(v6, less(v6))
+	return v4, v6, skipped, held
+}
+
+// firewallEntry formats p as firewall tools expect: a bare address for a
+// single host, CIDR notation otherwise.
+func firewallEntry(p netip.Prefix) string {
+	if p.IsSingleIP() {
+		return p.Addr().String()
+	}
+	return p.String()
+}
+
+// firewallHeader writes the comment opening every firewall export.
+func firewallHeader(w io.Writer, v4, v6 []netip.Prefix, skipped, held int) {
+	fmt.Fprintf(w, "# LiaCheckScanner blocklist generated %s: %d IPv4 and %d IPv6 entries\n",
+		time.Now().UTC().Format(time.RFC3339), len(v4), len(v6))
+	if held > 0 {
+		fmt.Fprintf(w, "# %d records left out: not blocked, or held back from enforcement\n", held)
+	}
+	if skipped > 0 {
+		fmt.Fprintf(w, "# %d entries skipped: neither an IP address nor a CIDR\n", skipped)
+	}
+}
+
+// writeNftables writes an nft script defining an inet table with one set
+// per address family and an input chain dropping their traffic. The table
+// is deleted first, so loading the script again replaces it.
+func writeNftables(w io.Writer, data []models.ScannerData) (int, error) {
+	v4, v6, skipped, held := firewallPrefixes(data)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "#!/usr/sbin/nft -f")
+	firewallHeader(bw, v4, v6, skipped, held)
+	fmt.Fprintf(bw, "table inet %s\ndelete table inet %s\n\n", firewallTable, firewallTable)
+	fmt.Fprintf(bw, "table inet %s {\n", firewallTable)
+	for _, set := range []struct {
+		name, typ string
+		prefixes  []netip.Prefix
+	}{
+		{firewallSetV4, "ipv4_addr", v4},
+		{firewallSetV6, "ipv6_addr", v6},
+	} {
+		fmt.Fprintf(bw, "\tset %s {\n\t\ttype %s\n\t\tflags interval\n\t\tauto-merge\n", set.name, set.typ)
+		if len(set.prefixes) > 0 {
+			fmt.Fprintln(bw, "\t\telements = {")
+			for i, p := range set.prefixes {
+				sep := ","
+				if i == len(set.prefixes)-1 {
+					sep = ""
+				}
+				fmt.Fprintf(bw, "\t\t\t%s%s\n", firewallEntry(p), sep)
+			}
+			fmt.Fprintln(bw, "\t\t}")
+		}
+		fmt.Fprintln(bw, "\t}")
+	}
+	fmt.Fprintln(bw, "\tchain input {")
+	fmt.Fprintln(bw, "\t\ttype filter hook input priority filter - 10; policy accept;")
+	fmt.Fprintf(bw, "\t\tip saddr @%s drop\n", firewallSetV4)
+	fmt.Fprintf(bw, "\t\tip6 saddr @%s drop\n", firewallSetV6)
+	fmt.Fprintln(bw, "\t}")
+	fmt.Fprintln(bw, "}")
+	if err := bw.Flush(); err != nil {
+		return 0, fmt.Errorf("writing nftables export: %w", err)
+	}
+	return len(v4) + len(v6), nil
+}
+
+// writeIPSet writes an ipset restore file creating, or emptying, one
+// hash:net set per address family and adding the entries. Matching traffic
+// is dropped by a rule such as
+// "iptables -I INPUT -m set --match-set liacheckscanner_v4 src -j DROP".
+func writeIPSet(w io.Writer, data []models.ScannerData) (int, error) {
+	v4, v6, skipped, held := firewallPrefixes(data)
+	bw := bufio.NewWriter(w)
+	firewallHeader(bw, v4, v6, skipped, held)
+	for _, set := range []struct {
+		name, family string
+		prefixes     []netip.Prefix
+	}{
+		{firewallSetV4, "inet", v4},
+		{firewallSetV6, "inet6", v6},
+	} {
+		maxelem := 65536
+		if len(set.prefixes) > maxelem {
+			maxelem = len(set.prefixes)
+		}
+		fmt.Fprintf(bw, "create %s hash:net family %s maxelem %d -exist\n", set.name, set.family, maxelem)
+		fmt.Fprintf(bw, "flush %s\n", set.name)
+		for _, p := range set.prefixes {
+			fmt.Fprintf(bw, "add %s %s -exist\n", set.name, firewallEntry(p))
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return 0, fmt.Errorf("writing ipset export: %w", err)
+	}
+	return len(v4) + len(v6), nil
+}
+
+// writeIPTables writes a shell script filling a LIACHECKSCANNER chain with
+// one DROP rule per entry, with iptables for IPv4 and ip6tables for IPv6,
+// and jumping to it from INPUT. Running it again replaces the rules.
+func writeIPTables(w io.Writer, data []models.ScannerData) (int, error) {
+	v4, v6, skipped, held := firewallPrefixes(data)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "#!/bin/sh")
+	firewallHeader(bw, v4, v6, skipped, held)
+	fmt.Fprintln(bw, "set -e")
+	for _, fam := range []struct {
+		cmd      string
+		prefixes []netip.Prefix
+	}{
+		{"iptables", v4},
+		{"ip6tables", v6},
+	} {
+		if len(fam.prefixes) == 0 {
+			continue
+		}
+		fmt.Fprintln(bw)
+		fmt.Fprintf(bw, "%[1]s -N %[2]s 2>/dev/null || %[1]s -F %[2]s\n", fam.cmd, firewallChain)
+		fmt.Fprintf(bw, "%[1]s -C INPUT -j %[2]s 2>/dev/null || %[1]s -I INPUT -j %[2]s\n", fam.cmd, firewallChain)
+		for _, p := range fam.prefixes {
+			fmt.Fprintf(bw, "%s -A %s -s %s -j DROP\n", fam.cmd, firewallChain, firewallEntry(p))
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return 0, fmt.Errorf("writing iptables export: %w", err)
+	}
+	return len(v4) + len(v6), nil
+}
@@ -0,0 +1,334 @@
+package extractor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// GeoResult holds the provider-neutral geolocation data for one IP.
+type GeoResult struct {
+	CountryCode   string
+	Country       string
+	Continent     string
+	ContinentCode string
+	Region        string
+	City          string
+	ISP           string
+	ASN           string // "AS<number> <name>", whatever the provider's format
+	ReverseDNS    string
+	Timezone      string
+	Latitude      float64
+	Longitude     float64
+
+	// Sources maps each filled field (see geoFieldNames) to the provider that
+	// supplied it. It is set by the provider chain.
+	Sources map[string]string
+}
+
+// GeoProvider looks up geolocation data for a single IP address. Lookup
+// gives up when ctx is done.
+type GeoProvider interface {
+	Name() string
+	Lookup(ctx context.Context, ip string) (GeoResult, error)
+}
+
+// Geolocation provider names accepted in DatabaseConfig.GeoProvider and
+// DatabaseConfig.GeoProviders.
+const (
+	GeoProviderIPAPI   = "ip-api"
+	GeoProviderIPInfo  = "ipinfo"
+	GeoProviderIPData  = "ipdata"
+	GeoProviderMaxMind = "maxmind"
+)
+
+// geoStringFields lists the string fields of g by their Sources key.
+func geoStringFields(g *GeoResult) []struct {
+	name  string
+	value *string
+} {
+	return []struct {
+		name  string
+		value *string
+	}{
+		{"country_code", &g.CountryCode},
+		{"country", &g.Country},
+		{"continent", &g.Continent},
+		{"continent_code", &g.ContinentCode},
+		{"region", &g.Region},
+		{"city", &g.City},
+		{"isp", &g.ISP},
+		{"asn", &g.ASN},
+		{"reverse_dns", &g.ReverseDNS},
+		{"timezone", &g.Timezone},
+	}
+}
+
+// merge copies every field of other that is still empty in g and records
+// source as its origin.
+func (g *GeoResult) merge(other GeoResult, source string) {
+	if g.Sources == nil {
+		g.Sources = map[string]string{}
+	}
+	dst := geoStringFields(g)
+	src := geoStringFields(&other)
+	for i := range dst {
+		if *dst[i].value == "" && *src[i].value != "" {
+			*dst[i].value = *src[i].value
+			g.Sources[dst[i].name] = source
+		}
+	}
+	if g.Latitude == 0 && g.Longitude == 0 && (other.Latitude != 0 || other.Longitude != 0) {
+		g.Latitude, g.Longitude = other.Latitude, other.Longitude
+		g.Sources["location"] = source
+	}
+}
+
+// geoSourceLabel names the providers that filled g, as "ip-api" or
+// "ip-api+maxmind" for a chain; fallback names a result without Sources.
+func geoSourceLabel(g GeoResult, fallback string) string {
+	seen := map[string]bool{}
+	var names []string
+	for _, source := range g.Sources {
+		if !seen[source] {
+			seen[source] = true
+			names = append(names, source)
+		}
+	}
+	if len(names) == 0 {
+		return fallback
+	}
+	sort.Strings(names)
+	return strings.Join(names, "+")
+}
+
+// complete reports whether the fields used for enrichment are all filled, so
+// the chain can stop querying further providers.
+func (g *GeoResult) complete() bool {
+	return g.CountryCode != "" && g.Country != "" && g.ContinentCode != "" && g.ISP != "" && g.ASN != ""
+}
+
+// geoChain queries providers in order. A provider is consulted when the
+// previous ones failed or left one of the enrichment fields empty; each
+// field keeps the value of the first provider that supplied it.
+type geoChain struct {
+	providers []GeoProvider
+}
+
+func (c geoChain) Name() string {
+	names := make([]string, len(c.providers))
+	for i, p := range c.providers {
+		names[i] = p.Name()
+	}
+	return strings.Join(names, ">")
+}
+
+func (c geoChain) Lookup(ctx context.Context, ip string) (GeoResult, error) {
+	var res GeoResult
+	var errs []string
+	for _, p := range c.providers {
+		g, err := p.Lookup(ctx, ip)
+		if ctx.Err() != nil {
+			return GeoResult{}, ctx.Err()
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", p.Name(), err))
+			continue
+		}
+		g.normalize()
+		res.merge(g, p.Name())
+		if res.complete() {
+			break
+		}
+	}
+	if len(res.Sources) == 0 {
+		if len(errs) > 0 {
+			return GeoResult{}, fmt.Errorf("no geolocation for %s (%s)", ip, strings.Join(errs, "; "))
+		}
+		return GeoResult{}, fmt.Errorf("no geolocation for %s", ip)
+	}
+	return res, nil
+}
+
+const (
+	// ipAPIFreeBaseURL is the free ip-api.com endpoint (HTTP only, 45 req/min).
+	ipAPIFreeBaseURL = "http://ip-api.com/json/"
+	// ipAPIProBaseURL is the paid ip-api.com endpoint, served over HTTPS.
+	ipAPIProBaseURL = "https://pro.ip-api.com/json/"
+)
+
+// ipAPIURL builds the ip-api.com request URL for ip and the given fields.
+// When an IPAPIKey is configured the pro HTTPS endpoint is used and the key is
+// appended; otherwise the free plaintext endpoint is used.
+func (e *Extractor) ipAPIURL(ip, fields string) string {
+	base := e.geoBaseURL
+	key := e.settings().IPAPIKey
+	if base == "" {
+		if key != "" {
+			base = ipAPIProBaseURL
+		} else {
+			base = ipAPIFreeBaseURL
+		}
+	}
+	u := base + url.PathEscape(ip) + "?fields=" + fields
+	if key != "" {
+		u += "&key=" + url.QueryEscape(key)
+	}
+	return u
+}
+
+// warnPlaintextGeo logs, once per Extractor, that geolocation requests go
+// over unencrypted HTTP because no ip-api pro key is configured.
+func (e *Extractor) warnPlaintextGeo() {
+	if e.settings().IPAPIKey != "" || e.geoBaseURL != "" {
+		return
+	}
+	e.plaintextGeoOnce.Do(func() {
+		e.logger.Warning("Extractor", "Geolocalisation via l'endpoint gratuit ip-api.com en HTTP non chiffre; configurez ipapi_key pour utiliser HTTPS")
+	})
+}
+
+// GeoLookupRaw queries ip-api.com for ip and returns the decoded response.
+// fields is the comma-separated ip-api field list; "status" is always requested.
+// The pro HTTPS endpoint is used when an IPAPIKey is configured.
+func (e *Extractor) GeoLookupRaw(ctx context.Context, ip string, fields ...string) (map[string]interface{}, error) {
+	e.warnPlaintextGeo()
+	fieldList := strings.Join(append([]string{"status", "message"}, fields...), ",")
+	resp, err := e.httpGetWithRetry(ctx, e.ipAPIURL(ip, fieldList))
+	if err != nil {
+		return nil, fmt.Errorf("geo lookup request for %s: %w", ip, redactError(err))
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("geo http %d", resp.StatusCode)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, fmt.Errorf("unmarshaling geo response for %s: %w", ip, err)
+	}
+	if st, _ := m["status"].(string); st != "success" {
+		if msg, _ := m["message"].(string); msg != "" {
+			return nil, fmt.Errorf("geo status %s: %s", st, msg)
+		}
+		return nil, fmt.Errorf("geo status %s", st)
+	}
+	return m, nil
+}
+
+// newGeoProvider builds the provider chain from config.GeoProviders, or from
+// the single config.GeoProvider when no chain is configured. Unknown names are
+// skipped; an empty chain falls back to ip-api.com.
+func (e *Extractor) newGeoProvider(cfg models.DatabaseConfig) GeoProvider {
+	names := cfg.GeoProviders
+	if len(names) == 0 {
+		names = []string{cfg.GeoProvider}
+	}
+	var chain geoChain
+	for _, name := range names {
+		if p := e.geoProviderByName(cfg, name); p != nil {
+			chain.providers = append(chain.providers, p)
+		}
+	}
+	if len(chain.providers) == 0 {
+		chain.providers = []GeoProvider{ipAPIProvider{e: e}}
+	}
+	return chain
+}
+
+// geoProviderByName returns the built-in provider called name, or nil.
+func (e *Extractor) geoProviderByName(cfg models.DatabaseConfig, name string) GeoProvider {
+	switch name {
+	case "", GeoProviderIPAPI:
+		return ipAPIProvider{e: e}
+	case GeoProviderIPInfo:
+		return newIPInfoProvider(e, cfg.IPInfoToken, cfg.IPInfoThrottle)
+	case GeoProviderIPData:
+		return newIPDataProvider(e, cfg.IPDataKey, cfg.IPDataThrottle)
+	case GeoProviderMaxMind:
+		return newMaxMindProvider(cfg.MaxMindDB, cfg.MaxMindASNDB)
+	}
+	e.logger.Warning("Extractor", fmt.Sprintf("Fournisseur de geolocalisation inconnu %q ignore", name))
+	return nil
+}
+
+// SetGeoProvider replaces the geolocation provider. Passing nil restores the
+// provider selected in the configuration.
+func (e *Extractor) SetGeoProvider(p GeoProvider) {
+	e.configMu.Lock()
+	defer e.configMu.Unlock()
+	if p == nil {
+		p = e.newGeoProvider(e.config)
+	}
+	e.geo = p
+}
+
+// geoProvider returns the current geolocation provider.
+func (e *Extractor) geoProvider() GeoProvider {
+	e.configMu.RLock()
+	defer e.configMu.RUnlock()
+	return e.geo
+}
+
+// LookupGeo geolocates ip with the configured provider.
+func (e *Extractor) LookupGeo(ctx context.Context, ip string) (GeoResult, error) {
+	return e.geoProvider().Lookup(ctx, ip)
+}
+
+// ipAPIProvider is the ip-api.com GeoProvider (free or pro endpoint).
+type ipAPIProvider struct {
+	e *Extractor
+}
+
+func (p ipAPIProvider) Name() string { return GeoProviderIPAPI }
+
+func (p ipAPIProvider) Lookup(ctx context.Context, ip string) (GeoResult, error) {
+	m, err := p.e.GeoLookupRaw(ctx, ip, "continent", "continentCode", "country", "countryCode", "regionName", "city", "isp", "as", "reverse", "timezone", "lat", "lon")
+	if err != nil {
+		return GeoResult{}, err
+	}
+	str := func(key string) string {
+		v, _ := m[key].(string)
+		return v
+	}
+	lat, _ := m["lat"].(float64)
+	lon, _ := m["lon"].(float64)
+	return GeoResult{
+		CountryCode:   str("countryCode"),
+		Country:       str("country"),
+		Continent:     str("continent"),
+		ContinentCode: str("continentCode"),
+		Region:        str("regionName"),
+		City:          str("city"),
+		ISP:           str("isp"),
+		ASN:           str("as"),
+		ReverseDNS:    str("reverse"),
+		Timezone:      str("timezone"),
+		Latitude:      lat,
+		Longitude:     lon,
+	}, nil
+}
+
+// performGeoLookupExtended queries the geolocation provider for country/ISP/AS/reverse info.
+func (e *Extractor) performGeoLookupExtended(ctx context.Context, ip string) (string, string, string, string, string) {
+	g, err := e.geoProvider().Lookup(ctx, ip)
+	if err != nil {
+		return "", "", "", "", ""
+	}
+	return g.CountryCode, g.Country, g.ISP, g.ASN, g.ReverseDNS
+}
+
+// GeoLookupContinent returns the continent, continent code, country, and country code for the given IP.
+func (e *Extractor) GeoLookupContinent(ctx context.Context, ip string) (string, string, string, string, error) {
+	g, err := e.geoProvider().Lookup(ctx, ip)
+	if err != nil {
+		return "", "", "", "", err
+	}
+	return g.Continent, g.ContinentCode, g.Country, g.CountryCode, nil
+}
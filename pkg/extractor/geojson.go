@@ -0,0 +1,84 @@
+package extractor
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// geoJSONTemplate maps the located records to a GeoJSON FeatureCollection
+// (RFC 7946), for kepler.gl, QGIS and other mapping tools.
+var geoJSONTemplate = ExportTemplate{
+	Name:        "geojson",
+	Description: "GeoJSON FeatureCollection of located records (kepler.gl, QGIS)",
+	Extension:   ".geojson",
+	Write:       WriteGeoJSON,
+}
+
+type geoJSONCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string            `json:"type"`
+	Geometry   geoJSONPoint      `json:"geometry"`
+	Properties geoJSONProperties `json:"properties"`
+}
+
+// geoJSONPoint holds its coordinates as [longitude, latitude], the GeoJSON
+// order.
+type geoJSONPoint struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// geoJSONProperties are the record fields mapping tools color and filter
+// the points by.
+type geoJSONProperties struct {
+	IP          string `json:"ip"`
+	Scanner     string `json:"scanner"`
+	ScannerType string `json:"scanner_type"`
+	Risk        string `json:"risk"`
+	Score       int    `json:"score"`
+	ASN         string `json:"asn"`
+	ASName      string `json:"as_name"`
+	Country     string `json:"country"`
+	City        string `json:"city"`
+	State       string `json:"state"`
+	LastSeen    string `json:"last_seen"`
+}
+
+// WriteGeoJSON writes the records of data that geolocation located as a
+// GeoJSON FeatureCollection of points, and returns the number written. The
+// others are skipped.
+func WriteGeoJSON(w io.Writer, data []models.ScannerData) (int, error) {
+	fc := geoJSONCollection{Type: "FeatureCollection", Features: []geoJSONFeature{}}
+	for _, item := range data {
+		if !item.HasLocation() {
+			continue
+		}
+		fc.Features = append(fc.Features, geoJSONFeature{
+			Type:     "Feature",
+			Geometry: geoJSONPoint{Type: "Point", Coordinates: [2]float64{item.Longitude, item.Latitude}},
+			Properties: geoJSONProperties{
+				IP:          item.IPOrCIDR,
+				Scanner:     item.ScannerName,
+				ScannerType: string(item.ScannerType),
+				Risk:        item.RiskLevel,
+				Score:       item.AbuseConfidenceScore,
+				ASN:         item.ASN,
+				ASName:      item.ASName,
+				Country:     item.CountryCode,
+				City:        item.City,
+				State:       string(item.State),
+				LastSeen:    formatTemplateTime(item.LastSeen),
+			},
+		})
+	}
+	if err := json.NewEncoder(w).Encode(fc); err != nil {
+		return 0, err
+	}
+	return len(fc.Features), nil
+}
@@ -0,0 +1,281 @@
+package extractor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+const (
+	ipInfoBaseURL = "https://ipinfo.io/"
+	ipDataBaseURL = "https://api.ipdata.co/"
+)
+
+// throttleLimiter converts a throttle in seconds between requests into a RateLimiter.
+func throttleLimiter(throttle float64) *RateLimiter {
+	var rps float64
+	if throttle > 0 {
+		rps = 1.0 / throttle
+	}
+	return NewRateLimiter(rps)
+}
+
+// getGeoJSON performs a rate-limited GET and decodes the JSON body into v.
+// Non-2xx responses are turned into errors that include errMessage(body).
+func (e *Extractor) getGeoJSON(ctx context.Context, limiter *RateLimiter, provider, reqURL string, v interface{}, errMessage func([]byte) string) error {
+	if err := limiter.WaitContext(ctx); err != nil {
+		return err
+	}
+	resp, err := e.httpGetWithRetry(ctx, reqURL)
+	if err != nil {
+		return fmt.Errorf("%s request: %w", provider, err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("reading %s response: %w", provider, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if msg := errMessage(body); msg != "" {
+			return fmt.Errorf("%s http %d: %s", provider, resp.StatusCode, msg)
+		}
+		return fmt.Errorf("%s http %d", provider, resp.StatusCode)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("unmarshaling %s response: %w", provider, err)
+	}
+	return nil
+}
+
+// ipInfoProvider is the ipinfo.io GeoProvider.
+type ipInfoProvider struct {
+	e       *Extractor
+	token   string
+	baseURL string
+	limiter *RateLimiter
+}
+
+func newIPInfoProvider(e *Extractor, token string, throttle float64) *ipInfoProvider {
+	return &ipInfoProvider{e: e, token: token, baseURL: ipInfoBaseURL, limiter: throttleLimiter(throttle)}
+}
+
+func (p *ipInfoProvider) Name() string { return GeoProviderIPInfo }
+
+func (p *ipInfoProvider) Lookup(ctx context.Context, ip string) (GeoResult, error) {
+	reqURL := p.baseURL + url.PathEscape(ip) + "/json"
+	if p.token != "" {
+		reqURL += "?token=" + url.QueryEscape(p.token)
+	}
+	var r struct {
+		Hostname string `json:"hostname"`
+		City     string `json:"city"`
+		Region   string `json:"region"`
+		Country  string `json:"country"`
+		Loc      string `json:"loc"`
+		Org      string `json:"org"`
+		Timezone string `json:"timezone"`
+		Bogon    bool   `json:"bogon"`
+	}
+	errMessage := func(body []byte) string {
+		var e struct {
+			Error struct {
+				Title   string `json:"title"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(body, &e) == nil {
+			return strings.TrimSpace(e.Error.Title + " " + e.Error.Message)
+		}
+		return ""
+	}
+	if err := p.e.getGeoJSON(ctx, p.limiter, GeoProviderIPInfo, reqURL, &r, errMessage); err != nil {
+		return GeoResult{}, err
+	}
+	if r.Bogon {
+		return GeoResult{}, fmt.Errorf("ipinfo: %s is a bogon address", ip)
+	}
+
+	g := GeoResult{
+		CountryCode: r.Country,
+		Country:     r.Country,
+		Region:      r.Region,
+		City:        r.City,
+		ASN:         r.Org,
+		ReverseDNS:  r.Hostname,
+		Timezone:    r.Timezone,
+	}
+	if name := p.e.getCountryName(r.Country); name != "Unknown" {
+		g.Country = name
+	}
+	// org is "AS<number> <name>"; the name part is the closest thing to an ISP.
+	if parts := strings.SplitN(r.Org, " ", 2); len(parts) == 2 {
+		g.ISP = parts[1]
+	}
+	if lat, lon, ok := strings.Cut(r.Loc, ","); ok {
+		g.Latitude, _ = strconv.ParseFloat(lat, 64)
+		g.Longitude, _ = strconv.ParseFloat(lon, 64)
+	}
+	return g, nil
+}
+
+// ipDataProvider is the ipdata.co GeoProvider.
+type ipDataProvider struct {
+	e       *Extractor
+	key     string
+	baseURL string
+	limiter *RateLimiter
+}
+
+func newIPDataProvider(e *Extractor, key string, throttle float64) *ipDataProvider {
+	return &ipDataProvider{e: e, key: key, baseURL: ipDataBaseURL, limiter: throttleLimiter(throttle)}
+}
+
+func (p *ipDataProvider) Name() string { return GeoProviderIPData }
+
+func (p *ipDataProvider) Lookup(ctx context.Context, ip string) (GeoResult, error) {
+	if p.key == "" {
+		return GeoResult{}, fmt.Errorf("ipdata: no API key configured")
+	}
+	reqURL := p.baseURL + url.PathEscape(ip) + "?api-key=" + url.QueryEscape(p.key)
+	var r struct {
+		City          string  `json:"city"`
+		Region        string  `json:"region"`
+		CountryName   string  `json:"country_name"`
+		CountryCode   string  `json:"country_code"`
+		ContinentName string  `json:"continent_name"`
+		ContinentCode string  `json:"continent_code"`
+		Latitude      float64 `json:"latitude"`
+		Longitude     float64 `json:"longitude"`
+		ASN           struct {
+			ASN  string `json:"asn"`
+			Name string `json:"name"`
+		} `json:"asn"`
+		TimeZone struct {
+			Name string `json:"name"`
+		} `json:"time_zone"`
+	}
+	errMessage := func(body []byte) string {
+		var e struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &e) == nil {
+			return e.Message
+		}
+		return ""
+	}
+	if err := p.e.getGeoJSON(ctx, p.limiter, GeoProviderIPData, reqURL, &r, errMessage); err != nil {
+		return GeoResult{}, err
+	}
+
+	g := GeoResult{
+		CountryCode:   r.CountryCode,
+		Country:       r.CountryName,
+		Continent:     r.ContinentName,
+		ContinentCode: r.ContinentCode,
+		Region:        r.Region,
+		City:          r.City,
+		ISP:           r.ASN.Name,
+		Timezone:      r.TimeZone.Name,
+		Latitude:      r.Latitude,
+		Longitude:     r.Longitude,
+	}
+	if r.ASN.ASN != "" {
+		g.ASN = strings.TrimSpace(r.ASN.ASN + " " + r.ASN.Name)
+	}
+	return g, nil
+}
+
+// maxMindProvider reads GeoLite2/GeoIP2 City and (optionally) ASN databases
+// from disk, so lookups need no network access.
+type maxMindProvider struct {
+	city    *maxminddb.Reader
+	asn     *maxminddb.Reader
+	openErr error
+}
+
+func newMaxMindProvider(cityPath, asnPath string) *maxMindProvider {
+	p := &maxMindProvider{}
+	if cityPath == "" {
+		p.openErr = fmt.Errorf("maxmind: no city database configured")
+		return p
+	}
+	if p.city, p.openErr = maxminddb.Open(cityPath); p.openErr != nil {
+		p.openErr = fmt.Errorf("opening maxmind database %s: %w", cityPath, p.openErr)
+		return p
+	}
+	if asnPath != "" {
+		if p.asn, p.openErr = maxminddb.Open(asnPath); p.openErr != nil {
+			p.openErr = fmt.Errorf("opening maxmind ASN database %s: %w", asnPath, p.openErr)
+		}
+	}
+	return p
+}
+
+func (p *maxMindProvider) Name() string { return GeoProviderMaxMind }
+
+func (p *maxMindProvider) Lookup(_ context.Context, ip string) (GeoResult, error) {
+	if p.openErr != nil {
+		return GeoResult{}, p.openErr
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return GeoResult{}, fmt.Errorf("maxmind: invalid IP %q", ip)
+	}
+
+	var city struct {
+		City struct {
+			Names map[string]string `maxminddb:"names"`
+		} `maxminddb:"city"`
+		Continent struct {
+			Code  string            `maxminddb:"code"`
+			Names map[string]string `maxminddb:"names"`
+		} `maxminddb:"continent"`
+		Country struct {
+			ISOCode string            `maxminddb:"iso_code"`
+			Names   map[string]string `maxminddb:"names"`
+		} `maxminddb:"country"`
+		Location struct {
+			Latitude  float64 `maxminddb:"latitude"`
+			Longitude float64 `maxminddb:"longitude"`
+			TimeZone  string  `maxminddb:"time_zone"`
+		} `maxminddb:"location"`
+		Subdivisions []struct {
+			Names map[string]string `maxminddb:"names"`
+		} `maxminddb:"subdivisions"`
+	}
+	if err := p.city.Lookup(addr, &city); err != nil {
+		return GeoResult{}, fmt.Errorf("maxmind lookup for %s: %w", ip, err)
+	}
+	g := GeoResult{
+		CountryCode:   city.Country.ISOCode,
+		Country:       city.Country.Names["en"],
+		Continent:     city.Continent.Names["en"],
+		ContinentCode: city.Continent.Code,
+		City:          city.City.Names["en"],
+		Timezone:      city.Location.TimeZone,
+		Latitude:      city.Location.Latitude,
+		Longitude:     city.Location.Longitude,
+	}
+	if len(city.Subdivisions) > 0 {
+		g.Region = city.Subdivisions[0].Names["en"]
+	}
+
+	if p.asn != nil {
+		var as struct {
+			Number       uint   `maxminddb:"autonomous_system_number"`
+			Organization string `maxminddb:"autonomous_system_organization"`
+		}
+		if err := p.asn.Lookup(addr, &as); err == nil && as.Number != 0 {
+			g.ASN = fmt.Sprintf("AS%d %s", as.Number, as.Organization)
+			g.ISP = as.Organization
+		}
+	}
+	return g, nil
+}
@@ -0,0 +1,327 @@
+package extractor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/server"
+
+	"github.com/lia/liacheckscanner_go/pkg/events"
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// defaultRepoURL is the repository synced when none is configured.
+const defaultRepoURL = "https://github.com/MDMCK10/internet-scanners"
+
+// Errors a sync wraps when the repository cannot be cloned or updated, so
+// callers can tell a configuration problem from a transient one.
+var (
+	// ErrRepoAuth means the server asked for credentials or refused them.
+	ErrRepoAuth = errors.New("repository authentication failed")
+	// ErrRepoNotFound means the server has no repository at the URL.
+	ErrRepoNotFound = errors.New("repository not found")
+	// ErrRepoNetwork means the server could not be reached.
+	ErrRepoNetwork = errors.New("repository unreachable")
+)
+
+// gitClientsOnce installs the go-git clients once per process.
+var gitClientsOnce sync.Once
+
+// gitTransportKey is the context key of the http.RoundTripper a sync sends
+// its requests through.
+type gitTransportKey struct{}
+
+// installGitClients installs the go-git clients for HTTP(S), which send
+// each request through the RoundTripper of its context, and serves local
+// repositories in process, so no git binary is needed. go-git only selects
+// its clients process-wide, so the transport of each sync is passed in the
+// context of its clone or fetch rather than by swapping clients, and
+// concurrent syncs of different Extractors keep their own.
+func installGitClients() {
+	gitClientsOnce.Do(func() {
+		c := githttp.NewClient(&http.Client{Transport: contextTransport{}})
+		client.InstallProtocol("http", c)
+		client.InstallProtocol("https", c)
+		client.InstallProtocol("file", server.NewClient(localRepoLoader{}))
+	})
+}
+
+// withGitTransport returns ctx carrying the Extractor's transport, so the
+// HTTP(S) requests of a clone or fetch resolve the repository host with
+// the configured resolver and follow the proxy settings.
+func (e *Extractor) withGitTransport(ctx context.Context) context.Context {
+	installGitClients()
+	return context.WithValue(ctx, gitTransportKey{}, e.newTransport())
+}
+
+// contextTransport sends a request through the RoundTripper of its
+// context, or http.DefaultTransport when it has none.
+type contextTransport struct{}
+
+func (contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if rt, ok := req.Context().Value(gitTransportKey{}).(http.RoundTripper); ok {
+		return rt.RoundTrip(req)
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// isLocalRepo reports whether repoURL is a path or file:// URL, served by
+// localRepoLoader.
+func isLocalRepo(repoURL string) bool {
+	ep, err := transport.NewEndpoint(repoURL)
+	return err == nil && ep.Protocol == "file"
+}
+
+// localRepoLoader opens a local repository for the in-process server, bare
+// or with a worktree.
+type localRepoLoader struct{}
+
+// Load returns the storage of the repository at the endpoint's path or in
+// its .git directory.
+func (localRepoLoader) Load(ep *transport.Endpoint) (storer.Storer, error) {
+	for _, p := range []string{ep.Path, filepath.Join(ep.Path, git.GitDirName)} {
+		at := *ep
+		at.Path = p
+		if s, err := server.DefaultLoader.Load(&at); err == nil {
+			return s, nil
+		}
+	}
+	return nil, transport.ErrRepositoryNotFound
+}
+
+// cloneOrUpdateRepo clones or updates the configured repository. With
+// RepoRef set, the checkout is detached at that commit, tag or branch;
+// otherwise the local branch is moved to the tip of its remote branch,
+// discarding local changes. ShallowClone fetches the last commit only,
+// unless a ref is pinned or the repository is local. The commit reached is kept for the run metadata
+// (see LastSourceRevision). Cancelling ctx aborts the transfer.
+func (e *Extractor) cloneOrUpdateRepo(ctx context.Context) error {
+	cfg := e.settings()
+	repoURL := cfg.RepoURL
+	if repoURL == "" {
+		repoURL = defaultRepoURL
+	}
+	ref := strings.TrimSpace(cfg.RepoRef)
+	localPath := e.localPath()
+
+	e.logger.Info("Extractor", "Clonage/mise a jour du repository...")
+	e.logger.Info("Extractor", "Repository: "+repoDisplayURL(repoURL))
+	e.logger.Info("Extractor", "Local Path: "+localPath)
+
+	depth := 0
+	if cfg.ShallowClone {
+		switch {
+		case ref != "":
+			e.logger.Warning("Extractor", "Clone superficiel ignore: le repository est epingle sur "+ref)
+		case isLocalRepo(repoURL):
+			// The in-process server has no shallow support, and a local copy costs nothing
+		default:
+			depth = 1
+		}
+	}
+	tags := git.TagFollowing
+	if ref != "" {
+		tags = git.AllTags
+	}
+	progress := &gitProgress{publish: e.publish}
+
+	ctx = e.withGitTransport(ctx)
+	repo, err := git.PlainOpen(localPath)
+	switch {
+	case errors.Is(err, git.ErrRepositoryNotExists):
+		_, statErr := os.Stat(localPath)
+		created := os.IsNotExist(statErr)
+		if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+			return fmt.Errorf("cloneOrUpdateRepo: creating parent directory: %w", err)
+		}
+		e.logger.Info("Extractor", "Clonage du repository depuis "+repoDisplayURL(repoURL))
+		repo, err = git.PlainCloneContext(ctx, localPath, false, &git.CloneOptions{URL: repoURL, Depth: depth, Tags: tags, Progress: progress})
+		if err != nil {
+			// A failed clone leaves a partial repository the next sync would open
+			if created {
+				os.RemoveAll(localPath)
+			}
+			if ctx.Err() != nil {
+				e.logger.Warning("Extractor", "Clonage annule")
+				return ctx.Err()
+			}
+			e.logger.Error("Extractor", "Erreur lors du clonage: "+err.Error())
+			return gitError("clone", err)
+		}
+	case err != nil:
+		return fmt.Errorf("opening repository %s: %w", localPath, err)
+	default:
+		e.logger.Info("Extractor", "Repository local trouve, mise a jour...")
+		err := repo.FetchContext(ctx, &git.FetchOptions{RemoteName: git.DefaultRemoteName, Depth: depth, Tags: tags, Force: true, Progress: progress})
+		if ctx.Err() != nil {
+			e.logger.Warning("Extractor", "Mise a jour annulee")
+			return ctx.Err()
+		}
+		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			e.logger.Error("Extractor", "Erreur lors de la mise a jour: "+err.Error())
+			return gitError("fetch", err)
+		}
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("opening worktree: %w", err)
+	}
+	if ref != "" {
+		hash, err := resolveGitRef(repo, ref)
+		if err != nil {
+			e.logger.Error("Extractor", "Reference introuvable: "+ref)
+			return err
+		}
+		e.logger.Info("Extractor", "Repository epingle sur "+ref)
+		if err := wt.Checkout(&git.CheckoutOptions{Hash: hash, Force: true}); err != nil {
+			return fmt.Errorf("git checkout %s failed: %w", ref, err)
+		}
+	} else {
+		branch, err := checkoutBranch(ctx, repo)
+		if err != nil {
+			return err
+		}
+		tracking, err := repo.Reference(plumbing.NewRemoteReferenceName(git.DefaultRemoteName, branch.Short()), true)
+		if err != nil {
+			return fmt.Errorf("resolving origin/%s: %w", branch.Short(), err)
+		}
+		// Moves the branch like a fast-forward pull would, even past a force-push upstream
+		if err := repo.Storer.SetReference(plumbing.NewHashReference(branch, tracking.Hash())); err != nil {
+			return fmt.Errorf("updating %s: %w", branch.Short(), err)
+		}
+		if err := wt.Checkout(&git.CheckoutOptions{Branch: branch, Force: true}); err != nil {
+			return fmt.Errorf("git checkout %s failed: %w", branch.Short(), err)
+		}
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("reading HEAD: %w", err)
+	}
+	commit := head.Hash().String()
+	e.revision.Store(&models.SourceRevision{RepoURL: repoDisplayURL(repoURL), Ref: ref, Commit: commit, SyncedAt: time.Now().UTC()})
+	e.logger.Info("Extractor", "Repository synchronise au commit "+commit)
+	return nil
+}
+
+// repoDisplayURL hides the password of a repository URL that carries
+// credentials, for logs and run metadata.
+func repoDisplayURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	return u.Redacted()
+}
+
+// checkoutBranch returns the branch an unpinned sync follows: the current
+// one, or the remote's default branch when an earlier pin left HEAD
+// detached.
+func checkoutBranch(ctx context.Context, repo *git.Repository) (plumbing.ReferenceName, error) {
+	head, err := repo.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return "", fmt.Errorf("reading HEAD: %w", err)
+	}
+	if head.Type() == plumbing.SymbolicReference {
+		return head.Target(), nil
+	}
+	remote, err := repo.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return "", fmt.Errorf("reading remote: %w", err)
+	}
+	refs, err := remote.ListContext(ctx, &git.ListOptions{})
+	if err != nil {
+		return "", gitError("ls-remote", err)
+	}
+	for _, r := range refs {
+		if r.Name() == plumbing.HEAD && r.Type() == plumbing.SymbolicReference {
+			return r.Target(), nil
+		}
+	}
+	return "", errors.New("the remote does not advertise a default branch; set repo_ref")
+}
+
+// resolveGitRef returns the commit ref names: a tag, a branch of the remote
+// or a commit hash, in that order.
+func resolveGitRef(repo *git.Repository, ref string) (plumbing.Hash, error) {
+	for _, rev := range []string{
+		string(plumbing.NewTagReferenceName(ref)),
+		string(plumbing.NewRemoteReferenceName(git.DefaultRemoteName, ref)),
+		ref,
+	} {
+		if h, err := repo.ResolveRevision(plumbing.Revision(rev)); err == nil {
+			return *h, nil
+		}
+	}
+	return plumbing.ZeroHash, fmt.Errorf("repo_ref %q is not a tag, branch or commit of the repository", ref)
+}
+
+// gitError wraps err from a git operation with ErrRepoAuth, ErrRepoNotFound
+// or ErrRepoNetwork when it is one of those.
+func gitError(op string, err error) error {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, transport.ErrAuthenticationRequired), errors.Is(err, transport.ErrAuthorizationFailed):
+		return fmt.Errorf("git %s: %w (check the credentials in repo_url): %w", op, ErrRepoAuth, err)
+	case errors.Is(err, transport.ErrRepositoryNotFound):
+		return fmt.Errorf("git %s: %w (check repo_url)", op, ErrRepoNotFound)
+	case errors.As(err, &netErr):
+		return fmt.Errorf("git %s: %w: %w", op, ErrRepoNetwork, err)
+	}
+	return fmt.Errorf("git %s failed: %w", op, err)
+}
+
+// gitProgressCount matches the "(done/total)" part of a git progress line.
+var gitProgressCount = regexp.MustCompile(`\((\d+)/(\d+)\)`)
+
+// gitProgress publishes the progress lines a git server sends as
+// SourceProgress events, with the objects done and their total when the
+// line gives them.
+type gitProgress struct {
+	publish func(t events.Type, message string, count, total int)
+	buf     []byte
+	last    string
+}
+
+// Write splits p into lines, which git ends with \r while a count runs.
+func (p *gitProgress) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexAny(p.buf, "\r\n")
+		if i < 0 {
+			break
+		}
+		line := strings.TrimSpace(string(p.buf[:i]))
+		p.buf = p.buf[i+1:]
+		if line == "" || line == p.last {
+			continue
+		}
+		p.last = line
+		var count, total int
+		if m := gitProgressCount.FindStringSubmatch(line); m != nil {
+			count, _ = strconv.Atoi(m[1])
+			total, _ = strconv.Atoi(m[2])
+		}
+		p.publish(events.SourceProgress, line, count, total)
+	}
+	return len(b), nil
+}
@@ -1,12 +1,9 @@
 package extractor
 
 import (
-	"os"
-	"path/filepath"
-	"regexp"
 	"strings"
 
-	"github.com/lia/liacheckscanner_go/internal/models"
+	"github.com/lia/liacheckscanner_go/pkg/models"
 )
 
 // ScannerInfo holds the name, type, and source file of a scanner associated with an IP.
@@ -14,49 +11,42 @@ type ScannerInfo struct {
 	Name       string
 	Type       models.ScannerType
 	SourceFile string
+	Trust      int    // trust level of the feed (see FeedTrustLevel)
+	Reason     string // why this feed won over feeds naming other scanners
+	Ports      []int  // target ports the feed lists for the IP
 }
 
-// mapIPsToScanners maps IPs to their scanner information based on .nft files.
+// mapIPsToScanners maps IPs to their scanner information using the configured parser.
 func (e *Extractor) mapIPsToScanners(ips []string) map[string]ScannerInfo {
-	ipToScanner := make(map[string]ScannerInfo)
-
-	// Precompile regexes once before walking the filesystem.
-	ipv4Regex := regexp.MustCompile(`\b(?:[0-9]{1,3}\.){3}[0-9]{1,3}(?:/\d{1,2})?\b`)
-	ipv6Regex := regexp.MustCompile(`(?:[a-fA-F0-9]{0,4}:){2,7}[a-fA-F0-9]{0,4}(?:/\d{1,3})?`)
-
-	err := filepath.Walk(e.config.LocalPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if info.IsDir() || !strings.HasSuffix(strings.ToLower(path), ".nft") {
-			return nil
-		}
-
-		fileName := filepath.Base(path)
-		scannerName := strings.TrimSuffix(fileName, ".nft")
-		scannerType := e.getScannerType(scannerName)
-
-		fileIPs, err := e.extractIPsFromNFTFile(path, ipv4Regex, ipv6Regex)
-		if err != nil {
-			return nil
-		}
-
-		for _, ip := range fileIPs {
-			ipToScanner[ip] = ScannerInfo{
-				Name:       scannerName,
-				Type:       scannerType,
-				SourceFile: fileName,
-			}
-		}
-
-		return nil
-	})
+	return e.parser.MapScanners(e.localPath(), ips)
+}
 
+// mapIPsToScannersIn maps IPs to their scanner information based on .nft
+// files under root, inside the archives under root and inside the archive
+// sources. The files parsed by the last ParseIPs of root are not read again.
+func (e *Extractor) mapIPsToScannersIn(root string, ips []string) map[string]ScannerInfo {
+	feeds, err := e.parsedFeedsFor(root)
 	if err != nil {
 		e.logger.Warning("Extractor", "Erreur lors du mapping des scanners: "+err.Error())
+		return map[string]ScannerInfo{}
 	}
 
+	// An IP listed by several feeds goes to the one the conflict policy picks
+	cfg := e.settings()
+	ipToScanner := make(map[string]ScannerInfo, len(feeds.claims))
+	for ip, claims := range feeds.claims {
+		cs := make([]ScannerInfo, len(claims))
+		var ports []int
+		for i, info := range claims {
+			info.Trust = feedTrustLevel(cfg.FeedTrust, info.SourceFile)
+			cs[i] = info
+			ports = models.MergePorts(ports, info.Ports)
+		}
+		// The ports listed by every feed are kept, whichever feed wins
+		best := resolveAttribution(cs, cfg.ConflictPolicy)
+		best.Ports = ports
+		ipToScanner[ip] = best
+	}
 	return ipToScanner
 }
 
@@ -80,20 +70,7 @@ func (e *Extractor) getScannerType(scannerName string) models.ScannerType {
 
 // getCountryName returns the country name from a country code.
 func (e *Extractor) getCountryName(code string) string {
-	countries := map[string]string{
-		"FR": "France",
-		"US": "United States",
-		"DE": "Germany",
-		"GB": "United Kingdom",
-		"CA": "Canada",
-		"AU": "Australia",
-		"JP": "Japan",
-		"BR": "Brazil",
-		"IN": "India",
-		"RU": "Russia",
-	}
-
-	if name, exists := countries[code]; exists {
+	if _, name, ok := NormalizeCountry(code); ok {
 		return name
 	}
 	return "Unknown"
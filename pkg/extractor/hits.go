@@ -0,0 +1,323 @@
+package extractor
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// hitsFile returns the path of the honeypot hit store.
+func (e *Extractor) hitsFile() string {
+	if e.hitsPath != "" {
+		return e.hitsPath
+	}
+	return filepath.Join("build", "data", "hits.json")
+}
+
+func (e *Extractor) loadHits() ([]models.Hit, error) {
+	b, err := os.ReadFile(e.hitsFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading hit store: %w", err)
+	}
+	var hits []models.Hit
+	if err := json.Unmarshal(b, &hits); err != nil {
+		return nil, fmt.Errorf("decoding hit store: %w", err)
+	}
+	return hits, nil
+}
+
+func (e *Extractor) saveHits(hits []models.Hit) error {
+	path := e.hitsFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating hit directory: %w", err)
+	}
+	b, err := json.MarshalIndent(hits, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding hit store: %w", err)
+	}
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		return fmt.Errorf("writing hit store: %w", err)
+	}
+	return nil
+}
+
+// ParseHits reads a honeypot hits feed. Three layouts are accepted: a JSON
+// array or JSON lines of {"ip", "timestamp", "port"} objects, or CSV rows of
+// ip,timestamp[,port] with an optional header. Timestamps are RFC 3339,
+// "2006-01-02 15:04:05" (UTC) or Unix seconds.
+func ParseHits(r io.Reader) ([]models.Hit, error) {
+	br := bufio.NewReader(r)
+	first, err := firstNonSpace(br)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading hits: %w", err)
+	}
+	switch first {
+	case '[':
+		var raw []rawHit
+		if err := json.NewDecoder(br).Decode(&raw); err != nil {
+			return nil, fmt.Errorf("decoding hits JSON: %w", err)
+		}
+		return convertRawHits(raw)
+	case '{':
+		var raw []rawHit
+		dec := json.NewDecoder(br)
+		for {
+			var h rawHit
+			if err := dec.Decode(&h); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("decoding hits JSON line %d: %w", len(raw)+1, err)
+			}
+			raw = append(raw, h)
+		}
+		return convertRawHits(raw)
+	default:
+		return parseHitsCSV(br)
+	}
+}
+
+// rawHit is a hit as found in a JSON feed, before its timestamp is parsed.
+type rawHit struct {
+	IP        string          `json:"ip"`
+	Timestamp json.RawMessage `json:"timestamp"`
+	Port      int             `json:"port"`
+}
+
+func convertRawHits(raw []rawHit) ([]models.Hit, error) {
+	hits := make([]models.Hit, 0, len(raw))
+	for i, r := range raw {
+		ts := strings.Trim(string(r.Timestamp), `"`)
+		h, err := newHit(r.IP, ts, r.Port)
+		if err != nil {
+			return nil, fmt.Errorf("hit %d: %w", i+1, err)
+		}
+		hits = append(hits, h)
+	}
+	return hits, nil
+}
+
+func parseHitsCSV(r io.Reader) ([]models.Hit, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	var hits []models.Hit
+	for line := 1; ; line++ {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading hits CSV: %w", err)
+		}
+		if len(rec) == 0 || (len(rec) == 1 && strings.TrimSpace(rec[0]) == "") {
+			continue
+		}
+		// A header row names the columns instead of holding an address
+		if line == 1 && net.ParseIP(strings.TrimSpace(rec[0])) == nil {
+			continue
+		}
+		if len(rec) < 2 {
+			return nil, fmt.Errorf("hits CSV line %d: expected ip,timestamp[,port]", line)
+		}
+		port := 0
+		if len(rec) > 2 && strings.TrimSpace(rec[2]) != "" {
+			if port, err = strconv.Atoi(strings.TrimSpace(rec[2])); err != nil {
+				return nil, fmt.Errorf("hits CSV line %d: invalid port %q", line, rec[2])
+			}
+		}
+		h, err := newHit(rec[0], rec[1], port)
+		if err != nil {
+			return nil, fmt.Errorf("hits CSV line %d: %w", line, err)
+		}
+		hits = append(hits, h)
+	}
+	return hits, nil
+}
+
+// newHit validates and normalizes one hit.
+func newHit(ip, timestamp string, port int) (models.Hit, error) {
+	addr := net.ParseIP(strings.TrimSpace(ip))
+	if addr == nil {
+		return models.Hit{}, fmt.Errorf("invalid IP %q", ip)
+	}
+	ts, err := parseHitTime(strings.TrimSpace(timestamp))
+	if err != nil {
+		return models.Hit{}, err
+	}
+	if port < 0 || port > 65535 {
+		return models.Hit{}, fmt.Errorf("invalid port %d", port)
+	}
+	return models.Hit{IP: addr.String(), Timestamp: ts, Port: port}, nil
+}
+
+func parseHitTime(s string) (time.Time, error) {
+	if t, err := models.ParseTimestamp(s); err == nil && !t.IsZero() {
+		return t, nil
+	}
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(secs, 0).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+}
+
+// firstNonSpace returns the first byte of br that is neither whitespace nor
+// part of a UTF-8 byte order mark, without consuming it.
+func firstNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n', 0xEF, 0xBB, 0xBF:
+			continue
+		}
+		return b, br.UnreadByte()
+	}
+}
+
+// AddHits appends hits to the hit store, skipping those already stored
+// (same IP, timestamp and port). It is safe for concurrent use and returns
+// the number added.
+func (e *Extractor) AddHits(hits []models.Hit) (int, error) {
+	e.hitsMu.Lock()
+	defer e.hitsMu.Unlock()
+	stored, err := e.loadHits()
+	if err != nil {
+		return 0, err
+	}
+	seen := make(map[string]bool, len(stored)+len(hits))
+	for _, h := range stored {
+		seen[hitKey(h)] = true
+	}
+	added := 0
+	for _, h := range hits {
+		if k := hitKey(h); !seen[k] {
+			seen[k] = true
+			stored = append(stored, h)
+			added++
+		}
+	}
+	if added == 0 {
+		return 0, nil
+	}
+	if err := e.saveHits(stored); err != nil {
+		return 0, err
+	}
+	e.logger.Info("Extractor", fmt.Sprintf("%d hits honeypot ajoutes (%d au total)", added, len(stored)))
+	return added, nil
+}
+
+func hitKey(h models.Hit) string {
+	return fmt.Sprintf("%s|%d|%d", h.IP, h.Timestamp.UnixNano(), h.Port)
+}
+
+// ImportHits parses a hits feed (see ParseHits) and adds it to the store.
+func (e *Extractor) ImportHits(r io.Reader) (int, error) {
+	hits, err := ParseHits(r)
+	if err != nil {
+		return 0, err
+	}
+	return e.AddHits(hits)
+}
+
+// ImportHitsFile imports the hits feed stored at path.
+func (e *Extractor) ImportHitsFile(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("opening hits file: %w", err)
+	}
+	defer f.Close()
+	return e.ImportHits(f)
+}
+
+// Hits returns the stored honeypot hits, oldest import first.
+func (e *Extractor) Hits() ([]models.Hit, error) {
+	e.hitsMu.Lock()
+	defer e.hitsMu.Unlock()
+	return e.loadHits()
+}
+
+// ApplyHits sets HitCount and LastHit on every record from the stored hits,
+// and adds the ports hit to TargetPorts.
+func (e *Extractor) ApplyHits(data []models.ScannerData) error {
+	hits, err := e.Hits()
+	if err != nil {
+		return err
+	}
+	CorrelateHits(data, hits)
+	return nil
+}
+
+// CorrelateHits sets HitCount and LastHit on each record from the hits whose
+// address is the record's IP or falls inside its CIDR. Values from an
+// earlier correlation are replaced. The ports hit are added to TargetPorts,
+// which also holds the ports listed by the feeds.
+func CorrelateHits(data []models.ScannerData, hits []models.Hit) {
+	exact := map[string][]int{}
+	type rangeRecord struct {
+		idx int
+		net *net.IPNet
+	}
+	var ranges []rangeRecord
+	for i := range data {
+		data[i].HitCount, data[i].LastHit = 0, time.Time{}
+		s := strings.TrimSpace(data[i].IPOrCIDR)
+		if _, n, err := net.ParseCIDR(s); err == nil {
+			ranges = append(ranges, rangeRecord{i, n})
+		} else if ip := net.ParseIP(s); ip != nil {
+			exact[ip.String()] = append(exact[ip.String()], i)
+		}
+	}
+	record := func(i int, h models.Hit) {
+		data[i].HitCount++
+		if h.Timestamp.After(data[i].LastHit) {
+			data[i].LastHit = h.Timestamp
+		}
+		if h.Port > 0 {
+			data[i].TargetPorts = models.MergePorts(data[i].TargetPorts, []int{h.Port})
+		}
+	}
+	for _, h := range hits {
+		ip := net.ParseIP(h.IP)
+		if ip == nil {
+			continue
+		}
+		for _, i := range exact[ip.String()] {
+			record(i, h)
+		}
+		for _, r := range ranges {
+			if r.net.Contains(ip) {
+				record(r.idx, h)
+			}
+		}
+	}
+}
+
+// SeenAttacking returns the records with at least one honeypot hit, in
+// dataset order.
+func SeenAttacking(data []models.ScannerData) []models.ScannerData {
+	var out []models.ScannerData
+	for _, item := range data {
+		if item.HitCount > 0 {
+			out = append(out, item)
+		}
+	}
+	return out
+}
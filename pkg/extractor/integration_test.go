@@ -9,7 +9,7 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/lia/liacheckscanner_go/internal/models"
+	"github.com/lia/liacheckscanner_go/pkg/models"
 )
 
 // ---------------------------------------------------------------------------
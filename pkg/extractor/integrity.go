@@ -0,0 +1,207 @@
+package extractor
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// integrityAlgorithm names the hash recorded in DatasetIntegrity.
+const integrityAlgorithm = "sha256-merkle"
+
+// integrityChunkRows is the number of lines hashed together as one leaf of
+// the Merkle tree; a modification is located to the chunks it touches.
+const integrityChunkRows = 1000
+
+// HashDataset hashes the file at path as a Merkle tree over chunks of its
+// lines, to be recorded in its run metadata when it is written.
+func HashDataset(path string) (models.DatasetIntegrity, error) {
+	return hashDataset(path, integrityChunkRows)
+}
+
+func hashDataset(path string, chunkRows int) (models.DatasetIntegrity, error) {
+	integrity := models.DatasetIntegrity{Algorithm: integrityAlgorithm, ChunkRows: chunkRows}
+	f, err := os.Open(path)
+	if err != nil {
+		return integrity, fmt.Errorf("hashing dataset: %w", err)
+	}
+	defer f.Close()
+
+	var leaves [][]byte
+	var chunk hash.Hash
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			if chunk == nil {
+				// Leaves and nodes are prefixed apart, so a chunk cannot
+				// pass for a pair of hashes.
+				chunk = sha256.New()
+				chunk.Write([]byte{0})
+			}
+			chunk.Write(line)
+			integrity.Rows++
+			if integrity.Rows%chunkRows == 0 {
+				leaves = append(leaves, chunk.Sum(nil))
+				chunk = nil
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return integrity, fmt.Errorf("hashing dataset %s: %w", path, err)
+		}
+	}
+	if chunk != nil {
+		leaves = append(leaves, chunk.Sum(nil))
+	}
+	for _, leaf := range leaves {
+		integrity.Chunks = append(integrity.Chunks, hex.EncodeToString(leaf))
+	}
+	integrity.Root = hex.EncodeToString(merkleRoot(leaves))
+	return integrity, nil
+}
+
+// merkleRoot combines leaves pairwise up to a single hash; the last hash of
+// an odd level goes up unpaired. An empty tree hashes to the SHA-256 of
+// nothing.
+func merkleRoot(leaves [][]byte) []byte {
+	if len(leaves) == 0 {
+		sum := sha256.Sum256(nil)
+		return sum[:]
+	}
+	level := leaves
+	for len(level) > 1 {
+		var next [][]byte
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			h := sha256.New()
+			h.Write([]byte{1})
+			h.Write(level[i])
+			h.Write(level[i+1])
+			next = append(next, h.Sum(nil))
+		}
+		level = next
+	}
+	return level[0]
+}
+
+// IntegrityCheck is the result of checking a result file against the hash
+// recorded in its run metadata.
+type IntegrityCheck struct {
+	Path string
+	// Signed is false when no hash was recorded for the file: it was
+	// written before hashing existed, or by another tool.
+	Signed bool
+	// Intact is true when the file hashes to its recorded root.
+	Intact bool
+	// Rows and RecordedRows are the number of lines of the file now and
+	// when it was written.
+	Rows         int
+	RecordedRows int
+	// Modified are the line ranges, 1-based and inclusive, of the chunks
+	// that differ from the recorded ones.
+	Modified [][2]int
+}
+
+// VerifyDataset checks the file at path against the hash recorded in its
+// run metadata (see HashDataset). A file without metadata, or whose
+// metadata has no hash, is reported unsigned rather than as an error.
+func VerifyDataset(path string) (IntegrityCheck, error) {
+	check := IntegrityCheck{Path: path}
+	meta, err := ReadRunMetadata(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return check, nil
+	}
+	if err != nil {
+		return check, err
+	}
+	recorded := meta.Integrity
+	if recorded == nil {
+		return check, nil
+	}
+	if recorded.Algorithm != integrityAlgorithm || recorded.ChunkRows <= 0 {
+		return check, fmt.Errorf("unsupported dataset hash %q in %s", recorded.Algorithm, RunMetadataPath(path))
+	}
+	check.Signed = true
+	check.RecordedRows = recorded.Rows
+	now, err := hashDataset(path, recorded.ChunkRows)
+	if err != nil {
+		return check, err
+	}
+	check.Rows = now.Rows
+	check.Intact = now.Root == recorded.Root && now.Rows == recorded.Rows
+	if check.Intact {
+		return check, nil
+	}
+	last := max(now.Rows, recorded.Rows)
+	for i := 0; i < max(len(now.Chunks), len(recorded.Chunks)); i++ {
+		if i < len(now.Chunks) && i < len(recorded.Chunks) && now.Chunks[i] == recorded.Chunks[i] {
+			continue
+		}
+		from, to := i*recorded.ChunkRows+1, min((i+1)*recorded.ChunkRows, last)
+		if n := len(check.Modified); n > 0 && check.Modified[n-1][1] == from-1 {
+			check.Modified[n-1][1] = to
+			continue
+		}
+		check.Modified = append(check.Modified, [2]int{from, to})
+	}
+	return check, nil
+}
+
+// Warning describes a modification of the file for the user, "" when it is
+// intact or unsigned.
+func (c IntegrityCheck) Warning() string {
+	if !c.Signed || c.Intact {
+		return ""
+	}
+	var ranges []string
+	for _, r := range c.Modified {
+		if r[0] == r[1] {
+			ranges = append(ranges, fmt.Sprint(r[0]))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", r[0], r[1]))
+		}
+	}
+	return fmt.Sprintf("%s was modified outside LiaCheckScanner: lines %s differ from the hash recorded when it was written (%d lines now, %d then)",
+		filepath.Base(c.Path), strings.Join(ranges, ", "), c.Rows, c.RecordedRows)
+}
+
+// String reports the check in a line, for the CLI.
+func (c IntegrityCheck) String() string {
+	switch {
+	case !c.Signed:
+		return filepath.Base(c.Path) + ": no recorded hash, integrity unknown"
+	case c.Intact:
+		return fmt.Sprintf("%s: intact (%d lines)", filepath.Base(c.Path), c.Rows)
+	default:
+		return c.Warning()
+	}
+}
+
+// warnIfModified logs a warning when the result file at path was modified
+// since it was written.
+func (e *Extractor) warnIfModified(path string) {
+	check, err := VerifyDataset(path)
+	if err != nil {
+		e.logger.Warning("Extractor", err.Error())
+		return
+	}
+	if w := check.Warning(); w != "" {
+		e.logger.Warning("Extractor", w)
+	}
+}
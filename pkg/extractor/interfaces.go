@@ -0,0 +1,138 @@
+package extractor
+
+import (
+	"context"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// SourceSyncer fetches or refreshes the upstream scanner list sources on
+// disk, giving up when ctx is done.
+type SourceSyncer interface {
+	Sync(ctx context.Context) error
+}
+
+// Parser extracts scanner IPs from a local source tree and maps them back to
+// the scanner that lists them.
+type Parser interface {
+	ParseIPs(root string) ([]string, error)
+	MapScanners(root string, ips []string) map[string]ScannerInfo
+}
+
+// Enricher populates RDAP, geolocation and DNS fields on a single record,
+// giving up when ctx is done.
+type Enricher interface {
+	Enrich(ctx context.Context, data *models.ScannerData) error
+}
+
+// Store persists and reloads complete datasets by name.
+type Store interface {
+	SaveRecords(data []models.ScannerData, name string) error
+	LoadRecords(name string) ([]models.ScannerData, error)
+}
+
+// Exporter writes a dataset to an output artifact identified by name.
+type Exporter interface {
+	Export(data []models.ScannerData, name string) error
+}
+
+// The Extractor provides the default implementation of every pipeline stage.
+var (
+	_ SourceSyncer = (*Extractor)(nil)
+	_ Parser       = (*Extractor)(nil)
+	_ Enricher     = (*Extractor)(nil)
+	_ Store        = (*Extractor)(nil)
+	_ Exporter     = (*Extractor)(nil)
+)
+
+// SetSourceSyncer replaces the component used to synchronize sources.
+// Passing nil restores the built-in git implementation.
+func (e *Extractor) SetSourceSyncer(s SourceSyncer) {
+	if s == nil {
+		s = e
+	}
+	e.syncer = s
+}
+
+// SetParser replaces the component used to parse IPs from the source tree.
+// Passing nil restores the built-in .nft parser.
+func (e *Extractor) SetParser(p Parser) {
+	if p == nil {
+		p = e
+	}
+	e.parser = p
+}
+
+// SetEnricher replaces the component used to enrich records.
+// Passing nil restores the built-in RDAP and geolocation enricher.
+func (e *Extractor) SetEnricher(en Enricher) {
+	if en == nil {
+		en = e
+	}
+	e.enricher = en
+}
+
+// SetStore replaces the component used to persist datasets.
+// Passing nil restores the built-in JSON store.
+func (e *Extractor) SetStore(s Store) {
+	if s == nil {
+		s = e
+	}
+	e.store = s
+}
+
+// SetExporter replaces the component used to export datasets.
+// Passing nil restores the built-in CSV exporter.
+func (e *Extractor) SetExporter(ex Exporter) {
+	if ex == nil {
+		ex = e
+	}
+	e.exporter = ex
+}
+
+// SetAutoSave controls whether ExtractData saves its result with SaveRun.
+// It is on by default; callers that decide themselves what to write, and
+// where, turn it off.
+func (e *Extractor) SetAutoSave(enabled bool) {
+	e.noAutoSave = !enabled
+}
+
+// Sync clones or updates the configured repository, then downloads the
+// archive sources given by URL.
+func (e *Extractor) Sync(ctx context.Context) error {
+	if err := e.cloneOrUpdateRepo(ctx); err != nil {
+		return err
+	}
+	return e.syncArchives(ctx)
+}
+
+// ParseIPs returns the unique IPs found in the .nft files under root.
+func (e *Extractor) ParseIPs(root string) ([]string, error) {
+	return e.parseFilesForIPs(root)
+}
+
+// MapScanners maps each IP to the scanner whose .nft file under root lists it.
+func (e *Extractor) MapScanners(root string, ips []string) map[string]ScannerInfo {
+	return e.mapIPsToScannersIn(root, ips)
+}
+
+// Enrich enriches a single record via RDAP and geolocation lookups,
+// loading and persisting the on-disk cache around the call.
+func (e *Extractor) Enrich(ctx context.Context, data *models.ScannerData) error {
+	return e.enrichWithAPI(ctx, data)
+}
+
+// SaveRecords writes the dataset as JSON in the results directory.
+func (e *Extractor) SaveRecords(data []models.ScannerData, name string) error {
+	return e.SaveToJSON(data, name)
+}
+
+// LoadRecords reads a JSON dataset from the results or data directory.
+func (e *Extractor) LoadRecords(name string) ([]models.ScannerData, error) {
+	return e.LoadFromJSON(name)
+}
+
+// Export writes the dataset as CSV in the results directory.
+func (e *Extractor) Export(data []models.ScannerData, name string) error {
+	return e.SaveToCSV(data, name)
+}
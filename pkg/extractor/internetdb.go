@@ -0,0 +1,90 @@
+package extractor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// internetDBURL is Shodan's free InternetDB endpoint; the IP is appended.
+// It needs no key and answers 404 for IPs Shodan saw nothing on.
+const internetDBURL = "https://internetdb.shodan.io/"
+
+// InternetDBResult is what Shodan's InternetDB knows of an IP: the ports
+// seen open and the host names and CPEs (software identifiers) found on
+// them. All are empty for an IP Shodan has no data on.
+type InternetDBResult struct {
+	Ports     []int    `json:"ports"`
+	Hostnames []string `json:"hostnames"`
+	CPEs      []string `json:"cpes"`
+}
+
+// Empty reports whether InternetDB had nothing on the IP.
+func (r InternetDBResult) Empty() bool {
+	return len(r.Ports) == 0 && len(r.Hostnames) == 0 && len(r.CPEs) == 0
+}
+
+// Apply replaces the open ports, host names and CPEs of item with those of
+// r, so ports closed since the last lookup are dropped.
+func (r InternetDBResult) Apply(item *models.ScannerData) {
+	item.OpenPorts = r.Ports
+	item.Hostnames = r.Hostnames
+	item.CPEs = r.CPEs
+	item.SetProvenance(models.ProvenancePorts, "internetdb", time.Now())
+}
+
+// LookupInternetDB returns the open ports, host names and CPEs Shodan's
+// InternetDB has on ip. InternetDB only knows single addresses, so a CIDR
+// is an error.
+func (e *Extractor) LookupInternetDB(ctx context.Context, ip string) (InternetDBResult, error) {
+	if net.ParseIP(ip) == nil {
+		return InternetDBResult{}, fmt.Errorf("InternetDB lookup: %q is not a single IP address", ip)
+	}
+	base := e.internetDBURL
+	if base == "" {
+		base = internetDBURL
+	}
+	if rl := e.limiter(); rl != nil {
+		rl.Wait()
+	}
+	resp, err := e.httpGetWithRetry(ctx, base+ip)
+	if err != nil {
+		return InternetDBResult{}, fmt.Errorf("InternetDB request for %s: %w", ip, err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return InternetDBResult{}, fmt.Errorf("reading InternetDB response for %s: %w", ip, err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return InternetDBResult{}, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return InternetDBResult{}, fmt.Errorf("InternetDB http %d for %s", resp.StatusCode, ip)
+	}
+	var r InternetDBResult
+	if err := json.Unmarshal(body, &r); err != nil {
+		return InternetDBResult{}, fmt.Errorf("unmarshaling InternetDB response for %s: %w", ip, err)
+	}
+	sort.Ints(r.Ports)
+	return r, nil
+}
+
+// EnrichInternetDB looks the IP of item up in InternetDB and applies the
+// result to item.
+func (e *Extractor) EnrichInternetDB(ctx context.Context, item *models.ScannerData) error {
+	r, err := e.LookupInternetDB(ctx, item.IPOrCIDR)
+	if err != nil {
+		return err
+	}
+	r.Apply(item)
+	e.logger.Debug("Extractor", fmt.Sprintf("InternetDB %s: %d ports ouverts", item.IPOrCIDR, len(r.Ports)))
+	return nil
+}
@@ -0,0 +1,306 @@
+package extractor
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// SaveToJSON writes the scanner data to a JSON file in the configured results directory.
+func (e *Extractor) SaveToJSON(data []models.ScannerData, filename string) error {
+	e.logger.Info("Extractor", "Sauvegarde en JSON...")
+
+	if err := os.MkdirAll(e.settings().ResultsDir, 0755); err != nil {
+		return fmt.Errorf("creating results directory: %w", err)
+	}
+
+	filePath := filepath.Join(e.settings().ResultsDir, filename)
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("creating JSON file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(data); err != nil {
+		return fmt.Errorf("encoding JSON data: %w", err)
+	}
+
+	e.logger.Info("Extractor", fmt.Sprintf("Donnees sauvegardees: %s", filePath))
+	return nil
+}
+
+// SaveToCSV writes the scanner data to a CSV file in the configured results directory.
+func (e *Extractor) SaveToCSV(data []models.ScannerData, filename string) error {
+	e.logger.Info("Extractor", "Sauvegarde en CSV...")
+
+	if err := os.MkdirAll(e.settings().ResultsDir, 0755); err != nil {
+		return fmt.Errorf("creating results directory: %w", err)
+	}
+
+	filePath := filepath.Join(e.settings().ResultsDir, filename)
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("creating CSV file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	provenance := e.settings().ExportProvenance
+	headers := models.CSVHeaders
+	if provenance {
+		headers = append(append([]string(nil), headers...), "Provenance")
+	}
+	if err := writer.Write(headers); err != nil {
+		return fmt.Errorf("writing CSV headers: %w", err)
+	}
+
+	for _, item := range data {
+		row := models.ScannerDataToCSVRow(item)
+		if provenance {
+			row = append(row, models.FormatProvenance(item.Provenance))
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("writing CSV row for %s: %w", item.ID, err)
+		}
+	}
+
+	e.logger.Info("Extractor", fmt.Sprintf("Donnees sauvegardees: %s", filePath))
+	return nil
+}
+
+// LoadFromJSON reads scanner data from a JSON file, searching in the results and data directories.
+func (e *Extractor) LoadFromJSON(filename string) ([]models.ScannerData, error) {
+	filePath := filepath.Join(e.settings().ResultsDir, filename)
+	file, err := os.Open(filePath)
+	if err != nil {
+		filePath = filepath.Join("data", filename)
+		file, err = os.Open(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("aucun fichier de donnees trouve")
+		}
+	}
+	defer file.Close()
+	e.warnIfModified(filePath)
+
+	var data []models.ScannerData
+	decoder := json.NewDecoder(file)
+	if err := decoder.Decode(&data); err != nil {
+		e.logger.Warning("Extractor", "Erreur lors du decodage JSON")
+		return nil, fmt.Errorf("erreur lors du decodage JSON: %w", err)
+	}
+
+	return data, nil
+}
+
+// ReadCSVFile reads a CSV file written by SaveToCSV, mapping the columns by
+// their header, and returns its records. Returns an error if the file cannot
+// be opened, parsed, or contains fewer than 2 rows (header + at least one
+// data row).
+func ReadCSVFile(filename string) ([]models.ScannerData, error) {
+	var data []models.ScannerData
+	if err := scanCSVFile(filename, func(item models.ScannerData) {
+		data = append(data, item)
+	}); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// scanCSVFile reads a CSV file written by SaveToCSV row by row, calling fn
+// with each record, so that the whole file is never held in memory. It
+// fails like ReadCSVFile.
+func scanCSVFile(filename string, fn func(models.ScannerData)) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	headers, err := reader.Read()
+	if err == io.EOF {
+		return fmt.Errorf("insufficient data in CSV file")
+	}
+	if err != nil {
+		return err
+	}
+
+	// Build header index map
+	index := func(name string) int {
+		for i, h := range headers {
+			if strings.EqualFold(strings.TrimSpace(h), strings.TrimSpace(name)) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	ipIdx := index("IP/CIDR")
+	scannerNameIdx := index("Scanner Name")
+	scannerTypeIdx := index("Scanner Type")
+	countryCodeIdx := index("Country Code")
+	ispIdx := index("ISP")
+	orgIdx := index("Organization")
+	rdapNameIdx := index("RDAP Name")
+	rdapHandleIdx := index("RDAP Handle")
+	rdapCIDRIdx := index("RDAP CIDR")
+	registryIdx := index("RDAP Registry")
+	asnIdx := index("ASN")
+	asNameIdx := index("AS Name")
+	reverseIdx := index("Reverse DNS")
+	riskIdx := index("Risk Level")
+	scoreIdx := index("Abuse Confidence Score")
+	domainIdx := index("Domain")
+	lastSeenIdx := index("Last Seen")
+	tagsIdx := index("Tags")
+	notesIdx := index("Notes")
+	parentHandleIdx := index("Parent Handle")
+	eventRegIdx := index("Event Registration")
+	eventChangedIdx := index("Event Last Changed")
+	startAddrIdx := index("Start Address")
+	endAddrIdx := index("End Address")
+	ipVersionIdx := index("IP Version")
+	rdapTypeIdx := index("RDAP Type")
+	abuseEmailIdx := index("Abuse Email")
+	techEmailIdx := index("Tech Email")
+	peeringDBNameIdx := index("PeeringDB Name")
+	networkTypeIdx := index("Network Type")
+	trafficLevelIdx := index("Traffic Level")
+	peeringDBContactsIdx := index("PeeringDB Contacts")
+	stateIdx := index("State")
+	runsSeenIdx := index("Runs Seen")
+	previousOwnerIdx := index("Previous Owner")
+	cityIdx := index("City")
+	portsIdx := index("Open Ports")
+	hostnamesIdx := index("Hostnames")
+	cpesIdx := index("CPEs")
+	targetPortsIdx := index("Target Ports")
+	latitudeIdx := index("Latitude")
+	longitudeIdx := index("Longitude")
+	continentIdx := index("Continent")
+	continentCodeIdx := index("Continent Code")
+	regionIdx := index("Region")
+	provenanceIdx := index("Provenance")
+
+	rows := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		rows++
+		item := models.ScannerData{}
+		get := func(idx int) string {
+			if idx >= 0 && idx < len(record) {
+				return record[idx]
+			}
+			return ""
+		}
+
+		item.IPOrCIDR = get(ipIdx)
+		item.ScannerName = get(scannerNameIdx)
+		if v := get(scannerTypeIdx); v != "" {
+			item.ScannerType = models.ScannerType(v)
+		}
+		item.CountryCode = get(countryCodeIdx)
+		item.ISP = get(ispIdx)
+		item.Organization = get(orgIdx)
+		item.RDAPName = get(rdapNameIdx)
+		item.RDAPHandle = get(rdapHandleIdx)
+		item.RDAPCIDR = get(rdapCIDRIdx)
+		item.Registry = get(registryIdx)
+		item.StartAddress = get(startAddrIdx)
+		item.EndAddress = get(endAddrIdx)
+		item.IPVersion = get(ipVersionIdx)
+		item.RDAPType = get(rdapTypeIdx)
+		item.ParentHandle = get(parentHandleIdx)
+		item.EventRegistration, _ = models.ParseTimestamp(get(eventRegIdx))
+		item.EventLastChanged, _ = models.ParseTimestamp(get(eventChangedIdx))
+		item.ASN = get(asnIdx)
+		item.ASName = get(asNameIdx)
+		item.ReverseDNS = get(reverseIdx)
+		item.RiskLevel = get(riskIdx)
+		if v := get(scoreIdx); v != "" {
+			if score, err := strconv.Atoi(v); err == nil {
+				item.AbuseConfidenceScore = score
+			}
+		}
+		item.Domain = get(domainIdx)
+		if v := get(lastSeenIdx); v != "" {
+			if t, err := time.Parse("2006-01-02 15:04:05", v); err == nil {
+				item.LastSeen = t
+			} else {
+				item.LastSeen = time.Now()
+			}
+		} else {
+			item.LastSeen = time.Now()
+		}
+		if v := get(tagsIdx); v != "" {
+			if ts := strings.TrimSpace(v); ts != "" {
+				item.Tags = strings.Split(ts, ",")
+			}
+		}
+		item.Notes = get(notesIdx)
+		item.AbuseEmail = get(abuseEmailIdx)
+		item.TechEmail = get(techEmailIdx)
+		item.PeeringDBName = get(peeringDBNameIdx)
+		item.NetworkType = get(networkTypeIdx)
+		item.TrafficLevel = get(trafficLevelIdx)
+		item.PeeringDBContacts = get(peeringDBContactsIdx)
+		item.State = models.LifecycleState(get(stateIdx))
+		if v := get(runsSeenIdx); v != "" {
+			if n, err := strconv.Atoi(v); err == nil {
+				item.RunsSeen = n
+			}
+		}
+		item.PreviousOwner = get(previousOwnerIdx)
+		item.City = get(cityIdx)
+		item.OpenPorts = models.ParsePorts(get(portsIdx))
+		item.Hostnames = splitList(get(hostnamesIdx))
+		item.CPEs = splitList(get(cpesIdx))
+		item.TargetPorts = models.ParsePorts(get(targetPortsIdx))
+		item.Latitude, _ = strconv.ParseFloat(get(latitudeIdx), 64)
+		item.Longitude, _ = strconv.ParseFloat(get(longitudeIdx), 64)
+		item.Continent = get(continentIdx)
+		item.ContinentCode = get(continentCodeIdx)
+		item.Region = get(regionIdx)
+		item.Provenance = models.ParseProvenance(get(provenanceIdx))
+		// Files written before normalization may hold provider-specific values
+		NormalizeRecord(&item)
+
+		fn(item)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("insufficient data in CSV file")
+	}
+	return nil
+}
+
+// splitList splits a list written as "a, b" back into its entries, nil when
+// s is empty.
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,229 @@
+package extractor
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// Kinds of the issues reported by AuditIPv6.
+const (
+	// IPv6IssueUnparsed is a value taken for IPv6 that is no address or
+	// range, such as a time of day or a fragment of a longer token.
+	IPv6IssueUnparsed = "unparsed"
+	// IPv6IssueMissed is an IPv6 address or range of a feed line that the
+	// parser did not extract whole, e.g. with an embedded IPv4 address.
+	IPv6IssueMissed = "missed"
+	// IPv6IssueZoneID is an address with a zone ID (fe80::1%eth0), which is
+	// extracted without it.
+	IPv6IssueZoneID = "zone-id"
+	// IPv6IssueNonCanonical is an address not written in canonical form, so
+	// the same IP may be listed, cached and exported under two spellings.
+	IPv6IssueNonCanonical = "non-canonical"
+	// IPv6IssueRDAPCIDR is an enriched IPv6 record without an IPv6 RDAP
+	// range containing it.
+	IPv6IssueRDAPCIDR = "missing-rdap-cidr"
+	// IPv6IssueExport is an IPv6 value that enforcement exports would write
+	// as is although firewalls reject or reinterpret it: host bits set in a
+	// range, or an IPv4-mapped address.
+	IPv6IssueExport = "export-cidr"
+)
+
+// IPv6Issue is one IPv6-specific problem found by AuditIPv6.
+type IPv6Issue struct {
+	Kind string `json:"kind"`
+	// Value is the address, range or token concerned
+	Value string `json:"value"`
+	// Source is the feed file and line, or the ID of the record
+	Source string `json:"source"`
+	Detail string `json:"detail"`
+}
+
+// IPv6Audit is the report of AuditIPv6.
+type IPv6Audit struct {
+	Files   int         `json:"files"`
+	Lines   int         `json:"lines"`
+	Records int         `json:"records"`
+	Issues  []IPv6Issue `json:"issues"`
+}
+
+// Counts returns the number of issues of each kind.
+func (a IPv6Audit) Counts() map[string]int {
+	counts := map[string]int{}
+	for _, i := range a.Issues {
+		counts[i.Kind]++
+	}
+	return counts
+}
+
+// ipv6FieldSeparators splits a feed line into the fields checked against
+// what the parser extracts.
+var ipv6FieldSeparators = regexp.MustCompile(`[\s,;{}()"'=]+`)
+
+// parseIPv6 parses s as an IPv6 address or range, ignoring a zone ID and
+// the brackets of an [address]:port. It returns the value without them.
+func parseIPv6(s string) (string, bool) {
+	if strings.HasPrefix(s, "[") {
+		if end := strings.Index(s, "]"); end > 0 {
+			s = s[1:end]
+		}
+	}
+	if i := strings.Index(s, "%"); i >= 0 {
+		s = s[:i]
+	}
+	addr := s
+	if i := strings.Index(s, "/"); i >= 0 {
+		if _, _, err := net.ParseCIDR(s); err != nil {
+			return "", false
+		}
+		addr = s[:i]
+	}
+	ip := net.ParseIP(addr)
+	return s, ip != nil && strings.Contains(addr, ":")
+}
+
+// canonicalIPv6 returns the canonical form of an IPv6 address or range as
+// written, keeping the prefix length, or "" when s is not one.
+func canonicalIPv6(s string) string {
+	addr, prefix := s, ""
+	if i := strings.Index(s, "/"); i >= 0 {
+		addr, prefix = s[:i], s[i:]
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil || ip.To4() != nil {
+		return ""
+	}
+	return ip.String() + prefix
+}
+
+// AuditIPv6Line reports the IPv6 issues of one feed line: tokens the parser
+// extracts that are no address, addresses it cuts short or misses, zone IDs
+// and non-canonical spellings. Source is left empty.
+func AuditIPv6Line(line string) []IPv6Issue {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+	var issues []IPv6Issue
+	extracted := map[string]bool{}
+	for _, tok := range feedIPv6Regex.FindAllString(line, -1) {
+		extracted[tok] = true
+		if _, ok := parseIPv6(tok); !ok {
+			issues = append(issues, IPv6Issue{Kind: IPv6IssueUnparsed, Value: tok,
+				Detail: "extracted as IPv6 but is no IPv6 address or range"})
+		}
+	}
+	for _, field := range ipv6FieldSeparators.Split(line, -1) {
+		value, ok := parseIPv6(field)
+		if !ok {
+			continue
+		}
+		switch {
+		case strings.Contains(field, "%"):
+			issues = append(issues, IPv6Issue{Kind: IPv6IssueZoneID, Value: field,
+				Detail: "zone ID dropped, extracted as " + value})
+		case !extracted[value]:
+			issues = append(issues, IPv6Issue{Kind: IPv6IssueMissed, Value: value,
+				Detail: "not extracted whole"})
+			continue
+		}
+		if c := canonicalIPv6(value); c != "" && c != value {
+			issues = append(issues, IPv6Issue{Kind: IPv6IssueNonCanonical, Value: value,
+				Detail: "canonical form is " + c})
+		}
+	}
+	return issues
+}
+
+// AuditIPv6Record reports the IPv6 issues of a record: an IP that does not
+// parse or is not canonical, an RDAP range missing or not covering it, and
+// values enforcement exports would write wrong. Source is the record ID.
+func AuditIPv6Record(item models.ScannerData) []IPv6Issue {
+	value := strings.TrimSpace(item.IPOrCIDR)
+	if !strings.Contains(value, ":") {
+		return nil
+	}
+	issue := func(kind, detail string) IPv6Issue {
+		return IPv6Issue{Kind: kind, Value: value, Source: item.ID, Detail: detail}
+	}
+	if _, ok := parseIPv6(value); !ok || strings.ContainsAny(value, "%[") {
+		return []IPv6Issue{issue(IPv6IssueUnparsed, "not an IPv6 address or range")}
+	}
+	var issues []IPv6Issue
+	addr := value
+	if i := strings.Index(value, "/"); i >= 0 {
+		addr = value[:i]
+	}
+	ip := net.ParseIP(addr)
+	if ip.To4() != nil {
+		issues = append(issues, issue(IPv6IssueExport, "IPv4-mapped address, exported as IPv6 instead of "+ip.To4().String()))
+	} else if c := canonicalIPv6(value); c != value {
+		issues = append(issues, issue(IPv6IssueNonCanonical, "canonical form is "+c))
+	}
+	if _, n, err := net.ParseCIDR(value); err == nil && !n.IP.Equal(ip) {
+		issues = append(issues, issue(IPv6IssueExport, "host bits set, firewalls expect "+n.String()))
+	}
+	if item.RDAPHandle != "" || item.Registry != "" {
+		_, rdap, err := net.ParseCIDR(item.RDAPCIDR)
+		switch {
+		case item.RDAPCIDR == "":
+			issues = append(issues, issue(IPv6IssueRDAPCIDR, "RDAP data without a range"))
+		case err != nil || rdap.IP.To4() != nil:
+			issues = append(issues, issue(IPv6IssueRDAPCIDR, "RDAP range "+item.RDAPCIDR+" is not IPv6"))
+		case !rdap.Contains(ip):
+			issues = append(issues, issue(IPv6IssueRDAPCIDR, "RDAP range "+item.RDAPCIDR+" does not contain the address"))
+		}
+	}
+	return issues
+}
+
+// AuditIPv6 checks the IPv6 handling of the pipeline on the .nft files of
+// the local checkout, without syncing it, and on data. Issues are sorted by
+// kind, then in file and dataset order.
+func (e *Extractor) AuditIPv6(data []models.ScannerData) (IPv6Audit, error) {
+	var audit IPv6Audit
+	err := filepath.Walk(e.localPath(), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && strings.HasPrefix(info.Name(), ".") && path != e.localPath() {
+			return filepath.SkipDir
+		}
+		if info.IsDir() || !strings.HasSuffix(strings.ToLower(path), ".nft") {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("opening nft file %s: %w", path, err)
+		}
+		defer f.Close()
+		audit.Files++
+		scanner := bufio.NewScanner(f)
+		for n := 1; scanner.Scan(); n++ {
+			audit.Lines++
+			for _, i := range AuditIPv6Line(scanner.Text()) {
+				i.Source = fmt.Sprintf("%s:%d", filepath.Base(path), n)
+				audit.Issues = append(audit.Issues, i)
+			}
+		}
+		return scanner.Err()
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return audit, fmt.Errorf("auditing feed files: %w", err)
+	}
+	for _, item := range data {
+		audit.Records++
+		audit.Issues = append(audit.Issues, AuditIPv6Record(item)...)
+	}
+	sort.SliceStable(audit.Issues, func(i, j int) bool { return audit.Issues[i].Kind < audit.Issues[j].Kind })
+	e.logger.Info("Extractor", fmt.Sprintf("Audit IPv6: %d fichiers, %d enregistrements, %d problemes",
+		audit.Files, audit.Records, len(audit.Issues)))
+	return audit, nil
+}
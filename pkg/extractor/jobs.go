@@ -0,0 +1,108 @@
+package extractor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kinds of the jobs recorded with SaveJobState.
+const (
+	// JobEnrich is an EnrichIPs run, as made by the CLI and ExtractData.
+	JobEnrich = "enrich"
+	// JobBulkEnrich is a re-enrichment of records chosen in the GUI.
+	JobBulkEnrich = "bulk-enrich"
+)
+
+// jobCheckpointEvery is how many enriched records separate two checkpoints
+// of an EnrichIPs run: the RDAP cache and the job state are written, so an
+// interrupted run resumes from the cache.
+const jobCheckpointEvery = 250
+
+// JobState is a job in progress, written when it starts and removed when it
+// ends. A state still on disk at startup belongs to a job interrupted by a
+// crash or a kill. Resuming a job means running it again on the same IPs:
+// those done before the interruption come from the RDAP cache.
+type JobState struct {
+	Kind      string    `json:"kind"`
+	StartedAt time.Time `json:"started_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Done      int       `json:"done"`
+	IPs       []string  `json:"ips"`
+}
+
+// jobStateDir returns the directory of the job states.
+func (e *Extractor) jobStateDir() string {
+	if e.jobStatePath != "" {
+		return e.jobStatePath
+	}
+	return filepath.Join("build", "data", "jobs")
+}
+
+// SaveJobState writes the state of the job of kind s.Kind, replacing the
+// previous one.
+func (e *Extractor) SaveJobState(s *JobState) error {
+	if err := os.MkdirAll(e.jobStateDir(), 0755); err != nil {
+		return fmt.Errorf("creating job state directory: %w", err)
+	}
+	s.UpdatedAt = time.Now().UTC()
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding job state: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(e.jobStateDir(), s.Kind+".json"), b, 0644); err != nil {
+		return fmt.Errorf("writing job state: %w", err)
+	}
+	return nil
+}
+
+// ClearJobState removes the state of the job of kind, once it ended or was
+// abandoned.
+func (e *Extractor) ClearJobState(kind string) error {
+	if err := os.Remove(filepath.Join(e.jobStateDir(), kind+".json")); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing job state: %w", err)
+	}
+	return nil
+}
+
+// InterruptedJobs returns the job states left on disk, oldest first.
+// Unreadable states are skipped.
+func (e *Extractor) InterruptedJobs() ([]JobState, error) {
+	files, err := filepath.Glob(filepath.Join(e.jobStateDir(), "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("listing job states: %w", err)
+	}
+	var jobs []JobState
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		var s JobState
+		if err := json.Unmarshal(b, &s); err != nil || s.Kind != strings.TrimSuffix(filepath.Base(f), ".json") {
+			e.logger.Warning("Extractor", "Etat de job illisible ignore: "+f)
+			continue
+		}
+		jobs = append(jobs, s)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].StartedAt.Before(jobs[j].StartedAt) })
+	return jobs, nil
+}
+
+// InterruptedJob returns the state left on disk by the job of kind, or nil.
+func (e *Extractor) InterruptedJob(kind string) (*JobState, error) {
+	jobs, err := e.InterruptedJobs()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range jobs {
+		if s.Kind == kind {
+			return &s, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,159 @@
+package extractor
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// kmlTemplate maps the located records to a KML document, opened as is by
+// Google Earth for stakeholders without GIS tools.
+var kmlTemplate = ExportTemplate{
+	Name:        "kml",
+	Description: "KML document of located records, a folder per scanner (Google Earth)",
+	Extension:   ".kml",
+	Write:       WriteKML,
+}
+
+// kmlIcon is the Google Earth icon every placemark uses, tinted by the
+// style of its risk level.
+const kmlIcon = "http://maps.google.com/mapfiles/kml/shapes/placemark_circle.png"
+
+// kmlRiskStyles are the placemark styles by risk level, as returned by
+// getRiskLevel, with their icon colors in the KML aabbggrr order.
+var kmlRiskStyles = []struct {
+	risk  string
+	color string
+	scale float64
+}{
+	{"Critical", "ff0000ff", 1.4},
+	{"High", "ff0080ff", 1.2},
+	{"Medium", "ff00ffff", 1.0},
+	{"Low", "ff00ff00", 0.9},
+	{"Very Low", "ffff8000", 0.8},
+	{"", "ffaaaaaa", 0.8},
+}
+
+type kmlDocument struct {
+	XMLName xml.Name    `xml:"kml"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	Name    string      `xml:"Document>name"`
+	Styles  []kmlStyle  `xml:"Document>Style"`
+	Folders []kmlFolder `xml:"Document>Folder"`
+}
+
+type kmlStyle struct {
+	ID    string  `xml:"id,attr"`
+	Color string  `xml:"IconStyle>color"`
+	Scale float64 `xml:"IconStyle>scale"`
+	Icon  string  `xml:"IconStyle>Icon>href"`
+}
+
+type kmlFolder struct {
+	Name       string         `xml:"name"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlPlacemark struct {
+	Name        string    `xml:"name"`
+	Description string    `xml:"description"`
+	StyleURL    string    `xml:"styleUrl"`
+	Data        []kmlData `xml:"ExtendedData>Data"`
+	// Coordinates are "longitude,latitude", the KML order.
+	Coordinates string `xml:"Point>coordinates"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+// kmlStyleID returns the id of the style of a risk level; levels without a
+// style of their own share the unknown one.
+func kmlStyleID(risk string) string {
+	for _, s := range kmlRiskStyles {
+		if s.risk != "" && strings.EqualFold(s.risk, risk) {
+			return "risk-" + strings.ReplaceAll(strings.ToLower(s.risk), " ", "-")
+		}
+	}
+	return "risk-unknown"
+}
+
+// WriteKML writes the records of data that geolocation located as a KML
+// document, with a folder of placemarks per scanner ordered by name and an
+// icon style per risk level, and returns the number of placemarks written.
+// The others are skipped.
+func WriteKML(w io.Writer, data []models.ScannerData) (int, error) {
+	doc := kmlDocument{XMLNS: "http://www.opengis.net/kml/2.2", Name: "LiaCheckScanner"}
+	for _, s := range kmlRiskStyles {
+		doc.Styles = append(doc.Styles, kmlStyle{ID: kmlStyleID(s.risk), Color: s.color, Scale: s.scale, Icon: kmlIcon})
+	}
+
+	byScanner := map[string][]kmlPlacemark{}
+	n := 0
+	for _, item := range data {
+		if !item.HasLocation() {
+			continue
+		}
+		scanner := item.ScannerName
+		if scanner == "" {
+			scanner = "Unknown"
+		}
+		byScanner[scanner] = append(byScanner[scanner], kmlPlacemark{
+			Name:        item.IPOrCIDR,
+			Description: kmlDescription(scanner, item),
+			StyleURL:    "#" + kmlStyleID(item.RiskLevel),
+			Data: []kmlData{
+				{Name: "scanner_type", Value: string(item.ScannerType)},
+				{Name: "risk", Value: item.RiskLevel},
+				{Name: "score", Value: fmt.Sprint(item.AbuseConfidenceScore)},
+				{Name: "asn", Value: item.ASN},
+				{Name: "country", Value: item.CountryCode},
+				{Name: "city", Value: item.City},
+				{Name: "last_seen", Value: formatTemplateTime(item.LastSeen)},
+			},
+			Coordinates: fmt.Sprintf("%g,%g", item.Longitude, item.Latitude),
+		})
+		n++
+	}
+	scanners := make([]string, 0, len(byScanner))
+	for name := range byScanner {
+		scanners = append(scanners, name)
+	}
+	sort.Strings(scanners)
+	for _, name := range scanners {
+		doc.Folders = append(doc.Folders, kmlFolder{Name: name, Placemarks: byScanner[name]})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return 0, err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return 0, err
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// kmlDescription returns the balloon text of a placemark: its scanner, risk
+// level, ASN and country, leaving out the empty ones.
+func kmlDescription(scanner string, item models.ScannerData) string {
+	parts := []string{scanner}
+	if item.RiskLevel != "" {
+		parts = append(parts, item.RiskLevel+" risk")
+	}
+	for _, v := range []string{item.ASN, item.CountryName} {
+		if v != "" {
+			parts = append(parts, v)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
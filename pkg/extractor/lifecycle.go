@@ -0,0 +1,212 @@
+package extractor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// Default greylisting dwell times, in runs.
+const (
+	defaultCandidateAfterRuns = 2
+	defaultBlockAfterRuns     = 3
+	defaultRetireAfterRuns    = 3
+)
+
+// lifecycleStore is the on-disk greylisting history keyed by IP.
+type lifecycleStore struct {
+	Entries map[string]*models.LifecycleEntry `json:"entries"`
+}
+
+// lifecycleFile returns the path of the greylisting store.
+func (e *Extractor) lifecycleFile() string {
+	if e.lifecyclePath != "" {
+		return e.lifecyclePath
+	}
+	return filepath.Join("build", "data", "lifecycle.json")
+}
+
+// dwellRuns returns the configured dwell times, falling back to the defaults.
+func (e *Extractor) dwellRuns() (candidate, block, retire int) {
+	cfg := e.settings()
+	candidate, block, retire = cfg.CandidateAfterRuns, cfg.BlockAfterRuns, cfg.RetireAfterRuns
+	if candidate <= 0 {
+		candidate = defaultCandidateAfterRuns
+	}
+	if block < candidate {
+		block = defaultBlockAfterRuns
+		if block < candidate {
+			block = candidate
+		}
+	}
+	if retire <= 0 {
+		retire = defaultRetireAfterRuns
+	}
+	return candidate, block, retire
+}
+
+func (e *Extractor) loadLifecycle() (*lifecycleStore, error) {
+	store := &lifecycleStore{Entries: map[string]*models.LifecycleEntry{}}
+	b, err := os.ReadFile(e.lifecycleFile())
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading lifecycle store: %w", err)
+	}
+	if err := json.Unmarshal(b, store); err != nil {
+		return nil, fmt.Errorf("decoding lifecycle store: %w", err)
+	}
+	if store.Entries == nil {
+		store.Entries = map[string]*models.LifecycleEntry{}
+	}
+	return store, nil
+}
+
+// lockLifecycle takes the lock shared by the instances on the greylisting
+// store and reads it, so a change saved before unlock does not overwrite
+// the update of another instance.
+func (e *Extractor) lockLifecycle() (store *lifecycleStore, unlock func(), err error) {
+	path := e.lifecycleFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, nil, fmt.Errorf("creating lifecycle directory: %w", err)
+	}
+	unlock, err = lockDataFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("writing lifecycle store: %w", err)
+	}
+	store, err = e.loadLifecycle()
+	if err != nil {
+		unlock()
+		return nil, nil, err
+	}
+	return store, unlock, nil
+}
+
+// saveLifecycle writes store as the greylisting store. Its lock must be
+// held (see lockLifecycle).
+func (e *Extractor) saveLifecycle(store *lifecycleStore) error {
+	if err := writeJSONAtomic(e.lifecycleFile(), store); err != nil {
+		return fmt.Errorf("writing lifecycle store: %w", err)
+	}
+	return nil
+}
+
+// UpdateLifecycle records a run containing data: every IP present advances
+// observed -> candidate -> blocked as it persists, IPs absent for enough runs
+// are retired, and pinned (manually overridden) entries keep their state.
+// The resulting State and RunsSeen are written back into data.
+func (e *Extractor) UpdateLifecycle(data []models.ScannerData) error {
+	store, unlock, err := e.lockLifecycle()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	candidateRuns, blockRuns, retireRuns := e.dwellRuns()
+	now := time.Now().Format(time.RFC3339)
+
+	present := make(map[string]bool, len(data))
+	for i := range data {
+		ip := data[i].IPOrCIDR
+		present[ip] = true
+		entry, ok := store.Entries[ip]
+		if !ok {
+			entry = &models.LifecycleEntry{State: models.StateObserved, FirstSeen: now}
+			store.Entries[ip] = entry
+		}
+		if entry.State == models.StateRetired && !entry.Pinned {
+			// A retired IP that comes back starts over.
+			entry.RunsSeen = 0
+			entry.State = models.StateObserved
+		}
+		entry.RunsSeen++
+		entry.MissedRuns = 0
+		entry.LastSeen = now
+		if !entry.Pinned {
+			switch {
+			case entry.RunsSeen >= blockRuns:
+				entry.State = models.StateBlocked
+			case entry.RunsSeen >= candidateRuns:
+				entry.State = models.StateCandidate
+			default:
+				entry.State = models.StateObserved
+			}
+		}
+		data[i].State = entry.State
+		data[i].RunsSeen = entry.RunsSeen
+	}
+
+	retired := 0
+	for ip, entry := range store.Entries {
+		if present[ip] {
+			continue
+		}
+		entry.MissedRuns++
+		entry.RunsSeen = 0
+		if !entry.Pinned && entry.State != models.StateRetired && entry.MissedRuns >= retireRuns {
+			entry.State = models.StateRetired
+			retired++
+		}
+	}
+
+	e.logger.Info("Extractor", fmt.Sprintf("Greylisting: %d IPs suivies, %d retirees ce run", len(store.Entries), retired))
+	return e.saveLifecycle(store)
+}
+
+// SetLifecycleState manually overrides the state of ip. When pinned is true
+// the state no longer changes automatically; passing pinned=false lets the
+// next run re-evaluate it.
+func (e *Extractor) SetLifecycleState(ip string, state models.LifecycleState, pinned bool) error {
+	switch state {
+	case models.StateObserved, models.StateCandidate, models.StateBlocked, models.StateRetired:
+	default:
+		return fmt.Errorf("unknown lifecycle state %q", state)
+	}
+	store, unlock, err := e.lockLifecycle()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	entry, ok := store.Entries[ip]
+	if !ok {
+		entry = &models.LifecycleEntry{FirstSeen: time.Now().Format(time.RFC3339)}
+		store.Entries[ip] = entry
+	}
+	entry.State = state
+	entry.Pinned = pinned
+	e.logger.Info("Extractor", fmt.Sprintf("Greylisting: %s force a %s (pinned=%t)", ip, state, pinned))
+	return e.saveLifecycle(store)
+}
+
+// LifecycleEntries returns a copy of the persisted greylisting history.
+func (e *Extractor) LifecycleEntries() (map[string]models.LifecycleEntry, error) {
+	store, err := e.loadLifecycle()
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]models.LifecycleEntry, len(store.Entries))
+	for ip, entry := range store.Entries {
+		out[ip] = *entry
+	}
+	return out, nil
+}
+
+// Enforceable returns the records that may be pushed to enforcement exports,
+// i.e. those in the blocked state, not stale (see ApplyAging), not
+// excluded as too broad (see ApplyPrefixPolicy), not held back for a low
+// attribution confidence (see AttributeScanners) and not held back by the
+// enforcement policy (see ApplyEnforcementPolicy).
+func Enforceable(data []models.ScannerData) []models.ScannerData {
+	var out []models.ScannerData
+	for _, item := range data {
+		if item.State == models.StateBlocked && !item.Stale && item.BroadPrefix != models.BroadPrefixExcluded &&
+			!item.ConfidenceHold && item.PolicyHold == "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,239 @@
+package extractor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// ErrRecordLocked is returned when enriching a record whose IP is under
+// investigation (see LockRecords).
+var ErrRecordLocked = errors.New("record locked for investigation")
+
+// lockFile returns the path of the record lock store.
+func (e *Extractor) lockFile() string {
+	if e.lockPath != "" {
+		return e.lockPath
+	}
+	return filepath.Join("build", "data", "locks.json")
+}
+
+// fileStamp identifies a version of a data file, to tell whether another
+// instance rewrote it since it was read.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// statStamp returns the stamp of the file at path, zero when it does not
+// exist.
+func statStamp(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return fileStamp{}, nil
+	}
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{info.ModTime(), info.Size()}, nil
+}
+
+// readLocks reads the record lock store from disk.
+func (e *Extractor) readLocks() (map[string]models.RecordLock, error) {
+	locks := map[string]models.RecordLock{}
+	b, err := os.ReadFile(e.lockFile())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading record lock store: %w", err)
+	}
+	if err == nil {
+		var list []models.RecordLock
+		if err := json.Unmarshal(b, &list); err != nil {
+			return nil, fmt.Errorf("decoding record lock store: %w", err)
+		}
+		for _, l := range list {
+			locks[l.IP] = l
+		}
+	}
+	return locks, nil
+}
+
+// loadLocks returns the record lock store, read again whenever the file
+// changed since the last read so the locks of the other instances are
+// seen. e.lockMu must be held.
+func (e *Extractor) loadLocks() (map[string]models.RecordLock, error) {
+	stamp, err := statStamp(e.lockFile())
+	if err != nil {
+		return nil, fmt.Errorf("reading record lock store: %w", err)
+	}
+	if e.locks != nil && stamp == e.locksStamp {
+		return e.locks, nil
+	}
+	locks, err := e.readLocks()
+	if err != nil {
+		return nil, err
+	}
+	e.locks, e.locksStamp = locks, stamp
+	return locks, nil
+}
+
+// updateLocks applies change to the record lock store, read again under the
+// lock shared by the instances so their changes are merged rather than
+// overwritten, and writes it back when change returns a non-zero count.
+// e.lockMu must be held.
+func (e *Extractor) updateLocks(change func(locks map[string]models.RecordLock) int) (int, error) {
+	path := e.lockFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, fmt.Errorf("creating record lock directory: %w", err)
+	}
+	unlock, err := lockDataFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("writing record lock store: %w", err)
+	}
+	defer unlock()
+	locks, err := e.readLocks()
+	if err != nil {
+		return 0, err
+	}
+	n := change(locks)
+	if n > 0 {
+		list := make([]models.RecordLock, 0, len(locks))
+		for _, l := range locks {
+			list = append(list, l)
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].IP < list[j].IP })
+		if err := writeJSONAtomic(path, list); err != nil {
+			return 0, fmt.Errorf("writing record lock store: %w", err)
+		}
+	}
+	// A stamp that cannot be read leaves the cache to be read again
+	stamp, _ := statStamp(path)
+	e.locks, e.locksStamp = locks, stamp
+	return n, nil
+}
+
+// LockRecords marks ips as under investigation by author, for reason:
+// aging, re-enrichment and bulk deletions skip their records until
+// UnlockRecords. IPs already locked keep their lock. It returns the number
+// of IPs newly locked.
+func (e *Extractor) LockRecords(ips []string, author, reason string) (int, error) {
+	author, reason = strings.TrimSpace(author), strings.TrimSpace(reason)
+	if author == "" {
+		return 0, fmt.Errorf("author is required")
+	}
+	e.lockMu.Lock()
+	defer e.lockMu.Unlock()
+	now := time.Now().UTC().Format(time.RFC3339)
+	added, err := e.updateLocks(func(locks map[string]models.RecordLock) int {
+		added := 0
+		for _, ip := range ips {
+			if ip = strings.TrimSpace(ip); ip == "" {
+				continue
+			}
+			if _, ok := locks[ip]; ok {
+				continue
+			}
+			locks[ip] = models.RecordLock{IP: ip, Author: author, Reason: reason, LockedAt: now}
+			added++
+		}
+		return added
+	})
+	if err != nil || added == 0 {
+		return 0, err
+	}
+	e.logger.Info("Extractor", fmt.Sprintf("%d IPs verrouillees par %s", added, author))
+	return added, nil
+}
+
+// UnlockRecords lifts the lock of ips and returns the number unlocked.
+func (e *Extractor) UnlockRecords(ips []string) (int, error) {
+	e.lockMu.Lock()
+	defer e.lockMu.Unlock()
+	removed, err := e.updateLocks(func(locks map[string]models.RecordLock) int {
+		removed := 0
+		for _, ip := range ips {
+			if _, ok := locks[strings.TrimSpace(ip)]; ok {
+				delete(locks, strings.TrimSpace(ip))
+				removed++
+			}
+		}
+		return removed
+	})
+	if err != nil || removed == 0 {
+		return 0, err
+	}
+	e.logger.Info("Extractor", fmt.Sprintf("%d IPs deverrouillees", removed))
+	return removed, nil
+}
+
+// RecordLocks returns a copy of the record locks, by IP.
+func (e *Extractor) RecordLocks() (map[string]models.RecordLock, error) {
+	e.lockMu.Lock()
+	defer e.lockMu.Unlock()
+	locks, err := e.loadLocks()
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]models.RecordLock, len(locks))
+	for ip, l := range locks {
+		out[ip] = l
+	}
+	return out, nil
+}
+
+// IsLocked reports whether ip is under investigation. A lock store that
+// cannot be read locks nothing.
+func (e *Extractor) IsLocked(ip string) bool {
+	e.lockMu.Lock()
+	defer e.lockMu.Unlock()
+	locks, err := e.loadLocks()
+	if err != nil {
+		e.logger.Warning("Extractor", err.Error())
+		return false
+	}
+	_, ok := locks[strings.TrimSpace(ip)]
+	return ok
+}
+
+// ApplyLocks sets Lock on the records of data whose IP is locked and clears
+// it on the others.
+func (e *Extractor) ApplyLocks(data []models.ScannerData) error {
+	locks, err := e.RecordLocks()
+	if err != nil {
+		return err
+	}
+	for i := range data {
+		data[i].Lock = nil
+		if l, ok := locks[data[i].IPOrCIDR]; ok {
+			data[i].Lock = &l
+		}
+	}
+	return nil
+}
+
+// KeepLocked returns fresh, a dataset replacing previous, with the records
+// of previous that are locked in place of the fresh records of the same IP
+// and scanner, so a new extraction or enrichment does not overwrite them.
+func KeepLocked(previous, fresh []models.ScannerData) []models.ScannerData {
+	locked := map[string]models.ScannerData{}
+	for _, item := range previous {
+		if item.Lock != nil {
+			locked[dedupKey(item)] = item
+		}
+	}
+	if len(locked) == 0 {
+		return fresh
+	}
+	for i := range fresh {
+		if prev, ok := locked[dedupKey(fresh[i])]; ok {
+			fresh[i] = prev
+		}
+	}
+	return fresh
+}
@@ -0,0 +1,161 @@
+package extractor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// pushgatewayJob is the job label under which runs are pushed.
+const pushgatewayJob = "liacheckscanner"
+
+// RunMetrics are the aggregate counts of one run, as pushed to InfluxDB or
+// a Prometheus pushgateway.
+type RunMetrics struct {
+	At        time.Time
+	Total     int
+	ByScanner map[string]int
+	ByCountry map[string]int // country code, "unknown" when not enriched
+	ByRisk    map[string]int
+}
+
+// ComputeRunMetrics counts the records of data per scanner, country and
+// risk level.
+func ComputeRunMetrics(data []models.ScannerData, at time.Time) RunMetrics {
+	m := RunMetrics{
+		At:        at,
+		Total:     len(data),
+		ByScanner: map[string]int{},
+		ByCountry: map[string]int{},
+		ByRisk:    map[string]int{},
+	}
+	orUnknown := func(s string) string {
+		if s = strings.TrimSpace(s); s == "" {
+			return "unknown"
+		}
+		return s
+	}
+	for _, item := range data {
+		m.ByScanner[orUnknown(item.ScannerName)]++
+		m.ByCountry[orUnknown(item.CountryCode)]++
+		m.ByRisk[orUnknown(item.RiskLevel)]++
+	}
+	return m
+}
+
+// metricGroups lists the breakdowns of m with their label names, in output
+// order.
+func (m RunMetrics) metricGroups() []struct {
+	name, label string
+	counts      map[string]int
+} {
+	return []struct {
+		name, label string
+		counts      map[string]int
+	}{
+		{"liacheckscanner_records_by_scanner", "scanner", m.ByScanner},
+		{"liacheckscanner_records_by_country", "country", m.ByCountry},
+		{"liacheckscanner_records_by_risk", "risk", m.ByRisk},
+	}
+}
+
+func sortedKeys(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// InfluxLineProtocol formats m as InfluxDB line protocol, one point per
+// count, timestamped in nanoseconds.
+func InfluxLineProtocol(m RunMetrics) string {
+	var b strings.Builder
+	ts := m.At.UnixNano()
+	fmt.Fprintf(&b, "liacheckscanner_records total=%di %d\n", m.Total, ts)
+	tag := strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+	for _, g := range m.metricGroups() {
+		for _, k := range sortedKeys(g.counts) {
+			fmt.Fprintf(&b, "%s,%s=%s count=%di %d\n", g.name, g.label, tag.Replace(k), g.counts[k], ts)
+		}
+	}
+	return b.String()
+}
+
+// PrometheusText formats m in the Prometheus text exposition format, as
+// gauges.
+func PrometheusText(m RunMetrics) string {
+	var b strings.Builder
+	b.WriteString("# HELP liacheckscanner_records Records in the last run.\n")
+	b.WriteString("# TYPE liacheckscanner_records gauge\n")
+	fmt.Fprintf(&b, "liacheckscanner_records %d\n", m.Total)
+	b.WriteString("# HELP liacheckscanner_last_run_timestamp_seconds Time of the last run.\n")
+	b.WriteString("# TYPE liacheckscanner_last_run_timestamp_seconds gauge\n")
+	fmt.Fprintf(&b, "liacheckscanner_last_run_timestamp_seconds %d\n", m.At.Unix())
+	value := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	for _, g := range m.metricGroups() {
+		fmt.Fprintf(&b, "# HELP %s Records in the last run per %s.\n", g.name, g.label)
+		fmt.Fprintf(&b, "# TYPE %s gauge\n", g.name)
+		for _, k := range sortedKeys(g.counts) {
+			fmt.Fprintf(&b, "%s{%s=\"%s\"} %d\n", g.name, g.label, value.Replace(k), g.counts[k])
+		}
+	}
+	return b.String()
+}
+
+// PushMetrics sends the aggregate counts of data to the configured InfluxDB
+// write endpoint and Prometheus pushgateway. It does nothing when neither is
+// set. The pushgateway group is replaced, so scanners or countries absent
+// from this run disappear from it.
+func (e *Extractor) PushMetrics(ctx context.Context, data []models.ScannerData) error {
+	cfg := e.settings()
+	if cfg.MetricsInfluxURL == "" && cfg.MetricsPushgatewayURL == "" {
+		return nil
+	}
+	m := ComputeRunMetrics(data, time.Now().UTC())
+	if cfg.MetricsInfluxURL != "" {
+		header := http.Header{"Content-Type": {"text/plain; charset=utf-8"}}
+		if cfg.MetricsInfluxToken != "" {
+			header.Set("Authorization", "Token "+cfg.MetricsInfluxToken)
+		}
+		if err := e.sendHTTP(ctx, http.MethodPost, cfg.MetricsInfluxURL, header, InfluxLineProtocol(m)); err != nil {
+			return fmt.Errorf("pushing metrics to InfluxDB: %w", err)
+		}
+	}
+	if cfg.MetricsPushgatewayURL != "" {
+		url := strings.TrimSuffix(cfg.MetricsPushgatewayURL, "/") + "/metrics/job/" + pushgatewayJob
+		header := http.Header{"Content-Type": {"text/plain; version=0.0.4"}}
+		if err := e.sendHTTP(ctx, http.MethodPut, url, header, PrometheusText(m)); err != nil {
+			return fmt.Errorf("pushing metrics to pushgateway: %w", err)
+		}
+	}
+	e.logger.Info("Extractor", fmt.Sprintf("Metriques du run envoyees: %d enregistrements, %d scanners, %d pays",
+		m.Total, len(m.ByScanner), len(m.ByCountry)))
+	return nil
+}
+
+// sendHTTP sends body to url, failing on a status other than 2xx.
+func (e *Extractor) sendHTTP(ctx context.Context, method, url string, header http.Header, body string) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header = header
+	resp, err := e.apiClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("http %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,373 @@
+package extractor
+
+import (
+	"strings"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// isoCountries lists the ISO 3166-1 countries as "alpha-2|alpha-3|name".
+const isoCountries = `AD|AND|Andorra
+AE|ARE|United Arab Emirates
+AF|AFG|Afghanistan
+AG|ATG|Antigua and Barbuda
+AI|AIA|Anguilla
+AL|ALB|Albania
+AM|ARM|Armenia
+AO|AGO|Angola
+AQ|ATA|Antarctica
+AR|ARG|Argentina
+AS|ASM|American Samoa
+AT|AUT|Austria
+AU|AUS|Australia
+AW|ABW|Aruba
+AX|ALA|Aland Islands
+AZ|AZE|Azerbaijan
+BA|BIH|Bosnia and Herzegovina
+BB|BRB|Barbados
+BD|BGD|Bangladesh
+BE|BEL|Belgium
+BF|BFA|Burkina Faso
+BG|BGR|Bulgaria
+BH|BHR|Bahrain
+BI|BDI|Burundi
+BJ|BEN|Benin
+BL|BLM|Saint Barthelemy
+BM|BMU|Bermuda
+BN|BRN|Brunei
+BO|BOL|Bolivia
+BQ|BES|Caribbean Netherlands
+BR|BRA|Brazil
+BS|BHS|Bahamas
+BT|BTN|Bhutan
+BV|BVT|Bouvet Island
+BW|BWA|Botswana
+BY|BLR|Belarus
+BZ|BLZ|Belize
+CA|CAN|Canada
+CC|CCK|Cocos (Keeling) Islands
+CD|COD|DR Congo
+CF|CAF|Central African Republic
+CG|COG|Republic of the Congo
+CH|CHE|Switzerland
+CI|CIV|Ivory Coast
+CK|COK|Cook Islands
+CL|CHL|Chile
+CM|CMR|Cameroon
+CN|CHN|China
+CO|COL|Colombia
+CR|CRI|Costa Rica
+CU|CUB|Cuba
+CV|CPV|Cape Verde
+CW|CUW|Curacao
+CX|CXR|Christmas Island
+CY|CYP|Cyprus
+CZ|CZE|Czechia
+DE|DEU|Germany
+DJ|DJI|Djibouti
+DK|DNK|Denmark
+DM|DMA|Dominica
+DO|DOM|Dominican Republic
+DZ|DZA|Algeria
+EC|ECU|Ecuador
+EE|EST|Estonia
+EG|EGY|Egypt
+EH|ESH|Western Sahara
+ER|ERI|Eritrea
+ES|ESP|Spain
+ET|ETH|Ethiopia
+FI|FIN|Finland
+FJ|FJI|Fiji
+FK|FLK|Falkland Islands
+FM|FSM|Micronesia
+FO|FRO|Faroe Islands
+FR|FRA|France
+GA|GAB|Gabon
+GB|GBR|United Kingdom
+GD|GRD|Grenada
+GE|GEO|Georgia
+GF|GUF|French Guiana
+GG|GGY|Guernsey
+GH|GHA|Ghana
+GI|GIB|Gibraltar
+GL|GRL|Greenland
+GM|GMB|Gambia
+GN|GIN|Guinea
+GP|GLP|Guadeloupe
+GQ|GNQ|Equatorial Guinea
+GR|GRC|Greece
+GS|SGS|South Georgia and the South Sandwich Islands
+GT|GTM|Guatemala
+GU|GUM|Guam
+GW|GNB|Guinea-Bissau
+GY|GUY|Guyana
+HK|HKG|Hong Kong
+HM|HMD|Heard Island and McDonald Islands
+HN|HND|Honduras
+HR|HRV|Croatia
+HT|HTI|Haiti
+HU|HUN|Hungary
+ID|IDN|Indonesia
+IE|IRL|Ireland
+IL|ISR|Israel
+IM|IMN|Isle of Man
+IN|IND|India
+IO|IOT|British Indian Ocean Territory
+IQ|IRQ|Iraq
+IR|IRN|Iran
+IS|ISL|Iceland
+IT|ITA|Italy
+JE|JEY|Jersey
+JM|JAM|Jamaica
+JO|JOR|Jordan
+JP|JPN|Japan
+KE|KEN|Kenya
+KG|KGZ|Kyrgyzstan
+KH|KHM|Cambodia
+KI|KIR|Kiribati
+KM|COM|Comoros
+KN|KNA|Saint Kitts and Nevis
+KP|PRK|North Korea
+KR|KOR|South Korea
+KW|KWT|Kuwait
+KY|CYM|Cayman Islands
+KZ|KAZ|Kazakhstan
+LA|LAO|Laos
+LB|LBN|Lebanon
+LC|LCA|Saint Lucia
+LI|LIE|Liechtenstein
+LK|LKA|Sri Lanka
+LR|LBR|Liberia
+LS|LSO|Lesotho
+LT|LTU|Lithuania
+LU|LUX|Luxembourg
+LV|LVA|Latvia
+LY|LBY|Libya
+MA|MAR|Morocco
+MC|MCO|Monaco
+MD|MDA|Moldova
+ME|MNE|Montenegro
+MF|MAF|Saint Martin
+MG|MDG|Madagascar
+MH|MHL|Marshall Islands
+MK|MKD|North Macedonia
+ML|MLI|Mali
+MM|MMR|Myanmar
+MN|MNG|Mongolia
+MO|MAC|Macao
+MP|MNP|Northern Mariana Islands
+MQ|MTQ|Martinique
+MR|MRT|Mauritania
+MS|MSR|Montserrat
+MT|MLT|Malta
+MU|MUS|Mauritius
+MV|MDV|Maldives
+MW|MWI|Malawi
+MX|MEX|Mexico
+MY|MYS|Malaysia
+MZ|MOZ|Mozambique
+NA|NAM|Namibia
+NC|NCL|New Caledonia
+NE|NER|Niger
+NF|NFK|Norfolk Island
+NG|NGA|Nigeria
+NI|NIC|Nicaragua
+NL|NLD|Netherlands
+NO|NOR|Norway
+NP|NPL|Nepal
+NR|NRU|Nauru
+NU|NIU|Niue
+NZ|NZL|New Zealand
+OM|OMN|Oman
+PA|PAN|Panama
+PE|PER|Peru
+PF|PYF|French Polynesia
+PG|PNG|Papua New Guinea
+PH|PHL|Philippines
+PK|PAK|Pakistan
+PL|POL|Poland
+PM|SPM|Saint Pierre and Miquelon
+PN|PCN|Pitcairn Islands
+PR|PRI|Puerto Rico
+PS|PSE|Palestine
+PT|PRT|Portugal
+PW|PLW|Palau
+PY|PRY|Paraguay
+QA|QAT|Qatar
+RE|REU|Reunion
+RO|ROU|Romania
+RS|SRB|Serbia
+RU|RUS|Russia
+RW|RWA|Rwanda
+SA|SAU|Saudi Arabia
+SB|SLB|Solomon Islands
+SC|SYC|Seychelles
+SD|SDN|Sudan
+SE|SWE|Sweden
+SG|SGP|Singapore
+SH|SHN|Saint Helena
+SI|SVN|Slovenia
+SJ|SJM|Svalbard and Jan Mayen
+SK|SVK|Slovakia
+SL|SLE|Sierra Leone
+SM|SMR|San Marino
+SN|SEN|Senegal
+SO|SOM|Somalia
+SR|SUR|Suriname
+SS|SSD|South Sudan
+ST|STP|Sao Tome and Principe
+SV|SLV|El Salvador
+SX|SXM|Sint Maarten
+SY|SYR|Syria
+SZ|SWZ|Eswatini
+TC|TCA|Turks and Caicos Islands
+TD|TCD|Chad
+TF|ATF|French Southern Territories
+TG|TGO|Togo
+TH|THA|Thailand
+TJ|TJK|Tajikistan
+TK|TKL|Tokelau
+TL|TLS|Timor-Leste
+TM|TKM|Turkmenistan
+TN|TUN|Tunisia
+TO|TON|Tonga
+TR|TUR|Turkey
+TT|TTO|Trinidad and Tobago
+TV|TUV|Tuvalu
+TW|TWN|Taiwan
+TZ|TZA|Tanzania
+UA|UKR|Ukraine
+UG|UGA|Uganda
+UM|UMI|United States Minor Outlying Islands
+US|USA|United States
+UY|URY|Uruguay
+UZ|UZB|Uzbekistan
+VA|VAT|Vatican City
+VC|VCT|Saint Vincent and the Grenadines
+VE|VEN|Venezuela
+VG|VGB|British Virgin Islands
+VI|VIR|U.S. Virgin Islands
+VN|VNM|Vietnam
+VU|VUT|Vanuatu
+WF|WLF|Wallis and Futuna
+WS|WSM|Samoa
+YE|YEM|Yemen
+YT|MYT|Mayotte
+ZA|ZAF|South Africa
+ZM|ZMB|Zambia
+ZW|ZWE|Zimbabwe`
+
+// countryAliases maps other names used by providers and registries to
+// alpha-2 codes.
+var countryAliases = map[string]string{
+	"uk":                                     "GB",
+	"great britain":                          "GB",
+	"united states of america":               "US",
+	"russian federation":                     "RU",
+	"korea":                                  "KR",
+	"korea, republic of":                     "KR",
+	"republic of korea":                      "KR",
+	"korea, democratic people's republic of": "KP",
+	"viet nam":                               "VN",
+	"iran, islamic republic of":              "IR",
+	"czech republic":                         "CZ",
+	"the netherlands":                        "NL",
+	"holland":                                "NL",
+	"turkiye":                                "TR",
+	"türkiye":                                "TR",
+	"taiwan, province of china":              "TW",
+	"hong kong sar":                          "HK",
+	"macau":                                  "MO",
+	"moldova, republic of":                   "MD",
+	"tanzania, united republic of":           "TZ",
+	"syrian arab republic":                   "SY",
+	"lao people's democratic republic":       "LA",
+	"congo, the democratic republic of the":  "CD",
+	"democratic republic of the congo":       "CD",
+	"congo":                                  "CG",
+	"cote d'ivoire":                          "CI",
+	"côte d'ivoire":                          "CI",
+	"bolivia, plurinational state of":        "BO",
+	"venezuela, bolivarian republic of":      "VE",
+	"palestine, state of":                    "PS",
+	"north macedonia, republic of":           "MK",
+	"macedonia":                              "MK",
+	"swaziland":                              "SZ",
+	"burma":                                  "MM",
+	"brunei darussalam":                      "BN",
+	"vatican":                                "VA",
+	"holy see":                               "VA",
+}
+
+var (
+	countryNames   = map[string]string{} // alpha-2 -> canonical name
+	countryLookups = map[string]string{} // lower-case alpha-2, alpha-3, name or alias -> alpha-2
+)
+
+func init() {
+	for _, line := range strings.Split(isoCountries, "\n") {
+		parts := strings.SplitN(line, "|", 3)
+		countryNames[parts[0]] = parts[2]
+		for _, key := range parts {
+			countryLookups[strings.ToLower(key)] = parts[0]
+		}
+	}
+	for alias, code := range countryAliases {
+		countryLookups[alias] = code
+	}
+}
+
+// NormalizeCountry maps an ISO alpha-2 or alpha-3 code or a country name, as
+// returned by any provider ("US", "USA", "United States of America"), to the
+// alpha-2 code and canonical name. ok is false for unknown values.
+func NormalizeCountry(s string) (code, name string, ok bool) {
+	code, ok = countryLookups[strings.ToLower(strings.TrimSpace(s))]
+	if !ok {
+		return "", "", false
+	}
+	return code, countryNames[code], true
+}
+
+// splitASN splits "AS15169 Google LLC", "as15169" or "15169" into the
+// canonical ASN and the AS name that followed it, if any.
+func splitASN(s string) (asn, name string, ok bool) {
+	asn, err := NormalizeASN(s)
+	if err != nil {
+		return "", "", false
+	}
+	if fields := strings.SplitN(strings.TrimSpace(s), " ", 2); len(fields) == 2 {
+		name = strings.TrimSpace(fields[1])
+	}
+	return asn, name, true
+}
+
+// normalize rewrites the country and ASN of a provider result to ISO codes,
+// canonical names and "AS<number> <name>", so results of different providers
+// merge and aggregate alike.
+func (g *GeoResult) normalize() {
+	if code, name, ok := NormalizeCountry(g.CountryCode); ok {
+		g.CountryCode, g.Country = code, name
+	} else if code, name, ok := NormalizeCountry(g.Country); ok {
+		g.CountryCode, g.Country = code, name
+	}
+	if asn, name, ok := splitASN(g.ASN); ok {
+		g.ASN = strings.TrimSpace(asn + " " + name)
+	}
+}
+
+// NormalizeRecord stores the country of item as an ISO alpha-2 code with its
+// canonical name, and its ASN as "AS<number>" with the AS name moved to
+// ASName. Values it does not recognize are left as they are.
+func NormalizeRecord(item *models.ScannerData) {
+	if code, name, ok := NormalizeCountry(item.CountryCode); ok {
+		item.CountryCode, item.CountryName = code, name
+	} else if code, name, ok := NormalizeCountry(item.CountryName); ok {
+		item.CountryCode, item.CountryName = code, name
+	}
+	if asn, name, ok := splitASN(item.ASN); ok {
+		item.ASN = asn
+		if item.ASName == "" {
+			item.ASName = name
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package extractor
+
+import (
+	"strings"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// DefaultOptOutURLs are the opt-out or removal pages of the scanners that
+// publish one, by scanner name or type.
+var DefaultOptOutURLs = map[string]string{
+	"censys": "https://support.censys.io/hc/en-us/articles/360043177092-Opt-Out-of-Data-Collection",
+	"rapid7": "https://opt-out.rapid7.com/",
+}
+
+// OptOutURL returns the opt-out page of the scanner of item, looked up by
+// scanner name then type in DefaultOptOutURLs merged with overrides (an
+// empty URL removes a default). The URL may use the placeholders of
+// PivotURLs. ok is false when the scanner has no opt-out page.
+func OptOutURL(item models.ScannerData, overrides map[string]string) (string, bool) {
+	urls := make(map[string]string, len(DefaultOptOutURLs)+len(overrides))
+	for k, v := range DefaultOptOutURLs {
+		urls[k] = v
+	}
+	for k, v := range overrides {
+		urls[strings.ToLower(strings.TrimSpace(k))] = v
+	}
+	for _, key := range []string{strings.ToLower(item.ScannerName), string(item.ScannerType)} {
+		if u := urls[key]; u != "" {
+			links := PivotURLs(item, []models.PivotLink{{Name: key, URL: u}})
+			if len(links) == 0 {
+				return "", false
+			}
+			return links[0].URL, true
+		}
+	}
+	return "", false
+}
+
+// OptOutURL returns the opt-out page of the scanner of item with the
+// configured opt_out_urls.
+func (e *Extractor) OptOutURL(item models.ScannerData) (string, bool) {
+	return OptOutURL(item, e.settings().OptOutURLs)
+}
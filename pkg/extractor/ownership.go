@@ -0,0 +1,77 @@
+package extractor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lia/liacheckscanner_go/pkg/events"
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// OwnershipChange is a record whose RDAP owner differs from the one found by
+// the previous lookup, a possible prefix transfer or hijack.
+type OwnershipChange struct {
+	IP       string `json:"ip"`
+	Previous string `json:"previous"`
+	Current  string `json:"current"`
+}
+
+// OwnerLabel formats an RDAP owner as "name (handle)".
+func OwnerLabel(name, handle string) string {
+	switch {
+	case name == "":
+		return handle
+	case handle == "":
+		return name
+	}
+	return name + " (" + handle + ")"
+}
+
+// sameOwner compares two RDAP names or handles. An empty value is a failed
+// lookup, not a new owner.
+func sameOwner(prev, cur string) bool {
+	return prev == "" || cur == "" || strings.EqualFold(strings.TrimSpace(prev), strings.TrimSpace(cur))
+}
+
+// checkOwnership compares the RDAP name and handle of a fresh lookup with
+// the previous cached entry and sets PreviousOwner when either changed.
+func (e *Extractor) checkOwnership(prev models.RDAPCacheEntry, data *models.ScannerData) {
+	if sameOwner(prev.RDAPName, data.RDAPName) && sameOwner(prev.RDAPHandle, data.RDAPHandle) {
+		data.PreviousOwner = ""
+		return
+	}
+	data.PreviousOwner = OwnerLabel(prev.RDAPName, prev.RDAPHandle)
+	msg := fmt.Sprintf("%s: %s -> %s", data.IPOrCIDR, data.PreviousOwner, OwnerLabel(data.RDAPName, data.RDAPHandle))
+	e.logger.Warning("Extractor", "Changement de proprietaire RDAP "+msg)
+	e.publish(events.Warning, "RDAP ownership changed "+msg, 0, 0)
+}
+
+// OwnershipChanged returns the records whose RDAP owner changed at their
+// last lookup, in dataset order.
+func OwnershipChanged(data []models.ScannerData) []models.ScannerData {
+	var out []models.ScannerData
+	for _, item := range data {
+		if item.PreviousOwner != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// OwnershipChanges lists the ownership changes of data, one per IP.
+func OwnershipChanges(data []models.ScannerData) []OwnershipChange {
+	seen := map[string]bool{}
+	var out []OwnershipChange
+	for _, item := range OwnershipChanged(data) {
+		if seen[item.IPOrCIDR] {
+			continue
+		}
+		seen[item.IPOrCIDR] = true
+		out = append(out, OwnershipChange{
+			IP:       item.IPOrCIDR,
+			Previous: item.PreviousOwner,
+			Current:  OwnerLabel(item.RDAPName, item.RDAPHandle),
+		})
+	}
+	return out
+}
@@ -0,0 +1,310 @@
+package extractor
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/netip"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// ipv4Pattern and ipv6Pattern match the IPv4 and IPv6 addresses and ranges
+// of the feed files. AuditIPv6 reports what ipv6Pattern gets wrong.
+const (
+	ipv4Pattern = `\b(?:[0-9]{1,3}\.){3}[0-9]{1,3}(?:/\d{1,2})?\b`
+	ipv6Pattern = `(?:[a-fA-F0-9]{0,4}:){2,7}[a-fA-F0-9]{0,4}(?:/\d{1,3})?`
+)
+
+// feedIPv4Regex and feedIPv6Regex are ipv4Pattern and ipv6Pattern, compiled
+// once for every parse.
+var (
+	feedIPv4Regex = regexp.MustCompile(ipv4Pattern)
+	feedIPv6Regex = regexp.MustCompile(ipv6Pattern)
+)
+
+// scanBufPool holds the line buffers of the feed file scanners, so parsing
+// thousands of files does not allocate one per file.
+var scanBufPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 64*1024)
+		return &b
+	},
+}
+
+// parsedFeeds is the result of one pass over the feed files: the unique IPs
+// in the order found and, for each IP, the scanners whose files list it.
+type parsedFeeds struct {
+	root   string
+	ips    []string
+	claims map[string][]ScannerInfo
+}
+
+// parseFilesForIPs parses all .nft files in the given directory for IPs,
+// including those inside the archives of the directory and the configured
+// archive sources (see ArchiveSources). The scanner of each IP is recorded
+// in the same pass, for mapIPsToScannersIn.
+func (e *Extractor) parseFilesForIPs(localPath string) ([]string, error) {
+	feeds, err := e.scanFeeds(localPath)
+	if err != nil {
+		return nil, err
+	}
+	e.feedsMu.Lock()
+	e.feeds = feeds
+	e.feedsMu.Unlock()
+	return feeds.ips, nil
+}
+
+// parsedFeedsFor returns the feeds last parsed under root, or parses them
+// when they were not.
+func (e *Extractor) parsedFeedsFor(root string) (*parsedFeeds, error) {
+	e.feedsMu.Lock()
+	feeds := e.feeds
+	e.feedsMu.Unlock()
+	if feeds != nil && feeds.root == root {
+		return feeds, nil
+	}
+	return e.scanFeeds(root)
+}
+
+// forgetFeeds drops the last parse, so the next mapping reads the files.
+func (e *Extractor) forgetFeeds() {
+	e.feedsMu.Lock()
+	e.feeds = nil
+	e.feedsMu.Unlock()
+}
+
+// scanFeeds walks root once, reading the .nft files, those inside the
+// archives under root and those inside the archive sources. The source file
+// of an archived list is "<archive>:<file>".
+func (e *Extractor) scanFeeds(localPath string) (*parsedFeeds, error) {
+	e.logger.Info("Extractor", "Parsing des fichiers pour extraire les IPs...")
+
+	if _, err := os.Stat(localPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("le repertoire %s n'existe pas", localPath)
+	}
+
+	e.logger.Info("Extractor", fmt.Sprintf("Parsing du repertoire: %s", localPath))
+
+	feeds := &parsedFeeds{root: localPath, claims: make(map[string][]ScannerInfo)}
+	add := func(fileIPs []string, ports map[string][]int, info ScannerInfo) {
+		for _, ip := range fileIPs {
+			if _, seen := feeds.claims[ip]; !seen {
+				feeds.ips = append(feeds.ips, ip)
+			}
+			claim := info
+			claim.Ports = models.MergePorts(nil, ports[ip])
+			feeds.claims[ip] = append(feeds.claims[ip], claim)
+		}
+	}
+
+	parseArchive := func(archive string) {
+		err := e.forEachArchiveNFT(archive, feedIPv4Regex, feedIPv6Regex, func(name string, fileIPs []string, ports map[string][]int) {
+			e.logger.Info("Extractor", fmt.Sprintf("%s:%s: %d IPs extraites", filepath.Base(archive), name, len(fileIPs)))
+			base := path.Base(name)
+			scannerName := strings.TrimSuffix(base, path.Ext(base))
+			add(fileIPs, ports, ScannerInfo{
+				Name:       scannerName,
+				Type:       e.getScannerType(scannerName),
+				SourceFile: filepath.Base(archive) + ":" + name,
+			})
+		})
+		if err != nil {
+			e.logger.Warning("Extractor", fmt.Sprintf("Erreur lors du parsing de l'archive %s: %v", archive, err))
+		}
+	}
+
+	err := filepath.WalkDir(localPath, func(path string, info fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() && (info.Name() == ".git" || strings.HasPrefix(info.Name(), ".")) {
+			return filepath.SkipDir
+		}
+
+		if !info.IsDir() && strings.HasSuffix(strings.ToLower(path), ".nft") {
+			e.logger.Info("Extractor", fmt.Sprintf("Traitement du fichier: %s", filepath.Base(path)))
+			fileIPs, ports, err := e.extractNFTFile(path, feedIPv4Regex, feedIPv6Regex)
+			if err != nil {
+				e.logger.Warning("Extractor", fmt.Sprintf("Erreur lors du parsing de %s: %v", path, err))
+				return nil
+			}
+			e.logger.Info("Extractor", fmt.Sprintf("%s: %d IPs extraites", filepath.Base(path), len(fileIPs)))
+			fileName := filepath.Base(path)
+			scannerName := strings.TrimSuffix(fileName, ".nft")
+			add(fileIPs, ports, ScannerInfo{
+				Name:       scannerName,
+				Type:       e.getScannerType(scannerName),
+				SourceFile: fileName,
+			})
+		}
+
+		if !info.IsDir() && IsArchive(path) {
+			parseArchive(path)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("walking directory %s: %w", localPath, err)
+	}
+	for _, archive := range e.archiveSources() {
+		parseArchive(archive)
+	}
+
+	e.logger.Info("Extractor", fmt.Sprintf("%d IPs uniques extraites au total", len(feeds.ips)))
+	return feeds, nil
+}
+
+// forEachArchiveNFT calls fn with the name, the IPs and the target ports of
+// each .nft file inside archive, read in memory.
+func (e *Extractor) forEachArchiveNFT(archive string, ipv4Regex, ipv6Regex *regexp.Regexp, fn func(name string, ips []string, ports map[string][]int)) error {
+	return walkArchive(archive, func(name string, r io.Reader) error {
+		fileIPs, ports, err := extractNFT(r, name, ipv4Regex, ipv6Regex)
+		if err != nil {
+			return err
+		}
+		fn(name, fileIPs, ports)
+		return nil
+	})
+}
+
+// extractIPsFromNFTFile extracts IPs from a single .nft file.
+func (e *Extractor) extractIPsFromNFTFile(filePath string, ipv4Regex, ipv6Regex *regexp.Regexp) ([]string, error) {
+	ips, _, err := e.extractNFTFile(filePath, ipv4Regex, ipv6Regex)
+	return ips, err
+}
+
+// extractNFTFile extracts IPs and their target ports from a single .nft
+// file (see extractNFT).
+func (e *Extractor) extractNFTFile(filePath string, ipv4Regex, ipv6Regex *regexp.Regexp) ([]string, map[string][]int, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening nft file %s: %w", filePath, err)
+	}
+	defer file.Close()
+	return extractNFT(file, filePath, ipv4Regex, ipv6Regex)
+}
+
+// extractIPsFromNFT extracts IPs from the .nft content of r, named name in
+// errors.
+func extractIPsFromNFT(r io.Reader, name string, ipv4Regex, ipv6Regex *regexp.Regexp) ([]string, error) {
+	ips, _, err := extractNFT(r, name, ipv4Regex, ipv6Regex)
+	return ips, err
+}
+
+// extractNFT extracts IPs from the .nft content of r, named name in errors,
+// and the target ports listed with them, as "203.0.113.5:22" or as the
+// "203.0.113.5 . 22" of nftables concatenated sets. Lines are matched in the
+// pooled scanner buffer; only the IPs found are copied out of it.
+func extractNFT(r io.Reader, name string, ipv4Regex, ipv6Regex *regexp.Regexp) ([]string, map[string][]int, error) {
+	var ips []string
+	var ports map[string][]int
+	buf := scanBufPool.Get().(*[]byte)
+	defer scanBufPool.Put(buf)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(*buf, bufio.MaxScanTokenSize)
+
+	addMatches := func(line []byte, re *regexp.Regexp) {
+		for _, m := range re.FindAllIndex(line, -1) {
+			ip := string(line[m[0]:m[1]])
+			ips = append(ips, ip)
+			if port, ok := targetPort(line[m[1]:]); ok {
+				if ports == nil {
+					ports = make(map[string][]int)
+				}
+				ports[ip] = append(ports[ip], port)
+			}
+		}
+	}
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		if ip, ok := plainIPv4(line); ok {
+			ips = append(ips, ip)
+			continue
+		}
+
+		// Lines without the separators of an address cannot match
+		if bytes.IndexByte(line, '.') >= 0 {
+			addMatches(line, ipv4Regex)
+		}
+		if bytes.Count(line, []byte{':'}) >= 2 {
+			addMatches(line, ipv6Regex)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return ips, ports, fmt.Errorf("scanning nft file %s: %w", name, err)
+	}
+	return ips, ports, nil
+}
+
+// targetPort returns the port rest, the text following an address, starts
+// with: ":22" or " . 22". A following address ("1.2.3.4 . 5.6.7.8") is not
+// a port.
+func targetPort(rest []byte) (int, bool) {
+	switch {
+	case len(rest) > 0 && rest[0] == ':':
+		rest = rest[1:]
+	default:
+		rest = bytes.TrimLeft(rest, " \t")
+		if len(rest) == 0 || rest[0] != '.' {
+			return 0, false
+		}
+		rest = bytes.TrimLeft(rest[1:], " \t")
+	}
+	n := 0
+	for n < len(rest) && n < 6 && rest[n] >= '0' && rest[n] <= '9' {
+		n++
+	}
+	if n == 0 || n > 5 || (n < len(rest) && rest[n] == '.') {
+		return 0, false
+	}
+	port, _ := strconv.Atoi(string(rest[:n]))
+	if port < 1 || port > 65535 {
+		return 0, false
+	}
+	return port, true
+}
+
+// plainIPv4 returns the IPv4 address or prefix line holds alone, with an
+// optional trailing comma, as most feed lines do. The regexes, which
+// allocate their matches, are left for the other lines.
+func plainIPv4(line []byte) (string, bool) {
+	token := bytes.TrimSuffix(line, []byte{','})
+	if len(token) == 0 || token[0] < '0' || token[0] > '9' || bytes.IndexByte(token, ':') >= 0 {
+		return "", false
+	}
+	s := string(token)
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		// ipv4Pattern takes at most two digits of prefix length
+		if len(s)-i-1 > 2 {
+			return "", false
+		}
+		if p, err := netip.ParsePrefix(s); err != nil || !p.Addr().Is4() {
+			return "", false
+		}
+		return s, true
+	}
+	if a, err := netip.ParseAddr(s); err != nil || !a.Is4() {
+		return "", false
+	}
+	return s, true
+}
@@ -0,0 +1,136 @@
+package extractor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// peeringDBNetURL is the PeeringDB network endpoint; the numeric ASN is appended.
+const peeringDBNetURL = "https://www.peeringdb.com/api/net?depth=2&asn="
+
+// PeeringDBInfo is the subset of a PeeringDB network record used to judge
+// who operates an ASN.
+type PeeringDBInfo struct {
+	Name         string
+	NetworkType  string // e.g. "NSP", "Content", "Enterprise", "Educational/Research"
+	TrafficLevel string // e.g. "1-5Gbps"
+	Website      string
+	Contacts     []string // public points of contact, "role: name <email>"
+}
+
+// LookupPeeringDB returns the PeeringDB record for asn, or nil if the network
+// is not registered there.
+func (e *Extractor) LookupPeeringDB(ctx context.Context, asn string) (*PeeringDBInfo, error) {
+	norm, err := NormalizeASN(asn)
+	if err != nil {
+		return nil, err
+	}
+	base := e.peeringDBURL
+	if base == "" {
+		base = peeringDBNetURL
+	}
+	if rl := e.limiter(); rl != nil {
+		if err := rl.WaitContext(ctx); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := e.httpGetWithRetry(ctx, base+strings.TrimPrefix(norm, "AS"))
+	if err != nil {
+		return nil, fmt.Errorf("PeeringDB request for %s: %w", norm, err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading PeeringDB response for %s: %w", norm, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("PeeringDB http %d for %s", resp.StatusCode, norm)
+	}
+
+	var r struct {
+		Data []struct {
+			Name        string   `json:"name"`
+			InfoType    string   `json:"info_type"`
+			InfoTypes   []string `json:"info_types"`
+			InfoTraffic string   `json:"info_traffic"`
+			Website     string   `json:"website"`
+			PocSet      []struct {
+				Role    string `json:"role"`
+				Name    string `json:"name"`
+				Email   string `json:"email"`
+				Visible string `json:"visible"`
+			} `json:"poc_set"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, fmt.Errorf("unmarshaling PeeringDB response for %s: %w", norm, err)
+	}
+	if len(r.Data) == 0 {
+		return nil, nil
+	}
+
+	rec := r.Data[0]
+	info := &PeeringDBInfo{
+		Name:         rec.Name,
+		NetworkType:  rec.InfoType,
+		TrafficLevel: rec.InfoTraffic,
+		Website:      rec.Website,
+	}
+	if info.NetworkType == "" {
+		info.NetworkType = strings.Join(rec.InfoTypes, ", ")
+	}
+	for _, poc := range rec.PocSet {
+		if poc.Visible != "" && poc.Visible != "Public" {
+			continue
+		}
+		contact := poc.Role + ": " + poc.Name
+		if poc.Email != "" {
+			contact += " <" + poc.Email + ">"
+		}
+		info.Contacts = append(info.Contacts, strings.TrimSpace(contact))
+	}
+	return info, nil
+}
+
+// EnrichPeeringDB fills the PeeringDB fields of every record that has an ASN.
+// Each distinct ASN is queried once. It returns the number of records updated;
+// lookup failures are logged and skipped. Cancelling ctx stops before the
+// next record.
+func (e *Extractor) EnrichPeeringDB(ctx context.Context, data []models.ScannerData) int {
+	infos := make(map[string]*PeeringDBInfo)
+	updated := 0
+	for i := range data {
+		if ctx.Err() != nil {
+			break
+		}
+		asn, err := NormalizeASN(data[i].ASN)
+		if err != nil {
+			continue
+		}
+		info, seen := infos[asn]
+		if !seen {
+			info, err = e.LookupPeeringDB(ctx, asn)
+			if err != nil {
+				e.logger.Warning("Extractor", fmt.Sprintf("PeeringDB lookup failed for %s: %v", asn, err))
+			}
+			infos[asn] = info
+		}
+		if info == nil {
+			continue
+		}
+		data[i].PeeringDBName = info.Name
+		data[i].NetworkType = info.NetworkType
+		data[i].TrafficLevel = info.TrafficLevel
+		data[i].PeeringDBContacts = strings.Join(info.Contacts, "; ")
+		data[i].SetProvenance(models.ProvenancePeeringDB, "peeringdb", time.Now())
+		updated++
+	}
+	e.logger.Info("Extractor", fmt.Sprintf("PeeringDB: %d enregistrements enrichis (%d ASN)", updated, len(infos)))
+	return updated
+}
@@ -0,0 +1,102 @@
+package extractor
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// DefaultPivotLinks are the external tools a record can be opened in.
+var DefaultPivotLinks = []models.PivotLink{
+	{Name: "Shodan", URL: "https://www.shodan.io/host/{ip}"},
+	{Name: "Censys", URL: "https://search.censys.io/hosts/{ip}"},
+	{Name: "VirusTotal", URL: "https://www.virustotal.com/gui/ip-address/{ip}"},
+	{Name: "AbuseIPDB", URL: "https://www.abuseipdb.com/check/{ip}"},
+	{Name: "bgp.tools", URL: "https://bgp.tools/prefix/{ip}"},
+	{Name: "bgp.tools AS", URL: "https://bgp.tools/as/{asn}"},
+	{Name: "RDAP", URL: "{registry_url}"},
+}
+
+// registryWebURLs maps registry names to the web UI of their database, to
+// which the IP is appended.
+var registryWebURLs = map[string]string{
+	"arin":    "https://search.arin.net/rdap/?query=",
+	"ripe":    "https://apps.db.ripe.net/db-web-ui/query?searchtext=",
+	"apnic":   "https://wq.apnic.net/static/search.html?query=",
+	"lacnic":  "https://query.milacnic.lacnic.net/search?id=",
+	"afrinic": "https://afrinic.net/whois?searchtext=",
+}
+
+// registryWebURL returns the web UI page of ip at registry, as recorded in
+// ScannerData.Registry (a name or a whois host such as whois.ripe.net), or
+// the rdap.org client when the registry is unknown.
+func registryWebURL(registry, ip string) string {
+	registry = strings.ToLower(registry)
+	for _, k := range []string{"lacnic", "afrinic", "arin", "ripe", "apnic"} {
+		if strings.Contains(registry, k) {
+			return registryWebURLs[k] + url.QueryEscape(ip)
+		}
+	}
+	return "https://client.rdap.org/?type=ip&object=" + url.QueryEscape(ip)
+}
+
+// PivotLinks returns the default links merged with links: a link replaces
+// the default of the same name, is appended otherwise, and an empty URL
+// removes the default.
+func PivotLinks(links []models.PivotLink) []models.PivotLink {
+	out := append([]models.PivotLink(nil), DefaultPivotLinks...)
+	for _, l := range links {
+		i := 0
+		for i < len(out) && !strings.EqualFold(out[i].Name, l.Name) {
+			i++
+		}
+		switch {
+		case i == len(out) && l.URL != "":
+			out = append(out, l)
+		case i < len(out) && l.URL != "":
+			out[i] = l
+		case i < len(out):
+			out = append(out[:i], out[i+1:]...)
+		}
+	}
+	return out
+}
+
+// PivotURLs expands links for item. {ip} is the address (the network address
+// of a range), {cidr} the IP or range as listed, {asn} the AS number without
+// the AS prefix and {registry_url} the registry web UI page of the IP. Links
+// using a placeholder the record has no value for are left out.
+func PivotURLs(item models.ScannerData, links []models.PivotLink) []models.PivotLink {
+	cidr := strings.TrimSpace(item.IPOrCIDR)
+	ip := cidr
+	if addr, _, err := net.ParseCIDR(cidr); err == nil {
+		ip = addr.String()
+	}
+	values := map[string]string{
+		"{ip}":   ip,
+		"{cidr}": cidr,
+		"{asn}":  normalizeASN(item.ASN),
+	}
+	var out []models.PivotLink
+	for _, l := range links {
+		u := strings.ReplaceAll(l.URL, "{registry_url}", registryWebURL(item.Registry, ip))
+		missing := false
+		for k, v := range values {
+			if strings.Contains(u, k) {
+				missing = missing || v == ""
+				u = strings.ReplaceAll(u, k, v)
+			}
+		}
+		if !missing && ip != "" {
+			out = append(out, models.PivotLink{Name: l.Name, URL: u})
+		}
+	}
+	return out
+}
+
+// PivotURLs expands the default and configured pivot links for item.
+func (e *Extractor) PivotURLs(item models.ScannerData) []models.PivotLink {
+	return PivotURLs(item, PivotLinks(e.settings().PivotLinks))
+}
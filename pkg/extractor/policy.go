@@ -0,0 +1,90 @@
+package extractor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// PolicyViolation is a blocked record the enforcement policy keeps out of
+// enforcement exports, and why.
+type PolicyViolation struct {
+	IP      string `json:"ip"`
+	Scanner string `json:"scanner"`
+	Reason  string `json:"reason"`
+}
+
+// enforcementPolicy is a models.EnforcementPolicy normalized for matching.
+type enforcementPolicy struct {
+	excludeASNs, excludeCountries map[string]bool
+	onlyASNs, onlyCountries       map[string]bool
+}
+
+func compileEnforcementPolicy(p models.EnforcementPolicy) enforcementPolicy {
+	upper := func(s string) string { return strings.ToUpper(strings.TrimSpace(s)) }
+	return enforcementPolicy{
+		excludeASNs:      normalizedSet(p.ExcludeASNs, normalizeASN),
+		excludeCountries: normalizedSet(p.ExcludeCountries, upper),
+		onlyASNs:         normalizedSet(p.OnlyASNs, normalizeASN),
+		onlyCountries:    normalizedSet(p.OnlyCountries, upper),
+	}
+}
+
+// hold returns why p keeps item out of enforcement exports, or "".
+// Exclusions are checked first, as they name the reason most precisely.
+func (p enforcementPolicy) hold(item models.ScannerData) string {
+	asn := ""
+	if f := strings.Fields(item.ASN); len(f) > 0 {
+		asn = normalizeASN(f[0]) // "AS64500 Example" names the ASN first
+	}
+	country := strings.ToUpper(strings.TrimSpace(item.CountryCode))
+	switch {
+	case asn != "" && p.excludeASNs[asn]:
+		return fmt.Sprintf("ASN AS%s excluded", asn)
+	case country != "" && p.excludeCountries[country]:
+		return fmt.Sprintf("country %s excluded", country)
+	case p.onlyASNs != nil && asn == "":
+		return "unknown ASN, not in only_asns"
+	case p.onlyASNs != nil && !p.onlyASNs[asn]:
+		return fmt.Sprintf("ASN AS%s not in only_asns", asn)
+	case p.onlyCountries != nil && country == "":
+		return "unknown country, not in only_countries"
+	case p.onlyCountries != nil && !p.onlyCountries[country]:
+		return fmt.Sprintf("country %s not in only_countries", country)
+	}
+	return ""
+}
+
+// ApplyEnforcementPolicy sets PolicyHold on the records of data the
+// configured enforcement policy keeps out of enforcement exports, clearing
+// it on the others, and returns the blocked records held back. The records
+// stay in the dataset either way.
+func (e *Extractor) ApplyEnforcementPolicy(data []models.ScannerData) []PolicyViolation {
+	policy := e.settings().EnforcementPolicy
+	p := compileEnforcementPolicy(policy)
+	for i := range data {
+		data[i].PolicyHold = ""
+		if !policy.Empty() {
+			data[i].PolicyHold = p.hold(data[i])
+		}
+	}
+	violations := PolicyViolations(data)
+	if len(violations) > 0 {
+		e.logger.Warning("Extractor", fmt.Sprintf("%d IPs bloquees retenues par la politique d'application (premiere: %s, %s)",
+			len(violations), violations[0].IP, violations[0].Reason))
+	}
+	return violations
+}
+
+// PolicyViolations returns the blocked records of data held back by the
+// enforcement policy (see ApplyEnforcementPolicy).
+func PolicyViolations(data []models.ScannerData) []PolicyViolation {
+	var out []PolicyViolation
+	for _, item := range data {
+		if item.State == models.StateBlocked && item.PolicyHold != "" {
+			out = append(out, PolicyViolation{IP: item.IPOrCIDR, Scanner: item.ScannerName, Reason: item.PolicyHold})
+		}
+	}
+	return out
+}
@@ -0,0 +1,287 @@
+package extractor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/events"
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// Prefix lengths single feed IPs are grouped under by PrewarmPrefixes.
+const (
+	prewarmGroupV4 = 24
+	prewarmGroupV6 = 48
+)
+
+// PrewarmResult counts the prefixes of a PrewarmRDAP call.
+type PrewarmResult struct {
+	Prefixes int // valid prefixes in the list
+	Cached   int // already pre-warmed and not expired
+	Fetched  int // looked up now
+	Failed   int // no registry answered
+}
+
+// prefixKey returns the canonical network of the prefix or IP s ("/32" or
+// "/128" for a bare IP), or "" when s is neither.
+func prefixKey(s string) string {
+	s = strings.TrimSpace(s)
+	if _, n, err := net.ParseCIDR(s); err == nil {
+		return n.String()
+	}
+	if ip := net.ParseIP(s); ip != nil {
+		if ip.To4() != nil {
+			return ip.String() + "/32"
+		}
+		return ip.String() + "/128"
+	}
+	return ""
+}
+
+// PrewarmPrefixes aggregates the feed entries ips into the prefixes worth
+// pre-warming: the ranges as listed, and the /24 (IPv4) or /48 (IPv6) of
+// the single IPs sharing one with at least one other IP. Prefixes inside
+// another one are left out. The result is sorted.
+func PrewarmPrefixes(ips []string) []string {
+	groups := map[string]int{}
+	var nets []*net.IPNet
+	for _, s := range ips {
+		s = strings.TrimSpace(s)
+		if _, n, err := net.ParseCIDR(s); err == nil {
+			nets = append(nets, n)
+			continue
+		}
+		ip := net.ParseIP(s)
+		if ip == nil {
+			continue
+		}
+		bits, group := 128, prewarmGroupV6
+		if ip.To4() != nil {
+			ip, bits, group = ip.To4(), 32, prewarmGroupV4
+		}
+		g := &net.IPNet{IP: ip.Mask(net.CIDRMask(group, bits)), Mask: net.CIDRMask(group, bits)}
+		groups[g.String()]++
+	}
+	for g, n := range groups {
+		if n >= 2 {
+			_, gn, _ := net.ParseCIDR(g)
+			nets = append(nets, gn)
+		}
+	}
+	// Broadest first, so a prefix only has to be checked against the kept ones
+	sort.Slice(nets, func(i, j int) bool {
+		oi, _ := nets[i].Mask.Size()
+		oj, _ := nets[j].Mask.Size()
+		if oi != oj {
+			return oi < oj
+		}
+		return bytes.Compare(nets[i].IP, nets[j].IP) < 0
+	})
+	var kept []*net.IPNet
+	seen := map[string]bool{}
+	for _, n := range nets {
+		if seen[n.String()] {
+			continue
+		}
+		inside := false
+		for _, k := range kept {
+			if k.Contains(n.IP) && len(k.IP) == len(n.IP) {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			kept = append(kept, n)
+			seen[n.String()] = true
+		}
+	}
+	out := make([]string, 0, len(kept))
+	for _, n := range kept {
+		out = append(out, n.String())
+	}
+	sort.Strings(out)
+	return out
+}
+
+// ReadPrefixList reads one prefix or IP per line from path; blank lines and
+// lines starting with # are skipped.
+func ReadPrefixList(path string) ([]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading prefix list: %w", err)
+	}
+	var out []string
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for line := 1; sc.Scan(); line++ {
+		s := strings.TrimSpace(sc.Text())
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		if prefixKey(s) == "" {
+			return nil, fmt.Errorf("%s:%d: %q is not a prefix or an IP", path, line, s)
+		}
+		out = append(out, s)
+	}
+	return out, sc.Err()
+}
+
+// prefixFor returns the pre-warmed entry of the narrowest prefix holding ip
+// (an IP or a range), provided the registry network answered for it holds
+// ip too.
+func (c *rdapCache) prefixFor(ip string) (models.RDAPCacheEntry, bool) {
+	if len(c.Prefixes) == 0 {
+		return models.RDAPCacheEntry{}, false
+	}
+	key := prefixKey(ip)
+	if key == "" {
+		return models.RDAPCacheEntry{}, false
+	}
+	_, n, _ := net.ParseCIDR(key)
+	ones, bits := n.Mask.Size()
+	for l := ones; l >= 0; l-- {
+		p := &net.IPNet{IP: n.IP.Mask(net.CIDRMask(l, bits)), Mask: net.CIDRMask(l, bits)}
+		entry, ok := c.Prefixes[p.String()]
+		if ok && inRegistryRange(n, entry) {
+			return entry, true
+		}
+	}
+	return models.RDAPCacheEntry{}, false
+}
+
+// inRegistryRange reports whether n lies between the start and end
+// addresses of entry; entries without them cover their whole prefix.
+func inRegistryRange(n *net.IPNet, entry models.RDAPCacheEntry) bool {
+	start, end := net.ParseIP(entry.StartAddress), net.ParseIP(entry.EndAddress)
+	if start == nil || end == nil {
+		return true
+	}
+	first := n.IP.To16()
+	last := make(net.IP, len(first))
+	mask := net.CIDRMask(n.Mask.Size())
+	if len(mask) == net.IPv4len {
+		mask = append(net.CIDRMask(96, 128)[:12:12], mask...)
+	}
+	for i := range first {
+		last[i] = first[i] | ^mask[i]
+	}
+	return bytes.Compare(first, start.To16()) >= 0 && bytes.Compare(last, end.To16()) <= 0
+}
+
+// applyPrefix copies the RDAP fields of the pre-warmed prefix holding ip to
+// data and reports whether there was one.
+func (c *rdapCache) applyPrefix(ip string, data *models.ScannerData) bool {
+	entry, ok := c.prefixFor(ip)
+	if !ok {
+		return false
+	}
+	data.RDAPName = entry.RDAPName
+	data.RDAPHandle = entry.RDAPHandle
+	data.RDAPCIDR = entry.RDAPCIDR
+	data.Registry = entry.Registry
+	data.StartAddress = entry.StartAddress
+	data.EndAddress = entry.EndAddress
+	data.IPVersion = entry.IPVersion
+	data.RDAPType = entry.RDAPType
+	data.ParentHandle = entry.ParentHandle
+	data.EventRegistration = entry.EventRegistration
+	data.EventLastChanged = entry.EventLastChanged
+	if data.Organization == "" {
+		data.Organization = entry.Organization
+	}
+	data.AbuseEmail = entry.AbuseEmail
+	data.TechEmail = entry.TechEmail
+	data.Provenance = mergeProvenance(data.Provenance, entry.Provenance)
+	return true
+}
+
+func (sc *safeRDAPCache) applyPrefix(ip string, data *models.ScannerData) bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.cache.applyPrefix(ip, data)
+}
+
+// PrewarmRDAP looks up each prefix of prefixes once in the RDAP registries
+// and keeps the answer in the RDAP cache, so the per-IP enrichment of the
+// IPs inside takes its RDAP data from there instead of asking the
+// registries again. Prefixes still in the cache are skipped. Lookups are
+// sequential, wait for the rate limiter and stop when the registries are
+// all cooling down after HTTP 429 or ctx is done.
+func (e *Extractor) PrewarmRDAP(ctx context.Context, prefixes []string) (PrewarmResult, error) {
+	var res PrewarmResult
+	keys := map[string]bool{}
+	var todo []string
+	for _, p := range prefixes {
+		if k := prefixKey(p); k != "" && !keys[k] {
+			keys[k] = true
+			todo = append(todo, k)
+		}
+	}
+	res.Prefixes = len(todo)
+	if len(todo) == 0 {
+		return res, nil
+	}
+	e.logger.Info("Extractor", fmt.Sprintf("Prechauffage RDAP de %d prefixes", len(todo)))
+
+	cache := e.loadRDAPCache()
+	defer cache.save()
+	for i, key := range todo {
+		if _, ok := cache.Prefixes[key]; ok {
+			res.Cached++
+			continue
+		}
+		if rl := e.limiter(); rl != nil {
+			if err := rl.WaitContext(ctx); err != nil {
+				return res, fmt.Errorf("pre-warm stopped after %d/%d prefixes: %w", i, len(todo), err)
+			}
+		}
+		var d models.ScannerData
+		if err := e.performRDAPFull(ctx, key, &d); err != nil {
+			if ctx.Err() != nil {
+				return res, fmt.Errorf("pre-warm stopped after %d/%d prefixes: %w", i, len(todo), ctx.Err())
+			}
+			res.Failed++
+			e.logger.Warning("Extractor", fmt.Sprintf("Prechauffage RDAP de %s: %v", key, err))
+			if errors.Is(err, errRegistriesCoolingDown) {
+				return res, fmt.Errorf("pre-warm stopped after %d/%d prefixes: %w", i, len(todo), err)
+			}
+			continue
+		}
+		NormalizeRecord(&d)
+		cache.Prefixes[key] = models.RDAPCacheEntry{
+			RDAPName:          d.RDAPName,
+			RDAPHandle:        d.RDAPHandle,
+			RDAPCIDR:          d.RDAPCIDR,
+			Registry:          d.Registry,
+			StartAddress:      d.StartAddress,
+			EndAddress:        d.EndAddress,
+			IPVersion:         d.IPVersion,
+			RDAPType:          d.RDAPType,
+			ParentHandle:      d.ParentHandle,
+			EventRegistration: d.EventRegistration,
+			EventLastChanged:  d.EventLastChanged,
+			Organization:      d.Organization,
+			AbuseEmail:        d.AbuseEmail,
+			TechEmail:         d.TechEmail,
+			Provenance:        mergeProvenance(nil, d.Provenance),
+			CachedAt:          time.Now().UTC(),
+		}
+		res.Fetched++
+		if res.Fetched%jobCheckpointEvery == 0 {
+			cache.save()
+		}
+		if n := i + 1; n%enrichProgressEvery == 0 || n == len(todo) {
+			e.publish(events.RecordsEnriched, key, n, len(todo))
+		}
+	}
+	e.logger.Info("Extractor", fmt.Sprintf("Prechauffage RDAP termine: %d interroges, %d deja en cache, %d en echec",
+		res.Fetched, res.Cached, res.Failed))
+	return res, nil
+}
@@ -0,0 +1,372 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RateLimiter throttles outgoing requests to a configurable rate.
+type RateLimiter struct {
+	interval time.Duration
+	last     time.Time
+	mu       sync.Mutex
+	// waiting counts the callers held back by the limiter.
+	waiting atomic.Int32
+}
+
+// NewRateLimiter creates a RateLimiter that allows at most requestsPerSecond
+// requests per second.  A value <= 0 disables throttling (Wait returns
+// immediately).
+func NewRateLimiter(requestsPerSecond float64) *RateLimiter {
+	var interval time.Duration
+	if requestsPerSecond > 0 {
+		interval = time.Duration(float64(time.Second) / requestsPerSecond)
+	}
+	return &RateLimiter{
+		interval: interval,
+	}
+}
+
+// Wait blocks until enough time has elapsed since the last call so that the
+// configured rate is respected.
+func (r *RateLimiter) Wait() {
+	_ = r.WaitContext(context.Background())
+}
+
+// WaitContext is Wait returning early with the context's error when ctx is
+// done first; the slot is then left to the next caller.
+func (r *RateLimiter) WaitContext(ctx context.Context) error {
+	if r.interval <= 0 {
+		return ctx.Err()
+	}
+	r.waiting.Add(1)
+	defer r.waiting.Add(-1)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if !r.last.IsZero() {
+		elapsed := now.Sub(r.last)
+		if elapsed < r.interval {
+			if err := sleepContext(ctx, r.interval-elapsed); err != nil {
+				return err
+			}
+		}
+	}
+	r.last = time.Now()
+	return nil
+}
+
+// PerMinute returns the requests per minute the limiter allows, 0 when it
+// does not throttle.
+func (r *RateLimiter) PerMinute() float64 {
+	if r.interval <= 0 {
+		return 0
+	}
+	return float64(time.Minute) / float64(r.interval)
+}
+
+// Waiting returns the number of callers currently held back by the limiter.
+func (r *RateLimiter) Waiting() int {
+	return int(r.waiting.Load())
+}
+
+// sleepContext pauses for d, or until ctx is done, whose error it returns.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// defaultRegistryConcurrency is used when config.RDAPRegistryConcurrency is 0.
+const defaultRegistryConcurrency = 4
+
+// registrySemaphore caps the number of requests in flight to each RDAP
+// registry, so a large worker pool does not hammer a single RIR.
+type registrySemaphore struct {
+	limit int
+	mu    sync.Mutex
+	slots map[string]chan struct{}
+}
+
+// newRegistrySemaphore allows limit concurrent requests per registry, or
+// defaultRegistryConcurrency when limit <= 0.
+func newRegistrySemaphore(limit int) *registrySemaphore {
+	if limit <= 0 {
+		limit = defaultRegistryConcurrency
+	}
+	return &registrySemaphore{limit: limit, slots: map[string]chan struct{}{}}
+}
+
+// acquire blocks until a request slot for registry is free and returns the
+// function that gives it back.
+func (s *registrySemaphore) acquire(registry string) (release func()) {
+	s.mu.Lock()
+	ch, ok := s.slots[registry]
+	if !ok {
+		ch = make(chan struct{}, s.limit)
+		s.slots[registry] = ch
+	}
+	s.mu.Unlock()
+	ch <- struct{}{}
+	return func() { <-ch }
+}
+
+// registryCooldownDefault is how long a registry rests after a 429 that
+// carries no usable Retry-After header.
+const registryCooldownDefault = time.Minute
+
+// registryCooldown records the registries that answered 429 and when each
+// may be queried again.
+type registryCooldown struct {
+	mu    sync.Mutex
+	until map[string]time.Time
+}
+
+func newRegistryCooldown() *registryCooldown {
+	return &registryCooldown{until: map[string]time.Time{}}
+}
+
+// coolDown rests registry for d, or registryCooldownDefault when d <= 0. A
+// longer cooldown already in place is kept.
+func (c *registryCooldown) coolDown(registry string, d time.Duration) time.Time {
+	if d <= 0 {
+		d = registryCooldownDefault
+	}
+	until := time.Now().Add(d)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if until.After(c.until[registry]) {
+		c.until[registry] = until
+	}
+	return c.until[registry]
+}
+
+// coolingDown reports whether registry is resting at now.
+func (c *registryCooldown) coolingDown(registry string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	until, ok := c.until[registry]
+	if ok && !now.Before(until) {
+		delete(c.until, registry)
+		return false
+	}
+	return ok
+}
+
+// nextResume returns the earliest time a resting registry becomes available
+// again, or the zero time when none is resting.
+func (c *registryCooldown) nextResume() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var next time.Time
+	for _, until := range c.until {
+		if next.IsZero() || until.Before(next) {
+			next = until
+		}
+	}
+	return next
+}
+
+// rateLimitedError is returned by httpGet for a 429 it does not retry.
+type rateLimitedError struct {
+	retryAfter time.Duration
+}
+
+func (e *rateLimitedError) Error() string {
+	if e.retryAfter > 0 {
+		return fmt.Sprintf("HTTP 429 Too Many Requests (retry after %s)", e.retryAfter)
+	}
+	return "HTTP 429 Too Many Requests"
+}
+
+const (
+	// retryMaxAttempts is used when config.MaxRetries is 0.
+	retryMaxAttempts = 3
+	retryBaseDelay   = 500 * time.Millisecond
+	retryMaxDelay    = 10 * time.Second
+)
+
+// httpGetWithRetry performs an HTTP GET with exponential backoff retry.
+// It retries on network errors, HTTP 429 (Too Many Requests), and HTTP 5xx.
+// On 429 responses, it respects the Retry-After header if present.
+func (e *Extractor) httpGetWithRetry(ctx context.Context, url string) (*http.Response, error) {
+	return e.httpGet(ctx, url, true)
+}
+
+// httpGet is httpGetWithRetry with control over 429 handling: when
+// retryRateLimited is false a 429 is returned at once as a *rateLimitedError,
+// so the caller can turn to another server instead of waiting. Each attempt
+// takes a slot of the provider's autoscaled limit and reports its status,
+// to the autoscaler and to the request metrics.
+// Cancelling ctx aborts the request in flight and the waits between
+// attempts.
+func (e *Extractor) httpGet(ctx context.Context, url string, retryRateLimited bool) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, redactError(err)
+	}
+	maxRetries := e.settings().MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = retryMaxAttempts
+	}
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		e.logger.Debug("Extractor", fmt.Sprintf("GET %s (tentative %d/%d)", redactURL(url), attempt+1, maxRetries+1))
+		host := requestHost(url)
+		release := func(int) {}
+		if a := e.scaler(); a != nil {
+			slot, err := a.acquire(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+			release = slot
+		}
+		answered := e.metrics.start(host)
+		resp, err := e.apiClient.Do(req)
+		if err != nil {
+			answered(0)
+			release(0)
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			// The error holds the request URL, credentials included
+			lastErr = redactError(err)
+			if attempt < maxRetries {
+				delay := retryDelay(attempt)
+				e.logger.Debug("Extractor", fmt.Sprintf("Erreur reseau %v, nouvelle tentative dans %s", lastErr, delay.Round(time.Millisecond)))
+				if err := sleepContext(ctx, delay); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		answered(resp.StatusCode)
+		release(resp.StatusCode)
+
+		// Success range or client error (except 429): return as-is.
+		if resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			return resp, nil
+		}
+
+		// 429: respect Retry-After header if present.
+		if resp.StatusCode == http.StatusTooManyRequests {
+			resp.Body.Close()
+			if !retryRateLimited {
+				return nil, &rateLimitedError{retryAfter: retryAfterDelay(resp)}
+			}
+			lastErr = fmt.Errorf("HTTP 429 Too Many Requests")
+			if attempt < maxRetries {
+				delay := retryAfterDelay(resp)
+				if delay <= 0 {
+					delay = retryDelay(attempt)
+				}
+				e.logger.Debug("Extractor", fmt.Sprintf("HTTP 429 pour %s, nouvelle tentative dans %s", redactURL(url), delay.Round(time.Millisecond)))
+				if err := sleepContext(ctx, delay); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		// 5xx: retry with backoff.
+		resp.Body.Close()
+		lastErr = fmt.Errorf("HTTP %d", resp.StatusCode)
+		if attempt < maxRetries {
+			delay := retryDelay(attempt)
+			e.logger.Debug("Extractor", fmt.Sprintf("HTTP %d pour %s, nouvelle tentative dans %s", resp.StatusCode, redactURL(url), delay.Round(time.Millisecond)))
+			if err := sleepContext(ctx, delay); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return nil, fmt.Errorf("after %d retries: %w", maxRetries, lastErr)
+}
+
+// redactURL hides credential query parameters (key, token, api-key) so
+// request URLs can be logged safely.
+func redactURL(raw string) string {
+	u, err := neturl.Parse(raw)
+	if err != nil || u.RawQuery == "" {
+		return raw
+	}
+	q := u.Query()
+	for name := range q {
+		switch strings.ToLower(name) {
+		case "key", "token", "api-key", "api_key", "apikey":
+			q.Set(name, "REDACTED")
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// redactedError is an error whose message had a request URL redacted.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// redactError hides the credentials of the request URL carried by the
+// *url.Error err wraps, if any (see redactURL), so err can be logged or
+// shown.
+func redactError(err error) error {
+	var ue *neturl.Error
+	if !errors.As(err, &ue) {
+		return err
+	}
+	safe := redactURL(ue.URL)
+	if safe == ue.URL {
+		return err
+	}
+	return &redactedError{msg: strings.ReplaceAll(err.Error(), ue.URL, safe), err: err}
+}
+
+// retryDelay returns the backoff delay for the given attempt:
+// min(baseDelay * 2^attempt, maxDelay) + random jitter (0-25%).
+func retryDelay(attempt int) time.Duration {
+	delay := float64(retryBaseDelay) * math.Pow(2, float64(attempt))
+	if delay > float64(retryMaxDelay) {
+		delay = float64(retryMaxDelay)
+	}
+	jitter := delay * 0.25 * rand.Float64()
+	return time.Duration(delay + jitter)
+}
+
+// retryAfterDelay parses the Retry-After header from a 429 response.
+// Returns the delay duration, or 0 if the header is absent or unparseable.
+func retryAfterDelay(resp *http.Response) time.Duration {
+	ra := resp.Header.Get("Retry-After")
+	if ra == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(ra); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(ra); err == nil {
+		d := time.Until(t)
+		if d > 0 {
+			return d
+		}
+	}
+	return 0
+}
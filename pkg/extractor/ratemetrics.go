@@ -0,0 +1,163 @@
+package extractor
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Request metrics are kept per provider (URL host): the requests answered
+// in the last rateWindow give its effective rate, the 429s of the last
+// rateLimitedWindow show whether it pushes back.
+const (
+	rateWindow        = time.Minute
+	rateLimitedWindow = 5 * time.Minute
+)
+
+// ProviderRate is the current request activity towards one provider.
+type ProviderRate struct {
+	// Host is the provider's URL host.
+	Host string
+	// PerMinute is the number of requests answered in the last minute.
+	PerMinute int
+	// InFlight is the number of requests waiting for an answer.
+	InFlight int
+	// Limit is the autoscaled cap of requests in flight, 0 when
+	// autoscaling is disabled.
+	Limit int
+	// Remaining is the number of requests that can start without waiting
+	// (Limit - InFlight), -1 when there is no cap.
+	Remaining int
+	// RateLimited is the number of HTTP 429 answers of the last 5 minutes.
+	RateLimited int
+	// RestingUntil is when an RDAP registry cooling down after a 429 is
+	// queried again, the zero time when it is not resting.
+	RestingUntil time.Time
+}
+
+// RateReport is the request activity of the Extractor: the api_throttle
+// limiter shared by the enrichment workers, and each provider.
+type RateReport struct {
+	// ThrottlePerMinute is the lookups per minute api_throttle allows, 0
+	// when it does not throttle.
+	ThrottlePerMinute float64
+	// ThrottleWaiting is the number of workers currently held back by
+	// api_throttle; while it stays above 0 the throttle is the bottleneck.
+	ThrottleWaiting int
+	// Providers are the providers contacted so far, ordered by host.
+	Providers []ProviderRate
+}
+
+// hostRequests is the request activity of one provider.
+type hostRequests struct {
+	inFlight int
+	answered []time.Time
+	limited  []time.Time
+}
+
+// requestMetrics records the requests sent to each provider.
+type requestMetrics struct {
+	mu    sync.Mutex
+	hosts map[string]*hostRequests
+}
+
+func newRequestMetrics() *requestMetrics {
+	return &requestMetrics{hosts: map[string]*hostRequests{}}
+}
+
+// start records a request to host and returns the function recording its
+// HTTP status, 0 for a network error.
+func (m *requestMetrics) start(host string) (end func(status int)) {
+	m.mu.Lock()
+	h, ok := m.hosts[host]
+	if !ok {
+		h = &hostRequests{}
+		m.hosts[host] = h
+	}
+	h.inFlight++
+	m.mu.Unlock()
+
+	return func(status int) {
+		now := time.Now()
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		h.inFlight--
+		h.answered = append(since(h.answered, now.Add(-rateWindow)), now)
+		h.limited = since(h.limited, now.Add(-rateLimitedWindow))
+		if status == http.StatusTooManyRequests {
+			h.limited = append(h.limited, now)
+		}
+	}
+}
+
+// since drops the times of ts, in ascending order, before cutoff.
+func since(ts []time.Time, cutoff time.Time) []time.Time {
+	i := sort.Search(len(ts), func(i int) bool { return !ts[i].Before(cutoff) })
+	return ts[i:]
+}
+
+// snapshot returns the activity of each provider at now, by host.
+func (m *requestMetrics) snapshot(now time.Time) map[string]ProviderRate {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[string]ProviderRate, len(m.hosts))
+	for host, h := range m.hosts {
+		out[host] = ProviderRate{
+			Host:        host,
+			PerMinute:   len(since(h.answered, now.Add(-rateWindow))),
+			InFlight:    h.inFlight,
+			RateLimited: len(since(h.limited, now.Add(-rateLimitedWindow))),
+			Remaining:   -1,
+		}
+	}
+	return out
+}
+
+// resting returns when each registry cooling down at now is queried again,
+// by host.
+func (c *registryCooldown) resting(now time.Time) map[string]time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := map[string]time.Time{}
+	for registry, until := range c.until {
+		if now.Before(until) {
+			out[requestHost(registry)] = until
+		}
+	}
+	return out
+}
+
+// RateMetrics returns the current request activity: the workers held back
+// by api_throttle and, for each provider contacted so far, its effective
+// rate, its requests in flight against its autoscaled cap, its recent 429s
+// and, for an RDAP registry, the end of its cooldown. It shows whether the
+// throttle settings or the providers are slowing a run down.
+func (e *Extractor) RateMetrics() RateReport {
+	now := time.Now()
+	rl := e.limiter()
+	report := RateReport{ThrottlePerMinute: rl.PerMinute(), ThrottleWaiting: rl.Waiting()}
+	rates := e.metrics.snapshot(now)
+	var limits map[string]int
+	if a := e.scaler(); a != nil {
+		limits = a.limits()
+	}
+	for host, until := range e.cooldowns.resting(now) {
+		r, ok := rates[host]
+		if !ok {
+			r = ProviderRate{Host: host, Remaining: -1}
+		}
+		r.RestingUntil = until
+		rates[host] = r
+	}
+	report.Providers = make([]ProviderRate, 0, len(rates))
+	for host, r := range rates {
+		if limit, ok := limits[host]; ok {
+			r.Limit = limit
+			r.Remaining = max(0, limit-r.InFlight)
+		}
+		report.Providers = append(report.Providers, r)
+	}
+	sort.Slice(report.Providers, func(i, j int) bool { return report.Providers[i].Host < report.Providers[j].Host })
+	return report
+}
@@ -0,0 +1,985 @@
+package extractor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/events"
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// cacheAccessor abstracts cache read/write so the same enrichment logic
+// works for both single-threaded (rdapCache) and concurrent (safeRDAPCache) use.
+type cacheAccessor interface {
+	applyCache(ip string, data *models.ScannerData) bool
+	updateCache(ip string, data *models.ScannerData)
+	previous(ip string) (models.RDAPCacheEntry, bool)
+	applyPrefix(ip string, data *models.ScannerData) bool
+}
+
+// rdapCache manages simple on-disk cache for RDAP query results.
+type rdapCache struct {
+	Entries map[string]models.RDAPCacheEntry `json:"entries"`
+	Path    string                           `json:"-"`
+	// Prefixes holds the RDAP answers of the pre-warmed prefixes, by
+	// canonical network (see PrewarmRDAP)
+	Prefixes map[string]models.RDAPCacheEntry `json:"prefixes,omitempty"`
+
+	// expired holds the entries evicted on load, so a new lookup can be
+	// compared with the previous one
+	expired map[string]models.RDAPCacheEntry
+	// updated lists the IPs looked up since load, for the shared cache
+	updated map[string]bool
+	// loadedAt is when the file was read: entries cached after it were
+	// saved by another instance (see mergeSaved)
+	loadedAt time.Time
+}
+
+// previous returns the evicted entry of ip, if any.
+func (c *rdapCache) previous(ip string) (models.RDAPCacheEntry, bool) {
+	entry, ok := c.expired[ip]
+	return entry, ok
+}
+
+func (c *rdapCache) applyCache(ip string, data *models.ScannerData) bool {
+	entry, ok := c.Entries[ip]
+	if !ok {
+		return false
+	}
+	data.RDAPName = entry.RDAPName
+	data.RDAPHandle = entry.RDAPHandle
+	data.RDAPCIDR = entry.RDAPCIDR
+	data.Registry = entry.Registry
+	data.StartAddress = entry.StartAddress
+	data.EndAddress = entry.EndAddress
+	data.IPVersion = entry.IPVersion
+	data.RDAPType = entry.RDAPType
+	data.ParentHandle = entry.ParentHandle
+	data.EventRegistration = entry.EventRegistration
+	data.EventLastChanged = entry.EventLastChanged
+	data.ASN = entry.ASN
+	data.ASName = entry.ASName
+	data.ReverseDNS = entry.ReverseDNS
+	data.CountryCode = entry.CountryCode
+	data.CountryName = entry.CountryName
+	data.City = entry.City
+	data.Latitude, data.Longitude = entry.Latitude, entry.Longitude
+	data.Continent, data.ContinentCode, data.Region = entry.Continent, entry.ContinentCode, entry.Region
+	data.ISP = entry.ISP
+	data.Organization = entry.Organization
+	data.AbuseEmail = entry.AbuseEmail
+	data.TechEmail = entry.TechEmail
+	data.GeoSources = entry.GeoSources
+	data.PreviousOwner = entry.PreviousOwner
+	data.Provenance = mergeProvenance(data.Provenance, entry.Provenance)
+	return true
+}
+
+func (c *rdapCache) updateCache(ip string, data *models.ScannerData) {
+	c.Entries[ip] = models.RDAPCacheEntry{
+		RDAPName:          data.RDAPName,
+		RDAPHandle:        data.RDAPHandle,
+		RDAPCIDR:          data.RDAPCIDR,
+		Registry:          data.Registry,
+		StartAddress:      data.StartAddress,
+		EndAddress:        data.EndAddress,
+		IPVersion:         data.IPVersion,
+		RDAPType:          data.RDAPType,
+		ParentHandle:      data.ParentHandle,
+		EventRegistration: data.EventRegistration,
+		EventLastChanged:  data.EventLastChanged,
+		ASN:               data.ASN,
+		ASName:            data.ASName,
+		ReverseDNS:        data.ReverseDNS,
+		CountryCode:       data.CountryCode,
+		CountryName:       data.CountryName,
+		City:              data.City,
+		ISP:               data.ISP,
+		Organization:      data.Organization,
+		AbuseEmail:        data.AbuseEmail,
+		TechEmail:         data.TechEmail,
+		GeoSources:        data.GeoSources,
+		PreviousOwner:     data.PreviousOwner,
+		Provenance:        mergeProvenance(nil, data.Provenance),
+		CachedAt:          time.Now().UTC(),
+		Latitude:          data.Latitude,
+		Longitude:         data.Longitude,
+		Continent:         data.Continent,
+		ContinentCode:     data.ContinentCode,
+		Region:            data.Region,
+	}
+	if c.updated == nil {
+		c.updated = map[string]bool{}
+	}
+	c.updated[ip] = true
+}
+
+// mergeProvenance returns a copy of dst overlaid with src, so records and
+// cache entries never share a map.
+func mergeProvenance(dst, src map[string]string) map[string]string {
+	if len(dst)+len(src) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(dst)+len(src))
+	for k, v := range dst {
+		out[k] = v
+	}
+	for k, v := range src {
+		out[k] = v
+	}
+	return out
+}
+
+// safeRDAPCache wraps rdapCache with a mutex for concurrent access.
+type safeRDAPCache struct {
+	mu    sync.Mutex
+	cache *rdapCache
+}
+
+func newSafeRDAPCache(c *rdapCache) *safeRDAPCache {
+	return &safeRDAPCache{cache: c}
+}
+
+func (sc *safeRDAPCache) applyCache(ip string, data *models.ScannerData) bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.cache.applyCache(ip, data)
+}
+
+func (sc *safeRDAPCache) updateCache(ip string, data *models.ScannerData) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.cache.updateCache(ip, data)
+}
+
+func (sc *safeRDAPCache) previous(ip string) (models.RDAPCacheEntry, bool) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.cache.previous(ip)
+}
+
+func (sc *safeRDAPCache) save() error {
+	return sc.cache.save()
+}
+
+// checkpoint saves the cache while workers may still update it.
+func (sc *safeRDAPCache) checkpoint() error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.cache.save()
+}
+
+// cacheTTL returns the configured cache TTL as a time.Duration.
+// If CacheTTLHours is 0 or negative, it defaults to 168 hours (7 days).
+func (e *Extractor) cacheTTL() time.Duration {
+	ttl := e.settings().CacheTTLHours
+	if ttl <= 0 {
+		ttl = 168 // default: 7 days
+	}
+	return time.Duration(ttl) * time.Hour
+}
+
+// rdapCachePath is the location of the RDAP cache.
+var rdapCachePath = filepath.Join("build", "data", "rdap_cache.json")
+
+func (e *Extractor) loadRDAPCache() *rdapCache {
+	cachePath := rdapCachePath
+	_ = os.MkdirAll(filepath.Dir(cachePath), 0755)
+	c := &rdapCache{Entries: map[string]models.RDAPCacheEntry{}, Prefixes: map[string]models.RDAPCacheEntry{},
+		Path: cachePath, expired: map[string]models.RDAPCacheEntry{}, loadedAt: time.Now()}
+	f, err := os.Open(cachePath)
+	if err != nil {
+		return c
+	}
+	// Decoded into a value: "null" would otherwise set c to nil
+	var saved rdapCache
+	err = json.NewDecoder(f).Decode(&saved)
+	f.Close()
+	if err == nil && saved.Entries == nil {
+		err = errors.New("no entries map")
+	}
+	if err != nil {
+		// Keep the unreadable file for inspection and start from an empty
+		// cache rather than failing every lookup
+		backup := fmt.Sprintf("%s.corrupt-%s", cachePath, time.Now().Format("2006-01-02_15-04-05"))
+		if rerr := os.Rename(cachePath, backup); rerr != nil {
+			backup = "(not backed up: " + rerr.Error() + ")"
+		}
+		e.logger.Warning("Extractor", fmt.Sprintf("Cache RDAP illisible, reinitialise: %v; ancien fichier: %s", err, backup))
+		return c
+	}
+	c.Entries = saved.Entries
+	if saved.Prefixes != nil {
+		c.Prefixes = saved.Prefixes
+	}
+
+	// Evict entries older than the configured TTL.
+	ttl := e.cacheTTL()
+	now := time.Now()
+	evicted := 0
+	for ip, entry := range c.Entries {
+		if !entry.CachedAt.IsZero() && now.Sub(entry.CachedAt) > ttl {
+			c.expired[ip] = entry
+			delete(c.Entries, ip)
+			evicted++
+		}
+	}
+	if c.Prefixes == nil {
+		c.Prefixes = map[string]models.RDAPCacheEntry{}
+	}
+	for key, entry := range c.Prefixes {
+		if !entry.CachedAt.IsZero() && now.Sub(entry.CachedAt) > ttl {
+			delete(c.Prefixes, key)
+		}
+	}
+	e.logger.Debug("Extractor", fmt.Sprintf("Cache RDAP charge: %d entrees, %d expirees (TTL %s)", len(c.Entries), evicted, ttl))
+
+	return c
+}
+
+// CleanExpiredCache removes all cache entries older than the configured TTL
+// and persists the cleaned cache to disk.
+func (e *Extractor) CleanExpiredCache() {
+	cache := e.loadRDAPCache() // loadRDAPCache already evicts expired entries
+	if err := cache.save(); err != nil {
+		e.logger.Warning("Extractor", fmt.Sprintf("Sauvegarde du cache RDAP: %v", err))
+		return
+	}
+	e.logger.Info("Extractor", fmt.Sprintf("Cache cleaned: %d entries remaining", len(cache.Entries)))
+}
+
+// save writes the cache to its file. The file is replaced only once the
+// whole cache is written, so a crash mid-write leaves the previous one.
+// Other instances of the application save the same file: under its lock,
+// the entries they saved since the cache was loaded are merged in first
+// rather than overwritten.
+func (c *rdapCache) save() error {
+	unlock, err := lockDataFile(c.Path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	c.mergeSaved()
+	return writeJSONAtomic(c.Path, c)
+}
+
+// mergeSaved adds the entries of the cache file cached after c was loaded
+// and newer than those of c. An unreadable file adds nothing.
+func (c *rdapCache) mergeSaved() {
+	f, err := os.Open(c.Path)
+	if err != nil {
+		return
+	}
+	var saved rdapCache
+	err = json.NewDecoder(f).Decode(&saved)
+	f.Close()
+	if err != nil {
+		return
+	}
+	if c.Entries == nil {
+		c.Entries = map[string]models.RDAPCacheEntry{}
+	}
+	if c.Prefixes == nil {
+		c.Prefixes = map[string]models.RDAPCacheEntry{}
+	}
+	mergeNewer(c.Entries, saved.Entries, c.loadedAt)
+	mergeNewer(c.Prefixes, saved.Prefixes, c.loadedAt)
+}
+
+// mergeNewer adds to dst the entries of src cached after since and newer
+// than those of dst.
+func mergeNewer(dst, src map[string]models.RDAPCacheEntry, since time.Time) {
+	for key, entry := range src {
+		if !entry.CachedAt.After(since) {
+			continue
+		}
+		if cur, ok := dst[key]; ok && !entry.CachedAt.After(cur.CachedAt) {
+			continue
+		}
+		dst[key] = entry
+	}
+}
+
+// writeJSONAtomic writes v as indented JSON to a temporary file next to
+// path, then renames it over path.
+func writeJSONAtomic(path string, v interface{}) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	// CreateTemp makes the file private; keep the mode os.Create gave
+	err = f.Chmod(0644)
+	if err == nil {
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(v)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return nil
+}
+
+// enrichProgressEvery is how many enriched records separate two RecordsEnriched events.
+const enrichProgressEvery = 25
+
+const (
+	// enrichCooldownRounds is how many passes enrichData makes over records
+	// whose registries were all resting after a 429.
+	enrichCooldownRounds = 3
+	// enrichCooldownMaxWait caps the pause between two such passes.
+	enrichCooldownMaxWait = 2 * time.Minute
+)
+
+// enrichJob represents a single IP enrichment task for the worker pool.
+type enrichJob struct {
+	index       int
+	ip          string
+	scannerInfo ScannerInfo
+}
+
+// enrichData enriches IP data with scanner metadata and API lookups.
+// When config.Parallelism > 1, it uses a worker pool for concurrent enrichment.
+// Cancelling ctx stops the run: the requests in flight are aborted, the
+// cache is saved and the job state is kept, so the run can be resumed.
+func (e *Extractor) enrichData(ctx context.Context, ips []string) ([]models.ScannerData, error) {
+	e.logger.Info("Extractor", "Enrichissement des donnees...")
+	defer e.beginEnrichment()()
+
+	ipToScanner := e.mapIPsToScanners(ips)
+
+	// Load cache once for the entire enrichment batch.
+	cache := e.loadRDAPCache()
+	e.pullSharedCache(ctx, cache, ips)
+	safeCache := newSafeRDAPCache(cache)
+
+	workers := e.settings().Parallelism
+	if workers <= 0 {
+		workers = 1
+	}
+	budget := newRunBudget(e.settings(), time.Now())
+	e.budget.Store(budget)
+	defer e.budget.Store(nil)
+
+	// The built-in enricher shares one cache across the whole batch;
+	// a custom enricher is called per record and manages its own state.
+	enrich := func(data *models.ScannerData) error {
+		return e.enrichUsingCache(ctx, data, safeCache)
+	}
+	if e.enricher != Enricher(e) {
+		enrich = func(data *models.ScannerData) error {
+			return e.enricher.Enrich(ctx, data)
+		}
+	}
+
+	// The job state is kept until the run ends, so an interrupted run can
+	// be resumed (see JobState)
+	job := &JobState{Kind: JobEnrich, StartedAt: time.Now().UTC(), IPs: ips}
+	if err := e.SaveJobState(job); err != nil {
+		e.logger.Warning("Extractor", err.Error())
+	}
+	var checkpointMu sync.Mutex
+
+	var done int64
+	total := len(ips)
+	recordDone := func(ip string, err error) {
+		if err != nil {
+			msg := fmt.Sprintf("Erreur lors de l'enrichissement de %s: %v", ip, err)
+			e.logger.Warning("Extractor", msg)
+			e.publish(events.Warning, msg, 0, 0)
+		}
+		n := int(atomic.AddInt64(&done, 1))
+		if n%enrichProgressEvery == 0 || n == total {
+			e.publish(events.RecordsEnriched, ip, n, total)
+		}
+		if n%jobCheckpointEvery == 0 {
+			checkpointMu.Lock()
+			if err := safeCache.checkpoint(); err != nil {
+				e.logger.Warning("Extractor", fmt.Sprintf("Sauvegarde du cache RDAP: %v", err))
+			}
+			job.Done = n
+			if err := e.SaveJobState(job); err != nil {
+				e.logger.Warning("Extractor", err.Error())
+			}
+			checkpointMu.Unlock()
+		}
+	}
+
+	now := time.Now()
+	scannerData := make([]models.ScannerData, len(ips))
+	pending := make([]int, len(ips))
+	for i, ip := range ips {
+		scannerData[i] = e.buildRecord(i, ip, ipToScanner[ip], now)
+		pending[i] = i
+	}
+
+	// unspent holds the records left unenriched once the budget ran out.
+	var unspent []int
+	var unspentMu sync.Mutex
+
+	// runPass enriches the records at indices and returns those put back in
+	// the queue because every registry was cooling down after a 429.
+	runPass := func(indices []int, requeue bool) []int {
+		var mu sync.Mutex
+		var deferred []int
+		handle := func(i int) {
+			if ctx.Err() != nil {
+				return
+			}
+			err := e.enrichSafely(enrich, &scannerData[i])
+			if ctx.Err() != nil {
+				// Left for the resumed run
+				return
+			}
+			if errors.Is(err, errBudgetExhausted) {
+				unspentMu.Lock()
+				unspent = append(unspent, i)
+				unspentMu.Unlock()
+				recordDone(ips[i], nil)
+				return
+			}
+			if requeue && errors.Is(err, errRegistriesCoolingDown) {
+				mu.Lock()
+				deferred = append(deferred, i)
+				mu.Unlock()
+				return
+			}
+			recordDone(ips[i], err)
+		}
+
+		if workers == 1 {
+			// Sequential path (backward compatible).
+			for _, i := range indices {
+				handle(i)
+			}
+			return deferred
+		}
+
+		// Parallel path with worker pool.
+		jobs := make(chan enrichJob, len(indices))
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for job := range jobs {
+					handle(job.index)
+				}
+			}()
+		}
+		for _, i := range indices {
+			jobs <- enrichJob{index: i, ip: ips[i], scannerInfo: ipToScanner[ips[i]]}
+		}
+		close(jobs)
+		wg.Wait()
+		sort.Ints(deferred)
+		return deferred
+	}
+
+	e.logger.Info("Extractor", fmt.Sprintf("Enrichissement avec %d worker(s) pour %d IPs", workers, len(ips)))
+
+	for round := 1; len(pending) > 0; round++ {
+		pending = runPass(pending, round < enrichCooldownRounds)
+		if len(pending) == 0 || ctx.Err() != nil {
+			break
+		}
+		if budget.exhausted(time.Now()) != "" {
+			unspent = append(unspent, pending...)
+			break
+		}
+		wait := time.Until(e.cooldowns.nextResume())
+		if wait > enrichCooldownMaxWait {
+			wait = enrichCooldownMaxWait
+		}
+		if budget != nil && !budget.deadline.IsZero() && wait > time.Until(budget.deadline) {
+			wait = time.Until(budget.deadline)
+		}
+		e.logger.Info("Extractor", fmt.Sprintf("%d IPs en attente, registres RDAP en pause: reprise dans %s",
+			len(pending), wait.Round(time.Second)))
+		if wait > 0 {
+			if err := sleepContext(ctx, wait); err != nil {
+				break
+			}
+		}
+	}
+
+	if ctx.Err() != nil {
+		n := int(atomic.LoadInt64(&done))
+		if err := safeCache.save(); err != nil {
+			e.logger.Warning("Extractor", fmt.Sprintf("Sauvegarde du cache RDAP: %v", err))
+		}
+		job.Done = n
+		if err := e.SaveJobState(job); err != nil {
+			e.logger.Warning("Extractor", err.Error())
+		}
+		e.logger.Warning("Extractor", fmt.Sprintf("Enrichissement annule apres %d/%d IPs", n, total))
+		return nil, fmt.Errorf("enrichment canceled after %d of %d records: %w", n, total, ctx.Err())
+	}
+
+	// Persist cache once after processing all IPs.
+	if err := safeCache.save(); err != nil {
+		e.logger.Warning("Extractor", fmt.Sprintf("Sauvegarde du cache RDAP: %v", err))
+	}
+	e.pushSharedCache(ctx, cache)
+	e.recordBudgetStop(budget, ips, unspent)
+	if err := e.ClearJobState(JobEnrich); err != nil {
+		e.logger.Warning("Extractor", err.Error())
+	}
+
+	e.logger.Info("Extractor", fmt.Sprintf("%d enregistrements enrichis", len(scannerData)))
+	return scannerData, nil
+}
+
+// buildRecord creates a base ScannerData record for the given IP.
+func (e *Extractor) buildRecord(i int, ip string, info ScannerInfo, now time.Time) models.ScannerData {
+	return models.ScannerData{
+		ID:                fmt.Sprintf("scanner_%d", i+1),
+		IPOrCIDR:          ip,
+		ScannerName:       info.Name,
+		ScannerType:       info.Type,
+		SourceFile:        info.SourceFile,
+		LastSeen:          now,
+		FirstSeen:         now,
+		ExportDate:        now,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+		Tags:              []string{"extracted", info.Name},
+		RiskLevel:         "unknown",
+		FeedTrust:         info.Trust,
+		AttributionReason: info.Reason,
+		TargetPorts:       info.Ports,
+	}
+}
+
+// enrichUsingCache enriches a single ScannerData record via RDAP + geo APIs,
+// using the provided cacheAccessor (either rdapCache or safeRDAPCache).
+// A lookup interrupted by ctx returns its error and caches nothing.
+func (e *Extractor) enrichUsingCache(ctx context.Context, data *models.ScannerData, ca cacheAccessor) error {
+	if rl := e.limiter(); rl != nil {
+		if err := rl.WaitContext(ctx); err != nil {
+			return err
+		}
+	}
+	// Picked up by the delta sync of instances pulling from this one
+	data.UpdatedAt = time.Now()
+
+	if ca.applyCache(data.IPOrCIDR, data) {
+		// Entries cached by older versions may hold provider-specific values
+		NormalizeRecord(data)
+		applyTagRules(data, e.rules())
+		e.logger.Debug("Extractor", "Cache RDAP: "+data.IPOrCIDR+" trouve, pas de requete")
+		return nil
+	}
+	budget := e.budget.Load()
+	if err := budget.check(); err != nil {
+		return err
+	}
+	e.logger.Debug("Extractor", "Cache RDAP: "+data.IPOrCIDR+" absent, interrogation des registres")
+
+	if ca.applyPrefix(data.IPOrCIDR, data) {
+		e.logger.Debug("Extractor", "Cache RDAP: "+data.IPOrCIDR+" couvert par un prefixe prechauffe")
+	} else if err := e.performRDAPFull(ctx, data.IPOrCIDR, data); err != nil {
+		if errors.Is(err, errRegistriesCoolingDown) || ctx.Err() != nil {
+			// Left for a later pass rather than cached without RDAP data
+			return err
+		}
+		e.logger.Warning("Extractor", fmt.Sprintf("RDAP lookup failed for %s: %v", data.IPOrCIDR, err))
+	}
+
+	budget.spendGeo()
+	if g, err := e.geoProvider().Lookup(ctx, data.IPOrCIDR); err == nil {
+		if g.CountryCode != "" {
+			data.CountryCode = g.CountryCode
+			data.CountryName = g.Country
+		}
+		if g.City != "" {
+			data.City = g.City
+		}
+		if g.Latitude != 0 || g.Longitude != 0 {
+			data.Latitude, data.Longitude = g.Latitude, g.Longitude
+		}
+		if g.ContinentCode != "" {
+			data.Continent, data.ContinentCode = g.Continent, g.ContinentCode
+		}
+		if g.Region != "" {
+			data.Region = g.Region
+		}
+		if g.ISP != "" {
+			data.ISP = g.ISP
+		}
+		if g.ASN != "" {
+			data.ASN = g.ASN
+			if parts := strings.SplitN(g.ASN, " ", 2); len(parts) == 2 {
+				data.ASName = parts[1]
+			}
+		}
+		if g.ReverseDNS != "" {
+			data.ReverseDNS = g.ReverseDNS
+			if data.Domain == "" {
+				data.Domain = g.ReverseDNS
+			}
+		}
+		data.GeoSources = g.Sources
+		data.SetProvenance(models.ProvenanceGeo, geoSourceLabel(g, e.geoProvider().Name()), time.Now())
+	}
+
+	if data.Domain == "" {
+		if hostnames, err := e.lookupAddr(ctx, data.IPOrCIDR); err == nil && len(hostnames) > 0 {
+			data.Domain = strings.TrimSuffix(hostnames[0], ".")
+			if data.ReverseDNS == "" {
+				data.ReverseDNS = data.Domain
+			}
+			data.SetProvenance(models.ProvenanceDNS, "resolver", time.Now())
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	NormalizeRecord(data)
+	if prev, ok := ca.previous(data.IPOrCIDR); ok {
+		e.checkOwnership(prev, data)
+	}
+	ca.updateCache(data.IPOrCIDR, data)
+	applyTagRules(data, e.rules())
+	return nil
+}
+
+// enrichWithAPI enriches data with RDAP and public geolocation APIs.
+// It loads and persists the cache per call (use enrichUsingCache for batch operations).
+func (e *Extractor) enrichWithAPI(ctx context.Context, data *models.ScannerData) error {
+	cache := e.loadRDAPCache()
+	e.pullSharedCache(ctx, cache, []string{data.IPOrCIDR})
+	err := e.enrichUsingCache(ctx, data, cache)
+	cache.save()
+	e.pushSharedCache(ctx, cache)
+	return err
+}
+
+// rdapRegistryURLs maps registry names to their RDAP IP lookup base URLs.
+var rdapRegistryURLs = map[string]string{
+	"arin":    "https://rdap.arin.net/registry/ip/",
+	"ripe":    "https://rdap.ripe.net/ip/",
+	"apnic":   "https://rdap.apnic.net/ip/",
+	"lacnic":  "https://rdap.lacnic.net/rdap/ip/",
+	"afrinic": "https://rdap.afrinic.net/rdap/ip/",
+}
+
+// rdapEndpointList returns the RDAP base URLs of the configured registries,
+// or of all five when none is configured.
+func (e *Extractor) rdapEndpointList() []string {
+	if len(e.rdapEndpoints) > 0 {
+		return e.rdapEndpoints
+	}
+	var endpoints []string
+	for _, k := range e.settings().Registries {
+		if url, ok := rdapRegistryURLs[k]; ok {
+			endpoints = append(endpoints, url)
+		}
+	}
+	if len(endpoints) == 0 {
+		for _, k := range RegistryNames {
+			endpoints = append(endpoints, rdapRegistryURLs[k])
+		}
+	}
+	return endpoints
+}
+
+// errRegistriesCoolingDown is returned by performRDAPFull when no registry
+// answered and at least one was skipped or rate limited with HTTP 429, so the
+// lookup is worth retrying once the cooldown is over.
+var errRegistriesCoolingDown = errors.New("RDAP registries cooling down after HTTP 429")
+
+// fetchRDAP queries one registry for ip and returns the response body,
+// waiting for a free slot under the per-registry request cap first. A 429 is
+// not retried: the registry is put in cooldown for its Retry-After duration
+// and the caller moves on to the next one.
+func (e *Extractor) fetchRDAP(ctx context.Context, base, ip string) ([]byte, error) {
+	release := e.registryLimits().acquire(base)
+	defer release()
+	e.budget.Load().spendRDAP()
+	resp, err := e.httpGet(ctx, base+ip, false)
+	if err != nil {
+		var limited *rateLimitedError
+		if errors.As(err, &limited) {
+			until := e.cooldowns.coolDown(base, limited.retryAfter)
+			msg := fmt.Sprintf("Registre RDAP %s en pause jusqu'a %s (HTTP 429)", registryName(base), until.Format("15:04:05"))
+			e.logger.Warning("Extractor", msg)
+			e.publish(events.Warning, msg, 0, 0)
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading RDAP response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("RDAP HTTP %d", resp.StatusCode)
+	}
+	return body, nil
+}
+
+// performRDAPFull populates RDAP and contact fields on data from RDAP registries,
+// starting with the one owning ip in the IANA bootstrap (see rdapEndpointsFor).
+// Registries cooling down after a 429 are skipped in favour of the others.
+func (e *Extractor) performRDAPFull(ctx context.Context, ip string, data *models.ScannerData) error {
+	resting := false
+	for _, base := range e.rdapEndpointsFor(ctx, ip) {
+		if e.cooldowns.coolingDown(base, time.Now()) {
+			resting = true
+			continue
+		}
+		body, err := e.fetchRDAP(ctx, base, ip)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			var limited *rateLimitedError
+			if errors.As(err, &limited) {
+				resting = true
+			}
+			continue
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(body, &m); err != nil {
+			continue
+		}
+		if v, ok := m["name"].(string); ok && v != "" {
+			data.RDAPName = v
+			if data.Organization == "" {
+				data.Organization = v
+			}
+		}
+		if v, ok := m["handle"].(string); ok {
+			data.RDAPHandle = v
+		}
+		if v, ok := m["port43"].(string); ok && data.Registry == "" {
+			data.Registry = v
+		}
+		if v, ok := m["objectClassName"].(string); ok && data.Registry == "" {
+			data.Registry = v
+		}
+		if v, ok := m["startAddress"].(string); ok {
+			data.StartAddress = v
+		}
+		if v, ok := m["endAddress"].(string); ok {
+			data.EndAddress = v
+		}
+		if v, ok := m["ipVersion"].(string); ok {
+			data.IPVersion = v
+		}
+		if v, ok := m["type"].(string); ok {
+			data.RDAPType = v
+		}
+		if v, ok := m["parentHandle"].(string); ok {
+			data.ParentHandle = v
+		}
+		if ev, ok := m["events"].([]interface{}); ok {
+			for _, eraw := range ev {
+				if em, ok := eraw.(map[string]interface{}); ok {
+					action, _ := em["eventAction"].(string)
+					date, _ := em["eventDate"].(string)
+					t, err := models.ParseTimestamp(date)
+					if err != nil {
+						continue
+					}
+					if action == "registration" && data.EventRegistration.IsZero() {
+						data.EventRegistration = t
+					}
+					if action == "last changed" && data.EventLastChanged.IsZero() {
+						data.EventLastChanged = t
+					}
+				}
+			}
+		}
+		if network, ok := m["network"].(map[string]interface{}); ok {
+			if v, ok := network["cidr0_cidrs"].([]interface{}); ok && len(v) > 0 {
+				if first, ok := v[0].(map[string]interface{}); ok {
+					start, _ := first["v4prefix"].(string)
+					length := fmt.Sprintf("%v", first["length"])
+					if start != "" && length != "<nil>" {
+						data.RDAPCIDR = fmt.Sprintf("%s/%s", start, length)
+					}
+				}
+			}
+		}
+		if ents, ok := m["entities"].([]interface{}); ok {
+			for _, eraw := range ents {
+				em, ok := eraw.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				roles := map[string]bool{}
+				if rs, ok := em["roles"].([]interface{}); ok {
+					for _, r := range rs {
+						if s, ok := r.(string); ok {
+							roles[strings.ToLower(s)] = true
+						}
+					}
+				}
+				if vcard, ok := em["vcardArray"].([]interface{}); ok && len(vcard) > 1 {
+					if arr, ok := vcard[1].([]interface{}); ok {
+						for _, fld := range arr {
+							if pair, ok := fld.([]interface{}); ok && len(pair) >= 3 {
+								key, _ := pair[0].(string)
+								if key == "email" {
+									val, _ := pair[3].(string)
+									if roles["abuse"] && data.AbuseEmail == "" {
+										data.AbuseEmail = val
+									}
+									if (roles["technical"] || roles["tech"]) && data.TechEmail == "" {
+										data.TechEmail = val
+									}
+								}
+								if key == "fn" && data.RDAPName == "" {
+									val, _ := pair[3].(string)
+									if val != "" {
+										data.RDAPName = val
+										if data.Organization == "" {
+											data.Organization = val
+										}
+									}
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+		data.SetProvenance(models.ProvenanceRDAP, registryName(base), time.Now())
+		return nil
+	}
+	if resting {
+		return fmt.Errorf("no RDAP registry responded for %s: %w", ip, errRegistriesCoolingDown)
+	}
+	return fmt.Errorf("no RDAP registry responded for %s", ip)
+}
+
+// LookupRDAP returns a record for ip holding what the RDAP registries answer
+// for it: owner, network, registration events and contacts. Unlike Enrich,
+// neither the cache nor geolocation is involved.
+func (e *Extractor) LookupRDAP(ctx context.Context, ip string) (models.ScannerData, error) {
+	data := models.ScannerData{IPOrCIDR: ip}
+	err := e.performRDAPFull(ctx, ip, &data)
+	return data, err
+}
+
+// LoadProgressTracker loads the RDAP enrichment progress tracker from disk.
+func (e *Extractor) LoadProgressTracker() *models.RDAPProgressTracker {
+	progressPath := filepath.Join("build", "data", "rdap_progress.json")
+	tracker := &models.RDAPProgressTracker{
+		ProcessedIPs: []string{},
+	}
+
+	file, err := os.Open(progressPath)
+	if err != nil {
+		tracker.ProcessedIPSet = map[string]struct{}{}
+		return tracker
+	}
+	defer file.Close()
+
+	if err := json.NewDecoder(file).Decode(tracker); err != nil {
+		tracker.ProcessedIPSet = map[string]struct{}{}
+		return tracker
+	}
+
+	// Build the set from the slice for O(1) lookups.
+	tracker.ProcessedIPSet = make(map[string]struct{}, len(tracker.ProcessedIPs))
+	for _, ip := range tracker.ProcessedIPs {
+		tracker.ProcessedIPSet[ip] = struct{}{}
+	}
+
+	return tracker
+}
+
+// SaveProgressTracker persists the RDAP enrichment progress tracker to disk.
+func (e *Extractor) SaveProgressTracker(tracker *models.RDAPProgressTracker) error {
+	progressPath := filepath.Join("build", "data", "rdap_progress.json")
+	_ = os.MkdirAll(filepath.Dir(progressPath), 0755)
+
+	tracker.LastUpdatedAt = time.Now().Format(time.RFC3339)
+
+	unlock, err := lockDataFile(progressPath)
+	if err != nil {
+		return fmt.Errorf("saving progress tracker: %w", err)
+	}
+	defer unlock()
+	mergeProgress(tracker, e.LoadProgressTracker())
+	if err := writeJSONAtomic(progressPath, tracker); err != nil {
+		return fmt.Errorf("saving progress tracker: %w", err)
+	}
+	return nil
+}
+
+// mergeProgress adds to tracker the IPs another instance saved as processed
+// in the same run, identified by its start time, so saving does not drop
+// them. The tracker of another run is replaced.
+func mergeProgress(tracker, saved *models.RDAPProgressTracker) {
+	if saved.StartedAt != tracker.StartedAt {
+		return
+	}
+	seen := make(map[string]struct{}, len(tracker.ProcessedIPs))
+	for _, ip := range tracker.ProcessedIPs {
+		seen[ip] = struct{}{}
+	}
+	for _, ip := range saved.ProcessedIPs {
+		if _, ok := seen[ip]; ok {
+			continue
+		}
+		seen[ip] = struct{}{}
+		tracker.ProcessedIPs = append(tracker.ProcessedIPs, ip)
+		if tracker.ProcessedIPSet != nil {
+			tracker.ProcessedIPSet[ip] = struct{}{}
+		}
+	}
+	tracker.ProcessedRecords = max(tracker.ProcessedRecords, saved.ProcessedRecords, len(tracker.ProcessedIPs))
+}
+
+// IsIPProcessed reports whether the given IP has already been processed.
+func (e *Extractor) IsIPProcessed(ip string, tracker *models.RDAPProgressTracker) bool {
+	// Use the set for O(1) lookup if available.
+	if tracker.ProcessedIPSet != nil {
+		_, ok := tracker.ProcessedIPSet[ip]
+		return ok
+	}
+	// Fallback to linear scan for backward compatibility.
+	for _, processed := range tracker.ProcessedIPs {
+		if processed == ip {
+			return true
+		}
+	}
+	return false
+}
+
+// ClearProgressTracker removes the RDAP enrichment progress file from disk.
+func (e *Extractor) ClearProgressTracker() error {
+	progressPath := filepath.Join("build", "data", "rdap_progress.json")
+	unlock, err := lockDataFile(progressPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return os.Remove(progressPath)
+}
@@ -0,0 +1,118 @@
+package extractor
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// DefaultRDNSPatterns are the reverse DNS names of the known scanners.
+var DefaultRDNSPatterns = []models.RDNSPattern{
+	{Scanner: "shodan", Pattern: "*.shodan.io"},
+	{Scanner: "shodan", Pattern: "*.census.shodan.io"},
+	{Scanner: "censys", Pattern: "*.censys-scanner.com"},
+	{Scanner: "binaryedge", Pattern: "*.binaryedge.ninja"},
+	{Scanner: "rapid7", Pattern: "*.sonar.labs.rapid7.com"},
+	{Scanner: "shadowserver", Pattern: "*.shadowserver.org"},
+	{Scanner: "ipip", Pattern: "scanner-*.ipip.net"},
+}
+
+// rdnsLearnMinRecords is how many records of a scanner must share a reverse
+// DNS domain before LearnRDNSPatterns takes it for that scanner.
+const rdnsLearnMinRecords = 3
+
+// rdnsTagPrefix prefixes the tag of the records attributed by reverse DNS.
+const rdnsTagPrefix = "rdns:"
+
+// rdnsDomain returns the last two labels of a host name, lower-cased
+// without the trailing dot, or "" for a bare name.
+func rdnsDomain(host string) string {
+	labels := strings.Split(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), "."), ".")
+	if len(labels) < 2 || labels[len(labels)-2] == "" {
+		return ""
+	}
+	return strings.Join(labels[len(labels)-2:], ".")
+}
+
+// LearnRDNSPatterns derives "*.<domain>" patterns from data: a domain is
+// taken for a scanner when at least three of its records resolve under it
+// and no record of another scanner does. Patterns are sorted by scanner.
+func LearnRDNSPatterns(data []models.ScannerData) []models.RDNSPattern {
+	counts := map[string]map[string]int{}
+	for _, item := range data {
+		d := rdnsDomain(item.ReverseDNS)
+		if d == "" || item.ScannerName == "" {
+			continue
+		}
+		if counts[d] == nil {
+			counts[d] = map[string]int{}
+		}
+		counts[d][strings.ToLower(item.ScannerName)]++
+	}
+	var out []models.RDNSPattern
+	for d, byScanner := range counts {
+		if len(byScanner) != 1 {
+			continue
+		}
+		for scanner, n := range byScanner {
+			if n >= rdnsLearnMinRecords {
+				out = append(out, models.RDNSPattern{Scanner: scanner, Pattern: "*." + d})
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Scanner != out[j].Scanner {
+			return out[i].Scanner < out[j].Scanner
+		}
+		return out[i].Pattern < out[j].Pattern
+	})
+	return out
+}
+
+// ClassifyRDNS returns the scanner of the first of patterns matching host.
+// Patterns are shell globs on the lower-cased name, where * also matches
+// dots.
+func ClassifyRDNS(host string, patterns []models.RDNSPattern) (string, bool) {
+	host = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+	if host == "" {
+		return "", false
+	}
+	for _, p := range patterns {
+		if ok, err := path.Match(strings.ToLower(p.Pattern), host); err == nil && ok {
+			return strings.ToLower(p.Scanner), true
+		}
+	}
+	return "", false
+}
+
+// ValidateRDNSPattern reports why p cannot be used, or nil.
+func ValidateRDNSPattern(p models.RDNSPattern) error {
+	if strings.TrimSpace(p.Scanner) == "" {
+		return fmt.Errorf("rdns pattern %q names no scanner", p.Pattern)
+	}
+	if strings.TrimSpace(p.Pattern) == "" {
+		return fmt.Errorf("rdns pattern of %s is empty", p.Scanner)
+	}
+	if _, err := path.Match(p.Pattern, ""); err != nil {
+		return fmt.Errorf("rdns pattern %q: %w", p.Pattern, err)
+	}
+	return nil
+}
+
+// RDNSPatterns returns the patterns AttributeScanners uses on data: the
+// configured rdns_patterns, then the defaults, then those learned from data.
+func (e *Extractor) RDNSPatterns(data []models.ScannerData) []models.RDNSPattern {
+	var out []models.RDNSPattern
+	for _, p := range e.settings().RDNSPatterns {
+		if err := ValidateRDNSPattern(p); err != nil {
+			e.logger.Warning("Extractor", "Motif rDNS ignore: "+err.Error())
+			continue
+		}
+		out = append(out, p)
+	}
+	out = append(out, DefaultRDNSPatterns...)
+	return append(out, LearnRDNSPatterns(data)...)
+}
@@ -0,0 +1,132 @@
+package extractor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// Redact returns copies of data with the rules applied, for exports leaving
+// the team: removed fields are emptied (their CSV column stays, blank), text
+// fields are hashed, masked or reduced to their domain. data is left
+// untouched.
+func Redact(data []models.ScannerData, rules []models.RedactionRule) ([]models.ScannerData, error) {
+	type field struct {
+		index  int
+		action string
+	}
+	fields := make([]field, 0, len(rules))
+	for _, r := range rules {
+		if err := r.Validate(); err != nil {
+			return nil, fmt.Errorf("redaction rule %s/%s: %w", r.Field, r.Action, err)
+		}
+		f, _ := models.ScannerDataField(r.Field)
+		fields = append(fields, field{f.Index[0], r.Action})
+	}
+	out := make([]models.ScannerData, len(data))
+	for i, item := range data {
+		v := reflect.ValueOf(&item).Elem()
+		for _, f := range fields {
+			redactValue(v.Field(f.index), f.action)
+		}
+		out[i] = item
+	}
+	return out, nil
+}
+
+// redactValue applies action to v, a field of a copied record. Lists are
+// copied before their entries are redacted, as they share their array with
+// the original record.
+func redactValue(v reflect.Value, action string) {
+	if action == models.RedactRemove {
+		v.Set(reflect.Zero(v.Type()))
+		return
+	}
+	if v.Kind() == reflect.Slice {
+		if v.IsNil() {
+			return
+		}
+		list := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			list.Index(i).SetString(redactString(v.Index(i).String(), action))
+		}
+		v.Set(list)
+		return
+	}
+	v.SetString(redactString(v.String(), action))
+}
+
+// redactString hashes, masks or reduces s to its domain. Empty values stay
+// empty, so a hash never stands for a missing value.
+func redactString(s, action string) string {
+	if s == "" {
+		return ""
+	}
+	switch action {
+	case models.RedactHash:
+		sum := sha256.Sum256([]byte(s))
+		return "sha256:" + hex.EncodeToString(sum[:8])
+	case models.RedactMask:
+		r := []rune(s)
+		if len(r) <= 2 {
+			return strings.Repeat("*", len(r))
+		}
+		return string(r[0]) + strings.Repeat("*", len(r)-2) + string(r[len(r)-1])
+	case models.RedactDomain:
+		if strings.Contains(s, "@") {
+			return emailDomain(s)
+		}
+		return truncateHost(s)
+	}
+	return s
+}
+
+// ExportPreset returns the configured export preset named name, or the
+// default one (DefaultExportPreset) when name is empty. ok is false when no
+// preset applies: name is empty and there is no default.
+func (e *Extractor) ExportPreset(name string) (preset models.ExportPreset, ok bool, err error) {
+	cfg := e.settings()
+	if name == "" {
+		name = cfg.DefaultExportPreset
+	}
+	if name == "" {
+		return models.ExportPreset{}, false, nil
+	}
+	for _, p := range cfg.ExportPresets {
+		if p.Name == name {
+			return p, true, nil
+		}
+	}
+	return models.ExportPreset{}, false, fmt.Errorf("unknown export preset %q", name)
+}
+
+// ExportPresetNames returns the names of the configured export presets.
+func (e *Extractor) ExportPresetNames() []string {
+	cfg := e.settings()
+	names := make([]string, 0, len(cfg.ExportPresets))
+	for _, p := range cfg.ExportPresets {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// RedactForExport applies the export preset named preset, or the default one
+// when preset is empty, to data. Every export path goes through it, so the
+// default preset is enforced whoever exports. data is returned unchanged
+// when no preset applies.
+func (e *Extractor) RedactForExport(data []models.ScannerData, preset string) ([]models.ScannerData, error) {
+	p, ok, err := e.ExportPreset(preset)
+	if err != nil || !ok {
+		return data, err
+	}
+	out, err := Redact(data, p.Redact)
+	if err != nil {
+		return nil, fmt.Errorf("export preset %s: %w", p.Name, err)
+	}
+	e.logger.Info("Extractor", fmt.Sprintf("Preset d'export %s applique: %d regles sur %d enregistrements", p.Name, len(p.Redact), len(out)))
+	return out, nil
+}
@@ -0,0 +1,178 @@
+package extractor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// RegistryNames are the RDAP registries Registries accepts, in the order
+// they are queried when none is configured.
+var RegistryNames = []string{"arin", "ripe", "apnic", "lacnic", "afrinic"}
+
+// registryReportMinLookups is the number of RDAP answers below which
+// BuildRegistryReport suggests nothing, the distribution being too thin.
+const registryReportMinLookups = 20
+
+// ParseRegistries parses a comma-separated list of registry names, such as
+// the -registries flag; "all" or an empty list selects all of them (nil).
+func ParseRegistries(s string) ([]string, error) {
+	if strings.EqualFold(strings.TrimSpace(s), "all") {
+		return nil, nil
+	}
+	var regs []string
+	for _, r := range strings.Split(s, ",") {
+		r = strings.ToLower(strings.TrimSpace(r))
+		if r == "" {
+			continue
+		}
+		if !containsString(RegistryNames, r) {
+			return nil, fmt.Errorf("unknown RDAP registry %q (want %s or all)", r, strings.Join(RegistryNames, ", "))
+		}
+		if !containsString(regs, r) {
+			regs = append(regs, r)
+		}
+	}
+	return regs, nil
+}
+
+// RegistryOwner returns the registry owning the network of item, from the
+// whois server its RDAP answer names (port43), or "" when it names none.
+func RegistryOwner(item models.ScannerData) string {
+	server := strings.ToLower(item.Registry)
+	for _, name := range RegistryNames {
+		if strings.Contains(server, name) {
+			return name
+		}
+	}
+	return ""
+}
+
+// RegistryCount is the share of the RDAP lookups of a dataset one registry
+// answered.
+type RegistryCount struct {
+	Name string
+	// Owned is the number of records whose network the registry owns.
+	Owned int
+	// Authoritative is the number of lookups it answered for its own
+	// networks, Referred those it answered for another registry's.
+	Authoritative int
+	Referred      int
+}
+
+// RegistryReport is the distribution of the RDAP lookups of a dataset over
+// the registries, with the changes of Registries and of the RDAP bootstrap
+// it suggests.
+type RegistryReport struct {
+	// Registries are the registries that answered or own a network, most
+	// owned first.
+	Registries []RegistryCount
+	// Answered is the number of records an RDAP lookup filled, Unanswered
+	// the others.
+	Answered   int
+	Unanswered int
+	// Suggestions are the configuration changes the distribution calls for.
+	Suggestions []string
+}
+
+// BuildRegistryReport counts, for the records of data, which registry
+// answered their RDAP lookup (their provenance) and which owns their
+// network (their port43 server), and suggests narrowing Registries of
+// config to the owners seen, adding the owners it lacks, or turning the
+// RDAP bootstrap back on when lookups went to the wrong registry first.
+// Nothing is suggested below 20 answered lookups.
+func BuildRegistryReport(data []models.ScannerData, config models.DatabaseConfig) RegistryReport {
+	counts := map[string]*RegistryCount{}
+	count := func(name string) *RegistryCount {
+		c, ok := counts[name]
+		if !ok {
+			c = &RegistryCount{Name: name}
+			counts[name] = c
+		}
+		return c
+	}
+	var r RegistryReport
+	referred := 0
+	for _, item := range data {
+		answered, _, _ := strings.Cut(item.Provenance[models.ProvenanceRDAP], "@")
+		if answered == "" {
+			r.Unanswered++
+			continue
+		}
+		r.Answered++
+		owner := RegistryOwner(item)
+		if owner == "" {
+			owner = answered
+		}
+		count(owner).Owned++
+		if owner == answered {
+			count(answered).Authoritative++
+		} else {
+			count(answered).Referred++
+			referred++
+		}
+	}
+	for _, c := range counts {
+		r.Registries = append(r.Registries, *c)
+	}
+	sort.Slice(r.Registries, func(i, j int) bool {
+		if r.Registries[i].Owned != r.Registries[j].Owned {
+			return r.Registries[i].Owned > r.Registries[j].Owned
+		}
+		return r.Registries[i].Name < r.Registries[j].Name
+	})
+	if r.Answered < registryReportMinLookups {
+		return r
+	}
+
+	configured := RegistryNames
+	if len(config.Registries) > 0 {
+		configured = nil
+		for _, name := range config.Registries {
+			configured = append(configured, strings.ToLower(name))
+		}
+	}
+	owned := func(name string) int {
+		if c, ok := counts[name]; ok {
+			return c.Owned
+		}
+		return 0
+	}
+	var keep, unused []string
+	for _, name := range configured {
+		if owned(name) > 0 {
+			keep = append(keep, name)
+		} else {
+			unused = append(unused, name)
+		}
+	}
+	if len(unused) > 0 && len(keep) > 0 {
+		r.Suggestions = append(r.Suggestions, fmt.Sprintf("Narrow registries to [%s]: %s owned none of the %d networks looked up",
+			strings.Join(keep, ", "), strings.Join(unused, ", "), r.Answered))
+	}
+	for _, c := range r.Registries {
+		if c.Owned > 0 && containsString(RegistryNames, c.Name) && !containsString(configured, c.Name) {
+			r.Suggestions = append(r.Suggestions, fmt.Sprintf("Add %s to registries: it owns %d networks, answered through other registries", c.Name, c.Owned))
+		}
+	}
+	if config.DisableRDAPBootstrap && referred > 0 {
+		r.Suggestions = append(r.Suggestions, fmt.Sprintf("Enable the RDAP bootstrap (disable_rdap_bootstrap: false): %d lookups (%.0f%%) were answered by a registry other than the owner",
+			referred, 100*float64(referred)/float64(r.Answered)))
+	}
+	return r
+}
+
+// Lines formats the report a line per registry, then its suggestions.
+func (r RegistryReport) Lines() []string {
+	lines := []string{fmt.Sprintf("RDAP registries: %d lookups answered, %d unanswered", r.Answered, r.Unanswered)}
+	for _, c := range r.Registries {
+		lines = append(lines, fmt.Sprintf("  %s: %d owned, %d answered authoritatively, %d for other registries",
+			c.Name, c.Owned, c.Authoritative, c.Referred))
+	}
+	for _, s := range r.Suggestions {
+		lines = append(lines, "Suggestion: "+s)
+	}
+	return lines
+}
@@ -0,0 +1,178 @@
+package extractor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// ServerTimeHeader carries the time at which GET /api/records took its
+// snapshot, the cursor of the next delta sync.
+const ServerTimeHeader = "X-Server-Time"
+
+// remoteSyncState is the delta sync cursor stored between syncs.
+type remoteSyncState struct {
+	URL      string    `json:"url"`
+	LastSync time.Time `json:"last_sync"`
+}
+
+// remoteSyncFile returns the path of the delta sync cursor.
+func (e *Extractor) remoteSyncFile() string {
+	if e.remoteSyncPath != "" {
+		return e.remoteSyncPath
+	}
+	return filepath.Join("build", "data", "remote_sync.json")
+}
+
+func (e *Extractor) loadRemoteSync() (remoteSyncState, error) {
+	var state remoteSyncState
+	b, err := os.ReadFile(e.remoteSyncFile())
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("reading remote sync state: %w", err)
+	}
+	if err := json.Unmarshal(b, &state); err != nil {
+		return state, fmt.Errorf("decoding remote sync state: %w", err)
+	}
+	return state, nil
+}
+
+// saveRemoteSync writes state as the delta sync cursor, under the lock
+// shared by the instances. A cursor another instance moved further on the
+// same URL in the meantime is kept rather than moved back.
+func (e *Extractor) saveRemoteSync(state remoteSyncState) error {
+	path := e.remoteSyncFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating remote sync directory: %w", err)
+	}
+	unlock, err := lockDataFile(path)
+	if err != nil {
+		return fmt.Errorf("writing remote sync state: %w", err)
+	}
+	defer unlock()
+	if saved, err := e.loadRemoteSync(); err == nil && saved.URL == state.URL && saved.LastSync.After(state.LastSync) {
+		return nil
+	}
+	if err := writeJSONAtomic(path, state); err != nil {
+		return fmt.Errorf("writing remote sync state: %w", err)
+	}
+	return nil
+}
+
+// FetchRemoteRecords reads the records of the LiaCheckScanner API at
+// RemoteAPIURL updated after since (all of them for the zero time). It
+// returns them with the server's snapshot time, to pass as since next time.
+func (e *Extractor) FetchRemoteRecords(ctx context.Context, since time.Time) ([]models.ScannerData, time.Time, error) {
+	cfg := e.settings()
+	if cfg.RemoteAPIURL == "" {
+		return nil, time.Time{}, fmt.Errorf("remote_api_url is not configured")
+	}
+	u, err := url.Parse(strings.TrimSuffix(cfg.RemoteAPIURL, "/") + "/api/records")
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("parsing remote_api_url: %w", err)
+	}
+	if !since.IsZero() {
+		u.RawQuery = url.Values{"updated_since": {since.UTC().Format(time.RFC3339Nano)}}.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("building remote request: %w", err)
+	}
+	if cfg.RemoteAPIKey != "" {
+		req.Header.Set("X-API-Key", cfg.RemoteAPIKey)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := e.apiClient.Do(req)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("remote API request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, time.Time{}, fmt.Errorf("remote API http %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var data []models.ScannerData
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, time.Time{}, fmt.Errorf("decoding remote records: %w", err)
+	}
+	// Older servers send no snapshot time; their Date header is the next
+	// best cursor, as it comes from the same clock as UpdatedAt
+	serverTime, err := time.Parse(time.RFC3339Nano, resp.Header.Get(ServerTimeHeader))
+	if err != nil {
+		if serverTime, err = http.ParseTime(resp.Header.Get("Date")); err != nil {
+			serverTime = time.Now()
+		}
+	}
+	return data, serverTime.UTC(), nil
+}
+
+// MergeRecords returns local with each record of updates replacing the one
+// with the same canonical IP and scanner, unless the local one was updated
+// later, and the others appended. Record IDs are positions in an
+// extraction, reassigned by every run, so they cannot match records across
+// instances.
+func MergeRecords(local, updates []models.ScannerData) []models.ScannerData {
+	out := append([]models.ScannerData(nil), local...)
+	index := make(map[string]int, len(out))
+	for i, item := range out {
+		index[dedupKey(item)] = i
+	}
+	for _, item := range updates {
+		if i, ok := index[dedupKey(item)]; ok {
+			if !out[i].UpdatedAt.After(item.UpdatedAt) {
+				out[i] = item
+			}
+			continue
+		}
+		index[dedupKey(item)] = len(out)
+		out = append(out, item)
+	}
+	return out
+}
+
+// SyncRemote brings local up to date with the remote API. The first sync,
+// or any sync with an empty local dataset or a new RemoteAPIURL, downloads
+// the whole dataset; later ones only fetch the records updated since the
+// previous sync and merge them. It returns the dataset and the number of
+// records received. Records deleted on the server are only dropped by a
+// full sync.
+func (e *Extractor) SyncRemote(ctx context.Context, local []models.ScannerData) ([]models.ScannerData, int, error) {
+	remoteURL := e.settings().RemoteAPIURL
+	state, err := e.loadRemoteSync()
+	if err != nil {
+		return nil, 0, err
+	}
+	var since time.Time
+	if len(local) > 0 && state.URL == remoteURL {
+		since = state.LastSync
+	}
+	updates, serverTime, err := e.FetchRemoteRecords(ctx, since)
+	if err != nil {
+		return nil, 0, err
+	}
+	data := updates
+	if !since.IsZero() {
+		data = MergeRecords(local, updates)
+	}
+	if err := e.saveRemoteSync(remoteSyncState{URL: remoteURL, LastSync: serverTime}); err != nil {
+		return nil, 0, err
+	}
+	mode := "complete"
+	if !since.IsZero() {
+		mode = "delta"
+	}
+	e.logger.Info("Extractor", fmt.Sprintf("Synchronisation %s depuis %s: %d enregistrements recus, %d au total",
+		mode, remoteURL, len(updates), len(data)))
+	return data, len(updates), nil
+}
@@ -0,0 +1,170 @@
+package extractor
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// dohTimeout bounds one DNS-over-HTTPS exchange when the resolver sets no
+// deadline.
+const dohTimeout = 10 * time.Second
+
+// newResolver builds the resolver selected by config: DNS-over-HTTPS when
+// DoHURL is set, else the DNSServers in turn, else nil for the system
+// resolver.
+func newResolver(config models.DatabaseConfig) *net.Resolver {
+	if doh := strings.TrimSpace(config.DoHURL); doh != "" {
+		client := &http.Client{Timeout: dohTimeout}
+		return &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return &dohConn{ctx: ctx, url: doh, client: client}, nil
+			},
+		}
+	}
+	if len(config.DNSServers) == 0 {
+		return nil
+	}
+	servers := make([]string, len(config.DNSServers))
+	for i, s := range config.DNSServers {
+		servers[i] = dnsServerAddr(s)
+	}
+	var next uint32
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			server := servers[int(atomic.AddUint32(&next, 1)-1)%len(servers)]
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// dnsServerAddr adds the default port 53 to a DNS server address.
+func dnsServerAddr(s string) string {
+	s = strings.TrimSpace(s)
+	if _, _, err := net.SplitHostPort(s); err == nil {
+		return s
+	}
+	return net.JoinHostPort(strings.Trim(s, "[]"), "53")
+}
+
+// resolver returns the current resolver, or nil for the system one.
+func (e *Extractor) resolver() *net.Resolver {
+	e.configMu.RLock()
+	defer e.configMu.RUnlock()
+	return e.dns
+}
+
+// lookupAddr returns the host names of ip through the configured resolver.
+func (e *Extractor) lookupAddr(ctx context.Context, ip string) ([]string, error) {
+	if r := e.resolver(); r != nil {
+		return r.LookupAddr(ctx, ip)
+	}
+	return net.DefaultResolver.LookupAddr(ctx, ip)
+}
+
+// dialContext dials through the configured resolver, so RDAP, geolocation,
+// PeeringDB and repository host names are resolved the same way as reverse
+// lookups.
+func (e *Extractor) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	d := net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Resolver: e.resolver()}
+	return d.DialContext(ctx, network, addr)
+}
+
+// newTransport returns the transport behind the shared API client and the
+// git client.
+func (e *Extractor) newTransport() http.RoundTripper {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = e.dialContext
+	return t
+}
+
+// dohConn carries the Go resolver's TCP-framed DNS messages over
+// DNS-over-HTTPS (RFC 8484): each query written is POSTed to the resolver
+// URL and the answer is framed back for reading.
+type dohConn struct {
+	ctx      context.Context
+	url      string
+	client   *http.Client
+	deadline time.Time
+	query    bytes.Buffer
+	answer   bytes.Buffer
+}
+
+func (c *dohConn) Write(b []byte) (int, error) {
+	return c.query.Write(b)
+}
+
+func (c *dohConn) Read(b []byte) (int, error) {
+	if c.answer.Len() == 0 {
+		if err := c.exchange(); err != nil {
+			return 0, err
+		}
+	}
+	return c.answer.Read(b)
+}
+
+// exchange sends the buffered query and buffers the framed answer.
+func (c *dohConn) exchange() error {
+	q := c.query.Bytes()
+	if len(q) < 2 || len(q) < 2+int(binary.BigEndian.Uint16(q)) {
+		return io.EOF
+	}
+	n := int(binary.BigEndian.Uint16(q))
+	msg := append([]byte(nil), q[2:2+n]...)
+	c.query.Next(2 + n)
+
+	ctx := c.ctx
+	if !c.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, c.deadline)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(msg))
+	if err != nil {
+		return fmt.Errorf("building DoH request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("DoH request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("DoH request: HTTP %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 65535))
+	if err != nil {
+		return fmt.Errorf("reading DoH answer: %w", err)
+	}
+	var size [2]byte
+	binary.BigEndian.PutUint16(size[:], uint16(len(body)))
+	c.answer.Write(size[:])
+	c.answer.Write(body)
+	return nil
+}
+
+func (c *dohConn) Close() error                       { return nil }
+func (c *dohConn) LocalAddr() net.Addr                { return dohAddr{} }
+func (c *dohConn) RemoteAddr() net.Addr               { return dohAddr{} }
+func (c *dohConn) SetDeadline(t time.Time) error      { c.deadline = t; return nil }
+func (c *dohConn) SetReadDeadline(t time.Time) error  { c.deadline = t; return nil }
+func (c *dohConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// dohAddr is the placeholder address of a dohConn.
+type dohAddr struct{}
+
+func (dohAddr) Network() string { return "doh" }
+func (dohAddr) String() string  { return "doh" }
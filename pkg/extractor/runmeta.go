@@ -0,0 +1,108 @@
+package extractor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// runMetadataSuffix is appended to the path of a run or exported list to
+// name its metadata file.
+const runMetadataSuffix = ".meta.json"
+
+// RunMetadataPath returns the metadata file of the run or list at path.
+func RunMetadataPath(path string) string {
+	return path + runMetadataSuffix
+}
+
+// LastSourceRevision returns the upstream state the last sync of this
+// Extractor left the repository at, if it synced.
+func (e *Extractor) LastSourceRevision() (models.SourceRevision, bool) {
+	if r := e.revision.Load(); r != nil {
+		return *r, true
+	}
+	return models.SourceRevision{}, false
+}
+
+// RunMetadata describes data, saved or exported as name: the repository
+// commit of the last sync and the SHA-256 of the archive sources read.
+func (e *Extractor) RunMetadata(name string, data []models.ScannerData) models.RunMetadata {
+	meta := models.RunMetadata{Name: name, CreatedAt: time.Now().UTC(), Records: len(data)}
+	if r, ok := e.LastSourceRevision(); ok {
+		meta.Source = &r
+	}
+	for _, src := range e.settings().ArchiveSources {
+		sum, err := fileSHA256(e.archiveLocalPath(src))
+		if err != nil {
+			continue
+		}
+		meta.Archives = append(meta.Archives, models.ArchiveRevision{Source: redactURL(src), SHA256: sum})
+	}
+	return meta
+}
+
+// fileSHA256 returns the hex SHA-256 of the file at path.
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// WriteRunMetadata writes meta as the metadata file of the run or list at
+// path.
+func WriteRunMetadata(path string, meta models.RunMetadata) error {
+	b, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding run metadata: %w", err)
+	}
+	if err := os.WriteFile(RunMetadataPath(path), b, 0644); err != nil {
+		return fmt.Errorf("writing run metadata: %w", err)
+	}
+	return nil
+}
+
+// ReadRunMetadata reads the metadata file of the run or list at path.
+func ReadRunMetadata(path string) (models.RunMetadata, error) {
+	var meta models.RunMetadata
+	b, err := os.ReadFile(RunMetadataPath(path))
+	if err != nil {
+		return meta, fmt.Errorf("reading run metadata: %w", err)
+	}
+	if err := json.Unmarshal(b, &meta); err != nil {
+		return meta, fmt.Errorf("decoding run metadata %s: %w", RunMetadataPath(path), err)
+	}
+	return meta, nil
+}
+
+// SaveRunMetadata writes the metadata of data, saved or exported as name in
+// the results directory, next to it, with the hash of the file so that
+// VerifyDataset can tell when it is modified. An Exporter writing
+// elsewhere leaves no file to hash, and the metadata is written without it.
+func (e *Extractor) SaveRunMetadata(name string, data []models.ScannerData) error {
+	path := filepath.Join(e.settings().ResultsDir, name)
+	meta := e.RunMetadata(name, data)
+	integrity, err := HashDataset(path)
+	switch {
+	case err == nil:
+		meta.Integrity = &integrity
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+	return WriteRunMetadata(path, meta)
+}
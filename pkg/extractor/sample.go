@@ -0,0 +1,175 @@
+package extractor
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// Sampling modes of SampleRecords and SampleCSVFile.
+const (
+	// SampleRandom draws records uniformly.
+	SampleRandom = "random"
+	// SampleStratified draws from each scanner in proportion to its share
+	// of the dataset, with at least one record per scanner when the sample
+	// is large enough.
+	SampleStratified = "stratified"
+)
+
+// Sample is a subset of a dataset, loaded to explore datasets too large for
+// the machine. Counts computed over Records reflect the sample, not the
+// dataset of Total records.
+type Sample struct {
+	Records []models.ScannerData
+	Total   int    // records of the whole dataset
+	Mode    string // SampleRandom or SampleStratified
+}
+
+// Partial reports whether the sample leaves records of the dataset out.
+func (s Sample) Partial() bool {
+	return len(s.Records) < s.Total
+}
+
+// sampled is a record drawn by a sampler with its position in the dataset,
+// to give the sample back in dataset order.
+type sampled struct {
+	pos  int
+	item models.ScannerData
+}
+
+// reservoir keeps a uniform sample of up to size of the records it saw
+// (reservoir sampling, algorithm R).
+type reservoir struct {
+	seen  int
+	items []sampled
+}
+
+func (r *reservoir) add(s sampled, size int, rng *rand.Rand) {
+	r.seen++
+	if len(r.items) < size {
+		r.items = append(r.items, s)
+	} else if j := rng.Intn(r.seen); j < size {
+		r.items[j] = s
+	}
+}
+
+// sampler draws n records from a stream of unknown length, holding at most
+// n records per stratum: one stratum for SampleRandom, one per scanner name
+// for SampleStratified.
+type sampler struct {
+	n      int
+	mode   string
+	rng    *rand.Rand
+	total  int
+	strata map[string]*reservoir
+	order  []string // strata in order of first appearance
+}
+
+func newSampler(n int, mode string, rng *rand.Rand) (*sampler, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("sample size %d: must be positive", n)
+	}
+	if mode != SampleRandom && mode != SampleStratified {
+		return nil, fmt.Errorf("unknown sampling mode %q (want %s or %s)", mode, SampleRandom, SampleStratified)
+	}
+	if rng == nil {
+		rng = rand.New(rand.NewSource(rand.Int63()))
+	}
+	return &sampler{n: n, mode: mode, rng: rng, strata: map[string]*reservoir{}}, nil
+}
+
+func (s *sampler) add(item models.ScannerData) {
+	key := ""
+	if s.mode == SampleStratified {
+		key = item.ScannerName
+	}
+	r, ok := s.strata[key]
+	if !ok {
+		r = &reservoir{}
+		s.strata[key] = r
+		s.order = append(s.order, key)
+	}
+	r.add(sampled{s.total, item}, s.n, s.rng)
+	s.total++
+}
+
+// quotas splits the n records among the strata in proportion to their size
+// (D'Hondt method), after one record each when n allows it.
+func (s *sampler) quotas() map[string]int {
+	q := map[string]int{}
+	left := s.n
+	if left >= len(s.order) {
+		for _, key := range s.order {
+			q[key] = 1
+		}
+		left -= len(s.order)
+	}
+	for ; left > 0; left-- {
+		best, bestScore := "", -1.0
+		for _, key := range s.order {
+			seen := s.strata[key].seen
+			if q[key] >= seen {
+				continue
+			}
+			if score := float64(seen) / float64(q[key]+1); score > bestScore {
+				best, bestScore = key, score
+			}
+		}
+		if bestScore < 0 {
+			break
+		}
+		q[best]++
+	}
+	return q
+}
+
+// result returns the sample in dataset order.
+func (s *sampler) result() Sample {
+	var picked []sampled
+	quotas := s.quotas()
+	for _, key := range s.order {
+		items := s.strata[key].items
+		// The reservoir is a uniform sample, so is any part of it; shuffle
+		// it first, since its first slots hold the first records until
+		// they are replaced
+		s.rng.Shuffle(len(items), func(i, j int) { items[i], items[j] = items[j], items[i] })
+		if q := quotas[key]; q < len(items) {
+			items = items[:q]
+		}
+		picked = append(picked, items...)
+	}
+	sort.Slice(picked, func(i, j int) bool { return picked[i].pos < picked[j].pos })
+	out := Sample{Records: make([]models.ScannerData, len(picked)), Total: s.total, Mode: s.mode}
+	for i, p := range picked {
+		out.Records[i] = p.item
+	}
+	return out
+}
+
+// SampleRecords draws n records of data with mode. rng may be nil.
+func SampleRecords(data []models.ScannerData, n int, mode string, rng *rand.Rand) (Sample, error) {
+	s, err := newSampler(n, mode, rng)
+	if err != nil {
+		return Sample{}, err
+	}
+	for _, item := range data {
+		s.add(item)
+	}
+	return s.result(), nil
+}
+
+// SampleCSVFile draws n records of a CSV file written by SaveToCSV with
+// mode, reading it row by row: it holds at most n records, or n per scanner
+// when stratified, whatever the size of the file. rng may be nil.
+func SampleCSVFile(filename string, n int, mode string, rng *rand.Rand) (Sample, error) {
+	s, err := newSampler(n, mode, rng)
+	if err != nil {
+		return Sample{}, err
+	}
+	if err := scanCSVFile(filename, s.add); err != nil {
+		return Sample{}, fmt.Errorf("sampling %s: %w", filename, err)
+	}
+	return s.result(), nil
+}
@@ -0,0 +1,167 @@
+package extractor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// selfTestProbeIP is the address looked up to check RDAP and geolocation.
+const selfTestProbeIP = "1.1.1.1"
+
+// selfTestTimeout bounds each network check of the self-test.
+const selfTestTimeout = 10 * time.Second
+
+// maxClockSkew is the largest accepted difference with a registry's clock.
+const maxClockSkew = 5 * time.Minute
+
+// SelfTestResult is one line of the self-test checklist.
+type SelfTestResult struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail"`
+}
+
+// SelfTestPassed reports whether every check passed.
+func SelfTestPassed(results []SelfTestResult) bool {
+	for _, r := range results {
+		if !r.OK {
+			return false
+		}
+	}
+	return true
+}
+
+// SelfTest checks what a run depends on: write access to the data
+// directories, reachability of the configured RDAP registries and
+// of the geolocation provider, and the local clock against a registry's.
+// Network checks make one request each, without retries.
+func (e *Extractor) SelfTest() []SelfTestResult {
+	var results []SelfTestResult
+
+	cfg := e.settings()
+	dirs := []struct{ name, path string }{
+		{"results dir", cfg.ResultsDir},
+		{"logs dir", cfg.LogsDir},
+		{"repository dir", filepath.Dir(e.localPath())},
+		{"data dir", filepath.Join("build", "data")},
+	}
+	for _, d := range dirs {
+		if d.path == "" {
+			continue
+		}
+		if err := checkWritable(d.path); err != nil {
+			results = append(results, SelfTestResult{Name: d.name, Detail: err.Error()})
+		} else {
+			results = append(results, SelfTestResult{Name: d.name, OK: true, Detail: d.path})
+		}
+	}
+
+	rdapResults, serverTime := e.probeRDAP()
+	results = append(results, rdapResults...)
+
+	geo := e.geoProvider()
+	if g, err := geo.Lookup(context.Background(), selfTestProbeIP); err != nil {
+		results = append(results, SelfTestResult{Name: "geo " + geo.Name(), Detail: err.Error()})
+	} else {
+		results = append(results, SelfTestResult{Name: "geo " + geo.Name(), OK: true, Detail: fmt.Sprintf("%s -> %s", selfTestProbeIP, g.CountryCode)})
+	}
+
+	results = append(results, clockCheck(time.Now(), serverTime))
+	return results
+}
+
+// probeRDAP queries every configured registry once, concurrently. It also
+// returns the Date header of the first response, for the clock check.
+func (e *Extractor) probeRDAP() ([]SelfTestResult, time.Time) {
+	endpoints := e.rdapEndpointList()
+	client := &http.Client{Timeout: selfTestTimeout, Transport: e.apiClient.Transport}
+	results := make([]SelfTestResult, len(endpoints))
+	dates := make([]time.Time, len(endpoints))
+
+	var wg sync.WaitGroup
+	for i, base := range endpoints {
+		wg.Add(1)
+		go func(i int, base string) {
+			defer wg.Done()
+			name := "rdap " + registryName(base)
+			start := time.Now()
+			resp, err := client.Get(base + selfTestProbeIP)
+			if err != nil {
+				results[i] = SelfTestResult{Name: name, Detail: err.Error()}
+				return
+			}
+			resp.Body.Close()
+			if d, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+				dates[i] = d
+			}
+			// Any answer below 500 means the registry is up; 429 means we are
+			// throttled, which is worth reporting.
+			ok := resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests
+			results[i] = SelfTestResult{Name: name, OK: ok,
+				Detail: fmt.Sprintf("HTTP %d in %s", resp.StatusCode, time.Since(start).Round(time.Millisecond))}
+		}(i, base)
+	}
+	wg.Wait()
+
+	for _, d := range dates {
+		if !d.IsZero() {
+			return results, d
+		}
+	}
+	return results, time.Time{}
+}
+
+// registryName returns the registry name of an RDAP base URL, or its host.
+func registryName(base string) string {
+	for name, url := range rdapRegistryURLs {
+		if url == base {
+			return name
+		}
+	}
+	host := strings.TrimPrefix(strings.TrimPrefix(base, "https://"), "http://")
+	if i := strings.IndexByte(host, '/'); i >= 0 {
+		host = host[:i]
+	}
+	return host
+}
+
+// clockCheck compares the local time with a server's. A zero serverTime
+// means no registry answered with a usable Date header.
+func clockCheck(local, serverTime time.Time) SelfTestResult {
+	res := SelfTestResult{Name: "clock"}
+	if local.Year() < 2024 {
+		res.Detail = "local clock reads " + local.Format(time.RFC3339)
+		return res
+	}
+	if serverTime.IsZero() {
+		res.Detail = "no registry returned its time; local clock " + local.Format(time.RFC3339)
+		return res
+	}
+	skew := local.Sub(serverTime)
+	if skew < 0 {
+		skew = -skew
+	}
+	res.Detail = fmt.Sprintf("skew %s", skew.Round(time.Second))
+	res.OK = skew <= maxClockSkew
+	return res
+}
+
+// checkWritable verifies that dir exists or can be created and accepts files.
+func checkWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("cannot create %s: %w", dir, err)
+	}
+	f, err := os.CreateTemp(dir, ".selftest-*")
+	if err != nil {
+		return fmt.Errorf("%s is not writable: %w", dir, err)
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
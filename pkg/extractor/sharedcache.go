@@ -0,0 +1,190 @@
+package extractor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// sharedCacheBatch caps the IPs or entries sent in one shared cache request.
+const sharedCacheBatch = 1000
+
+// CacheEntries returns the unexpired RDAP cache entries of ips, for
+// instances sharing this one's cache. Unknown IPs are left out.
+func (e *Extractor) CacheEntries(ips []string) map[string]models.RDAPCacheEntry {
+	e.cacheMu.Lock()
+	defer e.cacheMu.Unlock()
+	cache := e.servedRDAPCache()
+	ttl, now := e.cacheTTL(), time.Now()
+	out := make(map[string]models.RDAPCacheEntry, len(ips))
+	for _, ip := range ips {
+		if entry, ok := cache.Entries[ip]; ok && now.Sub(entry.CachedAt) <= ttl {
+			out[ip] = entry
+		}
+	}
+	return out
+}
+
+// StoreCacheEntries adds entries looked up by another instance to the RDAP
+// cache. An entry older than the one already cached, or already expired, is
+// ignored. It returns the number stored, and an error when the cache could
+// not be saved.
+func (e *Extractor) StoreCacheEntries(entries map[string]models.RDAPCacheEntry) (int, error) {
+	e.cacheMu.Lock()
+	defer e.cacheMu.Unlock()
+	cache := e.servedRDAPCache()
+	stored := cache.merge(entries, e.cacheTTL(), time.Now())
+	if stored == 0 {
+		return 0, nil
+	}
+	err := cache.save()
+	// Read the file again next time, whether it was written or not
+	e.servedStamp, _ = statStamp(cache.Path)
+	if err != nil {
+		e.served = nil
+		return 0, fmt.Errorf("saving RDAP cache: %w", err)
+	}
+	e.logger.Info("Extractor", fmt.Sprintf("Cache RDAP partage: %d entrees recues", stored))
+	return stored, nil
+}
+
+// servedRDAPCache returns the RDAP cache served to the other instances,
+// kept in memory and read again only when the file changed since.
+// e.cacheMu must be held.
+func (e *Extractor) servedRDAPCache() *rdapCache {
+	stamp, err := statStamp(rdapCachePath)
+	if e.served != nil && err == nil && stamp == e.servedStamp {
+		return e.served
+	}
+	e.served = e.loadRDAPCache()
+	e.servedStamp, _ = statStamp(rdapCachePath)
+	return e.served
+}
+
+// merge adds the entries newer than those cached and not older than ttl,
+// and returns how many were added. An entry dated after now is taken as
+// cached now, so a wrong clock or a forged date cannot keep it from
+// expiring or make it win over later lookups.
+func (c *rdapCache) merge(entries map[string]models.RDAPCacheEntry, ttl time.Duration, now time.Time) int {
+	added := 0
+	for ip, entry := range entries {
+		if entry.CachedAt.After(now) {
+			entry.CachedAt = now
+		}
+		if ip == "" || entry.CachedAt.IsZero() || now.Sub(entry.CachedAt) > ttl {
+			continue
+		}
+		if cur, ok := c.Entries[ip]; ok && !entry.CachedAt.After(cur.CachedAt) {
+			continue
+		}
+		c.Entries[ip] = entry
+		added++
+	}
+	return added
+}
+
+// sharedCacheRequest is the body of the shared cache endpoints.
+type sharedCacheRequest struct {
+	IPs     []string                         `json:"ips,omitempty"`
+	Entries map[string]models.RDAPCacheEntry `json:"entries,omitempty"`
+}
+
+// pullSharedCache fills cache with the shared cache entries of the ips it
+// does not hold. Failures are logged: enrichment then queries the
+// registries as usual.
+func (e *Extractor) pullSharedCache(ctx context.Context, cache *rdapCache, ips []string) {
+	if e.settings().SharedCacheURL == "" {
+		return
+	}
+	var missing []string
+	for _, ip := range ips {
+		if _, ok := cache.Entries[ip]; !ok {
+			missing = append(missing, ip)
+		}
+	}
+	pulled := 0
+	for start := 0; start < len(missing); start += sharedCacheBatch {
+		end := start + sharedCacheBatch
+		if end > len(missing) {
+			end = len(missing)
+		}
+		var resp sharedCacheRequest
+		if err := e.sharedCacheCall(ctx, "/api/cache/lookup", sharedCacheRequest{IPs: missing[start:end]}, &resp); err != nil {
+			e.logger.Warning("Extractor", "Cache RDAP partage indisponible: "+err.Error())
+			return
+		}
+		pulled += cache.merge(resp.Entries, e.cacheTTL(), time.Now())
+	}
+	if len(missing) > 0 {
+		e.logger.Info("Extractor", fmt.Sprintf("Cache RDAP partage: %d/%d IPs trouvees", pulled, len(missing)))
+	}
+}
+
+// pushSharedCache sends the entries looked up during this run to the
+// shared cache. A canceled run sends nothing.
+func (e *Extractor) pushSharedCache(ctx context.Context, cache *rdapCache) {
+	if e.settings().SharedCacheURL == "" || len(cache.updated) == 0 || ctx.Err() != nil {
+		return
+	}
+	batch := map[string]models.RDAPCacheEntry{}
+	flush := func() bool {
+		if len(batch) == 0 {
+			return true
+		}
+		if err := e.sharedCacheCall(ctx, "/api/cache", sharedCacheRequest{Entries: batch}, nil); err != nil {
+			e.logger.Warning("Extractor", "Envoi au cache RDAP partage impossible: "+err.Error())
+			return false
+		}
+		batch = map[string]models.RDAPCacheEntry{}
+		return true
+	}
+	for ip := range cache.updated {
+		if entry, ok := cache.Entries[ip]; ok {
+			batch[ip] = entry
+		}
+		if len(batch) == sharedCacheBatch && !flush() {
+			return
+		}
+	}
+	if flush() {
+		e.logger.Info("Extractor", fmt.Sprintf("Cache RDAP partage: %d entrees envoyees", len(cache.updated)))
+	}
+}
+
+// sharedCacheCall POSTs body to path on the shared cache server and decodes
+// the answer into out, when not nil.
+func (e *Extractor) sharedCacheCall(ctx context.Context, path string, body sharedCacheRequest, out *sharedCacheRequest) error {
+	cfg := e.settings()
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(cfg.SharedCacheURL, "/")+path, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if cfg.SharedCacheKey != "" {
+		req.Header.Set("X-API-Key", cfg.SharedCacheKey)
+	}
+	resp, err := e.apiClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("http %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,155 @@
+package extractor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// tagRule is a models.TagRule with its conditions normalized for matching.
+type tagRule struct {
+	tags         []string
+	countries    map[string]bool
+	asns         map[string]bool
+	org          *regexp.Regexp
+	scannerTypes map[string]bool
+}
+
+// normalizeASN reduces "AS1234", "as1234" and "1234" to "1234".
+func normalizeASN(s string) string {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	return strings.TrimPrefix(s, "AS")
+}
+
+func normalizedSet(list []string, norm func(string) string) map[string]bool {
+	if len(list) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(list))
+	for _, v := range list {
+		if v = norm(v); v != "" {
+			set[v] = true
+		}
+	}
+	return set
+}
+
+// compileTagRule checks rule and prepares it for matching.
+func compileTagRule(rule models.TagRule) (tagRule, error) {
+	lower := func(s string) string { return strings.ToLower(strings.TrimSpace(s)) }
+	r := tagRule{
+		countries:    normalizedSet(rule.Countries, lower),
+		asns:         normalizedSet(rule.ASNs, normalizeASN),
+		scannerTypes: normalizedSet(rule.ScannerTypes, lower),
+	}
+	for _, t := range rule.Tags {
+		if t = strings.TrimSpace(t); t != "" {
+			r.tags = append(r.tags, t)
+		}
+	}
+	if len(r.tags) == 0 {
+		return r, fmt.Errorf("tag rule %q adds no tags", rule.Name)
+	}
+	if rule.OrgRegex != "" {
+		re, err := regexp.Compile(rule.OrgRegex)
+		if err != nil {
+			return r, fmt.Errorf("tag rule %q: invalid org_regex: %w", rule.Name, err)
+		}
+		r.org = re
+	}
+	if r.countries == nil && r.asns == nil && r.org == nil && r.scannerTypes == nil {
+		return r, fmt.Errorf("tag rule %q has no condition", rule.Name)
+	}
+	return r, nil
+}
+
+// ValidateTagRule reports why rule cannot be used, or nil.
+func ValidateTagRule(rule models.TagRule) error {
+	_, err := compileTagRule(rule)
+	return err
+}
+
+// matches reports whether item meets every condition of r.
+func (r tagRule) matches(item *models.ScannerData) bool {
+	if r.countries != nil && !r.countries[strings.ToLower(item.CountryCode)] {
+		return false
+	}
+	if r.asns != nil && !r.asns[normalizeASN(item.ASN)] {
+		return false
+	}
+	if r.scannerTypes != nil && !r.scannerTypes[strings.ToLower(string(item.ScannerType))] {
+		return false
+	}
+	if r.org != nil && !r.org.MatchString(item.RDAPName) && !r.org.MatchString(item.ASName) && !r.org.MatchString(item.ISP) {
+		return false
+	}
+	return true
+}
+
+// compileTagRules prepares the configured rules, logging and skipping the
+// invalid ones.
+func (e *Extractor) compileTagRules(rules []models.TagRule) []tagRule {
+	var out []tagRule
+	for _, rule := range rules {
+		r, err := compileTagRule(rule)
+		if err != nil {
+			e.logger.Warning("Extractor", "Regle de tag ignoree: "+err.Error())
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// rules returns the compiled auto-tagging rules.
+func (e *Extractor) rules() []tagRule {
+	e.configMu.RLock()
+	defer e.configMu.RUnlock()
+	return e.tagRules
+}
+
+// applyTagRules replaces the rule tags of item with those of the rules it
+// now matches. Tags the record already had before a rule matched are not
+// rule tags and are kept.
+func applyTagRules(item *models.ScannerData, rules []tagRule) {
+	if len(rules) == 0 && len(item.RuleTags) == 0 {
+		return
+	}
+	tags := item.Tags[:0:0]
+	for _, t := range item.Tags {
+		if !containsString(item.RuleTags, t) {
+			tags = append(tags, t)
+		}
+	}
+	var added []string
+	for _, r := range rules {
+		if !r.matches(item) {
+			continue
+		}
+		for _, t := range r.tags {
+			if !containsString(tags, t) {
+				tags = append(tags, t)
+				added = append(added, t)
+			}
+		}
+	}
+	item.Tags, item.RuleTags = tags, added
+}
+
+// ApplyTagRules evaluates the auto-tagging rules on every record of data.
+// Tags added by an earlier evaluation that no longer match are removed.
+func (e *Extractor) ApplyTagRules(data []models.ScannerData) {
+	rules := e.rules()
+	tagged := 0
+	for i := range data {
+		applyTagRules(&data[i], rules)
+		if len(data[i].RuleTags) > 0 {
+			tagged++
+		}
+	}
+	if len(rules) > 0 {
+		e.logger.Info("Extractor", fmt.Sprintf("Regles de tag: %d regles, %d enregistrements tagues", len(rules), tagged))
+	}
+}
@@ -0,0 +1,120 @@
+package extractor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// TimeField names a record date a time window can filter on.
+type TimeField string
+
+const (
+	TimeFieldRegistered  TimeField = "registered"   // RDAP registration event
+	TimeFieldLastChanged TimeField = "last_changed" // RDAP last changed event
+	TimeFieldFirstSeen   TimeField = "first_seen"
+	TimeFieldLastSeen    TimeField = "last_seen"
+)
+
+// TimeFields lists the fields accepted by a TimeWindow.
+func TimeFields() []TimeField {
+	return []TimeField{TimeFieldRegistered, TimeFieldLastChanged, TimeFieldFirstSeen, TimeFieldLastSeen}
+}
+
+// TimeWindow keeps the records whose Field date falls within the last
+// Within, e.g. netblocks registered in the last 90 days. The zero value
+// matches every record.
+type TimeWindow struct {
+	Field  TimeField
+	Within time.Duration
+}
+
+// ParseTimeWindow parses "field:duration", e.g. "registered:90d". Durations
+// take a d (days) or w (weeks) suffix besides the Go units.
+func ParseTimeWindow(s string) (TimeWindow, error) {
+	field, within, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return TimeWindow{}, fmt.Errorf("time window %q: expected field:duration, e.g. registered:90d", s)
+	}
+	w := TimeWindow{Field: TimeField(strings.ToLower(strings.TrimSpace(field)))}
+	if !validTimeField(w.Field) {
+		return TimeWindow{}, fmt.Errorf("time window %q: unknown field %q (available: registered, last_changed, first_seen, last_seen)", s, field)
+	}
+	d, err := parseWindowDuration(strings.TrimSpace(within))
+	if err != nil {
+		return TimeWindow{}, fmt.Errorf("time window %q: %w", s, err)
+	}
+	w.Within = d
+	return w, nil
+}
+
+func validTimeField(f TimeField) bool {
+	for _, known := range TimeFields() {
+		if f == known {
+			return true
+		}
+	}
+	return false
+}
+
+func parseWindowDuration(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.Atoi(n)
+			if err != nil || v <= 0 {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(v) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// RecordTime returns the date of field on item, and false when it is unset.
+func RecordTime(item models.ScannerData, field TimeField) (time.Time, bool) {
+	switch field {
+	case TimeFieldRegistered:
+		return item.EventRegistration, !item.EventRegistration.IsZero()
+	case TimeFieldLastChanged:
+		return item.EventLastChanged, !item.EventLastChanged.IsZero()
+	case TimeFieldFirstSeen:
+		return item.FirstSeen, !item.FirstSeen.IsZero()
+	case TimeFieldLastSeen:
+		return item.LastSeen, !item.LastSeen.IsZero()
+	}
+	return time.Time{}, false
+}
+
+// Match reports whether item's date lies between now-Within and now. Records
+// without that date never match a non-zero window.
+func (w TimeWindow) Match(item models.ScannerData, now time.Time) bool {
+	if w.Field == "" {
+		return true
+	}
+	t, ok := RecordTime(item, w.Field)
+	if !ok {
+		return false
+	}
+	return !t.Before(now.Add(-w.Within)) && !t.After(now)
+}
+
+// Filter returns the records matched by w, in dataset order.
+func (w TimeWindow) Filter(data []models.ScannerData, now time.Time) []models.ScannerData {
+	if w.Field == "" {
+		return data
+	}
+	var out []models.ScannerData
+	for _, item := range data {
+		if w.Match(item, now) {
+			out = append(out, item)
+		}
+	}
+	return out
+}
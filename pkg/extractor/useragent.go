@@ -0,0 +1,57 @@
+package extractor
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// Version is the application version advertised in the default User-Agent.
+// The application sets it at startup.
+var Version = "1.0.0"
+
+// UserAgent returns the User-Agent sent with config: the configured product
+// token, or LiaCheckScanner/<Version>, followed by the operator contact when
+// one is set, e.g. "LiaCheckScanner/1.0.0 (+mailto:noc@example.org)".
+func UserAgent(config models.DatabaseConfig) string {
+	agent := strings.TrimSpace(config.UserAgent)
+	if agent == "" {
+		agent = "LiaCheckScanner/" + Version
+	}
+	if contact := strings.TrimSpace(config.Contact); contact != "" {
+		if strings.Contains(contact, "@") && !strings.Contains(contact, ":") {
+			contact = "mailto:" + contact
+		}
+		agent += " (+" + contact + ")"
+	}
+	return agent
+}
+
+// userAgentTransport sets the extractor's current User-Agent on every
+// request that does not already carry one.
+type userAgentTransport struct {
+	e    *Extractor
+	base http.RoundTripper
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if req.Header.Get("User-Agent") != "" {
+		return base.RoundTrip(req)
+	}
+	// A RoundTripper must not modify the caller's request
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", UserAgent(t.e.settings()))
+	return base.RoundTrip(req)
+}
+
+// HTTPClient returns the client the extractor uses for its RDAP,
+// geolocation and PeeringDB requests, so other lookups share its timeout
+// and User-Agent.
+func (e *Extractor) HTTPClient() *http.Client {
+	return e.apiClient
+}
@@ -0,0 +1,300 @@
+package extractor
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lia/liacheckscanner_go/pkg/models"
+)
+
+// xlsxAllSheet is the sheet holding every record, before the per-scanner
+// sheets.
+const xlsxAllSheet = "All"
+
+// xlsxMaxCell is the longest text an Excel cell holds.
+const xlsxMaxCell = 32767
+
+// xlsxNumericColumns are the CSVHeaders columns written as numbers, so they
+// sort and filter numerically.
+var xlsxNumericColumns = map[string]bool{
+	"Abuse Confidence Score": true,
+	"Abuse Reports":          true,
+	"Runs Seen":              true,
+	"Latitude":               true,
+	"Longitude":              true,
+}
+
+// xlsxSheet is one worksheet of the workbook and the records it lists.
+type xlsxSheet struct {
+	name string
+	data []models.ScannerData
+}
+
+// SaveToXLSX writes data to an Excel workbook in the configured results
+// directory: an "All" sheet with every record, then one sheet per scanner.
+// Each sheet has the CSVHeaders columns (and Provenance with
+// ExportProvenance), a bold frozen header row and an auto-filter. The
+// workbook is written with the standard library, as a zip of SpreadsheetML
+// parts.
+func (e *Extractor) SaveToXLSX(data []models.ScannerData, filename string) error {
+	e.logger.Info("Extractor", "Sauvegarde en XLSX...")
+
+	if err := os.MkdirAll(e.settings().ResultsDir, 0755); err != nil {
+		return fmt.Errorf("creating results directory: %w", err)
+	}
+	filePath := filepath.Join(e.settings().ResultsDir, filename)
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("creating XLSX file %s: %w", filePath, err)
+	}
+	if err := writeXLSX(file, data, e.settings().ExportProvenance); err != nil {
+		file.Close()
+		return fmt.Errorf("writing XLSX file %s: %w", filePath, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("closing XLSX file %s: %w", filePath, err)
+	}
+
+	e.logger.Info("Extractor", fmt.Sprintf("Donnees sauvegardees: %s", filePath))
+	return nil
+}
+
+// xlsxSheets splits data into the "All" sheet and one sheet per scanner,
+// ordered by scanner name, with valid and unique sheet names.
+func xlsxSheets(data []models.ScannerData) []xlsxSheet {
+	byScanner := map[string][]models.ScannerData{}
+	for _, item := range data {
+		byScanner[item.ScannerName] = append(byScanner[item.ScannerName], item)
+	}
+	scanners := make([]string, 0, len(byScanner))
+	for name := range byScanner {
+		scanners = append(scanners, name)
+	}
+	sort.Strings(scanners)
+
+	sheets := []xlsxSheet{{name: xlsxAllSheet, data: data}}
+	used := map[string]bool{strings.ToLower(xlsxAllSheet): true}
+	for _, scanner := range scanners {
+		name := xlsxSheetName(scanner)
+		base := name
+		for n := 2; used[strings.ToLower(name)]; n++ {
+			suffix := fmt.Sprintf(" (%d)", n)
+			name = truncateRunes(base, 31-len(suffix)) + suffix
+		}
+		used[strings.ToLower(name)] = true
+		sheets = append(sheets, xlsxSheet{name: name, data: byScanner[scanner]})
+	}
+	return sheets
+}
+
+// xlsxSheetName makes a valid sheet name of s: at most 31 characters, none
+// of []:*?/\ and not starting or ending with an apostrophe.
+func xlsxSheetName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) || r < 0x20 {
+			return '_'
+		}
+		return r
+	}, s)
+	s = strings.Trim(truncateRunes(strings.TrimSpace(s), 31), "'")
+	if s == "" {
+		return "Unknown"
+	}
+	return s
+}
+
+// truncateRunes returns the first n runes of s.
+func truncateRunes(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
+
+// xlsxColumn returns the letters naming the 0-based column i: A, ..., Z, AA.
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// writeXLSX writes the workbook of data to w.
+func writeXLSX(w io.Writer, data []models.ScannerData, provenance bool) error {
+	headers := models.CSVHeaders
+	if provenance {
+		headers = append(append([]string(nil), headers...), "Provenance")
+	}
+	sheets := xlsxSheets(data)
+
+	zw := zip.NewWriter(w)
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes(len(sheets))},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook(sheets, len(headers))},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels(len(sheets))},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, p := range parts {
+		pw, err := zw.Create(p.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(pw, p.content); err != nil {
+			return err
+		}
+	}
+	for i, s := range sheets {
+		pw, err := zw.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1))
+		if err != nil {
+			return err
+		}
+		if err := writeXLSXSheet(pw, headers, s.data, provenance); err != nil {
+			return fmt.Errorf("sheet %s: %w", s.name, err)
+		}
+	}
+	return zw.Close()
+}
+
+// writeXLSXSheet writes the worksheet listing data, its header row frozen,
+// styled and filtered.
+func writeXLSXSheet(w io.Writer, headers []string, data []models.ScannerData, provenance bool) error {
+	bw := bufio.NewWriter(w)
+	last := xlsxColumn(len(headers) - 1)
+	fmt.Fprint(bw, xml.Header)
+	fmt.Fprint(bw, `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	fmt.Fprint(bw, `<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	fmt.Fprintf(bw, `<cols><col min="1" max="%d" width="18" customWidth="1"/></cols>`, len(headers))
+	fmt.Fprint(bw, `<sheetData>`)
+
+	fmt.Fprint(bw, `<row r="1">`)
+	for c, h := range headers {
+		writeXLSXText(bw, fmt.Sprintf("%s1", xlsxColumn(c)), h, 1)
+	}
+	fmt.Fprint(bw, `</row>`)
+	for i, item := range data {
+		r := i + 2
+		row := models.ScannerDataToCSVRow(item)
+		if provenance {
+			row = append(row, models.FormatProvenance(item.Provenance))
+		}
+		fmt.Fprintf(bw, `<row r="%d">`, r)
+		for c, v := range row {
+			if v == "" {
+				continue
+			}
+			ref := fmt.Sprintf("%s%d", xlsxColumn(c), r)
+			if xlsxNumericColumns[headers[c]] {
+				if _, err := strconv.ParseFloat(v, 64); err == nil {
+					fmt.Fprintf(bw, `<c r="%s"><v>%s</v></c>`, ref, v)
+					continue
+				}
+			}
+			writeXLSXText(bw, ref, v, 0)
+		}
+		fmt.Fprint(bw, `</row>`)
+	}
+
+	fmt.Fprint(bw, `</sheetData>`)
+	fmt.Fprintf(bw, `<autoFilter ref="A1:%s%d"/>`, last, len(data)+1)
+	fmt.Fprint(bw, `</worksheet>`)
+	return bw.Flush()
+}
+
+// writeXLSXText writes an inline text cell, with style 1 for headers.
+// Characters XML cannot hold are replaced, and text beyond what a cell holds
+// is cut.
+func writeXLSXText(w io.Writer, ref, text string, style int) {
+	fmt.Fprintf(w, `<c r="%s" t="inlineStr"`, ref)
+	if style != 0 {
+		fmt.Fprintf(w, ` s="%d"`, style)
+	}
+	fmt.Fprint(w, `><is><t xml:space="preserve">`)
+	_ = xml.EscapeText(w, []byte(truncateRunes(text, xlsxMaxCell)))
+	fmt.Fprint(w, `</t></is></c>`)
+}
+
+// xlsxEscape returns s escaped for an XML attribute.
+func xlsxEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func xlsxContentTypes(sheets int) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	b.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := 1; i <= sheets; i++ {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i)
+	}
+	b.WriteString(`</Types>`)
+	return b.String()
+}
+
+const xlsxRootRels = xml.Header +
+	`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+// xlsxWorkbook lists the sheets and the filter range of each, which Excel
+// keeps as a hidden defined name.
+func xlsxWorkbook(sheets []xlsxSheet, columns int) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, s := range sheets {
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xlsxEscape(s.name), i+1, i+1)
+	}
+	b.WriteString(`</sheets><definedNames>`)
+	last := xlsxColumn(columns - 1)
+	for i, s := range sheets {
+		quoted := "'" + strings.ReplaceAll(s.name, "'", "''") + "'"
+		fmt.Fprintf(&b, `<definedName name="_xlnm._FilterDatabase" localSheetId="%d" hidden="1">%s!$A$1:$%s$%d</definedName>`,
+			i, xlsxEscape(quoted), last, len(s.data)+1)
+	}
+	b.WriteString(`</definedNames></workbook>`)
+	return b.String()
+}
+
+func xlsxWorkbookRels(sheets int) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := 1; i <= sheets; i++ {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i, i)
+	}
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, sheets+1)
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+// xlsxStyles defines style 0, the default, and style 1, the bold white on
+// blue header.
+const xlsxStyles = xml.Header +
+	`<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><color rgb="FFFFFFFF"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="3"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill>` +
+	`<fill><patternFill patternType="solid"><fgColor rgb="FF1F4E78"/><bgColor indexed="64"/></patternFill></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="2" borderId="0" xfId="0" applyFont="1" applyFill="1"/></cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
@@ -0,0 +1,4 @@
+// Package models defines the data types shared by the LiaCheckScanner
+// extractor, application and exporters: scanner records, cache and progress
+// entries, configuration and log structures.
+package models
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Provenance groups: the sets of enriched fields whose source is recorded.
+const (
+	ProvenanceRDAP      = "rdap"      // RDAP name, handle, range, events, contacts
+	ProvenanceGeo       = "geo"       // country, ISP, ASN from geolocation
+	ProvenanceDNS       = "dns"       // reverse DNS from the system resolver
+	ProvenancePeeringDB = "peeringdb" // network type, traffic level, contacts
+	ProvenancePorts     = "ports"     // open ports, host names, CPEs from InternetDB
+)
+
+// SetProvenance records that provider produced the fields of group at time
+// at, as "provider@<RFC 3339 date>".
+func (d *ScannerData) SetProvenance(group, provider string, at time.Time) {
+	if d.Provenance == nil {
+		d.Provenance = map[string]string{}
+	}
+	d.Provenance[group] = provider + "@" + FormatTimestamp(at)
+}
+
+// FormatProvenance formats provenance as "geo:ip-api@2024-05-01T10:00:00Z,
+// rdap:ripe@...", groups in alphabetical order.
+func FormatProvenance(provenance map[string]string) string {
+	groups := make([]string, 0, len(provenance))
+	for g := range provenance {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+	parts := make([]string, len(groups))
+	for i, g := range groups {
+		parts[i] = g + ":" + provenance[g]
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ParseProvenance reads the output of FormatProvenance. It returns nil for an
+// empty string.
+func ParseProvenance(s string) map[string]string {
+	var provenance map[string]string
+	for _, part := range strings.Split(s, ",") {
+		group, source, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok || group == "" {
+			continue
+		}
+		if provenance == nil {
+			provenance = map[string]string{}
+		}
+		provenance[group] = source
+	}
+	return provenance
+}
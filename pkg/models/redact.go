@@ -0,0 +1,42 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ScannerDataField returns the ScannerData field whose JSON name is name,
+// e.g. "abuse_email" for AbuseEmail.
+func ScannerDataField(name string) (reflect.StructField, bool) {
+	t := reflect.TypeOf(ScannerData{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if tag := strings.Split(f.Tag.Get("json"), ",")[0]; tag != "" && tag != "-" && tag == name {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// Validate checks that r names a ScannerData field and an action applying to
+// it: any field can be removed, text fields and lists of text can also be
+// hashed, masked or reduced to their domain.
+func (r RedactionRule) Validate() error {
+	f, ok := ScannerDataField(r.Field)
+	if !ok {
+		return fmt.Errorf("unknown field %q", r.Field)
+	}
+	switch r.Action {
+	case RedactRemove:
+		return nil
+	case RedactHash, RedactMask, RedactDomain:
+		k := f.Type.Kind()
+		if k == reflect.String || (k == reflect.Slice && f.Type.Elem().Kind() == reflect.String) {
+			return nil
+		}
+		return fmt.Errorf("field %q is not text and can only be removed", r.Field)
+	default:
+		return fmt.Errorf("unknown action %q (want %s, %s, %s or %s)", r.Action, RedactRemove, RedactHash, RedactMask, RedactDomain)
+	}
+}
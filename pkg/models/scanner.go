@@ -0,0 +1,797 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScannerType represents the type of internet scanner as a string constant.
+type ScannerType string
+
+const (
+	// ScannerTypeUnknown represents an unidentified scanner type.
+	ScannerTypeUnknown ScannerType = "unknown"
+	// ScannerTypeShodan represents the Shodan internet scanner.
+	ScannerTypeShodan ScannerType = "shodan"
+	// ScannerTypeCensys represents the Censys internet scanner.
+	ScannerTypeCensys ScannerType = "censys"
+	// ScannerTypeBinaryEdge represents the BinaryEdge internet scanner.
+	ScannerTypeBinaryEdge ScannerType = "binaryedge"
+	// ScannerTypeRapid7 represents the Rapid7 internet scanner.
+	ScannerTypeRapid7 ScannerType = "rapid7"
+	// ScannerTypeShadowServer represents the ShadowServer internet scanner.
+	ScannerTypeShadowServer ScannerType = "shadowserver"
+	// ScannerTypeOther represents any other scanner type not specifically categorized.
+	ScannerTypeOther ScannerType = "other"
+)
+
+// ScannerData represents a single scanner record with IP information, RDAP details, geolocation, and risk assessment.
+type ScannerData struct {
+	ID                   string      `json:"id" csv:"ID"`
+	IPOrCIDR             string      `json:"ip_or_cidr" csv:"IP/CIDR"`
+	ScannerName          string      `json:"scanner_name" csv:"Scanner Name"`
+	ScannerType          ScannerType `json:"scanner_type" csv:"Scanner Type"`
+	SourceFile           string      `json:"source_file" csv:"Source File"`
+	CountryCode          string      `json:"country_code" csv:"Country Code"`
+	CountryName          string      `json:"country_name" csv:"Country Name"`
+	City                 string      `json:"city" csv:"City"`
+	ISP                  string      `json:"isp" csv:"ISP"`
+	Organization         string      `json:"organization" csv:"Organization"`
+	AbuseConfidenceScore int         `json:"abuse_confidence_score" csv:"Abuse Confidence Score"`
+	AbuseReports         int         `json:"abuse_reports" csv:"Abuse Reports"`
+	UsageType            string      `json:"usage_type" csv:"Usage Type"`
+	Domain               string      `json:"domain" csv:"Domain"`
+	// RDAP / WHOIS-like details
+	RDAPName          string    `json:"rdap_name" csv:"RDAP Name"`
+	RDAPHandle        string    `json:"rdap_handle" csv:"RDAP Handle"`
+	RDAPCIDR          string    `json:"rdap_cidr" csv:"RDAP CIDR"`
+	Registry          string    `json:"registry" csv:"RDAP Registry"`
+	StartAddress      string    `json:"start_address" csv:"Start Address"`
+	EndAddress        string    `json:"end_address" csv:"End Address"`
+	IPVersion         string    `json:"ip_version" csv:"IP Version"`
+	RDAPType          string    `json:"rdap_type" csv:"RDAP Type"`
+	ParentHandle      string    `json:"parent_handle" csv:"Parent Handle"`
+	EventRegistration time.Time `json:"event_registration" csv:"Event Registration"`
+	EventLastChanged  time.Time `json:"event_last_changed" csv:"Event Last Changed"`
+	// Owner before the last RDAP lookup found a different one (see
+	// extractor.OwnerLabel); empty when ownership did not change
+	PreviousOwner string `json:"previous_owner,omitempty" csv:"Previous Owner"`
+	// ASN
+	ASN    string `json:"asn" csv:"ASN"`
+	ASName string `json:"as_name" csv:"AS Name"`
+	// DNS reverse
+	ReverseDNS string `json:"reverse_dns" csv:"Reverse DNS"`
+	// PeeringDB network details for the ASN
+	PeeringDBName     string `json:"peeringdb_name" csv:"PeeringDB Name"`
+	NetworkType       string `json:"network_type" csv:"Network Type"`
+	TrafficLevel      string `json:"traffic_level" csv:"Traffic Level"`
+	PeeringDBContacts string `json:"peeringdb_contacts" csv:"PeeringDB Contacts"`
+	// Greylisting lifecycle
+	State    LifecycleState `json:"state" csv:"State"`
+	RunsSeen int            `json:"runs_seen" csv:"Runs Seen"`
+	// Contacts
+	AbuseEmail string    `json:"abuse_email" csv:"Abuse Email"`
+	TechEmail  string    `json:"tech_email" csv:"Tech Email"`
+	LastSeen   time.Time `json:"last_seen" csv:"Last Seen"`
+	FirstSeen  time.Time `json:"first_seen" csv:"First Seen"`
+	Tags       []string  `json:"tags" csv:"Tags"`
+	Notes      string    `json:"notes" csv:"Notes"`
+	RiskLevel  string    `json:"risk_level" csv:"Risk Level"`
+	ExportDate time.Time `json:"export_date" csv:"Export Date"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	// GeoSources records which geolocation provider supplied each field.
+	GeoSources map[string]string `json:"geo_sources,omitempty"`
+	// Provenance maps each enriched field group (ProvenanceRDAP, ...) to the
+	// provider that produced it and when, as "provider@date".
+	Provenance map[string]string `json:"provenance,omitempty"`
+	// Annotations are the attributed tags/notes added by analysts through the API.
+	Annotations []Annotation `json:"annotations,omitempty"`
+	// Lock is set while the IP is under investigation: aging, re-enrichment
+	// and bulk deletions leave the record as it is until it is unlocked.
+	Lock *RecordLock `json:"lock,omitempty"`
+	// HitCount and LastHit summarize the honeypot hits from this IP or range.
+	HitCount int       `json:"hit_count,omitempty"`
+	LastHit  time.Time `json:"last_hit"`
+	// RiskDecay is the share of risk lost since the IP was last seen (0 =
+	// fresh); Stale records are past the grace period and kept out of
+	// enforcement exports
+	RiskDecay float64 `json:"risk_decay,omitempty"`
+	Stale     bool    `json:"stale,omitempty"`
+	// BroadPrefix marks a range broader than the configured threshold:
+	// BroadPrefixFlagged, or BroadPrefixExcluded from enforcement exports
+	BroadPrefix string `json:"broad_prefix,omitempty"`
+	// RuleTags are the Tags added by the auto-tagging rules, replaced each
+	// time the rules are evaluated
+	RuleTags []string `json:"rule_tags,omitempty"`
+	// AttributedScanner is the scanner the reverse DNS or the ASN of a record
+	// from a feed naming no known scanner points to (see
+	// extractor.AttributeScanners)
+	AttributedScanner string `json:"attributed_scanner,omitempty"`
+	// AttributionConfidence says how the scanner of the record is known:
+	// ConfidenceHigh when the feed names it, ConfidenceMedium from reverse
+	// DNS, ConfidenceLow from the ASN heuristic. ConfidenceHold records are
+	// below min_enforcement_confidence and kept out of enforcement exports
+	AttributionConfidence string `json:"attribution_confidence,omitempty"`
+	ConfidenceHold        bool   `json:"confidence_hold,omitempty"`
+
+	// FeedTrust is the trust level of the feed the scanner name was taken
+	// from; AttributionReason says why that feed won when several feeds
+	// name different scanners for the IP
+	FeedTrust         int    `json:"feed_trust,omitempty"`
+	AttributionReason string `json:"attribution_reason,omitempty"`
+
+	// PolicyHold says why the enforcement policy keeps the record out of
+	// enforcement exports, e.g. "country FR excluded"; "" when it does not
+	PolicyHold string `json:"policy_hold,omitempty"`
+
+	// Open ports, host names and CPEs Shodan's InternetDB saw on the IP
+	OpenPorts []int    `json:"open_ports,omitempty" csv:"Open Ports"`
+	Hostnames []string `json:"hostnames,omitempty" csv:"Hostnames"`
+	CPEs      []string `json:"cpes,omitempty" csv:"CPEs"`
+
+	// TargetPorts are the ports the scanner was seen probing, listed by
+	// its feeds ("203.0.113.5:22") or hit on the honeypots
+	TargetPorts []int `json:"target_ports,omitempty" csv:"Target Ports"`
+
+	// Latitude and Longitude locate the IP, as found by geolocation; both
+	// are 0 when it is not located (see HasLocation)
+	Latitude  float64 `json:"latitude,omitempty" csv:"Latitude"`
+	Longitude float64 `json:"longitude,omitempty" csv:"Longitude"`
+
+	// Continent ("Europe"), its two-letter code ("EU") and region (state or
+	// province) of the IP, as found by geolocation
+	Continent     string `json:"continent,omitempty" csv:"Continent"`
+	ContinentCode string `json:"continent_code,omitempty" csv:"Continent Code"`
+	Region        string `json:"region,omitempty" csv:"Region"`
+}
+
+// HasLocation reports whether geolocation located the IP of d.
+func (d ScannerData) HasLocation() bool {
+	return d.Latitude != 0 || d.Longitude != 0
+}
+
+// BroadPrefix values.
+const (
+	BroadPrefixFlagged  = "flagged"
+	BroadPrefixExcluded = "excluded"
+)
+
+// AttributionConfidence values.
+const (
+	ConfidenceHigh   = "high"
+	ConfidenceMedium = "medium"
+	ConfidenceLow    = "low"
+)
+
+// Annotation is a tag and/or note added to an IP by one analyst. Annotations
+// are append-only and identified by ID, so stores from several users can be
+// merged without conflicts.
+type Annotation struct {
+	ID        string   `json:"id"`
+	IP        string   `json:"ip"`
+	Author    string   `json:"author"`
+	Tags      []string `json:"tags,omitempty"`
+	Note      string   `json:"note,omitempty"`
+	CreatedAt string   `json:"created_at"`
+}
+
+// RecordLock marks an IP as under investigation, by whom, when and why.
+type RecordLock struct {
+	IP       string `json:"ip"`
+	Author   string `json:"author"`
+	Reason   string `json:"reason,omitempty"`
+	LockedAt string `json:"locked_at"`
+}
+
+// Hit is one connection seen by the user's honeypots.
+type Hit struct {
+	IP        string    `json:"ip"`
+	Timestamp time.Time `json:"timestamp"`
+	Port      int       `json:"port,omitempty"`
+}
+
+// RDAPCacheEntry stores cached RDAP and geolocation lookup results for a single IP address.
+type RDAPCacheEntry struct {
+	RDAPName          string    `json:"rdap_name"`
+	RDAPHandle        string    `json:"rdap_handle"`
+	RDAPCIDR          string    `json:"rdap_cidr"`
+	Registry          string    `json:"registry"`
+	StartAddress      string    `json:"start_address"`
+	EndAddress        string    `json:"end_address"`
+	IPVersion         string    `json:"ip_version"`
+	RDAPType          string    `json:"rdap_type"`
+	ParentHandle      string    `json:"parent_handle"`
+	EventRegistration time.Time `json:"event_registration"`
+	EventLastChanged  time.Time `json:"event_last_changed"`
+	ASN               string    `json:"asn"`
+	ASName            string    `json:"as_name"`
+	ReverseDNS        string    `json:"reverse_dns"`
+	CountryCode       string    `json:"country_code"`
+	CountryName       string    `json:"country_name"`
+	City              string    `json:"city,omitempty"`
+	ISP               string    `json:"isp"`
+	Organization      string    `json:"organization"`
+	AbuseEmail        string    `json:"abuse_email"`
+	TechEmail         string    `json:"tech_email"`
+	CachedAt          time.Time `json:"cached_at"`
+
+	// Latitude and Longitude keep the location of the lookup cached here
+	Latitude  float64 `json:"latitude,omitempty"`
+	Longitude float64 `json:"longitude,omitempty"`
+	// Continent, ContinentCode and Region keep the geography of the lookup
+	Continent     string `json:"continent,omitempty"`
+	ContinentCode string `json:"continent_code,omitempty"`
+	Region        string `json:"region,omitempty"`
+
+	GeoSources map[string]string `json:"geo_sources,omitempty"`
+	// PreviousOwner keeps the ownership change flag of the lookup cached here
+	PreviousOwner string `json:"previous_owner,omitempty"`
+	// Provenance keeps the sources and dates of the lookup cached here
+	Provenance map[string]string `json:"provenance,omitempty"`
+}
+
+// SourceRevision is the upstream state a sync left the repository at.
+type SourceRevision struct {
+	RepoURL  string    `json:"repo_url"`
+	Ref      string    `json:"ref,omitempty"` // pinned commit or tag, "" for the default branch
+	Commit   string    `json:"commit"`
+	SyncedAt time.Time `json:"synced_at"`
+}
+
+// ArchiveRevision identifies the copy of an archive source a run read.
+type ArchiveRevision struct {
+	Source string `json:"source"`
+	SHA256 string `json:"sha256"`
+}
+
+// RunMetadata is saved next to each run and exported list, so it can be
+// traced back to the upstream state it was built from.
+type RunMetadata struct {
+	Name      string            `json:"name"`
+	CreatedAt time.Time         `json:"created_at"`
+	Records   int               `json:"records"`
+	Source    *SourceRevision   `json:"source,omitempty"`
+	Archives  []ArchiveRevision `json:"archives,omitempty"`
+	// Integrity is the hash of the file as written, checked when it is loaded
+	Integrity *DatasetIntegrity `json:"integrity,omitempty"`
+}
+
+// DatasetIntegrity is a Merkle tree over the lines of a result file: each
+// chunk of lines is hashed with SHA-256, and the chunk hashes are combined
+// pairwise up to Root. The chunk hashes locate the lines that changed.
+type DatasetIntegrity struct {
+	Algorithm string   `json:"algorithm"`
+	ChunkRows int      `json:"chunk_rows"`
+	Rows      int      `json:"rows"`
+	Root      string   `json:"root"`
+	Chunks    []string `json:"chunks"`
+}
+
+// RDAPProgressTracker tracks the state of a batch RDAP enrichment process, enabling resume after interruption.
+type RDAPProgressTracker struct {
+	TotalRecords     int                    `json:"total_records"`
+	ProcessedRecords int                    `json:"processed_records"`
+	ProcessedIPs     []string               `json:"processed_ips"`
+	ProcessedIPSet   map[string]struct{}    `json:"-"` // derived from ProcessedIPs for O(1) lookup
+	StartedAt        string                 `json:"started_at"`
+	LastUpdatedAt    string                 `json:"last_updated_at"`
+	Workers          int                    `json:"workers"`
+	Throttle         float64                `json:"throttle"`
+	Completed        bool                   `json:"completed"`
+}
+
+// LifecycleState is the greylisting stage of a record. Records only reach
+// enforcement exports once they are blocked.
+type LifecycleState string
+
+const (
+	// StateObserved marks an IP seen for the first time(s); it is not enforced yet.
+	StateObserved LifecycleState = "observed"
+	// StateCandidate marks an IP that persisted long enough to be reviewed for blocking.
+	StateCandidate LifecycleState = "candidate"
+	// StateBlocked marks an IP that is pushed to enforcement exports.
+	StateBlocked LifecycleState = "blocked"
+	// StateRetired marks an IP that disappeared from the sources for long enough.
+	StateRetired LifecycleState = "retired"
+)
+
+// LifecycleEntry is the persisted greylisting history of one IP across runs.
+type LifecycleEntry struct {
+	State      LifecycleState `json:"state"`
+	RunsSeen   int            `json:"runs_seen"`   // consecutive runs the IP was present
+	MissedRuns int            `json:"missed_runs"` // consecutive runs the IP was absent
+	FirstSeen  string         `json:"first_seen"`
+	LastSeen   string         `json:"last_seen"`
+	Pinned     bool           `json:"pinned"` // manual override: state is not changed automatically
+}
+
+// DatabaseConfig holds settings for repository access, API configuration, and data storage paths.
+type DatabaseConfig struct {
+	RepoURL        string   `json:"repo_url"`
+	LocalPath      string   `json:"local_path"`
+	ResultsDir     string   `json:"results_dir"`
+	LogsDir        string   `json:"logs_dir"`
+	APIKey         string   `json:"api_key"`
+	EnableAPI      bool     `json:"enable_api"`
+	APIThrottle    float64  `json:"api_throttle"`
+	Parallelism    int      `json:"parallelism"`
+	Registries     []string `json:"registries"`
+	AutoUpdate     bool     `json:"auto_update"`
+	UpdateInterval int      `json:"update_interval"`
+	CacheTTLHours  int      `json:"cache_ttl_hours"`
+	IPAPIKey       string   `json:"ipapi_key"`       // ip-api.com pro key; enables the HTTPS endpoint
+	GeoProvider    string   `json:"geo_provider"`    // "ip-api" (default), "ipinfo" or "ipdata"
+	IPInfoToken    string   `json:"ipinfo_token"`    // ipinfo.io access token
+	IPInfoThrottle float64  `json:"ipinfo_throttle"` // seconds between ipinfo.io requests
+	IPDataKey      string   `json:"ipdata_key"`      // ipdata.co API key
+	IPDataThrottle float64  `json:"ipdata_throttle"` // seconds between ipdata.co requests
+	GeoProviders   []string `json:"geo_providers"`   // ordered failover chain; overrides GeoProvider
+	MaxMindDB      string   `json:"maxmind_db"`      // path to a GeoLite2/GeoIP2 City .mmdb file
+	MaxMindASNDB   string   `json:"maxmind_asn_db"`  // optional path to a GeoLite2 ASN .mmdb file
+	MaxRetries     int      `json:"max_retries"`     // HTTP retries on errors, 429 and 5xx (0 = default 3)
+	BatchSize      int      `json:"batch_size"`      // records between progress checkpoints (0 = default 10)
+	Preset         string   `json:"preset"`          // performance preset last applied, informational
+
+	// Commit or tag the repository is pinned to, "" to follow its default
+	// branch
+	RepoRef string `json:"repo_ref"`
+
+	// Fetch only the last commit of an unpinned repository
+	ShallowClone bool `json:"shallow_clone"`
+
+	// ArchiveSources are .zip, .tar, .tar.gz or .tgz archives of .nft
+	// lists, by http(s) URL (downloaded on each sync) or local path, parsed
+	// along with the repository
+	ArchiveSources []string `json:"archive_sources"`
+
+	// Concurrent RDAP requests allowed per registry, whatever the
+	// parallelism (0 = default 4)
+	RDAPRegistryConcurrency int `json:"rdap_registry_concurrency"`
+
+	// RDAP lookups go first to the registry owning the IP in the IANA
+	// bootstrap files, downloaded every RDAPBootstrapDays (0 = default 7)
+	DisableRDAPBootstrap bool `json:"disable_rdap_bootstrap"`
+	RDAPBootstrapDays    int  `json:"rdap_bootstrap_days"`
+
+	// Requests in flight to each provider are cut when its 429/5xx rate
+	// climbs and raised again when healthy, between AutoscaleMinWorkers
+	// (0 = 1) and Parallelism, unless DisableAutoscale is set
+	AutoscaleMinWorkers int  `json:"autoscale_min_workers"`
+	DisableAutoscale    bool `json:"disable_autoscale"`
+
+	// Extraction-only runs: map IPs to their scanners and skip RDAP and
+	// geolocation entirely
+	SkipEnrichment bool `json:"skip_enrichment"`
+
+	// Adds a Provenance column (sources and dates of the enriched fields)
+	// to CSV exports
+	ExportProvenance bool `json:"export_provenance"`
+
+	// User-Agent sent on every RDAP, geolocation and PeeringDB request:
+	// product token (empty = LiaCheckScanner/<version>) and the operator
+	// contact URL or email registries can reach
+	UserAgent string `json:"user_agent"`
+	Contact   string `json:"contact"`
+
+	// Resolver for reverse lookups, API host names and feed downloads:
+	// DNS servers ("ip" or "ip:port"), tried in turn, or a DNS-over-HTTPS
+	// URL, which wins when both are set. Empty uses the system resolver
+	DNSServers []string `json:"dns_servers,omitempty"`
+	DoHURL     string   `json:"doh_url"`
+
+	// Per-run aggregate metrics for Grafana: InfluxDB v2 write URL (with
+	// org, bucket and precision=ns) and token, and Prometheus pushgateway
+	// base URL. Empty disables each
+	MetricsInfluxURL      string `json:"metrics_influx_url"`
+	MetricsInfluxToken    string `json:"metrics_influx_token"`
+	MetricsPushgatewayURL string `json:"metrics_pushgateway_url"`
+
+	// Bulk SQL exports, upserted by IP into SQLTable (empty =
+	// scanner_records): PostgreSQL connection string for psql, and
+	// ClickHouse HTTP URL with credentials and database
+	PostgresDSN   string `json:"postgres_dsn"`
+	ClickHouseURL string `json:"clickhouse_url"`
+	SQLTable      string `json:"sql_table"`
+
+	// Another instance's REST API to pull the dataset from instead of
+	// extracting it (thin clients of a central enrichment server), and
+	// its viewer key
+	RemoteAPIURL string `json:"remote_api_url"`
+	RemoteAPIKey string `json:"remote_api_key"`
+
+	// Team RDAP cache on another instance's API: entries missing locally
+	// are fetched from it before querying the registries, and new lookups
+	// are sent back. The key needs the analyst role
+	SharedCacheURL string `json:"shared_cache_url"`
+	SharedCacheKey string `json:"shared_cache_key"`
+
+	// Per-run enrichment budgets, 0 for none: once one is spent the run
+	// stops, keeps what it enriched and records the remaining IPs
+	MaxRDAPCalls  int `json:"max_rdap_calls"`
+	MaxGeoCalls   int `json:"max_geo_calls"`
+	MaxRunMinutes int `json:"max_run_minutes"`
+
+	// Records missing geolocation looked up in the background by the GUI,
+	// per hour, 0 for none. Paused while an enrichment runs
+	GeoBackfillPerHour int `json:"geo_backfill_per_hour"`
+
+	// Enforcement dry run: the list to publish is checked against these
+	// prefixes (own, partners, known-good services) and publishing is
+	// refused when it blocks one of the critical kinds
+	ProtectedPrefixesFile   string   `json:"protected_prefixes_file"`
+	CollateralCriticalKinds []string `json:"collateral_critical_kinds"`
+
+	// Ranges broader than these prefix lengths (0 = defaults /16 and /32)
+	// are flagged, or kept out of enforcement exports with policy "exclude";
+	// "off" disables the check
+	BroadPrefixV4     int    `json:"broad_prefix_v4"`
+	BroadPrefixV6     int    `json:"broad_prefix_v6"`
+	BroadPrefixPolicy string `json:"broad_prefix_policy"`
+
+	// Auto-tagging rules evaluated on extraction and re-enrichment
+	TagRules []TagRule `json:"tag_rules"`
+
+	// Links opening a record in external tools, added to or replacing the
+	// default ones by name
+	PivotLinks []PivotLink `json:"pivot_links"`
+
+	// Opt-out or removal pages of the scanners, by scanner name or type,
+	// added to or replacing the default ones (an empty URL removes one)
+	OptOutURLs map[string]string `json:"opt_out_urls"`
+
+	// Reverse DNS patterns attributing records to scanners, checked before
+	// the default and learned ones
+	RDNSPatterns []RDNSPattern `json:"rdns_patterns"`
+
+	// Lowest attribution confidence (low, medium or high; "" = medium) a
+	// record needs to be pushed to enforcement exports
+	MinEnforcementConfidence string `json:"min_enforcement_confidence"`
+
+	// Trust levels of the feeds, first matching rule wins (see FeedTrust)
+	FeedTrust []FeedTrust `json:"feed_trust"`
+
+	// How an IP listed by feeds naming different scanners is attributed:
+	// ConflictPolicyTrust ("" too) or ConflictPolicyMajority
+	ConflictPolicy string `json:"conflict_policy"`
+
+	// SQLite database the GUI keeps its records in ("" = build/data/records.db)
+	StorePath string `json:"store_path"`
+
+	// Greylisting dwell times, in runs
+	CandidateAfterRuns int `json:"candidate_after_runs"` // runs seen before observed -> candidate
+	BlockAfterRuns     int `json:"block_after_runs"`     // runs seen before candidate -> blocked
+	RetireAfterRuns    int `json:"retire_after_runs"`    // runs missed before -> retired
+
+	// Reputation aging of records missing from recent feeds, in days
+	RiskHalfLifeDays int `json:"risk_half_life_days"` // risk halves every N days unseen (0 = default 30)
+	StaleAfterDays   int `json:"stale_after_days"`    // grace before leaving enforcement exports (0 = default 90)
+
+	// IPs and CIDRs whose coming expiry is announced like that of the
+	// enforcement list, ExpiryWarnDays ahead (0 = default 7), in the GUI
+	// and to ExpiryWebhookURL when set
+	Watchlist        []string `json:"watchlist"`
+	ExpiryWarnDays   int      `json:"expiry_warn_days"`
+	ExpiryWebhookURL string   `json:"expiry_webhook_url"`
+
+	// REST server (enabled by EnableAPI)
+	APIListen string    `json:"api_listen"` // listen address, e.g. "127.0.0.1:8088"
+	APIUsers  []APIUser `json:"api_users"`  // per-user keys and roles; APIKey acts as an admin key
+
+	// AbuseIPDB reporting of blocked IPs (opt-in)
+	AbuseIPDBReport     bool              `json:"abuseipdb_report"`      // allow submitting blocked IPs to AbuseIPDB
+	AbuseIPDBKey        string            `json:"abuseipdb_key"`         // AbuseIPDB API key
+	AbuseIPDBDailyQuota int               `json:"abuseipdb_daily_quota"` // reports per UTC day (0 = default 1000)
+	AbuseIPDBCategories map[string]string `json:"abuseipdb_categories"`  // scanner type -> categories, e.g. "14,15"
+
+	// Field redaction of exports, by preset; DefaultExportPreset applies to
+	// exports naming no preset, so a configured redaction cannot be skipped
+	ExportPresets       []ExportPreset `json:"export_presets"`
+	DefaultExportPreset string         `json:"default_export_preset"`
+
+	// ASN and country policy of enforcement exports
+	EnforcementPolicy EnforcementPolicy `json:"enforcement_policy"`
+}
+
+// Role is the access level of an API user.
+type Role string
+
+const (
+	// RoleViewer can read records and annotations.
+	RoleViewer Role = "viewer"
+	// RoleAnalyst can also annotate and trigger enrichment.
+	RoleAnalyst Role = "analyst"
+	// RoleAdmin can also change the configuration and publish enforcement feeds.
+	RoleAdmin Role = "admin"
+)
+
+// Allows reports whether r grants at least the access of required.
+func (r Role) Allows(required Role) bool {
+	rank := map[Role]int{RoleViewer: 1, RoleAnalyst: 2, RoleAdmin: 3}
+	return rank[r] > 0 && rank[r] >= rank[required]
+}
+
+// TagRule adds Tags to the records matching every condition set: country
+// code, ASN, scanner type (each a list, any entry matching) and a regular
+// expression on the RDAP, AS or ISP name. A rule without conditions never
+// matches.
+type TagRule struct {
+	Name         string   `json:"name"`
+	Tags         []string `json:"tags"`
+	Countries    []string `json:"countries,omitempty"`
+	ASNs         []string `json:"asns,omitempty"`
+	OrgRegex     string   `json:"org_regex,omitempty"`
+	ScannerTypes []string `json:"scanner_types,omitempty"`
+}
+
+// RDNSPattern attributes the IPs whose reverse DNS name matches Pattern, a
+// shell glob such as "*.shodan.io" or "scanner-*.ipip.net", to Scanner.
+type RDNSPattern struct {
+	Scanner string `json:"scanner"`
+	Pattern string `json:"pattern"`
+}
+
+// FeedTrust gives the feeds whose source matches Source, a shell glob such
+// as "shodan.nft" or "partner-*.zip", a trust Level from 0 to 100. A source
+// is a feed file name, an archive name or "archive:path" for a feed inside
+// an archive. Unmatched feeds have DefaultFeedTrust.
+type FeedTrust struct {
+	Source string `json:"source"`
+	Level  int    `json:"level"`
+}
+
+// DefaultFeedTrust is the trust level of a feed no FeedTrust rule matches.
+const DefaultFeedTrust = 50
+
+// Conflict policies: which scanner an IP listed by several feeds is
+// attributed to.
+const (
+	// ConflictPolicyTrust takes the scanner of the most trusted feed
+	ConflictPolicyTrust = "trust"
+	// ConflictPolicyMajority takes the scanner named by the most feeds,
+	// the most trusted one on a tie
+	ConflictPolicyMajority = "majority"
+)
+
+// PivotLink opens a record in an external tool. URL may use the {ip},
+// {cidr}, {asn} and {registry_url} placeholders (see extractor.PivotURLs).
+// A link with an empty URL removes the default link of the same name.
+type PivotLink struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// APIUser is a REST API credential.
+type APIUser struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+	Role Role   `json:"role"`
+}
+
+// EnforcementPolicy keeps blocked records out of enforcement exports by ASN
+// or country code: never those listed in ExcludeASNs or ExcludeCountries,
+// and, when OnlyASNs or OnlyCountries is set, only those listed there.
+// A record whose ASN or country is unknown matches no list.
+type EnforcementPolicy struct {
+	ExcludeASNs      []string `json:"exclude_asns,omitempty"`
+	ExcludeCountries []string `json:"exclude_countries,omitempty"`
+	OnlyASNs         []string `json:"only_asns,omitempty"`
+	OnlyCountries    []string `json:"only_countries,omitempty"`
+}
+
+// Empty reports whether p holds no record back.
+func (p EnforcementPolicy) Empty() bool {
+	return len(p.ExcludeASNs) == 0 && len(p.ExcludeCountries) == 0 && len(p.OnlyASNs) == 0 && len(p.OnlyCountries) == 0
+}
+
+// Redaction actions of a RedactionRule.
+const (
+	// RedactRemove empties the field.
+	RedactRemove = "remove"
+	// RedactHash replaces the field by a short SHA-256 digest, so equal
+	// values can still be matched across records.
+	RedactHash = "hash"
+	// RedactMask keeps the first and last characters of the field.
+	RedactMask = "mask"
+	// RedactDomain keeps the domain of an email address or host name.
+	RedactDomain = "domain"
+)
+
+// RedactionRule redacts Field, the JSON name of a ScannerData field such as
+// "abuse_email", with Action in the exported records.
+type RedactionRule struct {
+	Field  string `json:"field"`
+	Action string `json:"action"`
+}
+
+// ExportPreset is a named set of redaction rules applied to exports.
+type ExportPreset struct {
+	Name   string          `json:"name"`
+	Redact []RedactionRule `json:"redact"`
+}
+
+// AppConfig represents the top-level application configuration including theme, logging, and database settings.
+type AppConfig struct {
+	AppName    string         `json:"app_name"`
+	Version    string         `json:"version"`
+	Owner      string         `json:"owner"`
+	Theme      string         `json:"theme"`
+	Language   string         `json:"language"`
+	LogLevel   string         `json:"log_level"`
+	MaxLogSize int            `json:"max_log_size"`
+	LogBackups int            `json:"log_backups"`
+	Database   DatabaseConfig `json:"database"`
+	// DisableUpdateCheck turns off the startup check for a newer release.
+	DisableUpdateCheck bool `json:"disable_update_check"`
+	// ColumnWidths holds the record table column widths set by the user, by
+	// column header. Columns not listed are sized to their content.
+	ColumnWidths map[string]float32 `json:"column_widths,omitempty"`
+	// RowHeight is the record table row height set by the user (0 = default).
+	RowHeight float32 `json:"row_height,omitempty"`
+	// ExtraColumns lists the optional record table columns shown, by header
+	// ("Continent", "Region"); the others are hidden.
+	ExtraColumns []string `json:"extra_columns,omitempty"`
+	// TextScale multiplies the GUI text size, e.g. 1.5 (0 = 1).
+	TextScale float32 `json:"text_scale,omitempty"`
+	// PlainLabels removes emoji from GUI labels and log messages.
+	PlainLabels bool `json:"plain_labels"`
+}
+
+// SearchFilter defines criteria for filtering scanner data by query, type, country, ISP, risk level, and date range.
+type SearchFilter struct {
+	Query       string      `json:"query"`
+	Type        string      `json:"type"`
+	ScannerType ScannerType `json:"scanner_type"`
+	Country     string      `json:"country"`
+	ISP         string      `json:"isp"`
+	RiskLevel   string      `json:"risk_level"`
+	DateFrom    time.Time   `json:"date_from"`
+	DateTo      time.Time   `json:"date_to"`
+}
+
+// CSVHeaders defines the canonical column headers for CSV export of ScannerData.
+var CSVHeaders = []string{
+	"ID", "IP/CIDR", "Scanner Name", "Scanner Type", "Source File",
+	"Country Code", "Country Name", "ISP", "Organization",
+	"RDAP Name", "RDAP Handle", "RDAP CIDR", "RDAP Registry",
+	"Start Address", "End Address", "IP Version", "RDAP Type", "Parent Handle",
+	"Event Registration", "Event Last Changed",
+	"ASN", "AS Name", "Reverse DNS",
+	"Abuse Confidence Score", "Abuse Reports", "Usage Type",
+	"Domain", "Last Seen", "First Seen", "Tags", "Notes",
+	"Risk Level", "Export Date", "Abuse Email", "Tech Email",
+	"PeeringDB Name", "Network Type", "Traffic Level", "PeeringDB Contacts",
+	"State", "Runs Seen", "Previous Owner", "City",
+	"Open Ports", "Hostnames", "CPEs", "Target Ports",
+	"Latitude", "Longitude", "Continent", "Continent Code", "Region",
+}
+
+// ScannerDataToCSVRow converts a ScannerData record to a CSV row matching CSVHeaders order.
+func ScannerDataToCSVRow(item ScannerData) []string {
+	return []string{
+		item.ID,
+		item.IPOrCIDR,
+		item.ScannerName,
+		string(item.ScannerType),
+		item.SourceFile,
+		item.CountryCode,
+		item.CountryName,
+		item.ISP,
+		item.Organization,
+		item.RDAPName,
+		item.RDAPHandle,
+		item.RDAPCIDR,
+		item.Registry,
+		item.StartAddress,
+		item.EndAddress,
+		item.IPVersion,
+		item.RDAPType,
+		item.ParentHandle,
+		FormatTimestamp(item.EventRegistration),
+		FormatTimestamp(item.EventLastChanged),
+		item.ASN,
+		item.ASName,
+		item.ReverseDNS,
+		fmt.Sprintf("%d", item.AbuseConfidenceScore),
+		fmt.Sprintf("%d", item.AbuseReports),
+		item.UsageType,
+		item.Domain,
+		item.LastSeen.Format("2006-01-02 15:04:05"),
+		item.FirstSeen.Format("2006-01-02 15:04:05"),
+		strings.Join(item.Tags, ", "),
+		item.Notes,
+		item.RiskLevel,
+		item.ExportDate.Format("2006-01-02 15:04:05"),
+		item.AbuseEmail,
+		item.TechEmail,
+		item.PeeringDBName,
+		item.NetworkType,
+		item.TrafficLevel,
+		item.PeeringDBContacts,
+		string(item.State),
+		fmt.Sprintf("%d", item.RunsSeen),
+		item.PreviousOwner,
+		item.City,
+		FormatPorts(item.OpenPorts),
+		strings.Join(item.Hostnames, ", "),
+		strings.Join(item.CPEs, ", "),
+		FormatPorts(item.TargetPorts),
+		formatCoordinate(item, item.Latitude),
+		formatCoordinate(item, item.Longitude),
+		item.Continent,
+		item.ContinentCode,
+		item.Region,
+	}
+}
+
+// formatCoordinate formats v, a coordinate of item, or "" when item is not
+// located.
+func formatCoordinate(item ScannerData, v float64) string {
+	if !item.HasLocation() {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// FormatPorts formats ports as "22, 80, 443".
+func FormatPorts(ports []int) string {
+	s := make([]string, len(ports))
+	for i, p := range ports {
+		s[i] = strconv.Itoa(p)
+	}
+	return strings.Join(s, ", ")
+}
+
+// ParsePorts parses a list formatted by FormatPorts, skipping entries that
+// are not port numbers.
+func ParsePorts(s string) []int {
+	var ports []int
+	for _, f := range strings.Split(s, ",") {
+		if p, err := strconv.Atoi(strings.TrimSpace(f)); err == nil && p > 0 && p <= 65535 {
+			ports = append(ports, p)
+		}
+	}
+	return ports
+}
+
+// MergePorts returns the ports of a and b, sorted and without duplicates.
+func MergePorts(a, b []int) []int {
+	seen := make(map[int]bool, len(a)+len(b))
+	var ports []int
+	for _, p := range append(append([]int(nil), a...), b...) {
+		if !seen[p] {
+			seen[p] = true
+			ports = append(ports, p)
+		}
+	}
+	sort.Ints(ports)
+	return ports
+}
+
+// LogLevel represents the severity level of a log entry.
+type LogLevel string
+
+const (
+	// LogLevelDebug indicates verbose debug-level logging.
+	LogLevelDebug LogLevel = "DEBUG"
+	// LogLevelInfo indicates informational messages.
+	LogLevelInfo LogLevel = "INFO"
+	// LogLevelWarning indicates potentially harmful situations.
+	LogLevelWarning LogLevel = "WARNING"
+	// LogLevelError indicates error events that might still allow the application to continue.
+	LogLevelError LogLevel = "ERROR"
+	// LogLevelCritical indicates severe error events that will likely cause the application to abort.
+	LogLevelCritical LogLevel = "CRITICAL"
+)
+
+// LogEntry represents a single structured log record with timestamp, level, component, and message.
+type LogEntry struct {
+	Timestamp time.Time              `json:"timestamp"`
+	Level     LogLevel               `json:"level"`
+	Component string                 `json:"component"`
+	Message   string                 `json:"message"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"strings"
 	"testing"
 	"time"
@@ -261,8 +262,8 @@ func TestScannerDataToCSVRow_BasicOutput(t *testing.T) {
 		IPVersion:            "v4",
 		RDAPType:             "DIRECT ALLOCATION",
 		ParentHandle:         "NET-192-0-0-0-0",
-		EventRegistration:    "2020-01-01",
-		EventLastChanged:     "2023-06-15",
+		EventRegistration:    time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		EventLastChanged:     time.Date(2023, 6, 15, 0, 0, 0, 0, time.FixedZone("CEST", 2*3600)),
 		ASN:                  "AS12345",
 		ASName:               "TestAS",
 		ReverseDNS:           "test.example.com",
@@ -298,6 +299,8 @@ func TestScannerDataToCSVRow_BasicOutput(t *testing.T) {
 		7:  "Test ISP",
 		8:  "Test Org",
 		9:  "TESTNET",
+		18: "2020-01-01T00:00:00Z",
+		19: "2023-06-14T22:00:00Z",
 		20: "AS12345",
 		21: "TestAS",
 		22: "test.example.com",
@@ -402,8 +405,8 @@ func TestScannerDataToCSVRow_IntegerFormatting(t *testing.T) {
 // -------------------------------------------------------
 
 func TestCSVHeaders_Count(t *testing.T) {
-	if len(CSVHeaders) != 35 {
-		t.Errorf("Expected 35 CSV headers, got %d", len(CSVHeaders))
+	if len(CSVHeaders) != 52 {
+		t.Errorf("Expected 52 CSV headers, got %d", len(CSVHeaders))
 	}
 }
 
@@ -418,6 +421,27 @@ func TestCSVHeaders_NoDuplicates(t *testing.T) {
 	}
 }
 
+// TestRole_Allows tests the API role hierarchy
+func TestRole_Allows(t *testing.T) {
+	tests := []struct {
+		role, required Role
+		want           bool
+	}{
+		{RoleViewer, RoleViewer, true},
+		{RoleViewer, RoleAnalyst, false},
+		{RoleAnalyst, RoleViewer, true},
+		{RoleAnalyst, RoleAdmin, false},
+		{RoleAdmin, RoleAnalyst, true},
+		{"", RoleViewer, false},
+		{"root", RoleViewer, false},
+	}
+	for _, tt := range tests {
+		if got := tt.role.Allows(tt.required); got != tt.want {
+			t.Errorf("Role(%q).Allows(%q) = %v, want %v", tt.role, tt.required, got, tt.want)
+		}
+	}
+}
+
 // BenchmarkScannerDataCreation benchmarks scanner data creation
 func BenchmarkScannerDataCreation(b *testing.B) {
 	for i := 0; i < b.N; i++ {
@@ -435,3 +459,89 @@ func BenchmarkScannerDataCreation(b *testing.B) {
 		}
 	}
 }
+
+// -------------------------------------------------------
+// Timestamps
+// -------------------------------------------------------
+
+func TestParseTimestamp_NormalizesToUTC(t *testing.T) {
+	want := time.Date(2009, 1, 1, 5, 0, 0, 0, time.UTC)
+	for _, in := range []string{"2009-01-01T00:00:00-05:00", "2009-01-01T05:00:00Z", "2009-01-01T05:00:00", "2009-01-01 05:00:00"} {
+		got, err := ParseTimestamp(in)
+		if err != nil || !got.Equal(want) || got.Location() != time.UTC {
+			t.Errorf("ParseTimestamp(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if got, err := ParseTimestamp(""); err != nil || !got.IsZero() {
+		t.Errorf("ParseTimestamp(\"\") = %v, %v; want zero time", got, err)
+	}
+	if _, err := ParseTimestamp("last week"); err == nil {
+		t.Error("ParseTimestamp(\"last week\"): expected an error")
+	}
+}
+
+func TestScannerDataJSON_EventDates(t *testing.T) {
+	legacy := `{"ip_or_cidr":"192.0.2.1","event_registration":"2020-01-01","event_last_changed":"","last_seen":"2024-06-15T12:00:00Z"}`
+	var d ScannerData
+	if err := json.Unmarshal([]byte(legacy), &d); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if !d.EventRegistration.Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)) || !d.EventLastChanged.IsZero() || d.IPOrCIDR != "192.0.2.1" || d.LastSeen.IsZero() {
+		t.Fatalf("decoded %+v", d)
+	}
+
+	b, err := json.Marshal(d)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	out := string(b)
+	if !strings.Contains(out, `"event_registration":"2020-01-01T00:00:00Z"`) || !strings.Contains(out, `"event_last_changed":""`) {
+		t.Errorf("event dates not written as RFC 3339 strings: %s", out)
+	}
+	var again ScannerData
+	if err := json.Unmarshal(b, &again); err != nil || !again.EventRegistration.Equal(d.EventRegistration) {
+		t.Errorf("round trip: %v, %v", again.EventRegistration, err)
+	}
+}
+
+func TestRDAPCacheEntryJSON_LegacyCachedAt(t *testing.T) {
+	var e RDAPCacheEntry
+	if err := json.Unmarshal([]byte(`{"rdap_name":"NET","cached_at":"2024-06-15T14:00:00+02:00","event_registration":"garbage"}`), &e); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if e.RDAPName != "NET" || !e.CachedAt.Equal(time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)) || !e.EventRegistration.IsZero() {
+		t.Errorf("decoded %+v", e)
+	}
+}
+
+func TestProvenance_RoundTrip(t *testing.T) {
+	var d ScannerData
+	at := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	d.SetProvenance(ProvenanceRDAP, "ripe", at)
+	d.SetProvenance(ProvenanceGeo, "ip-api", at)
+
+	s := FormatProvenance(d.Provenance)
+	if s != "geo:ip-api@2024-05-01T10:00:00Z, rdap:ripe@2024-05-01T10:00:00Z" {
+		t.Errorf("FormatProvenance = %q", s)
+	}
+	back := ParseProvenance(s)
+	if len(back) != 2 || back[ProvenanceRDAP] != "ripe@2024-05-01T10:00:00Z" {
+		t.Errorf("ParseProvenance = %v", back)
+	}
+	if ParseProvenance("") != nil {
+		t.Error("empty provenance should parse to nil")
+	}
+}
+
+func TestFormatParsePorts(t *testing.T) {
+	if got := FormatPorts([]int{22, 80, 443}); got != "22, 80, 443" {
+		t.Errorf("FormatPorts = %q", got)
+	}
+	got := ParsePorts("22, 80,x, 70000,443")
+	if len(got) != 3 || got[0] != 22 || got[2] != 443 {
+		t.Errorf("ParsePorts = %v, want [22 80 443]", got)
+	}
+	if ParsePorts("") != nil {
+		t.Error("ParsePorts(\"\") is not nil")
+	}
+}
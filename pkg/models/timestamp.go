@@ -0,0 +1,107 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// timestampLayouts are the date formats accepted by ParseTimestamp: RDAP
+// event dates, RFC 3339 with or without a zone, and bare dates.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// ParseTimestamp parses an RDAP event date or cache timestamp and returns it
+// in UTC. Dates without a zone are taken as UTC. An empty string is the zero
+// time.
+func ParseTimestamp(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+}
+
+// FormatTimestamp formats t as RFC 3339 in UTC, or "" for the zero time.
+func FormatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// parseStoredTimestamp reads a date from a JSON file written by any version.
+// Dates that cannot be parsed are left unset rather than failing the file.
+func parseStoredTimestamp(s string) time.Time {
+	t, _ := ParseTimestamp(s)
+	return t
+}
+
+// MarshalJSON writes the RDAP event dates as RFC 3339 strings, empty when
+// unknown, as older versions did.
+func (d ScannerData) MarshalJSON() ([]byte, error) {
+	type alias ScannerData
+	return json.Marshal(struct {
+		alias
+		EventRegistration string `json:"event_registration"`
+		EventLastChanged  string `json:"event_last_changed"`
+	}{alias(d), FormatTimestamp(d.EventRegistration), FormatTimestamp(d.EventLastChanged)})
+}
+
+// UnmarshalJSON reads the RDAP event dates with ParseTimestamp, so records
+// saved with free-form dates still load.
+func (d *ScannerData) UnmarshalJSON(b []byte) error {
+	type alias ScannerData
+	aux := struct {
+		*alias
+		EventRegistration string `json:"event_registration"`
+		EventLastChanged  string `json:"event_last_changed"`
+	}{alias: (*alias)(d)}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	d.EventRegistration = parseStoredTimestamp(aux.EventRegistration)
+	d.EventLastChanged = parseStoredTimestamp(aux.EventLastChanged)
+	return nil
+}
+
+// MarshalJSON writes the dates of a cache entry as RFC 3339 strings, empty
+// when unknown.
+func (c RDAPCacheEntry) MarshalJSON() ([]byte, error) {
+	type alias RDAPCacheEntry
+	return json.Marshal(struct {
+		alias
+		EventRegistration string `json:"event_registration"`
+		EventLastChanged  string `json:"event_last_changed"`
+		CachedAt          string `json:"cached_at"`
+	}{alias(c), FormatTimestamp(c.EventRegistration), FormatTimestamp(c.EventLastChanged), FormatTimestamp(c.CachedAt)})
+}
+
+// UnmarshalJSON reads the dates of a cache entry with ParseTimestamp, so
+// caches written with string dates keep working.
+func (c *RDAPCacheEntry) UnmarshalJSON(b []byte) error {
+	type alias RDAPCacheEntry
+	aux := struct {
+		*alias
+		EventRegistration string `json:"event_registration"`
+		EventLastChanged  string `json:"event_last_changed"`
+		CachedAt          string `json:"cached_at"`
+	}{alias: (*alias)(c)}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	c.EventRegistration = parseStoredTimestamp(aux.EventRegistration)
+	c.EventLastChanged = parseStoredTimestamp(aux.EventLastChanged)
+	c.CachedAt = parseStoredTimestamp(aux.CachedAt)
+	return nil
+}